@@ -0,0 +1,266 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// activityWindow is the span of each search GetMemberActivity makes.  The
+// windows are truncated to multiples of it so every call asks for the same
+// ones.
+const activityWindow = 30 * 24 * time.Hour
+
+// activitySettle is how long after a window ends its results are taken to
+// be final, results keep arriving for a while after a session ends
+const activitySettle = 24 * time.Hour
+
+// activity state outlives any sensible result ttl so searched windows stay
+// searched
+const activityStateTTL = 365 * 24 * time.Hour
+
+// ActivityDay counts the sessions of a member that started on a day
+type ActivityDay struct {
+	// Date is midnight of the day in the location of the activity
+	Date time.Time
+
+	Sessions int
+
+	// Official counts the official series sessions, Unofficial the series
+	// sessions that weren't official and Hosted the hosted and league
+	// sessions
+	Official   int
+	Unofficial int
+	Hosted     int
+
+	// EventTypes counts the sessions by event type, e.g. 5 for races
+	EventTypes map[int]int
+}
+
+// MemberActivity is what GetMemberActivity returns, an entry of Days per
+// day from the day of from until to
+type MemberActivity struct {
+	CustID   int64
+	Location *time.Location
+	Days     []ActivityDay
+
+	// Meta counts the searches made by this call and the rows they fetched,
+	// Returned the sessions counted in Days
+	Meta SearchMeta
+}
+
+// Day returns the day of Days t falls on in the location of the activity,
+// false if it's out of range
+func (a *MemberActivity) Day(t time.Time) (ActivityDay, bool) {
+	if len(a.Days) == 0 {
+		return ActivityDay{}, false
+	}
+
+	date := midnight(t.In(a.Location))
+
+	for _, day := range a.Days {
+		if day.Date.Equal(date) {
+			return day, true
+		}
+	}
+
+	return ActivityDay{}, false
+}
+
+// activityStateT is what GetMemberActivity keeps in the cache per member
+type activityStateT struct {
+	// Completed holds the beginning of every window searched after it
+	// settled
+	Completed map[int64]bool
+
+	// Sessions are the sessions seen so far by subsession id
+	Sessions map[int64]activitySessionT
+}
+
+type activitySessionT struct {
+	Start     int64 `json:"start"`
+	EventType int   `json:"event_type"`
+	Official  bool  `json:"official,omitempty"`
+	Hosted    bool  `json:"hosted,omitempty"`
+}
+
+// GetMemberActivity counts the sessions custID started per day from from
+// until to, official, unofficial and hosted, for an activity heatmap.  Days
+// run from midnight to midnight in loc, UTC if nil, so they're 23 or 25
+// hours long around a DST change.
+//
+// The sessions are found with search_series and search_hosted by cust_id, a
+// search of each per 30 day window.  With the cache enabled the sessions
+// seen are kept in it along with the windows that are done (those that ended
+// more than a day ago), so later calls only search the recent windows.
+func (i *Irdata) GetMemberActivity(ctx context.Context, custID int64, from time.Time, to time.Time, loc *time.Location) (*MemberActivity, error) {
+	if custID == 0 {
+		return nil, errors.New("must provide cust id")
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	begin := midnight(from.In(loc))
+
+	if !begin.Before(to) {
+		return nil, errors.New("must provide a time range")
+	}
+
+	key := fmt.Sprintf("irdata.activity.%d", custID)
+
+	state, err := i.loadActivity(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &MemberActivity{CustID: custID, Location: loc}
+
+	now := i.clock.Now()
+
+	for w := begin.Truncate(activityWindow); w.Before(to) && w.Before(now); w = w.Add(activityWindow) {
+		if state.Completed[w.Unix()] {
+			continue
+		}
+
+		if err := i.searchActivity(ctx, custID, w, &state, &activity.Meta); err != nil {
+			if saveErr := i.saveActivity(key, state); saveErr != nil {
+				i.logger.WithFields(log.Fields{"err": saveErr}).Error("Unable to save activity state")
+			}
+
+			return nil, err
+		}
+
+		if !w.Add(activityWindow + activitySettle).After(now) {
+			state.Completed[w.Unix()] = true
+		}
+	}
+
+	if err := i.saveActivity(key, state); err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+
+	for d := begin; d.Before(to); d = time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc) {
+		index[d.Format("2006-01-02")] = len(activity.Days)
+		activity.Days = append(activity.Days, ActivityDay{Date: d, EventTypes: make(map[int]int)})
+	}
+
+	for _, session := range state.Sessions {
+		start := time.Unix(session.Start, 0).In(loc)
+
+		n, ok := index[start.Format("2006-01-02")]
+		if !ok || !start.Before(to) {
+			continue
+		}
+
+		day := &activity.Days[n]
+
+		day.Sessions++
+		day.EventTypes[session.EventType]++
+
+		switch {
+		case session.Hosted:
+			day.Hosted++
+		case session.Official:
+			day.Official++
+		default:
+			day.Unofficial++
+		}
+
+		activity.Meta.Returned++
+	}
+
+	return activity, nil
+}
+
+// searchActivity adds the sessions of custID in the window beginning at w
+// to state
+func (i *Irdata) searchActivity(ctx context.Context, custID int64, w time.Time, state *activityStateT, meta *SearchMeta) error {
+	i.logger.WithFields(log.Fields{"cust_id": custID, "window": w}).Debug("Searching member activity")
+
+	series, err := i.SearchSeriesResults(ctx, SearchSeriesParams{
+		StartRangeBegin: w,
+		StartRangeEnd:   w.Add(activityWindow),
+		CustID:          custID,
+	})
+	if err != nil {
+		return err
+	}
+
+	hosted, err := i.SearchHostedResults(ctx, SearchHostedParams{
+		StartRangeBegin: w,
+		StartRangeEnd:   w.Add(activityWindow),
+		CustID:          custID,
+	})
+	if err != nil {
+		return err
+	}
+
+	meta.Windows += series.Meta.Windows + hosted.Meta.Windows
+	meta.Fetched += series.Meta.Fetched + hosted.Meta.Fetched
+
+	for _, row := range series.Rows {
+		if _, ok := state.Sessions[row.SubsessionID]; !ok {
+			state.Sessions[row.SubsessionID] = activitySessionT{
+				Start:     row.StartTime.Unix(),
+				EventType: row.EventType,
+				Official:  row.OfficialSession,
+			}
+		}
+	}
+
+	for _, row := range hosted.Rows {
+		if _, ok := state.Sessions[row.SubsessionID]; !ok {
+			state.Sessions[row.SubsessionID] = activitySessionT{
+				Start:     row.StartTime.Unix(),
+				EventType: row.EventType,
+				Hosted:    true,
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadActivity returns the activity state kept under key, an empty one if
+// there's none or the cache isn't enabled
+func (i *Irdata) loadActivity(ctx context.Context, key string) (activityStateT, error) {
+	var state activityStateT
+
+	if i.cache != nil {
+		if _, err := i.getCachedJSON(ctx, key, &state); err != nil {
+			return state, err
+		}
+	}
+
+	if state.Completed == nil {
+		state.Completed = make(map[int64]bool)
+	}
+
+	if state.Sessions == nil {
+		state.Sessions = make(map[int64]activitySessionT)
+	}
+
+	return state, nil
+}
+
+// saveActivity saves with the context of the instance so what was searched
+// before the call was cancelled is kept
+func (i *Irdata) saveActivity(key string, state activityStateT) error {
+	if i.cache == nil {
+		return nil
+	}
+
+	return i.setCachedJSON(i.ctx, key, state, activityStateTTL)
+}
+
+// midnight returns the beginning of the day of t in its location
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,170 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// activitySearch serves the rows of fixture starting within the range asked
+// for
+func activitySearch(t *testing.T, fixture string) func(r *http.Request) []string {
+	var rows []struct {
+		StartTime time.Time `json:"start_time"`
+	}
+
+	data := readFixture(t, fixture)
+	assert.NoError(t, json.Unmarshal(data, &rows))
+
+	var raw []json.RawMessage
+	assert.NoError(t, json.Unmarshal(data, &raw))
+
+	return func(r *http.Request) []string {
+		assert.Equal(t, "123", r.URL.Query().Get("cust_id"))
+
+		begin, err := time.Parse(searchTimeFormat, r.URL.Query().Get("start_range_begin"))
+		assert.NoError(t, err)
+
+		end, err := time.Parse(searchTimeFormat, r.URL.Query().Get("start_range_end"))
+		assert.NoError(t, err)
+
+		chunk := []json.RawMessage{}
+
+		for n, row := range rows {
+			if !row.StartTime.Before(begin) && row.StartTime.Before(end) {
+				chunk = append(chunk, raw[n])
+			}
+		}
+
+		out, err := json.Marshal(chunk)
+		assert.NoError(t, err)
+
+		return []string{string(out)}
+	}
+}
+
+func TestGetMemberActivity(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", activitySearch(t, "member_activity_series.json"))
+	m.handleChunked("/data/results/search_hosted", activitySearch(t, "member_activity_hosted.json"))
+
+	clock := newFakeClock()
+	clock.advance(15 * 24 * time.Hour)
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	ny, err := time.LoadLocation("America/New_York")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	from := time.Date(2024, 2, 10, 15, 0, 0, 0, ny)
+	to := time.Date(2024, 3, 15, 0, 0, 0, 0, ny)
+
+	activity, err := api.GetMemberActivity(context.Background(), 123, from, to, ny)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, activity.Days, 34)
+	assert.Equal(t, time.Date(2024, 2, 10, 0, 0, 0, 0, ny), activity.Days[0].Date)
+	assert.Equal(t, SearchMeta{Windows: 4, Fetched: 9, Returned: 8}, activity.Meta)
+
+	sessions := 0
+	for _, day := range activity.Days {
+		sessions += day.Sessions
+	}
+
+	assert.Equal(t, 8, sessions)
+
+	// a race at 23:30 on the 29th is on the 29th although it's March in UTC
+	day, ok := activity.Day(time.Date(2024, 2, 29, 12, 0, 0, 0, ny))
+	assert.True(t, ok)
+	assert.Equal(t, ActivityDay{
+		Date:       time.Date(2024, 2, 29, 0, 0, 0, 0, ny),
+		Sessions:   1,
+		Official:   1,
+		EventTypes: map[int]int{5: 1},
+	}, day)
+
+	day, _ = activity.Day(time.Date(2024, 3, 1, 12, 0, 0, 0, ny))
+	assert.Equal(t, ActivityDay{
+		Date:       time.Date(2024, 3, 1, 0, 0, 0, 0, ny),
+		Sessions:   2,
+		Official:   1,
+		Unofficial: 1,
+		EventTypes: map[int]int{2: 1, 3: 1},
+	}, day)
+
+	// clocks sprang forward on the 10th, which is 23 hours long
+	day, _ = activity.Day(time.Date(2024, 3, 9, 12, 0, 0, 0, ny))
+	assert.Equal(t, 1, day.Hosted)
+
+	day, _ = activity.Day(time.Date(2024, 3, 10, 12, 0, 0, 0, ny))
+	assert.Equal(t, 1, day.Unofficial)
+	assert.Equal(t, 1, day.Sessions)
+
+	next, _ := activity.Day(time.Date(2024, 3, 11, 12, 0, 0, 0, ny))
+	assert.Equal(t, 23*time.Hour, next.Date.Sub(day.Date))
+
+	// 04:30 UTC is past midnight once daylight saving time started
+	assert.Equal(t, 1, next.Official)
+
+	_, ok = activity.Day(to)
+	assert.False(t, ok, "to isn't included")
+
+	// the window still settling is searched again, the seen sessions are
+	// counted once
+	again, err := api.GetMemberActivity(context.Background(), 123, from, to, ny)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, again.Meta.Windows)
+	assert.Equal(t, activity.Days, again.Days)
+
+	// and not once it settled
+	clock.advance(3 * 24 * time.Hour)
+
+	again, err = api.GetMemberActivity(context.Background(), 123, from, to, ny)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, again.Meta.Windows)
+
+	again, err = api.GetMemberActivity(context.Background(), 123, from, to, ny)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, again.Meta.Windows)
+	assert.Equal(t, activity.Days, again.Days)
+}
+
+func TestGetMemberActivityUTC(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", activitySearch(t, "member_activity_series.json"))
+	m.handleChunked("/data/results/search_hosted", activitySearch(t, "member_activity_hosted.json"))
+
+	clock := newFakeClock()
+	clock.advance(15 * 24 * time.Hour)
+
+	// without the cache every call searches every window
+	api := m.openAuthed(t, WithClock(clock))
+
+	from := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	activity, err := api.GetMemberActivity(context.Background(), 123, from, to, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, activity.Days, 2)
+	assert.Equal(t, 0, activity.Days[0].Sessions)
+	assert.Equal(t, 3, activity.Days[1].Sessions)
+
+	again, err := api.GetMemberActivity(context.Background(), 123, from, to, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, activity.Meta, again.Meta)
+
+	_, err = api.GetMemberActivity(context.Background(), 123, to, from, nil)
+	assert.Error(t, err)
+}
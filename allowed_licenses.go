@@ -0,0 +1,261 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// License groups, as in License.GroupID and Season.LicenseGroup
+const (
+	LicenseGroupRookie = 1
+	LicenseGroupD      = 2
+	LicenseGroupC      = 3
+	LicenseGroupB      = 4
+	LicenseGroupA      = 5
+	LicenseGroupPro    = 6
+	LicenseGroupProWC  = 7
+)
+
+// levelsPerGroup is how many license levels each group below Pro spans,
+// Rookie is 1 to 4, D 5 to 8 and so on
+const levelsPerGroup = 4
+
+var licenseGroupNames = map[int]string{
+	LicenseGroupRookie: "Rookie",
+	LicenseGroupD:      "Class D",
+	LicenseGroupC:      "Class C",
+	LicenseGroupB:      "Class B",
+	LicenseGroupA:      "Class A",
+	LicenseGroupPro:    "Pro",
+	LicenseGroupProWC:  "Pro/WC",
+}
+
+// AllowedLicense is a license group a season accepts, the levels of it
+// from MinLicenseLevel to MaxLicenseLevel
+type AllowedLicense struct {
+	GroupName       string `json:"group_name"`
+	LicenseGroup    int    `json:"license_group"`
+	MinLicenseLevel int    `json:"min_license_level"`
+	MaxLicenseLevel int    `json:"max_license_level"`
+	ParentID        int64  `json:"parent_id"`
+}
+
+// AllowedLicenses are the licenses a season accepts.  The official seasons
+// list them as an array, the fixed setup variants as an object keyed by
+// license group; Raw holds them as sent for what Licenses doesn't model.
+type AllowedLicenses struct {
+	Licenses []AllowedLicense
+	Raw      json.RawMessage
+}
+
+// UnmarshalJSON decodes the licenses from either format
+func (a *AllowedLicenses) UnmarshalJSON(data []byte) error {
+	a.Raw = append(json.RawMessage{}, data...)
+	a.Licenses = nil
+
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case bytes.HasPrefix(data, []byte("{")):
+		var byGroup map[string]AllowedLicense
+
+		if err := json.Unmarshal(data, &byGroup); err != nil {
+			return fmt.Errorf("decoding allowed licenses: %w", err)
+		}
+
+		for key, license := range byGroup {
+			if license.LicenseGroup == 0 {
+				license.LicenseGroup, _ = strconv.Atoi(key)
+			}
+
+			a.Licenses = append(a.Licenses, license)
+		}
+
+		sort.Slice(a.Licenses, func(x, y int) bool { return a.Licenses[x].LicenseGroup < a.Licenses[y].LicenseGroup })
+	default:
+		if err := json.Unmarshal(data, &a.Licenses); err != nil {
+			return fmt.Errorf("decoding allowed licenses: %w", err)
+		}
+	}
+
+	// the object format leaves out the levels of whole groups
+	for n := range a.Licenses {
+		license := &a.Licenses[n]
+
+		if license.MinLicenseLevel == 0 {
+			license.MinLicenseLevel = groupMinLevel(license.LicenseGroup)
+		}
+
+		if license.MaxLicenseLevel == 0 {
+			license.MaxLicenseLevel = groupMaxLevel(license.LicenseGroup)
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the licenses as they were sent
+func (a AllowedLicenses) MarshalJSON() ([]byte, error) {
+	if len(a.Raw) > 0 {
+		return a.Raw, nil
+	}
+
+	if a.Licenses == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(a.Licenses)
+}
+
+// RookieOnly reports whether only rookies are accepted
+func (a AllowedLicenses) RookieOnly() bool {
+	if len(a.Licenses) == 0 {
+		return false
+	}
+
+	for _, license := range a.Licenses {
+		if license.LicenseGroup != LicenseGroupRookie {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LicenseGroupType is an entry of Season.LicenseGroupTypes
+type LicenseGroupType struct {
+	LicenseGroupType int `json:"license_group_type"`
+}
+
+func groupMinLevel(group int) int {
+	return (group-1)*levelsPerGroup + 1
+}
+
+func groupMaxLevel(group int) int {
+	return group * levelsPerGroup
+}
+
+// licenseGroupOf is the group of license, from its level if the group
+// isn't set
+func licenseGroupOf(license License) int {
+	if license.GroupID > 0 {
+		return int(license.GroupID)
+	}
+
+	if license.LicenseLevel <= 0 {
+		return 0
+	}
+
+	group := (license.LicenseLevel-1)/levelsPerGroup + 1
+	if group > LicenseGroupA {
+		group = LicenseGroupPro
+	}
+
+	return group
+}
+
+// licenseLevelOf is the level of license, the lowest of its group if the
+// level isn't set
+func licenseLevelOf(license License) int {
+	if license.LicenseLevel > 0 {
+		return license.LicenseLevel
+	}
+
+	return groupMinLevel(licenseGroupOf(license))
+}
+
+func licenseGroupName(group int) string {
+	if name, ok := licenseGroupNames[group]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("license group %d", group)
+}
+
+// CanRegister reports whether a member holding license, of the category of
+// the season, with irating may register for the season and if not why not.
+//
+// A license within one of the AllowedLicenses may register, as may one
+// above them all (e.g. an A license in a D series) unless the season is
+// rookie only.  Without AllowedLicenses the LicenseGroupTypes and then
+// LicenseGroup are the lowest group accepted.  MinIRating and MaxIRating
+// apply on top when set.  Seasons limited to AllowedSeasonMembers can't be
+// told from the license and are refused.
+func (s *Season) CanRegister(license License, irating int) (bool, string) {
+	if len(bytes.TrimSpace(s.AllowedSeasonMembers)) > 0 && !bytes.Equal(bytes.TrimSpace(s.AllowedSeasonMembers), []byte("null")) {
+		return false, "only the allowed season members may register"
+	}
+
+	if ok, reason := s.licenseAllowed(license); !ok {
+		return false, reason
+	}
+
+	if s.MinIRating > 0 && irating < s.MinIRating {
+		return false, fmt.Sprintf("requires an iRating of at least %d", s.MinIRating)
+	}
+
+	if s.MaxIRating > 0 && irating > s.MaxIRating {
+		return false, fmt.Sprintf("requires an iRating of at most %d", s.MaxIRating)
+	}
+
+	return true, ""
+}
+
+// licenseAllowed is the license half of CanRegister
+func (s *Season) licenseAllowed(license License) (bool, string) {
+	group, level := licenseGroupOf(license), licenseLevelOf(license)
+
+	allowed := s.AllowedLicenses.Licenses
+
+	if len(allowed) == 0 {
+		minGroup := s.LicenseGroup
+
+		for _, t := range s.LicenseGroupTypes {
+			if minGroup == 0 || t.LicenseGroupType < minGroup {
+				minGroup = t.LicenseGroupType
+			}
+		}
+
+		if minGroup == 0 || group >= minGroup {
+			return true, ""
+		}
+
+		return false, fmt.Sprintf("requires a %s license or better", licenseGroupName(minGroup))
+	}
+
+	lowest, highest := allowed[0], allowed[0]
+
+	for _, a := range allowed {
+		if level >= a.MinLicenseLevel && level <= a.MaxLicenseLevel {
+			return true, ""
+		}
+
+		if a.MinLicenseLevel < lowest.MinLicenseLevel {
+			lowest = a
+		}
+
+		if a.MaxLicenseLevel > highest.MaxLicenseLevel {
+			highest = a
+		}
+	}
+
+	if level > highest.MaxLicenseLevel || group > highest.LicenseGroup {
+		if s.AllowedLicenses.RookieOnly() {
+			return false, "only rookies may register"
+		}
+
+		return true, ""
+	}
+
+	if level < lowest.MinLicenseLevel {
+		return false, fmt.Sprintf("requires a %s license or better", licenseGroupName(lowest.LicenseGroup))
+	}
+
+	// between two allowed groups, e.g. a C license in a series for D and B
+	return false, fmt.Sprintf("doesn't accept %s licenses", licenseGroupName(group))
+}
@@ -0,0 +1,122 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func licenseSeasons(t *testing.T) map[int64]*Season {
+	m := newMockAPI(t)
+	m.handleLinked("/data/series/seasons", string(readFixture(t, "seasons_licenses.json")))
+
+	api := m.openAuthed(t)
+
+	seasons, err := api.GetSeasons(context.Background())
+	assert.NoError(t, err)
+
+	bySeries := make(map[int64]*Season)
+
+	for n := range seasons {
+		bySeries[seasons[n].SeriesID] = &seasons[n]
+	}
+
+	return bySeries
+}
+
+func TestAllowedLicensesDecode(t *testing.T) {
+	seasons := licenseSeasons(t)
+
+	rookie := seasons[116].AllowedLicenses
+	assert.True(t, rookie.RookieOnly())
+	assert.Equal(t, []AllowedLicense{{GroupName: "Rookie", LicenseGroup: 1, MinLicenseLevel: 1, MaxLicenseLevel: 4, ParentID: 116}}, rookie.Licenses)
+
+	vee := seasons[231].AllowedLicenses
+	assert.False(t, vee.RookieOnly())
+	assert.Len(t, vee.Licenses, 4)
+	assert.Equal(t, []LicenseGroupType{{LicenseGroupType: 2}}, seasons[231].LicenseGroupTypes)
+
+	// keyed by group, without levels
+	fixed := seasons[260].AllowedLicenses
+	assert.Equal(t, []AllowedLicense{
+		{GroupName: "Rookie", LicenseGroup: 1, MinLicenseLevel: 1, MaxLicenseLevel: 4, ParentID: 260},
+		{GroupName: "Class D", LicenseGroup: 2, MinLicenseLevel: 5, MaxLicenseLevel: 8, ParentID: 260},
+	}, fixed.Licenses)
+	assert.Contains(t, string(fixed.Raw), `"2": {"group_name": "Class D"`)
+
+	assert.Nil(t, seasons[301].AllowedLicenses.Licenses)
+	assert.Equal(t, 2000, seasons[301].MinIRating)
+	assert.Contains(t, string(seasons[402].AllowedSeasonMembers), "Invited Driver")
+
+	// re-encoded as sent, e.g. in watcher snapshots
+	data, err := json.Marshal(seasons[260])
+	assert.NoError(t, err)
+
+	var again Season
+	assert.NoError(t, json.Unmarshal(data, &again))
+	assert.Equal(t, fixed.Licenses, again.AllowedLicenses.Licenses)
+}
+
+func TestCanRegister(t *testing.T) {
+	seasons := licenseSeasons(t)
+
+	rookie := License{GroupID: LicenseGroupRookie, LicenseLevel: 3}
+	classD := License{GroupID: LicenseGroupD, LicenseLevel: 6}
+	classB := License{GroupID: LicenseGroupB, LicenseLevel: 14}
+	classA := License{LicenseLevel: 20}
+	pro := License{GroupID: LicenseGroupPro, LicenseLevel: 20}
+
+	tests := []struct {
+		seriesID int64
+		license  License
+		irating  int
+		ok       bool
+		reason   string
+	}{
+		// rookie only
+		{116, rookie, 1350, true, ""},
+		{116, classD, 1350, false, "only rookies may register"},
+
+		// D and up, listed group by group
+		{231, rookie, 1350, false, "requires a Class D license or better"},
+		{231, classD, 1350, true, ""},
+		{231, classA, 1350, true, ""},
+		{231, pro, 5000, true, ""},
+
+		// open, listing Rookie and D only, so licenses above those go too
+		{260, rookie, 1350, true, ""},
+		{260, classB, 1350, true, ""},
+
+		// license group types and iRating bounds
+		{301, classD, 2500, false, "requires a Class B license or better"},
+		{301, classB, 1999, false, "requires an iRating of at least 2000"},
+		{301, classB, 3501, false, "requires an iRating of at most 3500"},
+		{301, classA, 2000, true, ""},
+
+		{402, classA, 2000, false, "only the allowed season members may register"},
+	}
+
+	for _, test := range tests {
+		ok, reason := seasons[test.seriesID].CanRegister(test.license, test.irating)
+
+		assert.Equal(t, test.ok, ok, "%d %+v", test.seriesID, test.license)
+		assert.Equal(t, test.reason, reason, "%d %+v", test.seriesID, test.license)
+	}
+}
+
+func TestCanRegisterBetweenGroups(t *testing.T) {
+	season := Season{AllowedLicenses: AllowedLicenses{Licenses: []AllowedLicense{
+		{LicenseGroup: LicenseGroupD, MinLicenseLevel: 5, MaxLicenseLevel: 8},
+		{LicenseGroup: LicenseGroupB, MinLicenseLevel: 13, MaxLicenseLevel: 16},
+	}}}
+
+	ok, reason := season.CanRegister(License{LicenseLevel: 10}, 1500)
+	assert.False(t, ok)
+	assert.Equal(t, "doesn't accept Class C licenses", reason)
+
+	// nothing to go by
+	ok, _ = (&Season{}).CanRegister(License{}, 0)
+	assert.True(t, ok)
+}
@@ -0,0 +1,219 @@
+// Package analysis computes race gaps, stints and pace from the lap data of
+// a subsession.
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/popmonkey/irdata"
+)
+
+// laps slower than the median clean lap by more than this fraction are
+// rejected by default
+const defaultOutlierThreshold = 0.05
+
+// Gap is where a participant was relative to the leader on completing a lap
+type Gap struct {
+	Lap int
+
+	// ToLeader is the time since the leader completed the same lap
+	ToLeader time.Duration
+
+	// LapsDown counts the laps the leader had completed beyond this one
+	LapsDown int
+}
+
+// RaceGaps returns the gaps of every participant to the leader on each lap
+// they completed, ordered by lap.  The leader of a lap is whoever completed
+// it first so pass the laps of a single class to get class gaps.  Laps
+// without a session time are skipped, a car that retired simply has no
+// further gaps.
+func RaceGaps(laps map[irdata.ParticipantID][]irdata.Lap) map[irdata.ParticipantID][]Gap {
+	// when each lap was first completed
+	leader := make(map[int]time.Duration)
+
+	for _, participantLaps := range laps {
+		for _, lap := range participantLaps {
+			at, ok := lap.CompletedAt()
+			if !ok {
+				continue
+			}
+
+			if first, seen := leader[lap.LapNumber]; !seen || at < first {
+				leader[lap.LapNumber] = at
+			}
+		}
+	}
+
+	gaps := make(map[irdata.ParticipantID][]Gap)
+
+	for id, participantLaps := range laps {
+		var participantGaps []Gap
+
+		for _, lap := range sortedLaps(participantLaps) {
+			at, ok := lap.CompletedAt()
+			if !ok {
+				continue
+			}
+
+			completed := lap.LapNumber
+
+			for n, first := range leader {
+				if n > completed && first <= at {
+					completed = n
+				}
+			}
+
+			participantGaps = append(participantGaps, Gap{
+				Lap:      lap.LapNumber,
+				ToLeader: at - leader[lap.LapNumber],
+				LapsDown: completed - lap.LapNumber,
+			})
+		}
+
+		gaps[id] = participantGaps
+	}
+
+	return gaps
+}
+
+// ByClass groups laps by car class
+func ByClass(laps map[irdata.ParticipantID][]irdata.Lap) map[int64]map[irdata.ParticipantID][]irdata.Lap {
+	classes := make(map[int64]map[irdata.ParticipantID][]irdata.Lap)
+
+	for id, participantLaps := range laps {
+		if len(participantLaps) == 0 {
+			continue
+		}
+
+		classID := participantLaps[0].CarClassID
+
+		if classes[classID] == nil {
+			classes[classID] = make(map[irdata.ParticipantID][]irdata.Lap)
+		}
+
+		classes[classID][id] = participantLaps
+	}
+
+	return classes
+}
+
+// Stint is a run of laps between pit stops
+type Stint struct {
+	Laps []irdata.Lap
+
+	// Pitted is false for a stint that ended with the race or a retirement
+	Pitted bool
+}
+
+// Stints splits the laps of a participant on the laps they pitted on, the
+// in lap being the last of its stint
+func Stints(laps []irdata.Lap) []Stint {
+	var stints []Stint
+
+	var current Stint
+
+	for _, lap := range sortedLaps(laps) {
+		current.Laps = append(current.Laps, lap)
+
+		if lap.HasEvent(irdata.LapEventPitted) {
+			current.Pitted = true
+			stints = append(stints, current)
+			current = Stint{}
+		}
+	}
+
+	if len(current.Laps) > 0 {
+		stints = append(stints, current)
+	}
+
+	return stints
+}
+
+// PaceOptions tune FuelAgnosticPace
+type PaceOptions struct {
+	// OutlierThreshold rejects laps slower than the median clean lap by
+	// more than this fraction (0.05 when 0).  Negative keeps every lap.
+	OutlierThreshold float64
+}
+
+// Pace is an average lap time
+type Pace struct {
+	Average time.Duration
+
+	// Laps is how many laps the average is over, 0 if none qualified
+	Laps int
+}
+
+// FuelAgnosticPace returns the average green flag pace of a participant,
+// leaving out what pit strategy and incidents distort: the start (lap 0 and
+// the standing start lap 1), in and out laps, laps without a time and laps
+// slower than the outlier threshold.
+func FuelAgnosticPace(laps []irdata.Lap, opts PaceOptions) Pace {
+	threshold := opts.OutlierThreshold
+	if threshold == 0 {
+		threshold = defaultOutlierThreshold
+	}
+
+	var times []time.Duration
+
+	pitted := false
+
+	for _, lap := range sortedLaps(laps) {
+		outLap := pitted
+		pitted = lap.HasEvent(irdata.LapEventPitted)
+
+		lapTime, ok := lap.Time()
+		if !ok || lap.LapNumber <= 1 || outLap || pitted {
+			continue
+		}
+
+		times = append(times, lapTime)
+	}
+
+	if len(times) == 0 {
+		return Pace{}
+	}
+
+	limit := time.Duration(-1)
+
+	if threshold > 0 {
+		limit = time.Duration(float64(median(times)) * (1 + threshold))
+	}
+
+	var total time.Duration
+
+	count := 0
+
+	for _, lapTime := range times {
+		if limit >= 0 && lapTime > limit {
+			continue
+		}
+
+		total += lapTime
+		count++
+	}
+
+	return Pace{Average: total / time.Duration(count), Laps: count}
+}
+
+func median(times []time.Duration) time.Duration {
+	sorted := append([]time.Duration{}, times...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+	mid := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return sorted[mid]
+}
+
+func sortedLaps(laps []irdata.Lap) []irdata.Lap {
+	sorted := append([]irdata.Lap{}, laps...)
+	sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].LapNumber < sorted[b].LapNumber })
+
+	return sorted
+}
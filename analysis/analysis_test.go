@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/popmonkey/irdata"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	alice irdata.ParticipantID = 1 + iota
+	bob
+	carla
+	dan
+)
+
+// a six lap race of two GT3s and two MX5s: alice pits on lap 3 and spins on
+// lap 2, carla gets lapped and has a lap without a time, dan retires after
+// lap 3
+func raceFixture(t *testing.T) map[irdata.ParticipantID][]irdata.Lap {
+	data, err := os.ReadFile(filepath.Join("testdata", "multiclass_race.json"))
+	assert.NoError(t, err)
+
+	var rows []irdata.Lap
+
+	assert.NoError(t, json.Unmarshal(data, &rows))
+
+	laps := make(map[irdata.ParticipantID][]irdata.Lap)
+
+	for _, row := range rows {
+		laps[irdata.ParticipantID(row.CustID)] = append(laps[irdata.ParticipantID(row.CustID)], row)
+	}
+
+	return laps
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func TestRaceGaps(t *testing.T) {
+	gaps := RaceGaps(raceFixture(t))
+
+	assert.Equal(t, []Gap{
+		{0, 0, 0}, {1, 0, 0}, {2, seconds(4.5), 0}, {3, seconds(12.5), 0},
+		{4, seconds(30.5), 0}, {5, seconds(28.5), 0}, {6, seconds(27.5), 0},
+	}, gaps[alice])
+
+	assert.Equal(t, []Gap{
+		{0, seconds(0.5), 0}, {1, seconds(2.5), 0}, {2, 0, 0}, {3, 0, 0}, {4, 0, 0}, {5, 0, 0}, {6, 0, 0},
+	}, gaps[bob])
+
+	assert.Equal(t, []Gap{
+		{0, seconds(1), 0}, {1, seconds(31), 0}, {2, seconds(56.5), 0}, {3, seconds(84.5), 0},
+		{4, seconds(112.5), 1}, {5, seconds(140.5), 1},
+	}, gaps[carla])
+
+	assert.Equal(t, []Gap{
+		{0, seconds(1.5), 0}, {1, seconds(16.5), 0}, {2, seconds(26), 0}, {3, seconds(40), 0},
+	}, gaps[dan])
+}
+
+func TestRaceGapsByClass(t *testing.T) {
+	classes := ByClass(raceFixture(t))
+	assert.Len(t, classes, 2)
+
+	gaps := RaceGaps(classes[20])
+
+	assert.Len(t, gaps, 2)
+	assert.Equal(t, []Gap{
+		{0, 0, 0}, {1, seconds(14.5), 0}, {2, seconds(30.5), 0}, {3, seconds(44.5), 0},
+		{4, 0, 0}, {5, 0, 0},
+	}, gaps[carla])
+	assert.Equal(t, Gap{3, 0, 0}, gaps[dan][3])
+}
+
+func TestStints(t *testing.T) {
+	laps := raceFixture(t)
+
+	stints := Stints(laps[alice])
+
+	assert.Len(t, stints, 2)
+	assert.True(t, stints[0].Pitted)
+	assert.Len(t, stints[0].Laps, 4)
+	assert.Equal(t, 3, stints[0].Laps[3].LapNumber)
+	assert.False(t, stints[1].Pitted)
+	assert.Equal(t, 4, stints[1].Laps[0].LapNumber)
+	assert.Len(t, stints[1].Laps, 3)
+
+	// retired mid stint
+	stints = Stints(laps[dan])
+
+	assert.Len(t, stints, 1)
+	assert.False(t, stints[0].Pitted)
+	assert.Len(t, stints[0].Laps, 4)
+
+	assert.Empty(t, Stints(nil))
+}
+
+func TestFuelAgnosticPace(t *testing.T) {
+	laps := raceFixture(t)
+
+	// without the start, the in and out laps and the spin
+	assert.Equal(t, Pace{Average: seconds(181) / 2, Laps: 2}, FuelAgnosticPace(laps[alice], PaceOptions{}))
+
+	// keeping the spin
+	assert.Equal(t, Pace{Average: seconds(280) / 3, Laps: 3}, FuelAgnosticPace(laps[alice], PaceOptions{OutlierThreshold: -1}))
+
+	// the lap without a time is left out
+	assert.Equal(t, Pace{Average: seconds(120), Laps: 3}, FuelAgnosticPace(laps[carla], PaceOptions{}))
+
+	assert.Equal(t, Pace{Average: seconds(105), Laps: 2}, FuelAgnosticPace(laps[dan], PaceOptions{}))
+
+	assert.Equal(t, Pace{}, FuelAgnosticPace(laps[dan][:2], PaceOptions{}))
+}
+
+func TestFuelAgnosticPaceStandingStart(t *testing.T) {
+	laps := []irdata.Lap{
+		{LapNumber: 0, LapTime: -1},
+		{LapNumber: 1, LapTime: 1000000},
+		{LapNumber: 2, LapTime: 900000},
+		{LapNumber: 3, LapTime: 900000},
+	}
+
+	// the slow standing start lap doesn't count, even keeping every lap
+	assert.Equal(t, Pace{Average: seconds(90), Laps: 2}, FuelAgnosticPace(laps, PaceOptions{OutlierThreshold: -1}))
+}
@@ -0,0 +1,389 @@
+package irdata
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// archiveIndexFile is the index of an archive directory, one JSON
+// ArchiveEntry per line in the order the payloads were fetched
+const archiveIndexFile = "index.jsonl"
+
+type archiveT struct {
+	mu     sync.Mutex
+	dir    string
+	filter func(uri string) bool
+
+	// size is what the files in dir add up to, counted when enabling the
+	// archive and kept up to date with every write
+	maxBytes int64
+	size     int64
+	full     bool
+	fullHook func(entry ArchiveEntry)
+}
+
+// ArchiveEntry is a payload in the archive
+type ArchiveEntry struct {
+	URI     string    `json:"uri"`
+	Path    string    `json:"path"`
+	SHA256  string    `json:"sha256"`
+	Size    int       `json:"size"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// WithArchiveMaxBytes caps the size of the archive directory at n bytes,
+// counting the payloads and the index.  Once a payload would take it over
+// the cap it's not archived: the archive is never pruned as that would lose
+// what can't be fetched again.
+func WithArchiveMaxBytes(n int64) Option {
+	return func(i *Irdata) {
+		i.archive.maxBytes = n
+	}
+}
+
+// WithArchiveFullHook calls hook with the entry of every payload refused
+// because the archive is full, e.g. to count them in a metrics system
+func WithArchiveFullHook(hook func(entry ArchiveEntry)) Option {
+	return func(i *Irdata) {
+		i.archive.fullHook = hook
+	}
+}
+
+// EnableArchive keeps a copy of every payload fetched from the API (after
+// following the link and merging the chunks) whose uri filter accepts in
+// dir, nil archives everything.  Payloads are stored by endpoint, e.g.
+// dir/data/results/get/<sha256>.json, and listed in dir/index.jsonl.
+//
+// The archive is never read when fetching and doesn't expire, it's there
+// to reprocess what was fetched with ReadArchive.  Failing to archive is
+// logged and doesn't affect the result of the fetch, nor does the archive
+// being full (see WithArchiveMaxBytes).
+func (i *Irdata) EnableArchive(dir string, filter func(uri string) bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		return err
+	}
+
+	i.logger.WithFields(log.Fields{"dir": dir, "size": size}).Info("Enabling archive")
+
+	i.archive.mu.Lock()
+	defer i.archive.mu.Unlock()
+
+	i.archive.dir = dir
+	i.archive.filter = filter
+	i.archive.size = size
+	i.archive.full = false
+
+	return nil
+}
+
+// ArchiveSize returns how many bytes the enabled archive takes up
+func (i *Irdata) ArchiveSize() int64 {
+	i.archive.mu.Lock()
+	defer i.archive.mu.Unlock()
+
+	return i.archive.size
+}
+
+// dirSize adds up the sizes of the regular files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+
+		return nil
+	})
+
+	return size, err
+}
+
+// archivePayload writes p to the archive if it's enabled for uri
+func (i *Irdata) archivePayload(uri string, p *payload) {
+	dir, ok := i.archiveDir(uri)
+	if !ok {
+		return
+	}
+
+	data, err := p.assemble()
+	if err == nil {
+		err = i.archiveData(dir, uri, data)
+	}
+
+	if errors.Is(err, ErrArchiveFull) {
+		i.archiveFull(uri, len(data))
+		return
+	}
+
+	if err != nil {
+		i.logger.WithFields(log.Fields{
+			"uri": uri,
+			"err": err,
+		}).Warn("Failed to archive payload")
+	}
+}
+
+// archiveDir returns the directory payloads fetched for uri are archived
+// in, if they are
+func (i *Irdata) archiveDir(uri string) (string, bool) {
+	i.archive.mu.Lock()
+	dir, filter := i.archive.dir, i.archive.filter
+	i.archive.mu.Unlock()
+
+	if dir == "" || (filter != nil && !filter(uri)) {
+		return "", false
+	}
+
+	return dir, true
+}
+
+// archiving reports whether payloads fetched for uri are archived
+func (i *Irdata) archiving(uri string) bool {
+	_, ok := i.archiveDir(uri)
+
+	return ok
+}
+
+// archiveFull warns about the first payload refused, only debug logs the
+// rest and calls the hook with each
+func (i *Irdata) archiveFull(uri string, size int) {
+	i.archive.mu.Lock()
+	first := !i.archive.full
+	i.archive.full = true
+	hook := i.archive.fullHook
+	i.archive.mu.Unlock()
+
+	fields := log.Fields{"uri": uri, "size": size}
+
+	if first {
+		i.logger.WithFields(fields).Warn("Archive is full, no longer archiving payloads")
+	} else {
+		i.logger.WithFields(fields).Debug("Archive is full, not archiving payload")
+	}
+
+	if hook != nil {
+		entry := ArchiveEntry{URI: uri, Size: size, Fetched: i.clock.Now().UTC()}
+
+		i.callHook(func() { hook(entry) })
+	}
+}
+
+func (i *Irdata) archiveData(dir string, uri string, data []byte) error {
+	endpoint, err := archiveEndpoint(uri)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+
+	entry := ArchiveEntry{
+		URI:     uri,
+		Path:    path.Join(endpoint, hex.EncodeToString(sum[:])+".json"),
+		SHA256:  hex.EncodeToString(sum[:]),
+		Size:    len(data),
+		Fetched: i.clock.Now().UTC(),
+	}
+
+	fileName := filepath.Join(dir, filepath.FromSlash(entry.Path))
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	// held while writing so concurrent fetches can't both take the last of
+	// the space
+	i.archive.mu.Lock()
+	defer i.archive.mu.Unlock()
+
+	// the same content fetched again only gets another index entry
+	exists := true
+
+	if _, err := os.Stat(fileName); errors.Is(err, os.ErrNotExist) {
+		exists = false
+	} else if err != nil {
+		return err
+	}
+
+	needed := int64(len(line))
+	if !exists {
+		needed += int64(len(data))
+	}
+
+	if i.archive.maxBytes > 0 && i.archive.size+needed > i.archive.maxBytes {
+		return ErrArchiveFull
+	}
+
+	if !exists {
+		if err := writeFileAtomic(fileName, data); err != nil {
+			return err
+		}
+
+		i.archive.size += int64(len(data))
+	}
+
+	index, err := os.OpenFile(filepath.Join(dir, archiveIndexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	// a single write so a crash leaves at worst a partial last line, which
+	// ReadArchive skips
+	if _, err := index.Write(line); err != nil {
+		index.Close()
+		return err
+	}
+
+	i.archive.size += int64(len(line))
+
+	return index.Close()
+}
+
+// archiveEndpoint is the directory, relative to the archive, the payloads
+// of uri are kept in
+func archiveEndpoint(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	// cleaning it as an absolute path drops any .. that would escape
+	endpoint := strings.TrimPrefix(path.Clean("/"+u.Path), "/")
+	if endpoint == "" {
+		return "", errors.New("uri has no path to archive under")
+	}
+
+	return endpoint, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to fileName and
+// renames it into place so readers never see a partial file
+func writeFileAtomic(fileName string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), ".archive-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), fileName); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveEntries returns the index entries of the archive in dir whose uri
+// starts with prefix (e.g. "/data/results/get"), oldest first
+func ArchiveEntries(dir string, prefix string) ([]ArchiveEntry, error) {
+	index, err := os.Open(filepath.Join(dir, archiveIndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer index.Close()
+
+	var entries []ArchiveEntry
+
+	scanner := bufio.NewScanner(index)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var entry ArchiveEntry
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			packageLogger.WithFields(log.Fields{"err": err}).Warn("Skipping unreadable archive index line")
+			continue
+		}
+
+		if strings.HasPrefix(entry.URI, prefix) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ReadArchive calls fn with every payload archived in dir whose uri starts
+// with prefix, oldest first.  A uri fetched several times is passed once per
+// fetch.
+//
+// Returning ErrStopIteration from fn stops early without an error, any
+// other error is returned as is.
+func ReadArchive(dir string, prefix string, fn func(entry ArchiveEntry, data []byte) error) error {
+	entries, err := ArchiveEntries(dir, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return err
+		}
+
+		if err := fn(entry, data); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", `{"subsession_id":1}`)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2}]`))
+	m.handleJSON("/data/member/info", testMemberInfo)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	dir := t.TempDir()
+
+	assert.NoError(t, api.EnableArchive(dir, func(uri string) bool {
+		return strings.HasPrefix(uri, "/data/results/")
+	}))
+
+	for n := 0; n < 2; n++ {
+		_, err := api.Get("/data/results/get?subsession_id=1")
+		assert.NoError(t, err)
+
+		clock.advance(time.Minute)
+	}
+
+	// only what's fetched is archived, not cache hits
+	for n := 0; n < 2; n++ {
+		_, err := api.GetWithCache("/data/results/search_series?season_id=1", time.Hour)
+		assert.NoError(t, err)
+	}
+
+	_, err := api.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	entries, err := ArchiveEntries(dir, "/data/results/")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	var uris []string
+	var payloads []string
+
+	assert.NoError(t, ReadArchive(dir, "/data/results/get", func(entry ArchiveEntry, data []byte) error {
+		uris = append(uris, entry.URI)
+		payloads = append(payloads, string(data))
+		return nil
+	}))
+
+	assert.Equal(t, []string{"/data/results/get?subsession_id=1", "/data/results/get?subsession_id=1"}, uris)
+	assert.Equal(t, []string{`{"subsession_id":1}`, `{"subsession_id":1}`}, payloads)
+
+	// the same content is stored once
+	assert.Equal(t, entries[0].Path, entries[1].Path)
+	assert.Equal(t, time.Minute, entries[1].Fetched.Sub(entries[0].Fetched))
+
+	files, err := os.ReadDir(filepath.Join(dir, "data", "results", "get"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// chunked results are archived merged
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entries[2].Path)))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1},{"id":2}]`, string(data))
+	assert.Equal(t, len(data), entries[2].Size)
+
+	_, err = os.Stat(filepath.Join(dir, "data", "member"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestArchiveFailureKeepsResult(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/results/get", `{"subsession_id":1}`)
+
+	api := m.openAuthed(t)
+
+	dir := t.TempDir()
+	assert.NoError(t, api.EnableArchive(dir, nil))
+
+	// a file where the endpoint directory should be
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "data"), nil, 0644))
+
+	data, err := api.Get("/data/results/get?subsession_id=1")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"subsession_id":1}`, string(data))
+
+	entries, err := ArchiveEntries(dir, "")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestArchiveMaxBytes(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"subsession_id":%s,"padding":"%s"}`, r.URL.Query().Get("subsession_id"), strings.Repeat("x", 200))
+	})
+
+	var refused []ArchiveEntry
+
+	api := m.openAuthed(t, WithArchiveMaxBytes(1400), WithArchiveFullHook(func(entry ArchiveEntry) {
+		refused = append(refused, entry)
+	}))
+
+	dir := t.TempDir()
+
+	// what's already there counts
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), make([]byte, 300), 0644))
+	assert.NoError(t, api.EnableArchive(dir, nil))
+	assert.Equal(t, int64(300), api.ArchiveSize())
+
+	for id := 1; id <= 4; id++ {
+		data, err := api.Get(fmt.Sprintf("/data/results/get?subsession_id=%d", id))
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "padding")
+	}
+
+	entries, err := ArchiveEntries(dir, "")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	api.flushHooks(false)
+	assert.Len(t, refused, 2)
+	assert.Equal(t, "/data/results/get?subsession_id=3", refused[0].URI)
+	assert.Greater(t, refused[0].Size, 200)
+
+	size, err := dirSize(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, size, api.ArchiveSize())
+	assert.LessOrEqual(t, size, int64(1400))
+
+	// nothing was deleted to make room
+	_, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.NoError(t, err)
+}
+
+func TestArchiveMaxBytesConcurrent(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"subsession_id":%s,"padding":"%s"}`, r.URL.Query().Get("subsession_id"), strings.Repeat("x", 100))
+	})
+
+	var refused int32
+
+	api := m.openAuthed(t, WithArchiveMaxBytes(4096), WithArchiveFullHook(func(ArchiveEntry) {
+		atomic.AddInt32(&refused, 1)
+	}))
+
+	dir := t.TempDir()
+	assert.NoError(t, api.EnableArchive(dir, nil))
+
+	var wg sync.WaitGroup
+
+	for id := 1; id <= 40; id++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			_, err := api.Get(fmt.Sprintf("/data/results/get?subsession_id=%d", id))
+			assert.NoError(t, err)
+		}(id)
+	}
+
+	wg.Wait()
+
+	entries, err := ArchiveEntries(dir, "")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	api.flushHooks(false)
+	assert.Equal(t, 40, len(entries)+int(atomic.LoadInt32(&refused)))
+
+	size, err := dirSize(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, size, api.ArchiveSize())
+	assert.LessOrEqual(t, size, int64(4096))
+}
+
+func TestReadArchive(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/results/get", `{"subsession_id":1}`)
+	m.handleJSON("/data/results/lap_data", `{"laps":[]}`)
+
+	api := m.openAuthed(t)
+
+	dir := t.TempDir()
+	assert.NoError(t, api.EnableArchive(dir, nil))
+
+	for _, uri := range []string{"/data/results/get?subsession_id=1", "/data/results/lap_data?subsession_id=1", "/data/results/get?subsession_id=2"} {
+		_, err := api.Get(uri)
+		assert.NoError(t, err)
+	}
+
+	// left behind by a crash mid write
+	index, err := os.OpenFile(filepath.Join(dir, archiveIndexFile), os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	index.Write([]byte(`{"uri":"/data/resu`))
+	index.Close()
+
+	var uris []string
+
+	assert.NoError(t, ReadArchive(dir, "/data/results/", func(entry ArchiveEntry, data []byte) error {
+		uris = append(uris, entry.URI)
+
+		if len(uris) == 2 {
+			return ErrStopIteration
+		}
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"/data/results/get?subsession_id=1", "/data/results/lap_data?subsession_id=1"}, uris)
+
+	entries, err := ArchiveEntries(dir, "")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	entries, err = ArchiveEntries(t.TempDir(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestArchiveEndpoint(t *testing.T) {
+	endpoint, err := archiveEndpoint("/data/results/get?subsession_id=1")
+	assert.NoError(t, err)
+	assert.Equal(t, "data/results/get", endpoint)
+
+	endpoint, err = archiveEndpoint("/data/../../../etc/passwd")
+	assert.NoError(t, err)
+	assert.Equal(t, "etc/passwd", endpoint)
+
+	_, err = archiveEndpoint("?x=1")
+	assert.Error(t, err)
+}
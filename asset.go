@@ -0,0 +1,59 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// static assets rarely change
+const assetTTL = 24 * time.Hour
+
+// getAsset downloads a static asset such as a track map layer.  Assets are
+// public so the session cookies aren't sent along, and they are cached when
+// the cache is enabled.
+func (i *Irdata) getAsset(ctx context.Context, assetURL string) ([]byte, error) {
+	key := "irdata.asset." + assetURL
+
+	if i.cache != nil {
+		p, err := i.getCachedPayload(ctx, key)
+		if err != nil {
+			if err := i.cacheFailed("read", assetURL, err); err != nil {
+				return nil, err
+			}
+		} else if p != nil {
+			return p.data, nil
+		}
+	}
+
+	i.logger.WithFields(log.Fields{"assetURL": assetURL}).Info("Fetching asset")
+
+	resp, err := i.retryingDoWith(ctx, &i.assetClient, http.MethodGet, assetURL, nil, nil, retryServerErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := readResponse(assetURL, resp, i.responseLimit(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching asset %s failed: %s", assetURL, resp.Status)
+	}
+
+	if i.cache != nil {
+		if err := i.setCachedPayload(ctx, key, &payload{data: data}, assetTTL, ""); err != nil {
+			if err := i.cacheFailed("write", assetURL, err); err != nil {
+				return data, err
+			}
+		}
+	}
+
+	return data, nil
+}
@@ -2,14 +2,17 @@ package irdata
 
 import (
 	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/gob"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strings"
@@ -18,45 +21,47 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const loginURL = "https://members-ng.iracing.com/auth"
-const testUrl = "https://members-ng.iracing.com/data/constants/event_types"
+const loginURI = "/auth"
+const testURI = "/data/constants/event_types"
 
 type authDataT struct {
-	Username        string
-	EncodedPassword string
+	Username        string `json:"username"`
+	EncodedPassword string `json:"encoded_password"`
 }
 
 var additionalContext = []byte("irdata.auth")
 
 // AuthWithCredsFromFile loads the username and password from a file
-// at authFilename and encrypted with the key in keyFilename.
+// at authFilename and encrypted with the key in keyFilename.  If the file
+// holds profiles the DefaultProfile is used.
 func (i *Irdata) AuthWithCredsFromFile(keyFilename string, authFilename string) error {
-	authData := readCreds(keyFilename, authFilename)
+	authData, err := readCredsFile(keyFilename, authFilename)
+	if err != nil {
+		return i.fail(err)
+	}
 
 	return i.auth(authData)
 }
 
 // AuthWithProvideCreds calls the provided function for the username and password
 func (i *Irdata) AuthWithProvideCreds(authSource CredsProvider) error {
-	log.WithFields(log.Fields{"authSource": authSource}).Debug("Calling CredsProvider")
+	i.logger.WithFields(log.Fields{"authSource": fmt.Sprintf("%T", authSource)}).Debug("Calling CredsProvider")
 
 	username, password := authSource.GetCreds()
 
-	var authData authDataT
-
-	authData.Username = string(username)
-	authData.EncodedPassword = encodePassword(username, password)
-
-	return i.auth(authData)
+	return i.authenticate(&credentialsT{
+		username:        string(username),
+		encodedPassword: encodedPassword(username, password),
+	})
 }
 
 // SaveProvidedCredsToFile calls the provided function for the
 // username and password and then saves these credentials to authFilename
-// using the key within the keyFilename
+// using the key within the keyFilename, as its DefaultProfile
 //
 // This function will panic out on errors
 func SaveProvidedCredsToFile(keyFilename string, authFilename string, authSource CredsProvider) {
-	log.WithFields(log.Fields{"authSource": authSource}).Debug("Calling CredsProvider")
+	packageLogger.WithFields(log.Fields{"authSource": fmt.Sprintf("%T", authSource)}).Debug("Calling CredsProvider")
 
 	username, password := authSource.GetCreds()
 
@@ -69,186 +74,289 @@ func SaveProvidedCredsToFile(keyFilename string, authFilename string, authSource
 }
 
 func writeCreds(keyFilename string, authFilename string, authData authDataT) {
-	key := getKey(keyFilename)
-
-	block, err := aes.NewCipher(key)
-
-	// not a defer because we want to do this right away
-	shred(&key)
+	if err := writeCredsFile(keyFilename, authFilename, authData); err != nil {
+		packageLogger.Panic(err)
+	}
+}
 
+func writeCredsFile(keyFilename string, authFilename string, authData authDataT) error {
+	aesgcm, err := credsCipher(keyFilename)
 	if err != nil {
 		if errors.Is(err, aes.KeySizeError(0)) {
-			log.Panic(errors.New("key must be 16, 24, or 32 bytes long"))
-		} else {
-			log.Panic(err)
+			return errors.New("key must be 16, 24, or 32 bytes long")
 		}
-	}
 
-	aesgcm, err := cipher.NewGCM(block)
-	if err != nil {
-		log.Panic(err)
+		return err
 	}
 
-	nonce, err := makeNonce(aesgcm)
+	return writeProfiles(aesgcm, authFilename, &credsFileT{
+		profiles: map[string]authDataT{DefaultProfile: authData},
+	})
+}
+
+func readCreds(keyFilename string, authFilename string) authDataT {
+	authData, err := readCredsFile(keyFilename, authFilename)
 	if err != nil {
-		log.Panic(err)
+		packageLogger.Panic(err)
 	}
 
-	buf := bytes.Buffer{}
+	return authData
+}
 
-	enc := gob.NewEncoder(&buf)
+func readCredsFile(keyFilename string, authFilename string) (authDataT, error) {
+	aesgcm, err := credsCipher(keyFilename)
+	if err != nil {
+		return authDataT{}, err
+	}
 
-	err = enc.Encode(authData)
+	creds, err := readProfiles(aesgcm, authFilename, false)
 	if err != nil {
-		log.Panic(err)
+		return authDataT{}, err
 	}
 
-	data := aesgcm.Seal(nonce, nonce, buf.Bytes(), additionalContext)
+	authData, ok := creds.profiles[DefaultProfile]
+	if !ok {
+		return authDataT{}, fmt.Errorf("%s has no %s profile", authFilename, DefaultProfile)
+	}
 
-	base64data := base64.StdEncoding.Strict().EncodeToString(data)
+	upgradeCreds(aesgcm, authFilename, creds)
 
-	if err := os.WriteFile(authFilename, []byte(base64data), os.ModePerm); err != nil {
-		log.Panic(err)
-	}
+	return authData, nil
 }
 
-func readCreds(keyFilename string, authFilename string) authDataT {
-	key := getKey(keyFilename)
+// CredsInfo describes a creds file, see InspectCredsFile
+type CredsInfo struct {
+	// Username is that of the DefaultProfile, "" if the file has none
+	Username string
 
-	block, err := aes.NewCipher(key)
+	// Profiles are the names of the profiles in the file, in order
+	Profiles []string
 
-	// not a defer because we want to do this right away
-	shred(&key)
+	// Version is that of the file format: 1 for the single creds files
+	// older versions of SaveProvidedCredsToFile wrote, 2 and up for files
+	// holding profiles.  Reading a file to log in upgrades it to the
+	// current version.
+	Version int
 
+	// Written is when the file was written, zero for the formats before
+	// version 3 which don't record it
+	Written time.Time
+}
+
+// InspectCredsFile decrypts the creds file at authFilename with the key in
+// keyFilename and checks it's well formed without logging in, see
+// ValidateCreds to check the credentials work.  No password is returned.
+//
+// ErrWrongKey is returned for a file encrypted with another key,
+// ErrCredsCorrupted for a file that doesn't decode and a *CredsVersionError
+// for a format this version doesn't know.  Files before version 3 carry no
+// key check so a corrupted one reports ErrWrongKey too.
+func InspectCredsFile(keyFilename string, authFilename string) (CredsInfo, error) {
+	aesgcm, err := credsCipher(keyFilename)
 	if err != nil {
-		log.Panic(err)
+		return CredsInfo{}, err
 	}
 
-	aesgcm, err := cipher.NewGCM(block)
-
+	creds, err := readProfiles(aesgcm, authFilename, false)
 	if err != nil {
-		log.Panic(err)
+		return CredsInfo{}, err
 	}
 
-	var authData authDataT
+	info := CredsInfo{
+		Username: creds.profiles[DefaultProfile].Username,
+		Profiles: creds.names,
+		Version:  creds.version,
+		Written:  creds.written,
+	}
 
-	base64data, err := os.ReadFile(authFilename)
-	if err != nil {
-		log.Panic(err)
+	for _, authData := range creds.profiles {
+		if authData.Username == "" || authData.EncodedPassword == "" {
+			return CredsInfo{}, fmt.Errorf("creds file %s: %w: a profile lacks its username or password", authFilename, ErrCredsCorrupted)
+		}
 	}
 
-	data, err := base64.StdEncoding.Strict().DecodeString(string(base64data))
-	if err != nil {
-		log.Panic(err)
+	return info, nil
+}
+
+// auth client
+func (i *Irdata) auth(authData authDataT) error {
+	return i.authenticate(newCredentials(authData))
+}
+
+// authenticate logs in with creds, which are zeroed afterwards unless they
+// are retained
+func (i *Irdata) authenticate(creds *credentialsT) error {
+	if i.isAuthed && !i.sessionExpired() && !i.bearerSession() {
+		creds.zero()
+
+		return nil
 	}
 
-	authGob, err := aesgcm.Open(nil, data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():], additionalContext)
-	if err != nil {
-		log.Panic(err)
+	if len(creds.encodedPassword) == 0 {
+		return errors.New("must provide credentials before calling")
 	}
 
-	buf := bytes.NewReader(authGob)
+	i.startSession()
 
-	dec := gob.NewDecoder(buf)
+	if err := i.login(i.ctx, creds); err != nil {
+		creds.zero()
 
-	err = dec.Decode(&authData)
-	if err != nil {
-		log.Panic(err)
+		return i.failLogin(err)
 	}
 
-	return authData
+	i.isAuthed = true
+
+	// a fresh login may be for a different account
+	i.forgetMe()
+	i.setAccount(creds.username)
+
+	i.keepCredentials(creds)
+
+	return nil
 }
 
-// auth client
-func (i *Irdata) auth(authData authDataT) error {
-	if i.isAuthed {
-		return nil
-	}
+// ValidateCreds logs in with the credentials from authSource using a
+// temporary client and then discards the session.  It returns nil if the
+// credentials work or one of the Err* auth errors explaining why not.
+// Each request is sent once, when iRacing is rate limiting it fails with
+// ErrRateLimited straight away rather than waiting.
+//
+// The options are those accepted by Open.
+func ValidateCreds(ctx context.Context, authSource CredsProvider, opts ...Option) error {
+	username, password := authSource.GetCreds()
 
-	if authData.EncodedPassword == "" {
-		return errors.New("must provide credentials before calling")
+	creds := &credentialsT{
+		username:        string(username),
+		encodedPassword: encodedPassword(username, password),
 	}
+	defer creds.zero()
 
-	log.Info("Authenticating")
+	temp := Open(ctx, append([]Option{WithStrictErrors()}, opts...)...)
+	defer temp.Logout()
 
-	retries := 5
+	temp.SetAuthVerification(VerifyEveryLogin)
 
-	var err error
-	var resp *http.Response
+	return temp.login(withSingleAttempt(ctx), creds)
+}
 
-	for retries > 0 {
-		resp, err = i.httpClient.Post(loginURL, "application/json",
-			strings.NewReader(
-				fmt.Sprintf("{\"email\": \"%s\" ,\"password\": \"%s\"}", authData.Username, authData.EncodedPassword),
-			),
-		)
+// login posts the credentials and verifies the resulting session.  When
+// the requests were retried the error is a *RetryReport.
+func (i *Irdata) login(ctx context.Context, creds *credentialsT) error {
+	ctx, transcript := i.withRetryTranscript(withoutHedging(ctx))
 
-		if resp.StatusCode < 500 {
-			break
-		}
+	return transcript.report(i.postLogin(ctx, creds), i.clock.Now())
+}
 
-		log.WithFields(log.Fields{"resp.StatusCode": resp.StatusCode}).Debug(" *** Retrying Authentication due to error")
+// postLogin is login without the RetryReport
+func (i *Irdata) postLogin(ctx context.Context, creds *credentialsT) error {
+	i.logger.Info("Authenticating")
+
+	loginURL, err := i.resolveURL(loginURI)
+	if err != nil {
+		return err
+	}
 
-		retries--
+	body := creds.loginBody()
 
-		time.Sleep(time.Duration((6-retries)*5) * time.Second)
+	resp, err := i.retryingDo(ctx, http.MethodPost, loginURL.String(), body, http.Header{
+		"Content-Type": []string{"application/json"},
+	}, retryServerErrors)
+
+	zero(body)
+
+	if err != nil {
+		return &unreachableError{err: err}
 	}
 
+	respData, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
 
-	if resp.StatusCode != 200 {
-		log.WithFields(log.Fields{
+	if err := loginError(resp, respData); err != nil {
+		i.logger.WithFields(log.Fields{
 			"resp.Status":     resp.Status,
 			"resp.StatusCode": resp.StatusCode,
+			"err":             err,
 		}).Info("Failed to authenticate")
 
-		return errors.New("unexpected auth failure, try debug")
+		return err
 	}
 
 	// test we are really auth'ed
-	resp, err = i.retryingGet(testUrl)
-	if err != nil {
-		log.Panic(err)
+	if err := i.verifySession(ctx); err != nil {
+		return err
 	}
 
-	if resp.StatusCode != 200 {
-		if resp.StatusCode == 401 {
-			return errors.New("login failed, check creds")
-		} else {
-			log.WithFields(log.Fields{
-				"resp.Status":     resp.Status,
-				"resp.StatusCode": resp.StatusCode,
-				"testUrl":         testUrl,
-			}).Info("Unexpected status")
+	i.logger.Info("Login succeeded")
 
-			return errors.New("unexpected auth failure, try debug")
-		}
+	return nil
+}
+
+// loginError maps the response of the login endpoint to an auth error
+func loginError(resp *http.Response, data []byte) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return ErrBadCredentials
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusServiceUnavailable:
+		return ErrMaintenance
+	default:
+		return ErrAuthFailed
 	}
 
-	log.Info("Login succeeded")
+	var authResp struct {
+		AuthCode             json.RawMessage `json:"authcode"`
+		VerificationRequired bool            `json:"verificationRequired"`
+		Message              string          `json:"message"`
+	}
 
-	i.isAuthed = true
+	if err := json.Unmarshal(data, &authResp); err != nil {
+		// nothing to go on, let the verification probe decide
+		return nil
+	}
+
+	if authResp.VerificationRequired {
+		return ErrVerificationRequired
+	}
+
+	if string(authResp.AuthCode) == "0" {
+		return ErrBadCredentials
+	}
 
 	return nil
 }
 
 // See: https://forums.iracing.com/discussion/22109/login-form-changes/p1
 func encodePassword(username []byte, password []byte) string {
+	return string(encodedPassword(username, password))
+}
+
+// encodedPassword is encodePassword returning bytes that can be zeroed
+func encodedPassword(username []byte, password []byte) []byte {
 	hasher := sha256.New()
 
 	_, err := hasher.Write(password)
 	if err != nil {
-		log.Panic(err)
+		packageLogger.Panic(err)
 	}
 
 	_, err = hasher.Write([]byte(strings.ToLower(string(username))))
 	if err != nil {
-		log.Panic(err)
+		packageLogger.Panic(err)
 	}
 
-	return base64.StdEncoding.Strict().EncodeToString(hasher.Sum(nil))
+	sum := hasher.Sum(nil)
+	defer zero(sum)
+
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sum)))
+	base64.StdEncoding.Strict().Encode(encoded, sum)
+
+	return encoded
 }
 
 // nonce generator
@@ -262,28 +370,97 @@ func makeNonce(gcm cipher.AEAD) ([]byte, error) {
 
 // read secret key
 func getKey(keyFilename string) []byte {
-	stat, err := os.Stat(keyFilename)
+	key, err := getKeyFile(keyFilename)
+	if err != nil {
+		packageLogger.Panic(err)
+	}
 
+	return key
+}
+
+func getKeyFile(keyFilename string) ([]byte, error) {
+	stat, err := statFile("key", keyFilename)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 
 	if (stat.Mode() & os.ModePerm) != 0400 {
-		log.Panicf("key file %v must have perms set to 0400", keyFilename)
+		return nil, fmt.Errorf("key file %v must have perms set to 0400", keyFilename)
 	}
 
-	content, err := os.ReadFile(keyFilename)
-
+	content, err := readTextFile("key", keyFilename)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 
 	key, err := base64.StdEncoding.Strict().DecodeString(string(content))
 	if err != nil {
-		log.Panic(err)
+		return nil, fmt.Errorf("key file %s is not valid base64: %w", keyFilename, err)
 	}
 
-	return key
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("key file %s holds a %d byte key, expected 16, 24 or 32 bytes", keyFilename, len(key))
+	}
+
+	return key, nil
+}
+
+// statFile stats the key or creds file (what says which) catching the
+// paths that can't be one
+func statFile(what string, filename string) (os.FileInfo, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%s file %s is a directory, expected a file", what, filename)
+	}
+
+	if stat.Size() == 0 {
+		return nil, fmt.Errorf("%s file %s is empty", what, filename)
+	}
+
+	return stat, nil
+}
+
+// readTextFile reads the base64 text of a key or creds file.  Editors
+// like to add byte order marks and trailing newlines, those are dropped and
+// UTF-16 is decoded, but binary content is an error.
+func readTextFile(what string, filename string) ([]byte, error) {
+	if _, err := statFile(what, filename); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		content = content[len(bomUTF8):]
+	case bytes.HasPrefix(content, bomUTF16BE):
+		content = decodeUTF16(content[len(bomUTF16BE):], binary.BigEndian)
+	case bytes.HasPrefix(content, bomUTF16LE):
+		content = decodeUTF16(content[len(bomUTF16LE):], binary.LittleEndian)
+	}
+
+	content = bytes.TrimSpace(content)
+
+	if len(content) == 0 {
+		return nil, fmt.Errorf("%s file %s contains whitespace-only content", what, filename)
+	}
+
+	for _, b := range content {
+		if (b < 0x20 && b != '\n' && b != '\r' && b != '\t') || b >= 0x7f {
+			return nil, fmt.Errorf("%s file %s: expected base64, found binary data", what, filename)
+		}
+	}
+
+	return content, nil
 }
 
 func shred(key *[]byte) {
@@ -291,3 +468,18 @@ func shred(key *[]byte) {
 		(*key)[i] = 0x69
 	}
 }
+
+// checkAuthed returns ErrNotAuthenticated unless the instance has a
+// session, from an Auth method or cookies already in its jar, or the call
+// allows anonymous requests
+func (i *Irdata) checkAuthed(o *requestOptions) error {
+	if i.isAuthed || o.anonymous || i.bearerSession() {
+		return nil
+	}
+
+	if jar := i.httpClient.Jar; jar != nil && len(jar.Cookies(i.baseURL)) > 0 {
+		return nil
+	}
+
+	return ErrNotAuthenticated
+}
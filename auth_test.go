@@ -2,12 +2,17 @@ package irdata
 
 import (
 	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/base64"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -90,7 +95,8 @@ func TestShredKey(t *testing.T) {
 }
 
 func TestGetCreds(t *testing.T) {
-	auth := readCreds(testKeyFilename, testCredsFilename)
+	// a copy, reading upgrades the file
+	auth := readCreds(testKeyFilename, copyLegacyCreds(t))
 
 	assert.Equal(t, string(testUsername), auth.Username)
 	assert.Equal(t, encodePassword(testUsername, testPassword), auth.EncodedPassword)
@@ -119,3 +125,324 @@ func TestWriteCreds(t *testing.T) {
 	assert.Equal(t, authDataExpected.Username, authDataActual.Username)
 	assert.Equal(t, authDataExpected.EncodedPassword, authDataActual.EncodedPassword)
 }
+
+// writeTestKey writes a fresh key to a file of its own
+func writeTestKey(t *testing.T) string {
+	key := make([]byte, 16)
+
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+
+	keyFn := filepath.Join(t.TempDir(), "other.key")
+	assert.NoError(t, os.WriteFile(keyFn, []byte(base64.StdEncoding.EncodeToString(key)), 0400))
+
+	return keyFn
+}
+
+func TestInspectCredsFile(t *testing.T) {
+	info, err := InspectCredsFile(testKeyFilename, testCredsFilename)
+	assert.NoError(t, err)
+	assert.Equal(t, CredsInfo{Username: string(testUsername), Profiles: []string{DefaultProfile}, Version: 1}, info)
+
+	_, err = InspectCredsFile(writeTestKey(t), testCredsFilename)
+	assert.ErrorIs(t, err, ErrWrongKey)
+
+	credsFn := filepath.Join(t.TempDir(), "profiles.creds")
+
+	before := time.Now().Add(-time.Second)
+
+	assert.NoError(t, SaveProvidedCredsToProfile(testKeyFilename, credsFn, "team", teamCreds{}))
+	assert.NoError(t, SaveProvidedCredsToProfile(testKeyFilename, credsFn, DefaultProfile, testCreds{}))
+
+	info, err = InspectCredsFile(testKeyFilename, credsFn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(testUsername), info.Username)
+		assert.Equal(t, []string{DefaultProfile, "team"}, info.Profiles)
+		assert.Equal(t, credsVersion, info.Version)
+		assert.True(t, info.Written.After(before))
+	}
+
+	_, err = InspectCredsFile(writeTestKey(t), credsFn)
+	assert.ErrorIs(t, err, ErrWrongKey)
+
+	content, err := os.ReadFile(credsFn)
+	assert.NoError(t, err)
+
+	lines := strings.Split(string(content), "\n")
+
+	// a first character other than the one there, the ciphertext is random
+	flipped := "A"
+	if lines[4][0] == 'A' {
+		flipped = "B"
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		err     error
+	}{
+		{name: "modified", content: strings.Join(append(lines[:4:4], flipped+lines[4][1:]), "\n"), err: ErrCredsCorrupted},
+		{name: "not base64", content: strings.Join(append(lines[:3:3], "!!", lines[4]), "\n"), err: ErrCredsCorrupted},
+		{name: "missing line", content: strings.Join(lines[:4], "\n"), err: ErrCredsCorrupted},
+		{name: "newer version", content: "irdata.creds.v9\n" + strings.Join(lines[1:], "\n"), err: ErrUnsupportedCredsVersion},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "irdata.creds")
+			assert.NoError(t, os.WriteFile(fn, []byte(tc.content), 0600))
+
+			_, err := InspectCredsFile(testKeyFilename, fn)
+			assert.ErrorIs(t, err, tc.err)
+			assert.ErrorContains(t, err, fn)
+		})
+	}
+
+	var versionErr *CredsVersionError
+
+	fn := filepath.Join(t.TempDir(), "irdata.creds")
+	assert.NoError(t, os.WriteFile(fn, []byte("irdata.creds.v9\n"), 0600))
+
+	_, err = InspectCredsFile(testKeyFilename, fn)
+	if assert.ErrorAs(t, err, &versionErr) {
+		assert.Equal(t, "irdata.creds.v9", versionErr.Header)
+	}
+}
+
+func TestInspectCredsFileV2(t *testing.T) {
+	aesgcm := mustCredsCipher(t)
+
+	names, err := sealGob(aesgcm, []string{"team"}, namesContext)
+	assert.NoError(t, err)
+
+	profiles, err := sealGob(aesgcm, map[string]authDataT{
+		"team": {Username: "team@example.com", EncodedPassword: encodePassword([]byte("team@example.com"), []byte("pitwall"))},
+	}, profilesContext)
+	assert.NoError(t, err)
+
+	credsFn := filepath.Join(t.TempDir(), "v2.creds")
+	assert.NoError(t, os.WriteFile(credsFn, []byte(credsHeaderV2+"\n"+names+"\n"+profiles+"\n"), 0600))
+
+	info, err := InspectCredsFile(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, CredsInfo{Profiles: []string{"team"}, Version: 2}, info)
+
+	// rewritten in the current format
+	assert.NoError(t, SaveProvidedCredsToProfile(testKeyFilename, credsFn, "league", testCreds{}))
+
+	info, err = InspectCredsFile(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"league", "team"}, info.Profiles)
+	assert.Equal(t, credsVersion, info.Version)
+}
+
+type badCreds struct{}
+
+func (badCreds) GetCreds() ([]byte, []byte) {
+	return testUsername, []byte("mclaren")
+}
+
+func TestValidateCreds(t *testing.T) {
+	m := newMockAPI(t)
+
+	assert.NoError(t, ValidateCreds(context.Background(), testCreds{}, m.option(t)))
+	assert.Equal(t, 1, m.loginCount())
+}
+
+func TestValidateCredsFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{"verification", http.StatusOK, `{"authcode":0,"verificationRequired":true}`, ErrVerificationRequired},
+		{"unauthorized", http.StatusUnauthorized, `{}`, ErrBadCredentials},
+		{"maintenance", http.StatusServiceUnavailable, `{"error":"Site Maintenance"}`, ErrMaintenance},
+		{"rate limited", http.StatusTooManyRequests, `{}`, ErrRateLimited},
+		{"unexpected", http.StatusBadRequest, `{}`, ErrAuthFailed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newMockAPI(t)
+			m.failLogin(test.status, test.body)
+
+			clock := newFakeClock()
+
+			assert.ErrorIs(t, ValidateCreds(context.Background(), testCreds{}, m.option(t), WithClock(clock)), test.expected)
+
+			// a single attempt, nothing waited out
+			assert.Equal(t, 1, m.loginCount())
+			assert.Empty(t, clock.slept())
+		})
+	}
+}
+
+func TestValidateCredsBadCreds(t *testing.T) {
+	m := newMockAPI(t)
+
+	assert.ErrorIs(t, ValidateCreds(context.Background(), badCreds{}, m.option(t)), ErrBadCredentials)
+}
+
+func TestValidateCredsLeavesInstanceAlone(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.openAuthed(t)
+
+	assert.ErrorIs(t, ValidateCreds(context.Background(), badCreds{}, m.option(t)), ErrBadCredentials)
+	assert.NoError(t, ValidateCreds(context.Background(), testCreds{}, m.option(t)))
+
+	assert.True(t, api.isAuthed)
+
+	_, err := api.Get("/data/constants/event_types")
+	assert.NoError(t, err)
+}
+
+func TestAuthBadCreds(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.open(t)
+
+	assert.ErrorIs(t, api.AuthWithProvideCreds(badCreds{}), ErrBadCredentials)
+	assert.False(t, api.isAuthed)
+}
+
+func TestKeyFileMistakes(t *testing.T) {
+	key, err := os.ReadFile(testKeyFilename)
+	assert.NoError(t, err)
+
+	utf16 := []byte{0xff, 0xfe}
+	for _, b := range bytes.TrimSpace(key) {
+		utf16 = append(utf16, b, 0)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content []byte
+		dir     bool
+		perm    os.FileMode
+		err     string
+	}{
+		{name: "plain", content: key},
+		{name: "bom and crlf", content: append(append([]byte{0xef, 0xbb, 0xbf}, bytes.TrimSpace(key)...), "\r\n"...)},
+		{name: "surrounding blanks", content: append(append([]byte("  \n"), key...), "\n\n"...)},
+		{name: "utf16 with bom", content: utf16},
+		{name: "directory", dir: true, err: "is a directory"},
+		{name: "empty", content: []byte{}, err: "is empty"},
+		{name: "whitespace", content: []byte(" \r\n\t\n"), err: "contains whitespace-only content"},
+		{name: "binary", content: []byte{0x13, 0x37, 0x00, 0xff, 0x42, 0x10}, err: "expected base64, found binary data"},
+		{name: "not base64", content: []byte("not*base64!"), err: "is not valid base64"},
+		{name: "short key", content: []byte(base64.StdEncoding.EncodeToString([]byte("short"))), err: "holds a 5 byte key"},
+		{name: "perms", content: key, perm: 0644, err: "must have perms set to 0400"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			keyFn := filepath.Join(t.TempDir(), "irdata.key")
+
+			if tc.dir {
+				assert.NoError(t, os.Mkdir(keyFn, 0700))
+			} else {
+				perm := tc.perm
+				if perm == 0 {
+					perm = 0400
+				}
+
+				assert.NoError(t, os.WriteFile(keyFn, tc.content, perm))
+			}
+
+			got, err := getKeyFile(keyFn)
+
+			if tc.err == "" {
+				assert.NoError(t, err)
+				assert.Len(t, got, 16)
+
+				return
+			}
+
+			assert.ErrorContains(t, err, tc.err)
+			assert.ErrorContains(t, err, keyFn)
+
+			// exported functions return the error rather than panicking
+			assert.ErrorContains(t, SaveProvidedCredsToProfile(keyFn, filepath.Join(t.TempDir(), "creds"), "default", testCreds{}), tc.err)
+		})
+	}
+}
+
+func TestCredsFileMistakes(t *testing.T) {
+	creds, err := os.ReadFile(testCredsFilename)
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name    string
+		content []byte
+		dir     bool
+		err     string
+	}{
+		{name: "plain", content: creds},
+		{name: "bom and newline", content: append(append([]byte{0xef, 0xbb, 0xbf}, creds...), "\r\n"...)},
+		{name: "directory", dir: true, err: "is a directory"},
+		{name: "empty", content: []byte{}, err: "is empty"},
+		{name: "whitespace", content: []byte("\n\n"), err: "contains whitespace-only content"},
+		{name: "binary", content: []byte{0x1f, 0x8b, 0x08, 0x00}, err: "expected base64, found binary data"},
+		{name: "truncated", content: creds[:10], err: "creds file"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			credsFn := filepath.Join(t.TempDir(), "irdata.creds")
+
+			if tc.dir {
+				assert.NoError(t, os.Mkdir(credsFn, 0700))
+			} else {
+				assert.NoError(t, os.WriteFile(credsFn, tc.content, 0600))
+			}
+
+			authData, err := readCredsFile(testKeyFilename, credsFn)
+
+			if tc.err == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, string(testUsername), authData.Username)
+
+				return
+			}
+
+			assert.ErrorContains(t, err, tc.err)
+			assert.ErrorContains(t, err, credsFn)
+		})
+	}
+}
+
+func TestNotAuthenticated(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/member/info", `{}`)
+	m.mux.HandleFunc("/data/constants/divisions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"label":"ALL","value":-1}]`))
+	})
+
+	api := m.open(t)
+
+	_, err := api.Get("/data/member/info")
+	assert.ErrorIs(t, err, ErrNotAuthenticated)
+	assert.Contains(t, err.Error(), "AuthWithCredsFromFile")
+
+	var v interface{}
+	assert.ErrorIs(t, api.GetJSON(context.Background(), "/data/member/info", &v), ErrNotAuthenticated)
+	assert.ErrorIs(t, api.PostJSON(context.Background(), "/data/member/info", nil, nil), ErrNotAuthenticated)
+
+	_, err = api.Do(context.Background(), http.MethodGet, "/data/member/info", nil)
+	assert.ErrorIs(t, err, ErrNotAuthenticated)
+
+	assert.Equal(t, 0, m.hitCount("/data/member/info"))
+
+	// the escape hatch for endpoints answering without a session
+	data, err := api.Get("/data/constants/divisions", AllowAnonymous())
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"label":"ALL","value":-1}]`, string(data))
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	_, err = api.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	api.Logout()
+
+	_, err = api.Get("/data/member/info")
+	assert.ErrorIs(t, err, ErrNotAuthenticated)
+	assert.Equal(t, 1, m.hitCount("/data/member/info"))
+}
@@ -0,0 +1,151 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// authVerificationTTL is how long after a session is proven to work, by
+// the verification probe or a data request, logging in again within it
+// isn't probed
+const authVerificationTTL = 15 * time.Minute
+
+// AuthVerification is when logging in checks the session works with a
+// request to a data endpoint, see SetAuthVerification
+type AuthVerification int
+
+const (
+	// VerifyUnprovenSessions skips the check when logging in again after
+	// the session expired if the session was proven within the last 15
+	// minutes, by the check or a successful data request.  The default.
+	VerifyUnprovenSessions AuthVerification = iota
+
+	// VerifyEveryLogin checks after every login
+	VerifyEveryLogin
+)
+
+// authVerifyT tracks the session an Auth method started, which logging in
+// again with the retained credentials carries on
+type authVerifyT struct {
+	mu     sync.Mutex
+	mode   AuthVerification
+	proven time.Time
+}
+
+// SetAuthVerification sets when logging in, or logging in again after the
+// session expired, checks the session works, the default is
+// VerifyUnprovenSessions
+func (i *Irdata) SetAuthVerification(mode AuthVerification) {
+	i.authVerify.mu.Lock()
+	defer i.authVerify.mu.Unlock()
+
+	i.authVerify.mode = mode
+}
+
+// startSession forgets the proof of the previous session, called by the
+// Auth methods before logging in
+func (i *Irdata) startSession() {
+	i.authVerify.mu.Lock()
+	defer i.authVerify.mu.Unlock()
+
+	i.authVerify.proven = time.Time{}
+}
+
+// noteSessionProven records that the session works, called for successful
+// responses of data requests to the API
+func (i *Irdata) noteSessionProven(req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, "/data/") || req.URL.Host != i.baseURL.Host {
+		return
+	}
+
+	i.authVerify.mu.Lock()
+	defer i.authVerify.mu.Unlock()
+
+	i.authVerify.proven = i.clock.Now()
+}
+
+// sessionProven reports whether the verification probe can be skipped
+func (i *Irdata) sessionProven() bool {
+	i.authVerify.mu.Lock()
+	defer i.authVerify.mu.Unlock()
+
+	if i.authVerify.mode == VerifyEveryLogin || i.authVerify.proven.IsZero() {
+		return false
+	}
+
+	return i.clock.Now().Sub(i.authVerify.proven) < authVerificationTTL
+}
+
+// verifySession checks the session of a login works with the probe
+// request, unless the session was proven recently.  The probe waits its
+// turn with the throttle, unless the caller already holds a slot.
+func (i *Irdata) verifySession(ctx context.Context) error {
+	if i.sessionProven() {
+		i.logger.Debug("Session proven recently, skipping the verification")
+
+		return nil
+	}
+
+	testURL, err := i.resolveURL(testURI)
+	if err != nil {
+		return err
+	}
+
+	if !holdsSlot(ctx) {
+		release, err := i.acquireSlot(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	resp, err := i.retryingGet(ctx, testURL.String())
+	if err != nil {
+		return &unreachableError{err: err}
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		if resp.StatusCode == 401 {
+			return ErrBadCredentials
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return ErrRateLimited
+		}
+
+		i.logger.WithFields(log.Fields{
+			"resp.Status":     resp.Status,
+			"resp.StatusCode": resp.StatusCode,
+			"testURL":         testURL,
+		}).Info("Unexpected status")
+
+		return ErrAuthFailed
+	}
+
+	i.authVerify.mu.Lock()
+	i.authVerify.proven = i.clock.Now()
+	i.authVerify.mu.Unlock()
+
+	return nil
+}
+
+type slotHeldKeyT struct{}
+
+// withSlotHeld marks ctx as that of a request holding a throttle slot, so
+// what it does on the way, e.g. logging in again, doesn't wait for another
+func withSlotHeld(ctx context.Context) context.Context {
+	return context.WithValue(ctx, slotHeldKeyT{}, true)
+}
+
+func holdsSlot(ctx context.Context) bool {
+	held, _ := ctx.Value(slotHeldKeyT{}).(bool)
+
+	return held
+}
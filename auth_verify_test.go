@@ -0,0 +1,147 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthVerificationReauth(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/thing", `{"ok":true}`)
+
+	api := m.open(t)
+	api.SetCredentialRetention(RetainCredentials)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	var out map[string]bool
+
+	for n := 0; n < 5; n++ {
+		expireSession(t, api)
+
+		assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	}
+
+	assert.Equal(t, 6, m.loginCount())
+	assert.Equal(t, 1, m.hitCount(testURI))
+
+	// strict users keep the probe
+	api.SetAuthVerification(VerifyEveryLogin)
+
+	expireSession(t, api)
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	assert.Equal(t, 2, m.hitCount(testURI))
+}
+
+func TestAuthVerificationProvenByRequests(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/thing", `{"ok":true}`)
+
+	clock := newFakeClock()
+
+	api := m.open(t, WithClock(clock))
+	api.SetCredentialRetention(RetainCredentials)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	var out map[string]bool
+
+	reauth := func() {
+		expireSession(t, api)
+		assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	}
+
+	clock.advance(authVerificationTTL)
+	reauth()
+	assert.Equal(t, 2, m.hitCount(testURI))
+
+	// a successful data request proves the session as well
+	clock.advance(authVerificationTTL - time.Minute)
+
+	_, err := api.Get("/data/thing")
+	assert.NoError(t, err)
+
+	clock.advance(2 * time.Minute)
+	reauth()
+	assert.Equal(t, 2, m.hitCount(testURI))
+
+	clock.advance(authVerificationTTL)
+	reauth()
+	assert.Equal(t, 3, m.hitCount(testURI))
+}
+
+func TestAuthVerificationPerSession(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.openAuthed(t)
+	assert.Equal(t, 1, m.hitCount(testURI))
+
+	// logging in anew starts a new session, proven or not
+	api.Logout()
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+	assert.Equal(t, 2, m.hitCount(testURI))
+
+	m.openAuthed(t)
+	assert.Equal(t, 3, m.hitCount(testURI))
+
+	// ValidateCreds always probes
+	assert.NoError(t, ValidateCreds(context.Background(), testCreds{}, m.option(t)))
+	assert.Equal(t, 4, m.hitCount(testURI))
+}
+
+func TestAuthVerificationThrottled(t *testing.T) {
+	m := newMockAPI(t)
+
+	var rejected int32
+
+	m.mux.HandleFunc("/data/expiring", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&rejected, 1) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	api := m.open(t, WithMaxConcurrentRequests(1))
+	api.SetAuthVerification(VerifyEveryLogin)
+	api.SetCredentialRetention(RetainCredentials)
+
+	ctx := context.Background()
+
+	release, err := api.acquireSlot(ctx)
+	assert.NoError(t, err)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- api.AuthWithProvideCreds(testCreds{})
+	}()
+
+	// the probe waits for the slot
+	select {
+	case err := <-done:
+		t.Fatalf("logged in while the throttle was full: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Equal(t, 0, m.hitCount(testURI))
+
+	release()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, 1, m.hitCount(testURI))
+
+	// logging in again within a request doesn't wait for a second slot
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(ctx, "/data/expiring", &out))
+	assert.True(t, out["ok"])
+	assert.Equal(t, 2, m.hitCount(testURI))
+}
@@ -0,0 +1,184 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// job state outlives any sensible result ttl so finished windows stay finished
+const backfillStateTTL = 365 * 24 * time.Hour
+
+const defaultBackfillWindow = 24 * time.Hour
+
+// BackfillJob searches series results over a long time range one window at
+// a time, remembering in the cache which windows are done so a restarted
+// job resumes where it left off instead of fetching everything again.
+type BackfillJob struct {
+	i      *Irdata
+	key    string
+	params SearchSeriesParams
+	ranges []timeRange
+	state  backfillStateT
+}
+
+type backfillStateT struct {
+	// Completed holds the beginning of every finished window
+	Completed map[int64]bool
+	RateLimit RateLimit
+}
+
+// BackfillProgress reports how far a BackfillJob has come
+type BackfillProgress struct {
+	Completed int
+	Total     int
+}
+
+// Done reports whether every window has been fetched
+func (p BackfillProgress) Done() bool {
+	return p.Completed == p.Total
+}
+
+// NewBackfillJob returns a job searching with params over [begin, end) in
+// windows no longer than window (which defaults to a day when 0).  The
+// start range of params is replaced by each window in turn.
+//
+// The progress is kept in the cache under key, jobs with the same key share
+// their progress.  The cache must be enabled.
+func (i *Irdata) NewBackfillJob(key string, params SearchSeriesParams, begin time.Time, end time.Time, window time.Duration) (*BackfillJob, error) {
+	if i.cache == nil {
+		return nil, errors.New("cache must be enabled")
+	}
+
+	if window <= 0 {
+		window = defaultBackfillWindow
+	}
+
+	if window > maxSearchWindow {
+		return nil, errors.New("window is longer than the API allows")
+	}
+
+	var ranges []timeRange
+
+	for b := begin; b.Before(end); b = b.Add(window) {
+		e := b.Add(window)
+		if e.After(end) {
+			e = end
+		}
+
+		ranges = append(ranges, timeRange{begin: b, end: e})
+	}
+
+	j := &BackfillJob{
+		i:      i,
+		key:    "irdata.backfill." + key,
+		params: params,
+		ranges: ranges,
+	}
+
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Run fetches every window not yet completed, oldest first, and hands its
+// results to fn.  A window counts as completed once fn returns nil for it,
+// so an error from fn (or the API) stops the job and leaves that window to
+// the next Run.
+func (j *BackfillJob) Run(ctx context.Context, fn func(*SearchResults) error) error {
+	// don't walk straight back into the rate limit we were stopped by
+	if rl := j.state.RateLimit; rl.Limit > 0 && rl.Remaining == 0 && j.i.clock.Now().Before(rl.Reset) {
+		j.i.logger.WithFields(log.Fields{"reset": rl.Reset}).Info("Backfill waiting for rate limit reset")
+
+		if err := j.i.clock.Sleep(ctx, rl.Reset.Sub(j.i.clock.Now())); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range j.ranges {
+		if j.state.Completed[r.begin.Unix()] {
+			continue
+		}
+
+		params := j.params
+		params.StartRangeBegin = r.begin
+		params.StartRangeEnd = r.end
+		params.SeasonYear = 0
+		params.SeasonQuarter = 0
+
+		results, err := j.i.SearchSeriesResults(ctx, params)
+
+		j.state.RateLimit = j.i.RateLimit()
+
+		if err == nil {
+			err = fn(results)
+		}
+
+		if err != nil {
+			if saveErr := j.save(); saveErr != nil {
+				j.i.logger.WithFields(log.Fields{"err": saveErr}).Error("Unable to save backfill state")
+			}
+
+			return err
+		}
+
+		j.state.Completed[r.begin.Unix()] = true
+
+		if err := j.save(); err != nil {
+			return err
+		}
+
+		j.i.logger.WithFields(log.Fields{
+			"job":       j.key,
+			"begin":     r.begin,
+			"progress":  j.Progress(),
+			"len(rows)": len(results.Rows),
+		}).Debug("Backfill window done")
+	}
+
+	return nil
+}
+
+// Progress reports how many windows are done
+func (j *BackfillJob) Progress() BackfillProgress {
+	completed := 0
+
+	for _, r := range j.ranges {
+		if j.state.Completed[r.begin.Unix()] {
+			completed++
+		}
+	}
+
+	return BackfillProgress{Completed: completed, Total: len(j.ranges)}
+}
+
+// Reset forgets the progress so the next Run fetches every window again
+func (j *BackfillJob) Reset() error {
+	j.state = backfillStateT{Completed: make(map[int64]bool)}
+
+	return j.i.deleteCachedData(j.i.ctx, j.key)
+}
+
+func (j *BackfillJob) load() error {
+	j.state = backfillStateT{}
+
+	if _, err := j.i.getCachedJSON(j.i.ctx, j.key, &j.state); err != nil {
+		return err
+	}
+
+	if j.state.Completed == nil {
+		j.state.Completed = make(map[int64]bool)
+	}
+
+	return nil
+}
+
+// save saves with the context of the instance so the progress of a Run is
+// kept when its context is cancelled
+func (j *BackfillJob) save() error {
+	return j.i.setCachedJSON(j.i.ctx, j.key, j.state, backfillStateTTL)
+}
@@ -0,0 +1,165 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testBackfillBegin = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+type mockBackfill struct {
+	mu      sync.Mutex
+	queried []string
+}
+
+func (b *mockBackfill) chunks(r *http.Request) []string {
+	begin := r.URL.Query().Get("start_range_begin")
+
+	b.mu.Lock()
+	b.queried = append(b.queried, begin)
+	b.mu.Unlock()
+
+	return []string{fmt.Sprintf(`[{"subsession_id":%d,"start_time":"%s"}]`, len(begin), time.Now().UTC().Format(time.RFC3339))}
+}
+
+func (b *mockBackfill) queries() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string{}, b.queried...)
+}
+
+func newBackfillAPI(t *testing.T, m *mockAPI, cache CacheBackend) *Irdata {
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(cache)
+
+	return api
+}
+
+func TestBackfillJob(t *testing.T) {
+	m := newMockAPI(t)
+
+	backfill := &mockBackfill{}
+	m.handleChunked("/data/results/search_series", backfill.chunks)
+
+	api := newBackfillAPI(t, m, NewMemoryCache())
+
+	job, err := api.NewBackfillJob("test", SearchSeriesParams{SeriesID: 139}, testBackfillBegin, testBackfillBegin.Add(72*time.Hour), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, BackfillProgress{Completed: 0, Total: 3}, job.Progress())
+
+	windows := 0
+
+	assert.NoError(t, job.Run(context.Background(), func(results *SearchResults) error {
+		windows++
+		assert.Len(t, results.Rows, 1)
+		return nil
+	}))
+
+	assert.Equal(t, 3, windows)
+	assert.True(t, job.Progress().Done())
+	assert.Equal(t, []string{"2024-03-01T00:00Z", "2024-03-02T00:00Z", "2024-03-03T00:00Z"}, backfill.queries())
+
+	// nothing left to do
+	assert.NoError(t, job.Run(context.Background(), func(*SearchResults) error {
+		t.Fatal("refetched a completed window")
+		return nil
+	}))
+}
+
+func TestBackfillJobResumesAfterCrash(t *testing.T) {
+	m := newMockAPI(t)
+
+	backfill := &mockBackfill{}
+	m.handleChunked("/data/results/search_series", backfill.chunks)
+
+	cache := NewMemoryCache()
+	end := testBackfillBegin.Add(96 * time.Hour)
+
+	// the first process dies while handling the third window
+	job, err := newBackfillAPI(t, m, cache).NewBackfillJob("crash", SearchSeriesParams{}, testBackfillBegin, end, 0)
+	assert.NoError(t, err)
+
+	crash := errors.New("deployed")
+	windows := 0
+
+	assert.ErrorIs(t, job.Run(context.Background(), func(*SearchResults) error {
+		windows++
+		if windows == 3 {
+			return crash
+		}
+		return nil
+	}), crash)
+
+	assert.Equal(t, BackfillProgress{Completed: 2, Total: 4}, job.Progress())
+
+	// a new process with a new instance picks up at the third window
+	job, err = newBackfillAPI(t, m, cache).NewBackfillJob("crash", SearchSeriesParams{}, testBackfillBegin, end, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, BackfillProgress{Completed: 2, Total: 4}, job.Progress())
+
+	assert.NoError(t, job.Run(context.Background(), func(*SearchResults) error { return nil }))
+	assert.True(t, job.Progress().Done())
+
+	assert.Equal(t, []string{
+		"2024-03-01T00:00Z", "2024-03-02T00:00Z", "2024-03-03T00:00Z",
+		"2024-03-03T00:00Z", "2024-03-04T00:00Z",
+	}, backfill.queries())
+}
+
+func TestBackfillJobReset(t *testing.T) {
+	m := newMockAPI(t)
+
+	backfill := &mockBackfill{}
+	m.handleChunked("/data/results/search_series", backfill.chunks)
+
+	cache := NewMemoryCache()
+
+	job, err := newBackfillAPI(t, m, cache).NewBackfillJob("reset", SearchSeriesParams{}, testBackfillBegin, testBackfillBegin.Add(48*time.Hour), 0)
+	assert.NoError(t, err)
+	assert.NoError(t, job.Run(context.Background(), func(*SearchResults) error { return nil }))
+
+	assert.NoError(t, job.Reset())
+	assert.Equal(t, 0, job.Progress().Completed)
+
+	job, err = newBackfillAPI(t, m, cache).NewBackfillJob("reset", SearchSeriesParams{}, testBackfillBegin, testBackfillBegin.Add(48*time.Hour), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, job.Progress().Completed)
+
+	assert.NoError(t, job.Run(context.Background(), func(*SearchResults) error { return nil }))
+	assert.Len(t, backfill.queries(), 4)
+}
+
+func TestBackfillJobWaitsOutRateLimit(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", (&mockBackfill{}).chunks)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	reset := clock.Now().Add(90 * time.Second)
+
+	assert.NoError(t, api.setCachedJSON(context.Background(), "irdata.backfill.limited", backfillStateT{
+		RateLimit: RateLimit{Limit: 240, Remaining: 0, Reset: reset},
+	}, time.Hour))
+
+	job, err := api.NewBackfillJob("limited", SearchSeriesParams{}, testBackfillBegin, testBackfillBegin.Add(24*time.Hour), 0)
+	assert.NoError(t, err)
+
+	assert.NoError(t, job.Run(context.Background(), func(*SearchResults) error { return nil }))
+	assert.Equal(t, []time.Duration{90 * time.Second}, clock.slept())
+}
+
+func TestBackfillJobNeedsCache(t *testing.T) {
+	_, err := Open(context.Background()).NewBackfillJob("x", SearchSeriesParams{}, testBackfillBegin, testBackfillBegin, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,305 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultBreakerWindow = time.Minute
+const defaultBreakerMinRequests = 10
+const defaultBreakerFailureRate = 0.5
+const defaultBreakerOpenFor = 30 * time.Second
+
+// BreakerOptions configure the circuit breaker, see WithCircuitBreaker.
+// Zero fields take their defaults.
+type BreakerOptions struct {
+	// Window is how far back requests count, a minute by default
+	Window time.Duration
+
+	// MinRequests is how many requests the window must hold before the
+	// breaker may open, 10 by default
+	MinRequests int
+
+	// FailureRate is the share of failed requests in the window that opens
+	// the breaker, 0.5 by default
+	FailureRate float64
+
+	// OpenFor is how long the breaker stays open before letting a probe
+	// request through, 30 seconds by default
+	OpenFor time.Duration
+}
+
+// BreakerState is the state of the circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails requests with ErrCircuitOpen
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single probe request through, which closes
+	// the breaker if it succeeds and opens it again if not
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the state by name
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// BreakerStats are the state of the circuit breaker and what it did so far
+type BreakerStats struct {
+	State BreakerState `json:"state"`
+
+	// Requests and Failures are counted over the window
+	Requests int `json:"requests"`
+	Failures int `json:"failures"`
+
+	// Opened is how many times the breaker opened, Rejected how many
+	// requests it failed
+	Opened   int64 `json:"opened"`
+	Rejected int64 `json:"rejected"`
+
+	// NextProbe is when an open breaker lets a probe through, zero
+	// otherwise
+	NextProbe time.Time `json:"next_probe,omitempty"`
+}
+
+type breakerOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+type breakerT struct {
+	mu      sync.Mutex
+	enabled bool
+	opts    BreakerOptions
+
+	state    BreakerState
+	outcomes []breakerOutcome
+	openedAt time.Time
+	probing  bool
+	opened   int64
+	rejected int64
+}
+
+// WithCircuitBreaker stops sending requests once too many fail, as they do
+// while iRacing is down, instead of retrying each of them.  When at least
+// opts.MinRequests requests were made within opts.Window and opts.FailureRate
+// of them failed, requests fail with a *CircuitOpenError for opts.OpenFor.
+// Then a single probe is let through: the breaker closes if it succeeds and
+// stays open for another opts.OpenFor if not.
+//
+// Transport errors and 5xx responses count as failures.  Rate limited
+// requests count as neither, being rate limited doesn't mean iRacing is
+// down.
+func WithCircuitBreaker(opts BreakerOptions) Option {
+	return func(i *Irdata) {
+		if opts.Window <= 0 {
+			opts.Window = defaultBreakerWindow
+		}
+
+		if opts.MinRequests <= 0 {
+			opts.MinRequests = defaultBreakerMinRequests
+		}
+
+		if opts.FailureRate <= 0 {
+			opts.FailureRate = defaultBreakerFailureRate
+		}
+
+		if opts.OpenFor <= 0 {
+			opts.OpenFor = defaultBreakerOpenFor
+		}
+
+		i.breaker.enabled = true
+		i.breaker.opts = opts
+	}
+}
+
+// BreakerStats returns the state of the circuit breaker, nil without
+// WithCircuitBreaker
+func (i *Irdata) BreakerStats() *BreakerStats {
+	b := &i.breaker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.enabled {
+		return nil
+	}
+
+	now := i.clock.Now()
+
+	b.prune(now)
+
+	stats := &BreakerStats{
+		State:    b.state,
+		Requests: len(b.outcomes),
+		Opened:   b.opened,
+		Rejected: b.rejected,
+	}
+
+	for _, outcome := range b.outcomes {
+		if outcome.failed {
+			stats.Failures++
+		}
+	}
+
+	if b.state == BreakerOpen {
+		stats.NextProbe = b.openedAt.Add(b.opts.OpenFor)
+
+		if !now.Before(stats.NextProbe) {
+			stats.State = BreakerHalfOpen
+		}
+	}
+
+	return stats
+}
+
+// breakerAllow returns a *CircuitOpenError if the breaker refuses a request
+// now, probe is set for the request let through to probe an open breaker
+func (i *Irdata) breakerAllow() (probe bool, err error) {
+	b := &i.breaker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.enabled || b.state == BreakerClosed {
+		return false, nil
+	}
+
+	now := i.clock.Now()
+	nextProbe := b.openedAt.Add(b.opts.OpenFor)
+
+	if now.Before(nextProbe) {
+		b.rejected++
+		return false, &CircuitOpenError{RetryIn: nextProbe.Sub(now)}
+	}
+
+	if b.probing {
+		b.rejected++
+		return false, &CircuitOpenError{}
+	}
+
+	b.state = BreakerHalfOpen
+	b.probing = true
+
+	return true, nil
+}
+
+// breakerRecord counts the outcome of a request breakerAllow let through
+func (i *Irdata) breakerRecord(probe bool, resp *http.Response, err error) {
+	b := &i.breaker
+
+	if !b.enabled {
+		return
+	}
+
+	failed, counts := breakerFailure(resp, err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := i.clock.Now()
+
+	if probe {
+		b.probing = false
+
+		switch {
+		case !counts:
+			// nothing learnt, the next request probes again
+		case failed:
+			b.open(now)
+		default:
+			b.state = BreakerClosed
+			b.outcomes = nil
+
+			i.logger.Info("Circuit breaker closed")
+		}
+
+		return
+	}
+
+	if !counts || b.state != BreakerClosed {
+		return
+	}
+
+	b.outcomes = append(b.outcomes, breakerOutcome{at: now, failed: failed})
+	b.prune(now)
+
+	failures := 0
+
+	for _, outcome := range b.outcomes {
+		if outcome.failed {
+			failures++
+		}
+	}
+
+	if len(b.outcomes) >= b.opts.MinRequests && float64(failures) >= float64(len(b.outcomes))*b.opts.FailureRate {
+		b.open(now)
+
+		i.logger.WithFields(log.Fields{
+			"requests": len(b.outcomes),
+			"failures": failures,
+			"openFor":  b.opts.OpenFor,
+		}).Warn("Circuit breaker opened")
+	}
+}
+
+// open opens the breaker, the caller holds mu
+func (b *breakerT) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.opened++
+}
+
+// prune forgets the outcomes outside the window, the caller holds mu
+func (b *breakerT) prune(now time.Time) {
+	horizon := now.Add(-b.opts.Window)
+
+	n := 0
+	for n < len(b.outcomes) && !b.outcomes[n].at.After(horizon) {
+		n++
+	}
+
+	b.outcomes = b.outcomes[n:]
+}
+
+// breakerFailure classifies the outcome of a request: failed for transport
+// errors and 5xx responses, not counted for rate limiting and the request
+// being cancelled
+func breakerFailure(resp *http.Response, err error) (failed bool, counts bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return false, false
+		}
+
+		return true, true
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return false, false
+	}
+
+	return resp.StatusCode >= 500, true
+}
@@ -0,0 +1,164 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const breakerTestURI = "/data/constants/categories"
+
+// handleStatus serves breakerTestURI answering with the status held in
+// status
+func handleStatus(m *mockAPI, status *int32) {
+	m.handle(breakerTestURI, func(w http.ResponseWriter, r *http.Request) {
+		if code := int(atomic.LoadInt32(status)); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+
+		fmt.Fprint(w, `[{"label":"Oval","value":1}]`)
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	m := newMockAPI(t)
+
+	status := int32(http.StatusInternalServerError)
+	handleStatus(m, &status)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock), WithCircuitBreaker(BreakerOptions{
+		MinRequests: 4,
+		OpenFor:     time.Minute,
+	}))
+
+	opened := clock.Now()
+	ctx := context.Background()
+
+	var v interface{}
+
+	// the login's 2 successes and 2 failures, 10s apart, open it
+	err := api.GetJSON(ctx, breakerTestURI, &v)
+
+	var circuitErr *CircuitOpenError
+
+	if assert.ErrorAs(t, err, &circuitErr) {
+		assert.Equal(t, 45*time.Second, circuitErr.RetryIn)
+	}
+
+	assert.Equal(t, 2, m.hitCount(breakerTestURI))
+
+	stats := api.BreakerStats()
+	assert.Equal(t, BreakerOpen, stats.State)
+	assert.Equal(t, int64(1), stats.Opened)
+	assert.Equal(t, opened.Add(70*time.Second), stats.NextProbe)
+
+	// failing fast
+	assert.ErrorIs(t, api.GetJSON(ctx, breakerTestURI, &v), ErrCircuitOpen)
+	assert.Equal(t, 2, m.hitCount(breakerTestURI))
+
+	health := api.HealthCheck(ctx)
+	assert.False(t, health.Healthy)
+	assert.Equal(t, BreakerOpen, health.Breaker.State)
+
+	encoded, err := json.Marshal(health.Breaker)
+	assert.NoError(t, err)
+	assert.Contains(t, string(encoded), `"state":"open"`)
+
+	// a failed probe opens it again
+	clock.advance(45 * time.Second)
+	assert.Equal(t, BreakerHalfOpen, api.BreakerStats().State)
+
+	assert.ErrorIs(t, api.GetJSON(ctx, breakerTestURI, &v), ErrCircuitOpen)
+	assert.Equal(t, 3, m.hitCount(breakerTestURI))
+	assert.Equal(t, int64(2), api.BreakerStats().Opened)
+	assert.Equal(t, BreakerOpen, api.BreakerStats().State)
+
+	// a successful one closes it
+	atomic.StoreInt32(&status, http.StatusOK)
+	clock.advance(time.Minute)
+
+	assert.NoError(t, api.GetJSON(ctx, breakerTestURI, &v))
+	assert.Equal(t, 4, m.hitCount(breakerTestURI))
+
+	stats = api.BreakerStats()
+	assert.Equal(t, BreakerClosed, stats.State)
+	assert.True(t, stats.NextProbe.IsZero())
+	assert.Equal(t, int64(3), stats.Rejected)
+
+	assert.NoError(t, api.GetJSON(ctx, breakerTestURI, &v))
+	assert.True(t, api.HealthCheck(ctx).Healthy)
+}
+
+func TestCircuitBreakerIgnoresRateLimiting(t *testing.T) {
+	m := newMockAPI(t)
+
+	status := int32(http.StatusTooManyRequests)
+	handleStatus(m, &status)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock), WithCircuitBreaker(BreakerOptions{MinRequests: 2}))
+
+	var v interface{}
+
+	err := api.GetJSON(context.Background(), breakerTestURI, &v)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, maxAttempts, m.hitCount(breakerTestURI))
+
+	stats := api.BreakerStats()
+	assert.Equal(t, BreakerClosed, stats.State)
+	assert.Equal(t, 0, stats.Failures)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	m := newMockAPI(t)
+
+	status := int32(http.StatusOK)
+	handleStatus(m, &status)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock), WithCircuitBreaker(BreakerOptions{
+		MinRequests: 4,
+		FailureRate: 0.75,
+		Window:      time.Minute,
+	}))
+
+	var v interface{}
+
+	ctx := context.Background()
+
+	assert.NoError(t, api.GetJSON(ctx, breakerTestURI, &v))
+
+	stats := api.BreakerStats()
+	assert.Equal(t, 3, stats.Requests)
+
+	// the successes age out of the window, the failures alone must make
+	// up the minimum
+	clock.advance(2 * time.Minute)
+	assert.Zero(t, api.BreakerStats().Requests)
+
+	atomic.StoreInt32(&status, http.StatusBadGateway)
+
+	err := api.GetJSON(ctx, breakerTestURI, &v)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// 10s, 15s and 20s apart, the 4th failure opens it
+	assert.Equal(t, 5, m.hitCount(breakerTestURI))
+	assert.Equal(t, int64(1), api.BreakerStats().Opened)
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	api := Open(context.Background())
+
+	assert.Nil(t, api.BreakerStats())
+	assert.Nil(t, api.HealthCheck(context.Background()).Breaker)
+}
@@ -1,8 +1,14 @@
 package irdata
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"strconv"
 	"time"
 
 	"git.mills.io/prologic/bitcask"
@@ -12,40 +18,106 @@ import (
 const _maxValueSize = 1024 * 1024 * 256 // 256MB
 const _maxKeySize = 1024 * 4            // 4K
 
+// chunked results are cached as an index entry stored under the uri plus
+// an entry per chunk so no single value has to hold the whole result.
+// The marker can't start a JSON document so entries cached whole, including
+// those written before chunks were stored separately, are told apart.
+var chunkIndexMarker = []byte("\x00irdata.chunks\x00")
+
+// chunks outlive their index a little so a live index never points at
+// expired chunks
+const chunkTTLGrace = time.Minute
+
+// every entry cached under a uri or other key gets a small metadata record
+// so the cache can be described without reading any values
+var cacheMetaPrefix = []byte("\x00irdata.meta\x00")
+
 type hashedKey []byte
 
-func (i *Irdata) cacheOpen(cacheDir string) error {
+type cacheMetaT struct {
+	Key     string
+	Size    int
+	ChunkID string `json:",omitempty"`
+	Chunks  int    `json:",omitempty"`
+	Reason  string `json:",omitempty"`
+	AsOf    time.Time
+	Created time.Time
+	Expires time.Time
+}
+
+type chunkIndexT struct {
+	// ID is unique per write so a rewrite never mixes chunks of two results
+	ID     string
+	Chunks []string
+}
+
+func (i *Irdata) cacheOpen(cacheDir string, opts ...CacheOption) error {
+	var o cacheOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var backend CacheBackend
 	var err error
 
-	i.cask, err = bitcask.Open(
-		cacheDir,
-		bitcask.WithMaxValueSize(_maxValueSize),
-		bitcask.WithMaxKeySize(_maxKeySize),
-		bitcask.WithSync(true),
-	)
+	if o.shared {
+		if o.lockTimeout <= 0 {
+			o.lockTimeout = defaultSharedCacheLockTimeout
+		}
 
-	return err
-}
+		backend, err = openFileBackend(cacheDir, i.clock, o.lockTimeout, i.logger)
+	} else {
+		backend, err = i.openLockedBitcask(cacheDir, o.lockTimeout)
+	}
 
-func (i *Irdata) cacheClose() {
-	// call close no matter what
-	defer i.cask.Close()
+	if errors.Is(err, errCacheLocked) || errors.Is(err, bitcask.ErrDatabaseLocked) {
+		i.logger.WithFields(log.Fields{"cacheDir": cacheDir}).Warn("Cache is locked by another process, continuing without it")
 
-	log.Info("RunGC")
+		i.cache = lockedCache{}
+
+		return nil
+	}
 
-	err := i.cask.RunGC()
 	if err != nil {
-		log.WithField("err", err).Info("cask.RunGC failed")
+		return err
 	}
 
-	log.Info("Merging cache")
+	i.cache = backend
+
+	return nil
+}
+
+// openLockedBitcask opens the bitcask store in cacheDir, trying again for
+// up to timeout while another process holds it
+func (i *Irdata) openLockedBitcask(cacheDir string, timeout time.Duration) (CacheBackend, error) {
+	deadline := i.clock.Now().Add(timeout)
+
+	for {
+		backend, err := openBitcaskBackend(cacheDir, i.logger)
+		if err == nil {
+			return backend, nil
+		}
 
-	err = i.cask.Merge()
+		if !errors.Is(err, bitcask.ErrDatabaseLocked) || !i.clock.Now().Before(deadline) {
+			return nil, err
+		}
+
+		if err := i.clock.Sleep(i.ctx, cacheLockRetry); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (i *Irdata) cacheClose() error {
+	err := i.cache.Close()
 	if err != nil {
-		log.WithField("err", err).Info("cask.Merge failed")
+		i.logger.WithField("err", err).Info("Closing cache failed")
 	}
 
-	log.Info("Done")
+	i.cache = nil
+
+	return err
 }
 
 func hashKey(key string) hashedKey {
@@ -53,25 +125,365 @@ func hashKey(key string) hashedKey {
 	return hash[:]
 }
 
-func (i *Irdata) getCachedData(key string) ([]byte, error) {
-	data, err := i.cask.Get(hashKey(key))
+func (i *Irdata) getCachedData(ctx context.Context, key string) ([]byte, error) {
+	return i.cacheGet(ctx, hashKey(key))
+}
+
+func (i *Irdata) setCachedData(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return i.cachePut(ctx, hashKey(key), data, ttl)
+}
+
+func (i *Irdata) deleteCachedData(ctx context.Context, key string) error {
+	k := hashKey(key)
+	data, err := i.cacheGet(ctx, k)
+	if err != nil || data == nil {
+		return err
+	}
+
+	// dropping the index first makes the whole group a miss right away
+	if err := i.cacheDelete(ctx, k); err != nil {
+		return err
+	}
+
+	if meta := metaKeyOf(k); i.cacheHas(ctx, meta) {
+		if err := i.cacheDelete(ctx, meta); err != nil {
+			return err
+		}
+	}
+
+	if !bytes.HasPrefix(data, chunkIndexMarker) {
+		return nil
+	}
+
+	var index chunkIndexT
 
-	if errors.Is(err, bitcask.ErrKeyExpired) || errors.Is(err, bitcask.ErrKeyNotFound) {
+	if err := json.Unmarshal(data[len(chunkIndexMarker):], &index); err != nil {
+		return err
+	}
+
+	for n := range index.Chunks {
+		chunk := hashKey(chunkKey(key, index.ID, n))
+		if i.cacheHas(ctx, chunk) {
+			if err := i.cacheDelete(ctx, chunk); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func metaKey(key string) []byte {
+	return metaKeyOf(hashKey(key))
+}
+
+// metaKeyOf is metaKey of a key already hashed
+func metaKeyOf(k hashedKey) []byte {
+	meta := make([]byte, 0, len(cacheMetaPrefix)+len(k))
+
+	return append(append(meta, cacheMetaPrefix...), k...)
+}
+
+// setCacheMeta records what was just cached under key for ttl and kept
+// for keep
+func (i *Irdata) setCacheMeta(ctx context.Context, key string, meta cacheMetaT, ttl time.Duration, keep time.Duration) error {
+	meta.Key = key
+	meta.Created = i.clock.Now()
+	meta.Expires = meta.Created.Add(ttl)
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return i.cachePut(ctx, metaKey(key), data, keep)
+}
+
+// cachedMeta returns the metadata of the entry cached under key, nil if
+// there is none or it can't be read.  Only a backend that timed out or
+// whose context is done is an error, the entry can't be read either then.
+func (i *Irdata) cachedMeta(ctx context.Context, k hashedKey) (*cacheMetaT, error) {
+	data, err := i.cacheGet(ctx, metaKeyOf(k))
+	if errors.Is(err, ErrCacheTimeout) || ctx.Err() != nil {
+		return nil, err
+	}
+
+	if err != nil || data == nil {
+		return nil, nil
+	}
+
+	var meta cacheMetaT
+
+	if json.Unmarshal(data, &meta) != nil {
 		return nil, nil
 	}
 
-	return data, err
+	return &meta, nil
+}
+
+// servable reports whether the payload cached under key may be served and
+// whether it's stale, along with its metadata if there is any.  Payloads
+// are kept past their ttl for offline mode, only then and while
+// revalidating (see WithStaleWhileRevalidate) are they served once expired.
+func (i *Irdata) servable(ctx context.Context, k hashedKey) (meta *cacheMetaT, ok bool, stale bool, err error) {
+	meta, err = i.cachedMeta(ctx, k)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	if meta == nil {
+		// cached before the metadata was recorded, the backend expires it
+		return nil, true, false, nil
+	}
+
+	if i.clock.Now().After(meta.Expires) {
+		return meta, i.Offline() || i.revalidating(meta.Expires), true, nil
+	}
+
+	return meta, true, false, nil
 }
 
-func (i *Irdata) setCachedData(key string, data []byte, ttl time.Duration) error {
-	return i.cask.PutWithTTL(hashKey(key), data, ttl)
+func chunkKey(key string, id string, n int) string {
+	return key + "\x00chunk/" + id + "/" + strconv.Itoa(n)
 }
 
-func (i *Irdata) deleteCachedData(key string) error {
+// setCachedPayload caches p under key, storing chunks separately, with
+// reason in its metadata.  The ttl is jittered, see WithTTLJitter, and it's
+// kept for the stale retention past it, see WithStaleRetention.
+func (i *Irdata) setCachedPayload(ctx context.Context, key string, p *payload, ttl time.Duration, reason string) error {
+	if !p.isChunked() {
+		ttl = i.jitterTTL(ttl)
+		keep := ttl + i.staleKeep()
+
+		if err := i.setCachedData(ctx, key, p.data, keep); err != nil {
+			return err
+		}
+
+		return i.setCacheMeta(ctx, key, cacheMetaT{Size: len(p.data), Reason: reason, AsOf: p.asOf}, ttl, keep)
+	}
+
+	w, err := i.newChunkWriter(key, ttl)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range p.chunks {
+		if err := w.write(ctx, chunk); err != nil {
+			return err
+		}
+	}
+
+	return w.finish(ctx, reason, p.asOf)
+}
+
+// chunkWriterT caches a chunked result a chunk at a time, so it can be
+// cached as it's downloaded
+type chunkWriterT struct {
+	i     *Irdata
+	key   string
+	ttl   time.Duration
+	keep  time.Duration
+	index chunkIndexT
+	size  int
+}
+
+// newChunkWriter returns a writer caching a chunked result under key, see
+// setCachedPayload for the ttl
+func (i *Irdata) newChunkWriter(key string, ttl time.Duration) (*chunkWriterT, error) {
+	id := make([]byte, 8)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	ttl = i.jitterTTL(ttl)
+
+	return &chunkWriterT{
+		i:     i,
+		key:   key,
+		ttl:   ttl,
+		keep:  ttl + i.staleKeep(),
+		index: chunkIndexT{ID: hex.EncodeToString(id)},
+	}, nil
+}
+
+// write caches the next chunk
+func (w *chunkWriterT) write(ctx context.Context, chunk Chunk) error {
+	n := len(w.index.Chunks)
+
+	if err := w.i.setCachedData(ctx, chunkKey(w.key, w.index.ID, n), chunk.Data, w.keep+chunkTTLGrace); err != nil {
+		return err
+	}
+
+	w.index.Chunks = append(w.index.Chunks, chunk.FileName)
+	w.size += len(chunk.Data)
+
+	return nil
+}
+
+// finish caches the index of the chunks written, which makes them
+// readable, with reason and asOf in its metadata
+func (w *chunkWriterT) finish(ctx context.Context, reason string, asOf time.Time) error {
+	data, err := json.Marshal(w.index)
+	if err != nil {
+		return err
+	}
+
+	// written last so readers never find an index before its chunks
+	if err := w.i.setCachedData(ctx, w.key, append(append([]byte{}, chunkIndexMarker...), data...), w.keep); err != nil {
+		return err
+	}
+
+	return w.i.setCacheMeta(ctx, w.key, cacheMetaT{Size: w.size, ChunkID: w.index.ID, Chunks: len(w.index.Chunks), Reason: reason, AsOf: asOf}, w.ttl, w.keep)
+}
+
+// cachedEntryT is what lookupCached found cached under a key: the index
+// of a chunked result and the hashed keys of its chunks, or the data of a
+// result cached whole
+type cachedEntryT struct {
+	index     *chunkIndexT
+	chunkKeys []hashedKey
+	data      []byte
+
+	stale bool
+	asOf  time.Time
+}
+
+// lookupCached returns what's cached under key, nil on a miss.  An index
+// whose chunks aren't all present is a miss, as is an expired result unless
+// offline.  With shared the data is read without copying where the backend
+// allows, see CacheViewer, and must not be modified.
+func (i *Irdata) lookupCached(ctx context.Context, key string, shared bool) (*cachedEntryT, error) {
 	k := hashKey(key)
-	if i.cask.Has(k) {
-		return i.cask.Delete(k)
-	} else {
-		return nil
+
+	meta, ok, stale, err := i.servable(ctx, k)
+	if !ok {
+		return nil, err
+	}
+
+	limit := i.responseLimit(ctx)
+	_, uri := splitCacheKey(key)
+
+	if meta != nil {
+		if err := checkResponseSize(uri, int64(meta.Size), limit); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := i.cacheRead(ctx, k, shared)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	entry := &cachedEntryT{stale: stale}
+
+	if meta != nil {
+		entry.asOf = meta.AsOf
+	}
+
+	if !bytes.HasPrefix(data, chunkIndexMarker) {
+		if err := checkResponseSize(uri, int64(len(data)), limit); err != nil {
+			return nil, err
+		}
+
+		entry.data = data
+
+		return entry, nil
+	}
+
+	var index chunkIndexT
+
+	if err := json.Unmarshal(data[len(chunkIndexMarker):], &index); err != nil {
+		return nil, err
+	}
+
+	entry.index = &index
+	entry.chunkKeys = make([]hashedKey, len(index.Chunks))
+
+	for n := range index.Chunks {
+		entry.chunkKeys[n] = hashKey(chunkKey(key, index.ID, n))
+
+		if !i.cacheHas(ctx, entry.chunkKeys[n]) {
+			return nil, nil
+		}
+	}
+
+	return entry, nil
+}
+
+// getCachedIndex returns the index of the chunked result cached under key
+// or the data when the result was cached whole, see lookupCached
+func (i *Irdata) getCachedIndex(ctx context.Context, key string) (*chunkIndexT, []byte, error) {
+	entry, err := i.lookupCached(ctx, key, false)
+	if entry == nil {
+		return nil, nil, err
+	}
+
+	return entry.index, entry.data, nil
+}
+
+// getCachedPayload returns the result cached under key or nil
+func (i *Irdata) getCachedPayload(ctx context.Context, key string) (*payload, error) {
+	return i.cachedPayload(ctx, key, false)
+}
+
+// cachedPayload is getCachedPayload with shared as for lookupCached
+func (i *Irdata) cachedPayload(ctx context.Context, key string, shared bool) (*payload, error) {
+	entry, err := i.lookupCached(ctx, key, shared)
+	if entry == nil {
+		return nil, err
+	}
+
+	if entry.index == nil {
+		return &payload{data: entry.data, asOf: entry.asOf, stale: entry.stale}, nil
+	}
+
+	p := &payload{chunks: make([]Chunk, 0, len(entry.index.Chunks)), asOf: entry.asOf, stale: entry.stale}
+
+	limit := i.responseLimit(ctx)
+	_, uri := splitCacheKey(key)
+	size := int64(0)
+
+	for n, fileName := range entry.index.Chunks {
+		chunkData, err := i.cacheRead(ctx, entry.chunkKeys[n], shared)
+		if err != nil || chunkData == nil {
+			return nil, err
+		}
+
+		// the entries cached before their size was recorded are stopped
+		// once the chunks read so far are too large
+		size += int64(len(chunkData))
+
+		if err := checkResponseSize(uri, size, limit); err != nil {
+			return nil, err
+		}
+
+		p.chunks = append(p.chunks, Chunk{Number: n, FileName: fileName, Data: chunkData})
+	}
+
+	return p, nil
+}
+
+// getCachedJSON unmarshals the value stored under key into v, reporting
+// whether anything was found
+func (i *Irdata) getCachedJSON(ctx context.Context, key string, v interface{}) (bool, error) {
+	data, err := i.getCachedData(ctx, key)
+	if err != nil || data == nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, v)
+}
+
+func (i *Irdata) setCachedJSON(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
 	}
+
+	if err := i.setCachedData(ctx, key, data, ttl); err != nil {
+		return err
+	}
+
+	return i.setCacheMeta(ctx, key, cacheMetaT{Size: len(data)}, ttl, ttl)
 }
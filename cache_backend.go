@@ -0,0 +1,335 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"git.mills.io/prologic/bitcask"
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheBackend stores the entries of the caching layer.  Keys are opaque
+// byte strings.
+//
+// EnableCache uses a bitcask backend on disk, or a file per entry with
+// WithSharedCache, and EnableCacheBackend accepts any implementation.
+//
+// The operations get the context of the request they're made for and
+// should give up with its error once it's done, see WithCacheTimeout.
+type CacheBackend interface {
+	// Get returns the value stored under key or nil if there is none or
+	// it has expired
+	Get(ctx context.Context, key []byte) ([]byte, error)
+
+	// Has reports whether an unexpired value is stored under key without
+	// reading it
+	Has(ctx context.Context, key []byte) bool
+
+	// PutWithTTL stores value under key for ttl
+	PutWithTTL(ctx context.Context, key []byte, value []byte, ttl time.Duration) error
+
+	// Delete removes key
+	Delete(ctx context.Context, key []byte) error
+
+	// Close releases the backend
+	Close() error
+}
+
+// CacheEnumerator is implemented by backends able to list their keys.  It
+// is optional, CacheEntries needs it but nothing else does.
+type CacheEnumerator interface {
+	// Keys returns every unexpired key without reading the values
+	Keys(ctx context.Context) ([][]byte, error)
+}
+
+// CacheViewer is implemented by backends able to hand out the values they
+// store without copying them.  It is optional, ViewWithCache reads through
+// it when the backend is one.
+type CacheViewer interface {
+	// Peek is Get returning the stored value itself, which neither the
+	// backend nor the caller may modify
+	Peek(ctx context.Context, key []byte) ([]byte, error)
+}
+
+type bitcaskBackend struct {
+	cask   *bitcask.Bitcask
+	logger *log.Logger
+}
+
+func openBitcaskBackend(cacheDir string, logger *log.Logger) (*bitcaskBackend, error) {
+	cask, err := bitcask.Open(
+		cacheDir,
+		bitcask.WithMaxValueSize(_maxValueSize),
+		bitcask.WithMaxKeySize(_maxKeySize),
+		bitcask.WithSync(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bitcaskBackend{cask: cask, logger: logger}, nil
+}
+
+// the bitcask operations can't be interrupted, ctx is checked before and
+// after each so a slow disk doesn't go unnoticed
+
+func (b *bitcaskBackend) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := b.cask.Get(key)
+
+	if errors.Is(err, bitcask.ErrKeyExpired) || errors.Is(err, bitcask.ErrKeyNotFound) {
+		data, err = nil, nil
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (b *bitcaskBackend) Has(ctx context.Context, key []byte) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	return b.cask.Has(key) && ctx.Err() == nil
+}
+
+func (b *bitcaskBackend) PutWithTTL(ctx context.Context, key []byte, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := b.cask.PutWithTTL(key, value, ttl); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (b *bitcaskBackend) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := b.cask.Delete(key); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (b *bitcaskBackend) Keys(ctx context.Context) ([][]byte, error) {
+	var keys [][]byte
+
+	for key := range b.cask.Keys() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, append([]byte{}, key...))
+	}
+
+	return keys, nil
+}
+
+// Close compacts the cache before closing it
+func (b *bitcaskBackend) Close() error {
+	// call close no matter what
+	defer b.cask.Close()
+
+	b.logger.Info("RunGC")
+
+	err := b.cask.RunGC()
+	if err != nil {
+		b.logger.WithField("err", err).Info("cask.RunGC failed")
+	}
+
+	b.logger.Info("Merging cache")
+
+	err = b.cask.Merge()
+	if err != nil {
+		b.logger.WithField("err", err).Info("cask.Merge failed")
+	}
+
+	b.logger.Info("Done")
+
+	return nil
+}
+
+// MemoryCache is a CacheBackend that keeps entries in memory.  It is handy
+// for tests and for short lived processes that don't want files around.
+type MemoryCache struct {
+	mu      sync.Mutex
+	clock   Clock
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return NewMemoryCacheWithClock(realClock{})
+}
+
+// NewMemoryCacheWithClock returns an empty MemoryCache expiring its entries
+// by clock
+func NewMemoryCacheWithClock(clock Clock) *MemoryCache {
+	return &MemoryCache{clock: clock, entries: make(map[string]memoryEntry)}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.live(key)
+	if !ok {
+		return nil, nil
+	}
+
+	value := make([]byte, len(entry.value))
+	copy(value, entry.value)
+
+	return value, nil
+}
+
+// Peek is Get without the copy, the values are never modified once stored
+func (m *MemoryCache) Peek(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.live(key)
+	if !ok {
+		return nil, nil
+	}
+
+	return entry.value, nil
+}
+
+func (m *MemoryCache) Has(ctx context.Context, key []byte) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.live(key)
+
+	return ok
+}
+
+func (m *MemoryCache) PutWithTTL(ctx context.Context, key []byte, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := make([]byte, len(value))
+	copy(stored, value)
+
+	m.entries[string(key)] = memoryEntry{value: stored, expires: m.clock.Now().Add(ttl)}
+
+	return nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, string(key))
+
+	return nil
+}
+
+func (m *MemoryCache) Keys(ctx context.Context) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys [][]byte
+
+	for key := range m.entries {
+		if _, ok := m.live([]byte(key)); ok {
+			keys = append(keys, []byte(key))
+		}
+	}
+
+	return keys, nil
+}
+
+// Close is a no-op, entries survive so the cache can be shared between
+// instances
+func (m *MemoryCache) Close() error {
+	return nil
+}
+
+// live returns the unexpired entry for key, dropping it if expired
+func (m *MemoryCache) live(key []byte) (memoryEntry, bool) {
+	entry, ok := m.entries[string(key)]
+	if !ok {
+		return entry, false
+	}
+
+	if m.clock.Now().After(entry.expires) {
+		delete(m.entries, string(key))
+		return entry, false
+	}
+
+	return entry, true
+}
+
+// lockedCache stands in for a cache held by another process: nothing is
+// ever found and writes are dropped, so the caching calls keep working
+// without it
+type lockedCache struct{}
+
+func (lockedCache) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (lockedCache) Has(ctx context.Context, key []byte) bool {
+	return false
+}
+
+func (lockedCache) PutWithTTL(ctx context.Context, key []byte, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (lockedCache) Delete(ctx context.Context, key []byte) error {
+	return nil
+}
+
+func (lockedCache) Close() error {
+	return nil
+}
@@ -0,0 +1,115 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache()
+
+	key := []byte("key")
+
+	data, err := cache.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	assert.False(t, cache.Has(context.Background(), key))
+
+	value := []byte(testDataString1)
+
+	assert.NoError(t, cache.PutWithTTL(context.Background(), key, value, testTtl))
+
+	// stored values are copies
+	value[0] = 'X'
+
+	data, err = cache.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(testDataString1), data)
+	assert.True(t, cache.Has(context.Background(), key))
+
+	assert.NoError(t, cache.Delete(context.Background(), key))
+	assert.False(t, cache.Has(context.Background(), key))
+}
+
+func TestMemoryCachePeek(t *testing.T) {
+	cache := NewMemoryCache()
+
+	key := []byte("key")
+
+	data, err := cache.Peek(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	assert.NoError(t, cache.PutWithTTL(context.Background(), key, []byte(testDataString1), testTtl))
+
+	first, err := cache.Peek(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(testDataString1), first)
+
+	// the stored value itself, not a copy
+	second, _ := cache.Peek(context.Background(), key)
+	assert.Same(t, &first[0], &second[0])
+
+	var _ CacheViewer = cache
+}
+
+func TestMemoryCacheTtl(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewMemoryCacheWithClock(clock)
+
+	key := []byte("key")
+
+	assert.NoError(t, cache.PutWithTTL(context.Background(), key, []byte(testDataString1), time.Minute))
+	assert.True(t, cache.Has(context.Background(), key))
+
+	clock.advance(time.Minute + time.Second)
+
+	data, err := cache.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	assert.False(t, cache.Has(context.Background(), key))
+}
+
+func TestBackendsHonorContext(t *testing.T) {
+	for name, open := range map[string]func(t *testing.T) CacheBackend{
+		"memory": func(t *testing.T) CacheBackend { return NewMemoryCache() },
+		"bitcask": func(t *testing.T) CacheBackend {
+			backend, err := openBitcaskBackend(t.TempDir(), log.New())
+			assert.NoError(t, err)
+			return backend
+		},
+		"files": func(t *testing.T) CacheBackend {
+			backend, err := openFileBackend(t.TempDir(), realClock{}, time.Second, log.New())
+			assert.NoError(t, err)
+			return backend
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache := open(t)
+			defer cache.Close()
+
+			key := []byte("key")
+
+			assert.NoError(t, cache.PutWithTTL(context.Background(), key, []byte(testDataString1), time.Minute))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			data, err := cache.Get(ctx, key)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Nil(t, data)
+			assert.False(t, cache.Has(ctx, key))
+			assert.ErrorIs(t, cache.PutWithTTL(ctx, key, []byte(testDataString2), time.Minute), context.Canceled)
+			assert.ErrorIs(t, cache.Delete(ctx, key), context.Canceled)
+
+			// nothing was changed
+			data, err = cache.Get(context.Background(), key)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(testDataString1), data)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// CacheEntryInfo describes an entry of the cache
+type CacheEntryInfo struct {
+	// Key is the hex encoded key the entry is stored under
+	Key string
+
+	// URI is the uri (or other key) the entry was cached for.  It is empty
+	// for entries cached before this was recorded, as are the fields below.
+	URI string
+
+	// Namespace is the account namespace of account dependent responses,
+	// empty for shared ones
+	Namespace string
+
+	// Size is the size of the value in bytes, of all its chunks if chunked
+	Size    int
+	Chunks  int
+	Created time.Time
+	Expires time.Time
+
+	// Reason is why the entry was cached for as long as it is, for entries
+	// whose ttl was chosen from their content (see
+	// GetSubsessionResultWithCache), or what the cache filter did to it (see
+	// SetCacheFilter)
+	Reason string
+
+	// AsOf is when iRacing produced the cached response, from the
+	// Last-Modified of the s3 object or the Date of the API response
+	AsOf time.Time
+
+	// Stale is set for entries past Expires, which are only kept to be
+	// served while offline (see WithStaleRetention)
+	Stale bool
+}
+
+// CacheEntries lists the entries in the cache, sorted by URI.  Chunks are
+// counted with the entry they belong to rather than listed.  Only metadata
+// is read, never values.
+//
+// The cache backend must implement CacheEnumerator.
+func (i *Irdata) CacheEntries() ([]CacheEntryInfo, error) {
+	if i.cache == nil {
+		return nil, errors.New("cache must be enabled")
+	}
+
+	enumerator, ok := i.cache.(CacheEnumerator)
+	if !ok {
+		return nil, errors.New("cache backend can't list its keys")
+	}
+
+	keys, err := enumerator.Keys(i.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntryInfo
+
+	// everything described by a metadata record
+	described := make(map[string]bool)
+
+	for _, key := range keys {
+		if !bytes.HasPrefix(key, cacheMetaPrefix) {
+			continue
+		}
+
+		data, err := i.cacheGet(i.ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		var meta cacheMetaT
+
+		if data == nil || json.Unmarshal(data, &meta) != nil {
+			continue
+		}
+
+		hashed := hashKey(meta.Key)
+		described[string(hashed)] = true
+
+		for n := 0; n < meta.Chunks; n++ {
+			described[string(hashKey(chunkKey(meta.Key, meta.ChunkID, n)))] = true
+		}
+
+		namespace, uri := splitCacheKey(meta.Key)
+
+		entries = append(entries, CacheEntryInfo{
+			Key:       hex.EncodeToString(hashed),
+			URI:       uri,
+			Namespace: namespace,
+			Size:      meta.Size,
+			Chunks:    meta.Chunks,
+			Created:   meta.Created,
+			Expires:   meta.Expires,
+			Reason:    meta.Reason,
+			AsOf:      meta.AsOf,
+			Stale:     i.clock.Now().After(meta.Expires),
+		})
+	}
+
+	for _, key := range keys {
+		if bytes.HasPrefix(key, cacheMetaPrefix) || described[string(key)] {
+			continue
+		}
+
+		entries = append(entries, CacheEntryInfo{Key: hex.EncodeToString(key)})
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		if entries[a].URI != entries[b].URI {
+			return entries[a].URI < entries[b].URI
+		}
+
+		if entries[a].Namespace != entries[b].Namespace {
+			return entries[a].Namespace < entries[b].Namespace
+		}
+
+		return entries[a].Key < entries[b].Key
+	})
+
+	return entries, nil
+}
+
+// PurgeCache removes whatever this instance has cached for uri, chunks
+// included.  For account dependent responses that is the entry of its own
+// namespace, see PurgeCacheNamespace for the others.
+func (i *Irdata) PurgeCache(uri string) error {
+	if i.cache == nil {
+		return errors.New("cache must be enabled")
+	}
+
+	return i.deleteCachedData(i.ctx, i.cacheKey(uri))
+}
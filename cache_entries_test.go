@@ -0,0 +1,89 @@
+package irdata
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// plainCache is a backend without the optional capabilities
+type plainCache struct {
+	CacheBackend
+}
+
+func TestCacheEntries(t *testing.T) {
+	for name, enable := range map[string]func(*Irdata){
+		"memory":  func(i *Irdata) { i.EnableCacheBackend(NewMemoryCache()) },
+		"bitcask": func(i *Irdata) { assert.NoError(t, i.EnableCache(t.TempDir())) },
+		"shared":  func(i *Irdata) { assert.NoError(t, i.EnableCache(t.TempDir(), WithSharedCache())) },
+	} {
+		enable := enable
+
+		t.Run(name, func(t *testing.T) {
+			m := newMockAPI(t)
+			m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2},{"id":3}]`))
+			m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+
+			api := m.openAuthed(t)
+			enable(api)
+			defer api.Close()
+
+			before := time.Now()
+
+			_, err := api.GetWithCache("/data/results/search_series", time.Hour)
+			assert.NoError(t, err)
+
+			_, err = api.GetWithCache("/data/constants/categories", time.Minute)
+			assert.NoError(t, err)
+
+			// as written before metadata was recorded
+			assert.NoError(t, api.setCachedData(context.Background(), "legacy", []byte(testDataString1), time.Hour))
+
+			entries, err := api.CacheEntries()
+			assert.NoError(t, err)
+			assert.Len(t, entries, 3)
+
+			assert.Equal(t, CacheEntryInfo{Key: hex.EncodeToString(hashKey("legacy"))}, entries[0])
+
+			categories := entries[1]
+			assert.Equal(t, "/data/constants/categories", categories.URI)
+			assert.Equal(t, hex.EncodeToString(hashKey(categories.URI)), categories.Key)
+			assert.Equal(t, len(`[{"label":"Oval","value":1}]`), categories.Size)
+			assert.Equal(t, 0, categories.Chunks)
+			assert.False(t, categories.Created.Before(before))
+			assert.Equal(t, time.Minute, categories.Expires.Sub(categories.Created))
+
+			search := entries[2]
+			assert.Equal(t, "/data/results/search_series", search.URI)
+			assert.Equal(t, len(`[{"id":1}]`)+len(`[{"id":2},{"id":3}]`), search.Size)
+			assert.Equal(t, 2, search.Chunks)
+			assert.Equal(t, time.Hour, search.Expires.Sub(search.Created))
+
+			assert.NoError(t, api.PurgeCache("/data/results/search_series"))
+
+			entries, err = api.CacheEntries()
+			assert.NoError(t, err)
+			assert.Len(t, entries, 2)
+
+			// purged for real
+			_, err = api.GetWithCache("/data/results/search_series", time.Hour)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, m.hitCount("/data/results/search_series"))
+		})
+	}
+}
+
+func TestCacheEntriesNeedsEnumerator(t *testing.T) {
+	api := Open(context.Background())
+
+	_, err := api.CacheEntries()
+	assert.Error(t, err)
+
+	api.EnableCacheBackend(plainCache{NewMemoryCache()})
+
+	_, err = api.CacheEntries()
+	assert.Error(t, err)
+}
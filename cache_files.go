@@ -0,0 +1,343 @@
+package irdata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultSharedCacheLockTimeout is how long a shared cache waits for the
+// lock held by another process before giving up on a write
+const defaultSharedCacheLockTimeout = 5 * time.Second
+
+// cacheLockRetry is how often a held cache lock is tried again
+const cacheLockRetry = 10 * time.Millisecond
+
+// sharedCacheLockFile is the lock file of a shared cache directory, held
+// around every change to it
+const sharedCacheLockFile = ".lock"
+
+// errCacheLocked is returned when the lock of a cache wasn't released in
+// time by whoever holds it
+var errCacheLocked = errors.New("cache is locked by another process")
+
+// CacheOption adjusts the cache opened by EnableCache
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	shared      bool
+	lockTimeout time.Duration
+}
+
+// WithSharedCache makes EnableCache keep an entry per file in the
+// directory instead of a bitcask store, so processes running at once can
+// share it.  Changes are made under an advisory lock on the directory and
+// entries are replaced whole, readers never see half an entry.
+func WithSharedCache() CacheOption {
+	return func(o *cacheOptions) {
+		o.shared = true
+	}
+}
+
+// WithCacheLockTimeout sets how long EnableCache waits for the lock another
+// process holds on the cache, and a shared cache waits for it for every
+// write.  A bitcask cache doesn't wait by default, a shared cache waits 5
+// seconds.
+func WithCacheLockTimeout(d time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.lockTimeout = d
+	}
+}
+
+// fileBackend is the CacheBackend of WithSharedCache.  Each entry is a file
+// named after its key holding when it expires followed by the value.
+type fileBackend struct {
+	dir     string
+	clock   Clock
+	timeout time.Duration
+	logger  *log.Logger
+
+	// mu serializes the instance's own changes, the file lock is per
+	// process
+	mu   sync.Mutex
+	lock *flock.Flock
+}
+
+func openFileBackend(dir string, clock Clock, timeout time.Duration, logger *log.Logger) (*fileBackend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	b := &fileBackend{
+		dir:     dir,
+		clock:   clock,
+		timeout: timeout,
+		logger:  logger,
+		lock:    flock.New(filepath.Join(dir, sharedCacheLockFile)),
+	}
+
+	// taking the lock once tells a directory that can't be locked apart
+	// from one that's merely busy
+	unlock, err := b.acquire(context.Background())
+	if err != nil {
+		b.lock.Close()
+		return nil, err
+	}
+
+	unlock()
+
+	return b, nil
+}
+
+// acquire takes the lock of the directory, waiting for up to timeout or
+// until ctx is done
+func (b *fileBackend) acquire(ctx context.Context) (func(), error) {
+	b.mu.Lock()
+
+	lockCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	locked, err := b.lock.TryLockContext(lockCtx, cacheLockRetry)
+	if !locked {
+		b.mu.Unlock()
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		} else if err == nil || errors.Is(err, context.DeadlineExceeded) {
+			err = errCacheLocked
+		}
+
+		return nil, err
+	}
+
+	return func() {
+		if err := b.lock.Unlock(); err != nil {
+			b.logger.WithFields(log.Fields{"err": err}).Warn("Unlocking cache failed")
+		}
+
+		b.mu.Unlock()
+	}, nil
+}
+
+func (b *fileBackend) path(key []byte) string {
+	return filepath.Join(b.dir, hex.EncodeToString(key))
+}
+
+// read returns the value of the entry at path and when it expires, nil if
+// there is no entry
+func (b *fileBackend) read(path string, withValue bool) ([]byte, time.Time, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, time.Time{}, nil
+	}
+
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	defer f.Close()
+
+	var header [8]byte
+
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		// entries are renamed into place whole so this isn't one
+		b.logger.WithFields(log.Fields{"path": path, "err": err}).Warn("Ignoring unreadable cache entry")
+
+		return nil, time.Time{}, nil
+	}
+
+	expires := time.Unix(0, int64(binary.BigEndian.Uint64(header[:])))
+
+	if !withValue {
+		return header[:], expires, nil
+	}
+
+	value, err := io.ReadAll(f)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return value, expires, nil
+}
+
+// the file operations can't be interrupted, ctx is checked before and
+// after them
+
+func (b *fileBackend) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	value, expires, err := b.read(b.path(key), true)
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	if err != nil || value == nil || b.clock.Now().After(expires) {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (b *fileBackend) Has(ctx context.Context, key []byte) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	header, expires, err := b.read(b.path(key), false)
+
+	return err == nil && header != nil && !b.clock.Now().After(expires) && ctx.Err() == nil
+}
+
+// PutWithTTL writes the entry to a temporary file and renames it over the
+// old one under the lock
+func (b *fileBackend) PutWithTTL(ctx context.Context, key []byte, value []byte, ttl time.Duration) error {
+	unlock, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	var suffix [8]byte
+
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(b.dir, ".tmp-"+hex.EncodeToString(suffix[:]))
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+
+	var header [8]byte
+
+	binary.BigEndian.PutUint64(header[:], uint64(b.clock.Now().Add(ttl).UnixNano()))
+
+	_, err = f.Write(header[:])
+	if err == nil {
+		_, err = f.Write(value)
+	}
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, b.path(key))
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (b *fileBackend) Delete(ctx context.Context, key []byte) error {
+	unlock, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if err := os.Remove(b.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (b *fileBackend) Keys(ctx context.Context) ([][]byte, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]byte
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		key, err := hex.DecodeString(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if b.Has(ctx, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Close removes the expired entries, if the lock is free, and releases the
+// directory
+func (b *fileBackend) Close() error {
+	defer b.lock.Close()
+
+	unlock, err := b.acquire(context.Background())
+	if err != nil {
+		b.logger.WithFields(log.Fields{"err": err}).Info("Skipping cache cleanup")
+		return nil
+	}
+
+	defer unlock()
+
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+
+	now := b.clock.Now()
+
+	for _, entry := range entries {
+		path := filepath.Join(b.dir, entry.Name())
+
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			// left behind by a process that died mid write
+			if info, err := entry.Info(); err == nil && now.Sub(info.ModTime()) > time.Hour {
+				os.Remove(path)
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if _, expires, err := b.read(path, false); err == nil && now.After(expires) {
+			os.Remove(path)
+		}
+	}
+
+	return nil
+}
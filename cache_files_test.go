@@ -0,0 +1,241 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/flock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileBackend(t *testing.T) {
+	clock := newFakeClock()
+
+	cache, err := openFileBackend(t.TempDir(), clock, time.Second, log.New())
+	assert.NoError(t, err)
+
+	defer cache.Close()
+
+	key := hashKey("/data/constants/categories")
+
+	data, err := cache.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	assert.False(t, cache.Has(context.Background(), key))
+
+	assert.NoError(t, cache.PutWithTTL(context.Background(), key, []byte(testDataString1), time.Minute))
+
+	data, err = cache.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(testDataString1), data)
+	assert.True(t, cache.Has(context.Background(), key))
+
+	keys, err := cache.Keys(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{key}, keys)
+
+	clock.advance(time.Minute + time.Second)
+
+	data, err = cache.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	assert.False(t, cache.Has(context.Background(), key))
+
+	assert.NoError(t, cache.PutWithTTL(context.Background(), key, []byte(testDataString2), time.Minute))
+	assert.NoError(t, cache.Delete(context.Background(), key))
+	assert.False(t, cache.Has(context.Background(), key))
+
+	// deleting what isn't there is fine
+	assert.NoError(t, cache.Delete(context.Background(), key))
+}
+
+func TestFileBackendCloseRemovesExpired(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+
+	cache, err := openFileBackend(dir, clock, time.Second, log.New())
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.PutWithTTL(context.Background(), []byte("short"), []byte("1"), time.Minute))
+	assert.NoError(t, cache.PutWithTTL(context.Background(), []byte("long"), []byte("2"), time.Hour))
+
+	clock.advance(2 * time.Minute)
+
+	assert.NoError(t, cache.Close())
+
+	_, err = os.Stat(cache.path([]byte("short")))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = os.Stat(cache.path([]byte("long")))
+	assert.NoError(t, err)
+}
+
+// TestSharedCacheInstances has instances sharing a cache directory, as
+// processes running at once would, fetch and write the same entries
+func TestSharedCacheInstances(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2},{"id":3}]`))
+
+	for n := 0; n < 4; n++ {
+		m.handleJSON(fmt.Sprintf("/data/series/%d", n), fmt.Sprintf(`{"series":%d}`, n))
+	}
+
+	dir := t.TempDir()
+
+	const instances = 8
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < instances; n++ {
+		api := m.openAuthed(t)
+		assert.NoError(t, api.EnableCache(dir, WithSharedCache()))
+		assert.NotNil(t, api.cache)
+
+		wg.Add(1)
+
+		go func(api *Irdata) {
+			defer wg.Done()
+			defer api.Close()
+
+			for round := 0; round < 5; round++ {
+				data, err := api.GetWithCache("/data/results/search_series", time.Hour)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `[{"id":1},{"id":2},{"id":3}]`, string(data))
+
+				for s := 0; s < 4; s++ {
+					data, err := api.GetWithCache(fmt.Sprintf("/data/series/%d", s), time.Hour)
+					assert.NoError(t, err)
+					assert.JSONEq(t, fmt.Sprintf(`{"series":%d}`, s), string(data))
+				}
+
+				// rewrites race with the readers of the other instances
+				assert.NoError(t, api.setCachedData(context.Background(), "/data/series/0", []byte(`{"series":0}`), time.Hour))
+			}
+		}(api)
+	}
+
+	wg.Wait()
+
+	// each instance fetches at most once, the others read what it cached
+	for s := 0; s < 4; s++ {
+		assert.LessOrEqual(t, m.hitCount(fmt.Sprintf("/data/series/%d", s)), instances)
+	}
+
+	// a fresh instance reads what was left without fetching
+	hits := m.hitCount("/data/results/search_series")
+
+	api := m.openAuthed(t)
+	assert.NoError(t, api.EnableCache(dir, WithSharedCache()))
+
+	data, err := api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1},{"id":2},{"id":3}]`, string(data))
+	assert.Equal(t, hits, m.hitCount("/data/results/search_series"))
+
+	assert.NoError(t, api.Close())
+}
+
+// TestSharedCacheSameKey writes large values under one key from several
+// backends at once, every read must be one of them whole
+func TestSharedCacheSameKey(t *testing.T) {
+	dir := t.TempDir()
+
+	key := hashKey("/data/results/search_series")
+
+	values := make([][]byte, 4)
+	for n := range values {
+		values[n] = bytes.Repeat([]byte{byte('a' + n)}, 256*1024)
+	}
+
+	var wg sync.WaitGroup
+
+	for n := range values {
+		cache, err := openFileBackend(dir, realClock{}, 5*time.Second, log.New())
+		assert.NoError(t, err)
+
+		wg.Add(1)
+
+		go func(cache *fileBackend, value []byte) {
+			defer wg.Done()
+			defer cache.Close()
+
+			for round := 0; round < 10; round++ {
+				assert.NoError(t, cache.PutWithTTL(context.Background(), key, value, time.Hour))
+
+				data, err := cache.Get(context.Background(), key)
+				assert.NoError(t, err)
+				assert.Contains(t, values, data)
+			}
+		}(cache, values[n])
+	}
+
+	wg.Wait()
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	// no temporary files left behind
+	assert.ElementsMatch(t, []string{sharedCacheLockFile, fmt.Sprintf("%x", key)}, names)
+}
+
+func TestCacheLockedDegrades(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"value":1}]`)
+
+	t.Run("bitcask", func(t *testing.T) {
+		dir := t.TempDir()
+
+		holder := m.openAuthed(t)
+		assert.NoError(t, holder.EnableCache(dir))
+
+		defer holder.Close()
+
+		out, logTo := captureLog(t, log.WarnLevel)
+
+		api := m.openAuthed(t, logTo)
+		assert.NoError(t, api.EnableCache(dir, WithCacheLockTimeout(50*time.Millisecond)))
+		assert.Equal(t, lockedCache{}, api.cache)
+		assert.Contains(t, out.String(), "Cache is locked by another process")
+
+		// every call fetches
+		for n := 0; n < 2; n++ {
+			_, err := api.GetWithCache("/data/constants/categories", time.Hour)
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, m.hitCount("/data/constants/categories"))
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		dir := t.TempDir()
+
+		lock := flock.New(filepath.Join(dir, sharedCacheLockFile))
+		assert.NoError(t, lock.Lock())
+
+		out, logTo := captureLog(t, log.WarnLevel)
+
+		api := m.openAuthed(t, logTo)
+		assert.NoError(t, api.EnableCache(dir, WithSharedCache(), WithCacheLockTimeout(50*time.Millisecond)))
+		assert.Equal(t, lockedCache{}, api.cache)
+		assert.Contains(t, out.String(), "Cache is locked by another process")
+
+		// released meanwhile
+		assert.NoError(t, lock.Unlock())
+
+		assert.NoError(t, api.EnableCache(dir, WithSharedCache()))
+		assert.IsType(t, &fileBackend{}, api.cache)
+		assert.NoError(t, api.Close())
+	})
+}
@@ -0,0 +1,206 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheDecision is what a cache filter decided for a response, see
+// SetCacheFilter.  The zero value caches the response as it is.
+type CacheDecision struct {
+	// Skip leaves the response out of the cache
+	Skip bool
+
+	// Payload, if not nil, is cached instead of the response
+	Payload []byte
+
+	// TTL, if positive, caches the response for at most TTL
+	TTL time.Duration
+
+	// Reason is recorded in the Reason of the cache entry
+	Reason string
+}
+
+// CacheFilter decides how the response for uri is cached, see
+// SetCacheFilter
+type CacheFilter func(uri string, payload []byte) CacheDecision
+
+// SetCacheFilter has filter decide how the responses of GetWithCache (and
+// the cached getters built on it) and GetChunksWithCache are cached, nil
+// removes it.  It's called with the response as returned, after links are
+// followed and chunks merged, before it's encrypted and compressed for the
+// backend.  What the caller gets back is never rewritten, only what's
+// stored.
+//
+// Decisions are counted in CacheStats and recorded in the Reason of the
+// cache entry, see CacheEntries.
+func (i *Irdata) SetCacheFilter(filter CacheFilter) {
+	i.cacheFilter.Store(&filter)
+}
+
+// filterCached runs the cache filter on p, returning what to cache for how
+// long and why, nil if nothing is to be cached
+// hasCacheFilter reports whether a cache filter is set
+func (i *Irdata) hasCacheFilter() bool {
+	stored, _ := i.cacheFilter.Load().(*CacheFilter)
+
+	return stored != nil && *stored != nil
+}
+
+func (i *Irdata) filterCached(uri string, p *payload, ttl time.Duration) (*payload, time.Duration, string, error) {
+	stored, _ := i.cacheFilter.Load().(*CacheFilter)
+	if stored == nil || *stored == nil {
+		return p, ttl, "", nil
+	}
+
+	data, err := p.assemble()
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	decision := (*stored)(uri, data)
+
+	fields := log.Fields{"uri": uri, "reason": decision.Reason}
+
+	if decision.Skip {
+		atomic.AddInt64(&i.cacheFiltered.skipped, 1)
+		i.logger.WithFields(fields).Debug("Cache filter skipped response")
+
+		return nil, 0, "", nil
+	}
+
+	reason := decision.Reason
+
+	if decision.Payload != nil {
+		atomic.AddInt64(&i.cacheFiltered.rewritten, 1)
+		i.logger.WithFields(fields).Debug("Cache filter rewrote response")
+
+		p = &payload{data: decision.Payload, asOf: p.asOf, contentType: p.contentType}
+
+		if reason == "" {
+			reason = "rewritten by cache filter"
+		}
+	}
+
+	if decision.TTL > 0 && (ttl <= 0 || decision.TTL < ttl) {
+		atomic.AddInt64(&i.cacheFiltered.shortened, 1)
+		i.logger.WithFields(fields).WithField("ttl", decision.TTL).Debug("Cache filter shortened ttl")
+
+		ttl = decision.TTL
+
+		if reason == "" {
+			reason = "ttl shortened by cache filter"
+		}
+	}
+
+	return p, ttl, reason, nil
+}
+
+// cacheFilteredT counts the decisions of the cache filter atomically, see
+// CacheStats
+type cacheFilteredT struct {
+	skipped   int64
+	rewritten int64
+	shortened int64
+}
+
+// signedURLFields are the fields of iRacing responses known to hold signed
+// s3 links
+var signedURLFields = map[string]bool{
+	"link":              true,
+	"base_download_url": true,
+	"download_url":      true,
+	"data_url":          true,
+}
+
+// StripSignedURLs is a cache filter removing the fields known to hold
+// signed links from JSON responses, as those stop working long before most
+// responses expire and shouldn't sit in a shared cache.  Only the fields
+// whose value is signed are removed, responses without any are cached as
+// they are.
+func StripSignedURLs(uri string, data []byte) CacheDecision {
+	if !bytes.Contains(data, []byte("Signature")) {
+		return CacheDecision{}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+
+	if dec.Decode(&v) != nil {
+		return CacheDecision{}
+	}
+
+	if !stripSignedURLs(v) {
+		return CacheDecision{}
+	}
+
+	var buf bytes.Buffer
+
+	if writeCanonical(&buf, v) != nil {
+		return CacheDecision{Skip: true, Reason: "signed links"}
+	}
+
+	return CacheDecision{Payload: buf.Bytes(), Reason: "signed links stripped"}
+}
+
+// stripSignedURLs deletes the signed link fields from v, reporting whether
+// there were any
+func stripSignedURLs(v interface{}) bool {
+	stripped := false
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && signedURLFields[key] && isSignedURL(s) {
+				delete(v, key)
+				stripped = true
+
+				continue
+			}
+
+			stripped = stripSignedURLs(value) || stripped
+		}
+	case []interface{}:
+		for _, value := range v {
+			stripped = stripSignedURLs(value) || stripped
+		}
+	}
+
+	return stripped
+}
+
+// isSignedURL reports whether s is a link carrying an s3 or CloudFront
+// signature
+func isSignedURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for param := range u.Query() {
+		if strings.EqualFold(param, "X-Amz-Signature") || param == "Signature" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// storeCached caches p fetched for uri under key, through the cache filter
+func (i *Irdata) storeCached(ctx context.Context, uri string, key string, p *payload, ttl time.Duration) error {
+	p, ttl, reason, err := i.filterCached(uri, p, ttl)
+	if err != nil || p == nil {
+		return err
+	}
+
+	return i.setCachedPayload(ctx, key, p, ttl, reason)
+}
@@ -0,0 +1,102 @@
+package irdata
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const signedLinksJSON = `{"files":[{"name":"a","download_url":"https://example-bucket.s3.amazonaws.com/a.json?X-Amz-Expires=900&X-Amz-Signature=abc"},` +
+	`{"name":"b","download_url":"https://members-ng.iracing.com/b.json"}],"count":2}`
+
+func TestStripSignedURLs(t *testing.T) {
+	decision := StripSignedURLs("/data/x", []byte(signedLinksJSON))
+
+	assert.False(t, decision.Skip)
+	assert.Equal(t, `{"count":2,"files":[{"name":"a"},{"download_url":"https://members-ng.iracing.com/b.json","name":"b"}]}`, string(decision.Payload))
+	assert.NotEmpty(t, decision.Reason)
+
+	// nothing signed, nothing to rewrite
+	assert.Equal(t, CacheDecision{}, StripSignedURLs("/data/x", []byte(`{"link":"https://example.com/?Signature-less"}`)))
+	assert.Equal(t, CacheDecision{}, StripSignedURLs("/data/x", []byte("a,b\nSignature,1\n")))
+}
+
+func TestCacheFilter(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/files", signedLinksJSON)
+	m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2}]`))
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	var filtered []string
+
+	api.SetCacheFilter(func(uri string, payload []byte) CacheDecision {
+		filtered = append(filtered, string(payload))
+
+		switch {
+		case strings.HasPrefix(uri, "/data/constants"):
+			return CacheDecision{Skip: true}
+		case strings.HasPrefix(uri, "/data/results"):
+			return CacheDecision{TTL: time.Minute, Reason: "short lived"}
+		}
+
+		return StripSignedURLs(uri, payload)
+	})
+
+	// the caller gets the response as it is, the cache the rewrite
+	data, err := api.GetWithCache("/data/files", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, signedLinksJSON, string(data))
+
+	data, err = api.GetWithCache("/data/files", time.Hour)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "Signature")
+	assert.Equal(t, 1, m.hitCount("/data/files"))
+
+	// skipped responses are fetched every time
+	for n := 0; n < 2; n++ {
+		_, err = api.GetWithCache("/data/constants/categories", time.Hour)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, m.hitCount("/data/constants/categories"))
+
+	// chunks are merged before the filter sees them
+	_, err = api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.NoError(t, err)
+	assert.Contains(t, filtered, `[{"id":1},{"id":2}]`)
+
+	entries, err := api.CacheEntries()
+	if assert.NoError(t, err) && assert.Len(t, entries, 2) {
+		reasons := make(map[string]CacheEntryInfo)
+		for _, entry := range entries {
+			reasons[entry.URI] = entry
+		}
+
+		assert.Equal(t, "signed links stripped", reasons["/data/files"].Reason)
+
+		results := reasons["/data/results/search_series"]
+		assert.Equal(t, "short lived", results.Reason)
+		assert.Equal(t, 2, results.Chunks)
+		assert.Equal(t, time.Minute, results.Expires.Sub(results.Created))
+	}
+
+	stats := api.CacheStats()
+	assert.Equal(t, int64(2), stats.FilterSkipped)
+	assert.Equal(t, int64(1), stats.FilterRewritten)
+	assert.Equal(t, int64(1), stats.FilterShortened)
+
+	// without a filter everything is cached as it is
+	api.SetCacheFilter(nil)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, m.hitCount("/data/constants/categories"))
+}
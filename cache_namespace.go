@@ -0,0 +1,134 @@
+package irdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// accountKeyPrefix starts the cache keys of account dependent responses,
+// followed by the namespace, a NUL and the uri
+const accountKeyPrefix = "\x00irdata.ns\x00"
+
+// accountScopedPrefixes are the endpoints whose responses depend on the
+// authenticated account rather than only on the uri
+var accountScopedPrefixes = []string{
+	"/data/member/",
+	"/data/stats/member_",
+	"/data/hosted/",
+	"/data/league/membership",
+	"/data/team/membership",
+}
+
+type cacheNamespaceT struct {
+	name string
+
+	// fixed is set by WithCacheNamespace, the namespace then no longer
+	// follows the account
+	fixed bool
+}
+
+// WithCacheNamespace caches the account dependent responses of the instance
+// under namespace instead of one derived from the authenticated account.
+// Instances passed the same namespace share those entries, "" shares them
+// with every other instance using "" as well as with unauthenticated ones.
+func WithCacheNamespace(namespace string) Option {
+	return func(i *Irdata) {
+		i.cacheNamespace = cacheNamespaceT{name: namespace, fixed: true}
+	}
+}
+
+// CacheNamespace returns the namespace account dependent responses are
+// cached under, "" before authenticating
+func (i *Irdata) CacheNamespace() string {
+	return i.cacheNamespace.name
+}
+
+// accountNamespace is the namespace of the account logging in as username
+func accountNamespace(username string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(username)))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+func (i *Irdata) setAccount(username string) {
+	if !i.cacheNamespace.fixed {
+		i.cacheNamespace.name = accountNamespace(username)
+	}
+}
+
+func (i *Irdata) forgetAccount() {
+	if !i.cacheNamespace.fixed {
+		i.cacheNamespace.name = ""
+	}
+}
+
+// isAccountScoped reports whether the response for uri depends on the account
+func isAccountScoped(uri string) bool {
+	for _, prefix := range accountScopedPrefixes {
+		if strings.HasPrefix(uri, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cacheKey is the key the response for uri is cached under by this instance
+func (i *Irdata) cacheKey(uri string) string {
+	uri = normalizeURI(uri)
+
+	if !isAccountScoped(uri) || i.cacheNamespace.name == "" {
+		return uri
+	}
+
+	return namespacedKey(i.cacheNamespace.name, uri)
+}
+
+func namespacedKey(namespace string, uri string) string {
+	return accountKeyPrefix + namespace + "\x00" + uri
+}
+
+// splitCacheKey returns the namespace and uri of a key made by cacheKey
+func splitCacheKey(key string) (string, string) {
+	if !strings.HasPrefix(key, accountKeyPrefix) {
+		return "", key
+	}
+
+	rest := key[len(accountKeyPrefix):]
+
+	n := strings.IndexByte(rest, 0)
+	if n < 0 {
+		return "", key
+	}
+
+	return rest[:n], rest[n+1:]
+}
+
+// PurgeCacheNamespace removes every response cached under namespace.  The
+// shared entries and those of other namespaces are left alone.
+//
+// The cache backend must implement CacheEnumerator.
+func (i *Irdata) PurgeCacheNamespace(namespace string) error {
+	if namespace == "" {
+		return errors.New("must provide a namespace")
+	}
+
+	entries, err := i.CacheEntries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Namespace != namespace {
+			continue
+		}
+
+		if err := i.deleteCachedData(i.ctx, namespacedKey(namespace, entry.URI)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var otherUsername, otherPassword = []byte("michael"), []byte("benetton")
+
+type otherCreds struct{}
+
+func (otherCreds) GetCreds() ([]byte, []byte) {
+	return otherUsername, otherPassword
+}
+
+// newNamespaceMock serves member info and a catalog, the member info
+// depending on the account asking
+func newNamespaceMock(t *testing.T) *mockAPI {
+	m := newMockAPI(t)
+	m.addAccount(otherUsername, otherPassword)
+
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"display_name":"%s"}`, mockUsername(r))
+	})
+
+	m.handleJSON("/data/car/get", `[{"car_id":1}]`)
+
+	return m
+}
+
+func openOther(t *testing.T, m *mockAPI, opts ...Option) *Irdata {
+	api := m.open(t, opts...)
+
+	if err := api.AuthWithProvideCreds(otherCreds{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return api
+}
+
+func TestCacheNamespace(t *testing.T) {
+	m := newNamespaceMock(t)
+
+	cache := NewMemoryCache()
+
+	louis := m.openAuthed(t)
+	louis.EnableCacheBackend(cache)
+
+	michael := openOther(t, m)
+	michael.EnableCacheBackend(cache)
+
+	assert.NotEmpty(t, louis.CacheNamespace())
+	assert.NotEqual(t, louis.CacheNamespace(), michael.CacheNamespace())
+
+	data, err := louis.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"display_name":"louis"}`, string(data))
+
+	data, err = michael.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"display_name":"michael"}`, string(data))
+
+	// each from its own entry
+	data, err = louis.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"display_name":"louis"}`, string(data))
+	assert.Equal(t, 2, m.hitCount("/data/member/info"))
+
+	// catalogs are shared
+	_, err = louis.GetWithCache("/data/car/get", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = michael.GetWithCache("/data/car/get", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, m.hitCount("/data/car/get"))
+
+	entries, err := louis.CacheEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "/data/car/get", entries[0].URI)
+	assert.Empty(t, entries[0].Namespace)
+	assert.Equal(t, "/data/member/info", entries[1].URI)
+	assert.ElementsMatch(t,
+		[]string{louis.CacheNamespace(), michael.CacheNamespace()},
+		[]string{entries[1].Namespace, entries[2].Namespace},
+	)
+
+	// logging in as someone else moves to their namespace
+	louis.Logout()
+	assert.Empty(t, louis.CacheNamespace())
+
+	assert.NoError(t, louis.AuthWithProvideCreds(otherCreds{}))
+	assert.Equal(t, michael.CacheNamespace(), louis.CacheNamespace())
+}
+
+func TestPurgeCacheNamespace(t *testing.T) {
+	m := newNamespaceMock(t)
+
+	cache := NewMemoryCache()
+
+	louis := m.openAuthed(t)
+	louis.EnableCacheBackend(cache)
+
+	michael := openOther(t, m)
+	michael.EnableCacheBackend(cache)
+
+	for _, api := range []*Irdata{louis, michael} {
+		for _, uri := range []string{"/data/member/info", "/data/car/get"} {
+			_, err := api.GetWithCache(uri, time.Hour)
+			assert.NoError(t, err)
+		}
+	}
+
+	assert.Error(t, louis.PurgeCacheNamespace(""))
+	assert.NoError(t, louis.PurgeCacheNamespace(michael.CacheNamespace()))
+
+	entries, err := louis.CacheEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	for _, entry := range entries {
+		assert.NotEqual(t, michael.CacheNamespace(), entry.Namespace)
+	}
+
+	// PurgeCache only drops the entry of the instance's own namespace
+	_, err = michael.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, michael.PurgeCache("/data/member/info"))
+
+	entries, err = louis.CacheEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestWithCacheNamespace(t *testing.T) {
+	m := newNamespaceMock(t)
+
+	cache := NewMemoryCache()
+
+	louis := m.openAuthed(t, WithCacheNamespace("team"))
+	louis.EnableCacheBackend(cache)
+
+	michael := openOther(t, m, WithCacheNamespace("team"))
+	michael.EnableCacheBackend(cache)
+
+	assert.Equal(t, "team", louis.CacheNamespace())
+
+	_, err := louis.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+
+	// told to share, so it does
+	data, err := michael.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"display_name":"louis"}`, string(data))
+
+	louis.Logout()
+	assert.Equal(t, "team", louis.CacheNamespace())
+}
+
+func TestCacheKey(t *testing.T) {
+	api := Open(context.Background())
+
+	assert.Equal(t, "/data/member/info", api.cacheKey("/data/member/info"))
+
+	api.setAccount("Louis")
+	assert.Equal(t, accountNamespace("louis"), api.CacheNamespace())
+
+	key := api.cacheKey("/data/stats/member_recent_races?cust_id=1")
+	assert.NotEqual(t, "/data/stats/member_recent_races?cust_id=1", key)
+
+	namespace, uri := splitCacheKey(key)
+	assert.Equal(t, api.CacheNamespace(), namespace)
+	assert.Equal(t, "/data/stats/member_recent_races?cust_id=1", uri)
+
+	assert.Equal(t, "/data/track/get", api.cacheKey("/data/track/get"))
+	assert.Equal(t, "/data/constants/categories", api.cacheKey("/data/constants/categories"))
+}
@@ -0,0 +1,101 @@
+package irdata
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// repeated cache errors are only logged this often
+const cacheErrorLogInterval = time.Minute
+
+// CacheErrorPolicy decides what the cached getters do when the cache fails
+type CacheErrorPolicy int
+
+const (
+	// CacheErrorsFallThrough treats a failing read as a miss and ignores a
+	// failing write so results still come from the network.  The default.
+	CacheErrorsFallThrough CacheErrorPolicy = iota
+
+	// CacheErrorsStrict returns cache errors to the caller
+	CacheErrorsStrict
+)
+
+type cacheErrorsT struct {
+	mu     sync.Mutex
+	policy CacheErrorPolicy
+	hook   func(error)
+	last   error
+	count  int
+	logged time.Time
+}
+
+// WithCacheErrorPolicy sets what happens when the cache fails
+func WithCacheErrorPolicy(policy CacheErrorPolicy) Option {
+	return func(i *Irdata) {
+		i.cacheErrors.policy = policy
+	}
+}
+
+// WithCacheErrorHook calls hook with every cache error, e.g. to count them
+// in a metrics system
+func WithCacheErrorHook(hook func(error)) Option {
+	return func(i *Irdata) {
+		i.cacheErrors.hook = hook
+	}
+}
+
+// LastCacheError returns the most recent cache error or nil if there has
+// been none
+func (i *Irdata) LastCacheError() error {
+	i.cacheErrors.mu.Lock()
+	defer i.cacheErrors.mu.Unlock()
+
+	return i.cacheErrors.last
+}
+
+// CacheErrorCount returns how many cache errors there have been
+func (i *Irdata) CacheErrorCount() int {
+	i.cacheErrors.mu.Lock()
+	defer i.cacheErrors.mu.Unlock()
+
+	return i.cacheErrors.count
+}
+
+// cacheFailed records err from a cache read or write for uri and returns it
+// if the policy is strict, nil if the caller should carry on without the
+// cache
+func (i *Irdata) cacheFailed(op string, uri string, err error) error {
+	c := &i.cacheErrors
+
+	c.mu.Lock()
+
+	c.last = err
+	c.count++
+
+	if now := i.clock.Now(); now.Sub(c.logged) >= cacheErrorLogInterval {
+		c.logged = now
+
+		i.logger.WithFields(log.Fields{
+			"op":    op,
+			"uri":   uri,
+			"err":   err,
+			"count": c.count,
+		}).Error("Cache failed")
+	}
+
+	policy, hook := c.policy, c.hook
+
+	c.mu.Unlock()
+
+	if hook != nil {
+		i.callHook(func() { hook(err) })
+	}
+
+	if policy == CacheErrorsStrict {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errCacheGone = errors.New("cache volume is gone")
+
+// failingCache is a backend whose volume went away
+type failingCache struct{}
+
+func (failingCache) Get(context.Context, []byte) ([]byte, error) { return nil, errCacheGone }
+func (failingCache) Has(context.Context, []byte) bool            { return false }
+func (failingCache) PutWithTTL(context.Context, []byte, []byte, time.Duration) error {
+	return errCacheGone
+}
+func (failingCache) Delete(context.Context, []byte) error { return errCacheGone }
+func (failingCache) Close() error                         { return nil }
+
+func TestCacheErrorsFallThrough(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2}]`))
+
+	var hooked []error
+
+	api := m.openAuthed(t, WithCacheErrorHook(func(err error) { hooked = append(hooked, err) }))
+	api.EnableCacheBackend(failingCache{})
+
+	assert.NoError(t, api.LastCacheError())
+
+	data, err := api.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, testMemberInfo, string(data))
+
+	// the read and the write failed
+	assert.ErrorIs(t, api.LastCacheError(), errCacheGone)
+	assert.Equal(t, 2, api.CacheErrorCount())
+	api.flushHooks(false)
+	assert.Equal(t, []error{errCacheGone, errCacheGone}, hooked)
+
+	var chunks []string
+
+	assert.NoError(t, api.GetChunksWithCache("/data/results/search_series", time.Hour, func(chunk Chunk) error {
+		chunks = append(chunks, string(chunk.Data))
+		return nil
+	}))
+
+	assert.Equal(t, []string{`[{"id":1}]`, `[{"id":2}]`}, chunks)
+	assert.Equal(t, 4, api.CacheErrorCount())
+}
+
+func TestCacheErrorsStrict(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t, WithCacheErrorPolicy(CacheErrorsStrict))
+	api.EnableCacheBackend(failingCache{})
+
+	_, err := api.GetWithCache("/data/member/info", time.Hour)
+	assert.ErrorIs(t, err, errCacheGone)
+	assert.Equal(t, 0, m.hitCount("/data/member/info"))
+
+	assert.ErrorIs(t, api.GetChunksWithCache("/data/member/info", time.Hour, func(Chunk) error { return nil }), errCacheGone)
+	assert.Equal(t, 2, api.CacheErrorCount())
+}
+
+// writeFailingCache reads fine but is out of space
+type writeFailingCache struct {
+	*MemoryCache
+}
+
+func (writeFailingCache) PutWithTTL(context.Context, []byte, []byte, time.Duration) error {
+	return errCacheGone
+}
+
+func TestCacheErrorsStrictWrite(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t, WithCacheErrorPolicy(CacheErrorsStrict))
+	api.EnableCacheBackend(writeFailingCache{NewMemoryCache()})
+
+	// the data is still handed back
+	data, err := api.GetWithCache("/data/member/info", time.Hour)
+	assert.ErrorIs(t, err, errCacheGone)
+	assert.Equal(t, testMemberInfo, string(data))
+}
@@ -1,6 +1,7 @@
 package irdata
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -30,9 +31,9 @@ func TestSetGet(t *testing.T) {
 
 	key := "key"
 
-	assert.NoError(t, i.setCachedData(key, []byte(testDataString1), testTtl))
+	assert.NoError(t, i.setCachedData(context.Background(), key, []byte(testDataString1), testTtl))
 
-	data, err := i.getCachedData(key)
+	data, err := i.getCachedData(context.Background(), key)
 
 	assert.NoError(t, err)
 	assert.Equal(t, []byte(testDataString1), data)
@@ -44,15 +45,15 @@ func TestMultipleKVs(t *testing.T) {
 
 	key1, key2 := "key1", "key2"
 
-	assert.NoError(t, i.setCachedData(key1, []byte(testDataString1), testTtl))
-	assert.NoError(t, i.setCachedData(key2, []byte(testDataString2), testTtl))
+	assert.NoError(t, i.setCachedData(context.Background(), key1, []byte(testDataString1), testTtl))
+	assert.NoError(t, i.setCachedData(context.Background(), key2, []byte(testDataString2), testTtl))
 
-	data, err := i.getCachedData(key1)
+	data, err := i.getCachedData(context.Background(), key1)
 
 	assert.NoError(t, err)
 	assert.Equal(t, []byte(testDataString1), data)
 
-	data, err = i.getCachedData(key2)
+	data, err = i.getCachedData(context.Background(), key2)
 
 	assert.NoError(t, err)
 	assert.Equal(t, []byte(testDataString2), data)
@@ -65,11 +66,11 @@ func TestSetTtl(t *testing.T) {
 
 	key := "key"
 
-	assert.NoError(t, i.setCachedData(key, []byte(testDataString1), time.Duration(1)*time.Millisecond))
+	assert.NoError(t, i.setCachedData(context.Background(), key, []byte(testDataString1), time.Duration(1)*time.Millisecond))
 
 	time.Sleep(2 * time.Millisecond)
 
-	data, err := i.getCachedData(key)
+	data, err := i.getCachedData(context.Background(), key)
 
 	assert.NoError(t, err)
 	assert.Nil(t, data)
@@ -81,11 +82,109 @@ func TestDelete(t *testing.T) {
 
 	key := "key"
 
-	assert.NoError(t, i.setCachedData(key, []byte(testDataString1), testTtl))
-	assert.NoError(t, i.deleteCachedData(key))
+	assert.NoError(t, i.setCachedData(context.Background(), key, []byte(testDataString1), testTtl))
+	assert.NoError(t, i.deleteCachedData(context.Background(), key))
 
-	data, err := i.getCachedData(key)
+	data, err := i.getCachedData(context.Background(), key)
 
 	assert.NoError(t, err)
 	assert.Nil(t, data)
 }
+
+func testChunkedPayload() *payload {
+	return &payload{chunks: []Chunk{
+		{Number: 0, FileName: "a.json", Data: []byte(`[{"id":1},{"id":2}]`)},
+		{Number: 1, FileName: "b.json", Data: []byte(`[]`)},
+		{Number: 2, FileName: "c.json", Data: []byte(` [{"id":3}] `)},
+	}}
+}
+
+func TestChunkedPayloadRoundTrip(t *testing.T) {
+	setupCacheTest()
+	t.Cleanup(cleanupCacheTest)
+
+	key := "chunked"
+
+	assert.NoError(t, i.setCachedPayload(context.Background(), key, testChunkedPayload(), testTtl, ""))
+
+	p, err := i.getCachedPayload(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Equal(t, testChunkedPayload().chunks, p.chunks)
+
+	data, err := p.assemble()
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":1},{"id":2},{"id":3}]`, string(data))
+
+	// no single value holds the whole result
+	raw, err := i.getCachedData(context.Background(), key)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw), `"id"`)
+}
+
+func TestLegacyWholeEntryReadable(t *testing.T) {
+	setupCacheTest()
+	t.Cleanup(cleanupCacheTest)
+
+	key := "legacy"
+
+	assert.NoError(t, i.setCachedData(context.Background(), key, []byte(`[{"id":1}]`), testTtl))
+
+	p, err := i.getCachedPayload(context.Background(), key)
+	assert.NoError(t, err)
+	assert.False(t, p.isChunked())
+	assert.Equal(t, []byte(`[{"id":1}]`), p.data)
+}
+
+func TestMissingChunkIsMiss(t *testing.T) {
+	setupCacheTest()
+	t.Cleanup(cleanupCacheTest)
+
+	key := "chunked"
+
+	assert.NoError(t, i.setCachedPayload(context.Background(), key, testChunkedPayload(), testTtl, ""))
+
+	index, _, err := i.getCachedIndex(context.Background(), key)
+	assert.NoError(t, err)
+	assert.NoError(t, i.cache.Delete(context.Background(), hashKey(chunkKey(key, index.ID, 1))))
+
+	p, err := i.getCachedPayload(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestDeleteChunked(t *testing.T) {
+	setupCacheTest()
+	t.Cleanup(cleanupCacheTest)
+
+	key := "chunked"
+
+	assert.NoError(t, i.setCachedPayload(context.Background(), key, testChunkedPayload(), testTtl, ""))
+
+	index, _, err := i.getCachedIndex(context.Background(), key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, i.deleteCachedData(context.Background(), key))
+
+	for n := range index.Chunks {
+		assert.False(t, i.cache.Has(context.Background(), hashKey(chunkKey(key, index.ID, n))))
+	}
+
+	p, err := i.getCachedPayload(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestChunkedTtl(t *testing.T) {
+	setupCacheTest()
+	t.Cleanup(cleanupCacheTest)
+
+	key := "chunked"
+
+	assert.NoError(t, i.setCachedPayload(context.Background(), key, testChunkedPayload(), time.Duration(1)*time.Millisecond, ""))
+
+	time.Sleep(2 * time.Millisecond)
+
+	p, err := i.getCachedPayload(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+}
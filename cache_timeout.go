@@ -0,0 +1,211 @@
+package irdata
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// WithCacheTimeout bounds each cache operation to timeout.  An operation
+// taking longer fails with ErrCacheTimeout, which the cache error policy
+// handles like any cache failure: by default the read is a miss and the
+// request goes to the network.  The backend is left to finish in the
+// background.  There's no timeout by default.
+func WithCacheTimeout(timeout time.Duration) Option {
+	return func(i *Irdata) {
+		i.cacheTimeout = timeout
+	}
+}
+
+// CacheStats counts the operations of the cache
+type CacheStats struct {
+	Operations int64
+
+	// TimedOut is how many operations took longer than the cache timeout,
+	// see WithCacheTimeout
+	TimedOut int64
+
+	// FilterSkipped, FilterRewritten and FilterShortened count the
+	// responses the cache filter left out of the cache, rewrote or cached
+	// for less long, see SetCacheFilter
+	FilterSkipped   int64
+	FilterRewritten int64
+	FilterShortened int64
+
+	// TTLJittered is how many entries were cached with a jittered ttl, see
+	// WithTTLJitter
+	TTLJittered int64
+}
+
+// CacheStats returns what the cache did so far
+func (i *Irdata) CacheStats() CacheStats {
+	return CacheStats{
+		Operations: atomic.LoadInt64(&i.cacheOps),
+		TimedOut:   atomic.LoadInt64(&i.cacheTimeouts),
+
+		FilterSkipped:   atomic.LoadInt64(&i.cacheFiltered.skipped),
+		FilterRewritten: atomic.LoadInt64(&i.cacheFiltered.rewritten),
+		FilterShortened: atomic.LoadInt64(&i.cacheFiltered.shortened),
+
+		TTLJittered: atomic.LoadInt64(&i.ttlJittered),
+	}
+}
+
+// cacheDo runs op against the backend with ctx, giving up with
+// ErrCacheTimeout after the cache timeout or with the error of ctx once it's
+// done
+func (i *Irdata) cacheDo(ctx context.Context, op func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&i.cacheOps, 1)
+
+	if i.cacheTimeout <= 0 {
+		return op(ctx)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, i.cacheTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- op(opCtx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == nil && opCtx.Err() != nil {
+			return i.cacheTimedOut()
+		}
+
+		return err
+	case <-opCtx.Done():
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return i.cacheTimedOut()
+}
+
+func (i *Irdata) cacheTimedOut() error {
+	atomic.AddInt64(&i.cacheTimeouts, 1)
+
+	return ErrCacheTimeout
+}
+
+// cacheUntimed counts an operation run without a timeout, sparing the hot
+// read path the closure cacheDo needs.  It's false with a timeout set.
+func (i *Irdata) cacheUntimed(ctx context.Context) (bool, error) {
+	if i.cacheTimeout > 0 {
+		return false, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return true, err
+	}
+
+	atomic.AddInt64(&i.cacheOps, 1)
+
+	return true, nil
+}
+
+// the backend is taken before the operation starts as it may outlive the
+// call, e.g. past Close
+
+func (i *Irdata) cacheGet(ctx context.Context, key []byte) ([]byte, error) {
+	backend := i.cache
+
+	if untimed, err := i.cacheUntimed(ctx); untimed {
+		if err != nil {
+			return nil, err
+		}
+
+		return backend.Get(ctx, key)
+	}
+
+	var data []byte
+
+	err := i.cacheDo(ctx, func(ctx context.Context) error {
+		var err error
+
+		data, err = backend.Get(ctx, key)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// cacheRead is cacheGet, or with shared a CacheViewer's Peek when the
+// backend is one
+func (i *Irdata) cacheRead(ctx context.Context, key []byte, shared bool) ([]byte, error) {
+	viewer, ok := i.cache.(CacheViewer)
+	if !shared || !ok {
+		return i.cacheGet(ctx, key)
+	}
+
+	if untimed, err := i.cacheUntimed(ctx); untimed {
+		if err != nil {
+			return nil, err
+		}
+
+		return viewer.Peek(ctx, key)
+	}
+
+	var data []byte
+
+	err := i.cacheDo(ctx, func(ctx context.Context) error {
+		var err error
+
+		data, err = viewer.Peek(ctx, key)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// cacheHas is false if the backend didn't answer in time
+func (i *Irdata) cacheHas(ctx context.Context, key []byte) bool {
+	backend := i.cache
+
+	if untimed, err := i.cacheUntimed(ctx); untimed {
+		return err == nil && backend.Has(ctx, key)
+	}
+
+	var found bool
+
+	err := i.cacheDo(ctx, func(ctx context.Context) error {
+		found = backend.Has(ctx, key)
+
+		return nil
+	})
+
+	return err == nil && found
+}
+
+func (i *Irdata) cachePut(ctx context.Context, key []byte, value []byte, ttl time.Duration) error {
+	backend := i.cache
+
+	return i.cacheDo(ctx, func(ctx context.Context) error {
+		return backend.PutWithTTL(ctx, key, value, ttl)
+	})
+}
+
+func (i *Irdata) cacheDelete(ctx context.Context, key []byte) error {
+	backend := i.cache
+
+	return i.cacheDo(ctx, func(ctx context.Context) error {
+		return backend.Delete(ctx, key)
+	})
+}
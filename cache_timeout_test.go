@@ -0,0 +1,100 @@
+package irdata
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowCache is a backend on a failing disk: while slow is set its reads
+// block until released, whatever their context says
+type slowCache struct {
+	*MemoryCache
+	slow    int32
+	release chan struct{}
+}
+
+func newSlowCache(t *testing.T) *slowCache {
+	c := &slowCache{MemoryCache: NewMemoryCache(), release: make(chan struct{})}
+
+	t.Cleanup(func() { close(c.release) })
+
+	return c
+}
+
+func (c *slowCache) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if atomic.LoadInt32(&c.slow) != 0 {
+		<-c.release
+	}
+
+	return c.MemoryCache.Get(ctx, key)
+}
+
+func TestCacheTimeoutFallsThrough(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+
+	cache := newSlowCache(t)
+
+	api := m.openAuthed(t, WithCacheTimeout(50*time.Millisecond))
+	api.EnableCacheBackend(cache)
+
+	_, err := api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, m.hitCount("/data/constants/categories"))
+	assert.Zero(t, api.CacheStats().TimedOut)
+
+	atomic.StoreInt32(&cache.slow, 1)
+
+	started := time.Now()
+
+	data, err := api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"label":"Oval","value":1}]`, string(data))
+	assert.Less(t, time.Since(started), 5*time.Second)
+
+	assert.Equal(t, 2, m.hitCount("/data/constants/categories"))
+	assert.Positive(t, api.CacheStats().TimedOut)
+	assert.Greater(t, api.CacheStats().Operations, api.CacheStats().TimedOut)
+	assert.ErrorIs(t, api.LastCacheError(), ErrCacheTimeout)
+}
+
+func TestCacheTimeoutStrict(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+
+	cache := newSlowCache(t)
+	atomic.StoreInt32(&cache.slow, 1)
+
+	api := m.openAuthed(t, WithCacheTimeout(50*time.Millisecond), WithCacheErrorPolicy(CacheErrorsStrict))
+	api.EnableCacheBackend(cache)
+
+	_, err := api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.ErrorIs(t, err, ErrCacheTimeout)
+	assert.Zero(t, m.hitCount("/data/constants/categories"))
+	assert.Equal(t, int64(1), api.CacheStats().TimedOut)
+}
+
+func TestCacheHonorsRequestDeadline(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", `{"subsession_id":12345}`)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := api.GetSubsessionResultWithCache(ctx, 12345)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, m.hitCount("/data/results/get"))
+
+	// a deadline past isn't a cache timeout
+	assert.Zero(t, api.CacheStats().TimedOut)
+}
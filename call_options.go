@@ -0,0 +1,191 @@
+package irdata
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reservedHeaders are the headers of the session, which a call can't set
+var reservedHeaders = map[string]bool{
+	"Cookie":        true,
+	"Authorization": true,
+}
+
+// WithQueryParam adds the query parameter key to the request, replacing
+// one of the uri with the same key.  It's part of the cache key.
+func WithQueryParam(key string, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.query.Add(key, value)
+	}
+}
+
+// WithTimeout bounds the call, retries and waiting for the throttle
+// included, to d
+func WithTimeout(d time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = d
+	}
+}
+
+// WithHeadersInCacheKey makes the headers added with WithHeader part of the
+// cache key, so responses to the same uri with other headers are cached
+// apart.  By default they aren't.
+func WithHeadersInCacheKey() RequestOption {
+	return func(o *requestOptions) {
+		o.headersInCacheKey = true
+	}
+}
+
+// AllowAnonymous lets the call be made before authenticating, for the few
+// endpoints that answer without a session.  Otherwise calls made before an
+// Auth method succeeded, or after Logout, fail with ErrNotAuthenticated.
+func AllowAnonymous() RequestOption {
+	return func(o *requestOptions) {
+		o.anonymous = true
+	}
+}
+
+// WithDefaultRequestOptions sets the options every call starts from.  Those
+// of a call replace the defaults setting the same header or query
+// parameter.
+func WithDefaultRequestOptions(opts ...RequestOption) Option {
+	return func(i *Irdata) {
+		i.defaultRequestOptions = opts
+	}
+}
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a context whose calls are made with opts, on
+// top of those ctx already has.  It's how GetJSON and the typed getters,
+// which take a context, are given options, e.g. a beta header:
+//
+//	cars, err := api.GetCars(irdata.WithRequestOptions(ctx, irdata.WithHeader("X-Beta", "1")))
+func WithRequestOptions(ctx context.Context, opts ...RequestOption) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+
+	// kept apart so each replaces what the contexts before it set
+	parent, _ := ctx.Value(requestOptionsKey{}).([][]RequestOption)
+
+	layers := make([][]RequestOption, 0, len(parent)+1)
+	layers = append(append(layers, parent...), opts)
+
+	return context.WithValue(ctx, requestOptionsKey{}, layers)
+}
+
+// callOptions returns the options of a call made with ctx and opts, on top
+// of the defaults of the instance
+func (i *Irdata) callOptions(ctx context.Context, opts []RequestOption) *requestOptions {
+	o := newRequestOptions(nil)
+
+	o.merge(newRequestOptions(i.defaultRequestOptions))
+
+	layers, _ := ctx.Value(requestOptionsKey{}).([][]RequestOption)
+
+	for _, layer := range layers {
+		o.merge(newRequestOptions(layer))
+	}
+
+	o.merge(newRequestOptions(opts))
+
+	return o
+}
+
+// merge applies other on top of o
+func (o *requestOptions) merge(other *requestOptions) {
+	for key, values := range other.header {
+		o.header[key] = values
+	}
+
+	for key, values := range other.query {
+		o.query[key] = values
+	}
+
+	if other.timeout > 0 {
+		o.timeout = other.timeout
+	}
+
+	if other.priority != nil {
+		o.priority = other.priority
+	}
+
+	o.followLink = o.followLink || other.followLink
+	o.headersInCacheKey = o.headersInCacheKey || other.headersInCacheKey
+	o.anonymous = o.anonymous || other.anonymous
+
+	if o.err == nil {
+		o.err = other.err
+	}
+}
+
+// withQuery returns uri with the query parameters of o
+func (o *requestOptions) withQuery(uri string) string {
+	if len(o.query) == 0 {
+		return uri
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	q := u.Query()
+
+	for key, values := range o.query {
+		q[key] = values
+	}
+
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// cacheURI is what uri is cached under with o: with the query parameters
+// and, if WithHeadersInCacheKey, the headers
+func (o *requestOptions) cacheURI(uri string) string {
+	uri = o.withQuery(uri)
+
+	if !o.headersInCacheKey || len(o.header) == 0 {
+		return uri
+	}
+
+	keys := make([]string, 0, len(o.header))
+	for key := range o.header {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	b.WriteString(uri)
+
+	for _, key := range keys {
+		b.WriteString("\x00")
+		b.WriteString(key)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(o.header[key], ", "))
+	}
+
+	return b.String()
+}
+
+// hasCallOptions reports whether calls made with ctx have any options, so
+// the cached reads without can skip merging them
+func (i *Irdata) hasCallOptions(ctx context.Context) bool {
+	return len(i.defaultRequestOptions) > 0 || ctx.Value(requestOptionsKey{}) != nil
+}
+
+// callCacheKey is cacheKey for uri requested with ctx
+func (i *Irdata) callCacheKey(ctx context.Context, uri string) string {
+	if !i.hasCallOptions(ctx) {
+		return i.cacheKey(uri)
+	}
+
+	return i.cacheKey(i.callOptions(ctx, nil).cacheURI(uri))
+}
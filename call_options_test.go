@@ -0,0 +1,164 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// handleEcho registers path answering with an s3 link, recording the beta
+// header and query of each request to the API and whether the header made
+// it to the link
+func handleEcho(m *mockAPI, path string) func() []string {
+	var mu sync.Mutex
+	var seen []string
+
+	m.mux.HandleFunc("/s3"+path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Beta") != "" {
+			mu.Lock()
+			seen = append(seen, "leaked to s3")
+			mu.Unlock()
+		}
+
+		fmt.Fprint(w, `{"ok":true}`)
+	})
+
+	m.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("X-Beta")+"|"+r.URL.RawQuery)
+		mu.Unlock()
+
+		fmt.Fprintf(w, `{"link":"%s/s3%s"}`, m.URL, path)
+	})
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string{}, seen...)
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	m := newMockAPI(t)
+	seen := handleEcho(m, "/data/beta")
+
+	api := m.openAuthed(t)
+
+	_, err := api.Get("/data/beta?a=1", WithHeader("X-Beta", "yes"), WithQueryParam("flag", "on"))
+	assert.NoError(t, err)
+
+	// nothing carries over to the next call
+	_, err = api.Get("/data/beta?a=1")
+	assert.NoError(t, err)
+
+	var v map[string]bool
+
+	ctx := WithRequestOptions(context.Background(), WithHeader("X-Beta", "ctx"))
+	assert.NoError(t, api.GetJSON(WithRequestOptions(ctx, WithHeader("X-Beta", "inner")), "/data/beta", &v))
+	assert.NoError(t, api.GetJSON(ctx, "/data/beta", &v))
+	assert.True(t, v["ok"])
+
+	assert.Equal(t, []string{"yes|a=1&flag=on", "|a=1", "inner|", "ctx|"}, seen())
+}
+
+func TestDefaultRequestOptions(t *testing.T) {
+	m := newMockAPI(t)
+	seen := handleEcho(m, "/data/beta")
+
+	api := m.openAuthed(t, WithDefaultRequestOptions(WithHeader("X-Beta", "default"), WithQueryParam("flag", "on")))
+
+	_, err := api.Get("/data/beta", WithHeader("X-Beta", "call"), WithQueryParam("flag", "off"))
+	assert.NoError(t, err)
+
+	_, err = api.Get("/data/beta")
+	assert.NoError(t, err)
+
+	resp, err := api.Do(context.Background(), http.MethodGet, "/data/beta", nil)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{"call|flag=off", "default|flag=on", "default|flag=on"}, seen())
+}
+
+func TestRequestOptionsCacheKey(t *testing.T) {
+	m := newMockAPI(t)
+	seen := handleEcho(m, "/data/beta")
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	get := func(opts ...RequestOption) {
+		_, err := api.GetWithCache("/data/beta", time.Hour, opts...)
+		assert.NoError(t, err)
+	}
+
+	// query parameters are part of the key, headers aren't
+	get(WithQueryParam("season", "1"))
+	get(WithQueryParam("season", "2"))
+	get(WithQueryParam("season", "1"), WithHeader("X-Beta", "yes"))
+	get()
+
+	assert.Equal(t, 3, m.hitCount("/data/beta"))
+
+	// unless asked
+	get(WithHeader("X-Beta", "yes"), WithHeadersInCacheKey())
+	get(WithHeader("X-Beta", "yes"), WithHeadersInCacheKey())
+	get(WithHeader("X-Beta", "no"), WithHeadersInCacheKey())
+
+	assert.Equal(t, 5, m.hitCount("/data/beta"))
+	assert.NotContains(t, seen(), "leaked to s3")
+}
+
+func TestReservedHeaders(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/beta", `{}`)
+
+	api := m.openAuthed(t)
+
+	_, err := api.Get("/data/beta", WithHeader("cookie", "authtoken_members=stolen"))
+	assert.ErrorIs(t, err, ErrReservedHeader)
+
+	_, err = api.Do(context.Background(), http.MethodGet, "/data/beta", nil, WithHeader("Authorization", "Bearer x"))
+	assert.ErrorIs(t, err, ErrReservedHeader)
+
+	var v interface{}
+	assert.ErrorIs(t, api.GetJSON(WithRequestOptions(context.Background(), WithHeader("Cookie", "x")), "/data/beta", &v), ErrReservedHeader)
+
+	assert.Equal(t, 0, m.hitCount("/data/beta"))
+
+	defaults := m.openAuthed(t, WithDefaultRequestOptions(WithHeader("Authorization", "Bearer x")))
+
+	_, err = defaults.Get("/data/beta")
+	assert.ErrorIs(t, err, ErrReservedHeader)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	m := newMockAPI(t)
+
+	m.handle("/data/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	m.handleJSON("/data/fast", `{}`)
+
+	api := m.openAuthed(t)
+
+	started := time.Now()
+
+	_, err := api.Get("/data/slow", WithTimeout(50*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 2*time.Second)
+
+	// the timeout was that call's only
+	_, err = api.Get("/data/fast")
+	assert.NoError(t, err)
+}
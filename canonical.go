@@ -0,0 +1,228 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithCanonicalJSON makes Get, GetWithCache and GetJSON into a
+// *json.RawMessage return JSON in canonical form, see CanonicalJSON, so
+// payloads kept e.g. in git only differ where iRacing changed something.
+// It's applied after links are followed and chunks merged, responses that
+// aren't JSON (the CSV endpoints) are returned as they are.
+func WithCanonicalJSON() Option {
+	return func(i *Irdata) {
+		i.canonicalJSON = true
+	}
+}
+
+// CanonicalJSON rewrites data so that semantically identical JSON is
+// byte-identical: object keys sorted, no whitespace outside strings,
+// strings escaped the same way and numbers in their shortest decimal
+// form (1.50 and 15e-1 are 1.5).  Numbers are never converted to float64,
+// large ids stay exact.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: data after the top-level value")
+	}
+
+	var buf bytes.Buffer
+
+	buf.Grow(len(data))
+
+	if err := writeCanonical(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// canonical returns the canonical form of data when WithCanonicalJSON is
+// set and data is JSON
+func (i *Irdata) canonical(p *payload, data []byte) ([]byte, error) {
+	if !i.canonicalJSON || (!p.isChunked() && isCSV(p.contentType, data)) {
+		return data, nil
+	}
+
+	return CanonicalJSON(data)
+}
+
+func writeCanonical(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		buf.WriteString(canonicalNumber(string(v)))
+	case string:
+		return writeCanonicalString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+
+		for n, element := range v {
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeCanonical(buf, element); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+
+		for n, key := range keys {
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeCanonicalString(buf, key); err != nil {
+				return err
+			}
+
+			buf.WriteByte(':')
+
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte('}')
+	}
+
+	return nil
+}
+
+// writeCanonicalString writes s escaping only what JSON requires, so "<"
+// and "\u003c" come out the same
+func writeCanonicalString(buf *bytes.Buffer, s string) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+
+	// Encode ends the value with a newline
+	buf.Truncate(buf.Len() - 1)
+
+	return nil
+}
+
+// canonicalNumber rewrites the JSON number s in its shortest form: no
+// trailing fractional zeros, no exponent unless the number has more than
+// 21 integer digits or 6 leading fractional zeros, as JavaScript prints
+// numbers
+func canonicalNumber(s string) string {
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+
+	exp := 0
+
+	if e := strings.IndexAny(s, "eE"); e >= 0 {
+		exp = parseExponent(s[e+1:])
+		s = s[:e]
+	}
+
+	digits := s
+
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		digits = s[:dot] + s[dot+1:]
+		exp -= len(s) - dot - 1
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+
+	trimmed := strings.TrimRight(digits, "0")
+	exp += len(digits) - len(trimmed)
+	digits = trimmed
+
+	if digits == "" {
+		return "0"
+	}
+
+	// point is where the decimal point goes in digits
+	point := len(digits) + exp
+
+	var out string
+
+	switch {
+	case exp >= 0 && point <= 21:
+		out = digits + strings.Repeat("0", exp)
+	case point > 0 && point <= 21:
+		out = digits[:point] + "." + digits[point:]
+	case point <= 0 && point > -6:
+		out = "0." + strings.Repeat("0", -point) + digits
+	default:
+		out = digits[:1]
+		if len(digits) > 1 {
+			out += "." + digits[1:]
+		}
+
+		out += "e"
+		if point > 0 {
+			out += "+"
+		}
+
+		out += strconv.Itoa(point - 1)
+	}
+
+	if negative {
+		return "-" + out
+	}
+
+	return out
+}
+
+// parseExponent parses the exponent of a JSON number, which the decoder
+// already checked is valid
+func parseExponent(s string) int {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+
+	// beyond this the number isn't one iRacing sends anyway
+	const maxExponent = 1 << 20
+
+	exp := 0
+	for _, c := range s {
+		if exp < maxExponent {
+			exp = exp*10 + int(c-'0')
+		}
+	}
+
+	if negative {
+		return -exp
+	}
+
+	return exp
+}
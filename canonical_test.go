@@ -0,0 +1,96 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	for in, out := range map[string]string{
+		"0":                    "0",
+		"-0":                   "0",
+		"0.000":                "0",
+		"1.50":                 "1.5",
+		"15e-1":                "1.5",
+		"1E2":                  "100",
+		"1e+2":                 "100",
+		"-2.50e1":              "-25",
+		"0.001":                "0.001",
+		"1e-7":                 "1e-7",
+		"123e30":               "1.23e+32",
+		"9007199254740993":     "9007199254740993",
+		"90071992547409930e-1": "9007199254740993",
+	} {
+		assert.Equal(t, out, canonicalNumber(in), in)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	data, err := CanonicalJSON([]byte(" {\"b\" : [1.0, \"a\\u003cb\"],\n\t\"a\": {\"z\": null, \"y\": true}} "))
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"a":{"y":true,"z":null},"b":[1,"a<b"]}`, string(data))
+	}
+
+	_, err = CanonicalJSON([]byte(`{"a":1} {"b":2}`))
+	assert.Error(t, err)
+
+	_, err = CanonicalJSON([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestWithCanonicalJSON(t *testing.T) {
+	payloads := []string{
+		`{"subsession_id":9007199254740993,"track":{"track_name":"Spa","config_name":""},"sof":1.50e3}`,
+		"{\n  \"sof\": 1500,\n  \"track\": {\"config_name\": \"\", \"track_name\": \"Spa\"},\n  \"subsession_id\": 9007199254740993\n}\n",
+	}
+
+	var fetch int32
+
+	m := newMockAPI(t)
+	m.mux.HandleFunc("/s3/result.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payloads[(atomic.AddInt32(&fetch, 1)-1)%2]))
+	})
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"link":"` + m.URL + `/s3/result.json"}`))
+	})
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"b":2, "a":1}]`, `[{"a":3}]`))
+
+	const canonical = `{"sof":1500,"subsession_id":9007199254740993,"track":{"config_name":"","track_name":"Spa"}}`
+
+	api := m.openAuthed(t, WithCanonicalJSON())
+
+	first, err := api.Get("/data/results/get")
+	assert.NoError(t, err)
+
+	second, err := api.Get("/data/results/get")
+	assert.NoError(t, err)
+
+	assert.Equal(t, canonical, string(first))
+	assert.Equal(t, string(first), string(second))
+
+	var raw json.RawMessage
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/results/get", &raw))
+	assert.Equal(t, canonical, string(raw))
+
+	data, err := api.Get("/data/results/search_series")
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"a":1,"b":2},{"a":3}]`, string(data))
+
+	api.EnableCacheBackend(NewMemoryCache())
+
+	data, err = api.GetWithCache("/data/results/get", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, canonical, string(data))
+
+	// off by default
+	plain, err := m.openAuthed(t).Get("/data/results/get")
+	assert.NoError(t, err)
+	assert.Equal(t, payloads[(atomic.LoadInt32(&fetch)-1)%2], string(plain))
+}
@@ -0,0 +1,106 @@
+package irdata
+
+// CarRestriction is the balance of performance of a car in a season or a
+// race week.  The fields are nil when the payload doesn't set them, which
+// isn't the same as a field set to no restriction (e.g. a 100% fuel fill
+// or no ballast).
+type CarRestriction struct {
+	CarID int64 `json:"car_id"`
+
+	// RaceSetupID and QualSetupID are the fixed setups of the car
+	RaceSetupID *int64 `json:"race_setup_id,omitempty"`
+	QualSetupID *int64 `json:"qual_setup_id,omitempty"`
+
+	MaxPctFuelFill  *int     `json:"max_pct_fuel_fill,omitempty"`
+	WeightPenaltyKg *int     `json:"weight_penalty_kg,omitempty"`
+	PowerAdjustPct  *float64 `json:"power_adjust_pct,omitempty"`
+
+	// MaxDryTireSets is 0 for no limit
+	MaxDryTireSets *int `json:"max_dry_tire_sets,omitempty"`
+}
+
+// Restricted reports whether r sets anything restricting the car
+func (r CarRestriction) Restricted() bool {
+	return r.RaceSetupID != nil || r.QualSetupID != nil ||
+		(r.MaxPctFuelFill != nil && *r.MaxPctFuelFill < 100) ||
+		(r.WeightPenaltyKg != nil && *r.WeightPenaltyKg != 0) ||
+		(r.PowerAdjustPct != nil && *r.PowerAdjustPct != 0) ||
+		(r.MaxDryTireSets != nil && *r.MaxDryTireSets != 0)
+}
+
+// override returns r with the fields week sets replaced
+func (r CarRestriction) override(week CarRestriction) CarRestriction {
+	if week.RaceSetupID != nil {
+		r.RaceSetupID = week.RaceSetupID
+	}
+
+	if week.QualSetupID != nil {
+		r.QualSetupID = week.QualSetupID
+	}
+
+	if week.MaxPctFuelFill != nil {
+		r.MaxPctFuelFill = week.MaxPctFuelFill
+	}
+
+	if week.WeightPenaltyKg != nil {
+		r.WeightPenaltyKg = week.WeightPenaltyKg
+	}
+
+	if week.PowerAdjustPct != nil {
+		r.PowerAdjustPct = week.PowerAdjustPct
+	}
+
+	if week.MaxDryTireSets != nil {
+		r.MaxDryTireSets = week.MaxDryTireSets
+	}
+
+	return r
+}
+
+// Week returns the schedule of race week num (0 based), false if the
+// season doesn't have it
+func (s *Season) Week(num int) (*SeasonWeek, bool) {
+	for n := range s.Schedules {
+		if s.Schedules[n].RaceWeekNum == num {
+			return &s.Schedules[n], true
+		}
+	}
+
+	return nil, false
+}
+
+// RestrictionsFor returns the restriction of carID in race week week (0
+// based): the season's restriction of the car with the fields the week
+// sets for it overriding those.  A car neither lists is unrestricted.
+//
+// It returns false when there is no restriction data to go by, i.e. the
+// season doesn't have the week or neither the week nor the season carry
+// car_restrictions.
+func (s *Season) RestrictionsFor(week int, carID int64) (CarRestriction, bool) {
+	w, ok := s.Week(week)
+	if !ok || (s.CarRestrictions == nil && w.CarRestrictions == nil) {
+		return CarRestriction{}, false
+	}
+
+	restriction := CarRestriction{CarID: carID}
+
+	if r, ok := restrictionOf(s.CarRestrictions, carID); ok {
+		restriction = r
+	}
+
+	if r, ok := restrictionOf(w.CarRestrictions, carID); ok {
+		restriction = restriction.override(r)
+	}
+
+	return restriction, true
+}
+
+func restrictionOf(restrictions []CarRestriction, carID int64) (CarRestriction, bool) {
+	for _, r := range restrictions {
+		if r.CarID == carID {
+			return r, true
+		}
+	}
+
+	return CarRestriction{}, false
+}
@@ -0,0 +1,140 @@
+package irdata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestrictionsForMidSeasonBoP(t *testing.T) {
+	season := loadSeasons(t, "testdata/seasons_bop_midseason.json")[0]
+
+	intp := func(v int) *int { return &v }
+	floatp := func(v float64) *float64 { return &v }
+
+	for _, tc := range []struct {
+		name       string
+		week       int
+		carID      int64
+		want       CarRestriction
+		restricted bool
+	}{
+		{
+			name:  "season default",
+			week:  0,
+			carID: 156,
+			want: CarRestriction{CarID: 156, MaxPctFuelFill: intp(100), WeightPenaltyKg: intp(5),
+				PowerAdjustPct: floatp(-1), MaxDryTireSets: intp(0)},
+			restricted: true,
+		},
+		{
+			name:  "season default set to nothing",
+			week:  0,
+			carID: 132,
+			want: CarRestriction{CarID: 132, MaxPctFuelFill: intp(100), WeightPenaltyKg: intp(0),
+				PowerAdjustPct: floatp(0), MaxDryTireSets: intp(0)},
+		},
+		{
+			name:  "week overrides a field",
+			week:  1,
+			carID: 173,
+			want: CarRestriction{CarID: 173, MaxPctFuelFill: intp(92), WeightPenaltyKg: intp(10),
+				PowerAdjustPct: floatp(0), MaxDryTireSets: intp(0)},
+			restricted: true,
+		},
+		{
+			name:  "car the week doesn't list",
+			week:  1,
+			carID: 156,
+			want: CarRestriction{CarID: 156, MaxPctFuelFill: intp(100), WeightPenaltyKg: intp(5),
+				PowerAdjustPct: floatp(-1), MaxDryTireSets: intp(0)},
+			restricted: true,
+		},
+		{
+			name:  "bop update",
+			week:  2,
+			carID: 132,
+			want: CarRestriction{CarID: 132, MaxPctFuelFill: intp(100), WeightPenaltyKg: intp(15),
+				PowerAdjustPct: floatp(-0.5), MaxDryTireSets: intp(0)},
+			restricted: true,
+		},
+		{
+			name:  "bop update lifting a penalty",
+			week:  2,
+			carID: 156,
+			want: CarRestriction{CarID: 156, MaxPctFuelFill: intp(100), WeightPenaltyKg: intp(5),
+				PowerAdjustPct: floatp(0), MaxDryTireSets: intp(0)},
+			restricted: true,
+		},
+		{
+			name:  "week without restrictions",
+			week:  3,
+			carID: 173,
+			want: CarRestriction{CarID: 173, MaxPctFuelFill: intp(100), WeightPenaltyKg: intp(10),
+				PowerAdjustPct: floatp(0), MaxDryTireSets: intp(0)},
+			restricted: true,
+		},
+		{
+			name:  "car not in the series",
+			week:  2,
+			carID: 169,
+			want:  CarRestriction{CarID: 169},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			restriction, ok := season.RestrictionsFor(tc.week, tc.carID)
+			assert.True(t, ok)
+			assert.Equal(t, tc.want, restriction)
+			assert.Equal(t, tc.restricted, restriction.Restricted())
+		})
+	}
+
+	// data only the weeks carry is still read
+	weekly := season
+	weekly.CarRestrictions = nil
+
+	restriction, ok := weekly.RestrictionsFor(1, 173)
+	assert.True(t, ok)
+	assert.Equal(t, CarRestriction{CarID: 173, MaxPctFuelFill: intp(92)}, restriction)
+
+	_, ok = weekly.RestrictionsFor(3, 173)
+	assert.False(t, ok, "neither the week nor the season has restrictions")
+
+	_, ok = season.RestrictionsFor(11, 173)
+	assert.False(t, ok, "the week isn't scheduled")
+}
+
+func TestCarRestrictionPresence(t *testing.T) {
+	var weeks []SeasonWeek
+
+	err := json.Unmarshal([]byte(`[
+		{"race_week_num":0},
+		{"race_week_num":1,"car_restrictions":null},
+		{"race_week_num":2,"car_restrictions":[]},
+		{"race_week_num":3,"car_restrictions":[{"car_id":132,"weight_penalty_kg":0}]}
+	]`), &weeks)
+	assert.NoError(t, err)
+
+	assert.Nil(t, weeks[0].CarRestrictions)
+	assert.Nil(t, weeks[1].CarRestrictions)
+	assert.NotNil(t, weeks[2].CarRestrictions)
+	assert.Empty(t, weeks[2].CarRestrictions)
+
+	season := Season{Schedules: weeks}
+
+	_, ok := season.RestrictionsFor(0, 132)
+	assert.False(t, ok)
+
+	restriction, ok := season.RestrictionsFor(2, 132)
+	assert.True(t, ok)
+	assert.False(t, restriction.Restricted())
+
+	restriction, ok = season.RestrictionsFor(3, 132)
+	assert.True(t, ok)
+	assert.Nil(t, restriction.MaxPctFuelFill)
+	if assert.NotNil(t, restriction.WeightPenaltyKg) {
+		assert.Equal(t, 0, *restriction.WeightPenaltyKg)
+	}
+	assert.False(t, restriction.Restricted())
+}
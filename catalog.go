@@ -0,0 +1,141 @@
+package irdata
+
+import (
+	"context"
+	"time"
+)
+
+// catalogTTL is how long the catalogs are cached, they change with the
+// content releases
+const catalogTTL = 24 * time.Hour
+
+// Catalog is a catalog of iRacing content as of when iRacing produced it,
+// which for a cached catalog may be a while before it was read
+type Catalog[T any] struct {
+	Items []T
+
+	// AsOf is when the catalog was produced, from the Last-Modified of the
+	// s3 object holding it or the Date iRacing answered with
+	AsOf time.Time
+
+	// Stale is set when the catalog came from an expired cache entry
+	// because the instance is offline, see SetOffline
+	Stale bool
+}
+
+// Car is an entry of /data/car/get
+type Car struct {
+	CarID                int64    `json:"car_id"`
+	CarName              string   `json:"car_name"`
+	CarNameAbbreviated   string   `json:"car_name_abbreviated"`
+	CarMake              string   `json:"car_make"`
+	CarModel             string   `json:"car_model"`
+	Categories           []string `json:"categories"`
+	PackageID            int64    `json:"package_id"`
+	SKU                  int64    `json:"sku"`
+	Price                Cents    `json:"price"`
+	PriceDisplay         string   `json:"price_display"`
+	FreeWithSubscription bool     `json:"free_with_subscription"`
+	Retired              bool     `json:"retired"`
+}
+
+// Track is an entry of /data/track/get, a configuration of a track
+type Track struct {
+	TrackID              int64   `json:"track_id"`
+	TrackName            string  `json:"track_name"`
+	ConfigName           string  `json:"config_name"`
+	CategoryID           int64   `json:"category_id"`
+	Category             string  `json:"category"`
+	Location             string  `json:"location"`
+	TrackConfigLength    float64 `json:"track_config_length"`
+	CornersPerLap        int     `json:"corners_per_lap"`
+	PackageID            int64   `json:"package_id"`
+	SKU                  int64   `json:"sku"`
+	Price                Cents   `json:"price"`
+	PriceDisplay         string  `json:"price_display"`
+	FreeWithSubscription bool    `json:"free_with_subscription"`
+	Retired              bool    `json:"retired"`
+}
+
+// Series is an entry of /data/series/get
+type Series struct {
+	SeriesID        int64  `json:"series_id"`
+	SeriesName      string `json:"series_name"`
+	SeriesShortName string `json:"series_short_name"`
+	CategoryID      int64  `json:"category_id"`
+	Category        string `json:"category"`
+	MinStarters     int    `json:"min_starters"`
+	MaxStarters     int    `json:"max_starters"`
+	Eligible        bool   `json:"eligible" catalog:"volatile"`
+}
+
+// CarClass is an entry of /data/carclass/get
+type CarClass struct {
+	CarClassID    int64        `json:"car_class_id"`
+	Name          string       `json:"name"`
+	ShortName     string       `json:"short_name"`
+	RelativeSpeed int          `json:"relative_speed"`
+	RainEnabled   bool         `json:"rain_enabled"`
+	CarsInClass   []CarInClass `json:"cars_in_class"`
+}
+
+// CarInClass is a car of a CarClass
+type CarInClass struct {
+	CarID       int64  `json:"car_id"`
+	CarDirpath  string `json:"car_dirpath"`
+	RainEnabled bool   `json:"rain_enabled"`
+	Retired     bool   `json:"retired"`
+}
+
+// GetCars returns the car catalog.  It's cached for a day when the cache
+// is enabled.
+func (i *Irdata) GetCars(ctx context.Context) (*Catalog[Car], error) {
+	return getCatalog[Car](ctx, i, "/data/car/get")
+}
+
+// GetTracks returns the track catalog, an entry per configuration.  It's
+// cached for a day when the cache is enabled.
+func (i *Irdata) GetTracks(ctx context.Context) (*Catalog[Track], error) {
+	return getCatalog[Track](ctx, i, "/data/track/get")
+}
+
+// GetSeries returns the series catalog.  It's cached for a day when the
+// cache is enabled.
+func (i *Irdata) GetSeries(ctx context.Context) (*Catalog[Series], error) {
+	return getCatalog[Series](ctx, i, "/data/series/get")
+}
+
+// GetCarClasses returns the car class catalog.  It's cached for a day when
+// the cache is enabled.
+func (i *Irdata) GetCarClasses(ctx context.Context) (*Catalog[CarClass], error) {
+	return getCatalog[CarClass](ctx, i, "/data/carclass/get")
+}
+
+// getCatalog gets the catalog at uri, through the cache when it is enabled
+func getCatalog[T any](ctx context.Context, i *Irdata, uri string) (*Catalog[T], error) {
+	var p *payload
+	var err error
+
+	if i.cache == nil {
+		p, err = i.fetch(ctx, uri)
+	} else {
+		p, err = i.getPayloadWithCache(ctx, uri, catalogTTL)
+	}
+
+	if p == nil {
+		return nil, err
+	}
+
+	data, assembleErr := p.assemble()
+	if assembleErr != nil {
+		return nil, assembleErr
+	}
+
+	catalog := &Catalog[T]{AsOf: p.asOf, Stale: p.stale}
+
+	if err := i.decodeJSON(uri, data, &catalog.Items); err != nil {
+		return nil, err
+	}
+
+	return catalog, err
+}
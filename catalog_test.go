@@ -0,0 +1,99 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogAsOfLastModified(t *testing.T) {
+	m := newMockAPI(t)
+
+	lastModified := time.Date(2025, 2, 25, 8, 0, 0, 0, time.UTC)
+
+	m.mux.HandleFunc("/s3/cars.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		fmt.Fprint(w, `[{"car_id":1,"car_name":"Skip Barber Formula 2000"},{"car_id":67,"car_name":"Global Mazda MX-5 Cup"}]`)
+	})
+
+	m.handle("/data/car/get", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/s3/cars.json?signature=abc"}`, m.URL)
+	})
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	cars, err := api.GetCars(api.ctx)
+	assert.NoError(t, err)
+	assert.Len(t, cars.Items, 2)
+	assert.Equal(t, "Global Mazda MX-5 Cup", cars.Items[1].CarName)
+	assert.Equal(t, lastModified, cars.AsOf)
+
+	clock.advance(6 * time.Hour)
+
+	// the cached catalog is as old as it was
+	cars, err = api.GetCars(api.ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, lastModified, cars.AsOf)
+	assert.Equal(t, 1, m.hitCount("/data/car/get"))
+
+	entries, err := api.CacheEntries()
+	assert.NoError(t, err)
+
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "/data/car/get", entries[0].URI)
+		assert.Equal(t, lastModified, entries[0].AsOf)
+	}
+}
+
+func TestCatalogAsOfDate(t *testing.T) {
+	m := newMockAPI(t)
+
+	date := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	m.handle("/data/track/get", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", date.Format(http.TimeFormat))
+		fmt.Fprint(w, `[{"track_id":1,"track_name":"Lime Rock Park","config_name":"Full Course"}]`)
+	})
+
+	api := m.openAuthed(t)
+
+	tracks, err := api.GetTracks(api.ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "Full Course", tracks.Items[0].ConfigName)
+	assert.Equal(t, date, tracks.AsOf)
+}
+
+func TestCatalogAsOfNow(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/series/get", `[{"series_id":139,"series_name":"Advanced Mazda MX-5 Cup Series"}]`)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock))
+
+	series, err := api.GetSeries(api.ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(139), series.Items[0].SeriesID)
+
+	// the mock s3 doesn't say, the API's Date is the real time
+	assert.False(t, series.AsOf.IsZero())
+	assert.Equal(t, time.UTC, series.AsOf.Location())
+}
+
+func TestResponseAsOf(t *testing.T) {
+	clock := newFakeClock()
+	api := Open(context.Background(), WithClock(clock))
+
+	date := http.Header{"Date": []string{"Sat, 01 Mar 2025 10:30:00 GMT"}}
+	linked := http.Header{"Last-Modified": []string{"Tue, 25 Feb 2025 08:00:00 GMT"}}
+
+	assert.Equal(t, time.Date(2025, 2, 25, 8, 0, 0, 0, time.UTC), api.responseAsOf(date, linked))
+	assert.Equal(t, time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC), api.responseAsOf(date, nil))
+	assert.Equal(t, time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC), api.responseAsOf(date, http.Header{"Last-Modified": []string{"garbage"}}))
+	assert.Equal(t, clock.Now().UTC(), api.responseAsOf(nil, nil))
+}
@@ -0,0 +1,354 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// catalogSnapshotTTL is how long a CatalogWatcher snapshot is kept
+const catalogSnapshotTTL = 90 * 24 * time.Hour
+
+// CatalogEntry is an entry of a catalog, CatalogID is what identifies it
+// from one version of the catalog to the next
+type CatalogEntry interface {
+	Car | Track | Series | CarClass
+
+	CatalogID() int64
+}
+
+// CatalogID returns the car id
+func (c Car) CatalogID() int64 { return c.CarID }
+
+// CatalogID returns the track id, which is that of the configuration
+func (t Track) CatalogID() int64 { return t.TrackID }
+
+// CatalogID returns the series id
+func (s Series) CatalogID() int64 { return s.SeriesID }
+
+// CatalogID returns the car class id
+func (c CarClass) CatalogID() int64 { return c.CarClassID }
+
+// CatalogChangeKind says how an entry of a catalog changed
+type CatalogChangeKind string
+
+// The kinds of catalog changes
+const (
+	EntryAdded   CatalogChangeKind = "added"
+	EntryRemoved CatalogChangeKind = "removed"
+	EntryChanged CatalogChangeKind = "changed"
+)
+
+// CatalogChange is an entry of a catalog added, removed or changed.  Old
+// and New are the entry before and after, nil when it was added or
+// removed, and Fields the json names of the fields that changed.
+type CatalogChange[T CatalogEntry] struct {
+	Kind   CatalogChangeKind
+	ID     int64
+	Old    *T
+	New    *T
+	Fields []string
+}
+
+func (c CatalogChange[T]) String() string {
+	if c.Kind == EntryChanged {
+		return fmt.Sprintf("%d changed: %s", c.ID, strings.Join(c.Fields, ", "))
+	}
+
+	return fmt.Sprintf("%d %s", c.ID, c.Kind)
+}
+
+// CatalogDiff returns how the entries of a catalog changed from old to
+// new, ordered by id.  Entries are matched by CatalogID and compared field
+// by field, leaving out the fields tagged `catalog:"volatile"` that depend
+// on the member rather than the content (e.g. Series.Eligible).  Fields the
+// bindings don't decode, e.g. image paths, never count as a change.
+func CatalogDiff[T CatalogEntry](old []T, new []T) []CatalogChange[T] {
+	var changes []CatalogChange[T]
+
+	before := catalogByID(old)
+	after := catalogByID(new)
+
+	for id, o := range before {
+		n, ok := after[id]
+		if !ok {
+			changes = append(changes, CatalogChange[T]{Kind: EntryRemoved, ID: id, Old: o})
+			continue
+		}
+
+		if fields := changedFields(o, n); len(fields) > 0 {
+			changes = append(changes, CatalogChange[T]{Kind: EntryChanged, ID: id, Old: o, New: n, Fields: fields})
+		}
+	}
+
+	for id, n := range after {
+		if _, ok := before[id]; !ok {
+			changes = append(changes, CatalogChange[T]{Kind: EntryAdded, ID: id, New: n})
+		}
+	}
+
+	sort.Slice(changes, func(a, b int) bool { return changes[a].ID < changes[b].ID })
+
+	return changes
+}
+
+func catalogByID[T CatalogEntry](entries []T) map[int64]*T {
+	byID := make(map[int64]*T)
+
+	for n := range entries {
+		byID[entries[n].CatalogID()] = &entries[n]
+	}
+
+	return byID
+}
+
+// changedFields returns the json names of the fields that differ between
+// the structs old and new
+func changedFields(old interface{}, new interface{}) []string {
+	o := reflect.ValueOf(old).Elem()
+	n := reflect.ValueOf(new).Elem()
+
+	var fields []string
+
+	for f := 0; f < o.NumField(); f++ {
+		field := o.Type().Field(f)
+
+		if field.Tag.Get("catalog") == "volatile" {
+			continue
+		}
+
+		if !reflect.DeepEqual(o.Field(f).Interface(), n.Field(f).Interface()) {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" {
+				name = field.Name
+			}
+
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// CatalogWatcher polls a catalog and delivers how its entries changed, so
+// a copy of it can be updated entry by entry rather than reloaded.  The
+// catalog is read through the cache when it's enabled, so a poll only
+// downloads it once the cached copy expired.
+//
+// When the cache is enabled the last version seen is kept in it so a
+// restarted watcher reports what changed while it was down.
+type CatalogWatcher[T CatalogEntry] struct {
+	// MaxBackoff caps the delay between polls after consecutive errors
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every error encountered while polling
+	OnError func(error)
+
+	i        *Irdata
+	get      func(ctx context.Context) (*Catalog[T], error)
+	name     string
+	interval time.Duration
+	stateKey string
+
+	snapshot []T
+	asOf     time.Time
+}
+
+// catalogSnapshotT is what a CatalogWatcher keeps in the cache
+type catalogSnapshotT[T CatalogEntry] struct {
+	Items []T
+	AsOf  time.Time
+}
+
+// NewCarsWatcher returns a watcher of the car catalog that polls every
+// interval.  The catalogs change with the content releases, an interval of
+// hours is plenty.  Call Watch to start it.
+func (i *Irdata) NewCarsWatcher(interval time.Duration) *CatalogWatcher[Car] {
+	return newCatalogWatcher(i, "cars", i.GetCars, interval)
+}
+
+// NewTracksWatcher is NewCarsWatcher for the track catalog
+func (i *Irdata) NewTracksWatcher(interval time.Duration) *CatalogWatcher[Track] {
+	return newCatalogWatcher(i, "tracks", i.GetTracks, interval)
+}
+
+// NewSeriesWatcher is NewCarsWatcher for the series catalog
+func (i *Irdata) NewSeriesWatcher(interval time.Duration) *CatalogWatcher[Series] {
+	return newCatalogWatcher(i, "series", i.GetSeries, interval)
+}
+
+// NewCarClassesWatcher is NewCarsWatcher for the car class catalog
+func (i *Irdata) NewCarClassesWatcher(interval time.Duration) *CatalogWatcher[CarClass] {
+	return newCatalogWatcher(i, "carclasses", i.GetCarClasses, interval)
+}
+
+func newCatalogWatcher[T CatalogEntry](i *Irdata, name string, get func(context.Context) (*Catalog[T], error), interval time.Duration) *CatalogWatcher[T] {
+	return &CatalogWatcher[T]{
+		MaxBackoff: defaultWatchMaxBackoff,
+		i:          i,
+		get:        get,
+		name:       name,
+		interval:   interval,
+		stateKey:   "irdata.watcher.catalog." + name,
+	}
+}
+
+// Watch starts polling and returns the channel the changes found by each
+// poll are delivered on.  The first poll only takes a snapshot unless one
+// was cached.  The channel is closed once ctx is done or the instance is
+// closed.
+func (w *CatalogWatcher[T]) Watch(ctx context.Context) <-chan []CatalogChange[T] {
+	out := make(chan []CatalogChange[T])
+
+	ctx, stop, ok := w.i.beginWatch(ctx)
+	if !ok {
+		close(out)
+		return out
+	}
+
+	w.loadSnapshot()
+
+	go func() {
+		defer close(out)
+		defer stop()
+
+		failures := 0
+
+		for {
+			delay := w.interval
+
+			changes, err := w.poll(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				failures++
+				delay = watchBackoff(w.interval, w.MaxBackoff, failures)
+
+				w.i.logger.WithFields(log.Fields{
+					"catalog":  w.name,
+					"err":      err,
+					"failures": failures,
+					"delay":    delay,
+				}).Info("Catalog watcher poll failed")
+
+				if w.OnError != nil {
+					w.OnError(err)
+				}
+			} else {
+				failures = 0
+			}
+
+			if len(changes) > 0 {
+				select {
+				case out <- changes:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			timer := w.i.clock.NewTimer(delay)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+
+	return out
+}
+
+// poll reads the catalog and returns how it changed since the last
+// snapshot, which it replaces.  A catalog no newer than the snapshot, e.g.
+// still cached, or served stale while offline isn't compared.
+func (w *CatalogWatcher[T]) poll(ctx context.Context) ([]CatalogChange[T], error) {
+	catalog, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if catalog.Stale {
+		return nil, nil
+	}
+
+	if w.snapshot != nil && !catalog.AsOf.IsZero() && !catalog.AsOf.After(w.asOf) {
+		return nil, nil
+	}
+
+	var changes []CatalogChange[T]
+
+	if w.snapshot != nil {
+		changes = CatalogDiff(w.snapshot, catalog.Items)
+	}
+
+	if len(changes) > 0 {
+		w.i.logger.WithFields(log.Fields{
+			"catalog": w.name,
+			"added":   countChanges(changes, EntryAdded),
+			"removed": countChanges(changes, EntryRemoved),
+			"changed": countChanges(changes, EntryChanged),
+			"asOf":    catalog.AsOf,
+		}).Info("Catalog changed")
+	}
+
+	w.snapshot = catalog.Items
+	if w.snapshot == nil {
+		w.snapshot = []T{}
+	}
+
+	w.asOf = catalog.AsOf
+	w.saveSnapshot()
+
+	return changes, nil
+}
+
+func countChanges[T CatalogEntry](changes []CatalogChange[T], kind CatalogChangeKind) int {
+	n := 0
+
+	for _, c := range changes {
+		if c.Kind == kind {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (w *CatalogWatcher[T]) loadSnapshot() {
+	if w.i.cache == nil {
+		return
+	}
+
+	var snapshot catalogSnapshotT[T]
+
+	found, err := w.i.getCachedJSON(w.i.ctx, w.stateKey, &snapshot)
+	if err != nil {
+		w.i.logger.WithFields(log.Fields{"catalog": w.name, "err": err}).Info("Unable to load catalog watcher state")
+		return
+	}
+
+	if found {
+		w.snapshot, w.asOf = snapshot.Items, snapshot.AsOf
+	}
+}
+
+func (w *CatalogWatcher[T]) saveSnapshot() {
+	if w.i.cache == nil {
+		return
+	}
+
+	snapshot := catalogSnapshotT[T]{Items: w.snapshot, AsOf: w.asOf}
+
+	if err := w.i.setCachedJSON(w.i.ctx, w.stateKey, snapshot, catalogSnapshotTTL); err != nil {
+		w.i.logger.WithFields(log.Fields{"catalog": w.name, "err": err}).Info("Unable to save catalog watcher state")
+	}
+}
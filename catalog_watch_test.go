@@ -0,0 +1,146 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadCars(t *testing.T, fn string) []Car {
+	var cars []Car
+
+	if err := json.Unmarshal(readFixture(t, fn), &cars); err != nil {
+		t.Fatal(err)
+	}
+
+	return cars
+}
+
+func TestCatalogDiff(t *testing.T) {
+	changes := CatalogDiff(loadCars(t, "cars_week1.json"), loadCars(t, "cars_week2.json"))
+
+	// the MX-5 only changed images and search filters, which aren't decoded
+	if assert.Len(t, changes, 4) {
+		assert.Equal(t, EntryChanged, changes[0].Kind)
+		assert.Equal(t, int64(1), changes[0].ID)
+		assert.Equal(t, []string{"retired"}, changes[0].Fields)
+
+		assert.Equal(t, EntryRemoved, changes[1].Kind)
+		assert.Equal(t, "BMW M4 GT3", changes[1].Old.CarName)
+		assert.Nil(t, changes[1].New)
+
+		assert.Equal(t, []string{"car_name", "price"}, changes[2].Fields)
+		assert.Equal(t, Cents(995), changes[2].New.Price)
+		assert.Equal(t, "169 changed: car_name, price", changes[2].String())
+
+		assert.Equal(t, EntryAdded, changes[3].Kind)
+		assert.Equal(t, int64(188), changes[3].ID)
+		assert.Nil(t, changes[3].Old)
+	}
+
+	assert.Empty(t, CatalogDiff(loadCars(t, "cars_week2.json"), loadCars(t, "cars_week2.json")))
+}
+
+func TestCatalogDiffVolatile(t *testing.T) {
+	old := []Series{{SeriesID: 1, SeriesName: "Mazda Cup", Eligible: true}}
+	new := []Series{{SeriesID: 1, SeriesName: "Mazda Cup", Eligible: false}}
+
+	// eligibility depends on the member, not the series
+	assert.Empty(t, CatalogDiff(old, new))
+}
+
+// mockCatalog serves a catalog fixture through an s3 link, last modified
+// at the week it's the snapshot of
+type mockCatalog struct {
+	mu      sync.Mutex
+	fixture string
+	week    int
+}
+
+func (c *mockCatalog) set(fixture string, week int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fixture, c.week = fixture, week
+}
+
+func (c *mockCatalog) register(m *mockAPI, path string) {
+	m.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/s3%s"}`, m.URL, path)
+	})
+
+	m.mux.HandleFunc("/s3"+path, func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		fixture, week := c.fixture, c.week
+		c.mu.Unlock()
+
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		lastModified := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC).Add(time.Duration(week) * 7 * 24 * time.Hour)
+
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		w.Write(data)
+	})
+}
+
+func TestCarsWatcher(t *testing.T) {
+	m := newMockAPI(t)
+
+	catalog := &mockCatalog{fixture: "testdata/cars_week1.json", week: 1}
+	catalog.register(m, "/data/car/get")
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	changes := api.NewCarsWatcher(6 * time.Hour).Watch(ctx)
+
+	// the first poll only takes the snapshot
+	assert.Eventually(t, func() bool { return clock.waiting() == 1 }, time.Second, time.Millisecond)
+
+	catalog.set("testdata/cars_week2.json", 2)
+
+	// still cached, nothing to compare
+	clock.advance(6 * time.Hour)
+	assert.Eventually(t, func() bool { return clock.waiting() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, m.hitCount("/data/car/get"))
+
+	// downloaded again once the cached copy expired
+	clock.advance(24 * time.Hour)
+
+	got := <-changes
+	assert.Len(t, got, 4)
+	assert.Equal(t, 2, m.hitCount("/data/car/get"))
+
+	cancel()
+
+	for range changes {
+	}
+
+	// a restarted watcher compares with the cached snapshot
+	catalog.set("testdata/cars_week1.json", 3)
+	assert.NoError(t, api.PurgeCache("/data/car/get"))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	got = <-api.NewCarsWatcher(6 * time.Hour).Watch(ctx)
+	if assert.Len(t, got, 4) {
+		assert.Equal(t, EntryAdded, got[1].Kind)
+		assert.Equal(t, int64(132), got[1].ID)
+	}
+}
@@ -0,0 +1,124 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ChunkInfo describes the chunks of a chunked result without downloading
+// them, e.g. to hand the downloads to other processes.  Merge what they
+// download with AssembleChunks.
+type ChunkInfo struct {
+	BaseDownloadURL string
+	ChunkFileNames  []string
+
+	// ChunkSize is the rows per chunk, Rows the rows of all of them
+	ChunkSize int64
+	NumChunks int64
+	Rows      int64
+
+	// Expires is when the link to the envelope expired according to
+	// iRacing, the chunk URLs can't be counted on after that.  It's zero if
+	// iRacing didn't say.
+	Expires time.Time
+
+	// Type and Data are the rest of the envelope, Data without chunk_info
+	Type string
+	Data map[string]json.RawMessage
+}
+
+// URLs returns the URL of every chunk in order
+func (c *ChunkInfo) URLs() []string {
+	urls := make([]string, 0, len(c.ChunkFileNames))
+
+	for _, fileName := range c.ChunkFileNames {
+		urls = append(urls, c.BaseDownloadURL+fileName)
+	}
+
+	return urls
+}
+
+// GetChunkInfo returns the chunk info of the chunked result for uri
+// without downloading any chunks, ErrNotChunked if the result isn't
+// chunked.  Unlike GetChunksWithCache nothing is cached, the chunk URLs
+// expire.
+func (i *Irdata) GetChunkInfo(ctx context.Context, uri string) (*ChunkInfo, error) {
+	fetched, err := i.getEnvelope(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	link := fetched.link
+
+	var envelope struct {
+		Type string
+		Data map[string]json.RawMessage
+	}
+
+	if json.Unmarshal(fetched.data, &envelope) != nil || envelope.Data["chunk_info"] == nil {
+		return nil, ErrNotChunked
+	}
+
+	var chunkInfo struct {
+		ChunkSize       int64    `json:"chunk_size"`
+		NumChunks       int64    `json:"num_chunks"`
+		Rows            int64    `json:"rows"`
+		BaseDownloadURL string   `json:"base_download_url"`
+		ChunkFileNames  []string `json:"chunk_file_names"`
+	}
+
+	if err := json.Unmarshal(envelope.Data["chunk_info"], &chunkInfo); err != nil {
+		return nil, fmt.Errorf("reading chunk_info of %s: %w", uri, err)
+	}
+
+	delete(envelope.Data, "chunk_info")
+
+	info := &ChunkInfo{
+		BaseDownloadURL: chunkInfo.BaseDownloadURL,
+		ChunkFileNames:  chunkInfo.ChunkFileNames,
+		ChunkSize:       chunkInfo.ChunkSize,
+		NumChunks:       chunkInfo.NumChunks,
+		Rows:            chunkInfo.Rows,
+		Type:            envelope.Type,
+		Data:            envelope.Data,
+	}
+
+	if link != nil {
+		// a malformed expiry just leaves it unknown
+		info.Expires, _ = time.Parse(time.RFC3339, link.Expires)
+	}
+
+	return info, nil
+}
+
+// AssembleChunks merges chunks downloaded elsewhere, in the order of
+// ChunkInfo.ChunkFileNames, the way Get merges them: the encoding problems
+// chunks are known to have are repaired and a result without rows is null.
+func AssembleChunks(readers []io.Reader) (io.Reader, error) {
+	p := &payload{chunks: []Chunk{}}
+
+	for n, r := range readers {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = validateChunk(fmt.Sprintf("#%d", n), data)
+		if err != nil {
+			return nil, err
+		}
+
+		p.chunks = append(p.chunks, Chunk{Number: n, Data: data})
+	}
+
+	data, err := p.assemble()
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
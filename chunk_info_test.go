@@ -0,0 +1,95 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetChunkInfo(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2},{"id":3}]`))
+
+	m.mux.HandleFunc("/s3/data/stats/season_driver_standings", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"type":"stats_season_driver_standings","data":{"success":true,"season_id":4728,"car_class_id":84,`+
+			`"chunk_info":{"chunk_size":500,"num_chunks":2,"rows":742,"base_download_url":"%s/chunks/standings/","chunk_file_names":["a.json","b.json"]}}}`, m.URL)
+	})
+	m.handle("/data/stats/season_driver_standings", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/s3/data/stats/season_driver_standings","expires":"2024-03-01T12:15:00.000Z"}`, m.URL)
+	})
+	m.handleJSON("/data/constants/categories", `[{"value":1}]`)
+
+	api := m.openAuthed(t)
+
+	info, err := api.GetChunkInfo(context.Background(), "/data/stats/season_driver_standings?season_id=4728&car_class_id=84")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), info.ChunkSize)
+	assert.Equal(t, int64(2), info.NumChunks)
+	assert.Equal(t, int64(742), info.Rows)
+	assert.Equal(t, []string{m.URL + "/chunks/standings/a.json", m.URL + "/chunks/standings/b.json"}, info.URLs())
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 15, 0, 0, time.UTC), info.Expires)
+	assert.Equal(t, "stats_season_driver_standings", info.Type)
+	assert.JSONEq(t, "4728", string(info.Data["season_id"]))
+	assert.NotContains(t, info.Data, "chunk_info")
+
+	// nothing was downloaded
+	assert.Equal(t, 0, m.hitCount("/chunks/standings/a.json"))
+
+	_, err = api.GetChunkInfo(context.Background(), "/data/constants/categories")
+	assert.ErrorIs(t, err, ErrNotChunked)
+
+	// downloading the chunks yourself gets what Get merges
+	info, err = api.GetChunkInfo(context.Background(), "/data/results/search_series")
+	assert.NoError(t, err)
+	assert.True(t, info.Expires.IsZero())
+
+	var readers []io.Reader
+
+	for _, url := range info.URLs() {
+		resp, err := http.Get(url)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		readers = append(readers, resp.Body)
+	}
+
+	merged, err := AssembleChunks(readers)
+	assert.NoError(t, err)
+
+	data, err := io.ReadAll(merged)
+	assert.NoError(t, err)
+
+	whole, err := api.Get("/data/results/search_series")
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(whole), string(data))
+}
+
+func TestAssembleChunks(t *testing.T) {
+	bom, err := os.Open("testdata/chunk_bom.json")
+	assert.NoError(t, err)
+	defer bom.Close()
+
+	merged, err := AssembleChunks([]io.Reader{strings.NewReader(`[{"id":1}]`), strings.NewReader(`[]`), bom})
+	assert.NoError(t, err)
+
+	data, err := io.ReadAll(merged)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), `[{"id":1},`), string(data))
+
+	merged, err = AssembleChunks(nil)
+	assert.NoError(t, err)
+
+	data, err = io.ReadAll(merged)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+
+	_, err = AssembleChunks([]io.Reader{strings.NewReader(`{"id":1}`)})
+	assert.Error(t, err)
+}
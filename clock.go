@@ -0,0 +1,58 @@
+package irdata
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is where the package gets the time from: cache ttls, retry
+// backoff, rate limit waits and the polling watchers all go through it.
+// Tests can pass a fake (see the testutil package) to WithClock.
+type Clock interface {
+	Now() time.Time
+
+	// NewTimer returns a timer firing once d has passed
+	NewTimer(d time.Duration) Timer
+
+	// Sleep waits for d to pass, returning early with the error of ctx if
+	// it is done first
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Timer is a timer made by a Clock
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, see time.Timer.Stop
+	Stop() bool
+}
+
+// WithClock makes the instance use clock instead of the real time
+func WithClock(clock Clock) Option {
+	return func(i *Irdata) {
+		i.clock = clock
+	}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleepContext(ctx, d)
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
@@ -0,0 +1,474 @@
+// Command irdata is a small CLI over the irdata library: log in once, then
+// fetch raw /data responses, subsession results, lap data and series
+// searches, look after the cache and scrub recorded responses to share.
+//
+//	irdata [global flags] <command> [flags] [args]
+//
+// Output is JSON unless --csv is given to a command that has CSV.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/popmonkey/irdata"
+)
+
+const usage = `usage: irdata [global flags] <command> [flags] [args]
+
+commands:
+  login                           save credentials, prompting for them
+  get [--ttl d] <uri>             raw JSON of a /data uri
+  results <subsession id>         results of a subsession
+  laps <subsession id> <cust id>  laps of a driver in the race of a subsession
+  search-series [flags]           official series results, see --help
+  driver-stats [--csv] <category> driver stats of oval, road, dirt_oval,
+                                  dirt_road, sports_car or formula_car
+  cache stats                     what's cached
+  cache purge <uri>|--namespace n drop cached responses
+  scrub <dir> [<out dir>]         pseudonymize the personal data of the
+                                  recorded .json responses under dir, in
+                                  place unless out dir is given
+
+global flags:
+`
+
+// app is the CLI, its fields let tests run it against a mock API
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	// prompt asks for the credentials to save on login
+	prompt irdata.CredsProvider
+
+	// options are passed to irdata.Open
+	options []irdata.Option
+
+	keyFile   string
+	credsFile string
+	profile   string
+	cacheDir  string
+	noCache   bool
+	debug     bool
+}
+
+func main() {
+	a := &app{stdout: os.Stdout, stderr: os.Stderr, prompt: irdata.CredsFromTerminal{}}
+
+	if err := a.run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "irdata:", err)
+		os.Exit(1)
+	}
+}
+
+func (a *app) run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("irdata", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+
+	flags.Usage = func() {
+		fmt.Fprint(a.stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&a.keyFile, "key", os.Getenv("IRDATA_KEY"), "key file encrypting the credentials (IRDATA_KEY)")
+	flags.StringVar(&a.credsFile, "creds", os.Getenv("IRDATA_CREDS"), "credentials file (IRDATA_CREDS)")
+	flags.StringVar(&a.profile, "profile", irdata.DefaultProfile, "profile of the credentials file")
+	flags.StringVar(&a.cacheDir, "cache", defaultCacheDir(), "cache directory")
+	flags.BoolVar(&a.noCache, "no-cache", false, "don't use the cache")
+	flags.BoolVar(&a.debug, "debug", false, "log debugging output to stderr")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing command")
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+
+	switch command {
+	case "login":
+		return a.login(args)
+	case "get":
+		return a.withAPI(ctx, true, func(api *irdata.Irdata) error { return a.get(api, args) })
+	case "results":
+		return a.withAPI(ctx, true, func(api *irdata.Irdata) error { return a.results(ctx, api, args) })
+	case "laps":
+		return a.withAPI(ctx, true, func(api *irdata.Irdata) error { return a.laps(ctx, api, args) })
+	case "search-series":
+		return a.withAPI(ctx, true, func(api *irdata.Irdata) error { return a.searchSeries(ctx, api, args) })
+	case "driver-stats":
+		return a.withAPI(ctx, true, func(api *irdata.Irdata) error { return a.driverStats(ctx, api, args) })
+	case "cache":
+		return a.withAPI(ctx, false, func(api *irdata.Irdata) error { return a.cache(api, args) })
+	case "scrub":
+		return a.scrub(args)
+	}
+
+	flags.Usage()
+
+	return fmt.Errorf("unknown command %q", command)
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".cache"
+	}
+
+	return filepath.Join(dir, "irdata")
+}
+
+// withAPI opens an instance, with the cache unless --no-cache and logged in
+// if authed, and hands it to fn
+func (a *app) withAPI(ctx context.Context, authed bool, fn func(api *irdata.Irdata) error) error {
+	api := irdata.Open(ctx, a.options...)
+
+	defer api.Close()
+
+	if a.debug {
+		api.EnableDebug()
+	}
+
+	if !a.noCache {
+		if err := api.EnableCache(a.cacheDir, irdata.WithSharedCache()); err != nil {
+			return err
+		}
+	}
+
+	if authed {
+		if a.keyFile == "" || a.credsFile == "" {
+			return errors.New("must provide --key and --creds, see irdata login")
+		}
+
+		if err := api.AuthWithProfile(a.keyFile, a.credsFile, a.profile); err != nil {
+			return err
+		}
+	}
+
+	return fn(api)
+}
+
+func (a *app) login(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: irdata login")
+	}
+
+	if a.keyFile == "" || a.credsFile == "" {
+		return errors.New("must provide --key and --creds")
+	}
+
+	if err := irdata.SaveProvidedCredsToProfile(a.keyFile, a.credsFile, a.profile, a.prompt); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stderr, "Saved profile %s to %s\n", a.profile, a.credsFile)
+
+	return nil
+}
+
+func (a *app) get(api *irdata.Irdata, args []string) error {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+
+	ttl := flags.Duration("ttl", 15*time.Minute, "how long to cache the response")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: irdata get [--ttl d] <uri>")
+	}
+
+	var data []byte
+	var err error
+
+	if a.noCache {
+		data, err = api.Get(flags.Arg(0))
+	} else {
+		data, err = api.GetWithCache(flags.Arg(0), *ttl)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.stdout.Write(data); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(a.stdout)
+
+	return err
+}
+
+func (a *app) results(ctx context.Context, api *irdata.Irdata, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: irdata results <subsession id>")
+	}
+
+	subsessionID, err := parseID("subsession id", args[0])
+	if err != nil {
+		return err
+	}
+
+	var result *irdata.SubsessionResult
+
+	if a.noCache {
+		result, err = api.GetSubsessionResult(ctx, subsessionID)
+	} else {
+		result, err = api.GetSubsessionResultWithCache(ctx, subsessionID)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return a.writeJSON(result)
+}
+
+func (a *app) laps(ctx context.Context, api *irdata.Irdata, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: irdata laps <subsession id> <cust id>")
+	}
+
+	subsessionID, err := parseID("subsession id", args[0])
+	if err != nil {
+		return err
+	}
+
+	custID, err := parseID("cust id", args[1])
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/data/results/lap_data?subsession_id=%d&simsession_number=0&cust_id=%d", subsessionID, custID)
+
+	var laps []irdata.Lap
+
+	if err := api.GetJSON(ctx, uri, &laps); err != nil {
+		return err
+	}
+
+	return a.writeJSON(laps)
+}
+
+func (a *app) searchSeries(ctx context.Context, api *irdata.Irdata, args []string) error {
+	flags := flag.NewFlagSet("search-series", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+
+	var params irdata.SearchSeriesParams
+
+	from := flags.String("from", "", "earliest start, a date or RFC 3339 time")
+	to := flags.String("to", "", "latest start, a date or RFC 3339 time (default now)")
+	days := flags.Int("days", 0, "search the days before --to instead of giving --from")
+
+	flags.Int64Var(&params.SeriesID, "series", 0, "series id")
+	flags.Int64Var(&params.CustID, "cust", 0, "cust id of a driver")
+	flags.Int64Var(&params.TeamID, "team", 0, "team id")
+	flags.IntVar(&params.SeasonYear, "year", 0, "season year, with --quarter instead of a time range")
+	flags.IntVar(&params.SeasonQuarter, "quarter", 0, "season quarter")
+	flags.BoolVar(&params.OfficialOnly, "official", false, "only official sessions")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return errors.New("usage: irdata search-series [flags]")
+	}
+
+	end := time.Now().UTC()
+
+	if *to != "" {
+		t, err := parseTime(*to)
+		if err != nil {
+			return fmt.Errorf("--to: %w", err)
+		}
+
+		end = t
+	}
+
+	switch {
+	case *from != "":
+		t, err := parseTime(*from)
+		if err != nil {
+			return fmt.Errorf("--from: %w", err)
+		}
+
+		params.StartRangeBegin, params.StartRangeEnd = t, end
+	case *days > 0:
+		params.StartRangeBegin, params.StartRangeEnd = end.AddDate(0, 0, -*days), end
+	case params.SeasonYear == 0:
+		return errors.New("must provide --from, --days or --year and --quarter")
+	}
+
+	results, err := api.SearchSeriesResults(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	return a.writeJSON(results.Rows)
+}
+
+func (a *app) driverStats(ctx context.Context, api *irdata.Irdata, args []string) error {
+	flags := flag.NewFlagSet("driver-stats", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+
+	asCSV := flags.Bool("csv", false, "write the CSV as downloaded")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	categories := map[string]int64{
+		"oval":        irdata.CategoryOval,
+		"road":        irdata.CategoryRoad,
+		"dirt_oval":   irdata.CategoryDirtOval,
+		"dirt_road":   irdata.CategoryDirtRoad,
+		"sports_car":  irdata.CategorySportsCar,
+		"formula_car": irdata.CategoryFormulaCar,
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: irdata driver-stats [--csv] <category>")
+	}
+
+	categoryID, ok := categories[flags.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown category %q", flags.Arg(0))
+	}
+
+	if *asCSV {
+		data, err := api.GetDriverStatsByCategoryCSV(ctx, categoryID)
+		if err != nil {
+			return err
+		}
+
+		_, err = a.stdout.Write(data)
+
+		return err
+	}
+
+	stats, err := api.GetDriverStatsByCategory(ctx, categoryID)
+	if err != nil {
+		return err
+	}
+
+	return a.writeJSON(stats)
+}
+
+// cacheStats is what cache stats writes
+type cacheStats struct {
+	Entries int   `json:"entries"`
+	Stale   int   `json:"stale"`
+	Bytes   int64 `json:"bytes"`
+
+	Namespaces map[string]int `json:"namespaces,omitempty"`
+}
+
+func (a *app) cache(api *irdata.Irdata, args []string) error {
+	if a.noCache {
+		return errors.New("the cache commands need the cache")
+	}
+
+	if len(args) == 0 {
+		return errors.New("usage: irdata cache stats|purge")
+	}
+
+	switch args[0] {
+	case "stats":
+		entries, err := api.CacheEntries()
+		if err != nil {
+			return err
+		}
+
+		stats := cacheStats{Entries: len(entries), Namespaces: make(map[string]int)}
+
+		for _, entry := range entries {
+			stats.Bytes += int64(entry.Size)
+
+			if entry.Stale {
+				stats.Stale++
+			}
+
+			if entry.Namespace != "" {
+				stats.Namespaces[entry.Namespace]++
+			}
+		}
+
+		return a.writeJSON(stats)
+	case "purge":
+		flags := flag.NewFlagSet("cache purge", flag.ContinueOnError)
+		flags.SetOutput(a.stderr)
+
+		namespace := flags.String("namespace", "", "drop everything cached by the account of the namespace")
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		switch {
+		case *namespace != "" && flags.NArg() == 0:
+			return api.PurgeCacheNamespace(*namespace)
+		case *namespace == "" && flags.NArg() == 1:
+			return api.PurgeCache(flags.Arg(0))
+		}
+
+		return errors.New("usage: irdata cache purge <uri>|--namespace n")
+	}
+
+	return fmt.Errorf("unknown cache command %q", args[0])
+}
+
+func (a *app) scrub(args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errors.New("usage: irdata scrub <dir> [<out dir>]")
+	}
+
+	out := args[len(args)-1]
+
+	if err := irdata.ScrubFixtures(args[0], out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stderr, "Scrubbed %s to %s\n", args[0], out)
+
+	return nil
+}
+
+func (a *app) writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(a.stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+func parseID(name string, value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number, not %q", name, value)
+	}
+
+	return id, nil
+}
+
+// parseTime accepts a date, taken as midnight UTC, or an RFC 3339 time
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
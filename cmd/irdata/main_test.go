@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/popmonkey/irdata"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCreds struct{}
+
+func (testCreds) GetCreds() ([]byte, []byte) {
+	return []byte("prost@example.com"), []byte("senna")
+}
+
+// fakeAPI serves the fixtures of the library's testdata the way iRacing
+// does: behind s3 links, chunked where iRacing chunks
+type fakeAPI struct {
+	*httptest.Server
+
+	mux  *http.ServeMux
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	f := &fakeAPI{mux: http.NewServeMux(), hits: make(map[string]int)}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.hits[r.URL.Path]++
+		f.mu.Unlock()
+
+		f.mux.ServeHTTP(w, r)
+	}))
+
+	t.Cleanup(f.Close)
+
+	f.mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "authtoken_members", Value: "let-me-in", Path: "/"})
+		fmt.Fprint(w, `{"authcode":"let-me-in"}`)
+	})
+
+	// what logging in is checked with
+	f.handle("/data/constants/event_types", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"label":"Race","value":5}]`)
+	})
+
+	return f
+}
+
+func (f *fakeAPI) handle(path string, handler http.HandlerFunc) {
+	f.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("authtoken_members"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		handler(w, r)
+	})
+}
+
+// handleLinked serves body, of contentType, behind an s3 link
+func (f *fakeAPI) handleLinked(path string, contentType string, body []byte) {
+	f.mux.HandleFunc("/s3"+path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	})
+
+	f.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/s3%s?signature=abc"}`, f.URL, path)
+	})
+}
+
+// handleChunked serves chunks, JSON arrays, behind an s3 link to their
+// chunk_info
+func (f *fakeAPI) handleChunked(path string, chunks ...string) {
+	var names []string
+
+	for n, chunk := range chunks {
+		chunk := chunk
+
+		names = append(names, fmt.Sprintf(`"%d.json"`, n))
+
+		f.mux.HandleFunc(fmt.Sprintf("/chunks%s/%d.json", path, n), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, chunk)
+		})
+	}
+
+	f.handleLinked(path, "application/json", []byte(fmt.Sprintf(
+		`{"type":"chunked","data":{"success":true,"chunk_info":{"num_chunks":%d,"base_download_url":"%s/chunks%s/","chunk_file_names":[%s]}}}`,
+		len(chunks), f.URL, path, strings.Join(names, ","),
+	)))
+}
+
+func (f *fakeAPI) hitCount(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.hits[path]
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// newTestApp returns an app talking to f, run prepends the global flags
+// pointing at a key, creds file and cache of its own
+func newTestApp(t *testing.T, f *fakeAPI) (run func(args ...string) (string, error)) {
+	baseURL, err := url.Parse(f.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "test.key")
+
+	if err := os.WriteFile(keyFile, readFixture(t, "test.key"), 0o400); err != nil {
+		t.Fatal(err)
+	}
+
+	global := []string{
+		"--key", keyFile,
+		"--creds", filepath.Join(dir, "test.creds"),
+		"--cache", filepath.Join(dir, "cache"),
+	}
+
+	return func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+
+		a := &app{
+			stdout:  &stdout,
+			stderr:  &stderr,
+			prompt:  testCreds{},
+			options: []irdata.Option{irdata.WithBaseURL(baseURL)},
+		}
+
+		err := a.run(context.Background(), append(append([]string{}, global...), args...))
+
+		return stdout.String(), err
+	}
+}
+
+func TestCLI(t *testing.T) {
+	f := newFakeAPI(t)
+
+	f.handle("/data/constants/categories", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"label":"Oval","value":1}]`)
+	})
+
+	f.handleLinked("/data/results/get", "application/json", readFixture(t, "subsession_fresh.json"))
+	f.handleLinked("/data/driver_stats_by_category/road", "text/csv", readFixture(t, "driver_stats_road.csv"))
+	f.handleChunked("/data/results/search_series", string(readFixture(t, "split_search.json")))
+	f.handleChunked("/data/results/lap_data",
+		`[{"cust_id":123,"lap_number":1,"lap_time":912345,"flags":2,"lap_events":["pitted"]}]`,
+		`[{"cust_id":123,"lap_number":2,"lap_time":901234}]`,
+	)
+
+	run := newTestApp(t, f)
+
+	_, err := run("get", "/data/constants/categories")
+	assert.Error(t, err, "not logged in yet")
+
+	_, err = run("login")
+	assert.NoError(t, err)
+
+	t.Run("get", func(t *testing.T) {
+		for n := 0; n < 2; n++ {
+			out, err := run("get", "/data/constants/categories")
+			assert.NoError(t, err)
+			assert.JSONEq(t, `[{"label":"Oval","value":1}]`, out)
+		}
+
+		assert.Equal(t, 1, f.hitCount("/data/constants/categories"))
+
+		_, err := run("--no-cache", "get", "/data/constants/categories")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, f.hitCount("/data/constants/categories"))
+	})
+
+	t.Run("results", func(t *testing.T) {
+		out, err := run("results", "68911202")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var result irdata.SubsessionResult
+
+		assert.NoError(t, json.Unmarshal([]byte(out), &result))
+		assert.Equal(t, int64(68911202), result.SubsessionID)
+
+		_, err = run("results", "nope")
+		assert.Error(t, err)
+	})
+
+	t.Run("laps", func(t *testing.T) {
+		out, err := run("laps", "68911202", "123")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var laps []irdata.Lap
+
+		assert.NoError(t, json.Unmarshal([]byte(out), &laps))
+
+		if assert.Len(t, laps, 2) {
+			assert.True(t, laps[0].Flags.Has(irdata.LapFlagPitted))
+			assert.Equal(t, 2, laps[1].LapNumber)
+		}
+	})
+
+	t.Run("search-series", func(t *testing.T) {
+		out, err := run("search-series", "--series", "139", "--from", "2024-02-13", "--to", "2024-02-14")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var rows []irdata.SearchResult
+
+		assert.NoError(t, json.Unmarshal([]byte(out), &rows))
+		assert.NotEmpty(t, rows)
+
+		_, err = run("search-series", "--series", "139")
+		assert.Error(t, err)
+	})
+
+	t.Run("driver-stats", func(t *testing.T) {
+		out, err := run("driver-stats", "road")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var stats []irdata.DriverStats
+
+		assert.NoError(t, json.Unmarshal([]byte(out), &stats))
+
+		if assert.Len(t, stats, 3) {
+			assert.Equal(t, "Smith, Jane", stats[0].DisplayName)
+		}
+
+		out, err = run("driver-stats", "--csv", "road")
+		assert.NoError(t, err)
+
+		records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 4)
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		out, err := run("cache", "stats")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var stats cacheStats
+
+		assert.NoError(t, json.Unmarshal([]byte(out), &stats))
+		assert.Positive(t, stats.Entries)
+		assert.Positive(t, stats.Bytes)
+
+		_, err = run("cache", "purge", "/data/constants/categories")
+		assert.NoError(t, err)
+
+		_, err = run("get", "/data/constants/categories")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, f.hitCount("/data/constants/categories"))
+	})
+}
+
+func TestCLIScrub(t *testing.T) {
+	f := newFakeAPI(t)
+	run := newTestApp(t, f)
+
+	src := t.TempDir()
+	out := filepath.Join(t.TempDir(), "scrubbed")
+
+	if err := os.WriteFile(filepath.Join(src, "results.json"), readFixture(t, "subsession_fresh.json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := run("scrub", src, out)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	scrubbed, err := os.ReadFile(filepath.Join(out, "results.json"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(scrubbed), "Prost")
+	assert.Contains(t, string(scrubbed), "Okayama")
+
+	// in place
+	_, err = run("scrub", out)
+	assert.NoError(t, err)
+
+	again, err := os.ReadFile(filepath.Join(out, "results.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(scrubbed), string(again))
+
+	_, err = run("scrub")
+	assert.Error(t, err)
+}
+
+func TestCLIUsage(t *testing.T) {
+	f := newFakeAPI(t)
+	run := newTestApp(t, f)
+
+	_, err := run()
+	assert.Error(t, err)
+
+	_, err = run("bogus")
+	assert.Error(t, err)
+
+	_, err = run("cache", "bogus")
+	assert.Error(t, err)
+}
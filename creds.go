@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 
-	log "github.com/sirupsen/logrus"
 	"golang.org/x/term"
 )
 
@@ -29,7 +28,7 @@ func (CredsFromTerminal) GetCreds() ([]byte, []byte) {
 	fmt.Printf("\n\n")
 
 	if err != nil {
-		log.Panic("Error ReadPassword", err)
+		packageLogger.Panic("Error ReadPassword", err)
 	}
 
 	return []byte(username), password_bytes
@@ -0,0 +1,201 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// DistributionOptions are the options of the lap time distributions
+type DistributionOptions struct {
+	// OutlierFactor, if set, leaves out the laps slower than this multiple
+	// of the median, e.g. 1.5 to drop laps with an off
+	OutlierFactor float64
+}
+
+// LapDistribution is the distribution of a set of lap times, e.g. the best
+// qualifying laps of a season, to tell how a lap compares
+type LapDistribution struct {
+	// times are fastest first
+	times    []LapTime
+	excluded int
+}
+
+// NewLapDistribution returns the distribution of times.  Laps without a
+// time are ignored, outliers left out as opts says.
+func NewLapDistribution(times []LapTime, opts DistributionOptions) *LapDistribution {
+	d := &LapDistribution{}
+
+	for _, t := range times {
+		if t > 0 {
+			d.times = append(d.times, t)
+		}
+	}
+
+	sort.Slice(d.times, func(a, b int) bool { return d.times[a] < d.times[b] })
+
+	if opts.OutlierFactor > 0 && len(d.times) > 0 {
+		limit := LapTime(math.Floor(float64(d.quantile(0.5)) * opts.OutlierFactor))
+
+		n := sort.Search(len(d.times), func(n int) bool { return d.times[n] > limit })
+
+		d.excluded = len(d.times) - n
+		d.times = d.times[:n]
+	}
+
+	return d
+}
+
+// Count is the number of laps in the distribution
+func (d *LapDistribution) Count() int {
+	return len(d.times)
+}
+
+// Excluded is the number of outliers left out
+func (d *LapDistribution) Excluded() int {
+	return d.excluded
+}
+
+// Fastest returns the fastest lap, NoTime if there are none
+func (d *LapDistribution) Fastest() LapTime {
+	if len(d.times) == 0 {
+		return NoTime
+	}
+
+	return d.times[0]
+}
+
+// Median returns the median lap, NoTime if there are none
+func (d *LapDistribution) Median() LapTime {
+	return d.Quantile(0.5)
+}
+
+// P90 returns the lap 90% of the laps are at least as fast as, NoTime if
+// there are none
+func (d *LapDistribution) P90() LapTime {
+	return d.Quantile(0.9)
+}
+
+// Quantile returns the lap a share q (0 to 1) of the laps are at least as
+// fast as, interpolating between the laps around it.  It returns NoTime if
+// there are no laps.
+func (d *LapDistribution) Quantile(q float64) LapTime {
+	if len(d.times) == 0 {
+		return NoTime
+	}
+
+	return d.quantile(math.Max(0, math.Min(1, q)))
+}
+
+func (d *LapDistribution) quantile(q float64) LapTime {
+	h := float64(len(d.times)-1) * q
+	below := int(math.Floor(h))
+
+	if below+1 >= len(d.times) {
+		return d.times[below]
+	}
+
+	return LapTime(math.Round(float64(d.times[below]) + (h-float64(below))*float64(d.times[below+1]-d.times[below])))
+}
+
+// PercentileFor returns the percentile of lap: the percentage of the laps
+// slower than it, those as fast counting half.  The fastest of 100 laps is
+// in the 99.5th percentile.  It returns 0 if there are no laps.
+func (d *LapDistribution) PercentileFor(lap LapTime) float64 {
+	if len(d.times) == 0 {
+		return 0
+	}
+
+	faster := sort.Search(len(d.times), func(n int) bool { return d.times[n] >= lap })
+	asFast := sort.Search(len(d.times), func(n int) bool { return d.times[n] > lap }) - faster
+
+	slower := len(d.times) - faster - asFast
+
+	return 100 * (float64(slower) + float64(asFast)/2) / float64(len(d.times))
+}
+
+// GetQualifyDistribution returns the distribution of the best qualifying
+// laps of a class in a season.  The rows are decoded a chunk at a time
+// and only their lap times kept.
+func (i *Irdata) GetQualifyDistribution(ctx context.Context, params LeaderboardParams, opts DistributionOptions) (*LapDistribution, error) {
+	uri, err := leaderboardURI("/data/stats/season_qualify_results", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var times []LapTime
+
+	err = i.eachElement(ctx, uri, func(element json.RawMessage) error {
+		var row QualifyResult
+
+		if err := json.Unmarshal(element, &row); err != nil {
+			return err
+		}
+
+		times = append(times, row.BestQualLapTime)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLapDistribution(times, opts), nil
+}
+
+// GetWorldRecordDistribution returns the distribution of the laps lap picks
+// from the world records of a car at a track, the qualifying laps if nil.
+// The rows are decoded a chunk at a time and only their lap times kept.
+func (i *Irdata) GetWorldRecordDistribution(ctx context.Context, params WorldRecordParams, lap func(WorldRecord) LapTime, opts DistributionOptions) (*LapDistribution, error) {
+	uri, err := worldRecordsURI(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if lap == nil {
+		lap = func(r WorldRecord) LapTime { return r.Qualify }
+	}
+
+	var times []LapTime
+
+	err = i.eachElement(ctx, uri, func(element json.RawMessage) error {
+		var row WorldRecord
+
+		if err := json.Unmarshal(element, &row); err != nil {
+			return err
+		}
+
+		times = append(times, lap(row))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLapDistribution(times, opts), nil
+}
+
+// eachElement fetches uri and calls fn with each element of the array it
+// returns, decoding one chunk at a time rather than the assembled result so
+// WithMaxResponseSize doesn't apply
+func (i *Irdata) eachElement(ctx context.Context, uri string, fn func(json.RawMessage) error) error {
+	p, err := i.fetch(withoutResponseLimit(ctx), uri)
+	if err != nil {
+		return err
+	}
+
+	if !p.isChunked() {
+		return DecodeArrayStream(bytes.NewReader(p.data), fn)
+	}
+
+	for _, chunk := range p.chunks {
+		if err := DecodeArrayStream(bytes.NewReader(chunk.Data), fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syntheticLaps are the laps of 1s to 100s, an outlier of 1000s and a lap
+// without a time
+func syntheticLaps() []LapTime {
+	var times []LapTime
+
+	for n := 100; n >= 1; n-- {
+		times = append(times, LapTime(n*10000))
+	}
+
+	return append(times, 1000*10000, NoTime)
+}
+
+func TestLapDistribution(t *testing.T) {
+	d := NewLapDistribution(syntheticLaps(), DistributionOptions{OutlierFactor: 2})
+
+	assert.Equal(t, 100, d.Count())
+	assert.Equal(t, 1, d.Excluded())
+	assert.Equal(t, LapTime(10000), d.Fastest())
+	assert.Equal(t, LapTime(505000), d.Median())
+	assert.Equal(t, LapTime(901000), d.P90())
+	assert.Equal(t, LapTime(1000000), d.Quantile(1))
+	assert.Equal(t, LapTime(10000), d.Quantile(-1))
+
+	assert.Equal(t, 99.5, d.PercentileFor(10000))
+	assert.Equal(t, 90.5, d.PercentileFor(100000))
+	assert.Equal(t, 90.0, d.PercentileFor(105000))
+	assert.Equal(t, 100.0, d.PercentileFor(5000))
+	assert.Equal(t, 0.0, d.PercentileFor(2000000))
+
+	all := NewLapDistribution(syntheticLaps(), DistributionOptions{})
+
+	assert.Equal(t, 101, all.Count())
+	assert.Zero(t, all.Excluded())
+	assert.Equal(t, LapTime(510000), all.Median())
+	assert.Equal(t, LapTime(10000000), all.Quantile(1))
+
+	empty := NewLapDistribution(nil, DistributionOptions{OutlierFactor: 2})
+
+	assert.Zero(t, empty.Count())
+	assert.Equal(t, LapTime(NoTime), empty.Median())
+	assert.Zero(t, empty.PercentileFor(10000))
+}
+
+// lapRows renders times as rows of field, split into chunks of 40
+func lapRows(field string, times []LapTime) []string {
+	var chunks []string
+	var rows []string
+
+	for n, lap := range times {
+		rows = append(rows, fmt.Sprintf(`{"cust_id":%d,"%s":%d}`, n+1, field, lap))
+
+		if len(rows) == 40 || n == len(times)-1 {
+			chunks = append(chunks, "["+strings.Join(rows, ",")+"]")
+			rows = nil
+		}
+	}
+
+	return chunks
+}
+
+func TestGetQualifyDistribution(t *testing.T) {
+	m := newMockAPI(t)
+
+	m.handleChunked("/data/stats/season_qualify_results", func(r *http.Request) []string {
+		return lapRows("best_qual_lap_time", syntheticLaps())
+	})
+
+	api := m.openAuthed(t)
+
+	d, err := api.GetQualifyDistribution(context.Background(), LeaderboardParams{SeasonID: 4711, CarClassID: 1}, DistributionOptions{OutlierFactor: 2})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 100, d.Count())
+		assert.Equal(t, LapTime(505000), d.Median())
+	}
+
+	_, err = api.GetQualifyDistribution(context.Background(), LeaderboardParams{SeasonID: 4711}, DistributionOptions{})
+	assert.Error(t, err)
+}
+
+func TestGetWorldRecordDistribution(t *testing.T) {
+	m := newMockAPI(t)
+
+	var query string
+
+	m.handleChunked("/data/stats/world_records", func(r *http.Request) []string {
+		query = r.URL.RawQuery
+		return lapRows("race", syntheticLaps())
+	})
+
+	api := m.openAuthed(t)
+
+	params := WorldRecordParams{CarID: 67, TrackID: 18, SeasonYear: 2024, SeasonQuarter: 1}
+
+	d, err := api.GetWorldRecordDistribution(context.Background(), params, func(r WorldRecord) LapTime { return r.Race }, DistributionOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 101, d.Count())
+		assert.Equal(t, 100.0, d.PercentileFor(1))
+	}
+
+	assert.Equal(t, "car_id=67&season_quarter=1&season_year=2024&track_id=18", query)
+
+	// no qualifying laps in these rows
+	d, err = api.GetWorldRecordDistribution(context.Background(), params, nil, DistributionOptions{})
+	if assert.NoError(t, err) {
+		assert.Zero(t, d.Count())
+	}
+
+	records, err := api.GetWorldRecords(context.Background(), params)
+	if assert.NoError(t, err) && assert.Len(t, records, 102) {
+		assert.Equal(t, LapTime(1000000), records[0].Race)
+	}
+
+	_, err = api.GetWorldRecords(context.Background(), WorldRecordParams{CarID: 67})
+	assert.Error(t, err)
+}
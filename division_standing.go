@@ -0,0 +1,157 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// DriverStanding is a row of /data/stats/season_driver_standings
+type DriverStanding struct {
+	Rank              int    `json:"rank"`
+	CustID            int64  `json:"cust_id" pii:"cust_id"`
+	DisplayName       string `json:"display_name" pii:"name"`
+	Division          int    `json:"division"`
+	ClubID            int64  `json:"club_id" pii:"club"`
+	ClubName          string `json:"club_name" pii:"club"`
+	CountryCode       string `json:"country_code"`
+	CountryName       string `json:"country_name"`
+	WeeksCounted      int    `json:"weeks_counted"`
+	Starts            int    `json:"starts"`
+	Wins              int    `json:"wins"`
+	Top5              int    `json:"top5"`
+	Poles             int    `json:"poles"`
+	AvgStartPosition  int    `json:"avg_start_position"`
+	AvgFinishPosition int    `json:"avg_finish_position"`
+	AvgFieldSize      int    `json:"avg_field_size"`
+	Laps              int    `json:"laps"`
+	LapsLed           int    `json:"laps_led"`
+	Incidents         int    `json:"incidents"`
+	Points            int    `json:"points"`
+	RawPoints         int    `json:"raw_points"`
+}
+
+// GetSeasonDriverStandings returns the championship standings of a class in
+// a season, merging the chunks.  Club names iRacing leaves out and country
+// names are filled in from the lookups, fetched when first needed.
+func (i *Irdata) GetSeasonDriverStandings(ctx context.Context, params LeaderboardParams) ([]DriverStanding, error) {
+	var rows []DriverStanding
+
+	if err := i.getLeaderboard(ctx, "/data/stats/season_driver_standings", params, &rows); err != nil {
+		return nil, err
+	}
+
+	i.resolveStandings(ctx, params.SeasonID, rows)
+
+	return rows, nil
+}
+
+func (i *Irdata) resolveStandings(ctx context.Context, seasonID int64, rows []DriverStanding) {
+	var season *seasonKeyT
+
+	i.loadCountries(ctx)
+
+	for n := range rows {
+		row := &rows[n]
+
+		if row.ClubName == "" && row.ClubID != 0 {
+			if season == nil {
+				key := i.seasonKey(ctx, seasonID)
+				i.loadClubs(ctx, key)
+
+				season = &key
+			}
+
+			row.ClubName = i.ClubName(season.year, season.quarter, row.ClubID)
+		}
+
+		if row.CountryName == "" && row.CountryCode != "" {
+			row.CountryName = i.CountryName(row.CountryCode)
+		}
+	}
+}
+
+// DivisionStanding is where the authenticated member stands in their
+// division of a class in a season, see GetMyDivisionStanding
+type DivisionStanding struct {
+	SeasonID   int64
+	CarClassID int64
+
+	// Division is the member's, 0 based like iRacing's, Projected is set
+	// while it's only projected from their rating
+	Division  int
+	Projected bool
+
+	// Position is the member's place in the division, from 1, of the Size
+	// members ranked in it
+	Position int
+	Size     int
+
+	// Standing is the member's row, Neighbors the rows from window places
+	// ahead to window places behind, the member's included, in order
+	Standing  DriverStanding
+	Neighbors []DriverStanding
+}
+
+// GetMyDivisionStanding returns where the authenticated member stands in
+// their division of the car class in the season, with the window members
+// ahead and behind.  It fails with ErrNotRanked when the member isn't in
+// the standings, e.g. hasn't raced the series this season.
+func (i *Irdata) GetMyDivisionStanding(ctx context.Context, seasonID int64, carClassID int64, window int) (*DivisionStanding, error) {
+	if window < 0 {
+		window = 0
+	}
+
+	me, err := i.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var division MemberDivision
+
+	// divisions are those of the races, event type 5
+	uri := fmt.Sprintf("/data/stats/member_division?season_id=%d&event_type=5", seasonID)
+
+	if err := i.getProfileData(ctx, uri, &division); err != nil {
+		return nil, err
+	}
+
+	rows, err := i.GetSeasonDriverStandings(ctx, LeaderboardParams{
+		SeasonID:   seasonID,
+		CarClassID: carClassID,
+		Division:   &division.Division,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(rows, func(a, b int) bool { return rows[a].Rank < rows[b].Rank })
+
+	for n, row := range rows {
+		if row.CustID != me.CustID {
+			continue
+		}
+
+		first, last := n-window, n+window+1
+		if first < 0 {
+			first = 0
+		}
+
+		if last > len(rows) {
+			last = len(rows)
+		}
+
+		return &DivisionStanding{
+			SeasonID:   seasonID,
+			CarClassID: carClassID,
+			Division:   division.Division,
+			Projected:  division.Projected,
+			Position:   n + 1,
+			Size:       len(rows),
+			Standing:   row,
+			Neighbors:  rows[first:last],
+		}, nil
+	}
+
+	return nil, ErrNotRanked
+}
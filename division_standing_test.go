@@ -0,0 +1,99 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// handleDivisionStandings serves the standings fixture in two chunks, the
+// authenticated member being custID in division 3
+func (m *mockAPI) handleDivisionStandings(t *testing.T, custID int64) {
+	var rows []json.RawMessage
+
+	assert.NoError(t, json.Unmarshal(readFixture(t, "season_driver_standings_division.json"), &rows))
+
+	chunk := func(rows []json.RawMessage) string {
+		data, err := json.Marshal(rows)
+		assert.NoError(t, err)
+
+		return string(data)
+	}
+
+	m.handleLinked("/data/member/info", fmt.Sprintf(`{"cust_id":%d,"display_name":"Driver"}`, custID))
+	m.handleLinked("/data/stats/member_division", `{"season_id":4711,"event_type":5,"division":3,"projected":false}`)
+
+	m.handleChunked("/data/stats/season_driver_standings", func(r *http.Request) []string {
+		assert.Equal(t, "4711", r.URL.Query().Get("season_id"))
+		assert.Equal(t, "74", r.URL.Query().Get("car_class_id"))
+		assert.Equal(t, "3", r.URL.Query().Get("division"))
+
+		// the chunks aren't in rank order
+		return []string{chunk(rows[4:]), chunk(rows[:4])}
+	})
+}
+
+func neighborIDs(standing *DivisionStanding) []int64 {
+	var ids []int64
+
+	for _, row := range standing.Neighbors {
+		ids = append(ids, row.CustID)
+	}
+
+	return ids
+}
+
+func TestGetMyDivisionStandingMidDivision(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleDivisionStandings(t, 2004)
+
+	api := m.openAuthed(t)
+
+	standing, err := api.GetMyDivisionStanding(context.Background(), 4711, 74, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 3, standing.Division)
+	assert.False(t, standing.Projected)
+	assert.Equal(t, 4, standing.Position)
+	assert.Equal(t, 7, standing.Size)
+	assert.Equal(t, 1102, standing.Standing.Points)
+	assert.Equal(t, 318, standing.Standing.Laps)
+	assert.Equal(t, []int64{2002, 2003, 2004, 2005, 2006}, neighborIDs(standing))
+
+	standing, err = api.GetMyDivisionStanding(context.Background(), 4711, 74, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2004}, neighborIDs(standing))
+}
+
+func TestGetMyDivisionStandingLeader(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleDivisionStandings(t, 2001)
+
+	api := m.openAuthed(t)
+
+	standing, err := api.GetMyDivisionStanding(context.Background(), 4711, 74, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 1, standing.Position)
+	assert.Equal(t, 7, standing.Size)
+	assert.Equal(t, 4, standing.Standing.Wins)
+	assert.Equal(t, []int64{2001, 2002, 2003}, neighborIDs(standing))
+}
+
+func TestGetMyDivisionStandingNotRanked(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleDivisionStandings(t, 9999)
+
+	api := m.openAuthed(t)
+
+	_, err := api.GetMyDivisionStanding(context.Background(), 4711, 74, 2)
+	assert.ErrorIs(t, err, ErrNotRanked)
+}
@@ -0,0 +1,236 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// driverStatsTTL is how long the driver stats are cached, iRacing
+// regenerates them daily
+const driverStatsTTL = 24 * time.Hour
+
+// driverStatsCategories name the categories in the driver stats urls
+var driverStatsCategories = map[int64]string{
+	CategoryOval:       "oval",
+	CategoryRoad:       "road",
+	CategoryDirtOval:   "dirt_oval",
+	CategoryDirtRoad:   "dirt_road",
+	CategorySportsCar:  "sports_car",
+	CategoryFormulaCar: "formula_car",
+}
+
+// DriverStats is a row of the driver stats of a category
+type DriverStats struct {
+	CustID      int64
+	DisplayName string
+	Location    string
+	Region      string
+	ClubName    string
+
+	// License is the license class and safety rating, e.g. "A 4.99"
+	License  string
+	IRating  int
+	TTRating int
+
+	Starts       int
+	Wins         int
+	AvgStart     float64
+	AvgFinish    float64
+	AvgPoints    float64
+	Top25Pct     float64
+	Laps         int
+	LapsLed      int
+	AvgIncidents float64
+}
+
+// driverStatsColumns set the field of a column, by normalized header
+var driverStatsColumns = map[string]func(s *DriverStats, value string) error{
+	"CUSTID":    func(s *DriverStats, v string) (err error) { s.CustID, err = parseCSVInt(v); return },
+	"DRIVER":    func(s *DriverStats, v string) error { s.DisplayName = v; return nil },
+	"LOCATION":  func(s *DriverStats, v string) error { s.Location = v; return nil },
+	"REGION":    func(s *DriverStats, v string) error { s.Region = v; return nil },
+	"CLUBNAME":  func(s *DriverStats, v string) error { s.ClubName = v; return nil },
+	"CLASS":     func(s *DriverStats, v string) error { s.License = v; return nil },
+	"IRATING":   func(s *DriverStats, v string) (err error) { s.IRating, err = parseCSVCount(v); return },
+	"TTRATING":  func(s *DriverStats, v string) (err error) { s.TTRating, err = parseCSVCount(v); return },
+	"STARTS":    func(s *DriverStats, v string) (err error) { s.Starts, err = parseCSVCount(v); return },
+	"WINS":      func(s *DriverStats, v string) (err error) { s.Wins, err = parseCSVCount(v); return },
+	"AVGSTART":  func(s *DriverStats, v string) (err error) { s.AvgStart, err = parseCSVFloat(v); return },
+	"AVGFINISH": func(s *DriverStats, v string) (err error) { s.AvgFinish, err = parseCSVFloat(v); return },
+	"AVGPOINTS": func(s *DriverStats, v string) (err error) { s.AvgPoints, err = parseCSVFloat(v); return },
+	"TOP25PCNT": func(s *DriverStats, v string) (err error) { s.Top25Pct, err = parseCSVFloat(v); return },
+	"LAPS":      func(s *DriverStats, v string) (err error) { s.Laps, err = parseCSVCount(v); return },
+	"LAPSLEAD":  func(s *DriverStats, v string) (err error) { s.LapsLed, err = parseCSVCount(v); return },
+	"AVGINC":    func(s *DriverStats, v string) (err error) { s.AvgIncidents, err = parseCSVFloat(v); return },
+}
+
+// GetDriverStatsByCategory returns the stats of every driver of a category,
+// e.g. CategoryOval, parsed from the CSV iRacing links to.  It's cached for a
+// day when the cache is enabled.
+func (i *Irdata) GetDriverStatsByCategory(ctx context.Context, categoryID int64) ([]DriverStats, error) {
+	data, err := i.GetDriverStatsByCategoryCSV(ctx, categoryID)
+	if data == nil {
+		return nil, err
+	}
+
+	stats, parseErr := ParseDriverStats(bytes.NewReader(data))
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return stats, err
+}
+
+// GetDriverStatsByCategoryCSV is GetDriverStatsByCategory returning the CSV
+// as it is
+func (i *Irdata) GetDriverStatsByCategoryCSV(ctx context.Context, categoryID int64) ([]byte, error) {
+	category, ok := driverStatsCategories[categoryID]
+	if !ok {
+		return nil, fmt.Errorf("unknown category %d", categoryID)
+	}
+
+	uri := "/data/driver_stats_by_category/" + category
+
+	var p *payload
+	var err error
+
+	if i.cache == nil {
+		p, err = i.fetch(ctx, uri)
+	} else {
+		p, err = i.getPayloadWithCache(ctx, uri, driverStatsTTL)
+	}
+
+	if p == nil {
+		return nil, err
+	}
+
+	if p.isChunked() || !isCSV(p.contentType, p.data) {
+		return nil, fmt.Errorf("driver stats of %s aren't CSV", category)
+	}
+
+	return p.data, err
+}
+
+// ParseDriverStats parses driver stats CSV.  Columns are found by their
+// header, ignoring case and underscores, so their order doesn't matter and
+// unknown ones are skipped.  The CUSTID column is required.
+func ParseDriverStats(r io.Reader) ([]DriverStats, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading driver stats header: %w", err)
+	}
+
+	header = append([]string{}, header...)
+
+	setters := make([]func(*DriverStats, string) error, len(header))
+	found := false
+
+	for n, name := range header {
+		column := normalizeCSVHeader(name)
+
+		setters[n] = driverStatsColumns[column]
+		found = found || column == "CUSTID"
+	}
+
+	if !found {
+		return nil, errors.New("driver stats have no CUSTID column")
+	}
+
+	var stats []DriverStats
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return stats, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("reading driver stats: %w", err)
+		}
+
+		var row DriverStats
+
+		for n, value := range record {
+			if setters[n] == nil {
+				continue
+			}
+
+			if err := setters[n](&row, value); err != nil {
+				line, _ := reader.FieldPos(n)
+
+				return nil, fmt.Errorf("driver stats line %d column %s: %w", line, header[n], err)
+			}
+		}
+
+		stats = append(stats, row)
+	}
+}
+
+// normalizeCSVHeader is name upper cased without spaces, underscores and a
+// byte order mark
+func normalizeCSVHeader(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff")
+
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, strings.ToUpper(strings.TrimSpace(name)))
+}
+
+func parseCSVInt(value string) (int64, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func parseCSVCount(value string) (int, error) {
+	n, err := parseCSVInt(value)
+
+	return int(n), err
+}
+
+func parseCSVFloat(value string) (float64, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+// isCSV reports whether a linked object is CSV, by its content type or, for
+// cached objects which have none, by not being JSON
+func isCSV(contentType string, data []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return true
+	case "application/json":
+		return false
+	}
+
+	if json.Valid(data) {
+		return false
+	}
+
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+
+	return bytes.IndexByte(firstLine, ',') >= 0
+}
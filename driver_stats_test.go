@@ -0,0 +1,154 @@
+package irdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// handleCSV serves csv behind an s3 link at path, gzipped without a
+// Content-Encoding if gzipped is set
+func handleCSV(m *mockAPI, path string, csv string, gzipped bool) {
+	s3Path := "/s3" + path
+
+	m.mux.HandleFunc(s3Path, func(w http.ResponseWriter, r *http.Request) {
+		if !gzipped {
+			w.Header().Set("Content-Type", "text/csv")
+			fmt.Fprint(w, csv)
+			return
+		}
+
+		var buf bytes.Buffer
+
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(csv))
+		zw.Close()
+
+		w.Header().Set("Content-Type", "binary/octet-stream")
+		w.Write(buf.Bytes())
+	})
+
+	m.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s%s?signature=abc"}`, m.URL, s3Path)
+	})
+}
+
+func TestGetDriverStatsByCategory(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		t.Run(fmt.Sprintf("gzipped=%v", gzipped), func(t *testing.T) {
+			m := newMockAPI(t)
+			handleCSV(m, "/data/driver_stats_by_category/road", string(readFixture(t, "driver_stats_road.csv")), gzipped)
+
+			api := m.openAuthed(t)
+
+			stats, err := api.GetDriverStatsByCategory(context.Background(), CategoryRoad)
+			if !assert.NoError(t, err) || !assert.Len(t, stats, 3) {
+				return
+			}
+
+			assert.Equal(t, DriverStats{
+				CustID:       123456,
+				DisplayName:  "Smith, Jane",
+				Location:     "Oregon, US",
+				Region:       "US",
+				ClubName:     "US West",
+				License:      "A 4.99",
+				IRating:      5123,
+				TTRating:     1350,
+				Starts:       142,
+				Wins:         37,
+				AvgStart:     3.2,
+				AvgFinish:    2.9,
+				AvgPoints:    118.4,
+				Top25Pct:     68,
+				Laps:         5120,
+				LapsLed:      1877,
+				AvgIncidents: 1.74,
+			}, stats[0])
+
+			assert.Equal(t, `Jean "JJ" Dupont, Jr.`, stats[2].DisplayName)
+			assert.Equal(t, "Paris, FR", stats[2].Location)
+			assert.Equal(t, 1350, stats[2].IRating)
+
+			raw, err := api.GetDriverStatsByCategoryCSV(context.Background(), CategoryRoad)
+			assert.NoError(t, err)
+			assert.Equal(t, readFixture(t, "driver_stats_road.csv"), raw)
+		})
+	}
+}
+
+func TestGetDriverStatsByCategoryCached(t *testing.T) {
+	m := newMockAPI(t)
+	handleCSV(m, "/data/driver_stats_by_category/oval", "CUSTID,DRIVER,IRATING\n1,\"Doe, John\",2000\n", false)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	for n := 0; n < 2; n++ {
+		stats, err := api.GetDriverStatsByCategory(context.Background(), CategoryOval)
+		if assert.NoError(t, err) && assert.Len(t, stats, 1) {
+			assert.Equal(t, "Doe, John", stats[0].DisplayName)
+		}
+	}
+
+	assert.Equal(t, 1, m.hitCount("/data/driver_stats_by_category/oval"))
+
+	clock.advance(25 * time.Hour)
+
+	_, err := api.GetDriverStatsByCategory(context.Background(), CategoryOval)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, m.hitCount("/data/driver_stats_by_category/oval"))
+}
+
+func TestGetDriverStatsByCategoryUnknown(t *testing.T) {
+	m := newMockAPI(t)
+	api := m.openAuthed(t)
+
+	_, err := api.GetDriverStatsByCategory(context.Background(), 42)
+	assert.Error(t, err)
+}
+
+func TestGetJSONOfCSV(t *testing.T) {
+	m := newMockAPI(t)
+	handleCSV(m, "/data/driver_stats_by_category/oval", "CUSTID,DRIVER\n1,Doe\n", true)
+
+	api := m.openAuthed(t)
+
+	var v interface{}
+
+	err := api.GetJSON(context.Background(), "/data/driver_stats_by_category/oval", &v)
+	assert.ErrorIs(t, err, ErrNotJSON)
+
+	var notJSON *NotJSONError
+	if assert.ErrorAs(t, err, &notJSON) {
+		assert.Equal(t, "text/csv", notJSON.ContentType)
+	}
+}
+
+func TestParseDriverStats(t *testing.T) {
+	// column order and case don't matter, unknown columns are skipped
+	stats, err := ParseDriverStats(strings.NewReader(
+		"\ufeffwins,Cust_ID,NEW_COLUMN,driver,avg_inc\n3,99,x,\"Last, First\",\n",
+	))
+	if assert.NoError(t, err) && assert.Len(t, stats, 1) {
+		assert.Equal(t, DriverStats{CustID: 99, DisplayName: "Last, First", Wins: 3}, stats[0])
+	}
+
+	_, err = ParseDriverStats(strings.NewReader("DRIVER,IRATING\nDoe,2000\n"))
+	assert.Error(t, err)
+
+	_, err = ParseDriverStats(strings.NewReader("CUSTID,IRATING\n1,lots\n"))
+	assert.ErrorContains(t, err, "line 2 column IRATING")
+
+	_, err = ParseDriverStats(strings.NewReader(""))
+	assert.Error(t, err)
+}
@@ -0,0 +1,215 @@
+package irdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReasonOut is the decoded reason_out of a result row
+type ReasonOut int
+
+// Reasons out, ReasonOther for those not decoded
+const (
+	ReasonUnknown ReasonOut = iota
+	ReasonRunning
+	ReasonDisconnected
+	ReasonDisqualified
+	ReasonOther
+)
+
+var reasonOutNames = map[string]ReasonOut{
+	"running":      ReasonRunning,
+	"disconnected": ReasonDisconnected,
+	"disqualified": ReasonDisqualified,
+}
+
+func (r ReasonOut) String() string {
+	switch r {
+	case ReasonUnknown:
+		return "unknown"
+	case ReasonRunning:
+		return "running"
+	case ReasonDisconnected:
+		return "disconnected"
+	case ReasonDisqualified:
+		return "disqualified"
+	case ReasonOther:
+		return "other"
+	}
+
+	return fmt.Sprintf("ReasonOut(%d)", int(r))
+}
+
+// Reason decodes ReasonOut
+func (r SessionResultRow) Reason() ReasonOut {
+	if r.ReasonOut == "" {
+		return ReasonUnknown
+	}
+
+	if reason, ok := reasonOutNames[strings.ToLower(strings.TrimSpace(r.ReasonOut))]; ok {
+		return reason
+	}
+
+	return ReasonOther
+}
+
+// DriverSessionSummary is how a driver did in one simsession
+type DriverSessionSummary struct {
+	SimsessionNumber   int
+	SimsessionType     int
+	SimsessionTypeName string
+
+	// FinishPosition and ClassPosition are 0 based like iRacing's, of the
+	// team in team events.  ClassPosition is ranked from the overall
+	// positions within the car class, -1 for entries that didn't start.
+	FinishPosition int
+	ClassPosition  int
+
+	BestLap      LapTime
+	AverageLap   LapTime
+	LapsComplete int
+	LapsLed      int
+	Incidents    int
+	Reason       ReasonOut
+	Status       ParticipationStatus
+}
+
+// DriverSummary is what the result of a subsession says about a driver
+// across its simsessions, no lap data needed
+type DriverSummary struct {
+	CustID      int64
+	DisplayName string
+	TeamID      int64
+	CarClassID  int64
+
+	// Sessions are in the order of the result, e.g. practice, qualifying
+	// and race
+	Sessions []DriverSessionSummary
+
+	// BestLap is the best of all the simsessions, NoTime if none was set,
+	// the rest are totals
+	BestLap      LapTime
+	LapsComplete int
+	LapsLed      int
+	Incidents    int
+}
+
+// Session returns the summary of the simsession of type name (e.g. "Race"),
+// false if the driver wasn't in one
+func (s *DriverSummary) Session(name string) (DriverSessionSummary, bool) {
+	for _, session := range s.Sessions {
+		if strings.EqualFold(session.SimsessionTypeName, name) {
+			return session, true
+		}
+	}
+
+	return DriverSessionSummary{}, false
+}
+
+// DriverSummary returns the summary of the driver custID, false if they
+// aren't in the result
+func (r *SubsessionResult) DriverSummary(custID int64) (*DriverSummary, bool) {
+	for _, summary := range r.DriverSummaries() {
+		if summary.CustID == custID {
+			return &summary, true
+		}
+	}
+
+	return nil, false
+}
+
+// DriverSummaries returns the summaries of every driver in the result, in
+// the order they first appear.  In team events the drivers are those of
+// driver_results.
+func (r *SubsessionResult) DriverSummaries() []DriverSummary {
+	var summaries []DriverSummary
+
+	index := make(map[int64]int)
+
+	for _, session := range r.SessionResults {
+		classPositions := classPositions(session.Results)
+
+		for n, row := range session.Results {
+			drivers := row.DriverResults
+			if len(drivers) == 0 {
+				drivers = []SessionResultRow{row}
+			}
+
+			for _, driver := range drivers {
+				k, ok := index[driver.CustID]
+				if !ok {
+					k = len(summaries)
+					index[driver.CustID] = k
+
+					summaries = append(summaries, DriverSummary{
+						CustID:      driver.CustID,
+						DisplayName: driver.DisplayName,
+						TeamID:      row.TeamID,
+						CarClassID:  row.CarClassID,
+						BestLap:     NoTime,
+					})
+				}
+
+				summary := &summaries[k]
+
+				sessionSummary := DriverSessionSummary{
+					SimsessionNumber:   session.SimsessionNumber,
+					SimsessionType:     session.SimsessionType,
+					SimsessionTypeName: session.SimsessionTypeName,
+					FinishPosition:     row.FinishPosition,
+					ClassPosition:      classPositions[n],
+					BestLap:            lapTime(driver.BestLapTime),
+					AverageLap:         lapTime(driver.AverageLap),
+					LapsComplete:       driver.LapsComplete,
+					LapsLed:            driver.LapsLead,
+					Incidents:          driver.Incidents,
+					Reason:             row.Reason(),
+					Status:             row.Status,
+				}
+
+				summary.Sessions = append(summary.Sessions, sessionSummary)
+
+				if sessionSummary.BestLap != NoTime && (summary.BestLap == NoTime || sessionSummary.BestLap < summary.BestLap) {
+					summary.BestLap = sessionSummary.BestLap
+				}
+
+				summary.LapsComplete += driver.LapsComplete
+				summary.LapsLed += driver.LapsLead
+				summary.Incidents += driver.Incidents
+			}
+		}
+	}
+
+	return summaries
+}
+
+// classPositions ranks the rows within their car class by overall finish
+// position, iRacing doesn't always fill in the class positions.  Rows that
+// didn't start aren't ranked and are -1.
+func classPositions(rows []SessionResultRow) []int {
+	positions := make([]int, len(rows))
+
+	for n, row := range rows {
+		if !row.started() {
+			positions[n] = -1
+			continue
+		}
+
+		for _, other := range rows {
+			if other.started() && other.CarClassID == row.CarClassID && other.FinishPosition < row.FinishPosition {
+				positions[n]++
+			}
+		}
+	}
+
+	return positions
+}
+
+// lapTime is t as a LapTime, iRacing uses both -1 and 0 for no time
+func lapTime(t int) LapTime {
+	if t <= 0 {
+		return NoTime
+	}
+
+	return LapTime(t)
+}
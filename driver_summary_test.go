@@ -0,0 +1,131 @@
+package irdata
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSubsession(t *testing.T, fileName string) *SubsessionResult {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result SubsessionResult
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	return &result
+}
+
+func TestDriverSummarySingleClass(t *testing.T) {
+	result := loadSubsession(t, "testdata/subsession_fresh.json")
+
+	summaries := result.DriverSummaries()
+	assert.Len(t, summaries, 2)
+
+	prost, ok := result.DriverSummary(101)
+	assert.True(t, ok)
+	assert.Equal(t, "Prost Alain", prost.DisplayName)
+	assert.Equal(t, LapTime(1013422), prost.BestLap)
+	assert.Equal(t, "1:41.3422", prost.BestLap.String())
+	assert.Equal(t, 2, prost.Incidents)
+	assert.Equal(t, 14, prost.LapsComplete)
+
+	race, ok := prost.Session("race")
+	assert.True(t, ok)
+	assert.Equal(t, 0, race.FinishPosition)
+	assert.Equal(t, 0, race.ClassPosition)
+	assert.Equal(t, ReasonRunning, race.Reason)
+	assert.Equal(t, LapTime(NoTime), race.AverageLap)
+
+	_, ok = prost.Session("qualify")
+	assert.False(t, ok)
+
+	_, ok = result.DriverSummary(999)
+	assert.False(t, ok)
+}
+
+func TestDriverSummaryMultiClass(t *testing.T) {
+	result := loadSubsession(t, "testdata/subsession_multiclass.json")
+
+	var order []int64
+	for _, summary := range result.DriverSummaries() {
+		order = append(order, summary.CustID)
+	}
+
+	assert.Equal(t, []int64{201, 203, 204, 202}, order)
+
+	gtd, ok := result.DriverSummary(204)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4046), gtd.CarClassID)
+	assert.Len(t, gtd.Sessions, 3)
+
+	// the qualifying lap was the best of the weekend
+	assert.Equal(t, LapTime(1219120), gtd.BestLap)
+	assert.Equal(t, 10, gtd.Incidents)
+	assert.Equal(t, 28, gtd.LapsComplete)
+
+	race, _ := gtd.Session("Race")
+	assert.Equal(t, 3, race.FinishPosition)
+	assert.Equal(t, 1, race.ClassPosition)
+	assert.Equal(t, ReasonDisconnected, race.Reason)
+	assert.Equal(t, LapTime(1244390), race.AverageLap)
+
+	qualifying, _ := gtd.Session("Lone Qualifying")
+	assert.Equal(t, 2, qualifying.FinishPosition)
+	assert.Equal(t, 0, qualifying.ClassPosition)
+
+	// no qualifying time
+	other, _ := result.DriverSummary(203)
+	qualifying, _ = other.Session("Lone Qualifying")
+	assert.Equal(t, LapTime(NoTime), qualifying.BestLap)
+	assert.Equal(t, 1, qualifying.ClassPosition)
+
+	gtp, _ := result.DriverSummary(202)
+	assert.Len(t, gtp.Sessions, 2)
+	assert.Equal(t, LapTime(1095998), gtp.BestLap)
+	assert.Equal(t, 14, gtp.LapsLed)
+
+	race, _ = gtp.Session("race")
+	assert.Equal(t, 1, race.ClassPosition)
+}
+
+func TestDriverSummaryTeam(t *testing.T) {
+	result := loadSubsession(t, "testdata/subsession_team.json")
+
+	summaries := result.DriverSummaries()
+	assert.Len(t, summaries, 4)
+
+	driver, ok := result.DriverSummary(302)
+	assert.True(t, ok)
+	assert.Equal(t, int64(301301), driver.TeamID)
+	assert.Equal(t, LapTime(5038977), driver.BestLap)
+	assert.Equal(t, "8:23.8977", driver.BestLap.String())
+	assert.Equal(t, 2, driver.Incidents)
+	assert.Equal(t, 6, driver.LapsLed)
+
+	driver, _ = result.DriverSummary(304)
+	race, _ := driver.Session("race")
+	assert.Equal(t, 1, race.FinishPosition)
+	assert.Equal(t, 1, race.ClassPosition)
+	assert.Equal(t, 9, race.Incidents)
+	assert.Equal(t, 12, race.LapsComplete)
+
+	// the team rows aren't drivers
+	_, ok = result.DriverSummary(-301301)
+	assert.False(t, ok)
+}
+
+func TestReasonOut(t *testing.T) {
+	assert.Equal(t, ReasonRunning, SessionResultRow{ReasonOut: "Running"}.Reason())
+	assert.Equal(t, ReasonDisqualified, SessionResultRow{ReasonOut: "Disqualified"}.Reason())
+	assert.Equal(t, ReasonOther, SessionResultRow{ReasonOut: "Out of fuel"}.Reason())
+	assert.Equal(t, ReasonUnknown, SessionResultRow{}.Reason())
+	assert.Equal(t, "disconnected", ReasonDisconnected.String())
+}
@@ -0,0 +1,226 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ParticipationStatus is how an entry of a result took part in a
+// simsession, see SessionResultRow.Status
+type ParticipationStatus int
+
+// The participation statuses.  Entries that are StatusDNS or
+// StatusWithdrawn didn't start and are left out of the strength of field
+// and class positions.
+const (
+	StatusUnknown ParticipationStatus = iota
+
+	// StatusFinished is running at the end of a race, StatusRunning at the
+	// end of a session without a finish, e.g. practice or qualifying
+	StatusFinished
+	StatusRunning
+
+	StatusDNF
+	StatusDNS
+	StatusWithdrawn
+	StatusDisqualified
+)
+
+func (s ParticipationStatus) String() string {
+	switch s {
+	case StatusUnknown:
+		return "unknown"
+	case StatusFinished:
+		return "finished"
+	case StatusRunning:
+		return "running"
+	case StatusDNF:
+		return "dnf"
+	case StatusDNS:
+		return "dns"
+	case StatusWithdrawn:
+		return "withdrawn"
+	case StatusDisqualified:
+		return "disqualified"
+	}
+
+	return fmt.Sprintf("ParticipationStatus(%d)", int(s))
+}
+
+// Started reports whether the entry took the start, i.e. neither didn't
+// start nor withdrew
+func (s ParticipationStatus) Started() bool {
+	return s != StatusDNS && s != StatusWithdrawn
+}
+
+// reasonOutIDs are the reason_out_id codes known without loading them with
+// LoadReasonOuts
+var reasonOutIDs = map[int]ReasonOut{
+	0:  ReasonRunning,
+	32: ReasonDisconnected,
+}
+
+// knownReasonOut returns the reason of a reason_out_id known without
+// LoadReasonOuts
+func knownReasonOut(id int) (ReasonOut, bool) {
+	reason, ok := reasonOutIDs[id]
+
+	return reason, ok
+}
+
+// reasonOutsT are the reason_out_id codes of LoadReasonOuts
+type reasonOutsT struct {
+	mu   sync.Mutex
+	byID map[int]ReasonOut
+}
+
+// lookupTableT is an entry of /data/lookup/get
+type lookupTableT struct {
+	Tag          string `json:"tag"`
+	LookupValues []struct {
+		Description string `json:"description"`
+		Value       string `json:"value"`
+	} `json:"lookup_values"`
+}
+
+// LoadReasonOuts loads the reason_out_id codes from the lookup endpoint,
+// which Status then goes by along with the few it knows without, for rows
+// whose reason_out text isn't one it decodes
+func (i *Irdata) LoadReasonOuts(ctx context.Context) error {
+	var tables []lookupTableT
+
+	if err := i.getLookup(ctx, "/data/lookup/get", &tables); err != nil {
+		return err
+	}
+
+	byID := make(map[int]ReasonOut)
+
+	for _, table := range tables {
+		if table.Tag != "reason_out" {
+			continue
+		}
+
+		for _, v := range table.LookupValues {
+			id, err := strconv.Atoi(v.Value)
+			if err != nil {
+				continue
+			}
+
+			reason, ok := reasonOutNames[strings.ToLower(strings.TrimSpace(v.Description))]
+			if !ok {
+				reason = ReasonOther
+			}
+
+			byID[id] = reason
+		}
+	}
+
+	i.logger.WithFields(log.Fields{"len(byID)": len(byID)}).Debug("Loaded reason out codes")
+
+	i.reasonOuts.mu.Lock()
+	defer i.reasonOuts.mu.Unlock()
+
+	i.reasonOuts.byID = byID
+
+	return nil
+}
+
+// reasonOutByID returns the reason of a reason_out_id, from LoadReasonOuts
+// or else the codes known without
+func (i *Irdata) reasonOutByID(id int) (ReasonOut, bool) {
+	i.reasonOuts.mu.Lock()
+	defer i.reasonOuts.mu.Unlock()
+
+	if reason, ok := i.reasonOuts.byID[id]; ok {
+		return reason, true
+	}
+
+	return knownReasonOut(id)
+}
+
+// withdrawn reports whether the row is of an entry that withdrew, which
+// iRacing marks with a finish position of -1
+func (r SessionResultRow) withdrawn() bool {
+	return r.FinishPosition < 0 || strings.Contains(strings.ToLower(r.ReasonOut), "withdr")
+}
+
+// started reports whether the row took the start, rows that weren't
+// classified only not when they withdrew
+func (r SessionResultRow) started() bool {
+	if r.Status != StatusUnknown {
+		return r.Status.Started()
+	}
+
+	return !r.withdrawn()
+}
+
+// participationStatus classifies row of a race, or of a session without a
+// finish, reasonByID decoding the reason_out_id.  laps is whether any row
+// of the session completed a lap, without it 0 laps tells nothing.
+func participationStatus(row SessionResultRow, race bool, laps bool, reasonByID func(int) (ReasonOut, bool)) ParticipationStatus {
+	reason := row.Reason()
+
+	if reason == ReasonUnknown || reason == ReasonOther {
+		if byID, ok := reasonByID(row.ReasonOutID); ok && (reason == ReasonUnknown || byID != ReasonRunning) {
+			reason = byID
+		}
+	}
+
+	switch {
+	case reason == ReasonDisqualified:
+		return StatusDisqualified
+	case row.withdrawn():
+		return StatusWithdrawn
+	case laps && row.LapsComplete == 0:
+		return StatusDNS
+	case reason == ReasonRunning && race:
+		return StatusFinished
+	case reason == ReasonRunning:
+		return StatusRunning
+	case reason == ReasonUnknown:
+		return StatusUnknown
+	}
+
+	return StatusDNF
+}
+
+// isRaceSession reports whether the simsession has a finish, heats and
+// features included
+func isRaceSession(session SessionResults) bool {
+	name := strings.ToLower(session.SimsessionTypeName)
+
+	return strings.Contains(name, "race") || strings.Contains(name, "heat") || strings.Contains(name, "feature") || strings.Contains(name, "consolation")
+}
+
+// ClassifyEntries sets the Status of every row of the result, the drivers
+// of a team getting the team's.  GetSubsessionResult already did, this is
+// for results decoded otherwise.
+func (r *SubsessionResult) ClassifyEntries() {
+	r.classifyEntries(knownReasonOut)
+}
+
+func (r *SubsessionResult) classifyEntries(reasonByID func(int) (ReasonOut, bool)) {
+	for s := range r.SessionResults {
+		session := &r.SessionResults[s]
+		race := isRaceSession(*session)
+
+		laps := false
+		for _, row := range session.Results {
+			laps = laps || row.LapsComplete > 0
+		}
+
+		for n := range session.Results {
+			row := &session.Results[n]
+			row.Status = participationStatus(*row, race, laps, reasonByID)
+
+			for d := range row.DriverResults {
+				row.DriverResults[d].Status = row.Status
+			}
+		}
+	}
+}
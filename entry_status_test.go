@@ -0,0 +1,123 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func statuses(rows []SessionResultRow) map[int64]ParticipationStatus {
+	byCustID := make(map[int64]ParticipationStatus)
+
+	for _, row := range rows {
+		byCustID[row.CustID] = row.Status
+	}
+
+	return byCustID
+}
+
+func TestClassifyEntries(t *testing.T) {
+	result := loadSubsession(t, "testdata/subsession_dns_withdrawn.json")
+	result.ClassifyEntries()
+
+	assert.Equal(t, map[int64]ParticipationStatus{
+		101: StatusRunning,
+		103: StatusDNS,
+	}, statuses(result.SessionResults[0].Results))
+
+	race := mainEvent(*result)
+
+	assert.Equal(t, map[int64]ParticipationStatus{
+		101: StatusFinished,
+		102: StatusDNF,
+		103: StatusDNS,
+		104: StatusWithdrawn,
+		105: StatusDisqualified,
+		106: StatusDNF,
+	}, statuses(race))
+
+	// the non-starters' ratings don't count
+	assert.Equal(t, result.EventStrengthOfField, ComputeSOF(race))
+
+	assert.Equal(t, []int{0, 1, 2, 3, -1, -1}, classPositions(race))
+
+	mansell, ok := result.DriverSummary(103)
+	assert.True(t, ok)
+
+	session, ok := mansell.Session("race")
+	assert.True(t, ok)
+	assert.Equal(t, StatusDNS, session.Status)
+	assert.Equal(t, -1, session.ClassPosition)
+	assert.False(t, session.Status.Started())
+
+	assert.Equal(t, "withdrawn", StatusWithdrawn.String())
+	assert.Equal(t, "ParticipationStatus(42)", ParticipationStatus(42).String())
+}
+
+func TestClassifyEntriesTeams(t *testing.T) {
+	result := SubsessionResult{SessionResults: []SessionResults{{
+		SimsessionTypeName: "Race",
+		Results: []SessionResultRow{
+			{TeamID: 1, LapsComplete: 20, ReasonOut: "Running", DriverResults: []SessionResultRow{
+				{CustID: 11, LapsComplete: 20},
+				{CustID: 12, LapsComplete: 0},
+			}},
+			{TeamID: 2, FinishPosition: -1, ReasonOut: "Withdrawn", DriverResults: []SessionResultRow{
+				{CustID: 21},
+			}},
+		},
+	}}}
+
+	result.ClassifyEntries()
+
+	rows := result.SessionResults[0].Results
+
+	// a driver who didn't drive a stint finished with the team
+	assert.Equal(t, StatusFinished, rows[0].Status)
+	assert.Equal(t, StatusFinished, rows[0].DriverResults[1].Status)
+	assert.Equal(t, StatusWithdrawn, rows[1].DriverResults[0].Status)
+}
+
+func TestGetSubsessionResultClassified(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", string(readFixture(t, "subsession_dns_withdrawn.json")))
+	m.handleLinked("/data/lookup/get", `[
+		{"tag": "reason_out", "lookup_values": [
+			{"description": "Running", "value": "0"},
+			{"description": "Disqualified", "value": "1"},
+			{"description": "Withdrawn", "value": "16"},
+			{"description": "Disconnected", "value": "32"}
+		]},
+		{"tag": "licenses", "lookup_values": [{"description": "Rookie", "value": "1"}]}
+	]`)
+
+	api := m.openAuthed(t)
+	ctx := context.Background()
+
+	reason, ok := api.reasonOutByID(1)
+	assert.False(t, ok)
+	assert.Equal(t, ReasonUnknown, reason)
+
+	assert.NoError(t, api.LoadReasonOuts(ctx))
+
+	reason, ok = api.reasonOutByID(1)
+	assert.True(t, ok)
+	assert.Equal(t, ReasonDisqualified, reason)
+
+	reason, _ = api.reasonOutByID(16)
+	assert.Equal(t, ReasonOther, reason)
+
+	result, err := api.GetSubsessionResult(ctx, 69100404)
+	assert.NoError(t, err)
+
+	race := mainEvent(*result)
+	assert.Equal(t, StatusDisqualified, race[1].Status)
+	assert.Equal(t, StatusDNF, race[2].Status)
+	assert.Equal(t, StatusWithdrawn, race[5].Status)
+
+	// the dq is told by the code alone once loaded
+	race[1].ReasonOut, race[1].Status = "", StatusUnknown
+	assert.Equal(t, StatusDisqualified, participationStatus(race[1], true, true, api.reasonOutByID))
+	assert.Equal(t, StatusUnknown, participationStatus(race[1], true, true, knownReasonOut))
+}
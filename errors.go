@@ -0,0 +1,362 @@
+package irdata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors returned when authentication fails
+var (
+	ErrBadCredentials       = errors.New("login failed, check creds")
+	ErrVerificationRequired = errors.New("login requires verification, log into the iRacing website first")
+	ErrMaintenance          = errors.New("iRacing is down for maintenance")
+	ErrRateLimited          = errors.New("iRacing is rate limiting requests")
+	ErrAuthFailed           = errors.New("unexpected auth failure, try debug")
+)
+
+// Errors returned when a creds file can't be read, see InspectCredsFile
+var (
+	ErrWrongKey       = errors.New("creds file was encrypted with another key")
+	ErrCredsCorrupted = errors.New("creds file is corrupted")
+)
+
+// ErrUnsupportedCredsVersion is returned, as a *CredsVersionError, for a
+// creds file written in a format this version doesn't know, e.g. by a newer
+// release
+var ErrUnsupportedCredsVersion = errors.New("unsupported creds file version")
+
+// CredsVersionError is returned for a creds file of an unknown format.
+// Header is the first line of the file.  It matches
+// ErrUnsupportedCredsVersion.
+type CredsVersionError struct {
+	Header string
+}
+
+func (e *CredsVersionError) Error() string {
+	return fmt.Sprintf("%s %q", ErrUnsupportedCredsVersion, e.Header)
+}
+
+func (e *CredsVersionError) Is(target error) bool {
+	return target == ErrUnsupportedCredsVersion
+}
+
+// ErrSessionExpired is returned when the session expired and the
+// credentials weren't retained to log in again, see SetCredentialRetention.
+// Call an Auth method again to continue.
+var ErrSessionExpired = errors.New("session expired, auth again")
+
+// ErrClosed is returned by requests made after Close
+var ErrClosed = errors.New("irdata instance closed")
+
+// ErrNotAuthenticated is returned for a request made before authenticating
+// or after Logout, see AllowAnonymous
+var ErrNotAuthenticated = errors.New("not authenticated, call AuthWithCredsFromFile, AuthWithProvideCreds, AuthWithProfile or AuthWithOAuth first")
+
+// ErrNoRateLimit is returned by RateBudget until a response reported the
+// rate limit
+var ErrNoRateLimit = errors.New("no rate limit reported yet")
+
+// ErrTLSVerification is returned, as a *TLSVerificationError, when the
+// certificate of a host couldn't be verified
+var ErrTLSVerification = errors.New("tls certificate verification failed")
+
+// TLSVerificationError is returned when the certificate a host presented
+// couldn't be verified, typically behind a proxy intercepting TLS.  It
+// matches ErrTLSVerification and unwraps to the error of the transport.
+type TLSVerificationError struct {
+	Host string
+	Err  error
+}
+
+func (e *TLSVerificationError) Error() string {
+	return fmt.Sprintf("%v for %s: %v (to trust the CA of an intercepting proxy use WithRootCAs or WithTLSConfig)", ErrTLSVerification, e.Host, e.Err)
+}
+
+func (e *TLSVerificationError) Is(target error) bool {
+	return target == ErrTLSVerification
+}
+
+func (e *TLSVerificationError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNotYetAvailable is returned for results iRacing hasn't posted yet,
+// e.g. those of a race that just ended
+var ErrNotYetAvailable = errors.New("not yet available")
+
+// ErrPrivateData is returned for the data of a member whose profile is
+// private
+var ErrPrivateData = errors.New("member data is private")
+
+// ErrNotRanked is returned by GetMyDivisionStanding when the member isn't
+// in the standings, e.g. hasn't raced the series this season
+var ErrNotRanked = errors.New("not ranked in the standings")
+
+// ErrNotChunked is returned by GetChunkInfo for results that aren't chunked
+var ErrNotChunked = errors.New("result is not chunked")
+
+// ErrArchiveFull is logged, and passed to the hook set with
+// WithArchiveFullHook, when a payload isn't archived because the archive
+// reached the size set with WithArchiveMaxBytes
+var ErrArchiveFull = errors.New("archive is full")
+
+// ErrOffline is returned for requests that would need the network while
+// the instance is offline, see SetOffline
+var ErrOffline = errors.New("irdata is offline")
+
+// ErrCacheTimeout is what a cache operation that took longer than the
+// WithCacheTimeout timeout failed with
+var ErrCacheTimeout = errors.New("cache operation timed out")
+
+// ErrTimedOutWaiting is returned, as a *WaitTimeoutError, when results
+// didn't become available in time
+var ErrTimedOutWaiting = errors.New("timed out waiting")
+
+// WaitTimeoutError is returned by WaitForSubsessionResult when maxWait
+// elapses.  It matches ErrTimedOutWaiting.
+type WaitTimeoutError struct {
+	Attempts int
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("%v after %d attempts", ErrTimedOutWaiting, e.Attempts)
+}
+
+func (e *WaitTimeoutError) Is(target error) bool {
+	return target == ErrTimedOutWaiting
+}
+
+// ErrEmptyPayload is returned, as an *EmptyPayloadError, when an s3 link or
+// chunk kept answering with an empty body
+var ErrEmptyPayload = errors.New("empty payload")
+
+// EmptyPayloadError is returned when following a link or downloading a
+// chunk got nothing but empty bodies.  It matches ErrEmptyPayload.
+type EmptyPayloadError struct {
+	URL      string
+	Attempts int
+}
+
+func (e *EmptyPayloadError) Error() string {
+	return fmt.Sprintf("%v from %s after %d attempts", ErrEmptyPayload, redactString(e.URL, logRedaction()), e.Attempts)
+}
+
+func (e *EmptyPayloadError) Is(target error) bool {
+	return target == ErrEmptyPayload
+}
+
+// ErrNotJSON is returned, as a *NotJSONError, when the API answered with a
+// web page rather than JSON, e.g. for a uri that isn't a /data endpoint, or
+// GetJSON was given CSV
+var ErrNotJSON = errors.New("response is not JSON")
+
+// NotJSONError is returned when a response was HTML, or CSV where JSON was
+// expected.  It matches ErrNotJSON.
+type NotJSONError struct {
+	URL         string
+	ContentType string
+
+	// Prefix is the start of the body
+	Prefix string
+}
+
+func (e *NotJSONError) Error() string {
+	return fmt.Sprintf("%v from %s (content type %q) starting %q", ErrNotJSON, redactString(e.URL, logRedaction()), e.ContentType, e.Prefix)
+}
+
+func (e *NotJSONError) Is(target error) bool {
+	return target == ErrNotJSON
+}
+
+// ErrLegacyURL is returned, as a *LegacyURLError, for the URLs of the old
+// member site, which the /data API doesn't serve
+var ErrLegacyURL = errors.New("legacy membersite url")
+
+// LegacyURLError is returned for a legacy membersite URL.  Endpoint is the
+// /data endpoint to use instead, "" if there's none.  It matches
+// ErrLegacyURL.
+type LegacyURLError struct {
+	URI      string
+	Endpoint string
+}
+
+func (e *LegacyURLError) Error() string {
+	if e.Endpoint == "" {
+		return fmt.Sprintf("%v %s has no /data equivalent", ErrLegacyURL, e.URI)
+	}
+
+	return fmt.Sprintf("%v %s, use %s instead", ErrLegacyURL, e.URI, e.Endpoint)
+}
+
+func (e *LegacyURLError) Is(target error) bool {
+	return target == ErrLegacyURL
+}
+
+// ErrLinkExpired is returned, as a *LinkError, when s3 refused a link
+// because its signature expired.  Fetching the endpoint again gets a fresh
+// link.
+var ErrLinkExpired = errors.New("s3 link expired")
+
+// ErrLinkRejected is returned, as a *LinkError, when s3 refused a link for
+// another reason, typically headers added to the request (an Authorization
+// header or cookies) breaking the signature
+var ErrLinkRejected = errors.New("s3 link rejected")
+
+// LinkError is returned when s3 refused to serve a link or chunk.  Code and
+// Message are those of the XML error s3 answered with.  It matches
+// ErrLinkExpired or ErrLinkRejected.
+type LinkError struct {
+	URL     string
+	Status  int
+	Code    string
+	Message string
+}
+
+// Expired reports whether the signature of the link expired
+func (e *LinkError) Expired() bool {
+	switch e.Code {
+	case "ExpiredToken", "RequestExpired":
+		return true
+	case "AccessDenied":
+		return strings.Contains(strings.ToLower(e.Message), "expired")
+	}
+
+	return false
+}
+
+func (e *LinkError) Error() string {
+	reason := ErrLinkRejected
+	if e.Expired() {
+		reason = ErrLinkExpired
+	}
+
+	return fmt.Sprintf("%v: %s answered %d %s: %s", reason, redactString(e.URL, logRedaction()), e.Status, e.Code, e.Message)
+}
+
+func (e *LinkError) Is(target error) bool {
+	if e.Expired() {
+		return target == ErrLinkExpired
+	}
+
+	return target == ErrLinkRejected
+}
+
+// ErrCircuitOpen is returned, as a *CircuitOpenError, for requests refused
+// by the circuit breaker, see WithCircuitBreaker
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned for a request refused by the circuit
+// breaker.  It matches ErrCircuitOpen.
+type CircuitOpenError struct {
+	// RetryIn is how long until the breaker lets a probe through, 0 while
+	// a probe is in flight
+	RetryIn time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.RetryIn <= 0 {
+		return fmt.Sprintf("%v, probing", ErrCircuitOpen)
+	}
+
+	return fmt.Sprintf("%v, next probe in %v", ErrCircuitOpen, e.RetryIn)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// ErrResponseTooLarge is returned, as a *ResponseTooLargeError, for
+// responses larger than the limit set with WithMaxResponseSize
+var ErrResponseTooLarge = errors.New("response too large")
+
+// ResponseTooLargeError is returned for a response over the size limit.
+// Size is how large it was or, if Partial, how much was read before the
+// download was aborted.  It matches ErrResponseTooLarge.
+type ResponseTooLargeError struct {
+	URL     string
+	Size    int64
+	Limit   int64
+	Partial bool
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	const stream = "use GetChunksWithCache or GetElementsWithCache to stream it"
+
+	if e.Partial {
+		return fmt.Sprintf("%v: %s is over the %d byte limit, %s", ErrResponseTooLarge, e.URL, e.Limit, stream)
+	}
+
+	return fmt.Sprintf("%v: %s is %d bytes, over the %d byte limit, %s", ErrResponseTooLarge, e.URL, e.Size, e.Limit, stream)
+}
+
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// ErrRegionLocked is returned by SetRegion once the instance is
+// authenticated
+var ErrRegionLocked = errors.New("region can't change once authenticated")
+
+// ErrForeignLink is returned, as a *ForeignLinkError, when a link or chunk
+// points outside the region set with WithRegion
+var ErrForeignLink = errors.New("link outside the region refused")
+
+// ForeignLinkError is returned for a refused link.  It matches
+// ErrForeignLink.
+type ForeignLinkError struct {
+	URL    string
+	Region Region
+}
+
+func (e *ForeignLinkError) Error() string {
+	return fmt.Sprintf("%v: %s isn't a %v host", ErrForeignLink, redactString(e.URL, logRedaction()), e.Region)
+}
+
+func (e *ForeignLinkError) Is(target error) bool {
+	return target == ErrForeignLink
+}
+
+// ErrReservedHeader is returned for a call setting a header of the session
+// with WithHeader
+var ErrReservedHeader = errors.New("header is reserved")
+
+// ErrRedirectRefused is returned, as a *RedirectError, when a link or chunk
+// redirected to another host not allowed with WithRedirectHosts
+var ErrRedirectRefused = errors.New("redirect to another host refused")
+
+// RedirectError is returned for a refused redirect.  It matches
+// ErrRedirectRefused.
+type RedirectError struct {
+	URL      string
+	Location string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("%v: %s redirected to %s", ErrRedirectRefused, redactString(e.URL, logRedaction()), redactString(e.Location, logRedaction()))
+}
+
+func (e *RedirectError) Is(target error) bool {
+	return target == ErrRedirectRefused
+}
+
+// ErrInvalidParams is returned, as an *InvalidParamsError, for a request
+// whose parameters iRacing is known to answer with empty or misleading data
+var ErrInvalidParams = errors.New("invalid parameters")
+
+// InvalidParamsError names the parameters of a refused request.  It
+// matches ErrInvalidParams.
+type InvalidParamsError struct {
+	URI    string
+	Fields []string
+	Reason string
+}
+
+func (e *InvalidParamsError) Error() string {
+	return fmt.Sprintf("%v for %s: %s %s", ErrInvalidParams, redactString(e.URI, logRedaction()), strings.Join(e.Fields, " and "), e.Reason)
+}
+
+func (e *InvalidParamsError) Is(target error) bool {
+	return target == ErrInvalidParams
+}
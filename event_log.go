@@ -0,0 +1,270 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventLogEntry is a single entry of /data/results/event_log.  Session
+// times are in ten thousandths of a second.
+type EventLogEntry struct {
+	SubsessionID     int64  `json:"subsession_id"`
+	SimsessionNumber int    `json:"simsession_number"`
+	SessionTime      int64  `json:"session_time"`
+	EventSeq         int    `json:"event_seq"`
+	EventCode        int    `json:"event_code"`
+	GroupID          int64  `json:"group_id"`
+	CustID           int64  `json:"cust_id" pii:"cust_id"`
+	DisplayName      string `json:"display_name" pii:"name"`
+	LapNumber        int    `json:"lap_number"`
+	Description      string `json:"description"`
+	Message          string `json:"message"`
+
+	// CarIdx and CarNumber are the car of the entry, when iRacing says
+	CarIdx    *int   `json:"car_idx,omitempty"`
+	CarNumber string `json:"car_number,omitempty"`
+
+	// Penalty is the penalty or incident the entry is about, decoded by
+	// GetEventLog, nil for other entries
+	Penalty *Penalty `json:"-"`
+}
+
+// At returns the session time of the entry, false if unknown
+func (e EventLogEntry) At() (time.Duration, bool) {
+	return ticks(e.SessionTime)
+}
+
+// PenaltyType is the kind of a Penalty
+type PenaltyType string
+
+// The kinds of penalties, PenaltyUnknown for those the message doesn't
+// tell
+const (
+	PenaltyUnknown      PenaltyType = ""
+	PenaltySlowDown     PenaltyType = "slow_down"
+	PenaltyDriveThrough PenaltyType = "drive_through"
+	PenaltyStopAndHold  PenaltyType = "stop_and_hold"
+	PenaltyDisqualified PenaltyType = "disqualified"
+
+	// PenaltyIncident is incident points rather than a penalty
+	PenaltyIncident PenaltyType = "incident"
+)
+
+// PenaltyCause is why a Penalty was given
+type PenaltyCause string
+
+// The causes of penalties, CauseUnknown for those the message doesn't tell
+const (
+	CauseUnknown       PenaltyCause = ""
+	CauseTrackLimits   PenaltyCause = "track_limits"
+	CauseContact       PenaltyCause = "contact"
+	CausePitViolation  PenaltyCause = "pit_violation"
+	CauseIncidents     PenaltyCause = "incident_limit"
+	CauseLossOfControl PenaltyCause = "loss_of_control"
+	CauseOffTrack      PenaltyCause = "off_track"
+)
+
+// Penalty is a penalty, or incident points, decoded from the text of an
+// event log entry.  Raw is the text as sent, kept whether it was
+// recognized or not.
+type Penalty struct {
+	Type  PenaltyType
+	Cause PenaltyCause
+
+	// Hold is how long a stop and hold or slow down lasts, 0 if not said
+	Hold time.Duration
+
+	// IncidentPoints are the points of an incident, e.g. 4 for a 4x
+	IncidentPoints int
+
+	// OtherCarNumber and OtherCarIdx are the car the message involves, e.g.
+	// in contact, OtherCustID who drove it when the log tells
+	OtherCarNumber string
+	OtherCarIdx    *int
+	OtherCustID    int64
+
+	// Served is set for entries reporting a penalty served, on ServedLap
+	// at ServedAt into the session
+	Served    bool
+	ServedLap int
+	ServedAt  time.Duration
+
+	// Recognized is set when the kind of penalty was told, the text is
+	// only kept in Raw otherwise
+	Recognized bool
+	Raw        string
+}
+
+var (
+	penaltyHold      = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:s|sec|secs|second|seconds)\b`)
+	penaltyIncident  = regexp.MustCompile(`(?:^|[\s(])(\d+)x\b`)
+	penaltyCarNumber = regexp.MustCompile(`car\s*(?:no\.?|number)?\s*#\s*(\w+)`)
+	penaltyCarIdx    = regexp.MustCompile(`car\s*(?:idx|index)\s*:?\s*(\d+)`)
+	penaltyDQ        = regexp.MustCompile(`\b(?:dq|dsq|disqualified|disqualification)\b`)
+)
+
+// penaltyTypes are the phrases of the penalty types, first match wins
+var penaltyTypes = []struct {
+	phrases []string
+	kind    PenaltyType
+}{
+	{[]string{"drive through", "drive-through", "drivethrough"}, PenaltyDriveThrough},
+	{[]string{"stop and hold", "stop & hold", "stop-and-hold", "stop and go", "stop & go", "stop-and-go"}, PenaltyStopAndHold},
+	{[]string{"slow down", "slow-down", "slowdown"}, PenaltySlowDown},
+}
+
+// penaltyCauses are the phrases of the penalty causes, first match wins
+var penaltyCauses = []struct {
+	phrases []string
+	cause   PenaltyCause
+}{
+	{[]string{"pit speed", "pit road speed", "pit lane speed", "speeding in pit", "pit entry", "pit exit", "pit road", "pit lane", "closed pits", "pits closed"}, CausePitViolation},
+	{[]string{"cutting", "cut the course", "course cut", "corner cut", "track limits"}, CauseTrackLimits},
+	{[]string{"excessive incidents", "incident limit", "too many incidents"}, CauseIncidents},
+	{[]string{"contact", "collision", "car contact"}, CauseContact},
+	{[]string{"lost control", "loss of control"}, CauseLossOfControl},
+	{[]string{"off track", "off-track", "offtrack"}, CauseOffTrack},
+}
+
+// ParsePenalty decodes the penalty or incident text of an event log entry,
+// nil for text about neither.  It's tolerant of the formats iRacing used
+// over the years, what it doesn't recognize is only kept as Raw.
+func ParsePenalty(text string) *Penalty {
+	text = strings.TrimSpace(text)
+	lower := strings.ToLower(text)
+
+	p := &Penalty{Raw: text}
+
+	for _, t := range penaltyTypes {
+		if containsAny(lower, t.phrases) {
+			p.Type = t.kind
+			break
+		}
+	}
+
+	if p.Type == PenaltyUnknown && penaltyDQ.MatchString(lower) {
+		p.Type = PenaltyDisqualified
+	}
+
+	if m := penaltyIncident.FindStringSubmatch(lower); m != nil {
+		p.IncidentPoints, _ = strconv.Atoi(m[1])
+
+		if p.Type == PenaltyUnknown {
+			p.Type = PenaltyIncident
+		}
+	}
+
+	for _, c := range penaltyCauses {
+		if containsAny(lower, c.phrases) {
+			p.Cause = c.cause
+			break
+		}
+	}
+
+	if m := penaltyCarNumber.FindStringSubmatch(lower); m != nil {
+		p.OtherCarNumber = m[1]
+	}
+
+	if m := penaltyCarIdx.FindStringSubmatch(lower); m != nil {
+		idx, _ := strconv.Atoi(m[1])
+		p.OtherCarIdx = &idx
+	}
+
+	if p.Type == PenaltyStopAndHold || p.Type == PenaltySlowDown {
+		if m := penaltyHold.FindStringSubmatch(lower); m != nil {
+			seconds, _ := strconv.ParseFloat(m[1], 64)
+			p.Hold = time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	p.Served = strings.Contains(lower, "served")
+	p.Recognized = p.Type != PenaltyUnknown
+
+	if !p.Recognized && !containsAny(lower, []string{"penalty", "black flag", "served", "meatball"}) {
+		return nil
+	}
+
+	return p
+}
+
+func containsAny(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetEventLog returns the event log of the simsession of subsessionID
+// (0 for the main event), with the penalties and incidents decoded.  The
+// cars a penalty involves are resolved to cust_ids with the car numbers
+// and indexes the log itself gives.
+func (i *Irdata) GetEventLog(ctx context.Context, subsessionID int64, simsessionNumber int) ([]EventLogEntry, error) {
+	var entries []EventLogEntry
+
+	uri := fmt.Sprintf("/data/results/event_log?subsession_id=%d&simsession_number=%d", subsessionID, simsessionNumber)
+
+	if err := i.GetJSON(ctx, uri, &entries); err != nil {
+		return nil, err
+	}
+
+	DecodePenalties(entries)
+
+	return entries, nil
+}
+
+// DecodePenalties sets the Penalty of the entries of an event log, see
+// GetEventLog
+func DecodePenalties(entries []EventLogEntry) {
+	byNumber := make(map[string]int64)
+	byIdx := make(map[int]int64)
+
+	for _, e := range entries {
+		if e.CustID == 0 {
+			continue
+		}
+
+		if e.CarNumber != "" {
+			byNumber[strings.ToLower(e.CarNumber)] = e.CustID
+		}
+
+		if e.CarIdx != nil {
+			byIdx[*e.CarIdx] = e.CustID
+		}
+	}
+
+	for n := range entries {
+		e := &entries[n]
+
+		text := e.Description
+		if text == "" {
+			text = e.Message
+		} else if e.Message != "" && !strings.EqualFold(e.Message, e.Description) {
+			text += " " + e.Message
+		}
+
+		p := ParsePenalty(text)
+		if p == nil {
+			continue
+		}
+
+		if p.OtherCarNumber != "" {
+			p.OtherCustID = byNumber[p.OtherCarNumber]
+		} else if p.OtherCarIdx != nil {
+			p.OtherCustID = byIdx[*p.OtherCarIdx]
+		}
+
+		if p.Served {
+			p.ServedLap = e.LapNumber
+			p.ServedAt, _ = e.At()
+		}
+
+		e.Penalty = p
+	}
+}
@@ -0,0 +1,98 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// penaltyCase is an entry of testdata/penalty_messages.json, the messages
+// seen in event logs and what they decode to
+type penaltyCase struct {
+	Text      string       `json:"text"`
+	Type      *PenaltyType `json:"type"`
+	Cause     PenaltyCause `json:"cause"`
+	Hold      string       `json:"hold"`
+	Incidents int          `json:"incidents"`
+	CarNumber string       `json:"car_number"`
+	CarIdx    *int         `json:"car_idx"`
+	Served    bool         `json:"served"`
+}
+
+func TestParsePenalty(t *testing.T) {
+	var cases []penaltyCase
+
+	if err := json.Unmarshal(readFixture(t, "penalty_messages.json"), &cases); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		p := ParsePenalty(c.Text)
+
+		// no type, not a penalty
+		if c.Type == nil {
+			assert.Nil(t, p, c.Text)
+			continue
+		}
+
+		if !assert.NotNil(t, p, c.Text) {
+			continue
+		}
+
+		assert.Equal(t, c.Text, p.Raw)
+		assert.Equal(t, *c.Type, p.Type, c.Text)
+		assert.Equal(t, *c.Type != PenaltyUnknown, p.Recognized, c.Text)
+		assert.Equal(t, c.Cause, p.Cause, c.Text)
+		assert.Equal(t, c.Incidents, p.IncidentPoints, c.Text)
+		assert.Equal(t, c.CarNumber, p.OtherCarNumber, c.Text)
+		assert.Equal(t, c.CarIdx, p.OtherCarIdx, c.Text)
+		assert.Equal(t, c.Served, p.Served, c.Text)
+
+		var hold time.Duration
+		if c.Hold != "" {
+			hold, _ = time.ParseDuration(c.Hold)
+		}
+
+		assert.Equal(t, hold, p.Hold, c.Text)
+	}
+}
+
+func TestGetEventLog(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/event_log", string(readFixture(t, "event_log_race.json")))
+
+	api := m.openAuthed(t)
+
+	entries, err := api.GetEventLog(context.Background(), 69120431, 0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 7)
+
+	assert.Nil(t, entries[0].Penalty)
+
+	// resolved by car number and by car index
+	contact := entries[2].Penalty
+	assert.Equal(t, PenaltyIncident, contact.Type)
+	assert.Equal(t, 4, contact.IncidentPoints)
+	assert.Equal(t, int64(301122), contact.OtherCustID)
+	assert.Equal(t, int64(188204), entries[3].Penalty.OtherCustID)
+
+	// the description and message together
+	drive := entries[4].Penalty
+	assert.Equal(t, PenaltyDriveThrough, drive.Type)
+	assert.Equal(t, CausePitViolation, drive.Cause)
+	assert.Equal(t, "Black flag Drive Through, Pit Road Speeding", drive.Raw)
+
+	served := entries[5].Penalty
+	assert.True(t, served.Served)
+	assert.Equal(t, 6, served.ServedLap)
+	assert.Equal(t, 281187500*time.Microsecond, served.ServedAt)
+
+	// not recognized, still there
+	unknown := entries[6].Penalty
+	assert.False(t, unknown.Recognized)
+	assert.Equal(t, "Penalty issued by race control Code 4711, see stewards", unknown.Raw)
+	assert.Equal(t, "Code 4711, see stewards", entries[6].Message)
+}
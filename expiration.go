@@ -0,0 +1,106 @@
+package irdata
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const docURI = "/data/doc"
+
+type endpointDocT struct {
+	Link              string `json:"link"`
+	ExpirationSeconds int    `json:"expirationSeconds"`
+}
+
+type expirationsT struct {
+	mu     sync.Mutex
+	ignore bool
+	byPath map[string]time.Duration
+}
+
+// IgnoreEndpointExpirations leaves cache ttls entirely to the caller even
+// once LoadEndpointExpirations has been called
+func IgnoreEndpointExpirations() Option {
+	return func(i *Irdata) {
+		i.expirations.ignore = true
+	}
+}
+
+// LoadEndpointExpirations fetches the endpoint index from /data/doc which
+// documents for how long the responses of many endpoints stay valid.  From
+// then on GetWithCache and GetChunksWithCache cache those responses no
+// longer than documented and for as long as documented when passed a ttl of
+// 0.
+func (i *Irdata) LoadEndpointExpirations(ctx context.Context) error {
+	var doc map[string]map[string]endpointDocT
+
+	if err := i.GetJSON(ctx, docURI, &doc); err != nil {
+		return err
+	}
+
+	byPath := make(map[string]time.Duration)
+
+	for _, endpoints := range doc {
+		for _, endpoint := range endpoints {
+			if endpoint.ExpirationSeconds <= 0 {
+				continue
+			}
+
+			link, err := url.Parse(endpoint.Link)
+			if err != nil {
+				continue
+			}
+
+			byPath[link.Path] = time.Duration(endpoint.ExpirationSeconds) * time.Second
+		}
+	}
+
+	i.logger.WithFields(log.Fields{"len(byPath)": len(byPath)}).Debug("Loaded endpoint expirations")
+
+	i.expirations.mu.Lock()
+	defer i.expirations.mu.Unlock()
+
+	i.expirations.byPath = byPath
+
+	return nil
+}
+
+// EndpointExpiration returns for how long iRacing says responses of uri
+// stay valid, if LoadEndpointExpirations found out
+func (i *Irdata) EndpointExpiration(uri string) (time.Duration, bool) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return 0, false
+	}
+
+	i.expirations.mu.Lock()
+	defer i.expirations.mu.Unlock()
+
+	expiration, ok := i.expirations.byPath[u.Path]
+
+	return expiration, ok
+}
+
+// cacheTTL is the ttl to cache uri for when the caller asked for ttl
+func (i *Irdata) cacheTTL(uri string, ttl time.Duration) time.Duration {
+	if i.expirations.ignore {
+		return ttl
+	}
+
+	expiration, ok := i.EndpointExpiration(uri)
+	if !ok || (ttl > 0 && ttl <= expiration) {
+		return ttl
+	}
+
+	i.logger.WithFields(log.Fields{
+		"uri":        uri,
+		"ttl":        ttl,
+		"expiration": expiration,
+	}).Debug("Using documented expiration")
+
+	return expiration
+}
@@ -0,0 +1,91 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDoc = `{
+	"member": {
+		"info": {"link": "https://members-ng.iracing.com/data/member/info", "expirationSeconds": 900},
+		"profile": {"link": "https://members-ng.iracing.com/data/member/profile", "note": "no expiration"}
+	},
+	"track": {
+		"assets": {"link": "https://members-ng.iracing.com/data/track/assets", "expirationSeconds": 86400}
+	}
+}`
+
+func newExpirationAPI(t *testing.T, opts ...Option) (*mockAPI, *Irdata) {
+	m := newMockAPI(t)
+	m.handleJSON(docURI, testDoc)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t, opts...)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	assert.NoError(t, api.LoadEndpointExpirations(context.Background()))
+
+	return m, api
+}
+
+func cachedTTL(t *testing.T, api *Irdata, uri string) time.Duration {
+	entries, err := api.CacheEntries()
+	assert.NoError(t, err)
+
+	for _, entry := range entries {
+		if entry.URI == uri {
+			return entry.Expires.Sub(entry.Created)
+		}
+	}
+
+	t.Fatalf("%s not cached", uri)
+
+	return 0
+}
+
+func TestEndpointExpiration(t *testing.T) {
+	_, api := newExpirationAPI(t)
+
+	expiration, ok := api.EndpointExpiration("/data/member/info")
+	assert.True(t, ok)
+	assert.Equal(t, 15*time.Minute, expiration)
+
+	expiration, ok = api.EndpointExpiration("/data/track/assets?foo=bar")
+	assert.True(t, ok)
+	assert.Equal(t, 24*time.Hour, expiration)
+
+	_, ok = api.EndpointExpiration("/data/member/profile")
+	assert.False(t, ok)
+}
+
+func TestGetWithCacheUsesExpiration(t *testing.T) {
+	for _, test := range []struct {
+		ttl      time.Duration
+		expected time.Duration
+	}{
+		{time.Hour, 15 * time.Minute},
+		{0, 15 * time.Minute},
+		{time.Minute, time.Minute},
+	} {
+		_, api := newExpirationAPI(t)
+
+		_, err := api.GetWithCache("/data/member/info", test.ttl)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, cachedTTL(t, api, "/data/member/info"), test.ttl)
+	}
+}
+
+func TestIgnoreEndpointExpirations(t *testing.T) {
+	_, api := newExpirationAPI(t, IgnoreEndpointExpirations())
+
+	_, err := api.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, cachedTTL(t, api, "/data/member/info"))
+
+	// the guidance is still there to look at
+	_, ok := api.EndpointExpiration("/data/member/info")
+	assert.True(t, ok)
+}
@@ -0,0 +1,197 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// healthKey is read to check the cache backend is there, nothing is stored
+// under it
+const healthKey = "\x00irdata.health"
+
+type healthT struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+
+	// probeMu is held while probing so callers meanwhile wait for the
+	// result.  probeInterval is set by WithHealthProbe, 0 means HealthCheck
+	// doesn't probe.
+	probeMu       sync.Mutex
+	probeInterval time.Duration
+	probe         *HealthProbe
+}
+
+// HealthStatus is what HealthCheck found, ready to be encoded as the JSON
+// response of a readiness probe
+type HealthStatus struct {
+	// Healthy is set when authenticated with a session that hasn't expired,
+	// the cache (if any) is readable, the last probe (if any) succeeded and
+	// the circuit breaker (if any) is closed
+	Healthy bool `json:"healthy"`
+
+	Authenticated  bool `json:"authenticated"`
+	SessionExpired bool `json:"session_expired"`
+
+	// LastSuccess is when iRacing last answered a request without an
+	// error, zero if it hasn't yet
+	LastSuccess             time.Time `json:"last_success"`
+	SecondsSinceLastSuccess float64   `json:"seconds_since_last_success,omitempty"`
+
+	RateLimit RateLimit `json:"rate_limit"`
+
+	CacheEnabled bool   `json:"cache_enabled"`
+	CacheError   string `json:"cache_error,omitempty"`
+
+	// Probe is the latest authenticated request made by HealthCheck, nil
+	// without WithHealthProbe or before authenticating
+	Probe *HealthProbe `json:"probe,omitempty"`
+
+	// Breaker is the state of the circuit breaker, nil without
+	// WithCircuitBreaker.  An open breaker is unhealthy.
+	Breaker *BreakerStats `json:"breaker,omitempty"`
+}
+
+// HealthProbe is the result of a request HealthCheck made
+type HealthProbe struct {
+	At     time.Time `json:"at"`
+	OK     bool      `json:"ok"`
+	Status int       `json:"status,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// WithHealthProbe makes HealthCheck fetch the event type constants to check
+// the session works, at most once per interval however often it's called
+func WithHealthProbe(interval time.Duration) Option {
+	return func(i *Irdata) {
+		i.health.probeInterval = interval
+	}
+}
+
+// HealthCheck reports whether the instance can talk to iRacing.  It's
+// cheap enough to answer every readiness probe with: it makes no request
+// unless WithHealthProbe is set and never logs in, even with retained
+// credentials.
+func (i *Irdata) HealthCheck(ctx context.Context) HealthStatus {
+	status := HealthStatus{
+		Authenticated:  i.isAuthed,
+		SessionExpired: i.sessionExpired(),
+		RateLimit:      i.RateLimit(),
+		CacheEnabled:   i.cache != nil,
+	}
+
+	if status.CacheEnabled {
+		if _, err := i.cacheGet(ctx, []byte(healthKey)); err != nil {
+			status.CacheError = err.Error()
+		}
+	}
+
+	if status.Authenticated {
+		status.Probe = i.healthProbe(ctx)
+	}
+
+	status.Breaker = i.BreakerStats()
+
+	i.health.mu.Lock()
+	status.LastSuccess = i.health.lastSuccess
+	i.health.mu.Unlock()
+
+	if !status.LastSuccess.IsZero() {
+		status.SecondsSinceLastSuccess = i.clock.Now().Sub(status.LastSuccess).Seconds()
+	}
+
+	status.Healthy = status.Authenticated && !status.SessionExpired && status.CacheError == "" &&
+		(status.Probe == nil || status.Probe.OK) && (status.Breaker == nil || status.Breaker.State == BreakerClosed)
+
+	return status
+}
+
+// healthProbe returns the latest probe, probing first if it's older than
+// the interval.  Callers arriving during a probe wait for it.
+func (i *Irdata) healthProbe(ctx context.Context) *HealthProbe {
+	i.health.probeMu.Lock()
+	defer i.health.probeMu.Unlock()
+
+	if i.health.probeInterval <= 0 {
+		return nil
+	}
+
+	now := i.clock.Now()
+
+	if i.health.probe != nil && now.Sub(i.health.probe.At) < i.health.probeInterval {
+		probe := *i.health.probe
+		return &probe
+	}
+
+	probe := &HealthProbe{At: now}
+
+	// a single attempt without authedDo, which would log in again
+	status, err := i.probeRequest(ctx)
+	if err != nil {
+		probe.Error = redactString(err.Error(), logRedaction())
+	} else {
+		probe.Status = status
+		probe.OK = status == http.StatusOK
+
+		if !probe.OK {
+			probe.Error = fmt.Sprintf("%s answered %d", testURI, status)
+		}
+	}
+
+	i.logger.WithFields(log.Fields{"ok": probe.OK, "status": probe.Status}).Debug("Health probe")
+
+	i.health.probe = probe
+
+	result := *probe
+
+	return &result
+}
+
+func (i *Irdata) probeRequest(ctx context.Context) (int, error) {
+	if i.Offline() {
+		return 0, ErrOffline
+	}
+
+	url, err := i.resolveURL(testURI)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	header := i.bearerHeader(url.String(), nil)
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := i.clientFor(header).Do(req)
+	if err != nil {
+		return 0, tlsVerificationError(req.URL.Host, err)
+	}
+
+	resp.Body.Close()
+
+	i.noteRateLimit(resp)
+
+	if resp.StatusCode < 400 {
+		i.noteSuccess()
+	}
+
+	return resp.StatusCode, nil
+}
+
+// noteSuccess records that iRacing just answered a request
+func (i *Irdata) noteSuccess() {
+	i.health.mu.Lock()
+	defer i.health.mu.Unlock()
+
+	i.health.lastSuccess = i.clock.Now()
+}
@@ -0,0 +1,102 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckUnauthenticated(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.open(t, WithHealthProbe(time.Minute))
+
+	status := api.HealthCheck(context.Background())
+	assert.False(t, status.Healthy)
+	assert.False(t, status.Authenticated)
+	assert.Nil(t, status.Probe)
+	assert.True(t, status.LastSuccess.IsZero())
+	assert.Equal(t, 0, m.hitCount(testURI))
+}
+
+func TestHealthCheckProbesOncePerInterval(t *testing.T) {
+	m := newMockAPI(t)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock), WithHealthProbe(time.Minute))
+
+	// the login verified the session
+	assert.Equal(t, 1, m.hitCount(testURI))
+
+	clock.advance(10 * time.Second)
+
+	for n := 0; n < 5; n++ {
+		status := api.HealthCheck(context.Background())
+		assert.True(t, status.Healthy)
+		assert.True(t, status.Probe.OK)
+		assert.Equal(t, 200, status.Probe.Status)
+	}
+
+	assert.Equal(t, 2, m.hitCount(testURI))
+
+	clock.advance(30 * time.Second)
+
+	status := api.HealthCheck(context.Background())
+	assert.Equal(t, 2, m.hitCount(testURI))
+	assert.Equal(t, 30.0, status.SecondsSinceLastSuccess)
+
+	clock.advance(30 * time.Second)
+
+	status = api.HealthCheck(context.Background())
+	assert.Equal(t, 3, m.hitCount(testURI))
+	assert.Equal(t, 0.0, status.SecondsSinceLastSuccess)
+}
+
+func TestHealthCheckNeverLogsIn(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.open(t, WithHealthProbe(time.Minute))
+	api.SetCredentialRetention(RetainCredentials)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	expireSession(t, api)
+
+	status := api.HealthCheck(context.Background())
+	assert.False(t, status.Healthy)
+	assert.True(t, status.Authenticated)
+	assert.False(t, status.Probe.OK)
+	assert.Equal(t, 401, status.Probe.Status)
+	assert.Equal(t, 1, m.loginCount())
+}
+
+func TestHealthCheckWithoutProbe(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	status := api.HealthCheck(context.Background())
+	assert.True(t, status.Healthy)
+	assert.True(t, status.CacheEnabled)
+	assert.Nil(t, status.Probe)
+	assert.Equal(t, 1, m.hitCount(testURI))
+
+	api.EnableCacheBackend(failingCache{})
+
+	status = api.HealthCheck(context.Background())
+	assert.False(t, status.Healthy)
+	assert.NotEmpty(t, status.CacheError)
+
+	data, err := json.Marshal(status)
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, false, decoded["healthy"])
+	assert.Contains(t, decoded, "rate_limit")
+	assert.NotContains(t, decoded, "probe")
+}
@@ -0,0 +1,265 @@
+package irdata
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// HeatInfo is the heat racing format of a subsession, nil in results of
+// sessions that aren't heat racing.  Lengths of -1 mean the session is
+// timed rather than run over a number of laps.
+type HeatInfo struct {
+	HeatInfoID   int64  `json:"heat_info_id"`
+	HeatInfoName string `json:"heat_info_name"`
+	Description  string `json:"description"`
+	MaxEntrants  int    `json:"max_entrants"`
+
+	HeatLaps          int `json:"heat_laps"`
+	HeatLengthMinutes int `json:"heat_length_minutes"`
+
+	// HeatNumFromEachToMain drivers of each heat go straight to the
+	// feature, the others race the consolations
+	HeatNumFromEachToMain   int `json:"heat_num_from_each_to_main"`
+	HeatNumPositionToInvert int `json:"heat_num_position_to_invert"`
+
+	ConsolationNumToMain        int `json:"consolation_num_to_main"`
+	ConsolationNumToConsolation int `json:"consolation_num_to_consolation"`
+	ConsolationLaps             int `json:"consolation_laps"`
+	ConsolationLengthMinutes    int `json:"consolation_length_minutes"`
+
+	MainLaps          int `json:"main_laps"`
+	MainLengthMinutes int `json:"main_length_minutes"`
+}
+
+// HeatStageKind is the kind of a stage of a heat racing event
+type HeatStageKind int
+
+const (
+	HeatStageHeat HeatStageKind = iota
+	HeatStageConsolation
+	HeatStageFeature
+)
+
+func (k HeatStageKind) String() string {
+	switch k {
+	case HeatStageHeat:
+		return "heat"
+	case HeatStageConsolation:
+		return "consolation"
+	case HeatStageFeature:
+		return "feature"
+	default:
+		return "unknown"
+	}
+}
+
+// HeatStage is a race of a heat racing event, a simsession of one of its
+// subsessions
+type HeatStage struct {
+	SubsessionID     int64
+	SimsessionNumber int
+	Kind             HeatStageKind
+	Name             string
+	Results          []SessionResultRow
+}
+
+// HeatAdvance is a driver (or team) racing stage From then stage To, the
+// next they raced, as indexes in Stages.  The positions are those of the
+// results, 0 based.
+type HeatAdvance struct {
+	CustID      int64
+	TeamID      int64
+	DisplayName string
+
+	From         int
+	FromPosition int
+	To           int
+	ToPosition   int
+}
+
+// HeatProgression is how the drivers of a heat racing event went from the
+// heats through the consolations to the feature
+type HeatProgression struct {
+	// HeatInfo is the format of the event, nil for a session that isn't
+	// heat racing
+	HeatInfo *HeatInfo
+
+	// Stages are in running order: heats, consolations then the feature
+	Stages   []HeatStage
+	Advances []HeatAdvance
+}
+
+// GetHeatProgression returns the progression of the heat racing event
+// subsessionID is part of, fetching the subsessions associated with it
+// through the cache when it's enabled.  The progression of a session that
+// isn't heat racing is its race alone, with no advances.
+func (i *Irdata) GetHeatProgression(ctx context.Context, subsessionID int64) (*HeatProgression, error) {
+	get := i.GetSubsessionResult
+	if i.cache != nil {
+		get = i.GetSubsessionResultWithCache
+	}
+
+	first, err := get(ctx, subsessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if first.HeatInfo == nil && len(first.AssociatedSubsessionIDs) == 0 {
+		return &HeatProgression{Stages: raceStage(first)}, nil
+	}
+
+	results := []*SubsessionResult{first}
+	seen := map[int64]bool{subsessionID: true}
+
+	for n := 0; n < len(results); n++ {
+		for _, id := range results[n].AssociatedSubsessionIDs {
+			if seen[id] {
+				continue
+			}
+
+			seen[id] = true
+
+			result, err := get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	progression := &HeatProgression{}
+
+	for _, result := range results {
+		if progression.HeatInfo == nil {
+			progression.HeatInfo = result.HeatInfo
+		}
+
+		progression.Stages = append(progression.Stages, heatStages(result)...)
+	}
+
+	startTimes := make(map[int64]int64)
+	for _, result := range results {
+		startTimes[result.SubsessionID] = result.StartTime.UnixNano()
+	}
+
+	sort.SliceStable(progression.Stages, func(a, b int) bool {
+		sa, sb := progression.Stages[a], progression.Stages[b]
+
+		switch {
+		case sa.Kind != sb.Kind:
+			return sa.Kind < sb.Kind
+		case sa.SubsessionID != sb.SubsessionID:
+			return startTimes[sa.SubsessionID] < startTimes[sb.SubsessionID]
+		default:
+			return sa.SimsessionNumber < sb.SimsessionNumber
+		}
+	})
+
+	progression.Advances = heatAdvances(progression.Stages)
+
+	return progression, nil
+}
+
+// heatStageKind is the kind of stage of the simsession named name, false
+// for the practice and qualifying sessions
+func heatStageKind(name string) (HeatStageKind, bool) {
+	name = strings.ToUpper(name)
+
+	switch {
+	case strings.HasPrefix(name, "HEAT"):
+		return HeatStageHeat, true
+	case strings.HasPrefix(name, "CONSOLATION"):
+		return HeatStageConsolation, true
+	case strings.HasPrefix(name, "FEATURE"), name == "RACE":
+		return HeatStageFeature, true
+	default:
+		return 0, false
+	}
+}
+
+func heatStages(result *SubsessionResult) []HeatStage {
+	var stages []HeatStage
+
+	for _, session := range result.SessionResults {
+		kind, ok := heatStageKind(session.SimsessionName)
+		if !ok {
+			continue
+		}
+
+		stages = append(stages, HeatStage{
+			SubsessionID:     result.SubsessionID,
+			SimsessionNumber: session.SimsessionNumber,
+			Kind:             kind,
+			Name:             session.SimsessionName,
+			Results:          session.Results,
+		})
+	}
+
+	return stages
+}
+
+// raceStage is the single stage of a session that isn't heat racing, its
+// main event
+func raceStage(result *SubsessionResult) []HeatStage {
+	sessions := result.SessionResults
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	race := sessions[len(sessions)-1]
+
+	for _, session := range sessions {
+		if session.SimsessionNumber == 0 {
+			race = session
+		}
+	}
+
+	return []HeatStage{{
+		SubsessionID:     result.SubsessionID,
+		SimsessionNumber: race.SimsessionNumber,
+		Kind:             HeatStageFeature,
+		Name:             race.SimsessionName,
+		Results:          race.Results,
+	}}
+}
+
+// heatAdvances links each driver's stages in running order
+func heatAdvances(stages []HeatStage) []HeatAdvance {
+	type entrant struct {
+		custID int64
+		teamID int64
+	}
+
+	type last struct {
+		stage    int
+		position int
+	}
+
+	raced := make(map[entrant]last)
+
+	var advances []HeatAdvance
+
+	for n, stage := range stages {
+		for _, row := range stage.Results {
+			e := entrant{custID: row.CustID, teamID: row.TeamID}
+
+			if previous, ok := raced[e]; ok {
+				advances = append(advances, HeatAdvance{
+					CustID:       row.CustID,
+					TeamID:       row.TeamID,
+					DisplayName:  row.DisplayName,
+					From:         previous.stage,
+					FromPosition: previous.position,
+					To:           n,
+					ToPosition:   row.FinishPosition,
+				})
+			}
+
+			raced[e] = last{stage: n, position: row.FinishPosition}
+		}
+	}
+
+	return advances
+}
@@ -0,0 +1,98 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func handleHeatFixtures(m *mockAPI) {
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("subsession_id") {
+		case "70000001":
+			http.ServeFile(w, r, "testdata/subsession_heats.json")
+		case "70000002":
+			http.ServeFile(w, r, "testdata/subsession_heats_feature.json")
+		case "61290877":
+			http.ServeFile(w, r, "testdata/subsession_old.json")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestGetHeatProgression(t *testing.T) {
+	m := newMockAPI(t)
+	handleHeatFixtures(m)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	// from the feature, the heats are found through the associated ids
+	progression, err := api.GetHeatProgression(context.Background(), 70000002)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.NotNil(t, progression.HeatInfo) {
+		assert.Equal(t, int64(2041), progression.HeatInfo.HeatInfoID)
+		assert.Equal(t, 1, progression.HeatInfo.HeatNumFromEachToMain)
+		assert.Equal(t, 2, progression.HeatInfo.ConsolationNumToMain)
+	}
+
+	var stages []string
+	for _, stage := range progression.Stages {
+		stages = append(stages, stage.Kind.String()+" "+stage.Name)
+	}
+
+	assert.Equal(t, []string{"heat HEAT 1", "heat HEAT 2", "consolation CONSOLATION", "feature FEATURE"}, stages)
+	assert.Equal(t, int64(70000002), progression.Stages[3].SubsessionID)
+
+	advances := make(map[int64][]HeatAdvance)
+	for _, advance := range progression.Advances {
+		advances[advance.CustID] = append(advances[advance.CustID], advance)
+	}
+
+	// the heat winners went straight to the feature
+	assert.Equal(t, []HeatAdvance{{CustID: 201, DisplayName: "Kyle Larson", From: 0, FromPosition: 0, To: 3, ToPosition: 1}}, advances[201])
+	assert.Equal(t, []HeatAdvance{{CustID: 204, DisplayName: "Carson Macedo", From: 1, FromPosition: 0, To: 3, ToPosition: 0}}, advances[204])
+
+	// the others through the consolation, where two made it
+	assert.Equal(t, []HeatAdvance{
+		{CustID: 205, DisplayName: "David Gravel", From: 1, FromPosition: 1, To: 2, ToPosition: 0},
+		{CustID: 205, DisplayName: "David Gravel", From: 2, FromPosition: 0, To: 3, ToPosition: 2},
+	}, advances[205])
+
+	if assert.Len(t, advances[206], 1) {
+		assert.Equal(t, 2, advances[206][0].To)
+	}
+
+	assert.Len(t, progression.Advances, 8)
+
+	// both subsessions are cached
+	hits := m.hitCount("/data/results/get")
+
+	_, err = api.GetHeatProgression(context.Background(), 70000001)
+	assert.NoError(t, err)
+	assert.Equal(t, hits, m.hitCount("/data/results/get"))
+}
+
+func TestGetHeatProgressionNotHeatRacing(t *testing.T) {
+	m := newMockAPI(t)
+	handleHeatFixtures(m)
+
+	progression, err := m.openAuthed(t).GetHeatProgression(context.Background(), 61290877)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Nil(t, progression.HeatInfo)
+	assert.Empty(t, progression.Advances)
+
+	if assert.Len(t, progression.Stages, 1) {
+		assert.Equal(t, HeatStageFeature, progression.Stages[0].Kind)
+		assert.Len(t, progression.Stages[0].Results, 2)
+	}
+}
@@ -0,0 +1,188 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// hedgeMinHeadroom is the share of the rate limit that must be left for a
+// request to be hedged
+const hedgeMinHeadroom = 0.2
+
+type hedgeT struct {
+	mu    sync.Mutex
+	delay time.Duration
+	stats HedgeStats
+}
+
+// HedgeStats counts what hedging did, see WithHedging
+type HedgeStats struct {
+	// Hedged is how many duplicate requests were sent and Won how many of
+	// those answered first
+	Hedged int64
+	Won    int64
+
+	// SkippedRateLimit is how many requests weren't hedged because too
+	// little of the rate limit was left
+	SkippedRateLimit int64
+}
+
+type noHedgeKey struct{}
+
+// withoutHedging returns a context whose requests aren't hedged
+func withoutHedging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noHedgeKey{}, true)
+}
+
+// WithHedging sends a second copy of a GET when no response to the first
+// arrived within delay and uses whichever answers first, cancelling the
+// other.  It trades an extra request for the odd one iRacing takes seconds
+// to answer.  At most one copy is sent per attempt and none while less than
+// a fifth of the rate limit is left.  POSTs and logging in are never
+// hedged.
+func WithHedging(delay time.Duration) Option {
+	return func(i *Irdata) {
+		i.hedge.delay = delay
+	}
+}
+
+// HedgeStats returns what hedging did so far
+func (i *Irdata) HedgeStats() HedgeStats {
+	i.hedge.mu.Lock()
+	defer i.hedge.mu.Unlock()
+
+	return i.hedge.stats
+}
+
+// shouldHedge reports whether req may be hedged
+func (i *Irdata) shouldHedge(req *http.Request) bool {
+	return i.hedge.delay > 0 && req.Method == http.MethodGet && req.Context().Value(noHedgeKey{}) == nil
+}
+
+// hedgeHeadroom reports whether enough of the rate limit is left to hedge,
+// counting the request skipped if not
+func (i *Irdata) hedgeHeadroom() bool {
+	rl := i.RateLimit()
+
+	if rl.Limit <= 0 || float64(rl.Remaining) >= float64(rl.Limit)*hedgeMinHeadroom {
+		return true
+	}
+
+	i.hedge.mu.Lock()
+	i.hedge.stats.SkippedRateLimit++
+	i.hedge.mu.Unlock()
+
+	return false
+}
+
+type hedgeResult struct {
+	resp  *http.Response
+	err   error
+	n     int
+	hedge bool
+}
+
+// sendHedged is client.Do(req) hedged as WithHedging describes.  Closing
+// the body of the response returned cancels its request.
+func (i *Irdata) sendHedged(client *http.Client, req *http.Request) (*http.Response, error) {
+	results := make(chan hedgeResult, 2)
+
+	var cancels []context.CancelFunc
+
+	send := func(hedge bool) {
+		ctx, cancel := context.WithCancel(req.Context())
+
+		cancels = append(cancels, cancel)
+		n := len(cancels) - 1
+
+		go func() {
+			resp, err := client.Do(req.Clone(ctx))
+			results <- hedgeResult{resp: resp, err: err, n: n, hedge: hedge}
+		}()
+	}
+
+	send(false)
+
+	timer := i.clock.NewTimer(i.hedge.delay)
+	defer timer.Stop()
+
+	var err error
+
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer.C():
+			if !i.hedgeHeadroom() {
+				continue
+			}
+
+			i.logger.WithFields(log.Fields{
+				"url":   req.URL.String(),
+				"delay": i.hedge.delay,
+			}).Info("*** Hedging slow request")
+
+			i.hedge.mu.Lock()
+			i.hedge.stats.Hedged++
+			i.hedge.mu.Unlock()
+
+			send(true)
+			pending++
+		case result := <-results:
+			pending--
+
+			if result.err != nil {
+				// the other request may still answer
+				cancels[result.n]()
+
+				if err == nil {
+					err = result.err
+				}
+
+				continue
+			}
+
+			for n, cancel := range cancels {
+				if n != result.n {
+					cancel()
+				}
+			}
+
+			if result.hedge {
+				i.hedge.mu.Lock()
+				i.hedge.stats.Won++
+				i.hedge.mu.Unlock()
+			}
+
+			discardHedge(results, pending)
+
+			result.resp.Body = &endingBody{ReadCloser: result.resp.Body, end: cancels[result.n]}
+
+			return result.resp, nil
+		}
+	}
+
+	return nil, err
+}
+
+// discardHedge closes the responses of the cancelled requests still to
+// come on results
+func discardHedge(results chan hedgeResult, pending int) {
+	if pending == 0 {
+		return
+	}
+
+	go func() {
+		for result := range results {
+			if result.err == nil {
+				result.resp.Body.Close()
+			}
+
+			if pending--; pending == 0 {
+				return
+			}
+		}
+	}()
+}
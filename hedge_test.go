@@ -0,0 +1,197 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const hedgeDelay = 50 * time.Millisecond
+
+// stallFirst answers payload, except for the first request which stalls
+// until it's cancelled or 5 seconds passed
+func stallFirst(payload string) (http.HandlerFunc, func() int) {
+	var mu sync.Mutex
+	requests := 0
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+
+		if first {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+
+		fmt.Fprint(w, payload)
+	}
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return requests
+	}
+
+	return handler, count
+}
+
+func TestHedgingStalledRequest(t *testing.T) {
+	m := newMockAPI(t)
+
+	handler, requests := stallFirst(`{"hedged":true}`)
+	m.handle("/data/slow", handler)
+
+	api := m.openAuthed(t, WithHedging(hedgeDelay))
+
+	started := time.Now()
+
+	data, err := api.Get("/data/slow")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"hedged":true}`, string(data))
+
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, 2, requests())
+	assert.Equal(t, HedgeStats{Hedged: 1, Won: 1}, api.HedgeStats())
+
+	// a quick answer isn't hedged
+	data, err = api.Get("/data/slow")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"hedged":true}`, string(data))
+
+	assert.Equal(t, 3, requests())
+	assert.Equal(t, HedgeStats{Hedged: 1, Won: 1}, api.HedgeStats())
+}
+
+func TestHedgingSendsOneCopy(t *testing.T) {
+	m := newMockAPI(t)
+
+	var requests int64
+
+	m.handle("/data/slow", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+
+		time.Sleep(5 * hedgeDelay)
+
+		fmt.Fprint(w, `{"slow":true}`)
+	})
+
+	api := m.openAuthed(t, WithHedging(hedgeDelay))
+
+	data, err := api.Get("/data/slow")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"slow":true}`, string(data))
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&requests))
+	assert.Equal(t, int64(1), api.HedgeStats().Hedged)
+}
+
+func TestHedgingOffByDefault(t *testing.T) {
+	m := newMockAPI(t)
+
+	var requests int64
+
+	m.handle("/data/slow", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+
+		time.Sleep(2 * hedgeDelay)
+
+		fmt.Fprint(w, `{"slow":true}`)
+	})
+
+	api := m.openAuthed(t)
+
+	_, err := api.Get("/data/slow")
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&requests))
+	assert.Equal(t, HedgeStats{}, api.HedgeStats())
+}
+
+func TestHedgingSkipsPostsAndAuth(t *testing.T) {
+	m := newMockAPI(t)
+
+	var posts int64
+
+	m.handle("/data/slow_post", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&posts, 1)
+
+		time.Sleep(2 * hedgeDelay)
+
+		fmt.Fprint(w, `{"posted":true}`)
+	})
+
+	var tests int64
+
+	// the login check is slowed down on the way out
+	slowTest := WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == testURI {
+				atomic.AddInt64(&tests, 1)
+				time.Sleep(2 * hedgeDelay)
+			}
+
+			return next.RoundTrip(req)
+		})
+	})
+
+	api := m.openAuthed(t, WithHedging(hedgeDelay), slowTest)
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&tests))
+
+	var out map[string]bool
+
+	assert.NoError(t, api.PostJSON(api.ctx, "/data/slow_post", map[string]int{"id": 1}, &out))
+	assert.True(t, out["posted"])
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&posts))
+	assert.Equal(t, HedgeStats{}, api.HedgeStats())
+}
+
+func TestHedgingLowRateLimit(t *testing.T) {
+	m := newMockAPI(t)
+
+	var requests int64
+
+	m.handle("/data/slow", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+
+		w.Header().Set("x-ratelimit-limit", "240")
+		w.Header().Set("x-ratelimit-remaining", "12")
+		w.Header().Set("x-ratelimit-reset", fmt.Sprint(time.Now().Add(time.Minute).Unix()))
+
+		time.Sleep(2 * hedgeDelay)
+
+		fmt.Fprint(w, `{"slow":true}`)
+	})
+
+	api := m.openAuthed(t, WithHedging(hedgeDelay))
+
+	// the first request is hedged, nothing is known about the rate limit
+	_, err := api.Get("/data/slow")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&requests))
+
+	_, err = api.Get("/data/slow")
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(3), atomic.LoadInt64(&requests))
+	assert.Equal(t, int64(1), api.HedgeStats().Hedged)
+	assert.Equal(t, int64(1), api.HedgeStats().SkippedRateLimit)
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,195 @@
+package irdata
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultHookQueueSize is how many hook calls wait for the dispatcher
+// before the oldest are dropped
+const defaultHookQueueSize = 1024
+
+// RequestEvent is an attempt at a request to iRacing, see WithRequestHook
+type RequestEvent struct {
+	Method string
+
+	// URL is redacted as it is in the logs
+	URL     string
+	Attempt int
+
+	// Status is the status answered, 0 if the request failed in transport
+	// with Err
+	Status int
+	Err    error
+
+	Duration time.Duration
+}
+
+type hookQueueT struct {
+	mu      sync.Mutex
+	size    int
+	queue   []func()
+	dropped int64
+	closed  bool
+
+	// drained is closed by the dispatcher, running while it isn't nil,
+	// when it has gone through the queue
+	drained chan struct{}
+}
+
+// WithRequestHook calls hook after every attempt at a request, e.g. to
+// count requests and their latency in a metrics system
+func WithRequestHook(hook func(RequestEvent)) Option {
+	return func(i *Irdata) {
+		i.requestHook = hook
+	}
+}
+
+// WithHookQueueSize sets how many hook calls may wait for the hooks, 1024
+// by default.
+//
+// The hooks (those of WithRequestHook, WithMetricHook, WithCacheErrorHook,
+// WithUnknownFieldsHook and WithArchiveFullHook) are called one at a time
+// in the order of the events by a goroutine of the instance, so a slow hook
+// doesn't hold up requests.  When they fall behind by more than n calls the
+// oldest are dropped, see DroppedHookCalls.  Close waits for those queued.
+func WithHookQueueSize(n int) Option {
+	return func(i *Irdata) {
+		i.hooks.size = n
+	}
+}
+
+// DroppedHookCalls returns how many hook calls were dropped because the
+// hooks didn't keep up, see WithHookQueueSize
+func (i *Irdata) DroppedHookCalls() int64 {
+	i.hooks.mu.Lock()
+	defer i.hooks.mu.Unlock()
+
+	return i.hooks.dropped
+}
+
+// callHook queues call for the dispatcher, starting it if it isn't running.
+// After Close call is made right away.
+func (i *Irdata) callHook(call func()) {
+	q := &i.hooks
+
+	q.mu.Lock()
+
+	if q.closed {
+		q.mu.Unlock()
+
+		i.runHook(call)
+
+		return
+	}
+
+	size := q.size
+	if size <= 0 {
+		size = defaultHookQueueSize
+	}
+
+	if len(q.queue) >= size {
+		q.queue[0] = nil
+		q.queue = q.queue[1:]
+		q.dropped++
+
+		if q.dropped == 1 {
+			i.logger.Warn("Hooks can't keep up, dropping the oldest calls")
+		}
+	}
+
+	q.queue = append(q.queue, call)
+
+	if q.drained == nil {
+		q.drained = make(chan struct{})
+
+		go i.dispatchHooks(q.drained)
+	}
+
+	q.mu.Unlock()
+}
+
+// dispatchHooks makes the queued calls until there are none left
+func (i *Irdata) dispatchHooks(drained chan struct{}) {
+	q := &i.hooks
+
+	for {
+		q.mu.Lock()
+
+		if len(q.queue) == 0 {
+			q.drained = nil
+			q.mu.Unlock()
+
+			close(drained)
+
+			return
+		}
+
+		call := q.queue[0]
+		q.queue[0] = nil
+		q.queue = q.queue[1:]
+
+		q.mu.Unlock()
+
+		i.runHook(call)
+	}
+}
+
+// runHook makes call, a hook panicking is logged rather than taking the
+// dispatcher down with it
+func (i *Irdata) runHook(call func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			i.logger.WithFields(log.Fields{"panic": r}).Error("Hook panicked")
+		}
+	}()
+
+	call()
+}
+
+// flushHooks waits for the queued hook calls to be made, the calls made
+// afterwards are made right away if closing
+func (i *Irdata) flushHooks(closing bool) {
+	q := &i.hooks
+
+	for {
+		q.mu.Lock()
+
+		drained := q.drained
+		if drained == nil {
+			q.closed = q.closed || closing
+			q.mu.Unlock()
+
+			return
+		}
+
+		q.mu.Unlock()
+
+		<-drained
+	}
+}
+
+// notifyRequest calls the request hook for a finished attempt
+func (i *Irdata) notifyRequest(method string, url string, attempt int, started time.Time, resp *http.Response, err error) {
+	hook := i.requestHook
+	if hook == nil {
+		return
+	}
+
+	event := RequestEvent{
+		Method:   method,
+		URL:      redactString(url, logRedaction()),
+		Attempt:  attempt,
+		Err:      err,
+		Duration: i.clock.Now().Sub(started),
+	}
+
+	if resp != nil {
+		event.Status = resp.StatusCode
+	}
+
+	i.callHook(func() { hook(event) })
+}
@@ -0,0 +1,115 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowRequestHook(t *testing.T) {
+	const hookDelay = 200 * time.Millisecond
+
+	m := newMockAPI(t)
+	m.handleJSON(breakerTestURI, `[{"label":"Oval","value":1}]`)
+
+	var mu sync.Mutex
+	var events []RequestEvent
+
+	release := make(chan struct{})
+
+	api := m.openAuthed(t, WithHookQueueSize(2), WithRequestHook(func(event RequestEvent) {
+		<-release
+		time.Sleep(hookDelay)
+
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+
+	// the hook is stuck on the login's first event
+	for n := 0; n < 5; n++ {
+		started := time.Now()
+
+		_, err := api.Get(breakerTestURI)
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(started), hookDelay)
+	}
+
+	assert.Positive(t, api.DroppedHookCalls())
+
+	close(release)
+	assert.NoError(t, api.Close())
+
+	// the call the hook was stuck on, then what was queued last
+	if assert.Len(t, events, 3) {
+		for _, event := range events[1:] {
+			assert.Equal(t, http.MethodGet, event.Method)
+			assert.Contains(t, event.URL, breakerTestURI)
+			assert.Equal(t, http.StatusOK, event.Status)
+			assert.Equal(t, 1, event.Attempt)
+		}
+	}
+
+	// after Close hooks are called right away
+	api.callHook(func() {
+		mu.Lock()
+		events = nil
+		mu.Unlock()
+	})
+
+	assert.Nil(t, events)
+}
+
+func TestRequestHookOrder(t *testing.T) {
+	m := newMockAPI(t)
+
+	status := int32(http.StatusBadGateway)
+	handleStatus(m, &status)
+
+	var attempts []int
+
+	api := m.openAuthed(t, WithClock(newFakeClock()), WithRequestHook(func(event RequestEvent) {
+		if event.Status == http.StatusBadGateway {
+			attempts = append(attempts, event.Attempt)
+		}
+	}))
+
+	var v interface{}
+	assert.Error(t, api.GetJSON(context.Background(), breakerTestURI, &v))
+
+	api.flushHooks(false)
+
+	var expected []int
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		expected = append(expected, attempt)
+	}
+
+	assert.Equal(t, expected, attempts)
+	assert.Zero(t, api.DroppedHookCalls())
+}
+
+func TestHookPanic(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON(breakerTestURI, `[]`)
+
+	calls := 0
+
+	api := m.openAuthed(t, WithRequestHook(func(RequestEvent) {
+		calls++
+		panic("collector down")
+	}))
+
+	for n := 0; n < 2; n++ {
+		_, err := api.Get(breakerTestURI)
+		assert.NoError(t, err)
+	}
+
+	api.flushHooks(false)
+
+	// the dispatcher survived the first
+	assert.GreaterOrEqual(t, calls, 3)
+}
@@ -0,0 +1,227 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HostedHost is the member who hosted a session
+type HostedHost struct {
+	CustID      int64  `json:"cust_id" pii:"cust_id"`
+	DisplayName string `json:"display_name" pii:"name"`
+}
+
+// HostedResult is a row returned by /data/results/search_hosted.  When
+// searching by cust_id the driver fields describe that member's
+// participation.
+type HostedResult struct {
+	SessionID         int64       `json:"session_id"`
+	SubsessionID      int64       `json:"subsession_id"`
+	StartTime         time.Time   `json:"start_time"`
+	EndTime           time.Time   `json:"end_time"`
+	SessionName       string      `json:"session_name"`
+	LeagueID          int64       `json:"league_id"`
+	LeagueSeasonID    int64       `json:"league_season_id"`
+	PasswordProtected bool        `json:"password_protected"`
+	Host              HostedHost  `json:"host"`
+	LicenseCategoryID int64       `json:"license_category_id"`
+	LicenseCategory   string      `json:"license_category"`
+	NumDrivers        int         `json:"num_drivers"`
+	EventType         int         `json:"event_type"`
+	EventTypeName     string      `json:"event_type_name"`
+	Track             SearchTrack `json:"track"`
+	WinnerGroupID     int64       `json:"winner_group_id"`
+	WinnerName        string      `json:"winner_name" pii:"name"`
+	CustID            int64       `json:"cust_id" pii:"cust_id"`
+	DisplayName       string      `json:"display_name" pii:"name"`
+	CarID             int64       `json:"car_id"`
+	CarClassID        int64       `json:"car_class_id"`
+	StartingPosition  int         `json:"starting_position"`
+	FinishPosition    int         `json:"finish_position"`
+	Incidents         int         `json:"incidents"`
+}
+
+// SearchHostedParams are the parameters of SearchHostedResults.  Zero
+// values are left out of the query.
+//
+// CustID or HostCustID and one of the time ranges must be provided.  Ranges
+// longer than the 90 days the API allows are split into several searches.
+//
+// LeagueID is also applied client side since the server doesn't reliably
+// honor it, NameContains and PasswordProtected only client side.
+type SearchHostedParams struct {
+	StartRangeBegin  time.Time
+	StartRangeEnd    time.Time
+	FinishRangeBegin time.Time
+	FinishRangeEnd   time.Time
+	CustID           int64
+	HostCustID       int64
+	TeamID           int64
+	LeagueID         int64
+	LeagueSeasonID   int64
+	CarID            int64
+	TrackID          int64
+	CategoryIDs      []int64
+
+	// NameContains keeps sessions whose name contains it, ignoring case
+	NameContains string
+
+	// PasswordProtected, if set, keeps only sessions that were (or weren't)
+	// password protected
+	PasswordProtected *bool
+}
+
+// HostedResults are the rows returned by SearchHostedResults and how they
+// were found
+type HostedResults struct {
+	Rows []HostedResult
+	Meta SearchMeta
+}
+
+func (p SearchHostedParams) values() url.Values {
+	v := url.Values{}
+
+	setTime(v, "start_range_begin", p.StartRangeBegin)
+	setTime(v, "start_range_end", p.StartRangeEnd)
+	setTime(v, "finish_range_begin", p.FinishRangeBegin)
+	setTime(v, "finish_range_end", p.FinishRangeEnd)
+	setInt(v, "cust_id", p.CustID)
+	setInt(v, "host_cust_id", p.HostCustID)
+	setInt(v, "team_id", p.TeamID)
+	setInt(v, "league_id", p.LeagueID)
+	setInt(v, "league_season_id", p.LeagueSeasonID)
+	setInt(v, "car_id", p.CarID)
+	setInt(v, "track_id", p.TrackID)
+	setInts(v, "category_ids", p.CategoryIDs)
+
+	return v
+}
+
+// windows splits the time ranges of p into searches the API will accept
+func (p SearchHostedParams) windows() []SearchHostedParams {
+	var windows []SearchHostedParams
+
+	for _, r := range searchWindows(
+		timeRange{begin: p.StartRangeBegin, end: p.StartRangeEnd},
+		timeRange{begin: p.FinishRangeBegin, end: p.FinishRangeEnd},
+	) {
+		w := p
+		w.StartRangeBegin, w.StartRangeEnd = r.start.begin, r.start.end
+		w.FinishRangeBegin, w.FinishRangeEnd = r.finish.begin, r.finish.end
+		windows = append(windows, w)
+	}
+
+	return windows
+}
+
+// matches applies the client side filters of p to row
+func (p SearchHostedParams) matches(row HostedResult) bool {
+	if p.LeagueID != 0 && row.LeagueID != p.LeagueID {
+		return false
+	}
+
+	if p.PasswordProtected != nil && row.PasswordProtected != *p.PasswordProtected {
+		return false
+	}
+
+	return p.NameContains == "" || strings.Contains(strings.ToLower(row.SessionName), strings.ToLower(p.NameContains))
+}
+
+// SearchHostedResults searches hosted and league session results via
+// /data/results/search_hosted, merging the chunks of every window searched.
+// A subsession showing up in more than one window is returned once.
+func (i *Irdata) SearchHostedResults(ctx context.Context, params SearchHostedParams) (*HostedResults, error) {
+	if params.CustID == 0 && params.HostCustID == 0 {
+		return nil, errors.New("must provide cust id or host cust id")
+	}
+
+	if params.StartRangeBegin.IsZero() && params.FinishRangeBegin.IsZero() {
+		return nil, errors.New("must provide a time range")
+	}
+
+	if err := validateParams("/data/results/search_hosted?" + params.values().Encode()); err != nil {
+		return nil, err
+	}
+
+	results := &HostedResults{}
+
+	seen := make(map[int64]bool)
+
+	for _, w := range params.windows() {
+		uri := "/data/results/search_hosted?" + w.values().Encode()
+
+		i.logger.WithFields(log.Fields{"uri": uri}).Debug("Searching hosted results")
+
+		var rows []HostedResult
+
+		if err := i.GetJSON(ctx, uri, &rows); err != nil {
+			return nil, err
+		}
+
+		results.Meta.Windows++
+		results.Meta.Fetched += len(rows)
+
+		for _, row := range rows {
+			if seen[row.SubsessionID] || !params.matches(row) {
+				continue
+			}
+
+			seen[row.SubsessionID] = true
+
+			results.Rows = append(results.Rows, row)
+		}
+	}
+
+	results.Meta.Returned = len(results.Rows)
+
+	return results, nil
+}
+
+// HostedSession is a hosted session listed by /data/hosted/combined_sessions
+type HostedSession struct {
+	SessionID         int64       `json:"session_id"`
+	SubsessionID      int64       `json:"subsession_id"`
+	SessionName       string      `json:"session_name"`
+	LaunchAt          time.Time   `json:"launch_at"`
+	Host              HostedHost  `json:"host"`
+	Track             SearchTrack `json:"track"`
+	PasswordProtected bool        `json:"password_protected"`
+	EntryCount        int         `json:"entry_count"`
+
+	Options SessionOptions `json:"-"`
+}
+
+// UnmarshalJSON decodes the session along with its options
+func (s *HostedSession) UnmarshalJSON(data []byte) error {
+	type session HostedSession
+
+	return decodeSession(data, (*session)(s), &s.Options)
+}
+
+// GetHostedSessions returns the hosted sessions that can be joined or
+// watched, only those for the package if packageID isn't 0
+func (i *Irdata) GetHostedSessions(ctx context.Context, packageID int64) ([]HostedSession, error) {
+	v := url.Values{}
+
+	setInt(v, "package_id", packageID)
+
+	uri := "/data/hosted/combined_sessions"
+	if len(v) > 0 {
+		uri += "?" + v.Encode()
+	}
+
+	var result struct {
+		Sessions []HostedSession `json:"sessions"`
+	}
+
+	if err := i.GetJSON(ctx, uri, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Sessions, nil
+}
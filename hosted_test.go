@@ -0,0 +1,90 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchHostedParamsValues(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	v := SearchHostedParams{
+		StartRangeBegin: begin,
+		HostCustID:      123,
+		LeagueID:        4403,
+		CategoryIDs:     []int64{2, 5},
+		NameContains:    "practice",
+	}.values()
+
+	assert.Equal(t, "category_ids=2%2C5&host_cust_id=123&league_id=4403&start_range_begin=2024-01-01T00%3A00Z", v.Encode())
+}
+
+func TestSearchHostedResults(t *testing.T) {
+	m := newMockAPI(t)
+
+	var queries []string
+
+	m.handleChunked("/data/results/search_hosted", func(r *http.Request) []string {
+		begin := r.URL.Query().Get("start_range_begin")
+		queries = append(queries, begin)
+
+		assert.Equal(t, "123", r.URL.Query().Get("host_cust_id"))
+
+		// subsession 2 started right at the window boundary and shows up twice
+		if begin == "2024-01-01T00:00Z" {
+			return []string{
+				`[{"subsession_id":1,"session_name":"Team Endurance PRACTICE","password_protected":true,"league_id":0}]`,
+				`[{"subsession_id":2,"session_name":"Endurance practice 2","password_protected":true,"host":{"cust_id":123}}]`,
+			}
+		}
+
+		return []string{
+			`[{"subsession_id":2,"session_name":"Endurance practice 2","password_protected":true}]`,
+			`[{"subsession_id":3,"session_name":"Open practice","password_protected":false},` +
+				`{"subsession_id":4,"session_name":"League race","password_protected":true,"league_id":4403}]`,
+		}
+	})
+
+	api := m.openAuthed(t)
+
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	protected := true
+
+	results, err := api.SearchHostedResults(context.Background(), SearchHostedParams{
+		StartRangeBegin:   begin,
+		StartRangeEnd:     begin.Add(100 * 24 * time.Hour),
+		HostCustID:        123,
+		NameContains:      "Practice",
+		PasswordProtected: &protected,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-01-01T00:00Z", "2024-03-31T00:00Z"}, queries)
+	assert.Equal(t, SearchMeta{Windows: 2, Fetched: 5, Returned: 2}, results.Meta)
+	assert.Equal(t, int64(1), results.Rows[0].SubsessionID)
+	assert.Equal(t, int64(2), results.Rows[1].SubsessionID)
+	assert.Equal(t, int64(123), results.Rows[1].Host.CustID)
+
+	results, err = api.SearchHostedResults(context.Background(), SearchHostedParams{
+		StartRangeBegin: begin,
+		StartRangeEnd:   begin.Add(100 * 24 * time.Hour),
+		HostCustID:      123,
+		LeagueID:        4403,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, results.Rows, 1)
+	assert.Equal(t, "League race", results.Rows[0].SessionName)
+}
+
+func TestSearchHostedResultsNeedsParams(t *testing.T) {
+	_, err := i.SearchHostedResults(context.Background(), SearchHostedParams{StartRangeBegin: time.Now()})
+	assert.Error(t, err)
+
+	_, err = i.SearchHostedResults(context.Background(), SearchHostedParams{CustID: 1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,223 @@
+//go:build integration
+
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The integration suite runs against the live API with your own account:
+//
+//	IRDATA_TEST_KEY=/path/to/key IRDATA_TEST_CREDS=/path/to/creds go test -tags integration -run Live
+//
+// IRDATA_TEST_CACHE sets the cache directory kept between runs (a directory
+// in the temp dir by default) and IRDATA_TEST_FIXTURES a directory to write
+// the responses to, scrubbed of personal data, to refresh fixtures from.
+const (
+	// liveRequestBudget is the most requests a run may send, well under the
+	// rate limit even when every response is a cache miss
+	liveRequestBudget = 40
+
+	// liveRateLimitFloor is how much of the rate limit must be left for the
+	// suite to go on
+	liveRateLimitFloor = 50
+
+	liveTTL = 6 * time.Hour
+)
+
+var live struct {
+	once     sync.Once
+	api      *Irdata
+	err      error
+	requests int64
+
+	scrubberMu sync.Mutex
+	scrubber   *FixtureScrubber
+}
+
+// liveAPI returns the authenticated instance of the suite, skipping t when
+// the creds aren't configured
+func liveAPI(t *testing.T) *Irdata {
+	t.Helper()
+
+	keyFilename := os.Getenv("IRDATA_TEST_KEY")
+	credsFilename := os.Getenv("IRDATA_TEST_CREDS")
+
+	if keyFilename == "" || credsFilename == "" {
+		t.Skip("IRDATA_TEST_KEY and IRDATA_TEST_CREDS aren't set")
+	}
+
+	live.once.Do(func() {
+		cacheDir := os.Getenv("IRDATA_TEST_CACHE")
+		if cacheDir == "" {
+			cacheDir = filepath.Join(os.TempDir(), "irdata-integration")
+		}
+
+		live.api = Open(context.Background(),
+			WithMaxConcurrentRequests(1),
+			WithRequestHook(func(RequestEvent) {
+				atomic.AddInt64(&live.requests, 1)
+			}),
+		)
+
+		if live.err = live.api.EnableCache(cacheDir); live.err != nil {
+			return
+		}
+
+		live.err = live.api.AuthWithCredsFromFile(keyFilename, credsFilename)
+	})
+
+	if !assert.NoError(t, live.err) {
+		t.FailNow()
+	}
+
+	live.api.flushHooks(false)
+
+	if n := atomic.LoadInt64(&live.requests); n > liveRequestBudget {
+		t.Fatalf("the suite sent %d requests, more than its budget of %d", n, liveRequestBudget)
+	}
+
+	if rl := live.api.RateLimit(); rl.Limit > 0 && rl.Remaining < liveRateLimitFloor {
+		t.Skipf("only %d requests of the rate limit left until %s", rl.Remaining, rl.Reset)
+	}
+
+	return live.api
+}
+
+// refreshFixture writes data scrubbed as the fixture name when
+// IRDATA_TEST_FIXTURES is set
+func refreshFixture(t *testing.T, name string, data []byte) {
+	dir := os.Getenv("IRDATA_TEST_FIXTURES")
+	if dir == "" {
+		return
+	}
+
+	live.scrubberMu.Lock()
+	defer live.scrubberMu.Unlock()
+
+	if live.scrubber == nil {
+		live.scrubber = NewFixtureScrubber()
+	}
+
+	assert.NoError(t, live.scrubber.WriteFixture(dir, name, data))
+}
+
+func TestLiveAuth(t *testing.T) {
+	api := liveAPI(t)
+
+	me, err := api.Me(context.Background())
+	if assert.NoError(t, err) {
+		assert.Positive(t, me.CustID)
+	}
+}
+
+func TestLiveGet(t *testing.T) {
+	api := liveAPI(t)
+
+	data, err := api.Get("/data/constants/event_types")
+	if assert.NoError(t, err) {
+		assertIsJson(t, data)
+		refreshFixture(t, "live_event_types.json", data)
+	}
+}
+
+func TestLiveRateLimit(t *testing.T) {
+	api := liveAPI(t)
+
+	// uncached so the headers of a response are read
+	_, err := api.Get("/data/constants/categories")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rl := api.RateLimit()
+	assert.Positive(t, rl.Limit)
+	assert.LessOrEqual(t, rl.Remaining, rl.Limit)
+	assert.True(t, rl.Reset.After(time.Now().Add(-time.Minute)), "reset %s", rl.Reset)
+}
+
+func TestLiveChunked(t *testing.T) {
+	api := liveAPI(t)
+
+	// a day that's over so the uri, and so the cache entry, is the same all
+	// day
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+
+	uri := fmt.Sprintf("/data/results/search_series?start_range_begin=%s&start_range_end=%s&official_only=true&event_types=5",
+		day.Format("2006-01-02T15:04Z"), day.Add(2*time.Hour).Format("2006-01-02T15:04Z"))
+
+	chunks := 0
+
+	err := api.GetChunksWithCache(uri, liveTTL, func(chunk Chunk) error {
+		assertIsJson(t, chunk.Data)
+
+		if chunks == 0 {
+			refreshFixture(t, "live_search_series_chunk.json", chunk.Data)
+		}
+
+		chunks++
+
+		return nil
+	})
+
+	if assert.NoError(t, err) {
+		assert.Positive(t, chunks)
+	}
+}
+
+func TestLiveCacheRoundTrip(t *testing.T) {
+	api := liveAPI(t)
+
+	const uri = "/data/constants/divisions"
+
+	first, err := api.GetWithCache(uri, liveTTL)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	api.flushHooks(false)
+	sent := atomic.LoadInt64(&live.requests)
+
+	second, err := api.GetWithCache(uri, liveTTL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, first, second)
+	}
+
+	api.flushHooks(false)
+	assert.Equal(t, sent, atomic.LoadInt64(&live.requests), "served from the cache")
+
+	entries, err := api.CacheEntries()
+	if assert.NoError(t, err) {
+		found := false
+
+		for _, entry := range entries {
+			found = found || strings.HasSuffix(entry.URI, uri)
+		}
+
+		assert.True(t, found, "%s in CacheEntries", uri)
+	}
+}
+
+func TestLiveTypedBinding(t *testing.T) {
+	api := liveAPI(t)
+
+	tracks, err := api.GetTracks(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.NotEmpty(t, tracks.Items) {
+		assert.Positive(t, tracks.Items[0].TrackID)
+		assert.NotEmpty(t, tracks.Items[0].TrackName)
+	}
+}
@@ -15,25 +15,103 @@ package irdata
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"git.mills.io/prologic/bitcask"
 	log "github.com/sirupsen/logrus"
 )
 
 type Irdata struct {
-	httpClient http.Client
-	isAuthed   bool
-	cask       *bitcask.Bitcask
+	// updated atomically so it comes first to be 64-bit aligned on 32-bit
+	// platforms
+	transportRetries int64
+
+	// cacheOps, cacheTimeouts and ttlJittered are counted atomically, see
+	// CacheStats
+	cacheOps      int64
+	cacheTimeouts int64
+	ttlJittered   int64
+	cacheFiltered cacheFilteredT
+	requestStats  requestStatsT
+
+	// offline and offlineUsed, whether SetOffline(true) was ever called,
+	// are set atomically, see SetOffline
+	offline     int32
+	offlineUsed int32
+
+	ctx         context.Context
+	clock       Clock
+	baseURL     *url.URL
+	assetBase   *url.URL
+	httpClient  http.Client
+	assetClient http.Client
+	rootCAs     *x509.CertPool
+	tlsConfig   *tls.Config
+	isAuthed    bool
+	cache       CacheBackend
+
+	strictErrors bool
+
+	// region is immutable once authenticated, regionStrict is set by
+	// WithRegion
+	region       Region
+	regionStrict bool
+
+	memberMu sync.Mutex
+	member   *MemberInfo
+
+	rateLimitMu sync.Mutex
+	rateLimit   RateLimit
+	rateBudget  rateBudgetT
+
+	cacheErrors    cacheErrorsT
+	cacheNamespace cacheNamespaceT
+	expirations    expirationsT
+	lookups        lookupsT
+	authVerify     authVerifyT
+	reasonOuts     reasonOutsT
+	session        sessionT
+	archive        archiveT
+	throttle       throttleT
+	health         healthT
+	hedge          hedgeT
+	breaker        breakerT
+	lifecycle      lifecycleT
+	hooks          hookQueueT
+	refresh        refreshT
+
+	staleRetention  *time.Duration
+	ttlJitter       float64
+	cacheTimeout    time.Duration
+	maxResponseSize int64
+	canonicalJSON   bool
+
+	defaultRequestOptions []RequestOption
+
+	// cacheFilter holds a *CacheFilter, see SetCacheFilter, and
+	// sessionChanged a *func(SessionInfo), see SetSessionChangedFunc
+	cacheFilter       atomic.Value
+	sessionChanged    atomic.Value
+	resultCachePolicy ResultCachePolicy
+	unknownFields     unknownFieldsT
+
+	requestHook   func(RequestEvent)
+	metricHook    func(MetricEvent)
+	middleware    []func(http.RoundTripper) http.RoundTripper
+	redirectHosts map[string]bool
+
+	logger *log.Logger
 }
 
+// Chunk is one piece of a chunked result.  Data holds a JSON array.
 type Chunk struct {
 	Number   int
 	FileName string
@@ -41,7 +119,8 @@ type Chunk struct {
 }
 
 type s3LinkT struct {
-	Link string
+	Link    string
+	Expires string
 }
 
 type chunkedResultT struct {
@@ -63,254 +142,882 @@ const rootURL = "https://members-ng.iracing.com"
 var urlBase *url.URL
 
 func init() {
-	log.SetFormatter(&log.TextFormatter{
-		FullTimestamp: true,
-	})
-
 	var err error
 	urlBase, err = url.Parse(rootURL)
 	if err != nil {
-		log.Panic(err)
+		packageLogger.Panic(err)
 	}
-
-	log.SetLevel(log.ErrorLevel)
 }
 
-func Open(ctx context.Context) *Irdata {
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		log.Panic(err)
+// Option configures an Irdata instance when passed to Open
+type Option func(*Irdata)
+
+// WithBaseURL points the instance at a different API host than
+// members-ng.iracing.com (e.g. a proxy or a test server)
+func WithBaseURL(baseURL *url.URL) Option {
+	return func(i *Irdata) {
+		i.baseURL = baseURL
 	}
+}
+
+// Open returns a new instance which must be authenticated with one of the
+// Auth methods before making requests
+func Open(ctx context.Context, opts ...Option) *Irdata {
+	// shared by both clients, the hosts get their own pools anyway
+	transport := NewTransport(TransportOptions{})
 
 	client := http.Client{
-		Jar: jar,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+		Transport: transport,
 	}
 
-	return &Irdata{
-		httpClient: client,
-		isAuthed:   false,
-		cask:       nil,
+	i := &Irdata{
+		ctx:         ctx,
+		clock:       realClock{},
+		baseURL:     urlBase,
+		httpClient:  client,
+		assetClient: http.Client{Transport: transport},
+		isAuthed:    false,
+		cache:       nil,
+		logger:      newLogger(),
 	}
-}
 
-// Close
-// Calling Close when done is important when using caching - this will compact the cache.
-func (i *Irdata) Close() {
-	if i.cask != nil {
-		i.cacheClose()
+	i.httpClient.Jar = i.newCookieJar()
+	i.httpClient.CheckRedirect = i.checkRedirect
+
+	for _, opt := range opts {
+		opt(i)
 	}
+
+	i.applyTLS()
+	i.wrapTransports()
+
+	return i
 }
 
 // EnableCache enables on the optional caching layer which will
 // use the directory path provided as cacheDir
-func (i *Irdata) EnableCache(cacheDir string) error {
-	log.WithFields(log.Fields{"cacheDir": cacheDir}).Info("Enabling cache")
-	return i.cacheOpen(cacheDir)
+//
+// If another process holds the cache (see WithSharedCache to share it)
+// a warning is logged and the instance carries on without caching.
+func (i *Irdata) EnableCache(cacheDir string, opts ...CacheOption) error {
+	i.logger.WithFields(log.Fields{"cacheDir": cacheDir}).Info("Enabling cache")
+	return i.cacheOpen(cacheDir, opts...)
 }
 
-// EnableDebug enables debug logging which uses the logrus module
+// EnableCacheBackend enables the optional caching layer storing entries in
+// backend instead of a directory
+func (i *Irdata) EnableCacheBackend(backend CacheBackend) {
+	i.cache = backend
+}
+
+// EnableDebug enables debug logging which uses the logrus module, see
+// SetLogLevel
 func (i *Irdata) EnableDebug() {
-	log.SetLevel(log.DebugLevel)
+	i.SetLogLevel(log.DebugLevel)
 }
 
 // DisableDebug disables debug logging
 func (i *Irdata) DisableDebug() {
-	log.SetLevel(log.ErrorLevel)
+	i.SetLogLevel(log.ErrorLevel)
 }
 
 // Get returns the result value for the uri provided (e.g. "/data/member/info")
 //
 // The value returned is a JSON byte array and a potential error.
 //
+// NOTE: ids such as subsession_id can exceed 2^53, beyond which a float64
+// can't hold every integer.  Unmarshalling the result into interface{} or
+// map[string]interface{} turns numbers into float64 and may silently change
+// such ids, use GetJSON with WithUseNumber or a struct with int64 fields.
+//
 // Get will automatically retry 5 times if iRacing returns 500 errors
-func (i *Irdata) Get(uri string) ([]byte, error) {
-	if !i.isAuthed {
-		return nil, errors.New("must auth first")
+func (i *Irdata) Get(uri string, opts ...RequestOption) ([]byte, error) {
+	return i.get(WithRequestOptions(i.ctx, opts...), uri)
+}
+
+func (i *Irdata) get(ctx context.Context, uri string) ([]byte, error) {
+	p, err := i.fetch(ctx, uri)
+	if err != nil {
+		return nil, err
 	}
 
-	uriRef, err := url.Parse(uri)
+	data, err := p.assemble()
 	if err != nil {
 		return nil, err
 	}
 
-	url := urlBase.ResolveReference(uriRef)
+	return i.canonical(p, data)
+}
+
+// JSONOption adjusts how GetJSON decodes the result
+type JSONOption func(*json.Decoder)
+
+// WithUseNumber makes GetJSON decode numbers stored in interface{} values
+// as json.Number, keeping large ids exact
+func WithUseNumber() JSONOption {
+	return func(dec *json.Decoder) {
+		dec.UseNumber()
+	}
+}
+
+// GetJSON is Get unmarshalling the result into v, see WithUnknownFields.
+// Endpoints linking to CSV rather than JSON return a *NotJSONError.
+func (i *Irdata) GetJSON(ctx context.Context, uri string, v interface{}, opts ...JSONOption) error {
+	p, err := i.fetch(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	data, err := p.assemble()
+	if err != nil {
+		return err
+	}
+
+	if !p.isChunked() && isCSV(p.contentType, data) {
+		return notJSON(uri, "text/csv", data)
+	}
+
+	if _, raw := v.(*json.RawMessage); raw {
+		if data, err = i.canonical(p, data); err != nil {
+			return err
+		}
+	}
+
+	return i.decodeJSON(uri, data, v, opts...)
+}
 
-	log.WithFields(log.Fields{"url": url}).Info("Fetching")
+// getJSONWithTTL is GetJSON through the cache, with ttl, when it is
+// enabled
+func (i *Irdata) getJSONWithTTL(ctx context.Context, uri string, ttl time.Duration, v interface{}) error {
+	if i.cache == nil {
+		return i.GetJSON(ctx, uri, v)
+	}
 
-	resp, err := i.retryingGet(url.String())
+	data, err := i.getWithCache(ctx, uri, ttl)
 	if err != nil {
+		return err
+	}
+
+	return i.decodeJSON(uri, data, v)
+}
+
+// payload is a fetched result, either the data itself or its chunks
+type payload struct {
+	data   []byte
+	chunks []Chunk
+
+	// asOf is when iRacing produced the payload, see responseAsOf
+	asOf time.Time
+
+	// stale is set for payloads served from an expired cache entry while
+	// offline
+	stale bool
+
+	// contentType is that of the linked object, "" if there was none or
+	// the payload came from the cache
+	contentType string
+}
+
+func (p *payload) isChunked() bool {
+	return p.chunks != nil
+}
+
+// assemble returns the data, merging the chunks if there are any
+func (p *payload) assemble() ([]byte, error) {
+	if !p.isChunked() {
+		return p.data, nil
+	}
+
+	var buf bytes.Buffer
+
+	if err := p.assembleTo(&buf); err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
+	return buf.Bytes(), nil
+}
+
+// assembleTo writes the merged chunks to buf
+func (p *payload) assembleTo(buf *bytes.Buffer) error {
+	size := 2
+
+	for _, chunk := range p.chunks {
+		size += len(chunk.Data) + 1
+	}
+
+	buf.Grow(size)
+
+	items := 0
+
+	buf.WriteByte('[')
+
+	for _, chunk := range p.chunks {
+		err := DecodeArrayStream(bytes.NewReader(chunk.Data), func(element json.RawMessage) error {
+			if items > 0 {
+				buf.WriteByte(',')
+			}
+
+			buf.Write(element)
+
+			items++
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if items == 0 {
+		buf.Reset()
+		buf.WriteString("null")
+
+		return nil
+	}
+
+	buf.WriteByte(']')
+
+	return nil
+}
+
+// chunkElements returns the elements of the JSON array in chunk without
+// the enclosing brackets so chunks can be spliced together.  Chunks are
+// validated when downloaded.
+func chunkElements(chunk []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(chunk)
+
+	if len(trimmed) < 2 || trimmed[0] != '[' || trimmed[len(trimmed)-1] != ']' {
+		return nil, errors.New("chunk is not a JSON array")
+	}
+
+	return bytes.TrimSpace(trimmed[1 : len(trimmed)-1]), nil
+}
 
-	data, err := io.ReadAll(resp.Body)
+// fetch gets uri following the s3 link and fetching the chunks, if any,
+// and archives the result
+func (i *Irdata) fetch(ctx context.Context, uri string) (*payload, error) {
+	ctx, end, err := i.beginRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var s3Link s3LinkT
+	defer end()
 
-	log.WithFields(log.Fields{"url": url}).Debug("Unmarshalling")
+	started := i.clock.Now()
+
+	p, err := i.download(ctx, uri)
+
+	if !isCachedCall(ctx) {
+		i.notifyMetric(uri, CacheUncached, p.size(), err, started)
+	}
 
-	err = json.Unmarshal(data, &s3Link)
 	if err != nil {
-		// there's no link so just return directly
-		return data, nil
+		return nil, err
+	}
+
+	i.archivePayload(uri, p)
+
+	return p, nil
+}
+
+func (i *Irdata) download(ctx context.Context, uri string) (*payload, error) {
+	chunks := []Chunk{}
+
+	envelope, chunked, err := i.downloadChunks(ctx, uri, func(chunk Chunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if chunked {
+		return &payload{chunks: chunks, asOf: envelope.asOf}, nil
+	}
+
+	return &payload{data: envelope.data, asOf: envelope.asOf, contentType: envelope.contentType}, nil
+}
+
+// downloadChunks gets uri and, if the result is chunked, downloads its
+// chunks one at a time handing each to fn, so only what fn keeps stays in
+// memory.  It reports whether the result was chunked, if not the data is
+// that of the envelope.  The error of fn is returned as is.
+func (i *Irdata) downloadChunks(ctx context.Context, uri string, fn func(Chunk) error) (*envelopeT, bool, error) {
+	envelope, err := i.getEnvelope(ctx, uri)
+	if err != nil {
+		return nil, false, err
+	}
+
+	data := envelope.data
+
+	// quick check for chunk info
+	if !bytes.Contains(data, []byte("chunk_info")) {
+		return envelope, false, nil
 	}
 
-	if s3Link.Link != "" {
-		log.WithFields(log.Fields{"s3Link.Link": s3Link.Link}).Debug("Following s3link")
+	var chunkedResult chunkedResultT
+
+	if err := json.Unmarshal(data, &chunkedResult); err != nil {
+		return envelope, false, nil
+	}
+
+	i.logger.Info("Chunked data detected")
+
+	limit := i.responseLimit(ctx)
+	size := int64(0)
+
+	for chunkNumber, chunkFileName := range chunkedResult.Data.Chunk_Info.Chunk_File_Names {
+		chunkUrl := fmt.Sprintf("%s%s", chunkedResult.Data.Chunk_Info.Base_Download_Url, chunkFileName)
 
-		s3Resp, err := i.retryingGet(s3Link.Link)
+		i.logger.WithFields(log.Fields{
+			"chunkNumber": chunkNumber,
+			"chunkUrl":    chunkUrl,
+		}).Debug("Fetching chunk")
+
+		chunkData, err := i.fetchChunk(ctx, chunkUrl)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
-		defer s3Resp.Body.Close()
+		// the chunks are each limited too, so at most one past the limit
+		// gets downloaded
+		size += int64(len(chunkData))
 
-		data, err = io.ReadAll(s3Resp.Body)
-		if err != nil {
-			return nil, err
+		if err := checkResponseSize(uri, size, limit); err != nil {
+			return nil, false, err
+		}
+
+		i.logger.WithFields(log.Fields{
+			"len(chunkData)": len(chunkData),
+		}).Debug("Got chunk bytes")
+
+		if err := fn(Chunk{Number: chunkNumber, FileName: chunkFileName, Data: chunkData}); err != nil {
+			return nil, false, err
 		}
 	}
 
-	// quick check for chunk info
-	if bytes.Contains(data, []byte("chunk_info")) {
-		var chunkedResult chunkedResultT
+	return envelope, true, nil
+}
 
-		err = json.Unmarshal(data, &chunkedResult)
+// envelopeT is what the API answered for a uri, after following the s3
+// link if there was one
+type envelopeT struct {
+	data []byte
+	link *s3LinkT
+	asOf time.Time
 
-		if err == nil {
-			log.Info("Chunked data detected")
+	// contentType is the content type of the linked object, "" unless a
+	// link was followed
+	contentType string
+}
 
-			var results []interface{}
+// getEnvelope gets uri following the s3 link, if any, which is returned
+// too.  For chunked results the envelope holds the chunk info.
+func (i *Irdata) getEnvelope(ctx context.Context, uri string) (*envelopeT, error) {
+	ctx, transcript := i.withRetryTranscript(ctx)
 
-			for chunkNumber, chunkFileName := range chunkedResult.Data.Chunk_Info.Chunk_File_Names {
-				chunkUrl := fmt.Sprintf("%s%s", chunkedResult.Data.Chunk_Info.Base_Download_Url, chunkFileName)
+	envelope, err := i.followEnvelope(ctx, uri)
+	if err != nil {
+		return nil, transcript.report(err, i.clock.Now())
+	}
 
-				log.WithFields(log.Fields{
-					"chunkNumber": chunkNumber,
-					"chunkUrl":    chunkUrl,
-				}).Debug("Fetching chunk")
+	return envelope, nil
+}
 
-				chunkResp, err := i.retryingGet(chunkUrl)
-				if err != nil {
-					return nil, err
-				}
+// followEnvelope is getEnvelope without the RetryReport
+func (i *Irdata) followEnvelope(ctx context.Context, uri string) (*envelopeT, error) {
+	if i.Offline() {
+		return nil, ErrOffline
+	}
 
-				chunkData, err := io.ReadAll(chunkResp.Body)
-				if err != nil {
-					return nil, err
-				}
+	o := i.callOptions(ctx, nil)
 
-				var r []interface{}
+	if err := i.checkAuthed(o); err != nil {
+		return nil, err
+	}
 
-				err = json.Unmarshal(chunkData, &r)
-				if err != nil {
-					return nil, err
-				}
+	if err := validateParams(o.withQuery(uri)); err != nil {
+		return nil, err
+	}
 
-				log.WithFields(log.Fields{
-					"len(chunkData)": len(chunkData),
-					"len(r)":         len(r),
-				}).Debug("Got chunk bytes")
+	url, err := i.resolveURL(o.withQuery(uri))
+	if err != nil {
+		return nil, err
+	}
 
-				results = append(results, r...)
-			}
+	i.logger.WithFields(log.Fields{"url": url}).Info("Fetching")
 
-			data, err = json.Marshal(results)
-			if err != nil {
-				return nil, err
-			}
+	data, header, err := i.getBodyWith(ctx, url.String(), o.header)
+	if err != nil {
+		return nil, err
+	}
 
-		}
+	var s3Link s3LinkT
+
+	i.logger.WithFields(log.Fields{"url": url}).Debug("Unmarshalling")
+
+	err = json.Unmarshal(data, &s3Link)
+	if err != nil || s3Link.Link == "" {
+		// there's no link so just return directly
+		return &envelopeT{data: data, asOf: i.responseAsOf(header, nil)}, nil
+	}
+
+	i.logger.WithFields(log.Fields{"s3Link.Link": s3Link.Link}).Debug("Following s3link")
+
+	linked, linkedHeader, err := i.getLinkedBody(ctx, s3Link.Link)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err = gunzipLinked(s3Link.Link, linked, i.responseLimit(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &envelopeT{
+		data:        linked,
+		link:        &s3Link,
+		asOf:        i.responseAsOf(header, linkedHeader),
+		contentType: linkedHeader.Get("Content-Type"),
+	}, nil
+}
+
+// responseAsOf is when the data answered with header, and linkedHeader if
+// an s3 link was followed, was produced: the Last-Modified of the s3 object
+// or else the Date of the API response, now if neither says
+func (i *Irdata) responseAsOf(header http.Header, linkedHeader http.Header) time.Time {
+	if t, err := http.ParseTime(linkedHeader.Get("Last-Modified")); err == nil {
+		return t.UTC()
+	}
+
+	if t, err := http.ParseTime(header.Get("Date")); err == nil {
+		return t.UTC()
 	}
 
-	return data, nil
+	return i.clock.Now().UTC()
+}
+
+func (i *Irdata) fetchChunk(ctx context.Context, chunkUrl string) ([]byte, error) {
+	ctx, transcript := i.withRetryTranscript(ctx)
+
+	chunkData, _, err := i.getLinkedBody(ctx, chunkUrl)
+	if err != nil {
+		return nil, transcript.report(err, i.clock.Now())
+	}
+
+	return validateChunk(chunkUrl, chunkData)
+}
+
+// validateChunk sanitizes the chunk downloaded from chunkUrl and checks it
+// holds a JSON array
+func validateChunk(chunkUrl string, chunkData []byte) ([]byte, error) {
+	raw := chunkData
+	chunkData = sanitizeChunk(chunkData)
+
+	if !json.Valid(chunkData) {
+		var v json.RawMessage
+
+		return nil, chunkError(chunkUrl, raw, json.Unmarshal(chunkData, &v))
+	}
+
+	if _, err := chunkElements(chunkData); err != nil {
+		return nil, err
+	}
+
+	return chunkData, nil
 }
 
 // GetWithCache will first check the local cache for an unexpired result
 // and will the call Get with the uri provided.
 //
-// The ttl defines for how long the results should be cached.
+// The ttl defines for how long the results should be cached.  After
+// LoadEndpointExpirations it is capped at the expiration iRacing documents
+// for the endpoint, a ttl of 0 then means exactly that long.
 //
 // You must call EnableCache before calling GetWithCache
-// NOTE: If data is fetched this will return the data even
-// if it can't be written to the cache (along with an error)
-func (i *Irdata) GetWithCache(uri string, ttl time.Duration) ([]byte, error) {
-	if i.cask == nil {
-		return nil, errors.New("cache must be enabled")
+// NOTE: By default cache errors are logged and the data is fetched
+// anyway, see WithCacheErrorPolicy.  In strict mode data that was fetched
+// but couldn't be written to the cache is returned along with the error.
+func (i *Irdata) GetWithCache(uri string, ttl time.Duration, opts ...RequestOption) ([]byte, error) {
+	return i.getWithCache(WithRequestOptions(i.ctx, opts...), uri, ttl)
+}
+
+func (i *Irdata) getWithCache(ctx context.Context, uri string, ttl time.Duration) ([]byte, error) {
+	p, err := i.getPayloadWithCache(ctx, uri, ttl)
+	if p == nil {
+		return nil, err
+	}
+
+	data, assembleErr := p.assemble()
+	if assembleErr != nil {
+		return nil, assembleErr
 	}
 
-	log.WithFields(log.Fields{"uri": uri}).Debug("Checking for cached data")
+	if data, assembleErr = i.canonical(p, data); assembleErr != nil {
+		return nil, assembleErr
+	}
+
+	return data, err
+}
+
+// ViewWithCache is GetWithCache handing the data to fn rather than
+// returning it, saving the copies GetWithCache makes.  Data cached whole is
+// passed as the cache holds it when the backend is a CacheViewer, as
+// MemoryCache is, and chunked results are merged into a reused buffer.
+//
+// The data is only valid until fn returns and must not be modified, fn
+// copies what it keeps.  The error of fn is returned as is.
+func (i *Irdata) ViewWithCache(uri string, ttl time.Duration, fn func(data []byte) error, opts ...RequestOption) error {
+	p, err := i.readThrough(WithRequestOptions(i.ctx, opts...), uri, ttl, true)
+	if p == nil {
+		return err
+	}
+
+	if !p.isChunked() {
+		if fnErr := fn(p.data); fnErr != nil {
+			return fnErr
+		}
+
+		return err
+	}
+
+	buf := assembleBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	defer func() {
+		if buf.Cap() <= maxPooledAssembly {
+			assembleBuffers.Put(buf)
+		}
+	}()
+
+	if assembleErr := p.assembleTo(buf); assembleErr != nil {
+		return assembleErr
+	}
+
+	if fnErr := fn(buf.Bytes()); fnErr != nil {
+		return fnErr
+	}
+
+	return err
+}
+
+// assembleBuffers are the buffers ViewWithCache merges chunks into, those
+// grown past maxPooledAssembly aren't kept
+var assembleBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
 
-	data, err := i.getCachedData(uri)
+const maxPooledAssembly = 16 * 1024 * 1024
+
+// getPayloadWithCache is getWithCache returning the payload rather than
+// the assembled data.  In strict mode a payload that couldn't be written to
+// the cache is returned along with the error.
+func (i *Irdata) getPayloadWithCache(ctx context.Context, uri string, ttl time.Duration) (*payload, error) {
+	return i.readThrough(ctx, uri, ttl, false)
+}
+
+// readThrough is getPayloadWithCache reading cached data shared with the
+// backend when shared is set, see lookupCached
+func (i *Irdata) readThrough(ctx context.Context, uri string, ttl time.Duration, shared bool) (*payload, error) {
+	if i.metricHook == nil {
+		p, _, err := i.readCached(ctx, uri, ttl, shared)
+		return p, err
+	}
+
+	started := i.clock.Now()
+
+	p, outcome, err := i.readCached(withCachedCall(ctx), uri, ttl, shared)
+
+	i.notifyMetric(uri, outcome, p.size(), err, started)
+
+	return p, err
+}
+
+// readCached is readThrough also returning how the cache served it
+func (i *Irdata) readCached(ctx context.Context, uri string, ttl time.Duration, shared bool) (*payload, CacheOutcome, error) {
+	ctx, end, err := i.beginRequest(ctx)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"err": err,
-			"uri": uri,
-		}).Error("Unable to get cached data")
-		return nil, err
+		return nil, CacheMiss, err
 	}
 
-	if data != nil {
-		return data, nil
+	defer end()
+
+	if i.cache == nil {
+		return nil, CacheMiss, errors.New("cache must be enabled")
+	}
+
+	// the fields are only built when logged, this runs for every cached read
+	debug := i.logger.IsLevelEnabled(log.DebugLevel)
+	trace := i.logger.IsLevelEnabled(log.TraceLevel)
+
+	if debug {
+		i.logger.WithFields(log.Fields{"uri": uri}).Debug("Checking for cached data")
 	}
 
-	log.WithFields(log.Fields{"uri": uri}).Debug("Nothing in cache")
+	key := i.callCacheKey(ctx, uri)
+
+	p, err := i.cachedPayload(ctx, key, shared)
+	if errors.Is(err, ErrResponseTooLarge) {
+		// the network would only answer with the same
+		return nil, CacheMiss, err
+	}
 
-	data, err = i.Get(uri)
 	if err != nil {
-		return nil, err
+		if err := i.cacheFailed("read", uri, err); err != nil {
+			return nil, CacheMiss, err
+		}
+
+		p = nil
+	}
+
+	if p != nil {
+		if trace {
+			i.logger.WithFields(log.Fields{"uri": uri, "cache": "hit"}).Trace("Cache decision")
+		}
+
+		if p.stale && !i.Offline() {
+			if debug {
+				i.logger.WithFields(log.Fields{"uri": uri}).Debug("Serving expired cache entry while refreshing it")
+			}
+
+			i.scheduleRefresh(ctx, uri, key, ttl)
+
+			return p, CacheStale, nil
+		}
+
+		if p.stale {
+			i.logger.WithFields(log.Fields{
+				"uri":   uri,
+				"stale": true,
+				"age":   i.clock.Now().Sub(p.asOf),
+			}).Warn("Serving expired cache entry while offline")
+
+			return p, CacheStale, nil
+		}
+
+		return p, CacheHit, nil
+	}
+
+	if trace {
+		i.logger.WithFields(log.Fields{"uri": uri, "cache": "miss"}).Trace("Cache decision")
+	}
+
+	if debug {
+		i.logger.WithFields(log.Fields{"uri": uri}).Debug("Nothing in cache")
+	}
+
+	p, err = i.fetch(ctx, uri)
+	if err != nil {
+		return nil, CacheMiss, err
 	}
 
-	log.WithFields(log.Fields{
+	i.logger.WithFields(log.Fields{
 		"ttl": ttl,
 		"uri": uri,
 	}).Debug("Got data, writing to cache")
 
-	err = i.setCachedData(uri, data, ttl)
+	if err := i.storeCached(ctx, uri, key, p, i.cacheTTL(uri, ttl)); err != nil {
+		return p, CacheMiss, i.cacheFailed("write", uri, err)
+	}
+
+	return p, CacheMiss, nil
+}
+
+// GetChunksWithCache is GetWithCache for results too large to hold in
+// memory at once.  Instead of returning the merged result it calls fn with
+// each chunk in order.  Cached chunks are read one at a time and, on a
+// miss, each is downloaded and cached before fn is called with it and the
+// next one is downloaded.  A result that isn't chunked is passed to fn as a
+// single chunk.
+//
+// Returning an error from fn stops the iteration and is returned as is.
+// WithMaxResponseSize doesn't apply.
+func (i *Irdata) GetChunksWithCache(uri string, ttl time.Duration, fn func(Chunk) error, opts ...RequestOption) error {
+	ctx := withoutResponseLimit(WithRequestOptions(i.ctx, opts...))
+
+	if i.metricHook == nil {
+		_, err := i.chunksWithCache(ctx, uri, ttl, fn)
+		return err
+	}
+
+	started := i.clock.Now()
+	size := 0
+
+	outcome, err := i.chunksWithCache(withCachedCall(ctx), uri, ttl, func(chunk Chunk) error {
+		size += len(chunk.Data)
+		return fn(chunk)
+	})
+
+	i.notifyMetric(uri, outcome, size, err, started)
+
+	return err
+}
+
+// chunksWithCache is GetChunksWithCache also returning how the cache
+// served it
+func (i *Irdata) chunksWithCache(ctx context.Context, uri string, ttl time.Duration, fn func(Chunk) error) (CacheOutcome, error) {
+	ctx, end, err := i.beginRequest(ctx)
+	if err != nil {
+		return CacheMiss, err
+	}
+
+	defer end()
+
+	if i.cache == nil {
+		return CacheMiss, errors.New("cache must be enabled")
+	}
+
+	key := i.callCacheKey(ctx, uri)
+
+	entry, err := i.lookupCached(ctx, key, false)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"uri":       uri,
-			"err":       err,
-			"len(data)": len(data),
-		}).Error("Unable to cache")
+		if err := i.cacheFailed("read", uri, err); err != nil {
+			return CacheMiss, err
+		}
+
+		entry = nil
+	}
+
+	if entry == nil {
+		return CacheMiss, i.fetchChunks(ctx, uri, ttl, fn)
+	}
+
+	outcome := CacheHit
+	if entry.stale {
+		outcome = CacheStale
+
+		if !i.Offline() {
+			i.scheduleRefresh(ctx, uri, key, ttl)
+		}
+	}
+
+	if entry.index != nil {
+		for n, fileName := range entry.index.Chunks {
+			chunkData, err := i.cacheGet(ctx, entry.chunkKeys[n])
+			if err != nil {
+				// chunks already handed to fn can't be taken back so only
+				// a failure on the first one can fall through
+				if failErr := i.cacheFailed("read", uri, err); failErr != nil || n > 0 {
+					return outcome, err
+				}
+
+				return CacheMiss, i.fetchChunks(ctx, uri, ttl, fn)
+			}
+
+			if chunkData == nil {
+				return outcome, errors.New("cached chunk expired while reading")
+			}
 
-		return data, err
+			if err := fn(Chunk{Number: n, FileName: fileName, Data: chunkData}); err != nil {
+				return outcome, err
+			}
+		}
+
+		return outcome, nil
 	}
 
-	return data, nil
+	return outcome, fn(Chunk{Data: entry.data})
 }
 
-func (i *Irdata) retryingGet(url string) (resp *http.Response, err error) {
-	retries := 5
+// fetchChunks is the uncached half of GetChunksWithCache.  Each chunk is
+// cached and handed to fn as it's downloaded, before the next one is, so
+// only one is held at a time.  Cache filters and archiving need the whole
+// result though, with either applying to uri the chunks are all downloaded
+// first.
+func (i *Irdata) fetchChunks(ctx context.Context, uri string, ttl time.Duration, fn func(Chunk) error) error {
+	if i.hasCacheFilter() || i.archiving(uri) {
+		return i.fetchWholeChunks(ctx, uri, ttl, fn)
+	}
 
-	for retries > 0 {
-		log.WithFields(log.Fields{
-			"url":     url,
-			"retries": retries,
-		}).Info("httpClient.Get")
+	key := i.callCacheKey(ctx, uri)
+	ttl = i.cacheTTL(uri, ttl)
 
-		resp, err = i.httpClient.Get(url)
+	w, err := i.newChunkWriter(key, ttl)
+	if err != nil {
+		if err := i.cacheFailed("write", uri, err); err != nil {
+			return err
+		}
+	}
+
+	envelope, chunked, err := i.downloadChunks(ctx, uri, func(chunk Chunk) error {
+		if w != nil {
+			if err := w.write(ctx, chunk); err != nil {
+				if err := i.cacheFailed("write", uri, err); err != nil {
+					return err
+				}
+
+				// the rest isn't cached either
+				w = nil
+			}
+		}
+
+		return fn(chunk)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !chunked {
+		p := &payload{data: envelope.data, asOf: envelope.asOf, contentType: envelope.contentType}
 
-		if resp.StatusCode < 500 {
-			break
+		if err := i.setCachedPayload(ctx, key, p, ttl, ""); err != nil {
+			if err := i.cacheFailed("write", uri, err); err != nil {
+				return err
+			}
 		}
 
-		log.WithFields(log.Fields{
-			"url":             url,
-			"resp.StatusCode": resp.StatusCode,
-		}).Info("*** Retrying")
+		return fn(Chunk{Data: p.data})
+	}
+
+	if w != nil {
+		if err := w.finish(ctx, "", envelope.asOf); err != nil {
+			return i.cacheFailed("write", uri, err)
+		}
+	}
 
-		retries--
+	return nil
+}
 
-		time.Sleep(time.Duration((6-retries)*5) * time.Second)
+// fetchWholeChunks is fetchChunks downloading all the chunks before fn is
+// called with the first
+func (i *Irdata) fetchWholeChunks(ctx context.Context, uri string, ttl time.Duration, fn func(Chunk) error) error {
+	p, err := i.fetch(ctx, uri)
+	if err != nil {
+		return err
 	}
 
-	return resp, err
+	if err := i.storeCached(ctx, uri, i.callCacheKey(ctx, uri), p, i.cacheTTL(uri, ttl)); err != nil {
+		if err := i.cacheFailed("write", uri, err); err != nil {
+			return err
+		}
+	}
+
+	if !p.isChunked() {
+		return fn(Chunk{Data: p.data})
+	}
+
+	for _, chunk := range p.chunks {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveURL resolves uri against the API host this instance talks to,
+// refusing legacy membersite URLs
+func (i *Irdata) resolveURL(uri string) (*url.URL, error) {
+	if err := checkLegacyURI(uri); err != nil {
+		return nil, err
+	}
+
+	uriRef, err := url.Parse(normalizeURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	return i.baseURL.ResolveReference(uriRef), nil
+}
+
+func (i *Irdata) retryingGet(ctx context.Context, url string) (*http.Response, error) {
+	return i.retryingDo(ctx, http.MethodGet, url, nil, nil, retryServerErrors)
 }
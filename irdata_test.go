@@ -3,7 +3,9 @@ package irdata
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -93,3 +95,330 @@ func TestCachedGetBasic(t *testing.T) {
 		assertIsJson(t, data)
 	}
 }
+
+func mockChunks(chunks ...string) func(*http.Request) []string {
+	return func(*http.Request) []string {
+		return chunks
+	}
+}
+
+func TestMockedChunkedGet(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2},{"id":3}]`))
+	m.handleChunked("/data/results/empty", mockChunks())
+
+	api := m.openAuthed(t)
+
+	data, err := api.Get("/data/results/search_series")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1},{"id":2},{"id":3}]`, string(data))
+
+	data, err = api.Get("/data/results/empty")
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+}
+
+func TestMockedChunkedGetWithCache(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2},{"id":3}]`))
+
+	api := m.openAuthed(t)
+	defer api.Close()
+
+	assert.NoError(t, api.EnableCache(t.TempDir()))
+
+	for n := 0; n < 2; n++ {
+		data, err := api.GetWithCache("/data/results/search_series", time.Hour)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"id":1},{"id":2},{"id":3}]`, string(data))
+	}
+
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+}
+
+func TestGetChunksWithCache(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2},{"id":3}]`))
+	m.handleJSON("/data/constants/categories", `[{"value":1}]`)
+
+	api := m.openAuthed(t)
+	defer api.Close()
+
+	assert.NoError(t, api.EnableCache(t.TempDir()))
+
+	for n := 0; n < 2; n++ {
+		var chunks []string
+
+		err := api.GetChunksWithCache("/data/results/search_series", time.Hour, func(chunk Chunk) error {
+			assert.Equal(t, len(chunks), chunk.Number)
+			chunks = append(chunks, string(chunk.Data))
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`[{"id":1}]`, `[{"id":2},{"id":3}]`}, chunks)
+	}
+
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+
+	var whole []string
+
+	assert.NoError(t, api.GetChunksWithCache("/data/constants/categories", time.Hour, func(chunk Chunk) error {
+		whole = append(whole, string(chunk.Data))
+		return nil
+	}))
+	assert.Equal(t, []string{`[{"value":1}]`}, whole)
+
+	stop := errors.New("stop")
+	calls := 0
+
+	assert.ErrorIs(t, api.GetChunksWithCache("/data/results/search_series", time.Hour, func(Chunk) error {
+		calls++
+		return stop
+	}), stop)
+	assert.Equal(t, 1, calls)
+}
+
+func benchmarkChunks() []string {
+	var chunks []string
+
+	for c := 0; c < 20; c++ {
+		chunk := "["
+
+		for r := 0; r < 500; r++ {
+			if r > 0 {
+				chunk += ","
+			}
+
+			chunk += fmt.Sprintf(`{"subsession_id":%d,"series_name":"Formula Vee","laps":12,"best":"1:02.345"}`, c*500+r)
+		}
+
+		chunks = append(chunks, chunk+"]")
+	}
+
+	return chunks
+}
+
+func benchmarkCachedAPI(b *testing.B) (*Irdata, string) {
+	m := newMockAPI(b)
+	m.handleChunked("/data/results/search_series", mockChunks(benchmarkChunks()...))
+
+	api := m.openAuthed(b)
+	b.Cleanup(func() { api.Close() })
+
+	if err := api.EnableCache(b.TempDir()); err != nil {
+		b.Fatal(err)
+	}
+
+	uri := "/data/results/search_series"
+
+	if _, err := api.GetWithCache(uri, time.Hour); err != nil {
+		b.Fatal(err)
+	}
+
+	return api, uri
+}
+
+// reading a result cached whole, the way every result was cached before
+// chunks were stored separately
+func BenchmarkCachedReadWhole(b *testing.B) {
+	api, uri := benchmarkCachedAPI(b)
+
+	data, err := api.GetWithCache(uri, time.Hour)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if err := api.setCachedData(context.Background(), "whole", data, time.Hour); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := api.getCachedData(context.Background(), "whole"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCachedReadAssembled(b *testing.B) {
+	api, uri := benchmarkCachedAPI(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := api.GetWithCache(uri, time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCachedReadChunks(b *testing.B) {
+	api, uri := benchmarkCachedAPI(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		err := api.GetChunksWithCache(uri, time.Hour, func(Chunk) error { return nil })
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// the hot path of a dashboard: a small result served from the cache over
+// and over
+func BenchmarkCachedReadSmall(b *testing.B) {
+	m := newMockAPI(b)
+	m.handleLinked("/data/constants/categories", `[{"label":"Oval","value":1},{"label":"Road","value":2}]`)
+
+	api := m.openAuthed(b)
+	b.Cleanup(func() { api.Close() })
+
+	api.EnableCacheBackend(NewMemoryCache())
+
+	uri := "/data/constants/categories"
+
+	if _, err := api.GetWithCache(uri, time.Hour); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := api.GetWithCache(uri, time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCachedReadView(b *testing.B) {
+	m := newMockAPI(b)
+	m.handleLinked("/data/constants/categories", `[{"label":"Oval","value":1},{"label":"Road","value":2}]`)
+
+	api := m.openAuthed(b)
+	b.Cleanup(func() { api.Close() })
+
+	api.EnableCacheBackend(NewMemoryCache())
+
+	uri := "/data/constants/categories"
+
+	view := func([]byte) error { return nil }
+
+	if err := api.ViewWithCache(uri, time.Hour, view); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if err := api.ViewWithCache(uri, time.Hour, view); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCachedReadAssembledView(b *testing.B) {
+	api, uri := benchmarkCachedAPI(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if err := api.ViewWithCache(uri, time.Hour, func([]byte) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCacheKey(b *testing.B) {
+	api := Open(context.Background())
+	api.cacheNamespace.name = "4242"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		hashKey(api.cacheKey("/data/member/info"))
+		metaKey(api.cacheKey("/data/results/search_series?cust_id=4242&season_year=2024"))
+	}
+}
+
+func TestViewWithCache(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2},{"id":3}]`))
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	var viewed []string
+
+	view := func(data []byte) error {
+		viewed = append(viewed, string(data))
+		return nil
+	}
+
+	for n := 0; n < 2; n++ {
+		assert.NoError(t, api.ViewWithCache("/data/constants/categories", time.Hour, view))
+		assert.NoError(t, api.ViewWithCache("/data/results/search_series", time.Hour, view))
+	}
+
+	assert.Equal(t, []string{
+		`[{"label":"Oval","value":1}]`,
+		`[{"id":1},{"id":2},{"id":3}]`,
+		`[{"label":"Oval","value":1}]`,
+		`[{"id":1},{"id":2},{"id":3}]`,
+	}, viewed)
+
+	assert.Equal(t, 1, m.hitCount("/data/constants/categories"))
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+
+	data, err := api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":1},{"id":2},{"id":3}]`, string(data))
+
+	failed := errors.New("failed")
+
+	assert.Equal(t, failed, api.ViewWithCache("/data/constants/categories", time.Hour, func([]byte) error { return failed }))
+}
+
+func TestGetJSONLargeIDs(t *testing.T) {
+	// 2^53 + 1 doesn't survive a trip through float64
+	const subsessionID = 9007199254740993
+
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", `{"subsession_id":9007199254740993,"cust_id":123}`)
+
+	api := m.openAuthed(t)
+
+	var result struct {
+		SubsessionID int64 `json:"subsession_id"`
+	}
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/results/get", &result))
+	assert.Equal(t, int64(subsessionID), result.SubsessionID)
+
+	var raw map[string]interface{}
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/results/get", &raw, WithUseNumber()))
+
+	id, err := raw["subsession_id"].(json.Number).Int64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(subsessionID), id)
+
+	// round trips unchanged
+	data, err := json.Marshal(raw)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "9007199254740993")
+
+	// the pitfall
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/results/get", &raw))
+	assert.NotEqual(t, int64(subsessionID), int64(raw["subsession_id"].(float64)))
+}
@@ -0,0 +1,83 @@
+package irdata
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// LapFlags is the flags bitfield of a lap, the lap events it lists as
+// LapEvents.  Bits without a constant are kept as they are.
+type LapFlags int64
+
+// The lap flags, named after the lap events iRacing reports for them
+const (
+	LapFlagInvalid LapFlags = 1 << iota
+	LapFlagPitted
+	LapFlagOffTrack
+	LapFlagBlackFlag
+	LapFlagCarReset
+	LapFlagContact
+	LapFlagCarContact
+	LapFlagLostControl
+	LapFlagDiscontinuity
+	LapFlagInterpolatedCrossing
+	LapFlagClockSmash
+	LapFlagTow
+)
+
+// lapFlagNames are the lap events of the flags, by bit
+var lapFlagNames = []string{
+	"invalid",
+	LapEventPitted,
+	"off track",
+	"black flag",
+	"car reset",
+	"contact",
+	"car contact",
+	"lost control",
+	"discontinuity",
+	"interpolated crossing",
+	"clock smash",
+	"tow",
+}
+
+// Has reports whether every bit of flag is set
+func (f LapFlags) Has(flag LapFlags) bool {
+	return f&flag == flag
+}
+
+// Events returns the lap events of the flags set, in bit order.  Unknown
+// bits are left out, see Unknown.
+func (f LapFlags) Events() []string {
+	var events []string
+
+	for bit, name := range lapFlagNames {
+		if f&(1<<bit) != 0 {
+			events = append(events, name)
+		}
+	}
+
+	return events
+}
+
+// Unknown returns the bits set that have no constant
+func (f LapFlags) Unknown() LapFlags {
+	return f &^ (1<<len(lapFlagNames) - 1)
+}
+
+// String lists the flags set, e.g. "pitted, off track", with unknown bits
+// as "bit 14".  It returns "none" when no flag is set.
+func (f LapFlags) String() string {
+	if f == 0 {
+		return "none"
+	}
+
+	names := f.Events()
+
+	for unknown := uint64(f.Unknown()); unknown != 0; unknown &= unknown - 1 {
+		names = append(names, fmt.Sprintf("bit %d", bits.TrailingZeros64(unknown)))
+	}
+
+	return strings.Join(names, ", ")
+}
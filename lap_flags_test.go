@@ -0,0 +1,54 @@
+package irdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLapFlagsReference checks the constants against flags and lap events
+// as lap_data reports them together
+func TestLapFlagsReference(t *testing.T) {
+	var laps []Lap
+
+	assert.NoError(t, json.Unmarshal(readFixture(t, "lap_flags.json"), &laps))
+
+	for _, lap := range laps {
+		assert.Equal(t, len(lap.LapEvents), len(lap.Flags.Events()), "flags %d", lap.Flags)
+
+		for _, event := range lap.LapEvents {
+			assert.Contains(t, lap.Flags.Events(), event, "flags %d", lap.Flags)
+		}
+
+		assert.Zero(t, lap.Flags.Unknown(), "flags %d", lap.Flags)
+	}
+}
+
+func TestLapFlags(t *testing.T) {
+	f := LapFlagPitted | LapFlagOffTrack
+
+	assert.True(t, f.Has(LapFlagPitted))
+	assert.True(t, f.Has(LapFlagPitted|LapFlagOffTrack))
+	assert.False(t, f.Has(LapFlagPitted|LapFlagTow))
+	assert.False(t, f.Has(LapFlagCarContact))
+
+	assert.Equal(t, "pitted, off track", f.String())
+	assert.Equal(t, "none", LapFlags(0).String())
+	assert.Equal(t, "lost control", fmt.Sprint(LapFlagLostControl))
+}
+
+func TestLapFlagsUnknownBits(t *testing.T) {
+	var lap Lap
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"flags":16386}`), &lap))
+
+	assert.Equal(t, LapFlags(1<<14), lap.Flags.Unknown())
+	assert.Equal(t, "pitted, bit 14", lap.Flags.String())
+	assert.Equal(t, []string{LapEventPitted}, lap.Flags.Events())
+
+	data, err := json.Marshal(lap)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"flags":16386`)
+}
@@ -0,0 +1,86 @@
+package irdata
+
+import (
+	"fmt"
+	"time"
+)
+
+// NoTime is the lap and session time iRacing reports when there is none
+const NoTime = -1
+
+// LapEventPitted marks the lap a car entered the pits on
+const LapEventPitted = "pitted"
+
+// ParticipantID identifies a driver (by cust_id) or, in team events, a team
+// (by team_id) in lap data
+type ParticipantID int64
+
+// Lap is a single lap of /data/results/lap_data or lap_chart_data.  Times
+// are in ten thousandths of a second.
+type Lap struct {
+	GroupID         int64    `json:"group_id"`
+	Name            string   `json:"name" pii:"name"`
+	CustID          int64    `json:"cust_id" pii:"cust_id"`
+	DisplayName     string   `json:"display_name" pii:"name"`
+	CarClassID      int64    `json:"car_class_id"`
+	CarNumber       string   `json:"car_number"`
+	LapNumber       int      `json:"lap_number"`
+	Flags           LapFlags `json:"flags"`
+	Incident        bool     `json:"incident"`
+	SessionTime     int64    `json:"session_time"`
+	LapTime         int64    `json:"lap_time"`
+	TeamFastestLap  bool     `json:"team_fastest_lap"`
+	PersonalBestLap bool     `json:"personal_best_lap"`
+	LapEvents       []string `json:"lap_events"`
+}
+
+// Time returns the lap time, false if the lap has none
+func (l Lap) Time() (time.Duration, bool) {
+	return ticks(l.LapTime)
+}
+
+// CompletedAt returns the session time the lap was completed at, false if
+// unknown
+func (l Lap) CompletedAt() (time.Duration, bool) {
+	return ticks(l.SessionTime)
+}
+
+// HasEvent reports whether event is among the lap events
+func (l Lap) HasEvent(event string) bool {
+	for _, e := range l.LapEvents {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LapTime is a lap time in ten thousandths of a second, NoTime if none was
+// set
+type LapTime int64
+
+// Duration returns the lap time, false if there is none
+func (t LapTime) Duration() (time.Duration, bool) {
+	return ticks(int64(t))
+}
+
+// String formats the lap time like iRacing does, e.g. 1:23.4567
+func (t LapTime) String() string {
+	if t < 0 {
+		return "-"
+	}
+
+	minutes := int64(t) / 600000
+	seconds := int64(t) % 600000
+
+	return fmt.Sprintf("%d:%02d.%04d", minutes, seconds/10000, seconds%10000)
+}
+
+func ticks(t int64) (time.Duration, bool) {
+	if t < 0 {
+		return 0, false
+	}
+
+	return time.Duration(t) * 100 * time.Microsecond, true
+}
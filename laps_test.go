@@ -0,0 +1,34 @@
+package irdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLap(t *testing.T) {
+	lap := Lap{LapTime: 905123, SessionTime: NoTime, LapEvents: []string{"off track", LapEventPitted}}
+
+	lapTime, ok := lap.Time()
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second+512300*time.Microsecond, lapTime)
+
+	_, ok = lap.CompletedAt()
+	assert.False(t, ok)
+
+	assert.True(t, lap.HasEvent(LapEventPitted))
+	assert.False(t, lap.HasEvent("invalid"))
+}
+
+func TestLapTime(t *testing.T) {
+	d, ok := LapTime(905123).Duration()
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second+512300*time.Microsecond, d)
+	assert.Equal(t, "1:30.5123", LapTime(905123).String())
+	assert.Equal(t, "0:09.0001", LapTime(90001).String())
+
+	_, ok = LapTime(NoTime).Duration()
+	assert.False(t, ok)
+	assert.Equal(t, "-", LapTime(NoTime).String())
+}
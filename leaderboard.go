@@ -0,0 +1,402 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// defaultLeaderboardConcurrency is how many classes SeasonLeaderboard
+// fetches at once unless told otherwise
+const defaultLeaderboardConcurrency = 4
+
+// LeaderboardParams are the parameters of the season qualifying and time
+// trial endpoints.  SeasonID and CarClassID are required, RaceWeekNum and
+// Division are left out of the query when nil.
+type LeaderboardParams struct {
+	SeasonID    int64
+	CarClassID  int64
+	RaceWeekNum *int
+	Division    *int
+}
+
+func (p LeaderboardParams) values() url.Values {
+	v := url.Values{}
+
+	setInt(v, "season_id", p.SeasonID)
+	setInt(v, "car_class_id", p.CarClassID)
+
+	if p.RaceWeekNum != nil {
+		v.Set("race_week_num", strconv.Itoa(*p.RaceWeekNum))
+	}
+
+	if p.Division != nil {
+		v.Set("division", strconv.Itoa(*p.Division))
+	}
+
+	return v
+}
+
+// QualifyResult is a row of /data/stats/season_qualify_results
+type QualifyResult struct {
+	Rank            int     `json:"rank"`
+	CustID          int64   `json:"cust_id" pii:"cust_id"`
+	DisplayName     string  `json:"display_name" pii:"name"`
+	Division        int     `json:"division"`
+	ClubID          int64   `json:"club_id" pii:"club"`
+	ClubName        string  `json:"club_name" pii:"club"`
+	Week            int     `json:"week"`
+	BestQualLapTime LapTime `json:"best_qual_lap_time"`
+}
+
+// TimeTrialResult is a row of /data/stats/season_tt_results
+type TimeTrialResult struct {
+	Rank          int     `json:"rank"`
+	CustID        int64   `json:"cust_id" pii:"cust_id"`
+	DisplayName   string  `json:"display_name" pii:"name"`
+	Division      int     `json:"division"`
+	ClubID        int64   `json:"club_id" pii:"club"`
+	ClubName      string  `json:"club_name" pii:"club"`
+	Week          int     `json:"week"`
+	Starts        int     `json:"starts"`
+	Points        int     `json:"points"`
+	BestNLapsTime LapTime `json:"best_nlaps_time"`
+}
+
+// TimeTrialStanding is a row of /data/stats/season_tt_standings
+type TimeTrialStanding struct {
+	Rank         int    `json:"rank"`
+	CustID       int64  `json:"cust_id" pii:"cust_id"`
+	DisplayName  string `json:"display_name" pii:"name"`
+	Division     int    `json:"division"`
+	ClubID       int64  `json:"club_id" pii:"club"`
+	ClubName     string `json:"club_name" pii:"club"`
+	WeeksCounted int    `json:"weeks_counted"`
+	Starts       int    `json:"starts"`
+	Points       int    `json:"points"`
+}
+
+// GetSeasonQualifyResults returns the qualifying results of a class in a
+// season, merging the chunks.  A class nobody qualified in has none.
+func (i *Irdata) GetSeasonQualifyResults(ctx context.Context, params LeaderboardParams) ([]QualifyResult, error) {
+	var rows []QualifyResult
+
+	if err := i.getLeaderboard(ctx, "/data/stats/season_qualify_results", params, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetSeasonTTResults returns the time trial results of a class in a
+// season, merging the chunks
+func (i *Irdata) GetSeasonTTResults(ctx context.Context, params LeaderboardParams) ([]TimeTrialResult, error) {
+	var rows []TimeTrialResult
+
+	if err := i.getLeaderboard(ctx, "/data/stats/season_tt_results", params, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetSeasonTTStandings returns the time trial standings of a class in a
+// season, merging the chunks
+func (i *Irdata) GetSeasonTTStandings(ctx context.Context, params LeaderboardParams) ([]TimeTrialStanding, error) {
+	var rows []TimeTrialStanding
+
+	if err := i.getLeaderboard(ctx, "/data/stats/season_tt_standings", params, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// WorldRecordParams are the parameters of /data/stats/world_records.
+// CarID and TrackID are required, without SeasonYear and SeasonQuarter the
+// all time records are returned.
+type WorldRecordParams struct {
+	CarID         int64
+	TrackID       int64
+	SeasonYear    int
+	SeasonQuarter int
+}
+
+func (p WorldRecordParams) values() url.Values {
+	v := url.Values{}
+
+	setInt(v, "car_id", p.CarID)
+	setInt(v, "track_id", p.TrackID)
+
+	if p.SeasonYear != 0 {
+		v.Set("season_year", strconv.Itoa(p.SeasonYear))
+	}
+
+	if p.SeasonQuarter != 0 {
+		v.Set("season_quarter", strconv.Itoa(p.SeasonQuarter))
+	}
+
+	return v
+}
+
+// WorldRecord is a row of /data/stats/world_records, a driver's best laps
+// with a car at a track.  The lap times are NoTime for sessions they didn't
+// set one in.
+type WorldRecord struct {
+	CustID        int64   `json:"cust_id" pii:"cust_id"`
+	DisplayName   string  `json:"display_name" pii:"name"`
+	Region        string  `json:"region"`
+	ClubID        int64   `json:"club_id" pii:"club"`
+	ClubName      string  `json:"club_name" pii:"club"`
+	CountryCode   string  `json:"country_code"`
+	SeasonYear    int     `json:"season_year"`
+	SeasonQuarter int     `json:"season_quarter"`
+	Practice      LapTime `json:"practice"`
+	Qualify       LapTime `json:"qualify"`
+	TimeTrial     LapTime `json:"tt"`
+	Race          LapTime `json:"race"`
+}
+
+// GetWorldRecords returns the best laps of every driver with a car at a
+// track, merging the chunks
+func (i *Irdata) GetWorldRecords(ctx context.Context, params WorldRecordParams) ([]WorldRecord, error) {
+	uri, err := worldRecordsURI(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []WorldRecord
+
+	if err := i.GetJSON(ctx, uri, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func worldRecordsURI(params WorldRecordParams) (string, error) {
+	if params.CarID == 0 || params.TrackID == 0 {
+		return "", errors.New("must provide car id and track id")
+	}
+
+	return "/data/stats/world_records?" + params.values().Encode(), nil
+}
+
+func (i *Irdata) getLeaderboard(ctx context.Context, endpoint string, params LeaderboardParams, v interface{}) error {
+	uri, err := leaderboardURI(endpoint, params)
+	if err != nil {
+		return err
+	}
+
+	return i.GetJSON(ctx, uri, v)
+}
+
+func leaderboardURI(endpoint string, params LeaderboardParams) (string, error) {
+	if params.SeasonID == 0 || params.CarClassID == 0 {
+		return "", errors.New("must provide season id and car class id")
+	}
+
+	return endpoint + "?" + params.values().Encode(), nil
+}
+
+// LeaderboardKind picks the lap times SeasonLeaderboard ranks by
+type LeaderboardKind int
+
+const (
+	// QualifyingLeaderboard ranks by best qualifying lap
+	QualifyingLeaderboard LeaderboardKind = iota
+
+	// TimeTrialLeaderboard ranks by best time trial
+	TimeTrialLeaderboard
+)
+
+// LeaderboardOptions are the options of SeasonLeaderboard.
+//
+// Division and RaceWeekNum are sent to the server, Divisions filters the
+// rows client side (e.g. to pick several divisions with one call per class).
+type LeaderboardOptions struct {
+	Kind        LeaderboardKind
+	RaceWeekNum *int
+	Division    *int
+	Divisions   []int
+
+	// Concurrency is how many classes are fetched at once, 4 if 0, fewer
+	// when little is left of the rate limit
+	Concurrency int
+}
+
+// LeaderboardRow is a row of a multi-class leaderboard
+type LeaderboardRow struct {
+	// Position is the place in the merged leaderboard, ClassRank the one
+	// iRacing gave within the class
+	Position    int
+	ClassRank   int
+	CarClassID  int64
+	CustID      int64
+	DisplayName string
+	Division    int
+	ClubID      int64
+	ClubName    string
+	Best        LapTime
+}
+
+// SeasonLeaderboard fetches the qualifying or time trial results of every
+// class of season and merges them into one leaderboard sorted by best
+// time, rows without one last.  Classes nobody ran in are left out.
+func (i *Irdata) SeasonLeaderboard(ctx context.Context, season Season, opts LeaderboardOptions) ([]LeaderboardRow, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultLeaderboardConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	perClass := make([][]LeaderboardRow, len(season.CarClassIDs))
+
+	sem := make(chan struct{}, i.tunedConcurrency(ctx, concurrency))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for n, carClassID := range season.CarClassIDs {
+		wg.Add(1)
+
+		go func(n int, carClassID int64) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			rows, err := i.classLeaderboard(ctx, opts.Kind, LeaderboardParams{
+				SeasonID:    season.SeasonID,
+				CarClassID:  carClassID,
+				RaceWeekNum: opts.RaceWeekNum,
+				Division:    opts.Division,
+			})
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+
+				// the other classes are of no use anymore
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+
+				return
+			}
+
+			perClass[n] = rows
+		}(n, carClassID)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var rows []LeaderboardRow
+
+	for _, classRows := range perClass {
+		for _, row := range classRows {
+			if inDivisions(row.Division, opts.Divisions) {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	sort.SliceStable(rows, func(a, b int) bool {
+		if (rows[a].Best < 0) != (rows[b].Best < 0) {
+			return rows[b].Best < 0
+		}
+
+		if rows[a].Best != rows[b].Best {
+			return rows[a].Best < rows[b].Best
+		}
+
+		return rows[a].CustID < rows[b].CustID
+	})
+
+	for n := range rows {
+		rows[n].Position = n + 1
+	}
+
+	return rows, nil
+}
+
+// classLeaderboard fetches the leaderboard rows of a single class
+func (i *Irdata) classLeaderboard(ctx context.Context, kind LeaderboardKind, params LeaderboardParams) ([]LeaderboardRow, error) {
+	var rows []LeaderboardRow
+
+	switch kind {
+	case QualifyingLeaderboard:
+		results, err := i.GetSeasonQualifyResults(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range results {
+			rows = append(rows, LeaderboardRow{
+				ClassRank:   r.Rank,
+				CarClassID:  params.CarClassID,
+				CustID:      r.CustID,
+				DisplayName: r.DisplayName,
+				Division:    r.Division,
+				ClubID:      r.ClubID,
+				ClubName:    r.ClubName,
+				Best:        r.BestQualLapTime,
+			})
+		}
+	case TimeTrialLeaderboard:
+		results, err := i.GetSeasonTTResults(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range results {
+			rows = append(rows, LeaderboardRow{
+				ClassRank:   r.Rank,
+				CarClassID:  params.CarClassID,
+				CustID:      r.CustID,
+				DisplayName: r.DisplayName,
+				Division:    r.Division,
+				ClubID:      r.ClubID,
+				ClubName:    r.ClubName,
+				Best:        r.BestNLapsTime,
+			})
+		}
+	default:
+		return nil, errors.New("unknown leaderboard kind")
+	}
+
+	return rows, nil
+}
+
+// inDivisions reports whether division is one of divisions, any if empty
+func inDivisions(division int, divisions []int) bool {
+	if len(divisions) == 0 {
+		return true
+	}
+
+	for _, d := range divisions {
+		if d == division {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,138 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockQualifying serves the qualifying results of three classes, the
+// third of which nobody qualified in, and tracks how many classes were
+// being fetched at once
+type mockQualifying struct {
+	mu      sync.Mutex
+	active  int
+	peak    int
+	queries []string
+}
+
+func (q *mockQualifying) chunks(r *http.Request) []string {
+	q.mu.Lock()
+	q.active++
+	if q.active > q.peak {
+		q.peak = q.active
+	}
+	q.queries = append(q.queries, r.URL.RawQuery)
+	q.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	q.mu.Lock()
+	q.active--
+	q.mu.Unlock()
+
+	switch r.URL.Query().Get("car_class_id") {
+	case "1":
+		return []string{
+			`[{"rank":1,"cust_id":11,"display_name":"A","division":0,"best_qual_lap_time":901000},{"rank":2,"cust_id":12,"display_name":"B","division":2,"best_qual_lap_time":905000}]`,
+			`[{"rank":3,"cust_id":13,"display_name":"C","division":2,"best_qual_lap_time":-1}]`,
+		}
+	case "2":
+		return []string{`[{"rank":1,"cust_id":21,"display_name":"D","division":1,"best_qual_lap_time":903000}]`}
+	}
+
+	return nil
+}
+
+var testLeaderboardSeason = Season{SeasonID: 4711, CarClassIDs: []int64{1, 2, 3}}
+
+func TestSeasonLeaderboard(t *testing.T) {
+	m := newMockAPI(t)
+
+	q := &mockQualifying{}
+	m.handleChunked("/data/stats/season_qualify_results", q.chunks)
+
+	api := m.openAuthed(t)
+
+	rows, err := api.SeasonLeaderboard(context.Background(), testLeaderboardSeason, LeaderboardOptions{Concurrency: 2})
+	assert.NoError(t, err)
+
+	var custIDs []int64
+
+	for n, row := range rows {
+		assert.Equal(t, n+1, row.Position)
+		custIDs = append(custIDs, row.CustID)
+	}
+
+	assert.Equal(t, []int64{11, 21, 12, 13}, custIDs)
+	assert.Equal(t, int64(2), rows[1].CarClassID)
+	assert.Equal(t, 1, rows[1].ClassRank)
+	assert.Equal(t, "1:30.5000", rows[2].Best.String())
+
+	assert.Len(t, q.queries, 3)
+	assert.LessOrEqual(t, q.peak, 2)
+}
+
+func TestSeasonLeaderboardDivisions(t *testing.T) {
+	m := newMockAPI(t)
+
+	q := &mockQualifying{}
+	m.handleChunked("/data/stats/season_qualify_results", q.chunks)
+
+	api := m.openAuthed(t)
+
+	rows, err := api.SeasonLeaderboard(context.Background(), testLeaderboardSeason, LeaderboardOptions{Divisions: []int{1, 2}})
+	assert.NoError(t, err)
+
+	assert.Len(t, rows, 3)
+
+	for _, row := range rows {
+		assert.Contains(t, []int{1, 2}, row.Division)
+	}
+
+	division := 2
+
+	_, err = api.SeasonLeaderboard(context.Background(), testLeaderboardSeason, LeaderboardOptions{Division: &division})
+	assert.NoError(t, err)
+
+	for _, query := range q.queries[3:] {
+		assert.Contains(t, query, "division=2")
+	}
+}
+
+func TestSeasonLeaderboardFails(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/stats/season_tt_results", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	api := m.openAuthed(t)
+
+	_, err := api.SeasonLeaderboard(context.Background(), testLeaderboardSeason, LeaderboardOptions{Kind: TimeTrialLeaderboard})
+	assert.Error(t, err)
+}
+
+func TestGetSeasonTTStandings(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/stats/season_tt_standings", func(r *http.Request) []string {
+		assert.Equal(t, "4711", r.URL.Query().Get("season_id"))
+		assert.Equal(t, "0", r.URL.Query().Get("race_week_num"))
+
+		return []string{`[{"rank":1,"cust_id":11,"weeks_counted":3,"points":120}]`}
+	})
+
+	api := m.openAuthed(t)
+
+	week := 0
+
+	rows, err := api.GetSeasonTTStandings(context.Background(), LeaderboardParams{SeasonID: 4711, CarClassID: 1, RaceWeekNum: &week})
+	assert.NoError(t, err)
+	assert.Equal(t, []TimeTrialStanding{{Rank: 1, CustID: 11, WeeksCounted: 3, Points: 120}}, rows)
+
+	_, err = api.GetSeasonTTStandings(context.Background(), LeaderboardParams{SeasonID: 4711})
+	assert.Error(t, err)
+}
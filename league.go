@@ -0,0 +1,268 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const leagueApplyURI = "/data/league/apply"
+
+// LeagueApplication is a request to join a league
+type LeagueApplication struct {
+	LeagueID int64  `json:"league_id"`
+	Message  string `json:"message,omitempty"`
+}
+
+// LeagueApplicationStatus is the answer to a LeagueApplication
+type LeagueApplicationStatus struct {
+	LeagueID int64  `json:"league_id"`
+	Success  bool   `json:"success"`
+	Pending  bool   `json:"pending"`
+	Message  string `json:"message"`
+}
+
+// ApplyToLeague asks to join the league, adding message to the application
+func (i *Irdata) ApplyToLeague(ctx context.Context, leagueID int64, message string) (*LeagueApplicationStatus, error) {
+	var status LeagueApplicationStatus
+
+	if err := i.PostJSON(ctx, leagueApplyURI, LeagueApplication{LeagueID: leagueID, Message: message}, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// LeagueSeasonSession is a session of a league season as listed by
+// /data/league/season_sessions
+type LeagueSeasonSession struct {
+	LeagueID       int64       `json:"league_id"`
+	LeagueSeasonID int64       `json:"league_season_id"`
+	SessionID      int64       `json:"session_id"`
+	SubsessionID   int64       `json:"subsession_id"`
+	LaunchAt       time.Time   `json:"launch_at"`
+	HasResults     bool        `json:"has_results"`
+	Status         int         `json:"status"`
+	Track          SearchTrack `json:"track"`
+
+	Options SessionOptions `json:"-"`
+}
+
+// UnmarshalJSON decodes the session along with its options
+func (s *LeagueSeasonSession) UnmarshalJSON(data []byte) error {
+	type session LeagueSeasonSession
+
+	return decodeSession(data, (*session)(s), &s.Options)
+}
+
+// LeagueStandingsDriver is the driver a row of league standings is for
+type LeagueStandingsDriver struct {
+	CustID      int64  `json:"cust_id" pii:"cust_id"`
+	DisplayName string `json:"display_name" pii:"name"`
+}
+
+// LeagueStandingsRow is a driver's row of /data/league/season_standings
+type LeagueStandingsRow struct {
+	Rownum              int                   `json:"rownum"`
+	Position            int                   `json:"position"`
+	Driver              LeagueStandingsDriver `json:"driver"`
+	CarNumber           string                `json:"car_number"`
+	DriverNickname      string                `json:"driver_nickname" pii:"name"`
+	Wins                int                   `json:"wins"`
+	AverageStart        int                   `json:"average_start"`
+	AverageFinish       int                   `json:"average_finish"`
+	BasePoints          int                   `json:"base_points"`
+	NegativeAdjustments int                   `json:"negative_adjustments"`
+	PositiveAdjustments int                   `json:"positive_adjustments"`
+	TotalAdjustments    int                   `json:"total_adjustments"`
+	TotalPoints         int                   `json:"total_points"`
+}
+
+// GetLeagueSeasonSessions returns the sessions of the league season,
+// only those with results if resultsOnly
+func (i *Irdata) GetLeagueSeasonSessions(ctx context.Context, leagueID int64, seasonID int64, resultsOnly bool) ([]LeagueSeasonSession, error) {
+	var result struct {
+		Sessions []LeagueSeasonSession `json:"sessions"`
+	}
+
+	uri := fmt.Sprintf("/data/league/season_sessions?league_id=%d&season_id=%d&results_only=%t", leagueID, seasonID, resultsOnly)
+
+	if err := i.GetJSON(ctx, uri, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Sessions, nil
+}
+
+// GetLeagueSeasonStandings returns the driver standings of the league season
+// as scored by iRacing
+func (i *Irdata) GetLeagueSeasonStandings(ctx context.Context, leagueID int64, seasonID int64) ([]LeagueStandingsRow, error) {
+	var result struct {
+		Standings struct {
+			DriverStandings []LeagueStandingsRow `json:"driver_standings"`
+		} `json:"standings"`
+	}
+
+	uri := fmt.Sprintf("/data/league/season_standings?league_id=%d&season_id=%d", leagueID, seasonID)
+
+	if err := i.GetJSON(ctx, uri, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Standings.DriverStandings, nil
+}
+
+// LeagueDirectoryEntry is a league listed by /data/league/directory
+type LeagueDirectoryEntry struct {
+	LeagueID           int64  `json:"league_id"`
+	OwnerID            int64  `json:"owner_id" pii:"cust_id"`
+	LeagueName         string `json:"league_name"`
+	Created            string `json:"created"`
+	About              string `json:"about"`
+	URL                string `json:"url"`
+	RosterCount        int    `json:"roster_count"`
+	Recruiting         bool   `json:"recruiting"`
+	IsAdmin            bool   `json:"is_admin"`
+	IsMember           bool   `json:"is_member"`
+	PendingApplication bool   `json:"pending_application"`
+	PendingInvitation  bool   `json:"pending_invitation"`
+}
+
+// LeagueDirectoryParams are the parameters of LeagueDirectory.  Zero values
+// are left out of the query.
+type LeagueDirectoryParams struct {
+	Search               string
+	Tag                  string
+	RestrictToMember     bool
+	RestrictToRecruiting bool
+	RestrictToFriends    bool
+	RestrictToWatched    bool
+	MinimumRosterCount   int
+	MaximumRosterCount   int
+
+	// Sort is one of relevance, leaguename, displayname or rostercount
+	Sort string
+
+	// Order is asc or desc
+	Order string
+}
+
+func (p LeagueDirectoryParams) values() url.Values {
+	v := url.Values{}
+
+	for key, value := range map[string]string{
+		"search": p.Search,
+		"tag":    p.Tag,
+		"sort":   p.Sort,
+		"order":  p.Order,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+
+	for key, value := range map[string]bool{
+		"restrict_to_member":     p.RestrictToMember,
+		"restrict_to_recruiting": p.RestrictToRecruiting,
+		"restrict_to_friends":    p.RestrictToFriends,
+		"restrict_to_watched":    p.RestrictToWatched,
+	} {
+		if value {
+			v.Set(key, "true")
+		}
+	}
+
+	setInt(v, "minimum_roster_count", int64(p.MinimumRosterCount))
+	setInt(v, "maximum_roster_count", int64(p.MaximumRosterCount))
+
+	return v
+}
+
+// LeagueDirectoryResults are all the leagues found and how many iRacing said
+// there were, which should be the same
+type LeagueDirectoryResults struct {
+	Leagues []LeagueDirectoryEntry
+	Total   int
+}
+
+// LeagueDirectory searches the league directory, following the pages until
+// every league was fetched
+func (i *Irdata) LeagueDirectory(ctx context.Context, params LeagueDirectoryParams) (*LeagueDirectoryResults, error) {
+	results := &LeagueDirectoryResults{}
+
+	total, err := i.EachLeagueDirectory(ctx, params, func(league LeagueDirectoryEntry) error {
+		results.Leagues = append(results.Leagues, league)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results.Total = total
+
+	return results, nil
+}
+
+// EachLeagueDirectory is LeagueDirectory calling fn with each league as the
+// pages come in.  Returning ErrStopIteration from fn stops early without
+// fetching further pages.  The total count iRacing reported is returned.
+func (i *Irdata) EachLeagueDirectory(ctx context.Context, params LeagueDirectoryParams, fn func(LeagueDirectoryEntry) error) (int, error) {
+	return paginateAs(ctx, i, "/data/league/directory", params.values(), "results_page", fn)
+}
+
+// CustLeagueSession is a session of a league the member can join, as listed
+// by /data/league/cust_league_sessions
+type CustLeagueSession struct {
+	LeagueID          int64       `json:"league_id"`
+	LeagueSeasonID    int64       `json:"league_season_id"`
+	SessionID         int64       `json:"session_id"`
+	SubsessionID      int64       `json:"subsession_id"`
+	SessionName       string      `json:"session_name"`
+	LaunchAt          time.Time   `json:"launch_at"`
+	Host              HostedHost  `json:"host"`
+	Track             SearchTrack `json:"track"`
+	PasswordProtected bool        `json:"password_protected"`
+
+	Options SessionOptions `json:"-"`
+}
+
+// UnmarshalJSON decodes the session along with its options
+func (s *CustLeagueSession) UnmarshalJSON(data []byte) error {
+	type session CustLeagueSession
+
+	return decodeSession(data, (*session)(s), &s.Options)
+}
+
+// CustLeagueSessions returns the league sessions open to the member, only
+// of their own leagues if mine and only for the package if packageID isn't
+// 0, following the pages until every session was fetched.  The total count
+// iRacing reported is returned along with them.
+func (i *Irdata) CustLeagueSessions(ctx context.Context, mine bool, packageID int64) ([]CustLeagueSession, int, error) {
+	var sessions []CustLeagueSession
+
+	total, err := i.EachCustLeagueSession(ctx, mine, packageID, func(session CustLeagueSession) error {
+		sessions = append(sessions, session)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sessions, total, nil
+}
+
+// EachCustLeagueSession is CustLeagueSessions calling fn with each session
+// as the pages come in, see EachLeagueDirectory
+func (i *Irdata) EachCustLeagueSession(ctx context.Context, mine bool, packageID int64, fn func(CustLeagueSession) error) (int, error) {
+	v := url.Values{}
+
+	if mine {
+		v.Set("mine", strconv.FormatBool(mine))
+	}
+
+	setInt(v, "package_id", packageID)
+
+	return paginateAs(ctx, i, "/data/league/cust_league_sessions", v, "sessions", fn)
+}
@@ -0,0 +1,41 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyToLeague(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle(leagueApplyURI, func(w http.ResponseWriter, r *http.Request) {
+		var application LeagueApplication
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&application))
+		assert.Equal(t, LeagueApplication{LeagueID: 4403, Message: "let me in"}, application)
+
+		w.Write([]byte(`{"league_id":4403,"success":true,"pending":true}`))
+	})
+
+	api := m.openAuthed(t)
+
+	status, err := api.ApplyToLeague(context.Background(), 4403, "let me in")
+	assert.NoError(t, err)
+	assert.Equal(t, &LeagueApplicationStatus{LeagueID: 4403, Success: true, Pending: true}, status)
+}
+
+func TestGetLeagueSeasonStandings(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/league/season_standings", `{"standings":{"driver_standings":[{"rownum":1,"position":1,"driver":{"cust_id":1},"total_points":46}]}}`)
+
+	api := m.openAuthed(t)
+
+	standings, err := api.GetLeagueSeasonStandings(context.Background(), 4403, 77)
+	assert.NoError(t, err)
+	assert.Equal(t, []LeagueStandingsRow{{Rownum: 1, Position: 1, Driver: LeagueStandingsDriver{CustID: 1}, TotalPoints: 46}}, standings)
+}
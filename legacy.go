@@ -0,0 +1,96 @@
+package irdata
+
+import (
+	"bytes"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// legacyEndpoints maps the old membersite and memberstats pages, which
+// community docs still link to, to the /data endpoints answering the same
+// question.  Those without an equivalent map to "".
+var legacyEndpoints = map[string]string{
+	"/membersite/member/getsubsessresults":     "/data/results/get",
+	"/membersite/member/geteventresults":       "/data/results/event_log",
+	"/membersite/member/getlapchart":           "/data/results/lap_chart_data",
+	"/membersite/member/getlaps":               "/data/results/lap_data",
+	"/membersite/member/getseasons":            "/data/series/seasons",
+	"/membersite/member/getseriesstandings":    "/data/stats/season_driver_standings",
+	"/membersite/member/getdriverstatus":       "",
+	"/membersite/member/getfriends":            "",
+	"/memberstats/member/getcareerstats":       "/data/stats/member_career",
+	"/memberstats/member/getyearlystats":       "/data/stats/member_yearly",
+	"/memberstats/member/getlastracesstats":    "/data/stats/member_recent_races",
+	"/memberstats/member/getchartdata":         "/data/member/chart_data",
+	"/memberstats/member/getseriesraceresults": "/data/results/season_results",
+	"/memberstats/member/getresults":           "/data/results/search_series",
+}
+
+// legacyHosts are the hosts of the old member site
+var legacyHosts = map[string]bool{
+	"members.iracing.com": true,
+}
+
+// normalizeURI makes "data/..." the "/data/..." it means so both resolve,
+// and are cached, the same
+func normalizeURI(uri string) string {
+	if strings.HasPrefix(uri, "data/") {
+		return "/" + uri
+	}
+
+	return uri
+}
+
+// checkLegacyURI returns a *LegacyURLError for uri if it's a page of the old
+// member site rather than a /data endpoint
+func checkLegacyURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil
+	}
+
+	p := strings.ToLower(strings.TrimSuffix(u.Path, "/"))
+
+	endpoint, known := legacyEndpoints[p]
+
+	// the right path on the old host
+	if strings.HasPrefix(p, "/data/") {
+		endpoint = u.Path
+	}
+
+	if known || legacyHosts[strings.ToLower(u.Hostname())] ||
+		strings.HasPrefix(p, "/membersite/") || strings.HasPrefix(p, "/memberstats/") {
+		return &LegacyURLError{URI: uri, Endpoint: endpoint}
+	}
+
+	return nil
+}
+
+// notJSONPrefix is how much of a body that isn't JSON NotJSONError shows
+const notJSONPrefix = 64
+
+// checkJSONBody returns a *NotJSONError if the body of a response from url
+// is a web page (e.g. the login page or a 404) rather than JSON.  Other
+// bodies are left for the JSON decoding to judge.
+func checkJSONBody(url string, contentType string, data []byte) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, bomUTF8), " \t\r\n")
+
+	if mediaType != "text/html" && (len(trimmed) == 0 || trimmed[0] != '<') {
+		return nil
+	}
+
+	return notJSON(url, contentType, trimmed)
+}
+
+// notJSON returns the *NotJSONError for the body data of url
+func notJSON(url string, contentType string, data []byte) error {
+	prefix := data
+	if len(prefix) > notJSONPrefix {
+		prefix = prefix[:notJSONPrefix]
+	}
+
+	return &NotJSONError{URL: url, ContentType: contentType, Prefix: string(prefix)}
+}
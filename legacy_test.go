@@ -0,0 +1,110 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const loginPage = "<!DOCTYPE html>\n<html><head><title>iRacing Login</title></head><body>Sign in</body></html>"
+
+func TestHTMLResponseIsNotJSON(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(loginPage))
+	})
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	for n := 0; n < 2; n++ {
+		data, err := api.GetWithCache("/data/member/info", time.Hour)
+		assert.Nil(t, data)
+		assert.ErrorIs(t, err, ErrNotJSON)
+
+		var notJSON *NotJSONError
+
+		assert.True(t, errors.As(err, &notJSON))
+		assert.Equal(t, "text/html; charset=utf-8", notJSON.ContentType)
+		assert.Equal(t, loginPage[:notJSONPrefix], notJSON.Prefix)
+	}
+
+	// never cached
+	assert.Equal(t, 2, m.hitCount("/data/member/info"))
+
+	keys, err := api.cache.(CacheEnumerator).Keys(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestHTMLLinkedResponseIsNotJSON(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", "\n  <html><body>AccessDenied</body></html>")
+
+	api := m.openAuthed(t)
+
+	_, err := api.Get("/data/results/get?subsession_id=1")
+	assert.ErrorIs(t, err, ErrNotJSON)
+	assert.NotContains(t, err.Error(), "signature=abc")
+}
+
+func TestCheckJSONBody(t *testing.T) {
+	assert.NoError(t, checkJSONBody("u", "application/json", []byte(`{"a":1}`)))
+	assert.NoError(t, checkJSONBody("u", "binary/octet-stream", []byte(`[1,2]`)))
+	assert.NoError(t, checkJSONBody("u", "", nil))
+
+	assert.ErrorIs(t, checkJSONBody("u", "text/html", []byte(`{"a":1}`)), ErrNotJSON)
+	assert.ErrorIs(t, checkJSONBody("u", "", append(bomUTF8, "<html>"...)), ErrNotJSON)
+	assert.ErrorIs(t, checkJSONBody("u", "application/xml", []byte(`<?xml version="1.0"?><Error/>`)), ErrNotJSON)
+}
+
+func TestLegacyURL(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.openAuthed(t)
+
+	for uri, endpoint := range map[string]string{
+		"/membersite/member/GetSubsessResults?subsessionID=68911202":             "/data/results/get",
+		"https://members.iracing.com/memberstats/member/GetCareerStats?custid=1": "/data/stats/member_career",
+		"https://members.iracing.com/membersite/member/GetFriends":               "",
+		"/membersite/member/SomethingElse.do":                                    "",
+		"https://members.iracing.com/data/member/info":                           "/data/member/info",
+	} {
+		_, err := api.Get(uri)
+		assert.ErrorIs(t, err, ErrLegacyURL, uri)
+
+		var legacy *LegacyURLError
+
+		if assert.True(t, errors.As(err, &legacy), uri) {
+			assert.Equal(t, endpoint, legacy.Endpoint, uri)
+		}
+	}
+
+	err := checkLegacyURI("/membersite/member/GetLaps")
+	assert.EqualError(t, err, "legacy membersite url /membersite/member/GetLaps, use /data/results/lap_data instead")
+
+	assert.NoError(t, checkLegacyURI("/data/results/get?subsession_id=1"))
+	assert.NoError(t, checkLegacyURI("data/results/get"))
+}
+
+func TestDataPrefix(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	data, err := api.GetWithCache("data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.JSONEq(t, testMemberInfo, string(data))
+
+	// the same entry either way
+	_, err = api.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, m.hitCount("/data/member/info"))
+}
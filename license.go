@@ -0,0 +1,239 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// License categories
+const (
+	CategoryOval       = 1
+	CategoryRoad       = 2
+	CategoryDirtOval   = 3
+	CategoryDirtRoad   = 4
+	CategorySportsCar  = 5
+	CategoryFormulaCar = 6
+)
+
+// legacyCategories maps the categories road was split into in 2024 to the
+// one their earlier history is under
+var legacyCategories = map[int64]int64{
+	CategorySportsCar:  CategoryRoad,
+	CategoryFormulaCar: CategoryRoad,
+}
+
+// LicenseClass is a license class by its group id
+type LicenseClass int
+
+// License classes from rookie up
+const (
+	LicenseRookie LicenseClass = iota + 1
+	LicenseD
+	LicenseC
+	LicenseB
+	LicenseA
+	LicensePro
+	LicenseProWC
+)
+
+var licenseClassNames = map[LicenseClass]string{
+	LicenseRookie: "R",
+	LicenseD:      "D",
+	LicenseC:      "C",
+	LicenseB:      "B",
+	LicenseA:      "A",
+	LicensePro:    "P",
+	LicenseProWC:  "PWC",
+}
+
+func (c LicenseClass) String() string {
+	if name, ok := licenseClassNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("LicenseClass(%d)", int(c))
+}
+
+// licensePoint is a point of the license chart, iRacing encodes the class
+// in the thousands and the safety rating in hundredths below, e.g. 4399 is
+// B 3.99
+type licensePoint struct {
+	when         time.Time
+	class        LicenseClass
+	safetyRating float64
+	categoryID   int64
+}
+
+func parseLicensePoints(points []ChartPoint, categoryID int64) []licensePoint {
+	var parsed []licensePoint
+
+	for _, point := range points {
+		when, err := time.Parse(seasonDateFormat, point.When)
+		if err != nil {
+			continue
+		}
+
+		value := int(point.Value)
+
+		parsed = append(parsed, licensePoint{
+			when:         when,
+			class:        LicenseClass(value / 1000),
+			safetyRating: float64(value%1000) / 100,
+			categoryID:   categoryID,
+		})
+	}
+
+	sort.SliceStable(parsed, func(a, b int) bool { return parsed[a].when.Before(parsed[b].when) })
+
+	return parsed
+}
+
+// LicenseChange is a promotion or demotion.  The chart has a point a week
+// at best, and weeks apart in early history, so the change happened after
+// NotBefore (the last point in the old class) and by When (the first in the
+// new one).
+type LicenseChange struct {
+	NotBefore    time.Time
+	When         time.Time
+	From         LicenseClass
+	To           LicenseClass
+	SafetyRating float64
+
+	// CategoryID is where the change is recorded, CategoryRoad for the
+	// history of sports and formula car licenses before the split
+	CategoryID int64
+}
+
+// Promotion reports whether the change was up a class
+func (c LicenseChange) Promotion() bool {
+	return c.To > c.From
+}
+
+// LicenseHistory is the class history of a member's license in a category
+type LicenseHistory struct {
+	CustID     int64
+	CategoryID int64
+
+	// Initial is the class at Start, the earliest point of the chart
+	Start   time.Time
+	Initial LicenseClass
+	Changes []LicenseChange
+}
+
+// Reached returns when the license first got to class (or above), false if
+// it never did.  A license that started there reached it with its first
+// point.
+func (h *LicenseHistory) Reached(class LicenseClass) (time.Time, bool) {
+	if h.Start.IsZero() {
+		return time.Time{}, false
+	}
+
+	if h.Initial >= class {
+		return h.Start, true
+	}
+
+	for _, change := range h.Changes {
+		if change.From < class && change.To >= class {
+			return change.When, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Current returns the class of the latest point
+func (h *LicenseHistory) Current() LicenseClass {
+	if len(h.Changes) == 0 {
+		return h.Initial
+	}
+
+	return h.Changes[len(h.Changes)-1].To
+}
+
+// LicenseHistoryFromChart detects the class changes in license chart data
+// (ChartTypeLicenseSR).  legacy is the chart of the category the history
+// was under before it was split, nil if there is none, and only its points
+// before the first of chart are used.
+func LicenseHistoryFromChart(chart *ChartData, legacy *ChartData) *LicenseHistory {
+	history := &LicenseHistory{CustID: chart.CustID, CategoryID: chart.CategoryID}
+
+	points := parseLicensePoints(chart.Data, chart.CategoryID)
+
+	if legacy != nil {
+		var earlier []licensePoint
+
+		for _, point := range parseLicensePoints(legacy.Data, legacy.CategoryID) {
+			if len(points) == 0 || point.when.Before(points[0].when) {
+				earlier = append(earlier, point)
+			}
+		}
+
+		points = append(earlier, points...)
+	}
+
+	for n, point := range points {
+		if n == 0 {
+			history.Start, history.Initial = point.when, point.class
+			continue
+		}
+
+		previous := points[n-1]
+
+		if point.class == previous.class {
+			continue
+		}
+
+		history.Changes = append(history.Changes, LicenseChange{
+			NotBefore:    previous.when,
+			When:         point.when,
+			From:         previous.class,
+			To:           point.class,
+			SafetyRating: point.safetyRating,
+			CategoryID:   point.categoryID,
+		})
+	}
+
+	return history
+}
+
+// GetChartData returns the chart (one of the ChartType* constants) of the
+// license category of the member
+func (i *Irdata) GetChartData(ctx context.Context, custID int64, categoryID int64, chartType int) (*ChartData, error) {
+	var chart ChartData
+
+	uri := fmt.Sprintf("/data/member/chart_data?cust_id=%d&category_id=%d&chart_type=%d", custID, categoryID, chartType)
+
+	if err := i.GetJSON(ctx, uri, &chart); err != nil {
+		return nil, err
+	}
+
+	return &chart, nil
+}
+
+// GetLicenseHistory returns the promotions and demotions of the member's
+// license in the category.  The sports and formula car histories include
+// the road license they were split from.
+func (i *Irdata) GetLicenseHistory(ctx context.Context, custID int64, categoryID int64) (*LicenseHistory, error) {
+	chart, err := i.GetChartData(ctx, custID, categoryID, ChartTypeLicenseSR)
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy *ChartData
+
+	if legacyID, ok := legacyCategories[categoryID]; ok {
+		legacy, err = i.GetChartData(ctx, custID, legacyID, ChartTypeLicenseSR)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	history := LicenseHistoryFromChart(chart, legacy)
+
+	// the chart may not echo them back
+	history.CustID, history.CategoryID = custID, categoryID
+
+	return history, nil
+}
@@ -0,0 +1,97 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func handleLicenseCharts(m *mockAPI) {
+	m.handle("/data/member/chart_data", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("category_id") {
+		case "2":
+			http.ServeFile(w, r, "testdata/license_chart_road.json")
+		case "5":
+			http.ServeFile(w, r, "testdata/license_chart_sports_car.json")
+		default:
+			w.Write([]byte(`{"success":true,"data":[{"when":"2024-01-02","value":1250}]}`))
+		}
+	})
+}
+
+func TestGetLicenseHistory(t *testing.T) {
+	m := newMockAPI(t)
+	handleLicenseCharts(m)
+
+	api := m.openAuthed(t)
+
+	history, err := api.GetLicenseHistory(context.Background(), 4242, CategorySportsCar)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(CategorySportsCar), history.CategoryID)
+	assert.Equal(t, date(2009, 1, 6), history.Start)
+	assert.Equal(t, LicenseRookie, history.Initial)
+
+	assert.Equal(t, []LicenseChange{
+		{NotBefore: date(2009, 3, 17), When: date(2009, 6, 2), From: LicenseRookie, To: LicenseD, SafetyRating: 3.04, CategoryID: CategoryRoad},
+		{NotBefore: date(2009, 11, 24), When: date(2010, 2, 9), From: LicenseD, To: LicenseC, SafetyRating: 3, CategoryID: CategoryRoad},
+		{NotBefore: date(2010, 2, 9), When: date(2010, 2, 16), From: LicenseC, To: LicenseD, SafetyRating: 2.88, CategoryID: CategoryRoad},
+		{NotBefore: date(2010, 2, 16), When: date(2010, 5, 4), From: LicenseD, To: LicenseC, SafetyRating: 3.01, CategoryID: CategoryRoad},
+		{NotBefore: date(2024, 1, 9), When: date(2024, 1, 16), From: LicenseC, To: LicenseB, SafetyRating: 3, CategoryID: CategorySportsCar},
+	}, history.Changes)
+
+	assert.False(t, history.Changes[2].Promotion())
+	assert.True(t, history.Changes[4].Promotion())
+	assert.Equal(t, LicenseB, history.Current())
+
+	reached, ok := history.Reached(LicenseB)
+	assert.True(t, ok)
+	assert.Equal(t, date(2024, 1, 16), reached)
+
+	// the first time counts, not getting back there after the demotion
+	reached, ok = history.Reached(LicenseC)
+	assert.True(t, ok)
+	assert.Equal(t, date(2010, 2, 9), reached)
+
+	reached, ok = history.Reached(LicenseRookie)
+	assert.True(t, ok)
+	assert.Equal(t, date(2009, 1, 6), reached)
+
+	_, ok = history.Reached(LicenseA)
+	assert.False(t, ok)
+}
+
+func TestGetLicenseHistoryUnsplitCategory(t *testing.T) {
+	m := newMockAPI(t)
+	handleLicenseCharts(m)
+
+	api := m.openAuthed(t)
+
+	history, err := api.GetLicenseHistory(context.Background(), 4242, CategoryOval)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4242), history.CustID)
+	assert.Equal(t, LicenseRookie, history.Current())
+	assert.Empty(t, history.Changes)
+	assert.Equal(t, 1, m.hitCount("/data/member/chart_data"))
+}
+
+func TestLicenseHistoryFromEmptyChart(t *testing.T) {
+	history := LicenseHistoryFromChart(&ChartData{Data: []ChartPoint{{When: "garbage", Value: 4000}}}, nil)
+
+	_, ok := history.Reached(LicenseRookie)
+	assert.False(t, ok)
+	assert.Empty(t, history.Changes)
+}
+
+func TestLicenseClassString(t *testing.T) {
+	assert.Equal(t, "B", LicenseB.String())
+	assert.Equal(t, "PWC", LicenseProWC.String())
+	assert.Equal(t, "LicenseClass(0)", LicenseClass(0).String())
+}
@@ -0,0 +1,247 @@
+package irdata
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultCloseGrace is how long Close lets requests in flight finish
+// before cancelling them
+const defaultCloseGrace = 10 * time.Second
+
+type lifecycleT struct {
+	mu       sync.Mutex
+	closed   bool
+	inFlight int
+	grace    *time.Duration
+
+	// stop is closed by Close to end the watchers, abort once the grace
+	// period is over to cancel the requests in flight and idle when the
+	// last of those ends after Close.  done is closed when Close returns.
+	stop  chan struct{}
+	abort chan struct{}
+	idle  chan struct{}
+	done  chan struct{}
+	err   error
+
+	watchers sync.WaitGroup
+}
+
+// init makes the channels, the caller holds mu
+func (l *lifecycleT) init() {
+	if l.stop == nil {
+		l.stop = make(chan struct{})
+		l.abort = make(chan struct{})
+		l.idle = make(chan struct{})
+		l.done = make(chan struct{})
+	}
+}
+
+// WithCloseGracePeriod sets how long Close lets requests in flight finish
+// before cancelling them, 10 seconds by default
+func WithCloseGracePeriod(d time.Duration) Option {
+	return func(i *Irdata) {
+		i.lifecycle.grace = &d
+	}
+}
+
+// Close ends the instance: the watchers stop, requests in flight get the
+// grace period set with WithCloseGracePeriod to finish before they are
+// cancelled, the cache is closed, which compacts it and releases its
+// directory, and idle connections are closed.  Requests made afterwards
+// fail with ErrClosed.
+//
+// Close can be called more than once and from several goroutines, they all
+// wait for the first and return what it did.
+func (i *Irdata) Close() error {
+	l := &i.lifecycle
+
+	l.mu.Lock()
+	l.init()
+
+	if l.closed {
+		l.mu.Unlock()
+		<-l.done
+
+		return l.err
+	}
+
+	l.closed = true
+	close(l.stop)
+
+	if l.inFlight == 0 {
+		close(l.idle)
+	}
+
+	grace := defaultCloseGrace
+	if l.grace != nil {
+		grace = *l.grace
+	}
+
+	l.mu.Unlock()
+
+	timer := i.clock.NewTimer(grace)
+
+	select {
+	case <-l.idle:
+		timer.Stop()
+	case <-timer.C():
+		i.logger.Info("Cancelling requests still in flight")
+
+		close(l.abort)
+		<-l.idle
+	}
+
+	l.watchers.Wait()
+
+	i.flushHooks(true)
+
+	if i.cache != nil {
+		l.err = i.cacheClose()
+	}
+
+	i.httpClient.CloseIdleConnections()
+	i.assetClient.CloseIdleConnections()
+
+	close(l.done)
+
+	return l.err
+}
+
+// beginRequest registers a request with the lifecycle, returning its
+// context, cancelled once the grace period of Close is over, and the func
+// to call when it's done.  It fails with ErrClosed after Close.
+func (i *Irdata) beginRequest(ctx context.Context) (context.Context, func(), error) {
+	ctx, untrack, cancel, err := i.trackRequest(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, func() {
+		cancel()
+		untrack()
+	}, nil
+}
+
+// requestKey marks the context of a request that was registered with the
+// lifecycle of the instance it holds, so the requests it makes in turn
+// aren't refused once Close is called
+type requestKey struct{}
+
+// trackRequest is beginRequest for a request whose context outlives it,
+// the caller calls untrack when it's done and cancel when the context is
+// no longer needed
+func (i *Irdata) trackRequest(ctx context.Context) (context.Context, func(), context.CancelFunc, error) {
+	if ctx.Value(requestKey{}) == i {
+		return ctx, func() {}, func() {}, nil
+	}
+
+	l := &i.lifecycle
+
+	l.mu.Lock()
+	l.init()
+
+	if l.closed {
+		l.mu.Unlock()
+		return nil, nil, nil, ErrClosed
+	}
+
+	l.inFlight++
+	abort := l.abort
+	l.mu.Unlock()
+
+	o := &requestOptions{}
+	if i.hasCallOptions(ctx) {
+		o = i.callOptions(ctx, nil)
+	}
+
+	if o.err != nil {
+		i.untrackRequest()
+		return nil, nil, nil, o.err
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, requestKey{}, i))
+
+	go func() {
+		select {
+		case <-abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if o.timeout > 0 {
+		timeoutCtx, cancelTimeout := context.WithTimeout(ctx, o.timeout)
+
+		return timeoutCtx, i.untrackRequest, func() {
+			cancelTimeout()
+			cancel()
+		}, nil
+	}
+
+	return ctx, i.untrackRequest, cancel, nil
+}
+
+func (i *Irdata) untrackRequest() {
+	l := &i.lifecycle
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	if l.inFlight == 0 && l.closed {
+		close(l.idle)
+	}
+}
+
+// beginWatch registers a watcher with the lifecycle, returning its context,
+// cancelled by Close, and the func to call when it stops.  It's false after
+// Close.
+func (i *Irdata) beginWatch(ctx context.Context) (context.Context, func(), bool) {
+	l := &i.lifecycle
+
+	l.mu.Lock()
+	l.init()
+
+	if l.closed {
+		l.mu.Unlock()
+		return nil, nil, false
+	}
+
+	l.watchers.Add(1)
+	stop := l.stop
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		cancel()
+		l.watchers.Done()
+	}, true
+}
+
+// endingBody calls end once the body is closed
+type endingBody struct {
+	io.ReadCloser
+	once sync.Once
+	end  func()
+}
+
+func (b *endingBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	b.once.Do(b.end)
+
+	return err
+}
@@ -0,0 +1,201 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestCloseStopsWatchers(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[]`))
+	m.handleLinked("/data/series/seasons", `[]`)
+
+	before := goleak.IgnoreCurrent()
+
+	api := m.openAuthed(t)
+	assert.NoError(t, api.EnableCache(t.TempDir()))
+
+	polled := make(chan struct{}, 1)
+
+	results := api.NewResultsWatcher(ResultsFilter{CustIDs: []int64{4242}}, time.Hour)
+	results.OnTick = func(time.Time) { polled <- struct{}{} }
+
+	resultsOut := results.Watch(context.Background())
+	seasonsOut := api.NewSeasonsWatcher([]int64{139}, time.Hour).Watch(context.Background())
+
+	<-polled
+
+	for m.hitCount("/data/series/seasons") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.NoError(t, api.Close())
+
+	for range resultsOut {
+	}
+
+	for range seasonsOut {
+	}
+
+	goleak.VerifyNone(t, before)
+
+	for range api.NewResultsWatcher(ResultsFilter{CustIDs: []int64{4242}}, time.Hour).Watch(context.Background()) {
+		assert.Fail(t, "watching after Close")
+	}
+}
+
+func TestCloseIdempotent(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"value":1}]`)
+
+	api := m.openAuthed(t)
+	assert.NoError(t, api.EnableCache(t.TempDir()))
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < 5; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, api.Close())
+		}()
+	}
+
+	wg.Wait()
+
+	assert.NoError(t, api.Close())
+
+	_, err := api.Get("/data/constants/categories")
+	assert.ErrorIs(t, err, ErrClosed)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.ErrorIs(t, err, ErrClosed)
+
+	var v interface{}
+	assert.ErrorIs(t, api.GetJSON(context.Background(), "/data/constants/categories", &v), ErrClosed)
+	assert.ErrorIs(t, api.PostJSON(context.Background(), "/data/constants/categories", nil, nil), ErrClosed)
+
+	_, err = api.Do(context.Background(), http.MethodGet, "/data/constants/categories", nil)
+	assert.ErrorIs(t, err, ErrClosed)
+
+	assert.Equal(t, 0, m.hitCount("/data/constants/categories"))
+}
+
+// a closed cache can be opened again at once, bitcask holds a lock on the
+// directory while it's open
+func TestCloseReleasesCache(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"value":1}]`)
+
+	dir := t.TempDir()
+
+	api := m.openAuthed(t)
+	assert.NoError(t, api.EnableCache(dir))
+
+	_, err := api.GetWithCache("/data/constants/categories", time.Hour)
+	assert.NoError(t, err)
+
+	assert.NoError(t, api.Close())
+
+	again := m.openAuthed(t)
+	assert.NoError(t, again.EnableCache(dir))
+
+	_, err = again.GetWithCache("/data/constants/categories", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, m.hitCount("/data/constants/categories"))
+
+	assert.NoError(t, again.Close())
+}
+
+func TestCloseGracePeriod(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		finish  bool
+		wantErr error
+	}{
+		{name: "finishes within grace", finish: true},
+		{name: "cancelled after grace", wantErr: context.Canceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newMockAPI(t)
+
+			entered := make(chan struct{})
+			release := make(chan struct{})
+
+			m.handle("/data/slow", func(w http.ResponseWriter, r *http.Request) {
+				close(entered)
+
+				select {
+				case <-release:
+					fmt.Fprint(w, `{"ok":true}`)
+				case <-r.Context().Done():
+				}
+			})
+
+			before := goleak.IgnoreCurrent()
+
+			clock := newFakeClock()
+			api := m.openAuthed(t, WithClock(clock), WithCloseGracePeriod(5*time.Second))
+
+			got := make(chan error, 1)
+
+			go func() {
+				_, err := api.Get("/data/slow")
+				got <- err
+			}()
+
+			<-entered
+
+			closed := make(chan error, 1)
+
+			go func() {
+				closed <- api.Close()
+			}()
+
+			// the grace timer is running
+			for clock.waiting() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			select {
+			case <-closed:
+				assert.Fail(t, "Close didn't wait for the request")
+			default:
+			}
+
+			// new requests are refused meanwhile
+			_, err := api.Get("/data/slow")
+			assert.ErrorIs(t, err, ErrClosed)
+
+			if tc.finish {
+				close(release)
+			} else {
+				clock.advance(5 * time.Second)
+			}
+
+			err = <-got
+
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+
+			assert.NoError(t, <-closed)
+
+			if !tc.finish {
+				close(release)
+			}
+
+			goleak.VerifyNone(t, before)
+		})
+	}
+}
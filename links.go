@@ -0,0 +1,175 @@
+package irdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects is how many redirects a link may go through
+const maxRedirects = 10
+
+// WithMiddleware wraps the transport requests are sent with, e.g. to add
+// tracing.  Whatever it adds, the Authorization and Cookie headers only
+// reach the API host: s3 links are signed and refuse requests carrying
+// other credentials.
+func WithMiddleware(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(i *Irdata) {
+		i.middleware = append(i.middleware, wrap)
+	}
+}
+
+// WithRedirectHosts allows links and chunks to redirect to hosts, which
+// (with their port, if any) are refused with a *RedirectError otherwise.
+// Redirects within the host of a link are always followed.
+func WithRedirectHosts(hosts ...string) Option {
+	return func(i *Irdata) {
+		if i.redirectHosts == nil {
+			i.redirectHosts = make(map[string]bool)
+		}
+
+		for _, host := range hosts {
+			i.redirectHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// wrapTransports puts the middleware and the credential guard around the
+// transports of the clients, the guard last so it sees what the middleware
+// added.  Without middleware the requests only carry credentials for the
+// API host already.
+func (i *Irdata) wrapTransports() {
+	if len(i.middleware) == 0 {
+		return
+	}
+
+	wrap := func(transport http.RoundTripper) http.RoundTripper {
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+
+		transport = &credentialGuard{next: transport, i: i}
+
+		for n := len(i.middleware) - 1; n >= 0; n-- {
+			transport = i.middleware[n](transport)
+		}
+
+		return transport
+	}
+
+	i.httpClient.Transport = wrap(i.httpClient.Transport)
+	i.assetClient.Transport = wrap(i.assetClient.Transport)
+}
+
+// credentialGuard drops the Authorization and Cookie headers of requests
+// to hosts other than the API host
+type credentialGuard struct {
+	next http.RoundTripper
+	i    *Irdata
+}
+
+func (g *credentialGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.EqualFold(req.URL.Host, g.i.baseURL.Host) || (req.Header.Get("Authorization") == "" && req.Header.Get("Cookie") == "") {
+		return g.next.RoundTrip(req)
+	}
+
+	// a RoundTripper mustn't change the request it was given
+	stripped := req.Clone(req.Context())
+	stripped.Header.Del("Authorization")
+	stripped.Header.Del("Cookie")
+
+	return g.next.RoundTrip(stripped)
+}
+
+// checkRedirect is the redirect policy of the client.  The API host's
+// redirects are handed back as they are, links and chunks follow those
+// staying on their host or going to an allowed one or, with WithRegion, into
+// the region's s3 domains.
+func (i *Irdata) checkRedirect(req *http.Request, via []*http.Request) error {
+	first := via[0].URL
+
+	if strings.EqualFold(first.Host, i.baseURL.Host) {
+		return http.ErrUseLastResponse
+	}
+
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	if strings.EqualFold(req.URL.Host, first.Host) || i.redirectHosts[strings.ToLower(req.URL.Host)] {
+		return nil
+	}
+
+	if !i.regionStrict || !i.isRegionLinkHost(req.URL.Hostname()) {
+		return &RedirectError{URL: first.String(), Location: req.URL.String()}
+	}
+
+	return nil
+}
+
+// s3ErrorT is the XML error s3 answers with
+type s3ErrorT struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// checkLinkResponse returns a *LinkError if s3 refused to serve url
+func (i *Irdata) checkLinkResponse(url string, resp *http.Response, data []byte) error {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusBadRequest {
+		return nil
+	}
+
+	if strings.EqualFold(resp.Request.URL.Host, i.baseURL.Host) {
+		return nil
+	}
+
+	var s3Error s3ErrorT
+
+	if err := xml.Unmarshal(data, &s3Error); err != nil || s3Error.Code == "" {
+		return nil
+	}
+
+	return &LinkError{URL: url, Status: resp.StatusCode, Code: s3Error.Code, Message: s3Error.Message}
+}
+
+// gzipMagic starts gzip streams
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gunzipLinked decompresses the object linked to by url if it's gzipped,
+// up to limit bytes if there's one.  Some stats downloads are stored
+// compressed without a Content-Encoding, so the transport hands them over
+// as they are.
+func gunzipLinked(url string, data []byte, limit int64) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: %w", redactString(url, logRedaction()), err)
+	}
+
+	defer r.Close()
+
+	unzipped, err := readLimited(url, r, limit)
+	if errors.Is(err, ErrResponseTooLarge) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: %w", redactString(url, logRedaction()), err)
+	}
+
+	return unzipped, nil
+}
+
+// CloseIdleConnections lets Close reach the transport
+func (g *credentialGuard) CloseIdleConnections() {
+	if closer, ok := g.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,229 @@
+package irdata
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// s3Error is the body s3 answers a refused link with
+func s3Error(code string, message string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>%s</Code><Message>%s</Message><RequestId>4442587FB7D0A2F9</RequestId></Error>`, code, message)
+}
+
+// pollutingMiddleware adds credentials to every request, like a careless
+// tracing or auth middleware would
+type pollutingMiddleware struct {
+	next http.RoundTripper
+}
+
+func (p pollutingMiddleware) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer middleware")
+	req.Header.Add("Cookie", "tracking=1")
+	req.Header.Set("X-Trace", "abc")
+
+	return p.next.RoundTrip(req)
+}
+
+func TestMiddlewareCredentialsStayOnAPIHost(t *testing.T) {
+	var mu sync.Mutex
+	var s3Headers []http.Header
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		s3Headers = append(s3Headers, r.Header.Clone())
+		mu.Unlock()
+
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, s3Error("InvalidArgument", "Only one auth mechanism allowed"))
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/chunks/0.json":
+			fmt.Fprint(w, `[{"id":1}]`)
+			return
+		case "/chunked":
+			fmt.Fprintf(w, `{"type":"chunked","data":{"success":true,"chunk_info":{"num_chunks":1,"base_download_url":"%s/chunks/","chunk_file_names":["0.json"]}}}`, "http://"+r.Host)
+			return
+		}
+
+		fmt.Fprintf(w, `{"linked":true}`)
+	}))
+	defer s3.Close()
+
+	m := newMockAPI(t)
+	m.acceptToken("middleware", true)
+
+	var apiAuthorization string
+
+	m.handle("/data/linked", func(w http.ResponseWriter, r *http.Request) {
+		apiAuthorization = r.Header.Get("Authorization")
+		fmt.Fprintf(w, `{"link":"%s/linked?X-Amz-Signature=abc"}`, s3.URL)
+	})
+
+	m.handle("/data/chunked", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/chunked"}`, s3.URL)
+	})
+
+	api := m.openAuthed(t, WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return pollutingMiddleware{next: next}
+	}))
+
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(api.ctx, "/data/linked", &out))
+	assert.True(t, out["linked"])
+	assert.Equal(t, "Bearer middleware", apiAuthorization)
+
+	data, err := api.Get("/data/chunked")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1}]`, string(data))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Len(t, s3Headers, 3)
+
+	for _, header := range s3Headers {
+		assert.Empty(t, header.Values("Authorization"))
+		assert.Empty(t, header.Values("Cookie"))
+
+		// the rest of what the middleware adds goes through
+		assert.Equal(t, "abc", header.Get("X-Trace"))
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int
+		body    string
+		want    error
+		code    string
+		expired bool
+	}{
+		{
+			name:    "expired",
+			status:  http.StatusForbidden,
+			body:    s3Error("AccessDenied", "Request has expired"),
+			want:    ErrLinkExpired,
+			code:    "AccessDenied",
+			expired: true,
+		},
+		{
+			name:   "signature",
+			status: http.StatusForbidden,
+			body:   s3Error("SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."),
+			want:   ErrLinkRejected,
+			code:   "SignatureDoesNotMatch",
+		},
+		{
+			name:   "two auth mechanisms",
+			status: http.StatusBadRequest,
+			body:   s3Error("InvalidArgument", "Only one auth mechanism allowed"),
+			want:   ErrLinkRejected,
+			code:   "InvalidArgument",
+		},
+		{
+			name:   "not xml",
+			status: http.StatusForbidden,
+			body:   "<html><body>Forbidden</body></html>",
+			want:   ErrNotJSON,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer s3.Close()
+
+			m := newMockAPI(t)
+			m.handle("/data/linked", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"link":"%s/linked?X-Amz-Signature=abc"}`, s3.URL)
+			})
+
+			api := m.openAuthed(t)
+
+			_, err := api.Get("/data/linked")
+			assert.ErrorIs(t, err, tc.want)
+
+			var linkErr *LinkError
+
+			if tc.code == "" {
+				assert.False(t, errors.As(err, &linkErr))
+				return
+			}
+
+			if assert.ErrorAs(t, err, &linkErr) {
+				assert.Equal(t, tc.status, linkErr.Status)
+				assert.Equal(t, tc.code, linkErr.Code)
+				assert.Equal(t, tc.expired, linkErr.Expired())
+				assert.NotContains(t, err.Error(), "X-Amz-Signature=abc")
+			}
+		})
+	}
+}
+
+func TestLinkRedirects(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"elsewhere":true}`)
+	}))
+	defer other.Close()
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/here", http.StatusTemporaryRedirect)
+		case "/away":
+			http.Redirect(w, r, other.URL+"/there", http.StatusTemporaryRedirect)
+		default:
+			fmt.Fprint(w, `{"here":true}`)
+		}
+	}))
+	defer s3.Close()
+
+	otherURL, err := url.Parse(other.URL)
+	assert.NoError(t, err)
+
+	m := newMockAPI(t)
+	m.handle("/data/moved", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/moved"}`, s3.URL)
+	})
+	m.handle("/data/away", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/away"}`, s3.URL)
+	})
+
+	api := m.openAuthed(t)
+
+	data, err := api.Get("/data/moved")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"here":true}`, string(data))
+
+	_, err = api.Get("/data/away")
+	assert.ErrorIs(t, err, ErrRedirectRefused)
+
+	var redirectErr *RedirectError
+
+	if assert.ErrorAs(t, err, &redirectErr) {
+		assert.Equal(t, other.URL+"/there", redirectErr.Location)
+	}
+
+	allowed := m.openAuthed(t, WithRedirectHosts(otherURL.Host))
+
+	data, err = allowed.Get("/data/away")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"elsewhere":true}`, string(data))
+}
@@ -0,0 +1,85 @@
+package irdata
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// newLogger returns the logger of an instance opened without WithLogger,
+// writing to stderr and only logging errors until told otherwise
+func newLogger() *log.Logger {
+	logger := log.New()
+
+	logger.Out = os.Stderr
+	logger.Formatter = &log.TextFormatter{
+		FullTimestamp: true,
+	}
+	logger.Level = log.ErrorLevel
+
+	addRedactionHook(logger)
+
+	return logger
+}
+
+// packageLogger logs for the functions that aren't bound to an instance,
+// like SaveProvidedCredsToFile, so the logrus standard logger is left alone.
+// Like an instance's it only logs errors.
+var packageLogger = newLogger()
+
+// addRedactionHook adds the redaction hook to logger unless it has it
+// already
+func addRedactionHook(logger *log.Logger) {
+	for _, hook := range logger.Hooks[log.PanicLevel] {
+		if _, ok := hook.(redactionHook); ok {
+			return
+		}
+	}
+
+	logger.AddHook(redactionHook{})
+}
+
+// WithLogger makes the instance log through logger rather than its own,
+// e.g. to log alongside the rest of an app.  The instance logs through a
+// copy of logger sharing its output, formatter and hooks, so what the app
+// logs itself isn't redacted and SetLogLevel only changes the instance's
+// level.
+func WithLogger(logger *log.Logger) Option {
+	return func(i *Irdata) {
+		i.logger = wrapLogger(logger)
+	}
+}
+
+// wrapLogger returns a logger writing like logger with the redaction hook
+// firing ahead of logger's own hooks
+func wrapLogger(logger *log.Logger) *log.Logger {
+	wrapped := &log.Logger{
+		Out:          logger.Out,
+		Hooks:        make(log.LevelHooks),
+		Formatter:    logger.Formatter,
+		ReportCaller: logger.ReportCaller,
+		Level:        logger.GetLevel(),
+		ExitFunc:     logger.ExitFunc,
+	}
+
+	wrapped.AddHook(redactionHook{})
+
+	for level, hooks := range logger.Hooks {
+		wrapped.Hooks[level] = append(wrapped.Hooks[level], hooks...)
+	}
+
+	return wrapped
+}
+
+// SetLogLevel sets how verbose the instance's logging is, other instances
+// and the logrus standard logger aren't affected.  At log.TraceLevel every
+// request is logged with its method, resolved URL, attempt, how long it
+// took and what the cache did.  Secrets are redacted at every level.
+func (i *Irdata) SetLogLevel(level log.Level) {
+	i.logger.SetLevel(level)
+}
+
+// LogLevel returns the level set with SetLogLevel
+func (i *Irdata) LogLevel() log.Level {
+	return i.logger.GetLevel()
+}
@@ -0,0 +1,115 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogLevelPerInstance(t *testing.T) {
+	level := log.GetLevel()
+
+	a := Open(context.Background())
+	b := Open(context.Background())
+
+	var outA, outB bytes.Buffer
+
+	a.logger.SetOutput(&outA)
+	b.logger.SetOutput(&outB)
+
+	a.SetLogLevel(log.TraceLevel)
+
+	assert.Equal(t, log.TraceLevel, a.LogLevel())
+	assert.Equal(t, log.ErrorLevel, b.LogLevel())
+	assert.Equal(t, level, log.GetLevel())
+
+	assert.NoError(t, a.EnableCache(t.TempDir()))
+	assert.NoError(t, b.EnableCache(t.TempDir()))
+	a.Close()
+	b.Close()
+
+	assert.Contains(t, outA.String(), "Enabling cache")
+	assert.Empty(t, outB.String())
+
+	b.EnableDebug()
+	assert.Equal(t, log.DebugLevel, b.LogLevel())
+	assert.Equal(t, log.TraceLevel, a.LogLevel())
+
+	b.DisableDebug()
+	assert.Equal(t, log.ErrorLevel, b.LogLevel())
+}
+
+func TestWithLogger(t *testing.T) {
+	var out bytes.Buffer
+
+	logger := log.New()
+	logger.SetOutput(&out)
+	logger.SetLevel(log.InfoLevel)
+
+	api := Open(context.Background(), WithLogger(logger))
+
+	// the app's logger is left as it was
+	assert.Empty(t, logger.Hooks)
+
+	api.logger.WithFields(log.Fields{"email": string(testEmail)}).Info("instance")
+	logger.WithFields(log.Fields{"email": string(testEmail)}).Info("app")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "msg=instance")
+	assert.NotContains(t, lines[0], string(testEmail))
+	assert.Contains(t, lines[1], "msg=app")
+	assert.Contains(t, lines[1], string(testEmail))
+
+	api.SetLogLevel(log.TraceLevel)
+	assert.Equal(t, log.InfoLevel, logger.GetLevel())
+}
+
+func TestPackageLoggerLeavesStandardLogger(t *testing.T) {
+	assert.Empty(t, log.StandardLogger().Hooks)
+	assert.Equal(t, log.ErrorLevel, packageLogger.GetLevel())
+}
+
+func TestTraceRequests(t *testing.T) {
+	m := newMockAPI(t)
+	m.addAccount(testEmail, testEmailPassword)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handleJSON("/data/results/get", `{"subsession_id":1}`)
+
+	out, logTo := captureLog(t, log.TraceLevel)
+
+	api := m.open(t, logTo)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	assert.NoError(t, api.AuthWithProvideCreds(fieldCreds{email: testEmail, password: testEmailPassword}))
+
+	_, err := api.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	for n := 0; n < 2; n++ {
+		_, err = api.GetWithCache("/data/results/get?subsession_id=1", time.Hour)
+		assert.NoError(t, err)
+	}
+
+	logged := out.String()
+
+	assert.Contains(t, logged, "Request done")
+	assert.Contains(t, logged, "method=GET")
+	assert.Contains(t, logged, "attempt=1")
+	assert.Contains(t, logged, "duration=")
+	assert.Contains(t, logged, "status=200")
+	assert.Contains(t, logged, "/data/results/get?subsession_id=1")
+	assert.Contains(t, logged, "cache=miss")
+	assert.Contains(t, logged, "cache=hit")
+
+	assert.NotContains(t, logged, string(testEmail))
+	assert.NotContains(t, logged, string(testEmailPassword))
+	assert.NotContains(t, logged, encodePassword(testEmail, testEmailPassword))
+	assert.NotContains(t, logged, "let-me-in")
+	assert.NotContains(t, logged, "signature=abc")
+}
@@ -0,0 +1,233 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// lookupTTL is how long lookup tables are cached, a past season's clubs
+// never change and countries hardly do
+const lookupTTL = 30 * 24 * time.Hour
+
+// Club is a club as it was in a season, iRacing reuses club ids so a club
+// is only identified by its id together with the season
+type Club struct {
+	ClubID        int64  `json:"club_id" pii:"club"`
+	ClubName      string `json:"club_name" pii:"club"`
+	SeasonYear    int    `json:"season_year"`
+	SeasonQuarter int    `json:"season_quarter"`
+	Region        string `json:"region"`
+}
+
+// Country is an entry of /data/lookup/countries
+type Country struct {
+	CountryCode string `json:"country_code"`
+	CountryName string `json:"country_name"`
+}
+
+// iRacing codes that aren't the ISO 3166-1 alpha-2 code of the country
+var countryISOAliases = map[string]string{
+	"UK": "GB",
+	"EL": "GR",
+}
+
+// ISOCode returns the ISO 3166-1 alpha-2 code of the country
+func (c Country) ISOCode() string {
+	code := strings.ToUpper(c.CountryCode)
+
+	if iso, ok := countryISOAliases[code]; ok {
+		return iso
+	}
+
+	return code
+}
+
+// Flag returns the flag emoji of the country or "" if the code isn't two
+// letters
+func (c Country) Flag() string {
+	code := c.ISOCode()
+
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return ""
+	}
+
+	// regional indicator symbols A to Z
+	return string([]rune{rune(code[0]-'A') + 0x1F1E6, rune(code[1]-'A') + 0x1F1E6})
+}
+
+type seasonKeyT struct {
+	year    int
+	quarter int
+}
+
+type lookupsT struct {
+	mu        sync.Mutex
+	clubs     map[seasonKeyT]map[int64]Club
+	latest    seasonKeyT
+	countries map[string]Country
+}
+
+// getLookup gets uri into v, through the cache when it is enabled
+func (i *Irdata) getLookup(ctx context.Context, uri string, v interface{}) error {
+	return i.getJSONWithTTL(ctx, uri, lookupTTL, v)
+}
+
+// GetClubs returns the clubs of the season and remembers them for
+// ClubName.  They are cached for a long time when the cache is enabled.
+func (i *Irdata) GetClubs(ctx context.Context, seasonYear int, seasonQuarter int) ([]Club, error) {
+	var clubs []Club
+
+	uri := fmt.Sprintf("/data/lookup/club_history?season_year=%d&season_quarter=%d", seasonYear, seasonQuarter)
+
+	if err := i.getLookup(ctx, uri, &clubs); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]Club)
+
+	for _, club := range clubs {
+		byID[club.ClubID] = club
+	}
+
+	key := seasonKeyT{year: seasonYear, quarter: seasonQuarter}
+
+	i.lookups.mu.Lock()
+	defer i.lookups.mu.Unlock()
+
+	if i.lookups.clubs == nil {
+		i.lookups.clubs = make(map[seasonKeyT]map[int64]Club)
+	}
+
+	i.lookups.clubs[key] = byID
+
+	if key.year > i.lookups.latest.year || (key.year == i.lookups.latest.year && key.quarter > i.lookups.latest.quarter) {
+		i.lookups.latest = key
+	}
+
+	return clubs, nil
+}
+
+// GetCountries returns the countries iRacing knows and remembers them for
+// CountryName.  They are cached for a long time when the cache is enabled.
+func (i *Irdata) GetCountries(ctx context.Context) ([]Country, error) {
+	var countries []Country
+
+	if err := i.getLookup(ctx, "/data/lookup/countries", &countries); err != nil {
+		return nil, err
+	}
+
+	byCode := make(map[string]Country)
+
+	for _, country := range countries {
+		byCode[strings.ToUpper(country.CountryCode)] = country
+	}
+
+	i.lookups.mu.Lock()
+	defer i.lookups.mu.Unlock()
+
+	i.lookups.countries = byCode
+
+	return countries, nil
+}
+
+// ClubName returns the name clubID had in the season if GetClubs fetched
+// that season and the id otherwise
+func (i *Irdata) ClubName(seasonYear int, seasonQuarter int, clubID int64) string {
+	i.lookups.mu.Lock()
+	defer i.lookups.mu.Unlock()
+
+	if club, ok := i.lookups.clubs[seasonKeyT{year: seasonYear, quarter: seasonQuarter}][clubID]; ok {
+		return club.ClubName
+	}
+
+	return strconv.FormatInt(clubID, 10)
+}
+
+// CountryName returns the name of the country if GetCountries knows it and
+// the code otherwise
+func (i *Irdata) CountryName(code string) string {
+	i.lookups.mu.Lock()
+	defer i.lookups.mu.Unlock()
+
+	if country, ok := i.lookups.countries[strings.ToUpper(code)]; ok {
+		return country.CountryName
+	}
+
+	return code
+}
+
+// seasonAt is the season, by calendar quarter, t is in
+func seasonAt(t time.Time) seasonKeyT {
+	return seasonKeyT{year: t.Year(), quarter: (int(t.Month())-1)/3 + 1}
+}
+
+// seasonKey is the year and quarter of the season if it's current, the
+// current season otherwise
+func (i *Irdata) seasonKey(ctx context.Context, seasonID int64) seasonKeyT {
+	seasons, err := i.GetSeasons(ctx)
+	if err != nil {
+		i.logger.WithFields(log.Fields{"err": err, "seasonID": seasonID}).Info("Unable to get the seasons")
+	}
+
+	for _, season := range seasons {
+		if season.SeasonID == seasonID {
+			return seasonKeyT{year: season.SeasonYear, quarter: season.SeasonQuarter}
+		}
+	}
+
+	return seasonAt(i.clock.Now())
+}
+
+// loadClubs fetches the clubs of the season unless GetClubs already did,
+// a failure only leaves the ids unresolved
+func (i *Irdata) loadClubs(ctx context.Context, season seasonKeyT) {
+	i.lookups.mu.Lock()
+	_, loaded := i.lookups.clubs[season]
+	i.lookups.mu.Unlock()
+
+	if loaded {
+		return
+	}
+
+	if _, err := i.GetClubs(ctx, season.year, season.quarter); err != nil {
+		i.logger.WithFields(log.Fields{"err": err, "season": season}).Info("Unable to get the clubs")
+	}
+}
+
+// loadCountries fetches the countries unless GetCountries already did, a
+// failure only leaves the codes unresolved
+func (i *Irdata) loadCountries(ctx context.Context) {
+	i.lookups.mu.Lock()
+	loaded := i.lookups.countries != nil
+	i.lookups.mu.Unlock()
+
+	if loaded {
+		return
+	}
+
+	if _, err := i.GetCountries(ctx); err != nil {
+		i.logger.WithFields(log.Fields{"err": err}).Info("Unable to get the countries")
+	}
+}
+
+// currentClubName is ClubName for the latest season GetClubs fetched or,
+// when it hasn't been called, the current season which is fetched
+func (i *Irdata) currentClubName(ctx context.Context, clubID int64) string {
+	i.lookups.mu.Lock()
+	season := i.lookups.latest
+	i.lookups.mu.Unlock()
+
+	if season == (seasonKeyT{}) {
+		season = seasonAt(i.clock.Now())
+
+		i.loadClubs(ctx, season)
+	}
+
+	return i.ClubName(season.year, season.quarter, clubID)
+}
@@ -0,0 +1,161 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func handleClubHistory(m *mockAPI) {
+	m.handle("/data/lookup/club_history", func(w http.ResponseWriter, r *http.Request) {
+		season := r.URL.Query().Get("season_year") + "Q" + r.URL.Query().Get("season_quarter")
+
+		// club 7 was reassigned between the seasons
+		switch season {
+		case "2023Q4":
+			fmt.Fprint(w, `[{"club_id":7,"club_name":"Benelux","season_year":2023,"season_quarter":4}]`)
+		case "2024Q1":
+			fmt.Fprint(w, `[{"club_id":7,"club_name":"Pacific","season_year":2024,"season_quarter":1},{"club_id":8,"club_name":"Benelux"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	})
+}
+
+func TestGetClubs(t *testing.T) {
+	m := newMockAPI(t)
+	handleClubHistory(m)
+
+	api := m.openAuthed(t)
+
+	// unknown until fetched
+	assert.Equal(t, "7", api.ClubName(2024, 1, 7))
+
+	clubs, err := api.GetClubs(context.Background(), 2024, 1)
+	assert.NoError(t, err)
+	assert.Len(t, clubs, 2)
+
+	_, err = api.GetClubs(context.Background(), 2023, 4)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Pacific", api.ClubName(2024, 1, 7))
+	assert.Equal(t, "Benelux", api.ClubName(2023, 4, 7))
+	assert.Equal(t, "Benelux", api.ClubName(2024, 1, 8))
+	assert.Equal(t, "8", api.ClubName(2023, 4, 8))
+
+	// the latest season fetched, not the last
+	assert.Equal(t, "Pacific", api.currentClubName(context.Background(), 7))
+}
+
+func TestGetClubsCached(t *testing.T) {
+	m := newMockAPI(t)
+	handleClubHistory(m)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	for n := 0; n < 2; n++ {
+		_, err := api.GetClubs(context.Background(), 2024, 1)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, m.hitCount("/data/lookup/club_history"))
+	assert.Equal(t, lookupTTL, cachedTTL(t, api, "/data/lookup/club_history?season_year=2024&season_quarter=1"))
+}
+
+func TestMeClubName(t *testing.T) {
+	m := newMockAPI(t)
+	handleClubHistory(m)
+	m.handleLinked("/data/member/info", `{"cust_id":4242,"club_id":7}`)
+
+	api := m.openAuthed(t)
+
+	_, err := api.GetClubs(context.Background(), 2024, 1)
+	assert.NoError(t, err)
+
+	member, err := api.Me(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Pacific", member.ClubName)
+}
+
+func TestMeClubNameFetchesClubs(t *testing.T) {
+	m := newMockAPI(t)
+	handleClubHistory(m)
+	m.handleLinked("/data/member/info", `{"cust_id":4242,"club_id":7}`)
+
+	// in 2024 Q1
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	member, err := api.Me(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Pacific", member.ClubName)
+	assert.Equal(t, 1, m.hitCount("/data/lookup/club_history"))
+}
+
+func TestMeClubNameUnknown(t *testing.T) {
+	m := newMockAPI(t)
+	handleClubHistory(m)
+	m.handleLinked("/data/member/info", `{"cust_id":4242,"club_id":9}`)
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	member, err := api.Me(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "9", member.ClubName)
+}
+
+func TestStandingsNames(t *testing.T) {
+	m := newMockAPI(t)
+	handleClubHistory(m)
+	m.handleLinked("/data/lookup/countries", `[{"country_code":"US","country_name":"United States"}]`)
+	m.handleLinked("/data/series/seasons", `[{"season_id":4711,"season_year":2023,"season_quarter":4}]`)
+	m.handleChunked("/data/stats/season_driver_standings", func(r *http.Request) []string {
+		return []string{`[{"rank":1,"cust_id":1,"club_id":7,"country_code":"US"},{"rank":2,"cust_id":2,"club_id":8,"country_code":"XX"},` +
+			`{"rank":3,"cust_id":3,"club_id":7,"club_name":"Sent","country_code":"us"}]`}
+	})
+
+	api := m.openAuthed(t)
+
+	for n := 0; n < 2; n++ {
+		rows, err := api.GetSeasonDriverStandings(context.Background(), LeaderboardParams{SeasonID: 4711, CarClassID: 74})
+		assert.NoError(t, err)
+
+		if assert.Len(t, rows, 3) {
+			// club 7 as it was in the season, not the latest
+			assert.Equal(t, "Benelux", rows[0].ClubName)
+			assert.Equal(t, "United States", rows[0].CountryName)
+			assert.Equal(t, "8", rows[1].ClubName)
+			assert.Equal(t, "XX", rows[1].CountryName)
+			assert.Equal(t, "Sent", rows[2].ClubName)
+			assert.Equal(t, "United States", rows[2].CountryName)
+		}
+	}
+
+	// fetched once
+	assert.Equal(t, 1, m.hitCount("/data/lookup/club_history"))
+	assert.Equal(t, 1, m.hitCount("/data/lookup/countries"))
+}
+
+func TestGetCountries(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/lookup/countries", `[{"country_code":"US","country_name":"United States"},{"country_code":"UK","country_name":"United Kingdom"}]`)
+
+	api := m.openAuthed(t)
+
+	assert.Equal(t, "US", api.CountryName("US"))
+
+	countries, err := api.GetCountries(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, countries, 2)
+
+	assert.Equal(t, "United States", api.CountryName("us"))
+	assert.Equal(t, "XX", api.CountryName("XX"))
+
+	assert.Equal(t, "GB", countries[1].ISOCode())
+	assert.Equal(t, "🇬🇧", countries[1].Flag())
+	assert.Equal(t, "🇺🇸", countries[0].Flag())
+	assert.Equal(t, "", Country{CountryCode: "1"}.Flag())
+}
@@ -0,0 +1,218 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+)
+
+// License is a member's license in a single category
+type License struct {
+	CategoryID    int64   `json:"category_id"`
+	Category      string  `json:"category"`
+	CategoryName  string  `json:"category_name"`
+	LicenseLevel  int     `json:"license_level"`
+	SafetyRating  float64 `json:"safety_rating"`
+	CPI           float64 `json:"cpi"`
+	IRating       int     `json:"irating"`
+	TTRating      int     `json:"tt_rating"`
+	MPRNumRaces   int     `json:"mpr_num_races"`
+	MPRNumTTs     int     `json:"mpr_num_tts"`
+	Color         string  `json:"color"`
+	GroupName     string  `json:"group_name"`
+	GroupID       int64   `json:"group_id"`
+	ProPromotable bool    `json:"pro_promotable"`
+}
+
+// ContentPackage is a purchased package and the content ids it unlocks
+type ContentPackage struct {
+	PackageID  int64   `json:"package_id"`
+	ContentIDs []int64 `json:"content_ids"`
+}
+
+// MemberInfo is the response of /data/member/info which describes the
+// authenticated member
+type MemberInfo struct {
+	CustID        int64                  `json:"cust_id" pii:"cust_id"`
+	DisplayName   string                 `json:"display_name" pii:"name"`
+	FirstName     string                 `json:"first_name" pii:"name"`
+	LastName      string                 `json:"last_name" pii:"name"`
+	MemberSince   string                 `json:"member_since"`
+	LastLogin     string                 `json:"last_login"`
+	ClubID        int64                  `json:"club_id" pii:"club"`
+	ClubName      string                 `json:"club_name" pii:"club"`
+	FlairID       int64                  `json:"flair_id"`
+	FlairName     string                 `json:"flair_name"`
+	Licenses      map[string]License     `json:"licenses"`
+	CarPackages   []ContentPackage       `json:"car_packages"`
+	TrackPackages []ContentPackage       `json:"track_packages"`
+	Account       map[string]interface{} `json:"account"`
+}
+
+// RecentRace is a single entry of /data/stats/member_recent_races
+type RecentRace struct {
+	SeasonID          int64  `json:"season_id"`
+	SeriesID          int64  `json:"series_id"`
+	SeriesName        string `json:"series_name"`
+	CarID             int64  `json:"car_id"`
+	CarClassID        int64  `json:"car_class_id"`
+	SessionStartTime  string `json:"session_start_time"`
+	StartPosition     int    `json:"start_position"`
+	FinishPosition    int    `json:"finish_position"`
+	Laps              int    `json:"laps"`
+	LapsLed           int    `json:"laps_led"`
+	Incidents         int    `json:"incidents"`
+	Points            int    `json:"points"`
+	StrengthOfField   int    `json:"strength_of_field"`
+	SubsessionID      int64  `json:"subsession_id"`
+	OldiRating        int    `json:"oldi_rating"`
+	NewiRating        int    `json:"newi_rating"`
+	OldSubLevel       int    `json:"old_sub_level"`
+	NewSubLevel       int    `json:"new_sub_level"`
+	OldLicenseLevel   int    `json:"old_license_level"`
+	NewLicenseLevel   int    `json:"new_license_level"`
+	WinnerGroupID     int64  `json:"winner_group_id"`
+	WinnerName        string `json:"winner_name" pii:"name"`
+	DropRace          bool   `json:"drop_race"`
+	LicenseCategoryID int64  `json:"license_category_id"`
+	Track             struct {
+		TrackID   int64  `json:"track_id"`
+		TrackName string `json:"track_name"`
+	} `json:"track"`
+}
+
+// ChartPoint is a single point of member chart data
+type ChartPoint struct {
+	When  string  `json:"when"`
+	Value float64 `json:"value"`
+}
+
+// ChartData is the response of /data/member/chart_data
+type ChartData struct {
+	Blackout   bool         `json:"blackout"`
+	CategoryID int64        `json:"category_id"`
+	ChartType  int          `json:"chart_type"`
+	CustID     int64        `json:"cust_id" pii:"cust_id"`
+	Success    bool         `json:"success"`
+	Data       []ChartPoint `json:"data"`
+}
+
+// ParticipationCredit is a single entry of /data/member/participation_credits
+type ParticipationCredit struct {
+	CustID               int64  `json:"cust_id" pii:"cust_id"`
+	SeasonID             int64  `json:"season_id"`
+	SeriesID             int64  `json:"series_id"`
+	SeriesName           string `json:"series_name"`
+	LicenseGroup         int    `json:"license_group"`
+	LicenseGroupName     string `json:"license_group_name"`
+	ParticipationCredits int    `json:"participation_credits"`
+	MinWeeks             int    `json:"min_weeks"`
+	Weeks                int    `json:"weeks"`
+	EarnedCredits        int    `json:"earned_credits"`
+	TotalCredits         int    `json:"total_credits"`
+}
+
+// Chart types accepted by MyChartData
+const (
+	ChartTypeIRating   = 1
+	ChartTypeTTRating  = 2
+	ChartTypeLicenseSR = 3
+)
+
+// Logout drops the current session.  An Auth method must be called again
+// before making further requests.
+func (i *Irdata) Logout() {
+	i.httpClient.Jar = i.newCookieJar()
+	i.isAuthed = false
+
+	i.forgetMe()
+	i.forgetAccount()
+	i.forgetCredentials()
+
+	i.logger.Info("Logged out")
+}
+
+// Me returns the member info of the authenticated account.  The info is
+// fetched once per session and remembered until Logout or a new login.
+func (i *Irdata) Me(ctx context.Context) (*MemberInfo, error) {
+	i.memberMu.Lock()
+	defer i.memberMu.Unlock()
+
+	if i.member != nil {
+		return i.member, nil
+	}
+
+	var member MemberInfo
+
+	if err := i.GetJSON(ctx, "/data/member/info", &member); err != nil {
+		return nil, err
+	}
+
+	if member.ClubName == "" && member.ClubID != 0 {
+		// iRacing leaves it out at times
+		member.ClubName = i.currentClubName(ctx, member.ClubID)
+	}
+
+	i.member = &member
+
+	return i.member, nil
+}
+
+// CustID returns the customer id of the authenticated account
+func (i *Irdata) CustID(ctx context.Context) (int64, error) {
+	member, err := i.Me(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return member.CustID, nil
+}
+
+// MyRecentRaces returns the recent races of the authenticated account
+func (i *Irdata) MyRecentRaces(ctx context.Context) ([]RecentRace, error) {
+	custID, err := i.CustID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		CustID int64        `json:"cust_id" pii:"cust_id"`
+		Races  []RecentRace `json:"races"`
+	}
+
+	if err := i.GetJSON(ctx, fmt.Sprintf("/data/stats/member_recent_races?cust_id=%d", custID), &result); err != nil {
+		return nil, err
+	}
+
+	return result.Races, nil
+}
+
+// MyChartData returns the chart (one of the ChartType* constants) for the
+// license category of the authenticated account
+func (i *Irdata) MyChartData(ctx context.Context, categoryID int64, chartType int) (*ChartData, error) {
+	custID, err := i.CustID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.GetChartData(ctx, custID, categoryID, chartType)
+}
+
+// MyParticipationCredits returns the participation credits of the authenticated account
+func (i *Irdata) MyParticipationCredits(ctx context.Context) ([]ParticipationCredit, error) {
+	// this endpoint is always scoped to the session's account so
+	// there is no cust_id to fill in
+	var credits []ParticipationCredit
+
+	if err := i.GetJSON(ctx, "/data/member/participation_credits", &credits); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+func (i *Irdata) forgetMe() {
+	i.memberMu.Lock()
+	defer i.memberMu.Unlock()
+
+	i.member = nil
+}
@@ -0,0 +1,254 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AwardGroup is the group an award is listed under on the member profile
+type AwardGroup struct {
+	GroupID   int64  `json:"group_id"`
+	GroupName string `json:"group_name"`
+	Order     int    `json:"order"`
+}
+
+// AwardCriteria are what earns an award, e.g. {"starts": 25, "series_id":
+// 231}.  iRacing sends them as an object or as a string holding one.
+type AwardCriteria map[string]json.RawMessage
+
+// UnmarshalJSON decodes the criteria whether or not they're quoted
+func (c *AwardCriteria) UnmarshalJSON(data []byte) error {
+	var quoted string
+
+	if json.Unmarshal(data, &quoted) == nil {
+		if quoted == "" {
+			*c = nil
+			return nil
+		}
+
+		data = []byte(quoted)
+	}
+
+	var criteria map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &criteria); err != nil {
+		return fmt.Errorf("decoding award criteria: %w", err)
+	}
+
+	*c = criteria
+
+	return nil
+}
+
+// Int returns the criterion name as a number, false if there's no such
+// numeric criterion
+func (c AwardCriteria) Int(name string) (int64, bool) {
+	value, ok := c[name]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// MemberAward is an award of /data/member/awards.  Achievements are the
+// awards earned once, the others count how often they were.
+type MemberAward struct {
+	MemberAwardID      int64  `json:"member_award_id"`
+	AwardID            int64  `json:"award_id"`
+	CustID             int64  `json:"cust_id" pii:"cust_id"`
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	AwardedDescription string `json:"awarded_description"`
+	Achievement        bool   `json:"achievement"`
+	AwardCount         int    `json:"award_count"`
+	AwardDate          string `json:"award_date"`
+	DisplayDate        string `json:"display_date"`
+	Progress           int    `json:"progress"`
+	Threshold          int    `json:"threshold"`
+	Viewed             bool   `json:"viewed"`
+	HasPDF             bool   `json:"has_pdf"`
+
+	// Group is nil for awards not listed under one
+	Group    *AwardGroup   `json:"group"`
+	Criteria AwardCriteria `json:"criteria"`
+}
+
+// AwardInstance is one time an award was earned, in the subsession that
+// earned it if there was one
+type AwardInstance struct {
+	MemberAwardID      int64  `json:"member_award_id"`
+	AwardID            int64  `json:"award_id"`
+	CustID             int64  `json:"cust_id" pii:"cust_id"`
+	AwardDate          string `json:"award_date"`
+	AwardedDescription string `json:"awarded_description"`
+	SubsessionID       int64  `json:"subsession_id"`
+}
+
+// RecapCar is the favorite car of a MemberRecap.  Car is the catalog entry
+// of the car, nil unless the car catalog was at hand.
+type RecapCar struct {
+	CarID    int64  `json:"car_id"`
+	CarName  string `json:"car_name"`
+	CarImage string `json:"car_image"`
+
+	Car *Car `json:"-"`
+}
+
+// RecapTrack is the favorite track of a MemberRecap.  Track is the catalog
+// entry of the track, nil unless the track catalog was at hand.
+type RecapTrack struct {
+	TrackID    int64  `json:"track_id"`
+	TrackName  string `json:"track_name"`
+	ConfigName string `json:"config_name"`
+	TrackLogo  string `json:"track_logo"`
+
+	Track *Track `json:"-"`
+}
+
+// RecapStats are the totals of a MemberRecap.  FavoriteCar and
+// FavoriteTrack are nil for members who didn't race.
+type RecapStats struct {
+	Starts            int         `json:"starts"`
+	Wins              int         `json:"wins"`
+	Top5              int         `json:"top5"`
+	AvgStartPosition  int         `json:"avg_start_position"`
+	AvgFinishPosition int         `json:"avg_finish_position"`
+	Laps              int         `json:"laps"`
+	LapsLed           int         `json:"laps_led"`
+	FavoriteCar       *RecapCar   `json:"favorite_car"`
+	FavoriteTrack     *RecapTrack `json:"favorite_track"`
+}
+
+// MemberRecap is the response of /data/stats/member_recap, the stats of a
+// year or one of its seasons
+type MemberRecap struct {
+	CustID int64      `json:"cust_id" pii:"cust_id"`
+	Year   int        `json:"year"`
+	Season int        `json:"season"`
+	Stats  RecapStats `json:"stats"`
+}
+
+// GetMemberAwards returns the awards of custID, ErrPrivateData if their
+// profile is private
+func (i *Irdata) GetMemberAwards(ctx context.Context, custID int64) ([]MemberAward, error) {
+	var result struct {
+		CustID int64         `json:"cust_id" pii:"cust_id"`
+		Awards []MemberAward `json:"awards"`
+	}
+
+	if err := i.getMemberData(ctx, fmt.Sprintf("/data/member/awards?cust_id=%d", custID), &result); err != nil {
+		return nil, err
+	}
+
+	return result.Awards, nil
+}
+
+// GetAwardInstances returns every time custID earned awardID,
+// ErrPrivateData if their profile is private
+func (i *Irdata) GetAwardInstances(ctx context.Context, custID int64, awardID int64) ([]AwardInstance, error) {
+	var result struct {
+		CustID         int64           `json:"cust_id" pii:"cust_id"`
+		AwardID        int64           `json:"award_id"`
+		AwardInstances []AwardInstance `json:"award_instances"`
+	}
+
+	uri := fmt.Sprintf("/data/member/award_instances?cust_id=%d&award_id=%d", custID, awardID)
+
+	if err := i.getMemberData(ctx, uri, &result); err != nil {
+		return nil, err
+	}
+
+	return result.AwardInstances, nil
+}
+
+// GetMemberRecap returns the recap of custID for season (1 to 4) of year,
+// or the whole year if season is 0, and ErrPrivateData if their profile is
+// private.  With the cache enabled the favorite car and track are looked up
+// in the catalogs.
+func (i *Irdata) GetMemberRecap(ctx context.Context, custID int64, year int, season int) (*MemberRecap, error) {
+	uri := fmt.Sprintf("/data/stats/member_recap?cust_id=%d&year=%d", custID, year)
+	if season > 0 {
+		uri += fmt.Sprintf("&season=%d", season)
+	}
+
+	var recap MemberRecap
+
+	if err := i.getMemberData(ctx, uri, &recap); err != nil {
+		return nil, err
+	}
+
+	if i.cache != nil {
+		i.resolveRecap(ctx, &recap.Stats)
+	}
+
+	return &recap, nil
+}
+
+// resolveRecap fills in the catalog entries of the favorites.  The recap
+// is complete without them so the catalogs failing is only logged.
+func (i *Irdata) resolveRecap(ctx context.Context, stats *RecapStats) {
+	if car := stats.FavoriteCar; car != nil {
+		cars, err := i.GetCars(ctx)
+		if err != nil {
+			i.logger.WithFields(log.Fields{"err": err}).Warn("Car catalog unavailable for recap")
+		} else {
+			for n := range cars.Items {
+				if cars.Items[n].CarID == car.CarID {
+					car.Car = &cars.Items[n]
+					break
+				}
+			}
+		}
+	}
+
+	if track := stats.FavoriteTrack; track != nil {
+		tracks, err := i.GetTracks(ctx)
+		if err != nil {
+			i.logger.WithFields(log.Fields{"err": err}).Warn("Track catalog unavailable for recap")
+		} else {
+			for n := range tracks.Items {
+				if tracks.Items[n].TrackID == track.TrackID {
+					track.Track = &tracks.Items[n]
+					break
+				}
+			}
+		}
+	}
+}
+
+// getMemberData gets the member data at uri into v, following the link.
+// iRacing answers 403 for members whose profile is private.
+func (i *Irdata) getMemberData(ctx context.Context, uri string, v interface{}) error {
+	resp, err := i.Do(ctx, http.MethodGet, uri, nil, WithFollowLink())
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := readResponse(uri, resp, i.responseLimit(ctx))
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return ErrPrivateData
+	default:
+		return fmt.Errorf("getting %s failed: %s", uri, resp.Status)
+	}
+
+	return i.decodeJSON(uri, data, v)
+}
@@ -0,0 +1,170 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMemberAwards(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/awards", string(readFixture(t, "member_awards.json")))
+
+	api := m.openAuthed(t)
+
+	awards, err := api.GetMemberAwards(context.Background(), 212345)
+	assert.NoError(t, err)
+	assert.Len(t, awards, 4)
+
+	graduate := awards[0]
+	assert.Equal(t, "Rookie Graduate", graduate.Name)
+	assert.True(t, graduate.Achievement)
+	assert.Equal(t, &AwardGroup{GroupID: 3, GroupName: "License", Order: 1}, graduate.Group)
+
+	level, ok := graduate.Criteria.Int("license_level")
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), level)
+
+	// criteria sent as an object rather than a string
+	winner := awards[1]
+	assert.Equal(t, 12, winner.AwardCount)
+
+	wins, ok := winner.Criteria.Int("wins")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), wins)
+
+	_, ok = winner.Criteria.Int("official")
+	assert.False(t, ok, "not a number")
+
+	participant := awards[2]
+	assert.Nil(t, participant.Group)
+
+	seriesID, ok := participant.Criteria.Int("series_id")
+	assert.True(t, ok)
+	assert.Equal(t, int64(139), seriesID)
+
+	unearned := awards[3]
+	assert.Nil(t, unearned.Group)
+	assert.Nil(t, unearned.Criteria)
+	assert.Equal(t, "", unearned.AwardDate)
+	assert.Equal(t, 3, unearned.Progress)
+}
+
+func TestGetAwardInstances(t *testing.T) {
+	m := newMockAPI(t)
+
+	var query string
+
+	m.mux.HandleFunc("/s3/award_instances", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(readFixture(t, "member_award_instances.json"))
+	})
+	m.handle("/data/member/award_instances", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		fmt.Fprintf(w, `{"link":"%s/s3/award_instances?signature=abc"}`, m.URL)
+	})
+
+	api := m.openAuthed(t)
+
+	instances, err := api.GetAwardInstances(context.Background(), 212345, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "cust_id=212345&award_id=7", query)
+
+	assert.Len(t, instances, 3)
+	assert.Equal(t, int64(67012255), instances[1].SubsessionID)
+	assert.Equal(t, int64(0), instances[2].SubsessionID)
+}
+
+func TestGetMemberRecap(t *testing.T) {
+	m := newMockAPI(t)
+
+	var query string
+
+	m.mux.HandleFunc("/s3/recap", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(readFixture(t, "member_recap_2024.json"))
+	})
+	m.handle("/data/stats/member_recap", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		fmt.Fprintf(w, `{"link":"%s/s3/recap?signature=abc"}`, m.URL)
+	})
+	m.handleLinked("/data/car/get", `[{"car_id":1,"car_name":"Skip Barber Formula 2000"},{"car_id":132,"car_name":"BMW M4 GT3","car_make":"BMW"}]`)
+	m.handleLinked("/data/track/get", `[{"track_id":163,"track_name":"Circuit de Spa-Francorchamps","config_name":"Grand Prix Pits","track_config_length":4.35}]`)
+
+	api := m.openAuthed(t)
+
+	recap, err := api.GetMemberRecap(context.Background(), 212345, 2024, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "cust_id=212345&year=2024", query)
+
+	assert.Equal(t, 2024, recap.Year)
+	assert.Equal(t, 0, recap.Season)
+	assert.Equal(t, 87, recap.Stats.Starts)
+	assert.Equal(t, int64(132), recap.Stats.FavoriteCar.CarID)
+	assert.Equal(t, int64(163), recap.Stats.FavoriteTrack.TrackID)
+
+	// no cache, no catalogs
+	assert.Nil(t, recap.Stats.FavoriteCar.Car)
+	assert.Nil(t, recap.Stats.FavoriteTrack.Track)
+	assert.Equal(t, 0, m.hitCount("/data/car/get"))
+
+	clock := newFakeClock()
+	cached := m.openAuthed(t, WithClock(clock))
+	cached.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	recap, err = cached.GetMemberRecap(context.Background(), 212345, 2024, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "cust_id=212345&year=2024&season=1", query)
+
+	if assert.NotNil(t, recap.Stats.FavoriteCar.Car) {
+		assert.Equal(t, "BMW", recap.Stats.FavoriteCar.Car.CarMake)
+	}
+
+	if assert.NotNil(t, recap.Stats.FavoriteTrack.Track) {
+		assert.Equal(t, 4.35, recap.Stats.FavoriteTrack.Track.TrackConfigLength)
+	}
+}
+
+func TestGetMemberRecapNoRaces(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/stats/member_recap", string(readFixture(t, "member_recap_no_races.json")))
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	recap, err := api.GetMemberRecap(context.Background(), 212346, 2024, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, recap.Season)
+	assert.Nil(t, recap.Stats.FavoriteCar)
+	assert.Nil(t, recap.Stats.FavoriteTrack)
+
+	// nothing to look up
+	assert.Equal(t, 0, m.hitCount("/data/car/get"))
+}
+
+func TestMemberDataPrivate(t *testing.T) {
+	m := newMockAPI(t)
+
+	private := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":"Forbidden","note":"This member's profile is private"}`)
+	}
+
+	m.handle("/data/member/awards", private)
+	m.handle("/data/member/award_instances", private)
+	m.handle("/data/stats/member_recap", private)
+
+	api := m.openAuthed(t)
+	ctx := context.Background()
+
+	_, err := api.GetMemberAwards(ctx, 1)
+	assert.ErrorIs(t, err, ErrPrivateData)
+
+	_, err = api.GetAwardInstances(ctx, 1, 7)
+	assert.ErrorIs(t, err, ErrPrivateData)
+
+	_, err = api.GetMemberRecap(ctx, 1, 2024, 0)
+	assert.ErrorIs(t, err, ErrPrivateData)
+}
@@ -0,0 +1,329 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// memberProfileTTL is how long each part of a MemberProfile is cached, it
+// changes with every race the member does
+const memberProfileTTL = 10 * time.Minute
+
+// defaultProfileConcurrency is how many requests GetMemberProfile makes at
+// once, fewer when little is left of the rate limit
+const defaultProfileConcurrency = 3
+
+// ProfileSection is a part of a MemberProfile, fetched by its own request,
+// or a request per chart
+type ProfileSection string
+
+// The sections of a MemberProfile
+const (
+	ProfileInfo        ProfileSection = "info"
+	ProfileCareer      ProfileSection = "career"
+	ProfileRecentRaces ProfileSection = "recent_races"
+	ProfileDivision    ProfileSection = "division"
+	ProfileCharts      ProfileSection = "charts"
+)
+
+// ProfileOptions are the options of GetMemberProfile
+type ProfileOptions struct {
+	// Sections are those to fetch, all of them if empty
+	Sections []ProfileSection
+
+	// ChartCategories are the license categories, e.g. CategoryRoad, to
+	// fetch the charts of, ChartTypes are the charts, ChartTypeIRating and
+	// ChartTypeLicenseSR if empty.  No charts are fetched without
+	// categories.
+	ChartCategories []int64
+	ChartTypes      []int
+
+	// DivisionSeasonID is the season to fetch the division in, the division
+	// isn't fetched without it.  DivisionEventType defaults to races (5).
+	// iRacing only tells the division of the authenticated member, whoever
+	// the profile is of.
+	DivisionSeasonID  int64
+	DivisionEventType int
+}
+
+func (o *ProfileOptions) wants(section ProfileSection) bool {
+	if len(o.Sections) == 0 {
+		return true
+	}
+
+	for _, s := range o.Sections {
+		if s == section {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CareerStats is a single category of /data/stats/member_career
+type CareerStats struct {
+	CategoryID        int64   `json:"category_id"`
+	Category          string  `json:"category"`
+	Starts            int     `json:"starts"`
+	Wins              int     `json:"wins"`
+	Top5              int     `json:"top5"`
+	Poles             int     `json:"poles"`
+	AvgStartPosition  int     `json:"avg_start_position"`
+	AvgFinishPosition int     `json:"avg_finish_position"`
+	Laps              int     `json:"laps"`
+	LapsLed           int     `json:"laps_led"`
+	AvgIncidents      float64 `json:"avg_incidents"`
+	AvgPoints         int     `json:"avg_points"`
+	WinPercentage     float64 `json:"win_percentage"`
+	Top5Percentage    float64 `json:"top5_percentage"`
+	LapsLedPercentage float64 `json:"laps_led_percentage"`
+	PolesPercentage   float64 `json:"poles_percentage"`
+}
+
+// MemberDivision is the response of /data/stats/member_division
+type MemberDivision struct {
+	SeasonID  int64 `json:"season_id"`
+	EventType int   `json:"event_type"`
+	Division  int   `json:"division"`
+	Projected bool  `json:"projected"`
+}
+
+// ChartKey identifies a chart of a MemberProfile
+type ChartKey struct {
+	CategoryID int64
+	ChartType  int
+}
+
+// MemberProfile is what a profile page shows of a member.  The sections
+// that weren't asked for or failed are left empty, the errors of those that
+// failed are in Errors, by section, and ChartErrors, by chart.  A private
+// profile e.g. fails with ErrPrivateData for the career and charts while
+// the info is public.
+type MemberProfile struct {
+	CustID      int64
+	Info        *Member
+	Career      []CareerStats
+	RecentRaces []RecentRace
+	Division    *MemberDivision
+	Charts      map[ChartKey]*ChartData
+
+	Errors      map[ProfileSection]error
+	ChartErrors map[ChartKey]error
+}
+
+// Err returns the error of section, nil if it didn't fail.  For
+// ProfileCharts it's the error of the first chart that failed.
+func (p *MemberProfile) Err(section ProfileSection) error {
+	if section != ProfileCharts || len(p.ChartErrors) == 0 {
+		return p.Errors[section]
+	}
+
+	keys := make([]ChartKey, 0, len(p.ChartErrors))
+	for key := range p.ChartErrors {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].CategoryID != keys[b].CategoryID {
+			return keys[a].CategoryID < keys[b].CategoryID
+		}
+
+		return keys[a].ChartType < keys[b].ChartType
+	})
+
+	return p.ChartErrors[keys[0]]
+}
+
+// profileFetchT is a request of GetMemberProfile, store keeps what v was
+// decoded into
+type profileFetchT struct {
+	section ProfileSection
+	chart   ChartKey
+	uri     string
+	v       interface{}
+	store   func()
+}
+
+// GetMemberProfile fetches the sections of the profile of custID opts asks
+// for, a few requests at once.  A section failing doesn't fail the others,
+// see MemberProfile; an error is only returned when nothing could be
+// fetched.  Each request is cached on its own for a few minutes when the
+// cache is enabled, other profiles asking for the same sections share
+// them.
+func (i *Irdata) GetMemberProfile(ctx context.Context, custID int64, opts ProfileOptions) (*MemberProfile, error) {
+	profile := &MemberProfile{
+		CustID:      custID,
+		Charts:      make(map[ChartKey]*ChartData),
+		Errors:      make(map[ProfileSection]error),
+		ChartErrors: make(map[ChartKey]error),
+	}
+
+	var fetches []profileFetchT
+
+	if opts.wants(ProfileInfo) {
+		var response membersResponseT
+
+		fetches = append(fetches, profileFetchT{
+			section: ProfileInfo,
+			uri:     fmt.Sprintf("/data/member/get?cust_ids=%d&include_licenses=true", custID),
+			v:       &response,
+			store: func() {
+				for n := range response.Members {
+					if response.Members[n].CustID == custID {
+						profile.Info = &response.Members[n]
+					}
+				}
+			},
+		})
+	}
+
+	if opts.wants(ProfileCareer) {
+		var response struct {
+			Stats []CareerStats `json:"stats"`
+		}
+
+		fetches = append(fetches, profileFetchT{
+			section: ProfileCareer,
+			uri:     fmt.Sprintf("/data/stats/member_career?cust_id=%d", custID),
+			v:       &response,
+			store:   func() { profile.Career = response.Stats },
+		})
+	}
+
+	if opts.wants(ProfileRecentRaces) {
+		var response struct {
+			Races []RecentRace `json:"races"`
+		}
+
+		fetches = append(fetches, profileFetchT{
+			section: ProfileRecentRaces,
+			uri:     fmt.Sprintf("/data/stats/member_recent_races?cust_id=%d", custID),
+			v:       &response,
+			store:   func() { profile.RecentRaces = response.Races },
+		})
+	}
+
+	if opts.wants(ProfileDivision) && opts.DivisionSeasonID != 0 {
+		eventType := opts.DivisionEventType
+		if eventType == 0 {
+			eventType = 5
+		}
+
+		var division MemberDivision
+
+		fetches = append(fetches, profileFetchT{
+			section: ProfileDivision,
+			uri:     fmt.Sprintf("/data/stats/member_division?season_id=%d&event_type=%d", opts.DivisionSeasonID, eventType),
+			v:       &division,
+			store:   func() { profile.Division = &division },
+		})
+	}
+
+	if opts.wants(ProfileCharts) {
+		chartTypes := opts.ChartTypes
+		if len(chartTypes) == 0 {
+			chartTypes = []int{ChartTypeIRating, ChartTypeLicenseSR}
+		}
+
+		for _, categoryID := range opts.ChartCategories {
+			for _, chartType := range chartTypes {
+				key := ChartKey{CategoryID: categoryID, ChartType: chartType}
+
+				chart := &ChartData{}
+
+				fetches = append(fetches, profileFetchT{
+					section: ProfileCharts,
+					chart:   key,
+					uri:     fmt.Sprintf("/data/member/chart_data?cust_id=%d&category_id=%d&chart_type=%d", custID, categoryID, chartType),
+					v:       chart,
+					store:   func() { profile.Charts[key] = chart },
+				})
+			}
+		}
+	}
+
+	sem := make(chan struct{}, i.tunedConcurrency(ctx, defaultProfileConcurrency))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	fetched := 0
+
+	for _, fetch := range fetches {
+		wg.Add(1)
+
+		go func(fetch profileFetchT) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := ctx.Err()
+			if err == nil {
+				err = i.getProfileData(ctx, fetch.uri, fetch.v)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				if fetch.section == ProfileCharts {
+					profile.ChartErrors[fetch.chart] = err
+				} else {
+					profile.Errors[fetch.section] = err
+				}
+
+				return
+			}
+
+			fetch.store()
+			fetched++
+		}(fetch)
+	}
+
+	wg.Wait()
+
+	if fetched == 0 && firstErr != nil {
+		return profile, firstErr
+	}
+
+	return profile, nil
+}
+
+// getProfileData decodes the member data at uri into v, from the cache when
+// it's enabled.  Only what was fetched is cached, not the failures.
+func (i *Irdata) getProfileData(ctx context.Context, uri string, v interface{}) error {
+	if i.cache == nil {
+		return i.getMemberData(ctx, uri, v)
+	}
+
+	key := "irdata.profile." + url.PathEscape(uri)
+
+	found, err := i.getCachedJSON(ctx, key, v)
+	if err != nil {
+		i.logger.WithFields(log.Fields{"uri": uri, "err": err}).Info("Unable to read cached profile data")
+	} else if found {
+		return nil
+	}
+
+	if err := i.getMemberData(ctx, uri, v); err != nil {
+		return err
+	}
+
+	if err := i.setCachedJSON(ctx, key, v, memberProfileTTL); err != nil {
+		i.logger.WithFields(log.Fields{"uri": uri, "err": err}).Info("Unable to cache profile data")
+	}
+
+	return nil
+}
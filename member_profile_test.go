@@ -0,0 +1,139 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockProfile serves the profile endpoints of a member whose charts are
+// private, counting how many requests are in flight at once
+type mockProfile struct {
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (p *mockProfile) slow(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&p.inFlight, 1)
+		defer atomic.AddInt32(&p.inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&p.maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&p.maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+
+		next(w, r)
+	}
+}
+
+func (p *mockProfile) register(t *testing.T, m *mockAPI) {
+	career := string(readFixture(t, "member_career.json"))
+
+	m.handle("/data/member/get", p.slow(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success":true,"cust_ids":[123456],"members":[{"cust_id":123456,"display_name":"Dale Jarrett","licenses":[{"category_id":5,"irating":2210}]}]}`)
+	}))
+	m.handle("/data/stats/member_career", p.slow(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, career)
+	}))
+	m.handle("/data/stats/member_recent_races", p.slow(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"cust_id":123456,"races":[{"subsession_id":70001,"finish_position":2},{"subsession_id":70002,"finish_position":5}]}`)
+	}))
+	m.handle("/data/member/chart_data", p.slow(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chart_type") == "3" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error":"Forbidden","note":"This member's profile is private"}`)
+			return
+		}
+
+		fmt.Fprintf(w, `{"success":true,"cust_id":123456,"category_id":%s,"chart_type":1,"data":[{"when":"2024-03-05","value":2210}]}`, r.URL.Query().Get("category_id"))
+	}))
+}
+
+func TestGetMemberProfile(t *testing.T) {
+	m := newMockAPI(t)
+
+	mock := &mockProfile{}
+	mock.register(t, m)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	opts := ProfileOptions{ChartCategories: []int64{CategoryOval, CategorySportsCar}}
+
+	profile, err := api.GetMemberProfile(context.Background(), 123456, opts)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Dale Jarrett", profile.Info.DisplayName)
+	assert.Equal(t, 2210, profile.Info.Licenses[0].IRating)
+	assert.Len(t, profile.Career, 2)
+	assert.Equal(t, 14, profile.Career[1].Wins)
+	assert.Len(t, profile.RecentRaces, 2)
+
+	// no season, no division
+	assert.Nil(t, profile.Division)
+	assert.Zero(t, m.hitCount("/data/stats/member_division"))
+
+	// the iRating charts are public, the license ones aren't
+	assert.Len(t, profile.Charts, 2)
+	assert.Equal(t, int64(CategorySportsCar), profile.Charts[ChartKey{CategorySportsCar, ChartTypeIRating}].CategoryID)
+	assert.Len(t, profile.ChartErrors, 2)
+	assert.ErrorIs(t, profile.ChartErrors[ChartKey{CategoryOval, ChartTypeLicenseSR}], ErrPrivateData)
+	assert.ErrorIs(t, profile.Err(ProfileCharts), ErrPrivateData)
+	assert.NoError(t, profile.Err(ProfileCareer))
+
+	// seven requests, a few at once
+	maxInFlight := atomic.LoadInt32(&mock.maxInFlight)
+	assert.Greater(t, maxInFlight, int32(1))
+	assert.LessOrEqual(t, maxInFlight, int32(defaultProfileConcurrency))
+
+	// each request was cached on its own, the failures weren't
+	_, err = api.GetMemberProfile(context.Background(), 123456, ProfileOptions{
+		Sections:        []ProfileSection{ProfileCareer, ProfileCharts},
+		ChartCategories: []int64{CategorySportsCar},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, m.hitCount("/data/member/get"))
+	assert.Equal(t, 1, m.hitCount("/data/stats/member_career"))
+	assert.Equal(t, 5, m.hitCount("/data/member/chart_data"))
+}
+
+func TestGetMemberProfileSections(t *testing.T) {
+	m := newMockAPI(t)
+
+	mock := &mockProfile{}
+	mock.register(t, m)
+	m.handleJSON("/data/stats/member_division", `{"season_id":4711,"event_type":5,"division":3,"projected":true}`)
+
+	api := m.openAuthed(t)
+
+	profile, err := api.GetMemberProfile(context.Background(), 123456, ProfileOptions{
+		Sections:         []ProfileSection{ProfileInfo, ProfileDivision},
+		DivisionSeasonID: 4711,
+	})
+	assert.NoError(t, err)
+
+	assert.NotNil(t, profile.Info)
+	assert.Equal(t, &MemberDivision{SeasonID: 4711, EventType: 5, Division: 3, Projected: true}, profile.Division)
+	assert.Nil(t, profile.Career)
+	assert.Zero(t, m.hitCount("/data/stats/member_career"))
+	assert.Zero(t, m.hitCount("/data/member/chart_data"))
+}
+
+func TestGetMemberProfileFails(t *testing.T) {
+	m := newMockAPI(t)
+
+	profile, err := m.open(t).GetMemberProfile(context.Background(), 123456, ProfileOptions{})
+	assert.ErrorIs(t, err, ErrNotAuthenticated)
+	assert.ErrorIs(t, profile.Err(ProfileInfo), ErrNotAuthenticated)
+}
@@ -0,0 +1,97 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMemberInfo = `{"cust_id":4242,"display_name":"Ayrton Senna","licenses":{"oval":{"category_id":1,"irating":1350}}}`
+
+func TestMe(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t)
+
+	member, err := api.Me(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4242), member.CustID)
+	assert.Equal(t, 1350, member.Licenses["oval"].IRating)
+
+	custID, err := api.CustID(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4242), custID)
+
+	// remembered for the session
+	assert.Equal(t, 1, m.hitCount("/data/member/info"))
+}
+
+func TestMeForgottenOnLogout(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t)
+
+	_, err := api.Me(context.Background())
+	assert.NoError(t, err)
+
+	api.Logout()
+
+	_, err = api.Me(context.Background())
+	assert.Error(t, err)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	_, err = api.Me(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, m.hitCount("/data/member/info"))
+}
+
+func TestMyRecentRaces(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handle("/data/stats/member_recent_races", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "4242", r.URL.Query().Get("cust_id"))
+		w.Write([]byte(`{"cust_id":4242,"races":[{"subsession_id":123456,"series_name":"Skip Barber","finish_position":1}]}`))
+	})
+
+	api := m.openAuthed(t)
+
+	races, err := api.MyRecentRaces(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, races, 1)
+	assert.Equal(t, int64(123456), races[0].SubsessionID)
+}
+
+func TestMyChartData(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handle("/data/member/chart_data", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "4242", r.URL.Query().Get("cust_id"))
+		assert.Equal(t, "2", r.URL.Query().Get("category_id"))
+		assert.Equal(t, "1", r.URL.Query().Get("chart_type"))
+		w.Write([]byte(`{"cust_id":4242,"category_id":2,"chart_type":1,"data":[{"when":"2024-01-02","value":1500}]}`))
+	})
+
+	api := m.openAuthed(t)
+
+	chart, err := api.MyChartData(context.Background(), 2, ChartTypeIRating)
+	assert.NoError(t, err)
+	assert.Equal(t, []ChartPoint{{When: "2024-01-02", Value: 1500}}, chart.Data)
+}
+
+func TestMyParticipationCredits(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/member/participation_credits", `[{"series_id":1,"weeks":4,"min_weeks":8}]`)
+
+	api := m.openAuthed(t)
+
+	credits, err := api.MyParticipationCredits(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, credits, 1)
+	assert.Equal(t, 8, credits[0].MinWeeks)
+}
@@ -0,0 +1,148 @@
+package irdata
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+// membersBatchSize is the most cust_ids GetMembersBulk asks /data/member/get
+// for at once, iRacing refuses longer lists
+const membersBatchSize = 50
+
+// defaultMembersConcurrency is how many batches GetMembersBulk fetches at
+// once, fewer when little is left of the rate limit
+const defaultMembersConcurrency = 4
+
+// Member is a member as returned by /data/member/get
+type Member struct {
+	CustID      int64     `json:"cust_id" pii:"cust_id"`
+	DisplayName string    `json:"display_name" pii:"name"`
+	MemberSince string    `json:"member_since"`
+	LastLogin   string    `json:"last_login"`
+	ClubID      int64     `json:"club_id" pii:"club"`
+	ClubName    string    `json:"club_name" pii:"club"`
+	FlairID     int64     `json:"flair_id"`
+	FlairName   string    `json:"flair_name"`
+	AI          bool      `json:"ai"`
+	Licenses    []License `json:"licenses"`
+}
+
+type membersResponseT struct {
+	Success bool     `json:"success"`
+	CustIDs []int64  `json:"cust_ids" pii:"cust_id"`
+	Members []Member `json:"members"`
+}
+
+// GetMembersBulk fetches the members custIDs, with their licenses, in as few
+// requests as /data/member/get allows, a few at once.  It returns them by
+// cust_id along with the ids iRacing returned nothing for (e.g. deleted
+// accounts), in the order asked for.  Repeated ids are fetched once.
+func (i *Irdata) GetMembersBulk(ctx context.Context, custIDs []int64) (map[int64]Member, []int64, error) {
+	ids := uniqueIDs(custIDs)
+
+	var batches [][]int64
+
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > membersBatchSize {
+			n = membersBatchSize
+		}
+
+		batches = append(batches, ids[:n])
+		ids = ids[n:]
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	members := make(map[int64]Member)
+
+	sem := make(chan struct{}, i.tunedConcurrency(ctx, defaultMembersConcurrency))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for _, batch := range batches {
+		wg.Add(1)
+
+		go func(batch []int64) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			v := url.Values{}
+			setInts(v, "cust_ids", batch)
+			v.Set("include_licenses", "true")
+
+			var response membersResponseT
+
+			// waits out the rate limit rather than running into it
+			err := i.ReserveRequests(ctx, 1)
+			if err == nil {
+				err = i.GetJSON(ctx, "/data/member/get?"+v.Encode(), &response)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				// the other batches are of no use anymore
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+
+				return
+			}
+
+			for _, member := range response.Members {
+				members[member.CustID] = member
+			}
+		}(batch)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var missing []int64
+
+	for _, batch := range batches {
+		for _, id := range batch {
+			if _, ok := members[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+	}
+
+	return members, missing, nil
+}
+
+// uniqueIDs returns ids without repeats, in order
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+
+	var unique []int64
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,121 @@
+package irdata
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// handleMembers serves /data/member/get knowing every id but multiples of 7
+// and records the batch sizes asked for
+func handleMembers(m *mockAPI) func() []int {
+	var mu sync.Mutex
+	var batches []int
+
+	m.handle("/data/member/get", func(w http.ResponseWriter, r *http.Request) {
+		ids := strings.Split(r.URL.Query().Get("cust_ids"), ",")
+
+		mu.Lock()
+		batches = append(batches, len(ids))
+		mu.Unlock()
+
+		response := membersResponseT{Success: true}
+
+		for _, s := range ids {
+			id, _ := strconv.ParseInt(s, 10, 64)
+
+			if id == 999 {
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<html>nope</html>"))
+				return
+			}
+
+			response.CustIDs = append(response.CustIDs, id)
+
+			if id%7 != 0 {
+				response.Members = append(response.Members, Member{
+					CustID:      id,
+					DisplayName: "Driver " + s,
+					Licenses:    []License{{CategoryID: 2, IRating: 1350}},
+				})
+			}
+		}
+
+		json.NewEncoder(w).Encode(response)
+	})
+
+	return func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]int(nil), batches...)
+	}
+}
+
+func memberIDs(from int64, to int64) []int64 {
+	var ids []int64
+
+	for id := from; id <= to; id++ {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func TestGetMembersBulk(t *testing.T) {
+	m := newMockAPI(t)
+	batches := handleMembers(m)
+
+	api := m.openAuthed(t)
+
+	members, missing, err := api.GetMembersBulk(api.ctx, memberIDs(1, membersBatchSize))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{membersBatchSize}, batches())
+	assert.Len(t, members, membersBatchSize-len(missing))
+	assert.Equal(t, []int64{7, 14, 21, 28, 35, 42, 49}, missing)
+
+	assert.Equal(t, "Driver 3", members[3].DisplayName)
+	assert.Equal(t, 1350, members[3].Licenses[0].IRating)
+}
+
+func TestGetMembersBulkBatches(t *testing.T) {
+	m := newMockAPI(t)
+	batches := handleMembers(m)
+
+	api := m.openAuthed(t)
+
+	// one over the batch size and repeats, which aren't fetched twice
+	ids := append(memberIDs(1, membersBatchSize+1), 3, 5, 51)
+
+	members, missing, err := api.GetMembersBulk(api.ctx, ids)
+	assert.NoError(t, err)
+
+	sizes := batches()
+	assert.ElementsMatch(t, []int{membersBatchSize, 1}, sizes)
+
+	assert.Len(t, members, 51-7)
+	assert.Equal(t, []int64{7, 14, 21, 28, 35, 42, 49}, missing)
+	assert.Equal(t, int64(51), members[51].CustID)
+
+	members, missing, err = api.GetMembersBulk(api.ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+	assert.Empty(t, missing)
+}
+
+func TestGetMembersBulkError(t *testing.T) {
+	m := newMockAPI(t)
+	handleMembers(m)
+
+	api := m.openAuthed(t)
+
+	members, missing, err := api.GetMembersBulk(api.ctx, append(memberIDs(1, 120), 999))
+	assert.ErrorIs(t, err, ErrNotJSON)
+	assert.Nil(t, members)
+	assert.Nil(t, missing)
+}
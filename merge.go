@@ -0,0 +1,144 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RowKey returns the key rows are deduplicated by when merging chunks,
+// rows with the same key are duplicates
+type RowKey func(row json.RawMessage) (string, error)
+
+// KeyByField keys rows by the value of their top level field, e.g.
+// KeyByField("subsession_id")
+func KeyByField(field string) RowKey {
+	return func(row json.RawMessage) (string, error) {
+		var fields map[string]json.RawMessage
+
+		if err := json.Unmarshal(row, &fields); err != nil {
+			return "", err
+		}
+
+		value, ok := fields[field]
+		if !ok {
+			return "", fmt.Errorf("row has no %s", field)
+		}
+
+		return string(value), nil
+	}
+}
+
+// MergeOptions adjust how GetMerged merges chunked results
+type MergeOptions struct {
+	// Key drops rows whose key was seen before, in this or an earlier
+	// chunk, keeping the first.  Nil keeps every row.
+	Key RowKey
+
+	// Cache reads and writes the result through the cache for TTL, see
+	// GetWithCache
+	Cache bool
+	TTL   time.Duration
+}
+
+// MergedResult is the merged result of GetMerged along with what the merge
+// did
+type MergedResult struct {
+	Data []byte
+
+	// Chunks is how many chunks were merged, 0 if the result wasn't chunked
+	Chunks int
+
+	// Rows is how many rows Data holds and Duplicates how many were
+	// dropped, both 0 if the result wasn't chunked
+	Rows       int
+	Duplicates int
+}
+
+// GetMerged is Get (or GetWithCache if opts.Cache) returning how the
+// chunks of the result were merged and, if opts.Key is set, dropping
+// duplicate rows along the way.  Some endpoints repeat rows at chunk
+// boundaries.  Rows are always in the order of the chunks iRacing lists.
+func (i *Irdata) GetMerged(ctx context.Context, uri string, opts MergeOptions) (*MergedResult, error) {
+	var p *payload
+	var err error
+
+	if opts.Cache {
+		p, err = i.getPayloadWithCache(ctx, uri, opts.TTL)
+	} else {
+		p, err = i.fetch(ctx, uri)
+	}
+
+	if p == nil {
+		return nil, err
+	}
+
+	result, mergeErr := mergeChunks(p, opts.Key)
+	if mergeErr != nil {
+		return nil, mergeErr
+	}
+
+	return result, err
+}
+
+// mergeChunks is payload.assemble keeping count and dropping rows with
+// keys seen before
+func mergeChunks(p *payload, key RowKey) (*MergedResult, error) {
+	if !p.isChunked() {
+		return &MergedResult{Data: p.data}, nil
+	}
+
+	result := &MergedResult{Chunks: len(p.chunks)}
+
+	seen := map[string]bool{}
+
+	var buf bytes.Buffer
+
+	buf.WriteByte('[')
+
+	for _, chunk := range p.chunks {
+		var rows []json.RawMessage
+
+		if err := json.Unmarshal(chunk.Data, &rows); err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			if key != nil {
+				k, err := key(row)
+				if err != nil {
+					return nil, fmt.Errorf("keying row of chunk %d: %w", chunk.Number, err)
+				}
+
+				if seen[k] {
+					result.Duplicates++
+					continue
+				}
+
+				seen[k] = true
+			}
+
+			if result.Rows > 0 {
+				buf.WriteByte(',')
+			}
+
+			buf.Write(row)
+
+			result.Rows++
+		}
+	}
+
+	if result.Rows == 0 {
+		result.Data = []byte("null")
+
+		return result, nil
+	}
+
+	buf.WriteByte(']')
+
+	result.Data = buf.Bytes()
+
+	return result, nil
+}
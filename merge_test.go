@@ -0,0 +1,116 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// boundaryDuplicateChunks are chunks repeating the last row of the first
+// chunk at the start of the second
+func boundaryDuplicateChunks(t *testing.T) func(*http.Request) []string {
+	var chunks []string
+
+	for n := 0; n < 3; n++ {
+		data, err := os.ReadFile(filepath.Join("testdata", "chunks_boundary_duplicate", fmt.Sprintf("%d.json", n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chunks = append(chunks, string(data))
+	}
+
+	return mockChunks(chunks...)
+}
+
+func subsessionIDs(t *testing.T, data []byte) []int64 {
+	var rows []struct {
+		SubsessionID int64 `json:"subsession_id"`
+	}
+
+	assert.NoError(t, json.Unmarshal(data, &rows))
+
+	ids := []int64{}
+
+	for _, row := range rows {
+		ids = append(ids, row.SubsessionID)
+	}
+
+	return ids
+}
+
+func TestGetMergedDedup(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", boundaryDuplicateChunks(t))
+
+	api := m.openAuthed(t)
+
+	result, err := api.GetMerged(context.Background(), "/data/results/search_series", MergeOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, &MergedResult{Data: result.Data, Chunks: 3, Rows: 7}, result)
+	assert.Equal(t, []int64{70001, 70002, 70003, 70003, 70004, 70005, 70006}, subsessionIDs(t, result.Data))
+
+	for n := 0; n < 3; n++ {
+		result, err = api.GetMerged(context.Background(), "/data/results/search_series", MergeOptions{Key: KeyByField("subsession_id")})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result.Chunks)
+		assert.Equal(t, 6, result.Rows)
+		assert.Equal(t, 1, result.Duplicates)
+		assert.Equal(t, []int64{70001, 70002, 70003, 70004, 70005, 70006}, subsessionIDs(t, result.Data))
+	}
+
+	// the undeduplicated merge is what Get returns
+	data, err := api.Get("/data/results/search_series")
+	assert.NoError(t, err)
+
+	result, err = api.GetMerged(context.Background(), "/data/results/search_series", MergeOptions{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(data), string(result.Data))
+}
+
+func TestGetMergedWithCache(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", boundaryDuplicateChunks(t))
+	m.handleJSON("/data/constants/categories", `[{"value":1}]`)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	opts := MergeOptions{Key: KeyByField("subsession_id"), Cache: true, TTL: time.Hour}
+
+	for n := 0; n < 2; n++ {
+		result, err := api.GetMerged(context.Background(), "/data/results/search_series", opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Duplicates)
+		assert.Equal(t, []int64{70001, 70002, 70003, 70004, 70005, 70006}, subsessionIDs(t, result.Data))
+	}
+
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+
+	result, err := api.GetMerged(context.Background(), "/data/constants/categories", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, &MergedResult{Data: []byte(`[{"value":1}]`)}, result)
+}
+
+func TestGetMergedKeyError(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[]`))
+	m.handleChunked("/data/results/empty", mockChunks(`[]`))
+
+	api := m.openAuthed(t)
+
+	_, err := api.GetMerged(context.Background(), "/data/results/search_series", MergeOptions{Key: KeyByField("subsession_id")})
+	assert.ErrorContains(t, err, "row has no subsession_id")
+
+	result, err := api.GetMerged(context.Background(), "/data/results/empty", MergeOptions{Key: KeyByField("id")})
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(result.Data))
+	assert.Equal(t, 1, result.Chunks)
+}
@@ -0,0 +1,161 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// CacheOutcome is how the cache served a call, see MetricEvent
+type CacheOutcome int
+
+const (
+	// CacheUncached is a call that doesn't go through the cache, e.g. Get
+	CacheUncached CacheOutcome = iota
+	CacheHit
+	CacheMiss
+
+	// CacheStale is an expired entry served while offline
+	CacheStale
+)
+
+func (o CacheOutcome) String() string {
+	switch o {
+	case CacheUncached:
+		return "uncached"
+	case CacheHit:
+		return "hit"
+	case CacheMiss:
+		return "miss"
+	case CacheStale:
+		return "stale"
+	}
+
+	return fmt.Sprintf("CacheOutcome(%d)", int(o))
+}
+
+// MetricEvent is a finished call for a uri, however many requests it took
+// (none for a cache hit), see WithMetricHook
+type MetricEvent struct {
+	// URI is redacted as it is in the logs
+	URI   string
+	Cache CacheOutcome
+
+	// Bytes is the size of the payload, of all its chunks if chunked
+	Bytes    int
+	Duration time.Duration
+	Err      error
+}
+
+// WithMetricHook calls hook after every call of Get, GetJSON, GetWithCache,
+// ViewWithCache, GetChunksWithCache and the getters built on them, e.g. to
+// count cache hits and payload sizes in a metrics system.  It's called like
+// the hooks of WithHookQueueSize.
+func WithMetricHook(hook func(MetricEvent)) Option {
+	return func(i *Irdata) {
+		i.metricHook = hook
+	}
+}
+
+// Stats is a snapshot of the counters of the instance
+type Stats struct {
+	// Requests is how many requests were sent, Retries how many of those
+	// were retries and RateLimitWaits how often a request waited for the
+	// rate limit to reset
+	Requests       int64
+	Retries        int64
+	RateLimitWaits int64
+
+	TransportRetries int64
+	DroppedHookCalls int64
+
+	RateLimit RateLimit
+	Cache     CacheStats
+	Hedge     HedgeStats
+	Throttle  ThrottleStats
+	Refresh   RefreshStats
+}
+
+// requestStatsT counts the requests sent atomically, see Stats
+type requestStatsT struct {
+	requests       int64
+	retries        int64
+	rateLimitWaits int64
+}
+
+// Stats returns a snapshot of the counters of the instance, for exporting
+// to a metrics system
+func (i *Irdata) Stats() Stats {
+	return Stats{
+		Requests:         atomic.LoadInt64(&i.requestStats.requests),
+		Retries:          atomic.LoadInt64(&i.requestStats.retries),
+		RateLimitWaits:   atomic.LoadInt64(&i.requestStats.rateLimitWaits),
+		TransportRetries: i.TransportRetryCount(),
+		DroppedHookCalls: i.DroppedHookCalls(),
+		RateLimit:        i.RateLimit(),
+		Cache:            i.CacheStats(),
+		Hedge:            i.HedgeStats(),
+		Throttle:         i.ThrottleStats(),
+		Refresh:          i.RefreshStats(),
+	}
+}
+
+// countRequest counts an attempt at a request
+func (i *Irdata) countRequest(attempt int) {
+	atomic.AddInt64(&i.requestStats.requests, 1)
+
+	if attempt > 1 {
+		atomic.AddInt64(&i.requestStats.retries, 1)
+	}
+}
+
+type cachedCallKey struct{}
+
+// withCachedCall marks ctx as that of a call through the cache, which
+// reports its own MetricEvent
+func withCachedCall(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cachedCallKey{}, true)
+}
+
+func isCachedCall(ctx context.Context) bool {
+	return ctx.Value(cachedCallKey{}) != nil
+}
+
+// notifyMetric calls the metric hook for a finished call
+func (i *Irdata) notifyMetric(uri string, outcome CacheOutcome, size int, err error, started time.Time) {
+	hook := i.metricHook
+	if hook == nil {
+		return
+	}
+
+	event := MetricEvent{
+		URI:      redactString(uri, logRedaction()),
+		Cache:    outcome,
+		Bytes:    size,
+		Duration: i.clock.Now().Sub(started),
+		Err:      err,
+	}
+
+	i.callHook(func() { hook(event) })
+}
+
+// size is the size of the data, of all the chunks if chunked, 0 without a
+// payload
+func (p *payload) size() int {
+	if p == nil {
+		return 0
+	}
+
+	if !p.isChunked() {
+		return len(p.data)
+	}
+
+	size := 0
+
+	for _, chunk := range p.chunks {
+		size += len(chunk.Data)
+	}
+
+	return size
+}
@@ -0,0 +1,97 @@
+package irdata
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricHook(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2}]`))
+
+	var events []MetricEvent
+
+	api := m.openAuthed(t, WithMetricHook(func(event MetricEvent) {
+		events = append(events, event)
+	}))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	_, err := api.Get("/data/constants/categories")
+	assert.NoError(t, err)
+
+	for n := 0; n < 2; n++ {
+		_, err = api.GetWithCache("/data/constants/categories", time.Hour)
+		assert.NoError(t, err)
+	}
+
+	for n := 0; n < 2; n++ {
+		assert.NoError(t, api.GetChunksWithCache("/data/results/search_series", time.Hour, func(Chunk) error { return nil }))
+	}
+
+	api.SetOffline(true)
+
+	_, err = api.Get("/data/constants/categories")
+	assert.ErrorIs(t, err, ErrOffline)
+
+	api.flushHooks(false)
+
+	var outcomes []CacheOutcome
+	for _, event := range events {
+		outcomes = append(outcomes, event.Cache)
+	}
+
+	// a call is reported once, however it was served
+	assert.Equal(t, []CacheOutcome{CacheUncached, CacheMiss, CacheHit, CacheMiss, CacheHit, CacheUncached}, outcomes)
+
+	size := len(`[{"label":"Oval","value":1}]`)
+
+	for _, event := range events[:3] {
+		assert.Equal(t, "/data/constants/categories", event.URI)
+		assert.Equal(t, size, event.Bytes)
+		assert.NoError(t, event.Err)
+	}
+
+	assert.Equal(t, len(`[{"id":1}][{"id":2}]`), events[4].Bytes)
+	assert.Error(t, events[5].Err)
+	assert.Zero(t, events[5].Bytes)
+}
+
+func TestStats(t *testing.T) {
+	var calls int32
+
+	m := newMockAPI(t)
+	m.handle("/data/flaky", func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("x-ratelimit-limit", "240")
+			w.Header().Set("x-ratelimit-remaining", "0")
+			w.Header().Set("x-ratelimit-reset", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Header().Set("x-ratelimit-limit", "240")
+			w.Header().Set("x-ratelimit-remaining", "239")
+			w.Write([]byte(`{}`))
+		}
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	before := api.Stats()
+
+	_, err := api.Get("/data/flaky")
+	assert.NoError(t, err)
+
+	stats := api.Stats()
+	assert.Equal(t, int64(3), stats.Requests-before.Requests)
+	assert.Equal(t, int64(2), stats.Retries-before.Retries)
+	assert.Equal(t, int64(1), stats.RateLimitWaits)
+	assert.Equal(t, 239, stats.RateLimit.Remaining)
+	assert.Equal(t, 0, stats.Throttle.InFlight)
+}
@@ -0,0 +1,281 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const mockAuthCookie = "authtoken_members"
+
+// mockAPI is a stand in for members-ng and its s3 links
+type mockAPI struct {
+	*httptest.Server
+
+	mux *http.ServeMux
+
+	mu     sync.Mutex
+	hits   map[string]int
+	logins int
+
+	// accounts maps the usernames the login endpoint accepts to their
+	// encoded passwords
+	accounts map[string]string
+
+	// loginFailure makes the login endpoint answer with this status and body
+	loginFailure *mockResponse
+
+	// tokens are the bearer tokens data endpoints accept
+	tokens map[string]bool
+}
+
+type mockResponse struct {
+	status int
+	body   string
+}
+
+func newMockAPI(t testing.TB) *mockAPI {
+	m := &mockAPI{
+		mux:    http.NewServeMux(),
+		hits:   make(map[string]int),
+		tokens: make(map[string]bool),
+		accounts: map[string]string{
+			string(testUsername): encodePassword(testUsername, testPassword),
+		},
+	}
+
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serve))
+
+	t.Cleanup(m.Close)
+
+	m.mux.HandleFunc(loginURI, func(w http.ResponseWriter, r *http.Request) {
+		var creds struct {
+			Email    string
+			Password string
+		}
+
+		json.NewDecoder(r.Body).Decode(&creds)
+
+		m.mu.Lock()
+		m.logins++
+		login := m.logins
+		failure := m.loginFailure
+		password, known := m.accounts[creds.Email]
+		m.mu.Unlock()
+
+		if failure != nil {
+			w.WriteHeader(failure.status)
+			fmt.Fprint(w, failure.body)
+
+			return
+		}
+
+		if !known || creds.Password != password {
+			fmt.Fprint(w, `{"authcode":0,"message":"Invalid email address or password. Please try again."}`)
+
+			return
+		}
+
+		// a new token every login, like the real API
+		http.SetCookie(w, &http.Cookie{Name: mockAuthCookie, Value: mockToken(login, creds.Email), Path: "/"})
+
+		fmt.Fprint(w, `{"authcode":"let-me-in"}`)
+	})
+
+	m.handleJSON(testURI, `[{"label":"Race","value":5}]`)
+
+	return m
+}
+
+func (m *mockAPI) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.hits[r.URL.Path]++
+	m.mu.Unlock()
+
+	m.mux.ServeHTTP(w, r)
+}
+
+// requireAuth rejects requests that carry neither the session cookie nor
+// an accepted bearer token
+func (m *mockAPI) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !m.authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Unauthorized"}`)
+
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// handle registers an authenticated data endpoint
+func (m *mockAPI) handle(path string, handler http.HandlerFunc) {
+	m.mux.HandleFunc(path, m.requireAuth(handler))
+}
+
+func (m *mockAPI) authorized(r *http.Request) bool {
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		return m.tokens[token]
+	}
+
+	_, err := r.Cookie(mockAuthCookie)
+
+	return err == nil
+}
+
+// acceptToken makes the data endpoints accept token as a bearer token, or
+// stop accepting it
+func (m *mockAPI) acceptToken(token string, accept bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[token] = accept
+}
+
+// handleJSON registers a data endpoint that answers with payload directly
+func (m *mockAPI) handleJSON(path string, payload string) {
+	m.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, payload)
+	})
+}
+
+// handleLinked registers a data endpoint that answers with an s3 link to payload
+func (m *mockAPI) handleLinked(path string, payload string) {
+	s3Path := "/s3" + path
+
+	m.mux.HandleFunc(s3Path, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, payload)
+	})
+
+	m.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s%s?signature=abc"}`, m.URL, s3Path)
+	})
+}
+
+// handleChunked registers a data endpoint that answers with an s3 link to
+// chunk_info describing the chunks returned by chunks (called per request).
+// Every request gets its own chunk urls so concurrent requests don't mix.
+func (m *mockAPI) handleChunked(path string, chunks func(r *http.Request) []string) {
+	chunkPath := "/chunks" + path + "/"
+
+	var mu sync.Mutex
+	var served [][]string
+
+	m.mux.HandleFunc(chunkPath, func(w http.ResponseWriter, r *http.Request) {
+		var set, n int
+
+		_, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, chunkPath), "%d/%d.json", &set, &n)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil || set >= len(served) || n >= len(served[set]) {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, served[set][n])
+	})
+
+	m.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		current := chunks(r)
+
+		mu.Lock()
+		set := len(served)
+		served = append(served, current)
+		mu.Unlock()
+
+		var names []string
+
+		for n := range current {
+			names = append(names, fmt.Sprintf(`"%d.json"`, n))
+		}
+
+		fmt.Fprintf(w,
+			`{"type":"chunked","data":{"success":true,"chunk_info":{"num_chunks":%d,"base_download_url":"%s%s%d/","chunk_file_names":[%s]}}}`,
+			len(current), m.URL, chunkPath, set, strings.Join(names, ","),
+		)
+	})
+}
+
+// addAccount makes the login endpoint accept another account
+func (m *mockAPI) addAccount(username []byte, password []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.accounts[string(username)] = encodePassword(username, password)
+}
+
+// mockUsername returns the account the session of r was logged in as
+func mockToken(login int, email string) string {
+	return fmt.Sprintf("let-me-in:%d:%s", login, email)
+}
+
+func mockUsername(r *http.Request) string {
+	cookie, err := r.Cookie(mockAuthCookie)
+	if err != nil {
+		return ""
+	}
+
+	_, email, _ := strings.Cut(strings.TrimPrefix(cookie.Value, "let-me-in:"), ":")
+
+	return email
+}
+
+func (m *mockAPI) failLogin(status int, body string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loginFailure = &mockResponse{status: status, body: body}
+}
+
+func (m *mockAPI) loginCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.logins
+}
+
+func (m *mockAPI) hitCount(path string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.hits[path]
+}
+
+// option points an instance at the mock
+func (m *mockAPI) option(t testing.TB) Option {
+	baseURL, err := url.Parse(m.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return WithBaseURL(baseURL)
+}
+
+// open returns an unauthenticated instance that talks to the mock
+func (m *mockAPI) open(t testing.TB, opts ...Option) *Irdata {
+	return Open(context.Background(), append([]Option{m.option(t)}, opts...)...)
+}
+
+// openAuthed returns an instance that has logged into the mock
+func (m *mockAPI) openAuthed(t testing.TB, opts ...Option) *Irdata {
+	api := m.open(t, opts...)
+
+	if err := api.AuthWithProvideCreds(testCreds{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return api
+}
@@ -0,0 +1,177 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TokenSource hands out the OAuth2 access tokens sent as bearer tokens by
+// AuthWithOAuth.  Token is called again when iRacing rejects the current
+// one and should then return a refreshed token.
+//
+// An oauth2.TokenSource is adapted with
+//
+//	irdata.TokenSourceFunc(func(ctx context.Context) (string, error) {
+//		token, err := ts.Token()
+//		if err != nil {
+//			return "", err
+//		}
+//		return token.AccessToken, nil
+//	})
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc is a func used as a TokenSource
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token calls f
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// bearerT is the state of a session authenticated with AuthWithOAuth
+type bearerT struct {
+	source TokenSource
+	token  string
+}
+
+// AuthWithOAuth authenticates with the OAuth2 access tokens of source
+// instead of the username and password login.  Requests to the API carry
+// the token as a bearer token rather than the session cookie and when it's
+// rejected source is asked for a new one.  If source hands back the same
+// token requests fail with ErrSessionExpired.
+//
+// The token is verified by fetching the member info of the account, whose
+// cust_id then names the cache namespace.  If that fails the session there
+// was before, if any, is kept.  Logout forgets source.
+func (i *Irdata) AuthWithOAuth(ctx context.Context, source TokenSource) error {
+	token, err := source.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	if token == "" {
+		return errors.New("token source returned an empty token")
+	}
+
+	i.session.mu.Lock()
+	prevBearer, prevExpired := i.session.bearer, i.session.expired
+	i.session.bearer = &bearerT{source: source, token: token}
+	i.session.expired = false
+	i.session.mu.Unlock()
+
+	i.memberMu.Lock()
+	prevMember := i.member
+	i.member = nil
+	i.memberMu.Unlock()
+
+	prevAuthed := i.isAuthed
+	i.isAuthed = true
+
+	me, err := i.Me(withoutHedging(ctx))
+	if err != nil {
+		// the session there was, if any, carries on
+		i.session.mu.Lock()
+		i.session.bearer, i.session.expired = prevBearer, prevExpired
+		i.session.mu.Unlock()
+
+		i.memberMu.Lock()
+		i.member = prevMember
+		i.memberMu.Unlock()
+
+		i.isAuthed = prevAuthed
+
+		if errors.Is(err, ErrSessionExpired) {
+			return ErrBadCredentials
+		}
+
+		return err
+	}
+
+	i.session.mu.Lock()
+	i.session.forget()
+	i.session.mu.Unlock()
+
+	if !i.cacheNamespace.fixed {
+		i.cacheNamespace.name = accountNamespace(fmt.Sprintf("cust_id:%d", me.CustID))
+	}
+
+	i.logger.WithFields(log.Fields{"custID": me.CustID}).Info("Authenticated with OAuth")
+
+	return nil
+}
+
+// bearerSession reports whether the session was authenticated with
+// AuthWithOAuth
+func (i *Irdata) bearerSession() bool {
+	i.session.mu.Lock()
+	defer i.session.mu.Unlock()
+
+	return i.session.bearer != nil
+}
+
+// clientFor returns the client to send a request with header, bearer
+// requests go without the cookies of an earlier password session
+func (i *Irdata) clientFor(header http.Header) *http.Client {
+	if !strings.HasPrefix(header.Get("Authorization"), "Bearer ") {
+		return &i.httpClient
+	}
+
+	client := i.httpClient
+	client.Jar = nil
+
+	return &client
+}
+
+// bearerHeader returns header with the bearer token added if the session
+// has one and rawURL is on the API host, the s3 links are signed already
+func (i *Irdata) bearerHeader(rawURL string, header http.Header) http.Header {
+	i.session.mu.Lock()
+	token := ""
+	if i.session.bearer != nil {
+		token = i.session.bearer.token
+	}
+	i.session.mu.Unlock()
+
+	if token == "" {
+		return header
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != i.baseURL.Host {
+		return header
+	}
+
+	withToken := header.Clone()
+	if withToken == nil {
+		withToken = http.Header{}
+	}
+
+	withToken.Set("Authorization", "Bearer "+token)
+
+	return withToken
+}
+
+// refreshBearer asks the token source for a new token after the current
+// one was rejected, the caller holds session.mu
+func (s *sessionT) refreshBearer(ctx context.Context) error {
+	token, err := s.bearer.source.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	if token == "" || token == s.bearer.token {
+		return ErrSessionExpired
+	}
+
+	s.bearer.token = token
+
+	return nil
+}
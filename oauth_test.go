@@ -0,0 +1,208 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTokenSource hands out tokens in order and then keeps returning the
+// last one
+type fakeTokenSource struct {
+	mu     sync.Mutex
+	tokens []string
+	calls  int
+}
+
+func (s *fakeTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+
+	if s.calls > len(s.tokens) {
+		return s.tokens[len(s.tokens)-1], nil
+	}
+
+	return s.tokens[s.calls-1], nil
+}
+
+func (s *fakeTokenSource) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+func newOAuthMock(t *testing.T) *mockAPI {
+	m := newMockAPI(t)
+	m.handleJSON("/data/member/info", testMemberInfo)
+	m.handleJSON("/data/thing", `{"ok":true}`)
+
+	return m
+}
+
+func TestAuthWithOAuth(t *testing.T) {
+	m := newOAuthMock(t)
+	m.acceptToken("t1", true)
+
+	var s3Authorization []string
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s3Authorization = r.Header.Values("Authorization")
+		fmt.Fprint(w, `{"linked":true}`)
+	}))
+	defer s3.Close()
+
+	m.handle("/data/linked", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/linked"}`, s3.URL)
+	})
+
+	api := m.open(t)
+
+	assert.NoError(t, api.AuthWithOAuth(context.Background(), &fakeTokenSource{tokens: []string{"t1"}}))
+	assert.Equal(t, 0, m.loginCount())
+	assert.NotEmpty(t, api.CacheNamespace())
+
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	assert.True(t, out["ok"])
+
+	// the signed s3 links mustn't get the token
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/linked", &out))
+	assert.True(t, out["linked"])
+	assert.Empty(t, s3Authorization)
+}
+
+func TestOAuthRefreshesRejectedToken(t *testing.T) {
+	m := newOAuthMock(t)
+	m.acceptToken("t1", true)
+
+	source := &fakeTokenSource{tokens: []string{"t1", "t2"}}
+
+	api := m.open(t)
+	assert.NoError(t, api.AuthWithOAuth(context.Background(), source))
+
+	m.acceptToken("t1", false)
+	m.acceptToken("t2", true)
+
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	assert.True(t, out["ok"])
+	assert.Equal(t, 2, source.callCount())
+}
+
+func TestOAuthSameTokenExpires(t *testing.T) {
+	m := newOAuthMock(t)
+	m.acceptToken("t1", true)
+
+	api := m.open(t)
+	assert.NoError(t, api.AuthWithOAuth(context.Background(), &fakeTokenSource{tokens: []string{"t1"}}))
+
+	m.acceptToken("t1", false)
+
+	var out map[string]bool
+
+	assert.ErrorIs(t, api.GetJSON(context.Background(), "/data/thing", &out), ErrSessionExpired)
+}
+
+func TestOAuthBadToken(t *testing.T) {
+	m := newOAuthMock(t)
+
+	api := m.open(t)
+
+	assert.ErrorIs(t, api.AuthWithOAuth(context.Background(), &fakeTokenSource{tokens: []string{"nope"}}), ErrBadCredentials)
+	assert.False(t, api.isAuthed)
+	assert.Nil(t, api.session.bearer)
+}
+
+func TestOAuthSkipsCookies(t *testing.T) {
+	m := newOAuthMock(t)
+	m.acceptToken("t1", true)
+
+	var cookies []*http.Cookie
+
+	m.handle("/data/cookies", func(w http.ResponseWriter, r *http.Request) {
+		cookies = r.Cookies()
+		fmt.Fprint(w, `{"ok":true}`)
+	})
+
+	api := m.openAuthed(t)
+
+	assert.NoError(t, api.AuthWithOAuth(context.Background(), &fakeTokenSource{tokens: []string{"t1"}}))
+
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/cookies", &out))
+	assert.True(t, out["ok"])
+	assert.Empty(t, cookies)
+
+	// the jar is left alone
+	assert.NotEmpty(t, api.httpClient.Jar.Cookies(api.baseURL))
+}
+
+func TestOAuthBadTokenKeepsSession(t *testing.T) {
+	m := newOAuthMock(t)
+
+	api := m.open(t)
+	api.SetCredentialRetention(RetainCredentials)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	member, err := api.Me(context.Background())
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, api.AuthWithOAuth(context.Background(), &fakeTokenSource{tokens: []string{"nope"}}), ErrBadCredentials)
+	assert.True(t, api.isAuthed)
+	assert.Nil(t, api.session.bearer)
+	assert.NotNil(t, api.session.creds)
+
+	again, err := api.Me(context.Background())
+	assert.NoError(t, err)
+	assert.Same(t, member, again)
+
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	assert.True(t, out["ok"])
+	assert.Equal(t, 1, m.loginCount())
+}
+
+func TestOAuthThenPassword(t *testing.T) {
+	m := newOAuthMock(t)
+	m.acceptToken("t1", true)
+
+	api := m.open(t)
+
+	assert.NoError(t, api.AuthWithOAuth(context.Background(), &fakeTokenSource{tokens: []string{"t1"}}))
+
+	// switching modes logs in even though the bearer session is fine
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+	assert.Equal(t, 1, m.loginCount())
+	assert.Nil(t, api.session.bearer)
+
+	m.acceptToken("t1", false)
+
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	assert.True(t, out["ok"])
+
+	api.Logout()
+
+	m.acceptToken("t2", true)
+
+	assert.NoError(t, api.AuthWithOAuth(context.Background(), &fakeTokenSource{tokens: []string{"t2"}}))
+
+	api.Logout()
+
+	assert.False(t, api.isAuthed)
+	assert.Nil(t, api.session.bearer)
+}
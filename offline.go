@@ -0,0 +1,60 @@
+package irdata
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// defaultStaleRetention is how long cached responses are kept past their
+// ttl for offline mode once it was used
+const defaultStaleRetention = 7 * 24 * time.Hour
+
+// WithStaleRetention sets how long responses cached by GetWithCache and the
+// catalogs are kept after they expire, to be served while offline.  Without
+// it they are dropped when they expire until SetOffline(true) is first
+// called, and kept for a week from then on.
+func WithStaleRetention(d time.Duration) Option {
+	return func(i *Irdata) {
+		i.staleRetention = &d
+	}
+}
+
+// staleKeep is how long past their ttl cached responses are kept, the
+// stale retention or the stale while revalidate window if that's longer
+func (i *Irdata) staleKeep() time.Duration {
+	var keep time.Duration
+
+	switch {
+	case i.staleRetention != nil:
+		keep = *i.staleRetention
+	case atomic.LoadInt32(&i.offlineUsed) == 1:
+		keep = defaultStaleRetention
+	}
+
+	if i.refresh.window > keep {
+		keep = i.refresh.window
+	}
+
+	return keep
+}
+
+// SetOffline switches the instance to (or back from) offline mode.  While
+// offline nothing is sent over the network: GetWithCache and the cached
+// getters serve what's cached even once it expired (marking catalogs
+// Stale), and everything else, including cache misses, fails right away
+// with ErrOffline.
+func (i *Irdata) SetOffline(offline bool) {
+	var v int32
+	if offline {
+		v = 1
+
+		atomic.StoreInt32(&i.offlineUsed, 1)
+	}
+
+	atomic.StoreInt32(&i.offline, v)
+}
+
+// Offline reports whether the instance is offline, see SetOffline
+func (i *Irdata) Offline() bool {
+	return atomic.LoadInt32(&i.offline) == 1
+}
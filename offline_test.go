@@ -0,0 +1,168 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineServesExpiredEntries(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+	m.handleJSON("/data/car/get", `[{"car_id":67,"car_name":"Global Mazda MX-5 Cup"}]`)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock), WithStaleRetention(72*time.Hour))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	_, err := api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+
+	_, err = api.GetCars(context.Background())
+	assert.NoError(t, err)
+
+	// expired entries aren't served online
+	clock.advance(2 * time.Minute)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, m.hitCount("/data/constants/categories"))
+
+	clock.advance(48 * time.Hour)
+
+	api.SetOffline(true)
+	assert.True(t, api.Offline())
+
+	data, err := api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"label":"Oval","value":1}]`, string(data))
+
+	cars, err := api.GetCars(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, cars.Stale)
+		assert.Equal(t, int64(67), cars.Items[0].CarID)
+	}
+
+	entries, err := api.CacheEntries()
+	assert.NoError(t, err)
+
+	for _, entry := range entries {
+		assert.True(t, entry.Stale, entry.URI)
+		assert.GreaterOrEqual(t, clock.Now().Sub(entry.Created), 48*time.Hour, entry.URI)
+	}
+
+	// misses and uncached requests fail without trying
+	_, err = api.GetWithCache("/data/track/get", time.Minute)
+	assert.ErrorIs(t, err, ErrOffline)
+
+	_, err = api.Get("/data/constants/categories")
+	assert.ErrorIs(t, err, ErrOffline)
+
+	var out map[string]interface{}
+	assert.ErrorIs(t, api.PostJSON(context.Background(), "/data/league/apply", map[string]int{"league_id": 1}, &out), ErrOffline)
+
+	assert.Equal(t, 2, m.hitCount("/data/constants/categories"))
+	assert.Equal(t, 1, m.hitCount("/data/car/get"))
+	assert.Equal(t, 0, m.hitCount("/data/track/get"))
+
+	// back online the expired entries are fetched again
+	api.SetOffline(false)
+
+	cars, err = api.GetCars(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, cars.Stale)
+	}
+
+	assert.Equal(t, 2, m.hitCount("/data/car/get"))
+}
+
+func TestOfflineWithoutStaleRetention(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock), WithStaleRetention(0))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	_, err := api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+
+	api.SetOffline(true)
+
+	// unexpired entries are served as usual
+	_, err = api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+
+	clock.advance(2 * time.Minute)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.ErrorIs(t, err, ErrOffline)
+
+	assert.Equal(t, 1, m.hitCount("/data/constants/categories"))
+}
+
+func TestStaleRetentionOnceOffline(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	// nothing is kept past its ttl before offline mode is used
+	_, err := api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+
+	clock.advance(2 * time.Minute)
+
+	api.SetOffline(true)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.ErrorIs(t, err, ErrOffline)
+
+	// from then on entries are kept for a week
+	api.SetOffline(false)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+
+	clock.advance(6 * 24 * time.Hour)
+
+	api.SetOffline(true)
+
+	_, err = api.GetWithCache("/data/constants/categories", time.Minute)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, m.hitCount("/data/constants/categories"))
+}
+
+func TestAuthOffline(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.open(t)
+	api.SetOffline(true)
+
+	assert.NotPanics(t, func() {
+		assert.ErrorIs(t, api.AuthWithProvideCreds(testCreds{}), ErrOffline)
+	})
+	assert.Equal(t, 0, m.loginCount())
+}
+
+func TestOfflineHealthCheck(t *testing.T) {
+	m := newMockAPI(t)
+	api := m.openAuthed(t, WithHealthProbe(time.Minute))
+
+	api.SetOffline(true)
+
+	status := api.HealthCheck(context.Background())
+	assert.False(t, status.Healthy)
+
+	if assert.NotNil(t, status.Probe) {
+		assert.Equal(t, ErrOffline.Error(), status.Probe.Error)
+	}
+}
@@ -0,0 +1,175 @@
+package irdata
+
+import (
+	"context"
+	"sort"
+)
+
+// OwnedContent is the cars and tracks the authenticated member can race
+// with, those bought and those free with the subscription
+type OwnedContent struct {
+	// CarIDs and TrackIDs are sorted, TrackIDs holds every configuration
+	// of the owned tracks
+	CarIDs   []int64
+	TrackIDs []int64
+
+	cars   map[int64]bool
+	tracks map[int64]bool
+
+	// classCars are the cars of each car class
+	classCars map[int64][]int64
+}
+
+// WeekEligibility says whether the member can race a week of a season
+// with what they own
+type WeekEligibility struct {
+	RaceWeekNum int
+	Track       SearchTrack
+
+	OwnsTrack bool
+
+	// CarIDs are the cars of the season the member owns
+	CarIDs []int64
+
+	// Eligible is set when the member owns the track and at least one of
+	// the cars
+	Eligible bool
+}
+
+// OwnedContent returns the content the authenticated member owns.  The
+// packages listed by /data/member/info are expanded to the content of the
+// catalogs sharing their package id, e.g. every configuration of a track,
+// and the free content is added.
+func (i *Irdata) OwnedContent(ctx context.Context) (*OwnedContent, error) {
+	member, err := i.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cars, err := i.GetCars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks, err := i.GetTracks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	classes, err := i.GetCarClasses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := &OwnedContent{
+		cars:      ownedIDs(member.CarPackages),
+		tracks:    ownedIDs(member.TrackPackages),
+		classCars: make(map[int64][]int64),
+	}
+
+	carPackages := ownedPackages(member.CarPackages)
+	trackPackages := ownedPackages(member.TrackPackages)
+
+	for _, car := range cars.Items {
+		if car.FreeWithSubscription || carPackages[car.PackageID] {
+			owned.cars[car.CarID] = true
+		}
+	}
+
+	for _, track := range tracks.Items {
+		if track.FreeWithSubscription || trackPackages[track.PackageID] {
+			owned.tracks[track.TrackID] = true
+		}
+	}
+
+	for _, class := range classes.Items {
+		for _, car := range class.CarsInClass {
+			owned.classCars[class.CarClassID] = append(owned.classCars[class.CarClassID], car.CarID)
+		}
+	}
+
+	owned.CarIDs = sortedIDs(owned.cars)
+	owned.TrackIDs = sortedIDs(owned.tracks)
+
+	return owned, nil
+}
+
+// OwnsCar reports whether the member owns carID
+func (o OwnedContent) OwnsCar(carID int64) bool {
+	return o.cars[carID]
+}
+
+// OwnsTrack reports whether the member owns the track configuration
+// trackID
+func (o OwnedContent) OwnsTrack(trackID int64) bool {
+	return o.tracks[trackID]
+}
+
+// EligibleWeeks returns for each week of the schedule whether the member
+// owns its track and which of the season's cars they own
+func (s *Season) EligibleWeeks(owned OwnedContent) []WeekEligibility {
+	var carIDs []int64
+
+	seen := make(map[int64]bool)
+
+	for _, classID := range s.CarClassIDs {
+		for _, carID := range owned.classCars[classID] {
+			if owned.OwnsCar(carID) && !seen[carID] {
+				seen[carID] = true
+				carIDs = append(carIDs, carID)
+			}
+		}
+	}
+
+	sort.Slice(carIDs, func(a, b int) bool { return carIDs[a] < carIDs[b] })
+
+	weeks := make([]WeekEligibility, 0, len(s.Schedules))
+
+	for _, week := range s.Schedules {
+		ownsTrack := owned.OwnsTrack(week.Track.TrackID)
+
+		weeks = append(weeks, WeekEligibility{
+			RaceWeekNum: week.RaceWeekNum,
+			Track:       week.Track,
+			OwnsTrack:   ownsTrack,
+			CarIDs:      carIDs,
+			Eligible:    ownsTrack && len(carIDs) > 0,
+		})
+	}
+
+	return weeks
+}
+
+// ownedIDs are the content ids the packages list
+func ownedIDs(packages []ContentPackage) map[int64]bool {
+	ids := make(map[int64]bool)
+
+	for _, p := range packages {
+		for _, id := range p.ContentIDs {
+			ids[id] = true
+		}
+	}
+
+	return ids
+}
+
+func ownedPackages(packages []ContentPackage) map[int64]bool {
+	ids := make(map[int64]bool)
+
+	for _, p := range packages {
+		ids[p.PackageID] = true
+	}
+
+	return ids
+}
+
+func sortedIDs(set map[int64]bool) []int64 {
+	ids := make([]int64, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+
+	return ids
+}
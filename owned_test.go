@@ -0,0 +1,59 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnedContent(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/member/info", string(readFixture(t, "owned_member_info.json")))
+	m.handleLinked("/data/car/get", string(readFixture(t, "owned_cars.json")))
+	m.handleLinked("/data/track/get", string(readFixture(t, "owned_tracks.json")))
+	m.handleLinked("/data/carclass/get", string(readFixture(t, "owned_carclasses.json")))
+	m.handleLinked("/data/series/seasons", string(readFixture(t, "owned_seasons.json")))
+
+	api := m.openAuthed(t)
+
+	owned, err := api.OwnedContent(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the free cars, 119 bought, 150 through its package and the 132/135
+	// bundle
+	assert.Equal(t, []int64{1, 67, 119, 132, 135, 150}, owned.CarIDs)
+	assert.False(t, owned.OwnsCar(143))
+
+	// every configuration of Spa and the free tracks
+	assert.Equal(t, []int64{7, 163, 164, 166}, owned.TrackIDs)
+	assert.False(t, owned.OwnsTrack(239))
+
+	seasons, err := api.GetSeasons(context.Background())
+	if !assert.NoError(t, err) || !assert.Len(t, seasons, 2) {
+		return
+	}
+
+	weeks := seasons[0].EligibleWeeks(*owned)
+	if assert.Len(t, weeks, 3) {
+		assert.True(t, weeks[0].Eligible)
+		assert.Equal(t, []int64{132, 135, 150}, weeks[0].CarIDs)
+
+		assert.False(t, weeks[1].OwnsTrack)
+		assert.False(t, weeks[1].Eligible)
+		assert.Equal(t, "Autodromo Nazionale Monza", weeks[1].Track.TrackName)
+
+		assert.True(t, weeks[2].Eligible)
+		assert.Equal(t, 2, weeks[2].RaceWeekNum)
+	}
+
+	// a free track but none of the cars
+	weeks = seasons[1].EligibleWeeks(*owned)
+	if assert.Len(t, weeks, 1) {
+		assert.True(t, weeks[0].OwnsTrack)
+		assert.Empty(t, weeks[0].CarIDs)
+		assert.False(t, weeks[0].Eligible)
+	}
+}
@@ -0,0 +1,138 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pageSize is how many rows are asked for per page of the endpoints
+// paginating with lowerbound and upperbound
+var pageSize = 100
+
+// maxPages stops paginating an endpoint that never runs out of rows
+const maxPages = 1000
+
+// pageT is a page of a paginated endpoint, rows are found under the key
+// the endpoint uses for them
+type pageT map[string]json.RawMessage
+
+// total returns the row count the endpoint reported or -1
+func (p pageT) total() int {
+	for _, key := range []string{"row_count", "results_count", "total_count"} {
+		var total int
+
+		if raw, ok := p[key]; ok && json.Unmarshal(raw, &total) == nil {
+			return total
+		}
+	}
+
+	return -1
+}
+
+// paginate calls fn with every row of endpoint, found under rowsKey, one
+// page at a time from lowerbound 1 until a short or empty page or, if the
+// endpoint reports one, the total count.  It returns that total or the
+// number of rows seen if there was none.
+//
+// Returning ErrStopIteration from fn stops early without an error.
+func (i *Irdata) paginate(ctx context.Context, endpoint string, v url.Values, rowsKey string, fn func(json.RawMessage) error) (int, error) {
+	seen := 0
+
+	for page := 0; page < maxPages; page++ {
+		if page > 0 {
+			if err := i.awaitRateLimit(ctx); err != nil {
+				return 0, err
+			}
+		}
+
+		v.Set("lowerbound", strconv.Itoa(page*pageSize+1))
+		v.Set("upperbound", strconv.Itoa((page+1)*pageSize))
+
+		uri := endpoint + "?" + v.Encode()
+
+		var p pageT
+
+		if err := i.GetJSON(ctx, uri, &p); err != nil {
+			return 0, err
+		}
+
+		var rows []json.RawMessage
+
+		if raw, ok := p[rowsKey]; ok {
+			if err := json.Unmarshal(raw, &rows); err != nil {
+				return 0, err
+			}
+		}
+
+		total := p.total()
+
+		i.logger.WithFields(log.Fields{
+			"uri":       uri,
+			"len(rows)": len(rows),
+			"total":     total,
+		}).Debug("Got page")
+
+		for _, row := range rows {
+			seen++
+
+			if err := fn(row); err != nil {
+				if errors.Is(err, ErrStopIteration) {
+					if total < 0 {
+						total = seen
+					}
+
+					return total, nil
+				}
+
+				return 0, err
+			}
+		}
+
+		if len(rows) < pageSize || (total >= 0 && seen >= total) {
+			if total < 0 {
+				total = seen
+			}
+
+			return total, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s still had rows after %d pages", endpoint, maxPages)
+}
+
+// awaitRateLimit waits for the rate limit to reset if it was used up
+func (i *Irdata) awaitRateLimit(ctx context.Context) error {
+	rl := i.RateLimit()
+
+	if rl.Limit == 0 || rl.Remaining > 0 {
+		return nil
+	}
+
+	wait := rl.Reset.Sub(i.clock.Now())
+	if wait <= 0 {
+		return nil
+	}
+
+	i.logger.WithFields(log.Fields{"reset": rl.Reset}).Info("Waiting for rate limit reset")
+
+	return i.clock.Sleep(ctx, wait)
+}
+
+// paginateAs is paginate unmarshalling each row into a T for fn
+func paginateAs[T any](ctx context.Context, i *Irdata, endpoint string, v url.Values, rowsKey string, fn func(T) error) (int, error) {
+	return i.paginate(ctx, endpoint, v, rowsKey, func(raw json.RawMessage) error {
+		var row T
+
+		if err := i.decodeJSON(endpoint, raw, &row); err != nil {
+			return err
+		}
+
+		return fn(row)
+	})
+}
@@ -0,0 +1,146 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func smallPages(t *testing.T) {
+	saved := pageSize
+	pageSize = 2
+
+	t.Cleanup(func() { pageSize = saved })
+}
+
+// handlePaged serves rows a page at a time the way the league endpoints do,
+// reporting the total under countKey unless it is empty
+func handlePaged(t *testing.T, m *mockAPI, path string, rowsKey string, countKey string, rows []string, header http.Header) *[]string {
+	var bounds []string
+
+	m.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		lower, err := strconv.Atoi(r.URL.Query().Get("lowerbound"))
+		assert.NoError(t, err)
+
+		upper, err := strconv.Atoi(r.URL.Query().Get("upperbound"))
+		assert.NoError(t, err)
+
+		bounds = append(bounds, fmt.Sprintf("%d-%d", lower, upper))
+
+		var page []string
+
+		for n := lower; n <= upper && n <= len(rows); n++ {
+			page = append(page, rows[n-1])
+		}
+
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+
+		count := ""
+		if countKey != "" {
+			count = fmt.Sprintf(`,"%s":%d`, countKey, len(rows))
+		}
+
+		fmt.Fprintf(w, `{"success":true,"%s":[%s]%s}`, rowsKey, strings.Join(page, ","), count)
+	})
+
+	return &bounds
+}
+
+func TestLeagueDirectoryExactPages(t *testing.T) {
+	smallPages(t)
+
+	m := newMockAPI(t)
+	bounds := handlePaged(t, m, "/data/league/directory", "results_page", "row_count", []string{
+		`{"league_id":1}`, `{"league_id":2}`, `{"league_id":3}`, `{"league_id":4}`,
+	}, nil)
+
+	api := m.openAuthed(t)
+
+	results, err := api.LeagueDirectory(context.Background(), LeagueDirectoryParams{Search: "endurance", RestrictToRecruiting: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, results.Total)
+	assert.Len(t, results.Leagues, 4)
+	assert.Equal(t, int64(4), results.Leagues[3].LeagueID)
+
+	// the count says we're done, no need to ask for an empty page
+	assert.Equal(t, []string{"1-2", "3-4"}, *bounds)
+}
+
+func TestLeagueDirectoryStops(t *testing.T) {
+	smallPages(t)
+
+	m := newMockAPI(t)
+	bounds := handlePaged(t, m, "/data/league/directory", "results_page", "row_count", []string{
+		`{"league_id":1}`, `{"league_id":2}`, `{"league_id":3}`,
+	}, nil)
+
+	api := m.openAuthed(t)
+
+	var seen []int64
+
+	total, err := api.EachLeagueDirectory(context.Background(), LeagueDirectoryParams{}, func(league LeagueDirectoryEntry) error {
+		seen = append(seen, league.LeagueID)
+		return ErrStopIteration
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []int64{1}, seen)
+	assert.Equal(t, []string{"1-2"}, *bounds)
+}
+
+func TestCustLeagueSessionsEmptyFinalPage(t *testing.T) {
+	smallPages(t)
+
+	m := newMockAPI(t)
+	bounds := handlePaged(t, m, "/data/league/cust_league_sessions", "sessions", "", []string{
+		`{"subsession_id":1}`, `{"subsession_id":2}`, `{"subsession_id":3}`, `{"subsession_id":4}`,
+	}, nil)
+
+	api := m.openAuthed(t)
+
+	sessions, total, err := api.CustLeagueSessions(context.Background(), true, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Len(t, sessions, 4)
+
+	// without a count only the empty page tells
+	assert.Equal(t, []string{"1-2", "3-4", "5-6"}, *bounds)
+}
+
+func TestPaginateWaitsForRateLimit(t *testing.T) {
+	smallPages(t)
+
+	clock := newFakeClock()
+	reset := clock.Now().Add(30 * time.Second)
+
+	m := newMockAPI(t)
+	handlePaged(t, m, "/data/league/directory", "results_page", "row_count", []string{
+		`{"league_id":1}`, `{"league_id":2}`, `{"league_id":3}`,
+	}, http.Header{
+		"X-Ratelimit-Limit":     []string{"240"},
+		"X-Ratelimit-Remaining": []string{"0"},
+		"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+	})
+
+	api := m.openAuthed(t)
+	api.clock = clock
+
+	results, err := api.LeagueDirectory(context.Background(), LeagueDirectoryParams{})
+	assert.NoError(t, err)
+	assert.Len(t, results.Leagues, 3)
+	assert.Equal(t, []time.Duration{30 * time.Second}, clock.slept())
+}
+
+func TestLeagueDirectoryParamsValues(t *testing.T) {
+	v := LeagueDirectoryParams{Search: "gt3", RestrictToMember: true, MinimumRosterCount: 10, Sort: "rostercount", Order: "desc"}.values()
+
+	assert.Equal(t, "minimum_roster_count=10&order=desc&restrict_to_member=true&search=gt3&sort=rostercount", v.Encode())
+}
@@ -0,0 +1,183 @@
+package irdata
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// paramRule is a combination of parameters an endpoint is known to answer
+// with empty or misleading data rather than an error
+type paramRule struct {
+	endpoints []string
+
+	// fields are the parameters named in the error, reason what's wrong
+	// with them
+	fields []string
+	reason string
+
+	invalid func(v url.Values) bool
+}
+
+var searchEndpoints = []string{"/data/results/search_series", "/data/results/search_hosted"}
+
+// paramRules are checked before any request is sent, see validateParams
+var paramRules = []paramRule{
+	{
+		endpoints: []string{"/data/results/lap_data"},
+		fields:    []string{"cust_id", "team_id"},
+		reason:    "can't both be set",
+		invalid: func(v url.Values) bool {
+			return v.Get("cust_id") != "" && v.Get("team_id") != ""
+		},
+	},
+	{
+		endpoints: searchEndpoints,
+		fields:    []string{"start_range_begin", "start_range_end"},
+		reason:    "is an empty range",
+		invalid: func(v url.Values) bool {
+			return !before(v, "start_range_begin", "start_range_end")
+		},
+	},
+	{
+		endpoints: searchEndpoints,
+		fields:    []string{"finish_range_begin", "finish_range_end"},
+		reason:    "is an empty range",
+		invalid: func(v url.Values) bool {
+			return !before(v, "finish_range_begin", "finish_range_end")
+		},
+	},
+	{
+		endpoints: searchEndpoints,
+		fields:    []string{"finish_range_end", "start_range_begin"},
+		reason:    "end the finish range before the start range begins",
+		invalid: func(v url.Values) bool {
+			return !before(v, "start_range_begin", "finish_range_end")
+		},
+	},
+	{
+		endpoints: []string{"/data/results/search_series"},
+		fields:    []string{"season_quarter"},
+		reason:    "must be 1 to 4 along with season_year",
+		invalid: func(v url.Values) bool {
+			quarter, set := intParam(v, "season_quarter")
+
+			return set && (quarter < 1 || quarter > 4 || v.Get("season_year") == "")
+		},
+	},
+	{
+		endpoints: []string{"/data/results/search_series"},
+		fields:    []string{"race_week_num"},
+		reason:    "can't be negative",
+		invalid: func(v url.Values) bool {
+			week, set := intParam(v, "race_week_num")
+
+			return set && week < 0
+		},
+	},
+	{
+		endpoints: []string{"/data/member/chart_data"},
+		fields:    []string{"chart_type"},
+		reason:    "must be one of the ChartType constants",
+		invalid: func(v url.Values) bool {
+			chartType, set := intParam(v, "chart_type")
+
+			return set && (chartType < ChartTypeIRating || chartType > ChartTypeLicenseSR)
+		},
+	},
+	{
+		endpoints: []string{"/data/member/chart_data"},
+		fields:    []string{"category_id"},
+		reason:    "must be one of the license categories",
+		invalid: func(v url.Values) bool {
+			category, set := intParam(v, "category_id")
+
+			return set && (category < CategoryOval || category > CategoryFormulaCar)
+		},
+	},
+	{
+		// time trials were retired before road was split up
+		endpoints: []string{"/data/member/chart_data"},
+		fields:    []string{"chart_type", "category_id"},
+		reason:    "don't go together: there's no time trial rating for sports or formula cars",
+		invalid: func(v url.Values) bool {
+			chartType, _ := intParam(v, "chart_type")
+			category, _ := intParam(v, "category_id")
+
+			return chartType == ChartTypeTTRating && (category == CategorySportsCar || category == CategoryFormulaCar)
+		},
+	},
+}
+
+// validateParams returns an *InvalidParamsError for the first rule uri
+// breaks
+func validateParams(uri string) error {
+	u, err := url.Parse(normalizeURI(uri))
+	if err != nil {
+		return nil
+	}
+
+	var v url.Values
+
+	for _, rule := range paramRules {
+		if !ruleApplies(rule, u.Path) {
+			continue
+		}
+
+		if v == nil {
+			v = u.Query()
+		}
+
+		if rule.invalid(v) {
+			return &InvalidParamsError{URI: uri, Fields: rule.fields, Reason: rule.reason}
+		}
+	}
+
+	return nil
+}
+
+func ruleApplies(rule paramRule, path string) bool {
+	for _, endpoint := range rule.endpoints {
+		if endpoint == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// before reports whether the time parameter a is before b, true if either
+// isn't set or can't be read
+func before(v url.Values, a string, b string) bool {
+	ta, okA := timeParam(v, a)
+	tb, okB := timeParam(v, b)
+
+	return !okA || !okB || ta.Before(tb)
+}
+
+func timeParam(v url.Values, key string) (time.Time, bool) {
+	s := v.Get(key)
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range []string{searchTimeFormat, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// intParam returns the integer parameter key, false if it isn't set
+func intParam(v url.Values, key string) (int64, bool) {
+	s := v.Get(key)
+	if s == "" {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+
+	return n, err == nil
+}
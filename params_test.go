@@ -0,0 +1,101 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		uri    string
+		fields []string
+	}{
+		{"/data/results/lap_data?subsession_id=1&simsession_number=0&cust_id=1&team_id=2", []string{"cust_id", "team_id"}},
+		{"data/results/lap_data?cust_id=1&team_id=2", []string{"cust_id", "team_id"}},
+		{"/data/results/search_series?start_range_begin=2024-02-01T00:00Z&start_range_end=2024-01-01T00:00Z", []string{"start_range_begin", "start_range_end"}},
+		{"/data/results/search_hosted?cust_id=1&finish_range_begin=2024-02-01T00:00Z&finish_range_end=2024-02-01T00:00Z", []string{"finish_range_begin", "finish_range_end"}},
+		{"/data/results/search_series?start_range_begin=2024-02-01T00:00:00Z&finish_range_end=2024-01-01T00:00:00Z", []string{"finish_range_end", "start_range_begin"}},
+		{"/data/results/search_series?season_year=2024&season_quarter=5", []string{"season_quarter"}},
+		{"/data/results/search_series?season_quarter=1", []string{"season_quarter"}},
+		{"/data/results/search_series?season_year=2024&season_quarter=1&race_week_num=-1", []string{"race_week_num"}},
+		{"/data/member/chart_data?cust_id=1&category_id=2&chart_type=4", []string{"chart_type"}},
+		{"/data/member/chart_data?cust_id=1&category_id=7&chart_type=1", []string{"category_id"}},
+		{"/data/member/chart_data?cust_id=1&category_id=6&chart_type=2", []string{"chart_type", "category_id"}},
+	}
+
+	for _, test := range tests {
+		err := validateParams(test.uri)
+
+		var invalid *InvalidParamsError
+		if assert.ErrorAs(t, err, &invalid, test.uri) {
+			assert.ErrorIs(t, err, ErrInvalidParams)
+			assert.Equal(t, test.fields, invalid.Fields, test.uri)
+		}
+	}
+
+	for _, uri := range []string{
+		"/data/results/lap_data?subsession_id=1&simsession_number=0&cust_id=1",
+		"/data/results/search_series?start_range_begin=2024-01-01T00:00Z&start_range_end=2024-02-01T00:00Z&finish_range_end=2024-02-02T00:00Z",
+		"/data/results/search_series?start_range_begin=tomorrow&start_range_end=today",
+		"/data/results/search_series?season_year=2024&season_quarter=4&race_week_num=0",
+		"/data/results/search_hosted?season_quarter=9",
+		"/data/member/chart_data?cust_id=1&category_id=2&chart_type=2",
+		"/data/member/chart_data?cust_id=1&category_id=5&chart_type=1",
+	} {
+		assert.NoError(t, validateParams(uri), uri)
+	}
+}
+
+func TestInvalidParamsBeforeRequest(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/results/lap_data", `{}`)
+	m.handleJSON("/data/member/chart_data", `{}`)
+
+	api := m.openAuthed(t)
+
+	_, err := api.Get("/data/results/lap_data?subsession_id=1&simsession_number=0&cust_id=1", WithQueryParam("team_id", "2"))
+	assert.ErrorIs(t, err, ErrInvalidParams)
+	assert.Contains(t, err.Error(), "cust_id and team_id")
+
+	_, err = api.GetChartData(context.Background(), 1, CategorySportsCar, ChartTypeTTRating)
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err = api.SearchSeriesResults(context.Background(), SearchSeriesParams{
+		StartRangeBegin:  begin,
+		StartRangeEnd:    begin.Add(200 * 24 * time.Hour),
+		FinishRangeBegin: begin.Add(-48 * time.Hour),
+		FinishRangeEnd:   begin.Add(-24 * time.Hour),
+	})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	_, err = api.SearchHostedResults(context.Background(), SearchHostedParams{
+		CustID:          1,
+		StartRangeBegin: begin,
+		StartRangeEnd:   begin,
+	})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	assert.Equal(t, 0, m.hitCount("/data/results/lap_data"))
+	assert.Equal(t, 0, m.hitCount("/data/member/chart_data"))
+}
+
+func TestSearchWindowsStopAtFinishRange(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	p := SearchSeriesParams{
+		StartRangeBegin: begin,
+		StartRangeEnd:   begin.Add(200 * 24 * time.Hour),
+		FinishRangeEnd:  begin.Add(30 * 24 * time.Hour),
+	}
+
+	windows := p.windows()
+
+	if assert.Len(t, windows, 1) {
+		assert.NoError(t, validateParams("/data/results/search_series?"+windows[0].values().Encode()))
+	}
+}
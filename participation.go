@@ -0,0 +1,159 @@
+package irdata
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// raceWeekLength is how long each week of a season schedule lasts
+const raceWeekLength = 7 * 24 * time.Hour
+
+// SeriesParticipation is how far the member is towards the participation
+// credits of a series
+type SeriesParticipation struct {
+	SeasonID   int64
+	SeriesID   int64
+	SeriesName string
+
+	// MinWeeks is the number of weeks needed to earn the credits
+	MinWeeks int
+
+	// WeeksCompleted is the number of weeks raced at least once
+	WeeksCompleted int
+
+	// WeeksRemaining is the number of weeks not raced yet that haven't
+	// ended, the current week included
+	WeeksRemaining int
+
+	// Met is set once WeeksCompleted reaches MinWeeks
+	Met bool
+
+	// Attainable is set while racing the remaining weeks would still be
+	// enough
+	Attainable bool
+}
+
+// WeeksNeeded is the number of weeks still to race for the credits
+func (p SeriesParticipation) WeeksNeeded() int {
+	if p.Met {
+		return 0
+	}
+
+	return p.MinWeeks - p.WeeksCompleted
+}
+
+// ParticipationProgress joins the participation credits with the member's
+// recent races and the season schedules to tell, per series, how many more
+// weeks are needed for the credits as of now.
+//
+// A week counts once however many races were run in it, in whatever class,
+// and dropped races count since they were still participated in.  Races are
+// matched by season rather than car class so a series that changed classes
+// mid-season is counted as one.  Because recent races only go back so far
+// the weeks iRacing reports in the credits win when they are more.
+func ParticipationProgress(credits []ParticipationCredit, races []RecentRace, seasons []Season, now time.Time) []SeriesParticipation {
+	schedules := make(map[int64][]SeasonWeek)
+
+	for _, season := range seasons {
+		schedules[season.SeasonID] = season.Schedules
+	}
+
+	// the race weeks raced per season
+	raced := make(map[int64]map[int]bool)
+
+	for _, race := range races {
+		start, err := time.Parse(time.RFC3339, race.SessionStartTime)
+		if err != nil {
+			continue
+		}
+
+		week, ok := raceWeekAt(schedules[race.SeasonID], start)
+		if !ok {
+			continue
+		}
+
+		if raced[race.SeasonID] == nil {
+			raced[race.SeasonID] = make(map[int]bool)
+		}
+
+		raced[race.SeasonID][week] = true
+	}
+
+	var progress []SeriesParticipation
+
+	for _, credit := range credits {
+		weeks := raced[credit.SeasonID]
+
+		completed := len(weeks)
+		if credit.Weeks > completed {
+			completed = credit.Weeks
+		}
+
+		remaining := 0
+
+		for _, week := range schedules[credit.SeasonID] {
+			if !weeks[week.RaceWeekNum] && week.Start().Add(raceWeekLength).After(now) {
+				remaining++
+			}
+		}
+
+		progress = append(progress, SeriesParticipation{
+			SeasonID:       credit.SeasonID,
+			SeriesID:       credit.SeriesID,
+			SeriesName:     credit.SeriesName,
+			MinWeeks:       credit.MinWeeks,
+			WeeksCompleted: completed,
+			WeeksRemaining: remaining,
+			Met:            completed >= credit.MinWeeks,
+			Attainable:     completed+remaining >= credit.MinWeeks,
+		})
+	}
+
+	sort.SliceStable(progress, func(a, b int) bool {
+		return progress[a].SeriesName < progress[b].SeriesName
+	})
+
+	return progress
+}
+
+// raceWeekAt returns the race week of schedule t falls in
+func raceWeekAt(schedule []SeasonWeek, t time.Time) (int, bool) {
+	for _, week := range schedule {
+		start := week.Start()
+
+		if !start.IsZero() && !t.Before(start) && t.Before(start.Add(raceWeekLength)) {
+			return week.RaceWeekNum, true
+		}
+	}
+
+	return 0, false
+}
+
+// GetParticipationCredits returns the participation credits of the
+// authenticated account, iRacing only has them for the session's account.
+// It is the same as MyParticipationCredits.
+func (i *Irdata) GetParticipationCredits(ctx context.Context) ([]ParticipationCredit, error) {
+	return i.MyParticipationCredits(ctx)
+}
+
+// MyParticipationProgress is ParticipationProgress for the authenticated
+// account, fetching its credits, recent races and the current seasons
+func (i *Irdata) MyParticipationProgress(ctx context.Context) ([]SeriesParticipation, error) {
+	credits, err := i.GetParticipationCredits(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	races, err := i.MyRecentRaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seasons, err := i.GetSeasons(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParticipationProgress(credits, races, seasons, i.clock.Now()), nil
+}
@@ -0,0 +1,95 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSeasonSchedules(t *testing.T) []Season {
+	var seasons []Season
+
+	if err := json.Unmarshal([]byte(testSeasons), &seasons); err != nil {
+		t.Fatal(err)
+	}
+
+	return seasons
+}
+
+func TestParticipationProgress(t *testing.T) {
+	credits := []ParticipationCredit{
+		{SeasonID: 4711, SeriesID: 139, SeriesName: "Advanced Mazda Cup", MinWeeks: 3},
+		{SeasonID: 4712, SeriesID: 140, SeriesName: "Unscheduled", MinWeeks: 2, Weeks: 2},
+	}
+
+	races := []RecentRace{
+		{SeasonID: 4711, SessionStartTime: "2024-03-12T18:00:00Z"},
+		// a second class in the same week counts once
+		{SeasonID: 4711, CarClassID: 75, SessionStartTime: "2024-03-14T18:00:00Z"},
+		// dropped races were still raced
+		{SeasonID: 4711, SessionStartTime: "2024-03-20T18:00:00Z", DropRace: true},
+		{SeasonID: 4711, SessionStartTime: "garbage"},
+	}
+
+	now := time.Date(2024, 3, 27, 12, 0, 0, 0, time.UTC)
+
+	progress := ParticipationProgress(credits, races, testSeasonSchedules(t), now)
+	assert.Len(t, progress, 2)
+
+	mazda := progress[0]
+	assert.Equal(t, int64(139), mazda.SeriesID)
+	assert.Equal(t, 2, mazda.WeeksCompleted)
+	// the current week and the last one
+	assert.Equal(t, 2, mazda.WeeksRemaining)
+	assert.False(t, mazda.Met)
+	assert.True(t, mazda.Attainable)
+	assert.Equal(t, 1, mazda.WeeksNeeded())
+
+	// iRacing's count wins when we can't see the races
+	unscheduled := progress[1]
+	assert.Equal(t, 2, unscheduled.WeeksCompleted)
+	assert.True(t, unscheduled.Met)
+	assert.Equal(t, 0, unscheduled.WeeksNeeded())
+
+	// too late once only the last week is left
+	progress = ParticipationProgress(credits[:1], races[:1], testSeasonSchedules(t), now.Add(raceWeekLength))
+	assert.Equal(t, 1, progress[0].WeeksRemaining)
+	assert.False(t, progress[0].Attainable)
+}
+
+func TestGetParticipationCredits(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/participation_credits", `[{"season_id":4711,"series_id":139,"min_weeks":3,"weeks":2,"earned_credits":0}]`)
+
+	api := m.openAuthed(t)
+
+	credits, err := api.GetParticipationCredits(context.Background())
+	assert.NoError(t, err)
+
+	if assert.Len(t, credits, 1) {
+		assert.Equal(t, int64(139), credits[0].SeriesID)
+		assert.Equal(t, 3, credits[0].MinWeeks)
+		assert.Equal(t, 2, credits[0].Weeks)
+	}
+}
+
+func TestMyParticipationProgress(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handleLinked("/data/member/participation_credits", `[{"season_id":4711,"series_id":139,"series_name":"Advanced Mazda Cup","min_weeks":3}]`)
+	m.handleLinked("/data/stats/member_recent_races", `{"cust_id":4242,"races":[{"season_id":4711,"session_start_time":"2024-03-12T18:00:00Z"}]}`)
+	m.handleLinked("/data/series/seasons", testSeasons)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t)
+	api.clock = clock
+
+	progress, err := api.MyParticipationProgress(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, progress, 1)
+	assert.Equal(t, 1, progress[0].WeeksCompleted)
+}
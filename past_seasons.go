@@ -0,0 +1,146 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// SeasonListEntry is a season of /data/season/list, which lists the
+// seasons of a quarter
+type SeasonListEntry struct {
+	SeasonID      int64  `json:"season_id"`
+	SeriesID      int64  `json:"series_id"`
+	SeasonName    string `json:"season_name"`
+	SeriesName    string `json:"series_name"`
+	SeasonYear    int    `json:"season_year"`
+	SeasonQuarter int    `json:"season_quarter"`
+	Official      bool   `json:"official"`
+
+	// LicenseGroup, FixedSetup and DriverChanges are nil for the seasons
+	// listed without them
+	LicenseGroup  *int  `json:"license_group"`
+	FixedSetup    *bool `json:"fixed_setup"`
+	DriverChanges *bool `json:"driver_changes"`
+}
+
+// PastSeries is a series and every season it ran, as listed by
+// /data/series/past_seasons
+type PastSeries struct {
+	SeriesID        int64        `json:"series_id"`
+	SeriesName      string       `json:"series_name"`
+	SeriesShortName string       `json:"series_short_name"`
+	CategoryID      int64        `json:"category_id"`
+	Category        string       `json:"category"`
+	Active          bool         `json:"active"`
+	Official        bool         `json:"official"`
+	Seasons         []PastSeason `json:"seasons"`
+}
+
+// PastSeason is a season of a PastSeries.  The oldest seasons lack fields
+// the newer ones have: the pointers are nil and CarClasses and RaceWeeks
+// empty for those.
+type PastSeason struct {
+	SeasonID        int64  `json:"season_id"`
+	SeriesID        int64  `json:"series_id"`
+	SeasonName      string `json:"season_name"`
+	SeasonShortName string `json:"season_short_name"`
+	SeasonYear      int    `json:"season_year"`
+	SeasonQuarter   int    `json:"season_quarter"`
+	Active          bool   `json:"active"`
+	Official        bool   `json:"official"`
+
+	LicenseGroup     *int  `json:"license_group"`
+	FixedSetup       *bool `json:"fixed_setup"`
+	DriverChanges    *bool `json:"driver_changes"`
+	HasSupersessions *bool `json:"has_supersessions"`
+
+	CarClasses []PastSeasonCarClass `json:"car_classes"`
+	RaceWeeks  []PastRaceWeek       `json:"race_weeks"`
+}
+
+// PastSeasonCarClass is a car class raced in a PastSeason
+type PastSeasonCarClass struct {
+	CarClassID    int64  `json:"car_class_id"`
+	Name          string `json:"name"`
+	ShortName     string `json:"short_name"`
+	RelativeSpeed int    `json:"relative_speed"`
+}
+
+// PastRaceWeek is a week of the schedule of a PastSeason
+type PastRaceWeek struct {
+	SeasonID    int64       `json:"season_id"`
+	RaceWeekNum int         `json:"race_week_num"`
+	Track       SearchTrack `json:"track"`
+}
+
+// SearchParams returns the parameters of SearchSeriesResults finding the
+// races of the season
+func (s PastSeason) SearchParams() SearchSeriesParams {
+	return SearchSeriesParams{
+		SeasonYear:    s.SeasonYear,
+		SeasonQuarter: s.SeasonQuarter,
+		SeriesID:      s.SeriesID,
+	}
+}
+
+// GetSeasonList returns the seasons of a quarter, past ones included
+// unlike GetSeasons.  It's cached for a long time when the cache is
+// enabled.
+func (i *Irdata) GetSeasonList(ctx context.Context, seasonYear int, seasonQuarter int) ([]SeasonListEntry, error) {
+	var list struct {
+		SeasonYear    int               `json:"season_year"`
+		SeasonQuarter int               `json:"season_quarter"`
+		Seasons       []SeasonListEntry `json:"seasons"`
+	}
+
+	uri := fmt.Sprintf("/data/season/list?season_year=%d&season_quarter=%d", seasonYear, seasonQuarter)
+
+	if err := i.getLookup(ctx, uri, &list); err != nil {
+		return nil, err
+	}
+
+	return list.Seasons, nil
+}
+
+// GetSeriesPastSeasons returns the series and every season it ran.  It's
+// cached for a long time when the cache is enabled.
+func (i *Irdata) GetSeriesPastSeasons(ctx context.Context, seriesID int64) (*PastSeries, error) {
+	var past struct {
+		Success bool       `json:"success"`
+		Series  PastSeries `json:"series"`
+	}
+
+	if err := i.getLookup(ctx, fmt.Sprintf("/data/series/past_seasons?series_id=%d", seriesID), &past); err != nil {
+		return nil, err
+	}
+
+	return &past.Series, nil
+}
+
+// SeriesSeasonHistory returns every season the series ran, oldest first,
+// e.g. to search the results of each with its SearchParams
+func (i *Irdata) SeriesSeasonHistory(ctx context.Context, seriesID int64) ([]PastSeason, error) {
+	past, err := i.GetSeriesPastSeasons(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	seasons := past.Seasons
+
+	sort.SliceStable(seasons, func(a, b int) bool {
+		sa, sb := seasons[a], seasons[b]
+
+		if sa.SeasonYear != sb.SeasonYear {
+			return sa.SeasonYear < sb.SeasonYear
+		}
+
+		if sa.SeasonQuarter != sb.SeasonQuarter {
+			return sa.SeasonQuarter < sb.SeasonQuarter
+		}
+
+		return sa.SeasonID < sb.SeasonID
+	})
+
+	return seasons, nil
+}
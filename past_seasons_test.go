@@ -0,0 +1,87 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSeasonList(t *testing.T) {
+	m := newMockAPI(t)
+
+	var query string
+
+	m.handle("/data/season/list", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		http.ServeFile(w, r, "testdata/season_list_2009_1.json")
+	})
+
+	api := m.openAuthed(t, WithUnknownFields(UnknownFieldsReject))
+
+	seasons, err := api.GetSeasonList(context.Background(), 2009, 1)
+	if !assert.NoError(t, err) || !assert.Len(t, seasons, 2) {
+		return
+	}
+
+	assert.Equal(t, "season_year=2009&season_quarter=1", query)
+
+	// listed without the fields added later
+	assert.Equal(t, "Skip Barber Race Series", seasons[0].SeriesName)
+	assert.Nil(t, seasons[0].LicenseGroup)
+	assert.Nil(t, seasons[0].FixedSetup)
+
+	if assert.NotNil(t, seasons[1].LicenseGroup) && assert.NotNil(t, seasons[1].FixedSetup) {
+		assert.Equal(t, 2, *seasons[1].LicenseGroup)
+		assert.False(t, *seasons[1].FixedSetup)
+	}
+
+	assert.Nil(t, seasons[1].DriverChanges)
+}
+
+func TestSeriesSeasonHistory(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/series/past_seasons", string(readFixture(t, "past_seasons_skip_barber.json")))
+
+	api := m.openAuthed(t, WithUnknownFields(UnknownFieldsReject))
+
+	past, err := api.GetSeriesPastSeasons(context.Background(), 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Skip Barber", past.SeriesShortName)
+		assert.Len(t, past.Seasons, 3)
+	}
+
+	seasons, err := api.SeriesSeasonHistory(context.Background(), 2)
+	if !assert.NoError(t, err) || !assert.Len(t, seasons, 3) {
+		return
+	}
+
+	var ids []int64
+	for _, season := range seasons {
+		ids = append(ids, season.SeasonID)
+	}
+
+	assert.Equal(t, []int64{31, 2, 4420}, ids)
+
+	// the old seasons have no classes, schedule or flags
+	old := seasons[0]
+	assert.Empty(t, old.CarClasses)
+	assert.Empty(t, old.RaceWeeks)
+	assert.Nil(t, old.LicenseGroup)
+	assert.Nil(t, old.HasSupersessions)
+	assert.Equal(t, SearchSeriesParams{SeasonYear: 2008, SeasonQuarter: 4, SeriesID: 2}, old.SearchParams())
+
+	recent := seasons[2]
+	if assert.Len(t, recent.CarClasses, 1) {
+		assert.Equal(t, int64(1), recent.CarClasses[0].CarClassID)
+	}
+
+	if assert.Len(t, recent.RaceWeeks, 1) {
+		assert.Equal(t, "Lime Rock Park", recent.RaceWeeks[0].Track.TrackName)
+	}
+
+	if assert.NotNil(t, recent.HasSupersessions) {
+		assert.False(t, *recent.HasSupersessions)
+	}
+}
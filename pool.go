@@ -0,0 +1,85 @@
+package irdata
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportOptions size the connection pools of the transport Open builds.
+// Zero values use the defaults.
+//
+// Connections are pooled per host so members-ng, the s3 hosts serving
+// links and chunks and the static asset host each get their own
+// MaxIdleConnsPerHost connections.
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is how many idle connections are kept to each
+	// host, 16 by default (net/http keeps 2)
+	MaxIdleConnsPerHost int
+
+	// MaxIdleConns caps the idle connections to all hosts, 64 by default
+	MaxIdleConns int
+
+	// IdleConnTimeout is how long an idle connection is kept, 90 seconds by
+	// default
+	IdleConnTimeout time.Duration
+
+	// DisableHTTP2 sticks to HTTP/1.1, e.g. to spread requests over several
+	// connections rather than multiplexing them over one
+	DisableHTTP2 bool
+}
+
+const (
+	defaultMaxIdleConnsPerHost = 16
+	defaultMaxIdleConns        = 64
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// WithTransportOptions sizes the connection pools, see TransportOptions
+func WithTransportOptions(opts TransportOptions) Option {
+	return func(i *Irdata) {
+		transport := NewTransport(opts)
+
+		i.httpClient.Transport = transport
+		i.assetClient.Transport = transport
+	}
+}
+
+// NewTransport returns the transport Open uses with opts applied.  When
+// making your own client start from it rather than http.DefaultTransport,
+// whose 2 idle connections per host make a collector keep reconnecting.
+func NewTransport(opts TransportOptions) *http.Transport {
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = defaultMaxIdleConns
+	}
+
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = defaultIdleConnTimeout
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     !opts.DisableHTTP2,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	if opts.DisableHTTP2 {
+		// a non-nil empty map is how net/http is told not to upgrade
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport
+}
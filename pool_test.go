@@ -0,0 +1,170 @@
+package irdata
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingServer counts the connections made to it.  Requests are held
+// until parallel of them are in flight so that many connections are needed.
+type countingServer struct {
+	*httptest.Server
+
+	conns int64
+
+	mu       sync.Mutex
+	parallel int
+	inFlight int
+	released chan struct{}
+}
+
+func newCountingServer(tb testing.TB, parallel int) *countingServer {
+	s := &countingServer{parallel: parallel, released: make(chan struct{})}
+
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serve))
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&s.conns, 1)
+		}
+	}
+	s.Start()
+
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *countingServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight++
+	released := s.released
+
+	if s.inFlight == s.parallel {
+		close(s.released)
+		s.inFlight = 0
+		s.released = make(chan struct{})
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+	}
+
+	w.Write([]byte(`[{"id":1}]`))
+}
+
+func (s *countingServer) connections() int64 {
+	return atomic.LoadInt64(&s.conns)
+}
+
+// getParallel GETs url n times at once and reads the bodies to the end so
+// the connections go back to the pool
+func getParallel(tb testing.TB, client *http.Client, url string, n int) {
+	var wg sync.WaitGroup
+
+	for k := 0; k < n; k++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := client.Get(url)
+			if err != nil {
+				tb.Error(err)
+				return
+			}
+
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestPerHostPoolsSizedIndependently(t *testing.T) {
+	const parallel = 8
+
+	api := newCountingServer(t, parallel)
+	s3 := newCountingServer(t, parallel)
+
+	client := &http.Client{Transport: NewTransport(TransportOptions{MaxIdleConnsPerHost: parallel})}
+
+	for round := 0; round < 3; round++ {
+		getParallel(t, client, api.URL, parallel)
+		getParallel(t, client, s3.URL, parallel)
+	}
+
+	// busy with one host the pool of the other is kept
+	assert.Equal(t, int64(parallel), api.connections())
+	assert.Equal(t, int64(parallel), s3.connections())
+}
+
+func TestDefaultTransportChurns(t *testing.T) {
+	const parallel = 8
+
+	s := newCountingServer(t, parallel)
+
+	client := &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+
+	getParallel(t, client, s.URL, parallel)
+	getParallel(t, client, s.URL, parallel)
+
+	// only 2 connections were kept for the second round
+	assert.Equal(t, int64(2*parallel-2), s.connections())
+}
+
+func TestTransportOptions(t *testing.T) {
+	transport := Open(context.Background()).httpClient.Transport.(*http.Transport)
+	assert.Equal(t, defaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, defaultIdleConnTimeout, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.TLSNextProto)
+
+	api := Open(context.Background(), WithTransportOptions(TransportOptions{MaxIdleConnsPerHost: 4, DisableHTTP2: true}))
+
+	transport = api.httpClient.Transport.(*http.Transport)
+	assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, defaultMaxIdleConns, transport.MaxIdleConns)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Same(t, transport, api.assetClient.Transport)
+}
+
+// BenchmarkParallelChunkConnections fetches 16 chunks at once per op and
+// reports the connections made per op
+func BenchmarkParallelChunkConnections(b *testing.B) {
+	const parallel = 16
+
+	for _, bench := range []struct {
+		name      string
+		transport *http.Transport
+	}{
+		{"default", http.DefaultTransport.(*http.Transport).Clone()},
+		{"tuned", NewTransport(TransportOptions{})},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			s := newCountingServer(b, parallel)
+
+			client := &http.Client{Transport: bench.transport}
+
+			for n := 0; n < b.N; n++ {
+				getParallel(b, client, s.URL, parallel)
+			}
+
+			b.ReportMetric(float64(s.connections())/float64(b.N), "conns/op")
+
+			bench.transport.CloseIdleConnections()
+		})
+	}
+}
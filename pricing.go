@@ -0,0 +1,309 @@
+package irdata
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Cents is an amount in US cents, the only currency of the API.  The
+// catalogs send prices as dollars, they're decoded to cents so sums are
+// exact.
+type Cents int64
+
+func (c *Cents) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	dollars, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("price %s: %w", data, err)
+	}
+
+	*c = Cents(math.Round(dollars * 100))
+
+	return nil
+}
+
+// MarshalJSON encodes the amount as dollars, like the catalogs
+func (c Cents) MarshalJSON() ([]byte, error) {
+	return []byte(c.dollars()), nil
+}
+
+// String returns the amount in dollars, e.g. $11.95
+func (c Cents) String() string {
+	return "$" + c.dollars()
+}
+
+func (c Cents) dollars() string {
+	sign := ""
+	if c < 0 {
+		sign, c = "-", -c
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// ContentKind is whether a package is of the car or the track catalog
+type ContentKind int
+
+const (
+	CarPackage ContentKind = iota
+	TrackPackage
+)
+
+// PackageRef refers to a package of the car or track catalog, what iRacing
+// sells on its own: a car, or every configuration of a track
+type PackageRef struct {
+	Kind      ContentKind
+	PackageID int64
+}
+
+// Package returns the package the car is sold in
+func (c Car) Package() PackageRef {
+	return PackageRef{Kind: CarPackage, PackageID: c.PackageID}
+}
+
+// Package returns the package the track configuration is sold in
+func (t Track) Package() PackageRef {
+	return PackageRef{Kind: TrackPackage, PackageID: t.PackageID}
+}
+
+// ContentPack is a bundle of packages sold together for a price.  The API
+// doesn't list them, they come from the shop.
+type ContentPack struct {
+	Name     string
+	SKU      int64
+	Price    Cents
+	Packages []PackageRef
+}
+
+// Purchase is a purchase of a PurchasePlan, a content pack or a single car
+// or track
+type Purchase struct {
+	Name     string
+	SKU      int64
+	Price    Cents
+	Packages []PackageRef
+
+	// Pack is set for content packs
+	Pack bool
+}
+
+// PurchasePlan is the cheapest set of purchases covering a season's
+// schedule, see Season.PlanPurchases
+type PurchasePlan struct {
+	Purchases []Purchase
+	Total     Cents
+
+	// Unavailable are the packages needed that the plan doesn't cover as
+	// nothing sells them, e.g. tracks missing from the catalog, referred
+	// to by their track id.  When no car of the season can be bought they
+	// are all listed.
+	Unavailable []PackageRef
+}
+
+// PlanPurchases returns the cheapest purchases giving the member every
+// track of the schedule and, if they own none, one of the season's cars,
+// choosing between the packs and buying the packages on their own.  Prices
+// are the list prices of the catalogs and packs, volume discounts aren't
+// taken into account.  The search is exact, which is fine for the handful
+// of packs that touch one season.
+func (s *Season) PlanPurchases(owned OwnedContent, cars []Car, tracks []Track, packs []ContentPack) PurchasePlan {
+	single := make(map[PackageRef]Purchase)
+
+	for _, car := range cars {
+		if ref := car.Package(); !car.Retired {
+			if _, ok := single[ref]; !ok {
+				single[ref] = Purchase{Name: car.CarName, SKU: car.SKU, Price: car.Price, Packages: []PackageRef{ref}}
+			}
+		}
+	}
+
+	for _, track := range tracks {
+		if ref := track.Package(); !track.Retired {
+			if _, ok := single[ref]; !ok {
+				single[ref] = Purchase{Name: track.TrackName, SKU: track.SKU, Price: track.Price, Packages: []PackageRef{ref}}
+			}
+		}
+	}
+
+	planner := purchasePlannerT{single: single}
+
+	trackRefs := make(map[int64]PackageRef)
+	for _, track := range tracks {
+		trackRefs[track.TrackID] = track.Package()
+	}
+
+	seen := make(map[PackageRef]bool)
+
+	for _, week := range s.Schedules {
+		if owned.OwnsTrack(week.Track.TrackID) {
+			continue
+		}
+
+		ref, ok := trackRefs[week.Track.TrackID]
+		if !ok {
+			ref = PackageRef{Kind: TrackPackage, PackageID: week.Track.TrackID}
+		}
+
+		if !seen[ref] {
+			seen[ref] = true
+			planner.tracks = append(planner.tracks, ref)
+		}
+	}
+
+	sort.Slice(planner.tracks, func(a, b int) bool { return planner.tracks[a].PackageID < planner.tracks[b].PackageID })
+
+	carRefs := make(map[int64]PackageRef)
+	for _, car := range cars {
+		carRefs[car.CarID] = car.Package()
+	}
+
+	ownsCar := false
+
+	var candidates []PackageRef
+
+	for _, classID := range s.CarClassIDs {
+		for _, carID := range owned.classCars[classID] {
+			if owned.OwnsCar(carID) {
+				ownsCar = true
+			}
+
+			ref, ok := carRefs[carID]
+			if !ok {
+				ref = PackageRef{Kind: CarPackage, PackageID: carID}
+			}
+
+			if !seen[ref] {
+				seen[ref] = true
+				candidates = append(candidates, ref)
+			}
+		}
+	}
+
+	if !ownsCar {
+		planner.cars = candidates
+	}
+
+	// only the packs with something needed are worth buying
+	for n := range packs {
+		for _, ref := range packs[n].Packages {
+			if seen[ref] && (ref.Kind == TrackPackage || !ownsCar) {
+				planner.packs = append(planner.packs, &packs[n])
+				break
+			}
+		}
+	}
+
+	planner.search(0, nil, 0)
+
+	return planner.best
+}
+
+type purchasePlannerT struct {
+	// tracks are the track packages needed, one of cars is needed too
+	// unless it's empty
+	tracks []PackageRef
+	cars   []PackageRef
+
+	single map[PackageRef]Purchase
+	packs  []*ContentPack
+
+	best  PurchasePlan
+	found bool
+}
+
+// search tries the packs from the n-th on with and without each, chosen
+// being those taken so far, costing cost
+func (p *purchasePlannerT) search(n int, chosen []*ContentPack, cost Cents) {
+	if p.found && len(p.best.Unavailable) == 0 && cost > p.best.Total {
+		return
+	}
+
+	if n == len(p.packs) {
+		if plan := p.plan(chosen); !p.found || plan.better(p.best) {
+			p.best, p.found = plan, true
+		}
+
+		return
+	}
+
+	p.search(n+1, append(chosen[:len(chosen):len(chosen)], p.packs[n]), cost+p.packs[n].Price)
+	p.search(n+1, chosen, cost)
+}
+
+// plan completes the packs chosen with the packages they don't cover
+func (p *purchasePlannerT) plan(chosen []*ContentPack) PurchasePlan {
+	var plan PurchasePlan
+
+	covered := make(map[PackageRef]bool)
+
+	for _, pack := range chosen {
+		for _, ref := range pack.Packages {
+			covered[ref] = true
+		}
+
+		plan.Purchases = append(plan.Purchases, Purchase{Name: pack.Name, SKU: pack.SKU, Price: pack.Price, Packages: pack.Packages, Pack: true})
+	}
+
+	for _, ref := range p.tracks {
+		if covered[ref] {
+			continue
+		}
+
+		if purchase, ok := p.single[ref]; ok {
+			plan.Purchases = append(plan.Purchases, purchase)
+		} else {
+			plan.Unavailable = append(plan.Unavailable, ref)
+		}
+	}
+
+	if len(p.cars) > 0 && !p.carCovered(covered) {
+		var cheapest *Purchase
+
+		for _, ref := range p.cars {
+			if purchase, ok := p.single[ref]; ok && (cheapest == nil || purchase.Price < cheapest.Price) {
+				cheapest = &purchase
+			}
+		}
+
+		if cheapest != nil {
+			plan.Purchases = append(plan.Purchases, *cheapest)
+		} else {
+			plan.Unavailable = append(plan.Unavailable, p.cars...)
+		}
+	}
+
+	for _, purchase := range plan.Purchases {
+		plan.Total += purchase.Price
+	}
+
+	return plan
+}
+
+func (p *purchasePlannerT) carCovered(covered map[PackageRef]bool) bool {
+	for _, ref := range p.cars {
+		if covered[ref] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// better reports whether plan covers more than other, or as much for less
+// or in fewer purchases
+func (plan PurchasePlan) better(other PurchasePlan) bool {
+	if len(plan.Unavailable) != len(other.Unavailable) {
+		return len(plan.Unavailable) < len(other.Unavailable)
+	}
+
+	if plan.Total != other.Total {
+		return plan.Total < other.Total
+	}
+
+	return len(plan.Purchases) < len(other.Purchases)
+}
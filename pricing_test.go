@@ -0,0 +1,147 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCents(t *testing.T) {
+	var car Car
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"car_id": 132, "price": 11.95, "sku": 10417}`), &car))
+	assert.Equal(t, Cents(1195), car.Price)
+	assert.Equal(t, "$11.95", car.Price.String())
+	assert.Equal(t, "$0.05", Cents(5).String())
+
+	data, err := json.Marshal(car.Price)
+	assert.NoError(t, err)
+	assert.Equal(t, "11.95", string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"price": "free"}`), &car))
+}
+
+// planSetup returns the member's owned content and the catalogs of the
+// owned_ fixtures, with their seasons
+func planSetup(t *testing.T) (*OwnedContent, []Car, []Track, []Season) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/member/info", string(readFixture(t, "owned_member_info.json")))
+	m.handleLinked("/data/car/get", string(readFixture(t, "owned_cars.json")))
+	m.handleLinked("/data/track/get", string(readFixture(t, "owned_tracks.json")))
+	m.handleLinked("/data/carclass/get", string(readFixture(t, "owned_carclasses.json")))
+	m.handleLinked("/data/series/seasons", string(readFixture(t, "owned_seasons.json")))
+
+	api := m.openAuthed(t)
+	ctx := context.Background()
+
+	owned, err := api.OwnedContent(ctx)
+	assert.NoError(t, err)
+
+	cars, err := api.GetCars(ctx)
+	assert.NoError(t, err)
+
+	tracks, err := api.GetTracks(ctx)
+	assert.NoError(t, err)
+
+	seasons, err := api.GetSeasons(ctx)
+	assert.NoError(t, err)
+
+	if t.Failed() || len(seasons) != 2 {
+		t.FailNow()
+	}
+
+	return owned, cars.Items, tracks.Items, seasons
+}
+
+var (
+	monza = PackageRef{Kind: TrackPackage, PackageID: 239}
+	gt3r  = PackageRef{Kind: CarPackage, PackageID: 143}
+)
+
+func TestPlanPurchases(t *testing.T) {
+	owned, cars, tracks, seasons := planSetup(t)
+
+	// Spa is owned, Okayama free and the GT3 and GT4 cars owned
+	plan := seasons[0].PlanPurchases(*owned, cars, tracks, nil)
+	if assert.Len(t, plan.Purchases, 1) {
+		assert.Equal(t, "Autodromo Nazionale Monza", plan.Purchases[0].Name)
+		assert.Equal(t, int64(10488), plan.Purchases[0].SKU)
+		assert.Equal(t, []PackageRef{monza}, plan.Purchases[0].Packages)
+		assert.False(t, plan.Purchases[0].Pack)
+	}
+
+	assert.Equal(t, Cents(1495), plan.Total)
+	assert.Empty(t, plan.Unavailable)
+
+	// nothing needed
+	owned.tracks[239] = true
+
+	plan = seasons[0].PlanPurchases(*owned, cars, tracks, nil)
+	assert.Empty(t, plan.Purchases)
+	assert.Equal(t, Cents(0), plan.Total)
+}
+
+func TestPlanPurchasesPacks(t *testing.T) {
+	owned, cars, tracks, seasons := planSetup(t)
+
+	packs := []ContentPack{
+		{Name: "GT3 R at Monza", SKU: 20001, Price: 1995, Packages: []PackageRef{gt3r, monza}},
+		{Name: "European tracks", SKU: 20002, Price: 2495, Packages: []PackageRef{monza, {Kind: TrackPackage, PackageID: 163}}},
+		{Name: "MX-5 and Lime Rock", SKU: 20003, Price: 500, Packages: []PackageRef{{Kind: CarPackage, PackageID: 67}, {Kind: TrackPackage, PackageID: 7}}},
+	}
+
+	// only the car is needed, buying it is cheaper than the pack
+	plan := seasons[1].PlanPurchases(*owned, cars, tracks, packs)
+	if assert.Len(t, plan.Purchases, 1) {
+		assert.Equal(t, "Porsche 911 GT3 R (992)", plan.Purchases[0].Name)
+		assert.Equal(t, int64(10532), plan.Purchases[0].SKU)
+	}
+
+	assert.Equal(t, Cents(1495), plan.Total)
+
+	// with Monza on the schedule the pack beats buying both for 29.90
+	season := seasons[1]
+	season.Schedules = append(season.Schedules, SeasonWeek{RaceWeekNum: 1, Track: SearchTrack{TrackID: 239}})
+
+	plan = season.PlanPurchases(*owned, cars, tracks, packs)
+	if assert.Len(t, plan.Purchases, 1) {
+		assert.Equal(t, "GT3 R at Monza", plan.Purchases[0].Name)
+		assert.True(t, plan.Purchases[0].Pack)
+	}
+
+	assert.Equal(t, Cents(1995), plan.Total)
+
+	// until it costs more
+	packs[0].Price = 2995
+
+	plan = season.PlanPurchases(*owned, cars, tracks, packs)
+	assert.Len(t, plan.Purchases, 2)
+	assert.Equal(t, Cents(2990), plan.Total)
+
+	// the same for fewer purchases
+	packs[0].Price = 2990
+
+	plan = season.PlanPurchases(*owned, cars, tracks, packs)
+	assert.Len(t, plan.Purchases, 1)
+	assert.Equal(t, Cents(2990), plan.Total)
+}
+
+func TestPlanPurchasesUnavailable(t *testing.T) {
+	owned, cars, tracks, seasons := planSetup(t)
+
+	season := seasons[0]
+	season.Schedules = append(season.Schedules, SeasonWeek{RaceWeekNum: 3, Track: SearchTrack{TrackID: 4242}})
+
+	lost := PackageRef{Kind: TrackPackage, PackageID: 4242}
+
+	plan := season.PlanPurchases(*owned, cars, tracks, nil)
+	assert.Equal(t, []PackageRef{lost}, plan.Unavailable)
+	assert.Equal(t, Cents(1495), plan.Total)
+
+	// a pack selling it is worth its price
+	plan = season.PlanPurchases(*owned, cars, tracks, []ContentPack{{Name: "Lost circuits", Price: 3000, Packages: []PackageRef{lost}}})
+	assert.Empty(t, plan.Unavailable)
+	assert.Equal(t, Cents(4495), plan.Total)
+}
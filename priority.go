@@ -0,0 +1,243 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Priority orders the requests waiting for the throttle set with
+// WithMaxConcurrentRequests
+type Priority int
+
+// Request priorities, PriorityNormal unless the context says otherwise
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// defaultPriorityAging is how long a request waits before it's treated as
+// one priority higher
+const defaultPriorityAging = 30 * time.Second
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose requests wait for the throttle with
+// priority p, e.g. PriorityHigh for what a user is waiting on and
+// PriorityLow for a backfill.  Get and GetWithCache use the context passed
+// to Open, give them WithRequestPriority instead.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// WithRequestPriority makes the request wait for the throttle with
+// priority p, over what the context says
+//
+//	data, err := api.Get("/data/member/profile?cust_id=4242", irdata.WithRequestPriority(irdata.PriorityHigh))
+func WithRequestPriority(p Priority) RequestOption {
+	return func(o *requestOptions) {
+		o.priority = &p
+	}
+}
+
+// requestPriority is the priority of a request made with ctx: the one set
+// with WithRequestPriority or else PriorityFromContext
+func (i *Irdata) requestPriority(ctx context.Context) Priority {
+	if i.hasCallOptions(ctx) {
+		if o := i.callOptions(ctx, nil); o.priority != nil {
+			return *o.priority
+		}
+	}
+
+	return PriorityFromContext(ctx)
+}
+
+// PriorityFromContext returns the priority set with WithPriority,
+// PriorityNormal if there is none
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+
+	return PriorityNormal
+}
+
+// WithMaxConcurrentRequests limits the requests to the API in flight at
+// once to n.  Waiting requests go by priority (see WithPriority and
+// WithRequestPriority) and then in order, so interactive requests jump
+// ahead of background work.  Priorities only apply with this option,
+// without it no request waits and there is nothing to order.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(i *Irdata) {
+		i.throttle.limit = n
+	}
+}
+
+// WithPriorityAging sets how long a waiting request takes to be treated as
+// one priority higher, 30 seconds by default.  It bounds how long
+// PriorityLow requests wait while higher priority ones keep coming.
+func WithPriorityAging(d time.Duration) Option {
+	return func(i *Irdata) {
+		i.throttle.aging = d
+	}
+}
+
+// ThrottleStats is what the throttle is doing
+type ThrottleStats struct {
+	InFlight int
+	Queued   map[Priority]int
+}
+
+// ThrottleStats returns the requests in flight and those waiting for the
+// throttle by priority
+func (i *Irdata) ThrottleStats() ThrottleStats {
+	i.throttle.mu.Lock()
+	defer i.throttle.mu.Unlock()
+
+	stats := ThrottleStats{
+		InFlight: i.throttle.inFlight,
+		Queued:   map[Priority]int{PriorityLow: 0, PriorityNormal: 0, PriorityHigh: 0},
+	}
+
+	for _, w := range i.throttle.waiters {
+		stats.Queued[w.priority]++
+	}
+
+	return stats
+}
+
+type throttleT struct {
+	mu       sync.Mutex
+	limit    int
+	aging    time.Duration
+	inFlight int
+	waiters  []*throttleWaiter
+}
+
+type throttleWaiter struct {
+	priority Priority
+	queued   time.Time
+	ready    chan struct{}
+}
+
+// acquireSlot waits for the throttle to let a request through and returns
+// the func to call once it's done
+func (i *Irdata) acquireSlot(ctx context.Context) (func(), error) {
+	t := &i.throttle
+
+	t.mu.Lock()
+
+	if t.limit <= 0 {
+		t.mu.Unlock()
+		return func() {}, nil
+	}
+
+	release := func() { i.releaseSlot() }
+
+	if t.inFlight < t.limit && len(t.waiters) == 0 {
+		t.inFlight++
+		t.mu.Unlock()
+
+		return release, nil
+	}
+
+	w := &throttleWaiter{
+		priority: i.requestPriority(ctx),
+		queued:   i.clock.Now(),
+		ready:    make(chan struct{}),
+	}
+
+	t.waiters = append(t.waiters, w)
+	t.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+
+	for n, waiting := range t.waiters {
+		if waiting == w {
+			t.waiters = append(t.waiters[:n], t.waiters[n+1:]...)
+			t.mu.Unlock()
+
+			return nil, ctx.Err()
+		}
+	}
+
+	t.mu.Unlock()
+
+	// the slot was handed over just as ctx was done
+	i.releaseSlot()
+
+	return nil, ctx.Err()
+}
+
+// releaseSlot hands the slot of a finished request to the waiter with the
+// highest priority, counting the time waited, or the longest waiting of
+// those
+func (i *Irdata) releaseSlot() {
+	t := &i.throttle
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+
+	if len(t.waiters) == 0 {
+		return
+	}
+
+	aging := t.aging
+	if aging <= 0 {
+		aging = defaultPriorityAging
+	}
+
+	now := i.clock.Now()
+
+	next := 0
+
+	for n, w := range t.waiters {
+		if w.effectivePriority(now, aging) > t.waiters[next].effectivePriority(now, aging) {
+			next = n
+		}
+	}
+
+	w := t.waiters[next]
+	t.waiters = append(t.waiters[:next], t.waiters[next+1:]...)
+
+	t.inFlight++
+	close(w.ready)
+}
+
+// effectivePriority is the priority of w raised a level for every aging it
+// has waited, up to PriorityHigh
+func (w *throttleWaiter) effectivePriority(now time.Time, aging time.Duration) Priority {
+	if w.priority >= PriorityHigh {
+		return w.priority
+	}
+
+	p := w.priority + Priority(now.Sub(w.queued)/aging)
+	if p > PriorityHigh {
+		return PriorityHigh
+	}
+
+	return p
+}
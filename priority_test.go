@@ -0,0 +1,179 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowAPI answers /data/slow only once released, recording the order the
+// requests got through the throttle in
+type slowAPI struct {
+	*mockAPI
+
+	mu    sync.Mutex
+	order []string
+	gate  chan struct{}
+}
+
+func newSlowAPI(t *testing.T) *slowAPI {
+	s := &slowAPI{mockAPI: newMockAPI(t), gate: make(chan struct{})}
+
+	s.handle("/data/slow", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.order = append(s.order, r.URL.Query().Get("name"))
+		s.mu.Unlock()
+
+		<-s.gate
+
+		fmt.Fprint(w, `{}`)
+	})
+
+	return s
+}
+
+func (s *slowAPI) requested() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.order...)
+}
+
+// queue starts a request for name with priority p and waits for it to be
+// in flight or queued
+func queue(t *testing.T, wg *sync.WaitGroup, api *Irdata, name string, p Priority) {
+	before := api.ThrottleStats()
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		var v interface{}
+		assert.NoError(t, api.GetJSON(WithPriority(context.Background(), p), "/data/slow?name="+name, &v))
+	}()
+
+	assert.Eventually(t, func() bool {
+		stats := api.ThrottleStats()
+		return stats.InFlight+stats.Queued[p] > before.InFlight+before.Queued[p]
+	}, time.Second, time.Millisecond)
+}
+
+func TestThrottlePriority(t *testing.T) {
+	s := newSlowAPI(t)
+
+	api := s.openAuthed(t, WithMaxConcurrentRequests(1))
+
+	var wg sync.WaitGroup
+
+	queue(t, &wg, api, "busy", PriorityNormal)
+	queue(t, &wg, api, "backfill-1", PriorityLow)
+	queue(t, &wg, api, "backfill-2", PriorityLow)
+	queue(t, &wg, api, "normal", PriorityNormal)
+	queue(t, &wg, api, "click", PriorityHigh)
+
+	assert.Equal(t, ThrottleStats{
+		InFlight: 1,
+		Queued:   map[Priority]int{PriorityLow: 2, PriorityNormal: 1, PriorityHigh: 1},
+	}, api.ThrottleStats())
+
+	close(s.gate)
+	wg.Wait()
+
+	assert.Equal(t, []string{"busy", "click", "normal", "backfill-1", "backfill-2"}, s.requested())
+	assert.Equal(t, 0, api.ThrottleStats().InFlight)
+}
+
+func TestThrottleAging(t *testing.T) {
+	s := newSlowAPI(t)
+
+	clock := newFakeClock()
+	api := s.openAuthed(t, WithClock(clock), WithMaxConcurrentRequests(1), WithPriorityAging(time.Minute))
+
+	var wg sync.WaitGroup
+
+	queue(t, &wg, api, "busy", PriorityNormal)
+	queue(t, &wg, api, "backfill", PriorityLow)
+
+	// waiting two minutes made it as urgent as a new high priority request
+	// and it's been waiting longer
+	clock.advance(2 * time.Minute)
+
+	queue(t, &wg, api, "click", PriorityHigh)
+
+	close(s.gate)
+	wg.Wait()
+
+	assert.Equal(t, []string{"busy", "backfill", "click"}, s.requested())
+}
+
+func TestThrottleCancelled(t *testing.T) {
+	s := newSlowAPI(t)
+
+	api := s.openAuthed(t, WithMaxConcurrentRequests(1))
+
+	var wg sync.WaitGroup
+
+	queue(t, &wg, api, "busy", PriorityNormal)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+
+	go func() {
+		var v interface{}
+		done <- api.GetJSON(ctx, "/data/slow?name=cancelled", &v)
+	}()
+
+	assert.Eventually(t, func() bool { return api.ThrottleStats().Queued[PriorityNormal] == 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, 0, api.ThrottleStats().Queued[PriorityNormal])
+
+	close(s.gate)
+	wg.Wait()
+
+	assert.Equal(t, []string{"busy"}, s.requested())
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	assert.Equal(t, PriorityNormal, PriorityFromContext(context.Background()))
+	assert.Equal(t, PriorityLow, PriorityFromContext(WithPriority(context.Background(), PriorityLow)))
+	assert.Equal(t, "high", PriorityHigh.String())
+}
+
+func TestGetWithRequestPriority(t *testing.T) {
+	s := newSlowAPI(t)
+
+	api := s.openAuthed(t, WithMaxConcurrentRequests(1))
+
+	var wg sync.WaitGroup
+
+	queue(t, &wg, api, "busy", PriorityNormal)
+	queue(t, &wg, api, "backfill-1", PriorityLow)
+	queue(t, &wg, api, "backfill-2", PriorityLow)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		_, err := api.Get("/data/slow?name=click", WithRequestPriority(PriorityHigh))
+		assert.NoError(t, err)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return api.ThrottleStats().Queued[PriorityHigh] == 1
+	}, time.Second, time.Millisecond)
+
+	close(s.gate)
+	wg.Wait()
+
+	assert.Equal(t, []string{"busy", "click", "backfill-1", "backfill-2"}, s.requested())
+}
@@ -0,0 +1,406 @@
+package irdata
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultProfile is the profile a creds file holding a single set of
+// credentials is read as
+const DefaultProfile = "default"
+
+// credsHeader starts creds files holding profiles.  The file is then made
+// of lines: the header, the check of the key, when it was written (sealed),
+// the sealed profile names and the sealed profiles, so the names can be
+// listed without decrypting any password.  The sealed payloads are
+// canonical JSON, see the README for the schema.
+const credsHeader = "irdata.creds.v4"
+
+// credsVersion is the version of the format written
+const credsVersion = 4
+
+// credsHeaderV3 starts the files whose sealed payloads are gob encoded,
+// which Go alone reads and writes
+const credsHeaderV3 = "irdata.creds.v3"
+
+// credsHeaderV2 starts the profile files written before the key check and
+// the time written were added, they lack those lines
+const credsHeaderV2 = "irdata.creds.v2"
+
+// credsHeaderPrefix starts every versioned creds file, it can't start the
+// base64 of the single creds format
+const credsHeaderPrefix = "irdata.creds."
+
+var namesContext = []byte("irdata.auth.names")
+var profilesContext = []byte("irdata.auth.profiles")
+var writtenContext = []byte("irdata.auth.written")
+var keyCheckContext = []byte("irdata.auth.keycheck")
+
+type credsFileT struct {
+	names    []string
+	profiles map[string]authDataT
+
+	// version is that of the format read, 1 for the single creds format,
+	// and written when the file was written if the format says
+	version int
+	written time.Time
+}
+
+// SaveProvidedCredsToProfile calls the provided function for the username
+// and password and saves them to authFilename as profile, encrypted with
+// the key within keyFilename.  Other profiles in the file are kept.
+func SaveProvidedCredsToProfile(keyFilename string, authFilename string, profile string, authSource CredsProvider) error {
+	if profile == "" {
+		return errors.New("profile must be named")
+	}
+
+	username, password := authSource.GetCreds()
+
+	aesgcm, err := credsCipher(keyFilename)
+	if err != nil {
+		return err
+	}
+
+	creds, err := readProfiles(aesgcm, authFilename, false)
+	if errors.Is(err, os.ErrNotExist) {
+		creds = &credsFileT{profiles: make(map[string]authDataT)}
+	} else if err != nil {
+		return err
+	}
+
+	creds.profiles[profile] = authDataT{
+		Username:        string(username),
+		EncodedPassword: encodePassword(username, password),
+	}
+
+	return writeProfiles(aesgcm, authFilename, creds)
+}
+
+// AuthWithProfile authenticates with the credentials saved as profile in
+// authFilename using the key in keyFilename
+func (i *Irdata) AuthWithProfile(keyFilename string, authFilename string, profile string) error {
+	aesgcm, err := credsCipher(keyFilename)
+	if err != nil {
+		return err
+	}
+
+	creds, err := readProfiles(aesgcm, authFilename, false)
+	if err != nil {
+		return err
+	}
+
+	authData, ok := creds.profiles[profile]
+	if !ok {
+		return fmt.Errorf("no profile %s in %s", profile, authFilename)
+	}
+
+	upgradeCreds(aesgcm, authFilename, creds)
+
+	return i.auth(authData)
+}
+
+// ListProfiles returns the names of the profiles saved in authFilename, in
+// order.  No passwords are decrypted.
+func ListProfiles(keyFilename string, authFilename string) ([]string, error) {
+	aesgcm, err := credsCipher(keyFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := readProfiles(aesgcm, authFilename, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return creds.names, nil
+}
+
+// DeleteProfile removes profile from authFilename
+func DeleteProfile(keyFilename string, authFilename string, profile string) error {
+	aesgcm, err := credsCipher(keyFilename)
+	if err != nil {
+		return err
+	}
+
+	creds, err := readProfiles(aesgcm, authFilename, false)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := creds.profiles[profile]; !ok {
+		return fmt.Errorf("no profile %s in %s", profile, authFilename)
+	}
+
+	delete(creds.profiles, profile)
+
+	return writeProfiles(aesgcm, authFilename, creds)
+}
+
+func credsCipher(keyFilename string) (cipher.AEAD, error) {
+	key, err := getKeyFile(keyFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+
+	// not a defer because we want to do this right away
+	shred(&key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// readProfiles reads authFilename, only the profile names if namesOnly.
+// A file in the single creds format is read as DefaultProfile.
+//
+// The errors match ErrWrongKey, ErrCredsCorrupted or
+// ErrUnsupportedCredsVersion when the file can't be decrypted.  Only
+// files with a key check tell a wrong key from a corrupted file, for the
+// others a file that fails to decrypt is taken to be of another key.
+func readProfiles(aesgcm cipher.AEAD, authFilename string, namesOnly bool) (*credsFileT, error) {
+	content, err := readTextFile("creds", authFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &credsFileT{profiles: make(map[string]authDataT)}
+
+	if !bytes.HasPrefix(content, []byte(credsHeaderPrefix)) {
+		var authData authDataT
+
+		if err := unseal(aesgcm, string(content), additionalContext, &authData, 1); err != nil {
+			return nil, fmt.Errorf("creds file %s: %w", authFilename, err)
+		}
+
+		creds.version = 1
+		creds.names = []string{DefaultProfile}
+		creds.profiles[DefaultProfile] = authData
+
+		return creds, nil
+	}
+
+	lines := strings.Split(string(content), "\n")
+
+	var sealed []string
+
+	// the sealed lines are those of the names and profiles plus, from v3,
+	// when the file was written
+	sealedLines := 3
+
+	switch header := strings.TrimSpace(lines[0]); header {
+	case credsHeaderV2:
+		creds.version = 2
+		sealed = lines[1:]
+		sealedLines = 2
+	case credsHeaderV3:
+		creds.version = 3
+		sealed = lines[2:]
+	case credsHeader:
+		creds.version = credsVersion
+		sealed = lines[2:]
+	default:
+		return nil, fmt.Errorf("creds file %s: %w", authFilename, &CredsVersionError{Header: header})
+	}
+
+	if len(sealed) != sealedLines {
+		return nil, fmt.Errorf("%s is not a valid creds file: %w", authFilename, ErrCredsCorrupted)
+	}
+
+	if creds.version > 2 && strings.TrimSpace(lines[1]) != keyCheck(aesgcm) {
+		return nil, fmt.Errorf("creds file %s: %w", authFilename, ErrWrongKey)
+	}
+
+	open := func(line string, context []byte, v interface{}) error {
+		err := unseal(aesgcm, line, context, v, creds.version)
+
+		// past the key check a file failing to decrypt was modified
+		if creds.version > 2 && errors.Is(err, ErrWrongKey) {
+			err = fmt.Errorf("%w: the key matches but the data doesn't decrypt", ErrCredsCorrupted)
+		}
+
+		if err != nil {
+			return fmt.Errorf("creds file %s: %w", authFilename, err)
+		}
+
+		return nil
+	}
+
+	if creds.version > 2 {
+		if err := open(sealed[0], writtenContext, &creds.written); err != nil {
+			return nil, err
+		}
+
+		sealed = sealed[1:]
+	}
+
+	if err := open(sealed[0], namesContext, &creds.names); err != nil {
+		return nil, err
+	}
+
+	if namesOnly {
+		return creds, nil
+	}
+
+	if err := open(sealed[1], profilesContext, &creds.profiles); err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+// upgradeCreds rewrites authFilename, read as creds, in the current format
+// if it's in an older one.  Failing to is only logged, the file still
+// reads as it is.
+func upgradeCreds(aesgcm cipher.AEAD, authFilename string, creds *credsFileT) {
+	if creds.version >= credsVersion {
+		return
+	}
+
+	if err := writeProfiles(aesgcm, authFilename, creds); err != nil {
+		packageLogger.WithFields(log.Fields{"file": authFilename, "err": err}).Warn("Failed to upgrade creds file")
+		return
+	}
+
+	packageLogger.WithFields(log.Fields{"file": authFilename, "from": creds.version, "to": credsVersion}).Info("Upgraded creds file")
+}
+
+// writeProfiles replaces authFilename atomically so a failure never leaves
+// a half written file behind.  An existing file keeps its permissions.
+func writeProfiles(aesgcm cipher.AEAD, authFilename string, creds *credsFileT) error {
+	names := make([]string, 0, len(creds.profiles))
+
+	for name := range creds.profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	sealedNames, err := seal(aesgcm, names, namesContext)
+	if err != nil {
+		return err
+	}
+
+	sealedProfiles, err := seal(aesgcm, creds.profiles, profilesContext)
+	if err != nil {
+		return err
+	}
+
+	sealedWritten, err := seal(aesgcm, time.Now().UTC(), writtenContext)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(authFilename), filepath.Base(authFilename)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if info, statErr := os.Stat(authFilename); statErr == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+
+	if err == nil {
+		_, err = fmt.Fprintf(tmp, "%s\n%s\n%s\n%s\n%s\n", credsHeader, keyCheck(aesgcm), sealedWritten, sealedNames, sealedProfiles)
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), authFilename)
+}
+
+// seal encodes v as canonical JSON and encrypts it, the payload is then
+// the base64 of the nonce followed by the ciphertext
+func seal(aesgcm cipher.AEAD, v interface{}, context []byte) (string, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := CanonicalJSON(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	defer zero(plain)
+
+	nonce, err := makeNonce(aesgcm)
+	if err != nil {
+		return "", err
+	}
+
+	data := aesgcm.Seal(nonce, nonce, plain, context)
+
+	return base64.StdEncoding.Strict().EncodeToString(data), nil
+}
+
+// unseal is the reverse of seal, for the payloads of format version, which
+// are gob encoded before v4.  Its errors match ErrCredsCorrupted, or
+// ErrWrongKey when the data doesn't decrypt.
+func unseal(aesgcm cipher.AEAD, sealed string, context []byte, v interface{}, version int) error {
+	data, err := base64.StdEncoding.Strict().DecodeString(strings.TrimSpace(sealed))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCredsCorrupted, err)
+	}
+
+	if len(data) < aesgcm.NonceSize() {
+		return fmt.Errorf("%w: creds are truncated", ErrCredsCorrupted)
+	}
+
+	plain, err := aesgcm.Open(nil, data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():], context)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWrongKey, err)
+	}
+
+	defer zero(plain)
+
+	if version < credsVersion {
+		err = gob.NewDecoder(bytes.NewReader(plain)).Decode(v)
+	} else {
+		err = json.Unmarshal(plain, v)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCredsCorrupted, err)
+	}
+
+	return nil
+}
+
+// keyCheck identifies the key of aesgcm without revealing it: the tag of
+// sealing nothing under a zero nonce, which no random nonce of seal repeats
+// in practice
+func keyCheck(aesgcm cipher.AEAD) string {
+	tag := aesgcm.Seal(nil, make([]byte, aesgcm.NonceSize()), nil, keyCheckContext)
+
+	return hex.EncodeToString(tag[:8])
+}
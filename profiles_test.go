@@ -0,0 +1,256 @@
+package irdata
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type teamCreds struct{}
+
+func (teamCreds) GetCreds() ([]byte, []byte) {
+	return []byte("team@example.com"), []byte("pitwall")
+}
+
+func copyLegacyCreds(t *testing.T) string {
+	credsFn := filepath.Join(t.TempDir(), "test.creds")
+
+	assert.NoError(t, os.WriteFile(credsFn, readFixture(t, "test.creds"), 0600))
+
+	return credsFn
+}
+
+func TestLegacyCredsAreDefaultProfile(t *testing.T) {
+	credsFn := copyLegacyCreds(t)
+
+	names, err := ListProfiles(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile}, names)
+
+	m := newMockAPI(t)
+
+	assert.NoError(t, m.open(t).AuthWithProfile(testKeyFilename, credsFn, DefaultProfile))
+	assert.Equal(t, 1, m.loginCount())
+}
+
+func TestProfiles(t *testing.T) {
+	credsFn := copyLegacyCreds(t)
+
+	assert.NoError(t, SaveProvidedCredsToProfile(testKeyFilename, credsFn, "team", teamCreds{}))
+	assert.NoError(t, SaveProvidedCredsToProfile(testKeyFilename, credsFn, "league", testCreds{}))
+
+	names, err := ListProfiles(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile, "league", "team"}, names)
+
+	creds, err := readProfiles(mustCredsCipher(t), credsFn, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "team@example.com", creds.profiles["team"].Username)
+	assert.Equal(t, encodePassword([]byte("team@example.com"), []byte("pitwall")), creds.profiles["team"].EncodedPassword)
+
+	// the legacy reader still finds the default profile
+	assert.Equal(t, string(testUsername), readCreds(testKeyFilename, credsFn).Username)
+
+	m := newMockAPI(t)
+
+	assert.NoError(t, m.open(t).AuthWithProfile(testKeyFilename, credsFn, "league"))
+	assert.Error(t, m.open(t).AuthWithProfile(testKeyFilename, credsFn, "nope"))
+
+	// team's creds aren't known to the mock
+	assert.ErrorIs(t, m.open(t).AuthWithProfile(testKeyFilename, credsFn, "team"), ErrBadCredentials)
+
+	assert.NoError(t, DeleteProfile(testKeyFilename, credsFn, "team"))
+	assert.Error(t, DeleteProfile(testKeyFilename, credsFn, "team"))
+
+	names, err = ListProfiles(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile, "league"}, names)
+
+	// nothing left behind by the rewrites
+	files, err := os.ReadDir(filepath.Dir(credsFn))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestSaveProfileToNewFile(t *testing.T) {
+	credsFn := filepath.Join(t.TempDir(), "new.creds")
+
+	assert.NoError(t, SaveProvidedCredsToProfile(testKeyFilename, credsFn, "team", teamCreds{}))
+
+	names, err := ListProfiles(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team"}, names)
+
+	assert.Error(t, SaveProvidedCredsToProfile(testKeyFilename, credsFn, "", teamCreds{}))
+}
+
+func mustCredsCipher(t *testing.T) cipher.AEAD {
+	aesgcm, err := credsCipher(testKeyFilename)
+	assert.NoError(t, err)
+
+	return aesgcm
+}
+
+// sealGob seals v the way the formats before v4 did
+func sealGob(aesgcm cipher.AEAD, v interface{}, context []byte) (string, error) {
+	buf := bytes.Buffer{}
+
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return "", err
+	}
+
+	nonce, err := makeNonce(aesgcm)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(aesgcm.Seal(nonce, nonce, buf.Bytes(), context)), nil
+}
+
+func TestLegacyCredsUpgrade(t *testing.T) {
+	credsFn := copyLegacyCreds(t)
+	assert.NoError(t, os.Chmod(credsFn, 0640))
+
+	info, err := InspectCredsFile(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Version)
+
+	m := newMockAPI(t)
+
+	assert.NoError(t, m.open(t).AuthWithCredsFromFile(testKeyFilename, credsFn))
+
+	info, err = InspectCredsFile(testKeyFilename, credsFn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, credsVersion, info.Version)
+		assert.Equal(t, string(testUsername), info.Username)
+	}
+
+	stat, err := os.Stat(credsFn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0640), stat.Mode().Perm())
+	}
+
+	// still the same creds
+	assert.NoError(t, m.open(t).AuthWithCredsFromFile(testKeyFilename, credsFn))
+	assert.Equal(t, 2, m.loginCount())
+
+	files, err := os.ReadDir(filepath.Dir(credsFn))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestProfilesV3Upgrade(t *testing.T) {
+	aesgcm := mustCredsCipher(t)
+
+	written, err := sealGob(aesgcm, time.Now().UTC(), writtenContext)
+	assert.NoError(t, err)
+
+	names, err := sealGob(aesgcm, []string{DefaultProfile}, namesContext)
+	assert.NoError(t, err)
+
+	profiles, err := sealGob(aesgcm, map[string]authDataT{
+		DefaultProfile: {Username: string(testUsername), EncodedPassword: encodePassword(testUsername, testPassword)},
+	}, profilesContext)
+	assert.NoError(t, err)
+
+	credsFn := filepath.Join(t.TempDir(), "v3.creds")
+	content := strings.Join([]string{credsHeaderV3, keyCheck(aesgcm), written, names, profiles}, "\n") + "\n"
+	assert.NoError(t, os.WriteFile(credsFn, []byte(content), 0600))
+
+	// listing doesn't rewrite the file
+	_, err = ListProfiles(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+
+	info, err := InspectCredsFile(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, info.Version)
+
+	m := newMockAPI(t)
+
+	assert.NoError(t, m.open(t).AuthWithProfile(testKeyFilename, credsFn, DefaultProfile))
+
+	info, err = InspectCredsFile(testKeyFilename, credsFn)
+	assert.NoError(t, err)
+	assert.Equal(t, credsVersion, info.Version)
+
+	// the payloads are JSON now
+	upgraded, err := os.ReadFile(credsFn)
+	assert.NoError(t, err)
+
+	var decoded []string
+	assert.NoError(t, unseal(aesgcm, strings.Split(string(upgraded), "\n")[3], namesContext, &decoded, credsVersion))
+	assert.Equal(t, []string{DefaultProfile}, decoded)
+}
+
+// TestExternalCreds writes a creds file following the schema in the README
+// without any of the package's code, as another tool would
+func TestExternalCreds(t *testing.T) {
+	encodedKey, err := os.ReadFile(testKeyFilename)
+	assert.NoError(t, err)
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedKey)))
+	assert.NoError(t, err)
+
+	block, err := aes.NewCipher(key)
+	assert.NoError(t, err)
+
+	aesgcm, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+
+	sealJSON := func(plain string, context string) string {
+		nonce := make([]byte, aesgcm.NonceSize())
+		_, err := rand.Read(nonce)
+		assert.NoError(t, err)
+
+		return base64.StdEncoding.EncodeToString(aesgcm.Seal(nonce, nonce, []byte(plain), []byte(context)))
+	}
+
+	tag := aesgcm.Seal(nil, make([]byte, aesgcm.NonceSize()), nil, []byte("irdata.auth.keycheck"))
+
+	content := strings.Join([]string{
+		"irdata.creds.v4",
+		hex.EncodeToString(tag[:8]),
+		sealJSON(`"2026-10-14T08:00:00Z"`, "irdata.auth.written"),
+		sealJSON(`["default"]`, "irdata.auth.names"),
+		sealJSON(fmt.Sprintf(`{"default":{"username":%q,"encoded_password":%q}}`, testUsername, encodePassword(testUsername, testPassword)), "irdata.auth.profiles"),
+	}, "\n")
+
+	credsFn := filepath.Join(t.TempDir(), "external.creds")
+	assert.NoError(t, os.WriteFile(credsFn, []byte(content), 0600))
+
+	info, err := InspectCredsFile(testKeyFilename, credsFn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, credsVersion, info.Version)
+		assert.Equal(t, time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC), info.Written)
+	}
+
+	m := newMockAPI(t)
+
+	assert.NoError(t, m.open(t).AuthWithCredsFromFile(testKeyFilename, credsFn))
+	assert.Equal(t, 1, m.loginCount())
+
+	// and the package writes the same layout
+	written := filepath.Join(t.TempDir(), "written.creds")
+	SaveProvidedCredsToFile(testKeyFilename, written, testCreds{})
+
+	ours, err := os.ReadFile(written)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(ours)), "\n")
+	if assert.Len(t, lines, 5) {
+		assert.Equal(t, "irdata.creds.v4", lines[0])
+		assert.Equal(t, hex.EncodeToString(tag[:8]), lines[1])
+	}
+}
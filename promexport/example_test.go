@@ -0,0 +1,26 @@
+package promexport_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/popmonkey/irdata"
+	"github.com/popmonkey/irdata/promexport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func Example() {
+	c := promexport.New()
+
+	api := irdata.Open(context.Background(), c.Options()...)
+	c.Observe(api)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	// authenticate and use api as usual, then serve the metrics
+	// http.ListenAndServe(":2112", nil)
+}
@@ -0,0 +1,290 @@
+// Package promexport exports the stats of an irdata instance as Prometheus
+// metrics.  It's a module of its own so irdata doesn't depend on
+// Prometheus.
+package promexport
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/popmonkey/irdata"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "irdata"
+
+// maxFamilies bounds the endpoint families labelled apart, the others are
+// labelled "other"
+const maxFamilies = 32
+
+// cacheSizeInterval is how long the cache size is reused between scrapes,
+// listing the cache reads the metadata of every entry
+const cacheSizeInterval = time.Minute
+
+var familySegment = regexp.MustCompile(`^[a-z_]+$`)
+
+// Collector is a prometheus.Collector for an irdata instance.  The events
+// come from the hooks of Options, the gauges from the Stats of the
+// instance passed to Observe.
+type Collector struct {
+	requests    *prometheus.CounterVec
+	retries     *prometheus.CounterVec
+	durations   *prometheus.HistogramVec
+	calls       *prometheus.CounterVec
+	payloadSize *prometheus.HistogramVec
+
+	rateLimitWaits   *prometheus.Desc
+	transportRetries *prometheus.Desc
+	droppedHookCalls *prometheus.Desc
+	cacheOperations  *prometheus.Desc
+	cacheTimeouts    *prometheus.Desc
+	rateLimit        *prometheus.Desc
+	rateLimitLeft    *prometheus.Desc
+	inFlight         *prometheus.Desc
+	cacheSize        *prometheus.Desc
+	cacheEntries     *prometheus.Desc
+
+	mu        sync.Mutex
+	api       *irdata.Irdata
+	families  map[string]bool
+	cacheSeen time.Time
+	cacheErr  error
+	sizeBytes int
+	entries   int
+}
+
+// New returns a collector, to be wired to an instance with Options and
+// Observe:
+//
+//	c := promexport.New()
+//	api := irdata.Open(ctx, c.Options()...)
+//	c.Observe(api)
+//	prometheus.MustRegister(c)
+func New() *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Requests sent, retries included.",
+		}, []string{"family", "status_class"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Requests that were retries.",
+		}, []string{"family"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "How long requests took.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, []string{"family", "status_class"}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "calls_total",
+			Help:      "Calls by how the cache served them.",
+		}, []string{"family", "cache"}),
+		payloadSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "payload_size_bytes",
+			Help:      "Size of the payloads returned.",
+			Buckets:   prometheus.ExponentialBuckets(256, 4, 10),
+		}, []string{"family", "cache"}),
+
+		rateLimitWaits:   desc("rate_limit_waits_total", "Times a request waited for the rate limit to reset."),
+		transportRetries: desc("transport_retries_total", "Requests retried after the connection dropped."),
+		droppedHookCalls: desc("dropped_hook_calls_total", "Hook calls dropped because the hooks fell behind."),
+		cacheOperations:  desc("cache_operations_total", "Operations of the cache backend."),
+		cacheTimeouts:    desc("cache_timeouts_total", "Cache operations that timed out."),
+		rateLimit:        desc("rate_limit", "Requests allowed per rate limit window."),
+		rateLimitLeft:    desc("rate_limit_remaining", "Requests left in the rate limit window."),
+		inFlight:         desc("requests_in_flight", "Requests in flight."),
+		cacheSize:        desc("cache_size_bytes", "Size of what's cached."),
+		cacheEntries:     desc("cache_entries", "Entries cached."),
+
+		families: make(map[string]bool),
+	}
+}
+
+func desc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+}
+
+// Options returns the options to pass to irdata.Open for the collector to
+// get the events of the instance
+func (c *Collector) Options() []irdata.Option {
+	return []irdata.Option{
+		irdata.WithRequestHook(c.ObserveRequest),
+		irdata.WithMetricHook(c.ObserveMetric),
+	}
+}
+
+// Observe sets the instance whose Stats the gauges report
+func (c *Collector) Observe(api *irdata.Irdata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.api = api
+}
+
+// ObserveRequest counts a request, see irdata.WithRequestHook
+func (c *Collector) ObserveRequest(event irdata.RequestEvent) {
+	family := c.family(event.URL)
+	class := statusClass(event.Status)
+
+	c.requests.WithLabelValues(family, class).Inc()
+	c.durations.WithLabelValues(family, class).Observe(event.Duration.Seconds())
+
+	if event.Attempt > 1 {
+		c.retries.WithLabelValues(family).Inc()
+	}
+}
+
+// ObserveMetric counts a call, see irdata.WithMetricHook
+func (c *Collector) ObserveMetric(event irdata.MetricEvent) {
+	family := c.family(event.URI)
+	outcome := event.Cache.String()
+
+	c.calls.WithLabelValues(family, outcome).Inc()
+
+	if event.Err == nil {
+		c.payloadSize.WithLabelValues(family, outcome).Observe(float64(event.Bytes))
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.retries.Describe(ch)
+	c.durations.Describe(ch)
+	c.calls.Describe(ch)
+	c.payloadSize.Describe(ch)
+
+	for _, d := range []*prometheus.Desc{
+		c.rateLimitWaits, c.transportRetries, c.droppedHookCalls, c.cacheOperations, c.cacheTimeouts,
+		c.rateLimit, c.rateLimitLeft, c.inFlight, c.cacheSize, c.cacheEntries,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.retries.Collect(ch)
+	c.durations.Collect(ch)
+	c.calls.Collect(ch)
+	c.payloadSize.Collect(ch)
+
+	c.mu.Lock()
+	api := c.api
+	c.mu.Unlock()
+
+	if api == nil {
+		return
+	}
+
+	stats := api.Stats()
+
+	counter := func(d *prometheus.Desc, v int64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v))
+	}
+
+	gauge := func(d *prometheus.Desc, v int) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(v))
+	}
+
+	counter(c.rateLimitWaits, stats.RateLimitWaits)
+	counter(c.transportRetries, stats.TransportRetries)
+	counter(c.droppedHookCalls, stats.DroppedHookCalls)
+	counter(c.cacheOperations, stats.Cache.Operations)
+	counter(c.cacheTimeouts, stats.Cache.TimedOut)
+
+	// nothing reported a rate limit yet
+	if stats.RateLimit.Limit > 0 {
+		gauge(c.rateLimit, stats.RateLimit.Limit)
+		gauge(c.rateLimitLeft, stats.RateLimit.Remaining)
+	}
+
+	gauge(c.inFlight, stats.Throttle.InFlight)
+
+	if size, entries, ok := c.cacheUsage(api); ok {
+		gauge(c.cacheSize, size)
+		gauge(c.cacheEntries, entries)
+	}
+}
+
+// cacheUsage returns the size and number of the cache entries, false if
+// there's no cache or it can't be listed
+func (c *Collector) cacheUsage(api *irdata.Irdata) (int, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cacheSeen.IsZero() || time.Since(c.cacheSeen) >= cacheSizeInterval {
+		c.cacheSeen = time.Now()
+
+		entries, err := api.CacheEntries()
+
+		c.cacheErr = err
+		c.sizeBytes = 0
+		c.entries = len(entries)
+
+		for _, entry := range entries {
+			c.sizeBytes += entry.Size
+		}
+	}
+
+	return c.sizeBytes, c.entries, c.cacheErr == nil
+}
+
+// family returns the endpoint family of uri, its first two path segments
+// (e.g. data/member), "link" for s3 links and chunks and "other" past
+// maxFamilies
+func (c *Collector) family(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "other"
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	if len(segments) < 2 || segments[0] != "data" {
+		if u.Host != "" {
+			return "link"
+		}
+
+		return "other"
+	}
+
+	if !familySegment.MatchString(segments[1]) {
+		return "other"
+	}
+
+	family := segments[0] + "/" + segments[1]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.families[family] {
+		if len(c.families) >= maxFamilies {
+			return "other"
+		}
+
+		c.families[family] = true
+	}
+
+	return family
+}
+
+// statusClass returns the class (2xx, 4xx...) of status, "error" for a
+// request that failed in transport
+func statusClass(status int) string {
+	if status == 0 {
+		return "error"
+	}
+
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,81 @@
+package promexport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/popmonkey/irdata"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	c := New()
+
+	c.ObserveRequest(irdata.RequestEvent{Method: http.MethodGet, URL: "https://members-ng.iracing.com/data/member/info", Attempt: 1, Status: 200, Duration: 80 * time.Millisecond})
+	c.ObserveRequest(irdata.RequestEvent{Method: http.MethodGet, URL: "https://members-ng.iracing.com/data/member/info", Attempt: 2, Status: 503})
+	c.ObserveRequest(irdata.RequestEvent{Method: http.MethodGet, URL: "https://bucket.s3.amazonaws.com/abc?X-Amz-Signature=[REDACTED]", Attempt: 1})
+	c.ObserveMetric(irdata.MetricEvent{URI: "/data/member/info?cust_id=1", Cache: irdata.CacheHit, Bytes: 512})
+	c.ObserveMetric(irdata.MetricEvent{URI: "/data/member/info", Cache: irdata.CacheMiss, Bytes: 2048})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.requests.WithLabelValues("data/member", "2xx")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.requests.WithLabelValues("data/member", "5xx")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.requests.WithLabelValues("link", "error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.retries.WithLabelValues("data/member")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.calls.WithLabelValues("data/member", "hit")))
+
+	// without an instance only the events are collected
+	assert.Equal(t, 0, testutil.CollectAndCount(c, "irdata_rate_limit_remaining"))
+
+	err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP irdata_calls_total Calls by how the cache served them.
+# TYPE irdata_calls_total counter
+irdata_calls_total{cache="hit",family="data/member"} 1
+irdata_calls_total{cache="miss",family="data/member"} 1
+`), "irdata_calls_total")
+	assert.NoError(t, err)
+}
+
+func TestCollectorStats(t *testing.T) {
+	c := New()
+
+	api := irdata.Open(context.Background(), c.Options()...)
+	api.EnableCacheBackend(irdata.NewMemoryCache())
+	c.Observe(api)
+
+	reg := prometheus.NewPedanticRegistry()
+	assert.NoError(t, reg.Register(c))
+
+	count, err := testutil.GatherAndCount(reg, "irdata_cache_entries", "irdata_requests_in_flight", "irdata_rate_limit_waits_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// nothing reported a rate limit yet
+	count, err = testutil.GatherAndCount(reg, "irdata_rate_limit_remaining")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestFamilyCardinality(t *testing.T) {
+	c := New()
+
+	assert.Equal(t, "data/results", c.family("/data/results/get?subsession_id=1"))
+	assert.Equal(t, "data/results", c.family("data/results/search_series"))
+	assert.Equal(t, "other", c.family("/data/Results%20x/get"))
+	assert.Equal(t, "other", c.family("/membersite/member/Home.do"))
+
+	for n := 0; n < 2*maxFamilies; n++ {
+		c.family(fmt.Sprintf("/data/f%s/get", strings.Repeat("x", n)))
+	}
+
+	assert.Len(t, c.families, maxFamilies)
+	assert.Equal(t, "other", c.family("/data/brand_new/get"))
+
+	// the families seen first keep their label
+	assert.Equal(t, "data/results", c.family("/data/results/get"))
+}
@@ -0,0 +1,122 @@
+package irdata
+
+import (
+	"sort"
+	"time"
+)
+
+// raceTimeFormat is the format of FirstSessionTime
+const raceTimeFormat = "15:04:05"
+
+// SessionsBetween returns the session starts the descriptor schedules from
+// from on and before to, in loc (UTC if nil).  The schedule repeats in UTC
+// so the sessions are the same instants whatever loc is: across a DST
+// change the local gap between two sessions grows or shrinks by the shift,
+// a start never lands on a local time that doesn't exist and two starts
+// with the same local time in the repeated hour are told apart by their
+// offset.
+func (d RaceTimeDescriptor) SessionsBetween(from time.Time, to time.Time, loc *time.Location) []time.Time {
+	var sessions []time.Time
+
+	for t, ok := d.nextSession(from); ok && t.Before(to); t, ok = d.nextSession(t.Add(time.Nanosecond)) {
+		sessions = append(sessions, inLocation(t, loc))
+	}
+
+	return sessions
+}
+
+// SessionsOn returns the session starts on the day of date in loc (UTC if
+// nil), which may be 23 or 25 hours long
+func (d RaceTimeDescriptor) SessionsOn(date time.Time, loc *time.Location) []time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	year, month, day := date.In(loc).Date()
+
+	return d.SessionsBetween(time.Date(year, month, day, 0, 0, 0, 0, loc), time.Date(year, month, day+1, 0, 0, 0, 0, loc), loc)
+}
+
+// NextSessions returns the next n session starts at or after t, in loc (UTC
+// if nil), fewer if the schedule ends first.  Only the days they fall on
+// are looked at.
+func (d RaceTimeDescriptor) NextSessions(t time.Time, n int, loc *time.Location) []time.Time {
+	var sessions []time.Time
+
+	for len(sessions) < n {
+		next, ok := d.nextSession(t)
+		if !ok {
+			break
+		}
+
+		sessions = append(sessions, inLocation(next, loc))
+		t = next.Add(time.Nanosecond)
+	}
+
+	return sessions
+}
+
+// nextSession returns the first session start at or after t
+func (d RaceTimeDescriptor) nextSession(t time.Time) (time.Time, bool) {
+	if !d.Repeating {
+		times := append([]time.Time(nil), d.SessionTimes...)
+
+		sort.Slice(times, func(a, b int) bool { return times[a].Before(times[b]) })
+
+		for _, session := range times {
+			if !session.Before(t) {
+				return session.UTC(), true
+			}
+		}
+
+		return time.Time{}, false
+	}
+
+	start, err := time.Parse(seasonDateFormat, d.StartDate)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	first, err := time.Parse(raceTimeFormat, d.FirstSessionTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	offset := first.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))
+	repeat := time.Duration(d.RepeatMinutes) * time.Minute
+
+	days := append([]int(nil), d.DayOffset...)
+	sort.Ints(days)
+
+	for _, day := range days {
+		dayStart := start.AddDate(0, 0, day)
+		dayFirst := dayStart.Add(offset)
+
+		// the sessions of a day repeat until the next day begins
+		dayEnd := dayStart.Add(24 * time.Hour)
+
+		if !t.After(dayFirst) {
+			return dayFirst, true
+		}
+
+		if repeat <= 0 || !t.Before(dayEnd) {
+			continue
+		}
+
+		k := (t.Sub(dayFirst) + repeat - 1) / repeat
+
+		if session := dayFirst.Add(k * repeat); session.Before(dayEnd) {
+			return session, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t.UTC()
+	}
+
+	return t.In(loc)
+}
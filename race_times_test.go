@@ -0,0 +1,167 @@
+package irdata
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func formatTimes(times []time.Time) []string {
+	var formatted []string
+
+	for _, t := range times {
+		formatted = append(formatted, t.Format(time.RFC3339))
+	}
+
+	return formatted
+}
+
+func TestRaceTimeSessionsOn(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	london, err := time.LoadLocation("Europe/London")
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name       string
+		descriptor RaceTimeDescriptor
+		day        time.Time
+		loc        *time.Location
+		count      int
+		first      []string
+	}{
+		{
+			// 02:00 local doesn't exist, the 07:00Z gap is 3 hours on the clock
+			name:       "spring forward every 2 hours",
+			descriptor: RaceTimeDescriptor{Repeating: true, StartDate: "2024-03-10", DayOffset: []int{0, 1}, FirstSessionTime: "00:00:00", RepeatMinutes: 120},
+			day:        time.Date(2024, 3, 10, 12, 0, 0, 0, newYork),
+			loc:        newYork,
+			count:      11,
+			first:      []string{"2024-03-10T01:00:00-05:00", "2024-03-10T04:00:00-04:00", "2024-03-10T06:00:00-04:00"},
+		},
+		{
+			name:       "fall back every 2 hours",
+			descriptor: RaceTimeDescriptor{Repeating: true, StartDate: "2024-11-03", DayOffset: []int{0, 1}, FirstSessionTime: "01:00:00", RepeatMinutes: 120},
+			day:        time.Date(2024, 11, 3, 12, 0, 0, 0, newYork),
+			loc:        newYork,
+			count:      12,
+			first:      []string{"2024-11-03T01:00:00-04:00", "2024-11-03T02:00:00-05:00", "2024-11-03T04:00:00-05:00"},
+		},
+		{
+			// 01:30 local happens twice, an hour apart
+			name:       "fall back every hour",
+			descriptor: RaceTimeDescriptor{Repeating: true, StartDate: "2024-11-03", DayOffset: []int{0}, FirstSessionTime: "00:30:00", RepeatMinutes: 60},
+			day:        time.Date(2024, 11, 3, 12, 0, 0, 0, newYork),
+			loc:        newYork,
+			count:      20,
+			first:      []string{"2024-11-03T00:30:00-04:00", "2024-11-03T01:30:00-04:00", "2024-11-03T01:30:00-05:00", "2024-11-03T02:30:00-05:00"},
+		},
+		{
+			name:       "spring forward in London",
+			descriptor: RaceTimeDescriptor{Repeating: true, StartDate: "2024-03-31", DayOffset: []int{0}, FirstSessionTime: "00:15:00", RepeatMinutes: 120},
+			day:        time.Date(2024, 3, 31, 12, 0, 0, 0, london),
+			loc:        london,
+			count:      12,
+			first:      []string{"2024-03-31T00:15:00Z", "2024-03-31T03:15:00+01:00", "2024-03-31T05:15:00+01:00"},
+		},
+		{
+			name:       "UTC",
+			descriptor: RaceTimeDescriptor{Repeating: true, StartDate: "2024-03-10", DayOffset: []int{0}, FirstSessionTime: "00:00:00", RepeatMinutes: 120},
+			day:        time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+			count:      12,
+			first:      []string{"2024-03-10T00:00:00Z", "2024-03-10T02:00:00Z"},
+		},
+		{
+			name:       "not a race day",
+			descriptor: RaceTimeDescriptor{Repeating: true, StartDate: "2024-03-10", DayOffset: []int{0}, FirstSessionTime: "00:00:00", RepeatMinutes: 120},
+			day:        time.Date(2024, 3, 12, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "set times",
+			descriptor: RaceTimeDescriptor{SessionTimes: []time.Time{
+				time.Date(2024, 11, 3, 18, 0, 0, 0, time.UTC),
+				time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC),
+				time.Date(2024, 11, 4, 18, 0, 0, 0, time.UTC),
+			}},
+			day:   time.Date(2024, 11, 3, 12, 0, 0, 0, newYork),
+			loc:   newYork,
+			count: 2,
+			first: []string{"2024-11-03T01:30:00-04:00", "2024-11-03T13:00:00-05:00"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions := tc.descriptor.SessionsOn(tc.day, tc.loc)
+
+			assert.Len(t, sessions, tc.count)
+
+			if len(sessions) >= len(tc.first) {
+				assert.Equal(t, tc.first, formatTimes(sessions[:len(tc.first)]))
+			}
+
+			for n := 1; n < len(sessions); n++ {
+				assert.True(t, sessions[n].After(sessions[n-1]))
+			}
+		})
+	}
+}
+
+func TestRaceTimeNextSessions(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	descriptor := RaceTimeDescriptor{Repeating: true, StartDate: "2024-03-09", DayOffset: []int{1, 0}, FirstSessionTime: "00:00:00", RepeatMinutes: 120}
+
+	for _, tc := range []struct {
+		name  string
+		after time.Time
+		n     int
+		want  []string
+	}{
+		{
+			name:  "across spring forward",
+			after: time.Date(2024, 3, 10, 1, 30, 0, 0, newYork),
+			n:     3,
+			want:  []string{"2024-03-10T04:00:00-04:00", "2024-03-10T06:00:00-04:00", "2024-03-10T08:00:00-04:00"},
+		},
+		{
+			name:  "on a session",
+			after: time.Date(2024, 3, 10, 6, 0, 0, 0, time.UTC),
+			n:     1,
+			want:  []string{"2024-03-10T01:00:00-05:00"},
+		},
+		{
+			name:  "before the week",
+			after: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			n:     2,
+			want:  []string{"2024-03-08T19:00:00-05:00", "2024-03-08T21:00:00-05:00"},
+		},
+		{
+			name:  "end of the week",
+			after: time.Date(2024, 3, 10, 21, 0, 0, 0, time.UTC),
+			n:     5,
+			want:  []string{"2024-03-10T18:00:00-04:00"},
+		},
+		{
+			name:  "after the week",
+			after: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+			n:     5,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, formatTimes(descriptor.NextSessions(tc.after, tc.n, newYork)))
+		})
+	}
+}
+
+func TestRaceTimeBadDescriptor(t *testing.T) {
+	descriptor := RaceTimeDescriptor{Repeating: true, StartDate: "soon", DayOffset: []int{0}, FirstSessionTime: "00:00:00", RepeatMinutes: 120}
+
+	assert.Empty(t, descriptor.NextSessions(time.Time{}, 3, nil))
+
+	descriptor = RaceTimeDescriptor{Repeating: true, StartDate: "2024-03-10", DayOffset: []int{0}, FirstSessionTime: "00:00:00"}
+
+	assert.Equal(t, []string{"2024-03-10T00:00:00Z"}, formatTimes(descriptor.NextSessions(time.Time{}, 3, nil)))
+}
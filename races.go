@@ -0,0 +1,249 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// raceEventType is the event type of races
+const raceEventType = 5
+
+// RaceQuery is what SearchRaces looks for.  Either SeasonYear and
+// SeasonQuarter or the start range must be provided, empty filters match
+// everything.
+//
+// The season, start range, SeriesIDs, OfficialOnly and EventTypes are sent
+// to /data/results/search_series (and checked again client side where the
+// server is known to leak), a search per series and 90 day window.
+// TrackIDs, CarIDs and CarClassIDs are applied client side to what those
+// searches return.
+type RaceQuery struct {
+	SeasonYear      int
+	SeasonQuarter   int
+	StartRangeBegin time.Time
+	StartRangeEnd   time.Time
+
+	SeriesIDs    []int64
+	OfficialOnly bool
+
+	// EventTypes are the event types searched, races if empty
+	EventTypes []int
+
+	// TrackIDs are track configurations, a track has one per layout
+	TrackIDs []int64
+
+	// CarIDs match sessions whose season raced a car class holding one of
+	// the cars, CarClassIDs those whose season raced one of the classes.
+	// The classes are those of the season of each session, not the
+	// current ones of its series.
+	CarIDs      []int64
+	CarClassIDs []int64
+}
+
+// Race is a search result along with the catalog entries of what it was
+// raced with
+type Race struct {
+	SearchResult
+
+	// Track and Series are the zero value if the catalogs don't list them
+	Track  Track
+	Series Series
+
+	// CarClasses are the car classes of the season, only resolved when
+	// filtering by car or car class
+	CarClasses []CarClass
+}
+
+// Races are the races found by SearchRaces and how they were found
+type Races struct {
+	Rows []Race
+
+	// Meta counts the searches made and the rows fetched and returned over
+	// all of them
+	Meta SearchMeta
+}
+
+// SearchRaces searches official series results matching q and joins them
+// with the track, series and car class catalogs.  Besides the searches it
+// reads the track and series catalogs and, when filtering by car or car
+// class, the car class catalog and the past seasons of every series found,
+// all of them cached when the cache is enabled.
+func (i *Irdata) SearchRaces(ctx context.Context, q RaceQuery) (*Races, error) {
+	if q.SeasonYear == 0 && q.StartRangeBegin.IsZero() {
+		return nil, errors.New("must provide season year and quarter or a start range")
+	}
+
+	eventTypes := q.EventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = []int{raceEventType}
+	}
+
+	seriesIDs := q.SeriesIDs
+	if len(seriesIDs) == 0 {
+		seriesIDs = []int64{0}
+	}
+
+	races := &Races{}
+
+	var rows []SearchResult
+
+	for _, seriesID := range seriesIDs {
+		results, err := i.SearchSeriesResults(ctx, SearchSeriesParams{
+			SeasonYear:      q.SeasonYear,
+			SeasonQuarter:   q.SeasonQuarter,
+			StartRangeBegin: q.StartRangeBegin,
+			StartRangeEnd:   q.StartRangeEnd,
+			SeriesID:        seriesID,
+			OfficialOnly:    q.OfficialOnly,
+			EventTypes:      eventTypes,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		races.Meta.Windows += results.Meta.Windows
+		races.Meta.Fetched += results.Meta.Fetched
+
+		for _, row := range results.Rows {
+			if len(q.TrackIDs) == 0 || containsID(q.TrackIDs, row.Track.TrackID) {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	tracks, err := i.GetTracks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := i.GetSeries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var seasonClasses map[int64][]CarClass
+	var wantedClasses map[int64]bool
+
+	if len(q.CarIDs) > 0 || len(q.CarClassIDs) > 0 {
+		classes, err := i.GetCarClasses(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		wantedClasses = carClassesWith(classes.Items, q.CarIDs, q.CarClassIDs)
+
+		if seasonClasses, err = i.seasonCarClasses(ctx, rows, classes.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	trackByID := make(map[int64]Track)
+	for _, track := range tracks.Items {
+		trackByID[track.TrackID] = track
+	}
+
+	seriesByID := make(map[int64]Series)
+	for _, s := range series.Items {
+		seriesByID[s.SeriesID] = s
+	}
+
+	for _, row := range rows {
+		race := Race{
+			SearchResult: row,
+			Track:        trackByID[row.Track.TrackID],
+			Series:       seriesByID[row.SeriesID],
+		}
+
+		if wantedClasses != nil {
+			race.CarClasses = seasonClasses[row.SeasonID]
+
+			if !racedAnyClass(race.CarClasses, wantedClasses) {
+				continue
+			}
+		}
+
+		races.Rows = append(races.Rows, race)
+	}
+
+	races.Meta.Returned = len(races.Rows)
+
+	return races, nil
+}
+
+// seasonCarClasses returns the car classes of the seasons of rows from the
+// past seasons of their series
+func (i *Irdata) seasonCarClasses(ctx context.Context, rows []SearchResult, classes []CarClass) (map[int64][]CarClass, error) {
+	classByID := make(map[int64]CarClass)
+	for _, class := range classes {
+		classByID[class.CarClassID] = class
+	}
+
+	seasonClasses := make(map[int64][]CarClass)
+	seen := make(map[int64]bool)
+
+	for _, row := range rows {
+		if seen[row.SeriesID] {
+			continue
+		}
+
+		seen[row.SeriesID] = true
+
+		past, err := i.GetSeriesPastSeasons(ctx, row.SeriesID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, season := range past.Seasons {
+			for _, class := range season.CarClasses {
+				c, ok := classByID[class.CarClassID]
+				if !ok {
+					c = CarClass{CarClassID: class.CarClassID}
+				}
+
+				seasonClasses[season.SeasonID] = append(seasonClasses[season.SeasonID], c)
+			}
+		}
+	}
+
+	return seasonClasses, nil
+}
+
+// carClassesWith returns classIDs and the classes holding one of carIDs
+func carClassesWith(classes []CarClass, carIDs []int64, classIDs []int64) map[int64]bool {
+	wanted := make(map[int64]bool)
+
+	for _, id := range classIDs {
+		wanted[id] = true
+	}
+
+	for _, class := range classes {
+		for _, car := range class.CarsInClass {
+			if containsID(carIDs, car.CarID) {
+				wanted[class.CarClassID] = true
+			}
+		}
+	}
+
+	return wanted
+}
+
+func racedAnyClass(classes []CarClass, wanted map[int64]bool) bool {
+	for _, class := range classes {
+		if wanted[class.CarClassID] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+
+	return false
+}
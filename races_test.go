@@ -0,0 +1,150 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the Porsche Cup moved from the 991 to the 992 between the seasons of the
+// fixtures
+func newRacesMock(t *testing.T) *mockAPI {
+	t.Helper()
+
+	search, err := os.ReadFile(filepath.Join("testdata", "races_porsche_cup.json"))
+	assert.NoError(t, err)
+
+	pastSeasons, err := os.ReadFile(filepath.Join("testdata", "past_seasons_porsche_cup.json"))
+	assert.NoError(t, err)
+
+	m := newMockAPI(t)
+
+	m.handleChunked("/data/results/search_series", func(r *http.Request) []string {
+		assert.Equal(t, "228", r.URL.Query().Get("series_id"))
+		assert.Equal(t, "5", r.URL.Query().Get("event_types"))
+
+		return []string{string(search)}
+	})
+
+	m.handle("/data/series/past_seasons", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "228", r.URL.Query().Get("series_id"))
+
+		w.Write(pastSeasons)
+	})
+
+	m.handleLinked("/data/track/get", `[
+		{"track_id":163,"track_name":"Circuit de Spa-Francorchamps","config_name":"Grand Prix Pits","location":"Stavelot, Belgium"},
+		{"track_id":165,"track_name":"Circuit de Spa-Francorchamps","config_name":"Endurance","location":"Stavelot, Belgium"},
+		{"track_id":240,"track_name":"Autodromo Nazionale Monza","config_name":"Grand Prix"}]`)
+
+	m.handleLinked("/data/series/get", `[{"series_id":228,"series_name":"Porsche Tag Heuer Esports Supercup","series_short_name":"Porsche Cup"}]`)
+
+	m.handleLinked("/data/carclass/get", `[
+		{"car_class_id":2100,"name":"Porsche 911 GT3 Cup (991)","short_name":"991 Cup","cars_in_class":[{"car_id":119,"retired":true}]},
+		{"car_class_id":3104,"name":"Porsche 911 GT3 Cup (992)","short_name":"992 Cup","cars_in_class":[{"car_id":143}]}]`)
+
+	return m
+}
+
+func raceIDs(races *Races) []int64 {
+	var ids []int64
+
+	for _, race := range races.Rows {
+		ids = append(ids, race.SubsessionID)
+	}
+
+	return ids
+}
+
+func TestSearchRaces(t *testing.T) {
+	begin := time.Date(2023, 10, 15, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name  string
+		query RaceQuery
+		want  []int64
+	}{
+		{
+			name:  "track",
+			query: RaceQuery{TrackIDs: []int64{163, 165}, OfficialOnly: true},
+			want:  []int64{101, 201, 203},
+		},
+		{
+			name:  "car of the new class",
+			query: RaceQuery{TrackIDs: []int64{163, 165}, CarIDs: []int64{143}, OfficialOnly: true},
+			want:  []int64{201, 203},
+		},
+		{
+			name:  "old class",
+			query: RaceQuery{TrackIDs: []int64{163, 165}, CarClassIDs: []int64{2100}},
+			want:  []int64{101},
+		},
+		{
+			name:  "either class",
+			query: RaceQuery{CarIDs: []int64{119}, CarClassIDs: []int64{3104}},
+			want:  []int64{101, 102, 201, 202, 203},
+		},
+		{
+			name:  "car not raced",
+			query: RaceQuery{CarIDs: []int64{67}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newRacesMock(t)
+			api := m.openAuthed(t)
+
+			q := tc.query
+			q.SeriesIDs = []int64{228}
+			q.StartRangeBegin = begin
+			q.StartRangeEnd = begin.Add(89 * 24 * time.Hour)
+
+			races, err := api.SearchRaces(context.Background(), q)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, raceIDs(races))
+			assert.Equal(t, SearchMeta{Windows: 1, Fetched: 5, Returned: len(tc.want)}, races.Meta)
+
+			if len(q.CarIDs) == 0 && len(q.CarClassIDs) == 0 {
+				assert.Equal(t, 0, m.hitCount("/data/series/past_seasons"))
+			}
+		})
+	}
+}
+
+func TestSearchRacesNames(t *testing.T) {
+	m := newRacesMock(t)
+	api := m.openAuthed(t)
+
+	races, err := api.SearchRaces(context.Background(), RaceQuery{
+		SeasonYear:    2024,
+		SeasonQuarter: 1,
+		SeriesIDs:     []int64{228},
+		TrackIDs:      []int64{165},
+		CarIDs:        []int64{143},
+	})
+	assert.NoError(t, err)
+
+	if assert.Len(t, races.Rows, 1) {
+		race := races.Rows[0]
+
+		assert.Equal(t, "Endurance", race.Track.ConfigName)
+		assert.Equal(t, "Stavelot, Belgium", race.Track.Location)
+		assert.Equal(t, "Porsche Tag Heuer Esports Supercup", race.Series.SeriesName)
+
+		if assert.Len(t, race.CarClasses, 1) {
+			assert.Equal(t, "Porsche 911 GT3 Cup (992)", race.CarClasses[0].Name)
+		}
+	}
+
+	assert.Equal(t, SearchMeta{Windows: 1, Fetched: 5, Returned: 1}, races.Meta)
+	assert.Equal(t, 1, m.hitCount("/data/series/past_seasons"))
+}
+
+func TestSearchRacesNeedsRange(t *testing.T) {
+	_, err := i.SearchRaces(context.Background(), RaceQuery{TrackIDs: []int64{163}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rateBudgetT is what RateBudget and ReserveRequests know on top of the
+// last RateLimit, guarded by rateLimitMu
+type rateBudgetT struct {
+	// cadence is the shortest time seen between two resets, 0 until two
+	// were seen
+	cadence time.Duration
+
+	// reserved is how many requests ReserveRequests handed out in the
+	// window ending at reservedReset that haven't been made yet
+	reserved      int
+	reservedReset time.Time
+}
+
+// note updates the budget for the rate limit rl reported after
+// prev
+func (b *rateBudgetT) note(prev RateLimit, rl RateLimit) {
+	if prev.Limit > 0 && rl.Reset.After(prev.Reset) {
+		// resets skipped while idle only make the gap longer
+		if gap := rl.Reset.Sub(prev.Reset); b.cadence == 0 || gap < b.cadence {
+			b.cadence = gap
+		}
+	}
+
+	if !b.reservedReset.Equal(rl.Reset) {
+		b.reserved, b.reservedReset = 0, rl.Reset
+		return
+	}
+
+	// the requests made since spend the reservations
+	if used := prev.Remaining - rl.Remaining; used > 0 {
+		b.reserved -= used
+		if b.reserved < 0 {
+			b.reserved = 0
+		}
+	}
+}
+
+// headroom is how many requests are left in the window rl.Reset ends, or
+// the one after when it's over, and when that window ends, the zero time
+// when that's unknown
+func (b *rateBudgetT) headroom(rl RateLimit, now time.Time) (int, time.Time) {
+	remaining, reset := rl.Remaining, rl.Reset
+
+	if !reset.After(now) {
+		remaining, reset = rl.Limit, time.Time{}
+
+		if b.cadence > 0 {
+			reset = rl.Reset.Add((now.Sub(rl.Reset)/b.cadence + 1) * b.cadence)
+		}
+	}
+
+	if b.reservedReset.Equal(reset) {
+		remaining -= b.reserved
+	}
+
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return remaining, reset
+}
+
+// RateBudget returns how many requests can be made in the next window
+// without running into iRacing's rate limit: what's left of the current
+// limit, less what ReserveRequests handed out, and the whole limit for
+// every reset within window.  The resets after the next are assumed to
+// come as often as they were seen to.
+//
+// It fails with ErrNoRateLimit until a response reported the rate limit.
+// WithMaxConcurrentRequests only caps how many requests are in flight and
+// doesn't change the budget.
+func (i *Irdata) RateBudget(ctx context.Context, window time.Duration) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	i.rateLimitMu.Lock()
+	defer i.rateLimitMu.Unlock()
+
+	rl := i.rateLimit
+	if rl.Limit <= 0 {
+		return 0, ErrNoRateLimit
+	}
+
+	now := i.clock.Now()
+	end := now.Add(window)
+
+	budget, reset := i.rateBudget.headroom(rl, now)
+
+	if reset.IsZero() || !end.After(reset) {
+		return budget, nil
+	}
+
+	resets := 1
+	if cadence := i.rateBudget.cadence; cadence > 0 {
+		resets = int((end.Sub(reset)-1)/cadence) + 1
+	}
+
+	return budget + resets*rl.Limit, nil
+}
+
+// ReserveRequests waits until n requests can be made in the current rate
+// limit window and sets them aside, so concurrent callers don't plan on the
+// same headroom.  The reservation is spent by the requests made after it
+// and lapses when the window ends.  It returns right away until a response
+// reported the rate limit, and fails for more requests than the limit
+// allows in a window or once ctx is done.
+func (i *Irdata) ReserveRequests(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	for {
+		i.rateLimitMu.Lock()
+
+		rl := i.rateLimit
+		if rl.Limit <= 0 {
+			i.rateLimitMu.Unlock()
+			return nil
+		}
+
+		if n > rl.Limit {
+			i.rateLimitMu.Unlock()
+			return fmt.Errorf("unable to reserve %d requests, the rate limit is %d", n, rl.Limit)
+		}
+
+		now := i.clock.Now()
+
+		budget, reset := i.rateBudget.headroom(rl, now)
+
+		if budget >= n {
+			if !i.rateBudget.reservedReset.Equal(reset) {
+				i.rateBudget.reserved, i.rateBudget.reservedReset = 0, reset
+			}
+
+			i.rateBudget.reserved += n
+			i.rateLimitMu.Unlock()
+
+			return nil
+		}
+
+		i.rateLimitMu.Unlock()
+
+		// without a known reset wait for a response to report one
+		wait := time.Second
+		if !reset.IsZero() && reset.Sub(now) > wait {
+			wait = reset.Sub(now)
+		}
+
+		i.logger.WithFields(log.Fields{"n": n, "budget": budget, "reset": reset}).Info("Waiting for rate limit headroom")
+
+		if err := i.clock.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// tunedConcurrency is concurrency lowered to what's left of the current
+// rate limit window, so batches don't start more requests than can be made
+func (i *Irdata) tunedConcurrency(ctx context.Context, concurrency int) int {
+	budget, err := i.RateBudget(ctx, 0)
+	if err != nil || budget >= concurrency {
+		return concurrency
+	}
+
+	if budget < 1 {
+		return 1
+	}
+
+	return budget
+}
@@ -0,0 +1,148 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockRateLimit answers /data/ping with the rate limit it's set to
+type mockRateLimit struct {
+	mu        sync.Mutex
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+func (rl *mockRateLimit) set(limit int, remaining int, reset time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.limit, rl.remaining, rl.reset = limit, remaining, reset
+}
+
+func (rl *mockRateLimit) register(m *mockAPI) {
+	m.handle("/data/ping", func(w http.ResponseWriter, r *http.Request) {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+
+		w.Header().Set("x-ratelimit-limit", fmt.Sprint(rl.limit))
+		w.Header().Set("x-ratelimit-remaining", fmt.Sprint(rl.remaining))
+		w.Header().Set("x-ratelimit-reset", fmt.Sprint(rl.reset.Unix()))
+		w.Write([]byte(`{}`))
+	})
+}
+
+func TestRateBudget(t *testing.T) {
+	m := newMockAPI(t)
+
+	rl := &mockRateLimit{}
+	rl.register(m)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock))
+
+	ctx := context.Background()
+	start := clock.Now()
+
+	_, err := api.RateBudget(ctx, time.Minute)
+	assert.ErrorIs(t, err, ErrNoRateLimit)
+
+	ping := func(remaining int, reset time.Time) {
+		rl.set(10, remaining, reset)
+
+		_, err := api.Get("/data/ping")
+		assert.NoError(t, err)
+	}
+
+	budget := func(window time.Duration) int {
+		n, err := api.RateBudget(ctx, window)
+		assert.NoError(t, err)
+
+		return n
+	}
+
+	ping(4, start.Add(time.Minute))
+
+	assert.Equal(t, 4, budget(30*time.Second))
+	assert.Equal(t, 14, budget(90*time.Second))
+
+	// how often it resets isn't known yet
+	assert.Equal(t, 14, budget(10*time.Minute))
+
+	clock.advance(65 * time.Second)
+	ping(9, start.Add(2*time.Minute))
+
+	// resets every minute from 12:02 to 12:11
+	assert.Equal(t, 109, budget(10*time.Minute))
+
+	// the window reported is over at its reset
+	clock.advance(55 * time.Second)
+	assert.Equal(t, 10, budget(0))
+	assert.Equal(t, 10, budget(time.Minute))
+	assert.Equal(t, 20, budget(time.Minute+time.Nanosecond))
+	assert.Equal(t, 40, budget(3*time.Minute+time.Second))
+
+	assert.Equal(t, 4, api.tunedConcurrency(ctx, 4))
+
+	ping(1, start.Add(3*time.Minute))
+	assert.Equal(t, 1, api.tunedConcurrency(ctx, 4))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = api.RateBudget(ctx, time.Minute)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReserveRequests(t *testing.T) {
+	m := newMockAPI(t)
+
+	rl := &mockRateLimit{}
+	rl.register(m)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock))
+
+	ctx := context.Background()
+	start := clock.Now()
+
+	// nothing to go by
+	assert.NoError(t, api.ReserveRequests(ctx, 100))
+
+	rl.set(10, 3, start.Add(time.Minute))
+	_, err := api.Get("/data/ping")
+	assert.NoError(t, err)
+
+	assert.NoError(t, api.ReserveRequests(ctx, 2))
+
+	n, _ := api.RateBudget(ctx, 0)
+	assert.Equal(t, 1, n)
+
+	// a request spends a reservation rather than the headroom left
+	rl.set(10, 2, start.Add(time.Minute))
+	_, err = api.Get("/data/ping")
+	assert.NoError(t, err)
+
+	n, _ = api.RateBudget(ctx, 0)
+	assert.Equal(t, 1, n)
+
+	// waits for the reset
+	assert.NoError(t, api.ReserveRequests(ctx, 2))
+	assert.Equal(t, []time.Duration{time.Minute}, clock.slept())
+
+	n, _ = api.RateBudget(ctx, 0)
+	assert.Equal(t, 8, n)
+
+	assert.Error(t, api.ReserveRequests(ctx, 11))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	assert.ErrorIs(t, api.ReserveRequests(ctx, 9), context.Canceled)
+}
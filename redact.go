@@ -0,0 +1,139 @@
+package irdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// RedactionPolicy decides which personal data is masked in log output.
+// Secrets (passwords, cookies, auth codes and link signatures) are always
+// removed whatever the policy.
+type RedactionPolicy struct {
+	// MaskPII hashes emails, usernames and display names
+	MaskPII bool
+
+	// MaskCustIDs hashes customer ids
+	MaskCustIDs bool
+}
+
+// defaultRedaction masks PII but leaves customer ids, which are public on
+// iRacing, readable for debugging
+var defaultRedaction = RedactionPolicy{MaskPII: true}
+
+var redaction = struct {
+	mu     sync.Mutex
+	policy RedactionPolicy
+}{policy: defaultRedaction}
+
+// SetLogRedaction sets the policy applied to everything the package logs
+func SetLogRedaction(policy RedactionPolicy) {
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+
+	redaction.policy = policy
+}
+
+func logRedaction() RedactionPolicy {
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+
+	return redaction.policy
+}
+
+var (
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)((?:signature|x-amz-signature|x-amz-credential|x-amz-security-token)=)[^&\s"]+`),
+		regexp.MustCompile(`(?i)(authtoken_[a-z_]*=)[^;\s"]+`),
+		regexp.MustCompile(`(?i)("(?:password|authcode)"\s*:\s*")[^"]*`),
+	}
+
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	custIDPattern = regexp.MustCompile(`(?i)((?:host_)?cust_ids?=)[^&\s"]+`)
+)
+
+// mask replaces s with a short hash so log lines about the same value can
+// still be matched up
+func mask(s string) string {
+	sum := sha256.Sum256([]byte(s))
+
+	return "[" + hex.EncodeToString(sum[:4]) + "]"
+}
+
+// redactString removes the secrets and, as policy says, the personal data
+// found in s
+func redactString(s string, policy RedactionPolicy) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+
+	if policy.MaskPII {
+		s = emailPattern.ReplaceAllStringFunc(s, mask)
+	}
+
+	if policy.MaskCustIDs {
+		s = custIDPattern.ReplaceAllStringFunc(s, func(m string) string {
+			n := strings.IndexByte(m, '=')
+
+			return m[:n+1] + mask(m[n+1:])
+		})
+	}
+
+	return s
+}
+
+// redactField returns value as it may be logged under key
+func redactField(key string, value interface{}, policy RedactionPolicy) interface{} {
+	k := strings.ToLower(key)
+
+	switch {
+	case strings.Contains(k, "password") || strings.Contains(k, "cookie") ||
+		strings.Contains(k, "authcode") || strings.Contains(k, "token") || strings.Contains(k, "secret"):
+		return redacted
+	case policy.MaskPII && (strings.Contains(k, "email") || strings.Contains(k, "username") ||
+		strings.Contains(k, "display_name") || strings.Contains(k, "displayname")):
+		return mask(fmt.Sprint(value))
+	case policy.MaskCustIDs && (strings.Contains(k, "cust_id") || strings.Contains(k, "custid")):
+		return mask(fmt.Sprint(value))
+	}
+
+	switch value.(type) {
+	case nil, bool, int, int64, float64, log.Level:
+		return value
+	}
+
+	s := fmt.Sprint(value)
+
+	if r := redactString(s, policy); r != s {
+		return r
+	}
+
+	return value
+}
+
+// redactionHook applies the redaction policy to every entry before it is
+// formatted
+type redactionHook struct{}
+
+func (redactionHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (redactionHook) Fire(entry *log.Entry) error {
+	policy := logRedaction()
+
+	for key, value := range entry.Data {
+		entry.Data[key] = redactField(key, value, policy)
+	}
+
+	entry.Message = redactString(entry.Message, policy)
+
+	return nil
+}
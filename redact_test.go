@@ -0,0 +1,118 @@
+package irdata
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEmail, testEmailPassword = []byte("louis@example.com"), []byte("ferrari")
+
+// fieldCreds keeps its credentials in fields, which logging it would show
+type fieldCreds struct {
+	email    []byte
+	password []byte
+}
+
+func (c fieldCreds) GetCreds() ([]byte, []byte) {
+	return c.email, c.password
+}
+
+// captureLog collects everything the instance opened with the option
+// returned logs at level during the test
+func captureLog(t *testing.T, level log.Level) (*bytes.Buffer, Option) {
+	var buf bytes.Buffer
+
+	logger := log.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(level)
+
+	t.Cleanup(func() {
+		SetLogRedaction(defaultRedaction)
+	})
+
+	return &buf, WithLogger(logger)
+}
+
+func TestLogRedactionAuthAndGet(t *testing.T) {
+	m := newMockAPI(t)
+	m.addAccount(testEmail, testEmailPassword)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handleLinked("/data/stats/member_recent_races", `{"races":[]}`)
+
+	out, logTo := captureLog(t, log.DebugLevel)
+
+	api := m.open(t, WithClock(newFakeClock()), logTo)
+	assert.NoError(t, api.AuthWithProvideCreds(fieldCreds{email: testEmail, password: testEmailPassword}))
+
+	_, err := api.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	_, err = api.MyRecentRaces(api.ctx)
+	assert.NoError(t, err)
+
+	logged := out.String()
+
+	assert.Contains(t, logged, "/data/member/info")
+	assert.NotContains(t, logged, string(testEmail))
+	assert.NotContains(t, logged, string(testEmailPassword))
+	assert.NotContains(t, logged, encodePassword(testEmail, testEmailPassword))
+	assert.NotContains(t, logged, "let-me-in")
+	assert.NotContains(t, logged, "signature=abc")
+
+	// customer ids are left readable unless asked
+	assert.Contains(t, logged, "cust_id=4242")
+}
+
+func TestLogRedactionCustIDs(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handleLinked("/data/stats/member_recent_races", `{"races":[]}`)
+
+	out, logTo := captureLog(t, log.DebugLevel)
+
+	SetLogRedaction(RedactionPolicy{MaskPII: true, MaskCustIDs: true})
+
+	api := m.openAuthed(t, logTo)
+
+	_, err := api.MyRecentRaces(api.ctx)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "/data/stats/member_recent_races")
+	assert.NotContains(t, out.String(), "4242")
+}
+
+func TestRedactString(t *testing.T) {
+	policy := RedactionPolicy{MaskPII: true}
+
+	assert.Equal(t, "https://s3/x.json?X-Amz-Signature=[REDACTED]&a=1", redactString("https://s3/x.json?X-Amz-Signature=deadbeef&a=1", policy))
+	assert.Equal(t, "authtoken_members=[REDACTED]; Path=/", redactString("authtoken_members=let-me-in; Path=/", policy))
+	assert.Equal(t, `{"email":"`+mask("a@b.co")+`","password":"[REDACTED]"}`, redactString(`{"email":"a@b.co","password":"hunter2"}`, policy))
+
+	// PII is configurable, secrets aren't
+	assert.Equal(t, `a@b.co "authcode":"[REDACTED]"`, redactString(`a@b.co "authcode":"xyz"`, RedactionPolicy{}))
+
+	assert.Equal(t, "/data/x?cust_id="+mask("1%2C2")+"&y=1", redactString("/data/x?cust_id=1%2C2&y=1", RedactionPolicy{MaskCustIDs: true}))
+}
+
+func TestRedactField(t *testing.T) {
+	policy := RedactionPolicy{MaskPII: true, MaskCustIDs: true}
+
+	assert.Equal(t, redacted, redactField("EncodedPassword", "abc", policy))
+	assert.Equal(t, redacted, redactField("cookie", "abc", policy))
+	assert.Equal(t, mask("Ayrton"), redactField("display_name", "Ayrton", policy))
+	assert.Equal(t, mask("4242"), redactField("custID", int64(4242), policy))
+	assert.Equal(t, 5, redactField("attempt", 5, policy))
+
+	u, _ := url.Parse("https://s3/x?signature=abc")
+	assert.Equal(t, "https://s3/x?signature=[REDACTED]", redactField("url", u, policy))
+
+	// left as is when there's nothing to redact
+	err := errors.New("boom")
+	assert.Equal(t, err, redactField("err", err, policy))
+	assert.Equal(t, "Ayrton", redactField("display_name", "Ayrton", RedactionPolicy{}))
+}
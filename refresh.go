@@ -0,0 +1,217 @@
+package irdata
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultMaxRefreshes is how many background refreshes run at once unless
+// WithStaleWhileRevalidate says otherwise
+const defaultMaxRefreshes = 4
+
+type refreshT struct {
+	mu     sync.Mutex
+	window time.Duration
+	max    int
+
+	running int
+	queue   []refreshJobT
+
+	// pending holds the keys running or queued so an entry is refreshed
+	// once however often it's read meanwhile
+	pending map[string]bool
+	stats   RefreshStats
+}
+
+type refreshJobT struct {
+	ctx context.Context
+	uri string
+	key string
+	ttl time.Duration
+}
+
+// RefreshStats counts what stale-while-revalidate did, see
+// WithStaleWhileRevalidate
+type RefreshStats struct {
+	// Running and Queued are the refreshes running and waiting now
+	Running int
+	Queued  int
+
+	// ServedStale is how many expired entries were served while being
+	// refreshed, Deferred how many of those refreshes had to queue for a
+	// free slot
+	ServedStale int64
+	Deferred    int64
+
+	Refreshed int64
+	Failed    int64
+}
+
+// WithStaleWhileRevalidate has GetWithCache, ViewWithCache and
+// GetChunksWithCache serve an entry for up to window after it expired while
+// it's refreshed in the background, so callers don't wait on the network.
+// At most maxRefreshes refreshes run at once (4 if 0), entries expiring
+// past that are served stale until their turn comes.  Entries are kept at
+// least window past their ttl for it, see WithStaleRetention.
+func WithStaleWhileRevalidate(window time.Duration, maxRefreshes int) Option {
+	return func(i *Irdata) {
+		if maxRefreshes <= 0 {
+			maxRefreshes = defaultMaxRefreshes
+		}
+
+		i.refresh.window = window
+		i.refresh.max = maxRefreshes
+	}
+}
+
+// WithTTLJitter shortens the ttl of every cached response by a random
+// amount of up to percent of it, so entries cached in one burst don't all
+// expire together.  Entries are never cached for longer than asked.
+func WithTTLJitter(percent float64) Option {
+	return func(i *Irdata) {
+		if percent > 100 {
+			percent = 100
+		}
+
+		i.ttlJitter = percent / 100
+	}
+}
+
+// RefreshStats returns what stale-while-revalidate did so far
+func (i *Irdata) RefreshStats() RefreshStats {
+	r := &i.refresh
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.Running = r.running
+	stats.Queued = len(r.queue)
+
+	return stats
+}
+
+// jitterTTL returns ttl shortened by the ttl jitter, see WithTTLJitter
+func (i *Irdata) jitterTTL(ttl time.Duration) time.Duration {
+	if i.ttlJitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+
+	atomic.AddInt64(&i.ttlJittered, 1)
+
+	return ttl - time.Duration(float64(ttl)*i.ttlJitter*rand.Float64())
+}
+
+// revalidating reports whether an entry that expired at expires may still
+// be served while it's refreshed
+func (i *Irdata) revalidating(expires time.Time) bool {
+	return i.refresh.window > 0 && !i.Offline() && !i.clock.Now().After(expires.Add(i.refresh.window))
+}
+
+// scheduleRefresh refreshes the stale entry cached for uri under key in
+// the background, queueing it while all the refresh slots are taken.  The
+// refresh is made with the call options of ctx, not its deadline.
+func (i *Irdata) scheduleRefresh(ctx context.Context, uri string, key string, ttl time.Duration) {
+	r := &i.refresh
+
+	refreshCtx := withCachedCall(i.ctx)
+
+	if layers := ctx.Value(requestOptionsKey{}); layers != nil {
+		refreshCtx = context.WithValue(refreshCtx, requestOptionsKey{}, layers)
+	}
+
+	if ctx.Value(noResponseLimitKey{}) != nil {
+		refreshCtx = withoutResponseLimit(refreshCtx)
+	}
+
+	job := refreshJobT{ctx: refreshCtx, uri: uri, key: key, ttl: ttl}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.max == 0 {
+		return
+	}
+
+	r.stats.ServedStale++
+
+	if r.pending[key] {
+		return
+	}
+
+	if r.pending == nil {
+		r.pending = make(map[string]bool)
+	}
+
+	r.pending[key] = true
+
+	if r.running >= r.max {
+		r.stats.Deferred++
+		r.queue = append(r.queue, job)
+
+		return
+	}
+
+	r.running++
+
+	go i.runRefreshes(job)
+}
+
+// runRefreshes runs job and then those queued until there are none left
+func (i *Irdata) runRefreshes(job refreshJobT) {
+	r := &i.refresh
+
+	for {
+		err := i.refreshCached(job)
+
+		r.mu.Lock()
+
+		if err != nil {
+			r.stats.Failed++
+		} else {
+			r.stats.Refreshed++
+		}
+
+		delete(r.pending, job.key)
+
+		if len(r.queue) == 0 {
+			r.running--
+			r.mu.Unlock()
+
+			return
+		}
+
+		job = r.queue[0]
+		r.queue = r.queue[1:]
+
+		r.mu.Unlock()
+	}
+}
+
+// refreshCached fetches and caches job's uri again
+func (i *Irdata) refreshCached(job refreshJobT) error {
+	ctx, end, err := i.beginRequest(job.ctx)
+	if err != nil {
+		return err
+	}
+
+	defer end()
+
+	p, err := i.fetch(ctx, job.uri)
+	if err != nil {
+		i.logger.WithFields(log.Fields{"uri": job.uri, "err": err}).Warn("Refreshing stale cache entry failed")
+		return err
+	}
+
+	if err := i.storeCached(ctx, job.uri, job.key, p, i.cacheTTL(job.uri, job.ttl)); err != nil {
+		i.cacheFailed("write", job.uri, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// refreshesPerStep caches entries uris for ttl at once, then reads them all
+// every step until ttl is over, returning how many were fetched again at
+// each step
+func refreshesPerStep(t *testing.T, entries int, ttl time.Duration, step time.Duration, opts ...Option) []int {
+	m := newMockAPI(t)
+	m.handle("/data/item", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, append([]Option{WithClock(clock)}, opts...)...)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	readAll := func() {
+		for n := 0; n < entries; n++ {
+			_, err := api.GetWithCache(fmt.Sprintf("/data/item?id=%d", n), ttl)
+			assert.NoError(t, err)
+		}
+	}
+
+	readAll()
+
+	var hits []int
+
+	for elapsed := step; elapsed <= ttl+step; elapsed += step {
+		before := m.hitCount("/data/item")
+
+		clock.advance(step)
+		readAll()
+
+		hits = append(hits, m.hitCount("/data/item")-before)
+	}
+
+	return hits
+}
+
+func maxOf(counts []int) int {
+	max := 0
+
+	for _, n := range counts {
+		if n > max {
+			max = n
+		}
+	}
+
+	return max
+}
+
+func TestTTLJitter(t *testing.T) {
+	const entries = 200
+
+	// an hour of steps a minute long with every entry expiring at once
+	flat := refreshesPerStep(t, entries, time.Hour, time.Minute)
+
+	assert.Equal(t, entries, maxOf(flat))
+
+	jittered := refreshesPerStep(t, entries, time.Hour, time.Minute, WithTTLJitter(50))
+
+	// the same refreshes spread over the last half hour
+	total, busy := 0, 0
+
+	for step, n := range jittered {
+		total += n
+
+		if n > 0 {
+			busy++
+			assert.GreaterOrEqual(t, step, 28, "refreshed before the jitter allows")
+		}
+	}
+
+	assert.Equal(t, entries, total)
+	assert.Greater(t, busy, 20)
+	assert.Less(t, maxOf(jittered), entries/5)
+}
+
+func TestTTLJitterStats(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/item", `{}`)
+
+	api := m.openAuthed(t, WithTTLJitter(10))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	_, err := api.GetWithCache("/data/item", time.Hour)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(1), api.Stats().Cache.TTLJittered)
+
+	entries, err := api.CacheEntries()
+	if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+		ttl := entries[0].Expires.Sub(entries[0].Created)
+
+		assert.LessOrEqual(t, ttl, time.Hour)
+		assert.GreaterOrEqual(t, ttl, 54*time.Minute)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	const entries = 10
+
+	var version, running, maxRunning int32
+	var mu sync.Mutex
+
+	release := make(chan struct{})
+	atomic.StoreInt32(&version, 1)
+
+	m := newMockAPI(t)
+	m.handle("/data/item", func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.LoadInt32(&version)
+
+		if v > 1 {
+			n := atomic.AddInt32(&running, 1)
+
+			mu.Lock()
+			if n > maxRunning {
+				maxRunning = n
+			}
+			mu.Unlock()
+
+			<-release
+			atomic.AddInt32(&running, -1)
+		}
+
+		fmt.Fprintf(w, `{"v":%d}`, v)
+	})
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock), WithStaleWhileRevalidate(time.Hour, 2))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	get := func(n int) string {
+		data, err := api.GetWithCache(fmt.Sprintf("/data/item?id=%d", n), time.Hour)
+		assert.NoError(t, err)
+
+		return string(data)
+	}
+
+	for n := 0; n < entries; n++ {
+		get(n)
+	}
+
+	atomic.StoreInt32(&version, 2)
+	clock.advance(time.Hour + time.Minute)
+
+	// everything is served stale right away, two refreshes at a time
+	for n := 0; n < entries; n++ {
+		assert.Equal(t, `{"v":1}`, get(n))
+	}
+
+	assert.Equal(t, `{"v":1}`, get(0))
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&running) == 2 }, time.Second, time.Millisecond)
+
+	stats := api.RefreshStats()
+	assert.Equal(t, 2, stats.Running)
+	assert.Equal(t, entries-2, stats.Queued)
+	assert.Equal(t, int64(entries+1), stats.ServedStale)
+	assert.Equal(t, int64(entries-2), stats.Deferred)
+
+	close(release)
+
+	assert.Eventually(t, func() bool { return api.RefreshStats().Refreshed == entries }, 5*time.Second, time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, int32(2), maxRunning)
+	mu.Unlock()
+
+	for n := 0; n < entries; n++ {
+		assert.Equal(t, `{"v":2}`, get(n))
+	}
+
+	assert.Equal(t, 2*entries, m.hitCount("/data/item"))
+
+	stats = api.Stats().Refresh
+	assert.Zero(t, stats.Running)
+	assert.Zero(t, stats.Queued)
+	assert.Zero(t, stats.Failed)
+}
+
+func TestStaleWhileRevalidateWindow(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/item", `{}`)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock), WithStaleWhileRevalidate(time.Hour, 0))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	_, err := api.GetWithCache("/data/item", time.Hour)
+	assert.NoError(t, err)
+
+	// too long expired to be served
+	clock.advance(3 * time.Hour)
+
+	_, err = api.GetWithCache("/data/item", time.Hour)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, m.hitCount("/data/item"))
+	assert.Zero(t, api.RefreshStats().ServedStale)
+}
@@ -0,0 +1,159 @@
+package irdata
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Region is the iRacing service an instance talks to.  The services have
+// the same API on hosts of their own, and sessions of one are meaningless
+// to the other.
+type Region int
+
+const (
+	// RegionGlobal is members-ng.iracing.com, the default
+	RegionGlobal Region = iota
+
+	// RegionChina is the service run for China
+	RegionChina
+)
+
+func (r Region) String() string {
+	switch r {
+	case RegionGlobal:
+		return "global"
+	case RegionChina:
+		return "china"
+	default:
+		return fmt.Sprintf("region(%d)", int(r))
+	}
+}
+
+// regionHostsT are the hosts of a region
+type regionHostsT struct {
+	// api serves the /data endpoints and /auth
+	api *url.URL
+
+	// assets serves the static assets, e.g. track maps
+	assets *url.URL
+
+	// links are the domains the s3 links and chunks point into
+	links []string
+}
+
+var regionHosts = map[Region]regionHostsT{}
+
+func init() {
+	for region, hosts := range map[Region][3]string{
+		RegionGlobal: {rootURL, "https://images-static.iracing.com", "amazonaws.com cloudfront.net"},
+		RegionChina:  {"https://members-ng.iracing.cn", "https://images-static.iracing.cn", "amazonaws.com.cn"},
+	} {
+		api, err := url.Parse(hosts[0])
+		if err != nil {
+			packageLogger.Panic(err)
+		}
+
+		assets, err := url.Parse(hosts[1])
+		if err != nil {
+			packageLogger.Panic(err)
+		}
+
+		regionHosts[region] = regionHostsT{api: api, assets: assets, links: strings.Fields(hosts[2])}
+	}
+}
+
+// WithRegion points the instance at the hosts of region: those logged into,
+// requested and downloaded assets from.  Links and chunks must then point
+// into the region's s3 domains (or hosts allowed with WithRedirectHosts) and
+// are refused with ErrForeignLink otherwise.  An unknown region is logged
+// and ignored.
+//
+// WithBaseURL passed after WithRegion still overrides the API host, e.g. for
+// a proxy.
+func WithRegion(region Region) Option {
+	return func(i *Irdata) {
+		hosts, ok := regionHosts[region]
+		if !ok {
+			i.logger.WithFields(log.Fields{"region": region}).Error("Unknown region")
+			return
+		}
+
+		i.region = region
+		i.regionStrict = true
+		i.baseURL = hosts.api
+		i.assetBase = hosts.assets
+	}
+}
+
+// Region returns the region the instance talks to
+func (i *Irdata) Region() Region {
+	return i.region
+}
+
+// SetRegion is WithRegion for an instance that isn't authenticated yet.  Once
+// it is, the region can't change and it returns ErrRegionLocked.
+func (i *Irdata) SetRegion(region Region) error {
+	if i.isAuthed {
+		return ErrRegionLocked
+	}
+
+	if _, ok := regionHosts[region]; !ok {
+		return fmt.Errorf("unknown region %v", region)
+	}
+
+	WithRegion(region)(i)
+
+	return nil
+}
+
+// isRegionLinkHost reports whether host, without its port, is in one of the
+// s3 domains of the region
+func (i *Irdata) isRegionLinkHost(host string) bool {
+	host = strings.ToLower(host)
+
+	for _, domain := range regionHosts[i.region].links {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkLinkHost refuses links and chunks outside the region, once one was
+// chosen with WithRegion
+func (i *Irdata) checkLinkHost(link string) error {
+	if !i.regionStrict {
+		return nil
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(u.Host, i.baseURL.Host) || i.redirectHosts[strings.ToLower(u.Host)] || i.isRegionLinkHost(u.Hostname()) {
+		return nil
+	}
+
+	return &ForeignLinkError{URL: link, Region: i.region}
+}
+
+// assetURL resolves ref, an asset url or a path on the asset host, against
+// the asset host of the region
+func (i *Irdata) assetURL(ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+
+	base := i.assetBase
+	if base == nil {
+		base = regionHosts[RegionGlobal].assets
+	}
+
+	return base.ResolveReference(u).String(), nil
+}
@@ -0,0 +1,151 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// regionRouter sends every request to the mock whatever its host, noting
+// the host each path was requested from
+type regionRouter struct {
+	m *mockAPI
+
+	mu    sync.Mutex
+	hosts map[string]string
+}
+
+func (r *regionRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	r.hosts[req.URL.Path] = req.URL.Host
+	r.mu.Unlock()
+
+	target, err := url.Parse(r.m.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	routed := req.Clone(req.Context())
+	routed.URL.Scheme = target.Scheme
+	routed.URL.Host = target.Host
+	routed.Host = target.Host
+
+	resp, err := http.DefaultTransport.RoundTrip(routed)
+	if resp != nil {
+		resp.Request = req
+	}
+
+	return resp, err
+}
+
+func (r *regionRouter) host(path string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.hosts[path]
+}
+
+func TestRegionHosts(t *testing.T) {
+	const s3 = "https://scorpio.s3.cn-north-1.amazonaws.com.cn"
+
+	m := newMockAPI(t)
+
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/result.json?signature=abc"}`, s3)
+	})
+	m.mux.HandleFunc("/result.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"subsession_id":1}`)
+	})
+
+	m.handle("/data/results/search_series", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/search.json?signature=abc"}`, s3)
+	})
+	m.mux.HandleFunc("/search.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"type":"chunked","data":{"success":true,"chunk_info":{"num_chunks":1,"base_download_url":"%s/chunks/","chunk_file_names":["0.json"]}}}`, s3)
+	})
+	m.mux.HandleFunc("/chunks/0.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"subsession_id":1}]`)
+	})
+
+	m.handle("/data/track/assets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/assets.json?signature=abc"}`, s3)
+	})
+	m.mux.HandleFunc("/assets.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"1":{"track_id":1,"track_map":"/public/track-maps/limerock/","track_map_layers":{"active":"active.svg"}}}`)
+	})
+	m.mux.HandleFunc("/public/track-maps/limerock/active.svg", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<svg xmlns="http://www.w3.org/2000/svg"><path d="M0 0"/></svg>`)
+	})
+
+	router := &regionRouter{m: m, hosts: make(map[string]string)}
+
+	api := m.openAuthed(t,
+		WithRegion(RegionChina),
+		WithMiddleware(func(http.RoundTripper) http.RoundTripper { return router }),
+	)
+
+	assert.Equal(t, RegionChina, api.Region())
+
+	ctx := context.Background()
+
+	_, err := api.GetSubsessionResult(ctx, 1)
+	assert.NoError(t, err)
+
+	_, err = api.SearchSeriesResults(ctx, SearchSeriesParams{SeasonYear: 2024, SeasonQuarter: 1})
+	assert.NoError(t, err)
+
+	_, err = api.GetTrackMapLayers(ctx, 1)
+	assert.NoError(t, err)
+
+	for path, host := range map[string]string{
+		loginURI:                                 "members-ng.iracing.cn",
+		testURI:                                  "members-ng.iracing.cn",
+		"/data/results/get":                      "members-ng.iracing.cn",
+		"/result.json":                           "scorpio.s3.cn-north-1.amazonaws.com.cn",
+		"/search.json":                           "scorpio.s3.cn-north-1.amazonaws.com.cn",
+		"/chunks/0.json":                         "scorpio.s3.cn-north-1.amazonaws.com.cn",
+		"/public/track-maps/limerock/active.svg": "images-static.iracing.cn",
+	} {
+		assert.Equal(t, host, router.host(path), path)
+	}
+
+	assert.ErrorIs(t, api.SetRegion(RegionGlobal), ErrRegionLocked)
+	assert.Equal(t, RegionChina, api.Region())
+}
+
+func TestRegionRefusesForeignLinks(t *testing.T) {
+	m := newMockAPI(t)
+
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"link":"https://scorpio-assets.s3.amazonaws.com/result.json?signature=abc"}`)
+	})
+
+	router := &regionRouter{m: m, hosts: make(map[string]string)}
+
+	api := m.openAuthed(t,
+		WithRegion(RegionChina),
+		WithMiddleware(func(http.RoundTripper) http.RoundTripper { return router }),
+	)
+
+	_, err := api.GetSubsessionResult(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrForeignLink)
+	assert.Empty(t, router.host("/result.json"))
+}
+
+func TestSetRegion(t *testing.T) {
+	api := Open(context.Background())
+
+	assert.Equal(t, RegionGlobal, api.Region())
+	assert.Equal(t, "members-ng.iracing.com", api.baseURL.Host)
+
+	assert.NoError(t, api.SetRegion(RegionChina))
+	assert.Equal(t, "members-ng.iracing.cn", api.baseURL.Host)
+
+	assert.Error(t, api.SetRegion(Region(7)))
+	assert.Equal(t, "region(7)", Region(7).String())
+}
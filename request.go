@@ -0,0 +1,478 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const maxAttempts = 5
+
+// retryBackoff is the unit of the linear backoff between retries
+var retryBackoff = 5 * time.Second
+
+// RequestOption adjusts a single request, or every call made with a
+// context, see WithRequestOptions
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	header     http.Header
+	query      url.Values
+	timeout    time.Duration
+	followLink bool
+
+	// headersInCacheKey is set by WithHeadersInCacheKey and anonymous by
+	// AllowAnonymous
+	headersInCacheKey bool
+	anonymous         bool
+
+	// priority is set by WithRequestPriority
+	priority *Priority
+
+	// err is the first option that was refused
+	err error
+}
+
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	o := &requestOptions{header: http.Header{}, query: url.Values{}}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithHeader adds a header to the request.  The headers the session is
+// made of, Cookie and Authorization, are refused with ErrReservedHeader.
+func WithHeader(key string, value string) RequestOption {
+	return func(o *requestOptions) {
+		if reservedHeaders[http.CanonicalHeaderKey(key)] {
+			if o.err == nil {
+				o.err = fmt.Errorf("%w: %s", ErrReservedHeader, key)
+			}
+
+			return
+		}
+
+		o.header.Add(key, value)
+	}
+}
+
+// WithFollowLink makes Do follow the s3 link iRacing answers most data
+// requests with and return the linked response instead
+func WithFollowLink() RequestOption {
+	return func(o *requestOptions) {
+		o.followLink = true
+	}
+}
+
+// Do sends a request for uri using the session, retry and rate limit
+// handling of this instance and returns the raw response.  Use it for
+// endpoints the rest of the package doesn't cover.
+//
+// Unlike Get, Do neither follows links (unless WithFollowLink is passed),
+// merges chunks nor caches.
+//
+// The caller owns the response and must close its body.  Close waits for
+// the request but not for the body, whose reads fail once the grace period
+// of Close is over.
+func (i *Irdata) Do(ctx context.Context, method string, uri string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	ctx, untrack, cancel, err := i.trackRequest(WithRequestOptions(ctx, opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	defer untrack()
+
+	resp, err := i.do(ctx, method, uri, body)
+	if err != nil || resp == nil {
+		cancel()
+		return resp, err
+	}
+
+	resp.Body = &endingBody{ReadCloser: resp.Body, end: cancel}
+
+	return resp, nil
+}
+
+func (i *Irdata) do(ctx context.Context, method string, uri string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	o := i.callOptions(ctx, opts)
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	if err := i.checkAuthed(o); err != nil {
+		return nil, err
+	}
+
+	url, err := i.resolveURL(o.withQuery(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+
+	if body != nil {
+		// buffered so the request can be replayed on retries
+		payload, err = io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, transcript := i.withRetryTranscript(ctx)
+
+	resp, err := i.authedDo(ctx, method, url.String(), payload, o.header, retryServerErrors)
+	if err != nil {
+		return nil, transcript.report(err, i.clock.Now())
+	}
+
+	if !o.followLink {
+		return resp, nil
+	}
+
+	data, err := readResponse(url.String(), resp, i.responseLimit(ctx))
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var s3Link s3LinkT
+
+	if json.Unmarshal(data, &s3Link) != nil || s3Link.Link == "" {
+		// nothing to follow, hand back what we read
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+
+		return resp, nil
+	}
+
+	i.logger.WithFields(log.Fields{"s3Link.Link": s3Link.Link}).Debug("Following s3link")
+
+	if err := i.checkLinkHost(s3Link.Link); err != nil {
+		return nil, err
+	}
+
+	resp, err = i.retryingGet(ctx, s3Link.Link)
+	if err != nil {
+		return nil, transcript.report(err, i.clock.Now())
+	}
+
+	return resp, nil
+}
+
+// PostJSON posts body marshalled as JSON to uri and unmarshals the response,
+// following the s3 link if there is one, into out (unless out is nil).
+//
+// POSTs may change state on the iRacing side so they are only retried when
+// iRacing says it never got them or rate limited them, and they are never
+// cached.  A response other than 2xx is an error.
+func (i *Irdata) PostJSON(ctx context.Context, uri string, body interface{}, out interface{}) error {
+	if err := i.checkAuthed(i.callOptions(ctx, nil)); err != nil {
+		return err
+	}
+
+	ctx, end, err := i.beginRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer end()
+
+	url, err := i.resolveURL(uri)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	ctx, transcript := i.withRetryTranscript(ctx)
+
+	resp, err := i.authedDo(ctx, http.MethodPost, url.String(), payload, http.Header{
+		"Content-Type": []string{"application/json"},
+	}, retryUnprocessed)
+	if err != nil {
+		return transcript.report(err, i.clock.Now())
+	}
+
+	data, err := readResponse(url.String(), resp, i.responseLimit(ctx))
+	resp.Body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		i.logger.WithFields(log.Fields{
+			"url":             url,
+			"resp.StatusCode": resp.StatusCode,
+			"len(data)":       len(data),
+		}).Info("POST failed")
+
+		return transcript.report(fmt.Errorf("POST %s failed: %s", uri, resp.Status), i.clock.Now())
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	var s3Link s3LinkT
+
+	if json.Unmarshal(data, &s3Link) == nil && s3Link.Link != "" {
+		i.logger.WithFields(log.Fields{"s3Link.Link": s3Link.Link}).Debug("Following s3link")
+
+		// fetching the link is a GET and safe to retry
+		data, _, err = i.getLinkedBody(ctx, s3Link.Link)
+		if err != nil {
+			return transcript.report(err, i.clock.Now())
+		}
+	}
+
+	return i.decodeJSON(uri, data, out)
+}
+
+// retryServerErrors retries server errors and rate limiting
+func retryServerErrors(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryUnprocessed only retries responses saying the request never reached
+// iRacing or was turned away by the rate limiting, for requests that
+// mustn't be repeated once they were handled
+func retryUnprocessed(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusTooManyRequests
+}
+
+type singleAttemptKey struct{}
+
+// withSingleAttempt returns a context whose requests are sent once, neither
+// retried nor waiting out rate limiting
+func withSingleAttempt(ctx context.Context) context.Context {
+	return context.WithValue(ctx, singleAttemptKey{}, true)
+}
+
+// retryingDo sends a request, retrying the responses retry accepts and
+// waiting out rate limiting.  After the final attempt the last response is
+// returned as is.
+func (i *Irdata) retryingDo(ctx context.Context, method string, url string, body []byte, header http.Header, retry func(status int) bool) (*http.Response, error) {
+	return i.retryingDoWith(ctx, i.clientFor(header), method, url, body, header, retry)
+}
+
+// retryingDoWith is retryingDo sending the requests with client
+func (i *Irdata) retryingDoWith(ctx context.Context, client *http.Client, method string, url string, body []byte, header http.Header, retry func(status int) bool) (*http.Response, error) {
+	if i.Offline() {
+		return nil, ErrOffline
+	}
+
+	transcript := retryTranscriptFrom(ctx)
+	single := ctx.Value(singleAttemptKey{}) != nil
+
+	for attempt := 1; ; attempt++ {
+		i.logger.WithFields(log.Fields{
+			"method":  method,
+			"url":     url,
+			"attempt": attempt,
+		}).Info("httpClient.Do")
+
+		var bodyReader io.Reader
+
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		probe, err := i.breakerAllow()
+		if err != nil {
+			return nil, err
+		}
+
+		started := i.clock.Now()
+
+		var resp *http.Response
+
+		if i.shouldHedge(req) {
+			resp, err = i.sendHedged(client, req)
+		} else {
+			resp, err = client.Do(req)
+		}
+
+		if err != nil {
+			err = tlsVerificationError(req.URL.Host, err)
+		}
+
+		i.breakerRecord(probe, resp, err)
+		i.countRequest(attempt)
+
+		i.traceRequest(method, url, attempt, started, resp, err)
+		i.notifyRequest(method, url, attempt, started, resp, err)
+		transcript.attempt(method, url, resp, err, i.clock.Now().Sub(started))
+
+		if err != nil {
+			// the response to a dropped request that wasn't idempotent
+			// may still have been acted on
+			if single || !isIdempotent(method) || !isTransientTransportError(err) || !transcript.canRetry(url, attempt) {
+				return nil, err
+			}
+
+			if err := i.transportRetry(ctx, url, attempt, err); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		i.noteRateLimit(resp)
+
+		if resp.StatusCode < 400 {
+			i.noteSuccess()
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			i.noteSessionProven(req)
+		}
+
+		if single || !retry(resp.StatusCode) || !transcript.canRetry(url, attempt) {
+			return resp, nil
+		}
+
+		delay := time.Duration(attempt+1) * retryBackoff
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			delay = rateLimitDelay(resp, attempt, i.clock.Now())
+			atomic.AddInt64(&i.requestStats.rateLimitWaits, 1)
+		}
+
+		resp.Body.Close()
+
+		i.logger.WithFields(log.Fields{
+			"url":             url,
+			"resp.StatusCode": resp.StatusCode,
+			"delay":           delay,
+		}).Info("*** Retrying")
+
+		transcript.backoff(delay)
+
+		if err := i.clock.Sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// traceRequest logs a finished attempt at log.TraceLevel
+func (i *Irdata) traceRequest(method string, url string, attempt int, started time.Time, resp *http.Response, err error) {
+	if !i.logger.IsLevelEnabled(log.TraceLevel) {
+		return
+	}
+
+	fields := log.Fields{
+		"method":   method,
+		"url":      url,
+		"attempt":  attempt,
+		"duration": i.clock.Now().Sub(started),
+	}
+
+	if err != nil {
+		fields["err"] = err
+	} else {
+		fields["status"] = resp.StatusCode
+	}
+
+	i.logger.WithFields(fields).Trace("Request done")
+}
+
+// RateLimit is the rate limit state iRacing reported with the most recent
+// response that carried the x-ratelimit headers
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// RateLimit returns the most recently reported rate limit state.  It is the
+// zero value until a response reported one.
+func (i *Irdata) RateLimit() RateLimit {
+	i.rateLimitMu.Lock()
+	defer i.rateLimitMu.Unlock()
+
+	return i.rateLimit
+}
+
+func (i *Irdata) noteRateLimit(resp *http.Response) {
+	rl, ok := parseRateLimit(resp)
+	if !ok {
+		return
+	}
+
+	i.rateLimitMu.Lock()
+	defer i.rateLimitMu.Unlock()
+
+	i.rateBudget.note(i.rateLimit, rl)
+	i.rateLimit = rl
+}
+
+// parseRateLimit reads the x-ratelimit headers of resp, false if it has
+// none
+func parseRateLimit(resp *http.Response) (RateLimit, bool) {
+	limit, err := strconv.Atoi(resp.Header.Get("x-ratelimit-limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	remaining, _ := strconv.Atoi(resp.Header.Get("x-ratelimit-remaining"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("x-ratelimit-reset"), 10, 64)
+
+	return RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}, true
+}
+
+// rateLimitDelay is how long to wait for the rate limit to reset, falling
+// back to the regular backoff when iRacing doesn't say
+func rateLimitDelay(resp *http.Response, attempt int, now time.Time) time.Duration {
+	reset, err := strconv.ParseInt(resp.Header.Get("x-ratelimit-reset"), 10, 64)
+	if err != nil {
+		return time.Duration(attempt+1) * retryBackoff
+	}
+
+	delay := time.Unix(reset, 0).Sub(now)
+	if delay < time.Second {
+		delay = time.Second
+	}
+
+	return delay
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
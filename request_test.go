@@ -0,0 +1,171 @@
+package irdata
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	return string(data)
+}
+
+func TestDoRaw(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t)
+
+	resp, err := api.Do(context.Background(), http.MethodGet, "/data/member/info", nil)
+	assert.NoError(t, err)
+	assert.Contains(t, readBody(t, resp), `"link"`)
+}
+
+func TestDoFollowLink(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+	m.handleJSON("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+
+	api := m.openAuthed(t)
+
+	resp, err := api.Do(context.Background(), http.MethodGet, "/data/member/info", nil, WithFollowLink())
+	assert.NoError(t, err)
+	assert.Equal(t, testMemberInfo, readBody(t, resp))
+
+	// no link to follow
+	resp, err = api.Do(context.Background(), http.MethodGet, "/data/constants/categories", nil, WithFollowLink())
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"label":"Oval","value":1}]`, readBody(t, resp))
+}
+
+func TestDoPostWithHeader(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/experimental", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "yes", r.Header.Get("X-Beta"))
+
+		w.Write(body)
+	})
+
+	api := m.openAuthed(t)
+
+	resp, err := api.Do(context.Background(), http.MethodPost, "/data/experimental", strings.NewReader(`{"a":1}`), WithHeader("X-Beta", "yes"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, readBody(t, resp))
+}
+
+func TestDoRetries(t *testing.T) {
+	var calls int32
+
+	m := newMockAPI(t)
+	m.handle("/data/flaky", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("x-ratelimit-reset", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			// the body is replayed on every attempt
+			w.Write(body)
+		}
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	resp, err := api.Do(context.Background(), http.MethodPost, "/data/flaky", strings.NewReader("again"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "again", readBody(t, resp))
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestDoGivesUp(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/down", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	resp, err := api.Do(context.Background(), http.MethodGet, "/data/down", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, maxAttempts, m.hitCount("/data/down"))
+}
+
+func TestDoMustAuth(t *testing.T) {
+	_, err := Open(context.Background()).Do(context.Background(), http.MethodGet, "/data/member/info", nil)
+	assert.Error(t, err)
+}
+
+func TestPostJSON(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.JSONEq(t, `{"a":1}`, string(body))
+
+		w.Write([]byte(`{"link":"` + m.URL + `/s3/echo"}`))
+	})
+	m.handleJSON("/s3/echo", `{"b":2}`)
+
+	api := m.openAuthed(t)
+
+	var out struct{ B int }
+
+	assert.NoError(t, api.PostJSON(context.Background(), "/data/echo", map[string]int{"a": 1}, &out))
+	assert.Equal(t, 2, out.B)
+
+	// POSTs are never cached
+	assert.NoError(t, api.PostJSON(context.Background(), "/data/echo", map[string]int{"a": 1}, nil))
+	assert.Equal(t, 2, m.hitCount("/data/echo"))
+}
+
+func TestPostJSONRetries(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		hits   int
+	}{
+		{http.StatusServiceUnavailable, maxAttempts},
+		{http.StatusBadGateway, maxAttempts},
+		{http.StatusTooManyRequests, maxAttempts},
+		// iRacing may have acted on these
+		{http.StatusInternalServerError, 1},
+		{http.StatusGatewayTimeout, 1},
+		{http.StatusBadRequest, 1},
+	} {
+		test := test
+
+		m := newMockAPI(t)
+		m.handle("/data/fail", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+		})
+
+		api := m.openAuthed(t, WithClock(newFakeClock()))
+
+		err := api.PostJSON(context.Background(), "/data/fail", nil, nil)
+		assert.Error(t, err, test.status)
+		assert.Equal(t, test.hits, m.hitCount("/data/fail"), test.status)
+	}
+}
+
+func TestPostJSONMustAuth(t *testing.T) {
+	assert.Error(t, Open(context.Background()).PostJSON(context.Background(), "/data/echo", nil, nil))
+}
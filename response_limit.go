@@ -0,0 +1,77 @@
+package irdata
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// WithMaxResponseSize fails requests whose response is larger than limit
+// bytes with a *ResponseTooLargeError rather than holding it in memory.
+// The limit applies to each response and to the total of the chunks of a
+// result, and to results read from the cache.  Downloads are aborted as
+// soon as they go past it.
+//
+// GetChunksWithCache and GetElementsWithCache aren't limited, use them for
+// results too large to hold at once.  There's no limit by default.
+func WithMaxResponseSize(limit int64) Option {
+	return func(i *Irdata) {
+		i.maxResponseSize = limit
+	}
+}
+
+type noResponseLimitKey struct{}
+
+// withoutResponseLimit returns a context whose requests aren't limited by
+// WithMaxResponseSize, for the APIs streaming their results
+func withoutResponseLimit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noResponseLimitKey{}, true)
+}
+
+// responseLimit is the size limit of the responses to requests made with
+// ctx, 0 if there's none
+func (i *Irdata) responseLimit(ctx context.Context) int64 {
+	if i.maxResponseSize <= 0 || ctx.Value(noResponseLimitKey{}) != nil {
+		return 0
+	}
+
+	return i.maxResponseSize
+}
+
+// checkResponseSize returns a *ResponseTooLargeError if size is over limit
+func checkResponseSize(url string, size int64, limit int64) error {
+	if limit <= 0 || size <= limit {
+		return nil
+	}
+
+	return &ResponseTooLargeError{URL: redactString(url, logRedaction()), Size: size, Limit: limit}
+}
+
+// readLimited reads all of r, giving up with a *ResponseTooLargeError as
+// soon as more than limit bytes were read
+func readLimited(url string, r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, &ResponseTooLargeError{URL: redactString(url, logRedaction()), Size: int64(len(data)), Limit: limit, Partial: true}
+	}
+
+	return data, nil
+}
+
+// readResponse is readLimited for the body of resp, which is refused
+// without reading if its Content-Length is already too large
+func readResponse(url string, resp *http.Response, limit int64) ([]byte, error) {
+	if err := checkResponseSize(url, resp.ContentLength, limit); err != nil {
+		return nil, err
+	}
+
+	return readLimited(url, resp.Body, limit)
+}
@@ -0,0 +1,169 @@
+package irdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const responseLimit = 4096
+
+// handleOversized serves a JSON array of total bytes at path, flushing as
+// it goes so the client sees no Content-Length.  The bytes written before
+// the client hung up end up in written.
+func handleOversized(m *mockAPI, path string, total int, written *int64, done chan struct{}) {
+	m.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+
+		row := []byte(`{"subsession_id":1234567890},`)
+
+		n, _ := w.Write([]byte("["))
+		atomic.AddInt64(written, int64(n))
+
+		for atomic.LoadInt64(written) < int64(total) {
+			n, err := w.Write(row)
+			atomic.AddInt64(written, int64(n))
+
+			if err != nil {
+				return
+			}
+
+			w.(http.Flusher).Flush()
+		}
+	})
+}
+
+func TestMaxResponseSizeAbortsDownload(t *testing.T) {
+	const total = 256 * 1024 * 1024
+
+	m := newMockAPI(t)
+
+	var written int64
+
+	done := make(chan struct{})
+
+	handleOversized(m, "/s3/big.json", total, &written, done)
+	m.handle("/data/results/search_series", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/s3/big.json?signature=abc"}`, m.URL)
+	})
+
+	api := m.openAuthed(t, WithMaxResponseSize(responseLimit))
+
+	_, err := api.Get("/data/results/search_series")
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	var tooLarge *ResponseTooLargeError
+
+	if assert.ErrorAs(t, err, &tooLarge) {
+		assert.True(t, tooLarge.Partial)
+		assert.Equal(t, int64(responseLimit), tooLarge.Limit)
+		assert.NotContains(t, tooLarge.URL, "signature=abc")
+		assert.Contains(t, err.Error(), "GetChunksWithCache")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("server kept writing")
+	}
+
+	// the socket buffers took some but not all of it
+	assert.Less(t, atomic.LoadInt64(&written), int64(total))
+
+	// not retried
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+}
+
+func TestMaxResponseSizeContentLength(t *testing.T) {
+	body := "[" + strings.Repeat(`{"id":1},`, responseLimit/9) + `{"id":1}]`
+
+	m := newMockAPI(t)
+	m.mux.HandleFunc("/s3/sized.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		fmt.Fprint(w, body)
+	})
+	m.handle("/data/results/search_series", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/s3/sized.json?signature=abc"}`, m.URL)
+	})
+	m.handleLinked("/data/constants/categories", `[{"label":"Oval","value":1}]`)
+
+	api := m.openAuthed(t, WithMaxResponseSize(responseLimit))
+
+	_, err := api.Get("/data/results/search_series")
+
+	var tooLarge *ResponseTooLargeError
+
+	if assert.ErrorAs(t, err, &tooLarge) {
+		assert.False(t, tooLarge.Partial)
+		assert.Equal(t, int64(len(body)), tooLarge.Size)
+	}
+
+	// smaller responses are fine
+	_, err = api.Get("/data/constants/categories")
+	assert.NoError(t, err)
+}
+
+func TestMaxResponseSizeChunks(t *testing.T) {
+	row := `{"subsession_id":1234567890}`
+	chunk := "[" + strings.Repeat(row+",", responseLimit/len(row)/2) + row + "]"
+
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(chunk, chunk, chunk))
+
+	api := m.openAuthed(t, WithMaxResponseSize(responseLimit))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	_, err := api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	// the third chunk was never downloaded
+	assert.Equal(t, 1, m.hitCount("/chunks/data/results/search_series/0/1.json"))
+	assert.Zero(t, m.hitCount("/chunks/data/results/search_series/0/2.json"))
+
+	// the streaming API isn't limited
+	chunks := 0
+
+	assert.NoError(t, api.GetChunksWithCache("/data/results/search_series", time.Hour, func(Chunk) error {
+		chunks++
+		return nil
+	}))
+	assert.Equal(t, 3, chunks)
+
+	// and now it's cached, reading it whole is refused without the network
+	hits := m.hitCount("/data/results/search_series")
+
+	_, err = api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Equal(t, hits, m.hitCount("/data/results/search_series"))
+}
+
+func TestMaxResponseSizeCachedWhole(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/search_series", "["+strings.Repeat(`{"id":1},`, responseLimit/9)+`{"id":1}]`)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	_, err := api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.NoError(t, err)
+
+	api.maxResponseSize = responseLimit
+
+	_, err = api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+
+	elements := 0
+
+	assert.NoError(t, api.GetElementsWithCache("/data/results/search_series", time.Hour, func(json.RawMessage) error {
+		elements++
+		return nil
+	}))
+	assert.Equal(t, responseLimit/9+1, elements)
+}
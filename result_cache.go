@@ -0,0 +1,170 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ResultCachePolicy decides how long GetSubsessionResultWithCache caches a
+// result.  Results can change while protests are pending, those of
+// sessions that ended more than FinalAge ago are taken to be final.
+type ResultCachePolicy struct {
+	// RecentTTL is how long results newer than FinalAge are cached, 15
+	// minutes by default.  It is capped by the documented expiration of
+	// /data/results/get after LoadEndpointExpirations.
+	RecentTTL time.Duration
+
+	// FinalAge is how long after the end of the session its results are
+	// final, 7 days by default
+	FinalAge time.Duration
+
+	// FinalTTL is how long final results are cached, 10 years by default
+	FinalTTL time.Duration
+}
+
+const (
+	defaultRecentResultTTL = 15 * time.Minute
+	defaultResultFinalAge  = 7 * 24 * time.Hour
+	defaultFinalResultTTL  = 10 * 365 * 24 * time.Hour
+)
+
+// WithResultCachePolicy sets how long GetSubsessionResultWithCache caches
+// results, zero fields keep the defaults
+func WithResultCachePolicy(policy ResultCachePolicy) Option {
+	return func(i *Irdata) {
+		i.resultCachePolicy = policy
+	}
+}
+
+func (p ResultCachePolicy) withDefaults() ResultCachePolicy {
+	if p.RecentTTL <= 0 {
+		p.RecentTTL = defaultRecentResultTTL
+	}
+
+	if p.FinalAge <= 0 {
+		p.FinalAge = defaultResultFinalAge
+	}
+
+	if p.FinalTTL <= 0 {
+		p.FinalTTL = defaultFinalResultTTL
+	}
+
+	return p
+}
+
+// resultAge is how long ago the session of result ended, false if the
+// result has no times
+func (i *Irdata) resultAge(result *SubsessionResult) (time.Duration, bool) {
+	ended := result.EndTime
+	if ended.IsZero() {
+		ended = result.StartTime
+	}
+
+	if ended.IsZero() {
+		return 0, false
+	}
+
+	return i.clock.Now().Sub(ended), true
+}
+
+// resultFinal reports whether result is final per the result cache policy
+func (i *Irdata) resultFinal(result *SubsessionResult) bool {
+	age, ok := i.resultAge(result)
+
+	return ok && age >= i.resultCachePolicy.withDefaults().FinalAge
+}
+
+// resultTTL is how long to cache result and why
+func (i *Irdata) resultTTL(result *SubsessionResult) (time.Duration, string) {
+	policy := i.resultCachePolicy.withDefaults()
+
+	official := "unofficial"
+	if result.OfficialSession {
+		official = "official"
+	}
+
+	if age, ok := i.resultAge(result); ok && age >= policy.FinalAge {
+		return policy.FinalTTL, fmt.Sprintf("final: %s session ended %s ago", official, age.Truncate(time.Hour))
+	}
+
+	ttl := i.cacheTTL(subsessionResultURI(result.SubsessionID), policy.RecentTTL)
+
+	return ttl, fmt.Sprintf("recent: %s session may change while protests are pending", official)
+}
+
+// GetSubsessionResultWithCache is GetSubsessionResult through the cache.
+// Unlike GetWithCache it picks the ttl itself from the result, see
+// WithResultCachePolicy, and records why in the Reason of the cache entry.
+//
+// You must call EnableCache before calling GetSubsessionResultWithCache
+func (i *Irdata) GetSubsessionResultWithCache(ctx context.Context, subsessionID int64) (*SubsessionResult, error) {
+	if i.cache == nil {
+		return nil, errors.New("cache must be enabled")
+	}
+
+	var result SubsessionResult
+
+	found, err := i.getCachedJSON(ctx, i.cacheKey(subsessionResultURI(subsessionID)), &result)
+	if err != nil {
+		if err := i.cacheFailed("read", subsessionResultURI(subsessionID), err); err != nil {
+			return nil, err
+		}
+
+		found = false
+	}
+
+	if found {
+		result.classifyEntries(i.reasonOutByID)
+
+		return &result, nil
+	}
+
+	return i.RefreshSubsessionResult(ctx, subsessionID)
+}
+
+// RefreshSubsessionResult fetches the results of the subsession even if
+// they are cached and caches them again, e.g. after a protest was upheld
+func (i *Irdata) RefreshSubsessionResult(ctx context.Context, subsessionID int64) (*SubsessionResult, error) {
+	if i.cache == nil {
+		return nil, errors.New("cache must be enabled")
+	}
+
+	uri := subsessionResultURI(subsessionID)
+
+	data, err := i.getSubsessionResultData(ctx, subsessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SubsessionResult
+
+	if err := i.decodeJSON(uri, data, &result); err != nil {
+		return nil, err
+	}
+
+	result.classifyEntries(i.reasonOutByID)
+
+	ttl, reason := i.resultTTL(&result)
+
+	i.logger.WithFields(log.Fields{
+		"uri":    uri,
+		"ttl":    ttl,
+		"reason": reason,
+	}).Debug("Caching subsession result")
+
+	key := i.cacheKey(uri)
+
+	if err := i.setCachedData(ctx, key, data, ttl); err != nil {
+		return &result, i.cacheFailed("write", uri, err)
+	}
+
+	if err := i.setCacheMeta(ctx, key, cacheMetaT{Size: len(data), Reason: reason}, ttl, ttl); err != nil {
+		return &result, i.cacheFailed("write", uri, err)
+	}
+
+	return &result, nil
+}
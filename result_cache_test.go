@@ -0,0 +1,115 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func handleSubsessionFixtures(m *mockAPI) {
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("subsession_id") {
+		case "68911202":
+			http.ServeFile(w, r, "testdata/subsession_fresh.json")
+		case "61290877":
+			http.ServeFile(w, r, "testdata/subsession_old.json")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func resultCacheEntry(t *testing.T, api *Irdata, subsessionID int64) CacheEntryInfo {
+	entries, err := api.CacheEntries()
+	assert.NoError(t, err)
+
+	for _, entry := range entries {
+		if entry.URI == subsessionResultURI(subsessionID) {
+			return entry
+		}
+	}
+
+	t.Fatalf("subsession %d isn't cached", subsessionID)
+
+	return CacheEntryInfo{}
+}
+
+func TestSubsessionResultTTLByAge(t *testing.T) {
+	m := newMockAPI(t)
+	handleSubsessionFixtures(m)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	fresh, err := api.GetSubsessionResultWithCache(context.Background(), 68911202)
+	assert.NoError(t, err)
+	assert.Equal(t, "Prost Alain", fresh.SessionResults[0].Results[0].DisplayName)
+
+	old, err := api.GetSubsessionResultWithCache(context.Background(), 61290877)
+	assert.NoError(t, err)
+	assert.Equal(t, "Summit Point Raceway", old.Track.TrackName)
+
+	entry := resultCacheEntry(t, api, 68911202)
+	assert.Equal(t, defaultRecentResultTTL, entry.Expires.Sub(entry.Created))
+	assert.True(t, strings.HasPrefix(entry.Reason, "recent:"), entry.Reason)
+
+	entry = resultCacheEntry(t, api, 61290877)
+	assert.Equal(t, defaultFinalResultTTL, entry.Expires.Sub(entry.Created))
+	assert.True(t, strings.HasPrefix(entry.Reason, "final:"), entry.Reason)
+
+	clock.advance(time.Hour)
+
+	for _, subsessionID := range []int64{68911202, 61290877} {
+		_, err := api.GetSubsessionResultWithCache(context.Background(), subsessionID)
+		assert.NoError(t, err)
+	}
+
+	// only the fresh result expired
+	assert.Equal(t, 3, m.hitCount("/data/results/get"))
+}
+
+func TestSubsessionResultCachePolicy(t *testing.T) {
+	m := newMockAPI(t)
+	handleSubsessionFixtures(m)
+
+	clock := newFakeClock()
+	api := m.openAuthed(t, WithClock(clock), WithResultCachePolicy(ResultCachePolicy{FinalAge: 365 * 24 * time.Hour, RecentTTL: time.Minute}))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	_, err := api.GetSubsessionResultWithCache(context.Background(), 61290877)
+	assert.NoError(t, err)
+
+	// not a year old yet
+	entry := resultCacheEntry(t, api, 61290877)
+	assert.Equal(t, time.Minute, entry.Expires.Sub(entry.Created))
+}
+
+func TestRefreshSubsessionResult(t *testing.T) {
+	m := newMockAPI(t)
+	handleSubsessionFixtures(m)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	for n := 0; n < 2; n++ {
+		_, err := api.GetSubsessionResultWithCache(context.Background(), 61290877)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, m.hitCount("/data/results/get"))
+
+	_, err := api.RefreshSubsessionResult(context.Background(), 61290877)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, m.hitCount("/data/results/get"))
+
+	_, err = api.GetSubsessionResultWithCache(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotYetAvailable)
+
+	_, err = m.openAuthed(t).GetSubsessionResultWithCache(context.Background(), 61290877)
+	assert.Error(t, err)
+}
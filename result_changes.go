@@ -0,0 +1,381 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ResultChangeKind says how the result of a participant changed
+type ResultChangeKind string
+
+// The kinds of result changes.  Penalties show up as incidents added or
+// laps taken away, a disqualification as a StatusChanged.
+const (
+	ParticipantAdded   ResultChangeKind = "participant_added"
+	ParticipantRemoved ResultChangeKind = "participant_removed"
+	PositionChanged    ResultChangeKind = "position_changed"
+	PenaltyChanged     ResultChangeKind = "penalty_changed"
+	PointsChanged      ResultChangeKind = "points_changed"
+	StatusChanged      ResultChangeKind = "status_changed"
+)
+
+// ResultChange is a change of the result of a participant, a driver or in
+// team events a team, in a simsession.  Old and New are the rows before
+// and after, nil when the participant was added or removed.
+type ResultChange struct {
+	Kind               ResultChangeKind
+	SimsessionNumber   int
+	SimsessionTypeName string
+	CustID             int64
+	TeamID             int64
+	DisplayName        string
+	Old                *SessionResultRow
+	New                *SessionResultRow
+}
+
+func (c ResultChange) String() string {
+	switch c.Kind {
+	case PositionChanged:
+		return fmt.Sprintf("%s %s: position %d to %d", c.SimsessionTypeName, c.DisplayName, c.Old.FinishPosition+1, c.New.FinishPosition+1)
+	case PenaltyChanged:
+		return fmt.Sprintf("%s %s: %d incidents and %d laps to %d and %d", c.SimsessionTypeName, c.DisplayName,
+			c.Old.Incidents, c.Old.LapsComplete, c.New.Incidents, c.New.LapsComplete)
+	case PointsChanged:
+		return fmt.Sprintf("%s %s: %d points to %d", c.SimsessionTypeName, c.DisplayName, c.Old.ChampPoints, c.New.ChampPoints)
+	case StatusChanged:
+		return fmt.Sprintf("%s %s: %s to %s", c.SimsessionTypeName, c.DisplayName, c.Old.Reason(), c.New.Reason())
+	}
+
+	return fmt.Sprintf("%s %s: %s", c.SimsessionTypeName, c.DisplayName, c.Kind)
+}
+
+// participantKey identifies a participant of a simsession, teams by their
+// team id
+type participantKey struct {
+	simsession int
+	custID     int64
+	teamID     int64
+}
+
+func rowKey(simsession int, row *SessionResultRow) participantKey {
+	if row.TeamID != 0 {
+		return participantKey{simsession: simsession, teamID: row.TeamID}
+	}
+
+	return participantKey{simsession: simsession, custID: row.CustID}
+}
+
+// DiffSubsessionResults returns how the results changed from old to new,
+// e.g. after protests were decided, in the order of the simsessions of new
+// and then of the positions.  Only the positions, incidents, laps, points
+// and status of the rows are compared, the order of the rows and anything
+// else may change freely.  In team events only the team rows are compared.
+func DiffSubsessionResults(old *SubsessionResult, new *SubsessionResult) []ResultChange {
+	var changes []ResultChange
+
+	// the simsessions of new first, then those only old has
+	var order []int
+
+	before := make(map[int]*SessionResults)
+	after := make(map[int]*SessionResults)
+
+	for n := range new.SessionResults {
+		session := &new.SessionResults[n]
+		after[session.SimsessionNumber] = session
+		order = append(order, session.SimsessionNumber)
+	}
+
+	for n := range old.SessionResults {
+		session := &old.SessionResults[n]
+		before[session.SimsessionNumber] = session
+
+		if _, ok := after[session.SimsessionNumber]; !ok {
+			order = append(order, session.SimsessionNumber)
+		}
+	}
+
+	for _, number := range order {
+		changes = append(changes, sessionChanges(number, before[number], after[number])...)
+	}
+
+	return changes
+}
+
+// sessionChanges compares the rows of a simsession, either may be nil
+func sessionChanges(number int, old *SessionResults, new *SessionResults) []ResultChange {
+	var changes []ResultChange
+
+	typeName := ""
+	before := make(map[participantKey]*SessionResultRow)
+	after := make(map[participantKey]*SessionResultRow)
+
+	if old != nil {
+		typeName = old.SimsessionTypeName
+
+		for n := range old.Results {
+			before[rowKey(number, &old.Results[n])] = &old.Results[n]
+		}
+	}
+
+	if new != nil {
+		typeName = new.SimsessionTypeName
+
+		for n := range new.Results {
+			after[rowKey(number, &new.Results[n])] = &new.Results[n]
+		}
+	}
+
+	change := func(kind ResultChangeKind, key participantKey, o *SessionResultRow, n *SessionResultRow) {
+		row := n
+		if row == nil {
+			row = o
+		}
+
+		changes = append(changes, ResultChange{
+			Kind:               kind,
+			SimsessionNumber:   number,
+			SimsessionTypeName: typeName,
+			CustID:             key.custID,
+			TeamID:             key.teamID,
+			DisplayName:        row.DisplayName,
+			Old:                o,
+			New:                n,
+		})
+	}
+
+	for key, n := range after {
+		o, ok := before[key]
+		if !ok {
+			change(ParticipantAdded, key, nil, n)
+			continue
+		}
+
+		if o.FinishPosition != n.FinishPosition || o.FinishPositionInClass != n.FinishPositionInClass {
+			change(PositionChanged, key, o, n)
+		}
+
+		if o.Incidents != n.Incidents || o.LapsComplete != n.LapsComplete {
+			change(PenaltyChanged, key, o, n)
+		}
+
+		if o.ChampPoints != n.ChampPoints {
+			change(PointsChanged, key, o, n)
+		}
+
+		if o.Reason() != n.Reason() {
+			change(StatusChanged, key, o, n)
+		}
+	}
+
+	for key, o := range before {
+		if _, ok := after[key]; !ok {
+			change(ParticipantRemoved, key, o, nil)
+		}
+	}
+
+	kinds := map[ResultChangeKind]int{
+		ParticipantAdded:   0,
+		ParticipantRemoved: 1,
+		PositionChanged:    2,
+		PenaltyChanged:     3,
+		PointsChanged:      4,
+		StatusChanged:      5,
+	}
+
+	position := func(c ResultChange) int {
+		if c.New != nil {
+			return c.New.FinishPosition
+		}
+
+		return c.Old.FinishPosition
+	}
+
+	sort.Slice(changes, func(a, b int) bool {
+		if position(changes[a]) != position(changes[b]) {
+			return position(changes[a]) < position(changes[b])
+		}
+
+		if changes[a].TeamID != changes[b].TeamID {
+			return changes[a].TeamID < changes[b].TeamID
+		}
+
+		if changes[a].CustID != changes[b].CustID {
+			return changes[a].CustID < changes[b].CustID
+		}
+
+		return kinds[changes[a].Kind] < kinds[changes[b].Kind]
+	})
+
+	return changes
+}
+
+// ResultUpdate is a new version of the result of a followed subsession
+// along with how it changed
+type ResultUpdate struct {
+	SubsessionID int64
+	Old          *SubsessionResult
+	New          *SubsessionResult
+	Changes      []ResultChange
+}
+
+// ResultChangesWatcher polls the results of followed subsessions and
+// delivers those that changed, e.g. after protests or penalties.  A
+// subsession is followed until its result is final per the result cache
+// policy, see WithResultCachePolicy.
+//
+// When the cache is enabled the first version compared with is the cached
+// one and every version fetched is cached, as RefreshSubsessionResult does.
+type ResultChangesWatcher struct {
+	// MaxBackoff caps the delay between polls after consecutive errors
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every error encountered while polling
+	OnError func(error)
+
+	i        *Irdata
+	ids      []int64
+	interval time.Duration
+	results  map[int64]*SubsessionResult
+}
+
+// NewResultChangesWatcher returns a watcher for the results of
+// subsessionIDs that polls every interval.  Call Watch to start it.
+func (i *Irdata) NewResultChangesWatcher(subsessionIDs []int64, interval time.Duration) *ResultChangesWatcher {
+	return &ResultChangesWatcher{
+		MaxBackoff: defaultWatchMaxBackoff,
+		i:          i,
+		ids:        uniqueIDs(subsessionIDs),
+		interval:   interval,
+		results:    make(map[int64]*SubsessionResult),
+	}
+}
+
+// Watch starts polling and returns the channel the updates are delivered
+// on.  The first result fetched of a subsession is only compared with
+// later.  The channel is closed once ctx is done, the instance is closed
+// or every result followed is final.
+func (w *ResultChangesWatcher) Watch(ctx context.Context) <-chan ResultUpdate {
+	out := make(chan ResultUpdate)
+
+	ctx, stop, ok := w.i.beginWatch(ctx)
+	if !ok {
+		close(out)
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		defer stop()
+
+		failures := 0
+
+		for len(w.ids) > 0 {
+			delay := w.interval
+
+			updates, err := w.poll(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				failures++
+				delay = watchBackoff(w.interval, w.MaxBackoff, failures)
+
+				w.i.logger.WithFields(log.Fields{
+					"err":      err,
+					"failures": failures,
+					"delay":    delay,
+				}).Info("Result changes watcher poll failed")
+
+				if w.OnError != nil {
+					w.OnError(err)
+				}
+			} else {
+				failures = 0
+			}
+
+			for _, update := range updates {
+				select {
+				case out <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(w.ids) == 0 {
+				return
+			}
+
+			timer := w.i.clock.NewTimer(delay)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+
+	return out
+}
+
+// poll fetches the results followed and returns those that changed,
+// dropping the final ones.  Results not yet available are tried again on
+// the next poll.
+func (w *ResultChangesWatcher) poll(ctx context.Context) ([]ResultUpdate, error) {
+	var updates []ResultUpdate
+
+	var following []int64
+
+	for n, id := range w.ids {
+		result, err := w.fetch(ctx, id)
+		if errors.Is(err, ErrNotYetAvailable) {
+			following = append(following, id)
+			continue
+		}
+
+		if err != nil {
+			w.ids = append(following, w.ids[n:]...)
+			return updates, err
+		}
+
+		if old := w.results[id]; old != nil {
+			if changes := DiffSubsessionResults(old, result); len(changes) > 0 {
+				updates = append(updates, ResultUpdate{SubsessionID: id, Old: old, New: result, Changes: changes})
+			}
+		}
+
+		w.results[id] = result
+
+		if w.i.resultFinal(result) {
+			w.i.logger.WithFields(log.Fields{"subsessionID": id}).Debug("Result final, no longer watching it")
+
+			delete(w.results, id)
+			continue
+		}
+
+		following = append(following, id)
+	}
+
+	w.ids = following
+
+	return updates, nil
+}
+
+func (w *ResultChangesWatcher) fetch(ctx context.Context, id int64) (*SubsessionResult, error) {
+	if w.i.cache == nil {
+		return w.i.GetSubsessionResult(ctx, id)
+	}
+
+	if w.results[id] == nil {
+		return w.i.GetSubsessionResultWithCache(ctx, id)
+	}
+
+	return w.i.RefreshSubsessionResult(ctx, id)
+}
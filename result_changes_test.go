@@ -0,0 +1,169 @@
+package irdata
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func changeStrings(changes []ResultChange) []string {
+	var s []string
+
+	for _, change := range changes {
+		s = append(s, change.String())
+	}
+
+	return s
+}
+
+// a protest disqualified Mansell and cost Piquet a lap, Berger was removed
+// and Alesi added.  The final version also reorders everything and changes
+// helmets.
+func TestDiffSubsessionResults(t *testing.T) {
+	provisional := loadSubsession(t, "testdata/subsession_protest_provisional.json")
+	final := loadSubsession(t, "testdata/subsession_protest_final.json")
+
+	changes := DiffSubsessionResults(provisional, final)
+
+	assert.Equal(t, []string{
+		"Race Piquet Nelson: position 4 to 3",
+		"Race Piquet Nelson: 8 incidents and 20 laps to 12 and 19",
+		"Race Piquet Nelson: 90 points to 100",
+		"Race Alesi Jean: participant_added",
+		"Race Mansell Nigel: position 3 to 5",
+		"Race Mansell Nigel: 100 points to 0",
+		"Race Mansell Nigel: running to disqualified",
+		"Race Berger Gerhard: participant_removed",
+	}, changeStrings(changes))
+
+	added := changes[3]
+	assert.Equal(t, ParticipantAdded, added.Kind)
+	assert.Equal(t, int64(206), added.CustID)
+	assert.Nil(t, added.Old)
+
+	removed := changes[7]
+	assert.Equal(t, int64(205), removed.CustID)
+	assert.Nil(t, removed.New)
+	assert.Equal(t, 0, removed.SimsessionNumber)
+
+	assert.Empty(t, DiffSubsessionResults(final, final))
+	assert.Len(t, DiffSubsessionResults(final, provisional), len(changes))
+}
+
+func TestDiffSubsessionResultsSessions(t *testing.T) {
+	provisional := loadSubsession(t, "testdata/subsession_protest_provisional.json")
+	final := loadSubsession(t, "testdata/subsession_protest_final.json")
+
+	// the qualifying only old has
+	final.SessionResults = final.SessionResults[1:]
+
+	changes := DiffSubsessionResults(provisional, final)
+
+	assert.Len(t, changes, 10)
+	assert.Equal(t, "Lone Qualifying Senna Ayrton: participant_removed", changes[8].String())
+	assert.Equal(t, "Lone Qualifying Prost Alain: participant_removed", changes[9].String())
+}
+
+func TestDiffSubsessionResultsTeams(t *testing.T) {
+	old := loadSubsession(t, "testdata/subsession_team.json")
+	new := loadSubsession(t, "testdata/subsession_team.json")
+
+	race := &new.SessionResults[len(new.SessionResults)-1]
+	race.Results[0].Incidents += 4
+
+	// a driver's incidents are the team's
+	race.Results[0].DriverResults[0].Incidents += 4
+
+	changes := DiffSubsessionResults(old, new)
+
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, PenaltyChanged, changes[0].Kind)
+		assert.Equal(t, race.Results[0].TeamID, changes[0].TeamID)
+		assert.Equal(t, int64(0), changes[0].CustID)
+	}
+}
+
+func TestResultChangesWatcher(t *testing.T) {
+	m := newMockAPI(t)
+
+	var mu sync.Mutex
+	fixture := ""
+
+	serve := func(fileName string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fixture = fileName
+	}
+
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fixture == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		http.ServeFile(w, r, fixture)
+	})
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCacheWithClock(clock))
+
+	updates := api.NewResultChangesWatcher([]int64{69120455}, time.Minute).Watch(api.ctx)
+
+	tick := func(d time.Duration) {
+		for clock.waiting() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		clock.advance(d)
+	}
+
+	polled := func(n int) {
+		for m.hitCount("/data/results/get") < n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// not scored yet, then the provisional result to compare with
+	polled(1)
+	serve("testdata/subsession_protest_provisional.json")
+	tick(time.Minute)
+	polled(2)
+
+	serve("testdata/subsession_protest_final.json")
+	tick(time.Minute)
+
+	select {
+	case update := <-updates:
+		assert.Equal(t, int64(69120455), update.SubsessionID)
+		assert.Equal(t, 5, update.Old.SessionResults[0].Results[4].FinishPosition+1)
+		assert.Len(t, update.Changes, 8)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no update")
+	}
+
+	// the cache holds the final result
+	cached, err := api.GetSubsessionResultWithCache(api.ctx, 69120455)
+	assert.NoError(t, err)
+	assert.Equal(t, "Disqualified", cached.SessionResults[1].Results[4].ReasonOut)
+
+	// once final the result is no longer watched
+	tick(12 * 24 * time.Hour)
+
+	select {
+	case update, ok := <-updates:
+		assert.False(t, ok, "unexpected update %v", update)
+	case <-time.After(2 * time.Second):
+		t.Fatal("still watching")
+	}
+
+	assert.Equal(t, 4, m.hitCount("/data/results/get"))
+}
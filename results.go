@@ -0,0 +1,323 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the /data/results/search_* endpoints reject ranges longer than this
+const maxSearchWindow = 90 * 24 * time.Hour
+
+// searchTimeFormat is the time format the search endpoints expect
+const searchTimeFormat = "2006-01-02T15:04Z"
+
+// SearchTrack is the track reference carried by search results
+type SearchTrack struct {
+	TrackID    int64  `json:"track_id"`
+	TrackName  string `json:"track_name"`
+	ConfigName string `json:"config_name"`
+}
+
+// SearchResult is a row returned by the /data/results/search_series and
+// /data/results/search_hosted endpoints.  When searching by cust_id the
+// driver fields describe that member's participation.
+type SearchResult struct {
+	SessionID               int64       `json:"session_id"`
+	SubsessionID            int64       `json:"subsession_id"`
+	StartTime               time.Time   `json:"start_time"`
+	EndTime                 time.Time   `json:"end_time"`
+	LicenseCategoryID       int64       `json:"license_category_id"`
+	LicenseCategory         string      `json:"license_category"`
+	NumDrivers              int         `json:"num_drivers"`
+	NumCautions             int         `json:"num_cautions"`
+	NumCautionLaps          int         `json:"num_caution_laps"`
+	NumLeadChanges          int         `json:"num_lead_changes"`
+	EventLapsComplete       int         `json:"event_laps_complete"`
+	DriverChanges           bool        `json:"driver_changes"`
+	WinnerGroupID           int64       `json:"winner_group_id"`
+	WinnerName              string      `json:"winner_name" pii:"name"`
+	Track                   SearchTrack `json:"track"`
+	OfficialSession         bool        `json:"official_session"`
+	SeasonID                int64       `json:"season_id"`
+	SeasonYear              int         `json:"season_year"`
+	SeasonQuarter           int         `json:"season_quarter"`
+	EventType               int         `json:"event_type"`
+	EventTypeName           string      `json:"event_type_name"`
+	SeriesID                int64       `json:"series_id"`
+	SeriesName              string      `json:"series_name"`
+	SeriesShortName         string      `json:"series_short_name"`
+	RaceWeekNum             int         `json:"race_week_num"`
+	EventStrengthOfField    int         `json:"event_strength_of_field"`
+	EventAverageLap         int         `json:"event_average_lap"`
+	EventBestLapTime        int         `json:"event_best_lap_time"`
+	CustID                  int64       `json:"cust_id" pii:"cust_id"`
+	DisplayName             string      `json:"display_name" pii:"name"`
+	CarID                   int64       `json:"car_id"`
+	CarClassID              int64       `json:"car_class_id"`
+	StartingPosition        int         `json:"starting_position"`
+	FinishPosition          int         `json:"finish_position"`
+	StartingPositionInClass int         `json:"starting_position_in_class"`
+	FinishPositionInClass   int         `json:"finish_position_in_class"`
+	Incidents               int         `json:"incidents"`
+}
+
+// SearchSeriesParams are the parameters of SearchSeriesResults.  Zero values
+// are left out of the query.
+//
+// Either SeasonYear and SeasonQuarter or one of the time ranges must be
+// provided.  Ranges longer than the 90 days the API allows are split into
+// several searches.
+type SearchSeriesParams struct {
+	SeasonYear       int
+	SeasonQuarter    int
+	StartRangeBegin  time.Time
+	StartRangeEnd    time.Time
+	FinishRangeBegin time.Time
+	FinishRangeEnd   time.Time
+	CustID           int64
+	TeamID           int64
+	SeriesID         int64
+	RaceWeekNum      *int
+	OfficialOnly     bool
+	EventTypes       []int
+	CategoryIDs      []int64
+}
+
+func (p SearchSeriesParams) values() url.Values {
+	v := url.Values{}
+
+	setInt(v, "season_year", int64(p.SeasonYear))
+	setInt(v, "season_quarter", int64(p.SeasonQuarter))
+	setTime(v, "start_range_begin", p.StartRangeBegin)
+	setTime(v, "start_range_end", p.StartRangeEnd)
+	setTime(v, "finish_range_begin", p.FinishRangeBegin)
+	setTime(v, "finish_range_end", p.FinishRangeEnd)
+	setInt(v, "cust_id", p.CustID)
+	setInt(v, "team_id", p.TeamID)
+	setInt(v, "series_id", p.SeriesID)
+	setInts(v, "event_types", p.EventTypes)
+	setInts(v, "category_ids", p.CategoryIDs)
+
+	if p.RaceWeekNum != nil {
+		v.Set("race_week_num", strconv.Itoa(*p.RaceWeekNum))
+	}
+
+	if p.OfficialOnly {
+		v.Set("official_only", "true")
+	}
+
+	return v
+}
+
+// windows splits the time ranges of p into searches the API will accept
+func (p SearchSeriesParams) windows() []SearchSeriesParams {
+	var windows []SearchSeriesParams
+
+	for _, r := range searchWindows(
+		timeRange{begin: p.StartRangeBegin, end: p.StartRangeEnd},
+		timeRange{begin: p.FinishRangeBegin, end: p.FinishRangeEnd},
+	) {
+		w := p
+		w.StartRangeBegin, w.StartRangeEnd = r.start.begin, r.start.end
+		w.FinishRangeBegin, w.FinishRangeEnd = r.finish.begin, r.finish.end
+		windows = append(windows, w)
+	}
+
+	return windows
+}
+
+// searchWindowT is the start and finish ranges of one search
+type searchWindowT struct {
+	start  timeRange
+	finish timeRange
+}
+
+// searchWindows splits the start or, failing that, the finish range of a
+// search into windows the API will accept, the ranges that don't need
+// splitting are kept as they are
+func searchWindows(start timeRange, finish timeRange) []searchWindowT {
+	var windows []searchWindowT
+
+	switch {
+	case start.end.Sub(start.begin) > maxSearchWindow && !start.begin.IsZero():
+		for _, r := range splitRange(start.begin, start.end) {
+			// nothing started in r finished in the finish range
+			if !finish.end.IsZero() && !r.begin.Before(finish.end) {
+				break
+			}
+
+			windows = append(windows, searchWindowT{start: r, finish: finish})
+		}
+	case finish.end.Sub(finish.begin) > maxSearchWindow && !finish.begin.IsZero():
+		for _, r := range splitRange(finish.begin, finish.end) {
+			windows = append(windows, searchWindowT{start: start, finish: r})
+		}
+	default:
+		windows = append(windows, searchWindowT{start: start, finish: finish})
+	}
+
+	return windows
+}
+
+type timeRange struct {
+	begin time.Time
+	end   time.Time
+}
+
+// splitRange splits [begin, end) into consecutive ranges no longer than maxSearchWindow
+func splitRange(begin time.Time, end time.Time) []timeRange {
+	var ranges []timeRange
+
+	for b := begin; b.Before(end); b = b.Add(maxSearchWindow) {
+		e := b.Add(maxSearchWindow)
+		if e.After(end) {
+			e = end
+		}
+
+		ranges = append(ranges, timeRange{begin: b, end: e})
+	}
+
+	return ranges
+}
+
+// SearchMeta describes how a set of search results was put together
+type SearchMeta struct {
+	// Windows is the number of searches made to cover the time range
+	Windows int
+
+	// Fetched is the number of rows the API returned
+	Fetched int
+
+	// Returned is the number of rows left after filtering client side
+	Returned int
+}
+
+// SearchResults are the rows returned by a search and how they were found
+type SearchResults struct {
+	Rows []SearchResult
+	Meta SearchMeta
+}
+
+// SearchSeriesResults searches official series results via
+// /data/results/search_series, merging the chunks of every window searched.
+//
+// The server doesn't reliably apply event_types (it's ignored for some
+// seasons) or official_only, so rows not matching EventTypes or
+// OfficialOnly are also dropped client side.  Compare Meta.Fetched with
+// Meta.Returned to see how much the server let through.
+func (i *Irdata) SearchSeriesResults(ctx context.Context, params SearchSeriesParams) (*SearchResults, error) {
+	return i.searchSeriesResults(ctx, params, 0)
+}
+
+// searchSeriesResults is SearchSeriesResults caching the searches for ttl,
+// if not 0, when the cache is enabled
+func (i *Irdata) searchSeriesResults(ctx context.Context, params SearchSeriesParams, ttl time.Duration) (*SearchResults, error) {
+	if params.SeasonYear == 0 && params.StartRangeBegin.IsZero() && params.FinishRangeBegin.IsZero() {
+		return nil, errors.New("must provide season year and quarter or a time range")
+	}
+
+	if err := validateParams("/data/results/search_series?" + params.values().Encode()); err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{}
+
+	for _, w := range params.windows() {
+		rows, err := i.searchResults(ctx, "/data/results/search_series", w.values(), ttl)
+		if err != nil {
+			return nil, err
+		}
+
+		results.Meta.Windows++
+		results.Meta.Fetched += len(rows)
+
+		for _, row := range rows {
+			if matchesEventFilter(row, params.EventTypes, params.OfficialOnly) {
+				results.Rows = append(results.Rows, row)
+			}
+		}
+	}
+
+	results.Meta.Returned = len(results.Rows)
+
+	return results, nil
+}
+
+// matchesEventFilter reports whether row is one of eventTypes (any if
+// empty) and official if officialOnly
+func matchesEventFilter(row SearchResult, eventTypes []int, officialOnly bool) bool {
+	if officialOnly && !row.OfficialSession {
+		return false
+	}
+
+	if len(eventTypes) == 0 {
+		return true
+	}
+
+	for _, eventType := range eventTypes {
+		if row.EventType == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchResults fetches one (chunked) search_* result set, through the
+// cache for ttl if not 0
+func (i *Irdata) searchResults(ctx context.Context, endpoint string, v url.Values, ttl time.Duration) ([]SearchResult, error) {
+	uri := endpoint + "?" + v.Encode()
+
+	i.logger.WithFields(log.Fields{"uri": uri}).Debug("Searching results")
+
+	var rows []SearchResult
+
+	if ttl > 0 {
+		if err := i.getJSONWithTTL(ctx, uri, ttl, &rows); err != nil {
+			return nil, err
+		}
+
+		return rows, nil
+	}
+
+	if err := i.GetJSON(ctx, uri, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func setInt(v url.Values, key string, value int64) {
+	if value != 0 {
+		v.Set(key, strconv.FormatInt(value, 10))
+	}
+}
+
+func setInts[T int | int64](v url.Values, key string, values []T) {
+	if len(values) == 0 {
+		return
+	}
+
+	s := ""
+
+	for n, value := range values {
+		if n > 0 {
+			s += ","
+		}
+
+		s += strconv.FormatInt(int64(value), 10)
+	}
+
+	v.Set(key, s)
+}
+
+func setTime(v url.Values, key string, value time.Time) {
+	if !value.IsZero() {
+		v.Set(key, value.UTC().Format(searchTimeFormat))
+	}
+}
@@ -0,0 +1,129 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchSeriesParamsWindows(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	p := SearchSeriesParams{StartRangeBegin: begin, StartRangeEnd: begin.Add(200 * 24 * time.Hour)}
+
+	windows := p.windows()
+
+	assert.Len(t, windows, 3)
+	assert.Equal(t, begin, windows[0].StartRangeBegin)
+	assert.Equal(t, windows[0].StartRangeEnd, windows[1].StartRangeBegin)
+	assert.Equal(t, p.StartRangeEnd, windows[2].StartRangeEnd)
+
+	short := SearchSeriesParams{StartRangeBegin: begin, StartRangeEnd: begin.Add(time.Hour)}
+
+	assert.Equal(t, []SearchSeriesParams{short}, short.windows())
+}
+
+func TestSearchSeriesParamsValues(t *testing.T) {
+	week := 0
+
+	v := SearchSeriesParams{
+		SeasonYear:    2024,
+		SeasonQuarter: 3,
+		CustID:        4242,
+		RaceWeekNum:   &week,
+		OfficialOnly:  true,
+		EventTypes:    []int{2, 5},
+	}.values()
+
+	assert.Equal(t, "2024", v.Get("season_year"))
+	assert.Equal(t, "4242", v.Get("cust_id"))
+	assert.Equal(t, "0", v.Get("race_week_num"))
+	assert.Equal(t, "true", v.Get("official_only"))
+	assert.Equal(t, "2,5", v.Get("event_types"))
+	assert.False(t, v.Has("team_id"))
+}
+
+func TestSearchSeriesResults(t *testing.T) {
+	m := newMockAPI(t)
+
+	var queries []string
+
+	m.handleChunked("/data/results/search_series", func(r *http.Request) []string {
+		queries = append(queries, r.URL.Query().Get("start_range_begin"))
+
+		return []string{
+			`[{"subsession_id":1,"start_time":"2024-01-01T12:00:00Z"}]`,
+			`[{"subsession_id":2,"start_time":"2024-01-01T13:00:00Z"}]`,
+		}
+	})
+
+	api := m.openAuthed(t)
+
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	results, err := api.SearchSeriesResults(context.Background(), SearchSeriesParams{
+		StartRangeBegin: begin,
+		StartRangeEnd:   begin.Add(100 * 24 * time.Hour),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-01-01T00:00Z", "2024-03-31T00:00Z"}, queries)
+	assert.Len(t, results.Rows, 4)
+	assert.Equal(t, int64(2), results.Rows[1].SubsessionID)
+	assert.Equal(t, begin.Add(13*time.Hour), results.Rows[1].StartTime)
+	assert.Equal(t, SearchMeta{Windows: 2, Fetched: 4, Returned: 4}, results.Meta)
+}
+
+func TestSearchSeriesResultsNeedsRange(t *testing.T) {
+	_, err := i.SearchSeriesResults(context.Background(), SearchSeriesParams{CustID: 1})
+	assert.Error(t, err)
+}
+
+// the server let practice and unofficial sessions through for this query
+func TestSearchSeriesResultsFilterLeak(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "search_series_leak.json"))
+	assert.NoError(t, err)
+
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", func(r *http.Request) []string {
+		assert.Equal(t, "5", r.URL.Query().Get("event_types"))
+		assert.Equal(t, "true", r.URL.Query().Get("official_only"))
+
+		return []string{string(fixture)}
+	})
+
+	api := m.openAuthed(t)
+
+	results, err := api.SearchSeriesResults(context.Background(), SearchSeriesParams{
+		SeasonYear:    2024,
+		SeasonQuarter: 2,
+		CustID:        4242,
+		EventTypes:    []int{5},
+		OfficialOnly:  true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, SearchMeta{Windows: 1, Fetched: 4, Returned: 2}, results.Meta)
+
+	for _, row := range results.Rows {
+		assert.Equal(t, 5, row.EventType)
+		assert.True(t, row.OfficialSession)
+	}
+}
+
+func TestMatchesEventFilter(t *testing.T) {
+	race := SearchResult{EventType: 5, OfficialSession: true}
+	practice := SearchResult{EventType: 2, OfficialSession: true}
+	unofficial := SearchResult{EventType: 5}
+
+	assert.True(t, matchesEventFilter(race, nil, false))
+	assert.True(t, matchesEventFilter(race, []int{4, 5}, true))
+	assert.False(t, matchesEventFilter(practice, []int{5}, false))
+	assert.False(t, matchesEventFilter(unofficial, nil, true))
+	assert.True(t, matchesEventFilter(unofficial, []int{5}, false))
+}
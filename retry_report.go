@@ -0,0 +1,212 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxReportAttempts bounds the attempts a RetryReport keeps, the earliest
+// are dropped past it
+const maxReportAttempts = 16
+
+// RetryAttempt is one attempt of a RetryReport
+type RetryAttempt struct {
+	Method string
+	URL    string
+
+	// Status is the status answered, 0 if the request failed in transport
+	// with Err.  Err is also set when a response was answered but its body
+	// couldn't be read or was empty.
+	Status int
+	Err    string
+
+	Duration time.Duration
+
+	// Backoff is how long was waited before the next attempt
+	Backoff time.Duration
+
+	// RateLimit is what the x-ratelimit headers of the response said, nil
+	// if it had none
+	RateLimit *RateLimit
+}
+
+// RetryReport is the error returned for a request that failed after being
+// retried, wrapping the error of the final attempt.  It lists what each
+// attempt got, up to the last 16 (Omitted counts those dropped).
+type RetryReport struct {
+	Attempts []RetryAttempt
+	Omitted  int
+	Elapsed  time.Duration
+	Err      error
+}
+
+func (r *RetryReport) Error() string {
+	outcomes := make([]string, 0, len(r.Attempts))
+
+	for _, attempt := range r.Attempts {
+		outcome := "error"
+		if attempt.Status != 0 {
+			outcome = fmt.Sprint(attempt.Status)
+		}
+
+		if attempt.Status != 0 && attempt.Err != "" {
+			outcome += "+error"
+		}
+
+		if attempt.Backoff > 0 {
+			outcome += fmt.Sprintf(" (waited %s)", attempt.Backoff)
+		}
+
+		outcomes = append(outcomes, outcome)
+	}
+
+	if r.Omitted > 0 {
+		outcomes = append([]string{fmt.Sprintf("%d more", r.Omitted)}, outcomes...)
+	}
+
+	return fmt.Sprintf("%v (%d attempts in %s: %s)", r.Err, r.Omitted+len(r.Attempts), r.Elapsed.Round(time.Millisecond), strings.Join(outcomes, ", "))
+}
+
+func (r *RetryReport) Unwrap() error {
+	return r.Err
+}
+
+// retryTranscript collects the attempts of a request for its RetryReport
+type retryTranscript struct {
+	mu       sync.Mutex
+	started  time.Time
+	attempts []RetryAttempt
+	omitted  int
+
+	// sent counts the attempts per url, which the nested retry loops share
+	sent map[string]int
+}
+
+type retryTranscriptKey struct{}
+
+// withRetryTranscript returns a context recording the attempts of the
+// requests made with it
+func (i *Irdata) withRetryTranscript(ctx context.Context) (context.Context, *retryTranscript) {
+	t := &retryTranscript{started: i.clock.Now()}
+
+	return context.WithValue(ctx, retryTranscriptKey{}, t), t
+}
+
+func retryTranscriptFrom(ctx context.Context) *retryTranscript {
+	t, _ := ctx.Value(retryTranscriptKey{}).(*retryTranscript)
+
+	return t
+}
+
+// attempt records an attempt, t may be nil
+func (t *retryTranscript) attempt(method string, url string, resp *http.Response, err error, duration time.Duration) {
+	if t == nil {
+		return
+	}
+
+	attempt := RetryAttempt{
+		Method:   method,
+		URL:      redactString(url, logRedaction()),
+		Duration: duration,
+	}
+
+	if err != nil {
+		attempt.Err = redactString(err.Error(), logRedaction())
+	} else {
+		attempt.Status = resp.StatusCode
+
+		if rl, ok := parseRateLimit(resp); ok {
+			attempt.RateLimit = &rl
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sent == nil {
+		t.sent = map[string]int{}
+	}
+
+	t.sent[url]++
+
+	if len(t.attempts) == maxReportAttempts {
+		t.attempts = append(t.attempts[:0], t.attempts[1:]...)
+		t.omitted++
+	}
+
+	t.attempts = append(t.attempts, attempt)
+}
+
+// failed records err as what went wrong with the last attempt, e.g. its
+// body being cut short
+func (t *retryTranscript) failed(err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.attempts) > 0 {
+		t.attempts[len(t.attempts)-1].Err = redactString(err.Error(), logRedaction())
+	}
+}
+
+// canRetry reports whether url may be sent again after attempt, the
+// attempt of the calling retry loop.  The retry loops nest (links, bodies
+// and responses are each retried) so they share maxAttempts per url,
+// counted by the attempts recorded.  t may be nil, then only attempt counts.
+func (t *retryTranscript) canRetry(url string, attempt int) bool {
+	if attempt >= maxAttempts {
+		return false
+	}
+
+	if t == nil {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.sent[url] < maxAttempts
+}
+
+// backoff records the wait after the last attempt
+func (t *retryTranscript) backoff(delay time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.attempts) > 0 {
+		t.attempts[len(t.attempts)-1].Backoff += delay
+	}
+}
+
+// report returns err as a *RetryReport if the request was retried, err
+// itself otherwise
+func (t *retryTranscript) report(err error, now time.Time) error {
+	if err == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.omitted+len(t.attempts) < 2 {
+		return err
+	}
+
+	return &RetryReport{
+		Attempts: append([]RetryAttempt{}, t.attempts...),
+		Omitted:  t.omitted,
+		Elapsed:  now.Sub(t.started),
+		Err:      err,
+	}
+}
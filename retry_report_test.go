@@ -0,0 +1,168 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryReportDataGet(t *testing.T) {
+	m := newMockAPI(t)
+
+	var calls int32
+
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.Header().Set("x-ratelimit-limit", "240")
+			w.Header().Set("x-ratelimit-remaining", "17")
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		dropConnection(t, w)
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	_, err := api.Get("/data/member/info")
+	assert.Error(t, err)
+
+	var report *RetryReport
+
+	if !assert.ErrorAs(t, err, &report) {
+		return
+	}
+
+	assert.Len(t, report.Attempts, maxAttempts)
+	assert.Equal(t, 0, report.Omitted)
+	assert.True(t, isTransientTransportError(report.Err))
+
+	for n, attempt := range report.Attempts {
+		assert.Equal(t, http.MethodGet, attempt.Method)
+		assert.Contains(t, attempt.URL, "/data/member/info")
+
+		if n < 2 {
+			assert.Equal(t, http.StatusServiceUnavailable, attempt.Status)
+			assert.Empty(t, attempt.Err)
+			assert.Equal(t, &RateLimit{Limit: 240, Remaining: 17, Reset: time.Unix(0, 0)}, attempt.RateLimit)
+		} else {
+			assert.Equal(t, 0, attempt.Status)
+			assert.NotEmpty(t, attempt.Err)
+			assert.Nil(t, attempt.RateLimit)
+		}
+
+		if n < maxAttempts-1 {
+			assert.Equal(t, time.Duration(n+2)*retryBackoff, attempt.Backoff)
+		} else {
+			assert.Zero(t, attempt.Backoff)
+		}
+	}
+
+	// the fake clock moved on by the backoffs
+	assert.Equal(t, 14*retryBackoff, report.Elapsed)
+
+	assert.Contains(t, err.Error(), "(5 attempts in 1m10s: 503 (waited 10s), 503 (waited 15s), error (waited 20s), error (waited 25s), error)")
+}
+
+func TestRetryReportLinks(t *testing.T) {
+	m := newMockAPI(t)
+
+	// the link and the chunk are empty while iRacing regenerates them
+	m.handleLinked("/data/member/info", "")
+	m.handleChunked("/data/results/search_series", func(r *http.Request) []string {
+		return []string{""}
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	// the envelope of the link is the first attempt of its request
+	for uri, envelopes := range map[string]int{"/data/member/info": 1, "/data/results/search_series": 0} {
+		_, err := api.Get(uri)
+		assert.ErrorIs(t, err, ErrEmptyPayload, uri)
+
+		var report *RetryReport
+
+		if assert.ErrorAs(t, err, &report, uri) {
+			assert.Len(t, report.Attempts, envelopes+maxAttempts, uri)
+
+			for _, attempt := range report.Attempts[envelopes:] {
+				assert.Equal(t, http.StatusOK, attempt.Status)
+				assert.Equal(t, ErrEmptyPayload.Error(), attempt.Err)
+				assert.NotContains(t, attempt.URL, "signature=abc", "links are redacted")
+			}
+
+			assert.Contains(t, err.Error(), "200+error (waited 10s)")
+		}
+	}
+}
+
+func TestRetryReportAuth(t *testing.T) {
+	m := newMockAPI(t)
+	m.failLogin(http.StatusServiceUnavailable, `{"error":"Site Maintenance"}`)
+
+	api := m.open(t, WithClock(newFakeClock()))
+
+	err := api.AuthWithProvideCreds(testCreds{})
+	assert.ErrorIs(t, err, ErrMaintenance)
+
+	var report *RetryReport
+
+	if assert.ErrorAs(t, err, &report) {
+		assert.Len(t, report.Attempts, maxAttempts)
+		assert.Equal(t, http.MethodPost, report.Attempts[0].Method)
+	}
+}
+
+func TestRetryReportOnlyAfterRetries(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html></html>")
+	})
+
+	api := m.openAuthed(t)
+
+	_, err := api.Get("/data/page")
+	assert.ErrorIs(t, err, ErrNotJSON)
+
+	var report *RetryReport
+	assert.False(t, errors.As(err, &report))
+}
+
+func TestRetryTranscriptBounded(t *testing.T) {
+	clock := newFakeClock()
+	api := Open(context.Background(), WithClock(clock))
+
+	_, transcript := api.withRetryTranscript(context.Background())
+
+	for n := 0; n < 40; n++ {
+		transcript.attempt(http.MethodGet, fmt.Sprintf("https://example.com/%d", n), nil, errors.New("reset"), time.Millisecond)
+		transcript.backoff(time.Second)
+	}
+
+	clock.advance(time.Minute)
+
+	var report *RetryReport
+
+	err := transcript.report(errors.New("gave up"), clock.Now())
+	assert.ErrorAs(t, err, &report)
+
+	assert.Len(t, report.Attempts, maxReportAttempts)
+	assert.Equal(t, 40-maxReportAttempts, report.Omitted)
+	assert.Equal(t, "https://example.com/39", report.Attempts[maxReportAttempts-1].URL)
+	assert.Equal(t, time.Minute, report.Elapsed)
+	assert.Contains(t, err.Error(), "gave up (40 attempts in 1m0s: 24 more, error (waited 1s)")
+
+	// a nil transcript records nothing
+	var none *retryTranscript
+	none.attempt(http.MethodGet, "https://example.com", nil, errors.New("reset"), 0)
+	none.failed(errors.New("reset"))
+	none.backoff(time.Second)
+}
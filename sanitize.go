@@ -0,0 +1,82 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// how much of a broken chunk makes it into the error
+const chunkDumpSize = 64
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16BE = []byte{0xfe, 0xff}
+	bomUTF16LE = []byte{0xff, 0xfe}
+)
+
+// sanitizeChunk repairs the encoding problems chunk files are known to
+// arrive with: byte order marks, UTF-16 and stray control characters.
+// Valid JSON is returned untouched.
+func sanitizeChunk(data []byte) []byte {
+	if json.Valid(data) {
+		return data
+	}
+
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		data = data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16BE):
+		data = decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian)
+	case bytes.HasPrefix(data, bomUTF16LE):
+		data = decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian)
+	}
+
+	if json.Valid(data) {
+		return data
+	}
+
+	// raw control characters are never valid JSON, not even within strings,
+	// so dropping them can't change the content of anything parseable
+	return bytes.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+
+		return r
+	}, data)
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+
+	for n := range units {
+		units[n] = order.Uint16(data[2*n:])
+	}
+
+	buf := make([]byte, 0, len(units))
+
+	for _, r := range utf16.Decode(units) {
+		buf = utf8.AppendRune(buf, r)
+	}
+
+	return buf
+}
+
+// chunkError explains why the chunk downloaded from chunkURL can't be used,
+// including the first bytes of data so odd encodings can be recognized
+func chunkError(chunkURL string, data []byte, err error) error {
+	dump := data
+	if len(dump) > chunkDumpSize {
+		dump = dump[:chunkDumpSize]
+	}
+
+	policy := logRedaction()
+
+	return fmt.Errorf("chunk %s is not valid JSON: %w\n%s",
+		redactString(chunkURL, policy), err, hex.Dump([]byte(redactString(string(dump), policy))))
+}
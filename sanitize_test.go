@@ -0,0 +1,65 @@
+package irdata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSanitizedChunk = `[{"subsession_id":1,"display_name":"Renée"},{"subsession_id":2,"display_name":"` + "\ufeff" + `BOM inside"}]`
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	assert.NoError(t, err)
+
+	return data
+}
+
+func TestSanitizeChunk(t *testing.T) {
+	for _, fixture := range []string{"chunk_bom.json", "chunk_utf16le.json", "chunk_control.json"} {
+		t.Run(fixture, func(t *testing.T) {
+			assert.JSONEq(t, testSanitizedChunk, string(sanitizeChunk(readFixture(t, fixture))))
+		})
+	}
+}
+
+func TestSanitizeChunkLeavesValidAlone(t *testing.T) {
+	for _, valid := range []string{
+		testSanitizedChunk,
+		`[{"name":"\u0000\ufeff\t"}]`,
+		"[\n\t{\"id\":1}\r\n]\n",
+	} {
+		assert.Equal(t, valid, string(sanitizeChunk([]byte(valid))))
+	}
+}
+
+func TestMockedChunkedGetSanitized(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(
+		string(readFixture(t, "chunk_bom.json")),
+		string(readFixture(t, "chunk_control.json")),
+	))
+
+	api := m.openAuthed(t)
+
+	data, err := api.Get("/data/results/search_series")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"subsession_id":1,"display_name":"Renée"},{"subsession_id":2,"display_name":"`+"\ufeff"+`BOM inside"},
+		{"subsession_id":1,"display_name":"Renée"},{"subsession_id":2,"display_name":"`+"\ufeff"+`BOM inside"}
+	]`, string(data))
+}
+
+func TestMockedChunkedGetBroken(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, "\xef\xbb\xbf[{\"id\":2}"))
+
+	api := m.openAuthed(t)
+
+	_, err := api.Get("/data/results/search_series")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), m.Server.URL+"/chunks/data/results/search_series/0/1.json")
+	assert.Contains(t, err.Error(), "ef bb bf 5b 7b 22 69 64")
+}
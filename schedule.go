@@ -0,0 +1,334 @@
+package irdata
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// seasonsSnapshotTTL is how long a SeasonsWatcher snapshot is kept, a
+// season lasts 12 weeks
+const seasonsSnapshotTTL = 180 * 24 * time.Hour
+
+// ScheduleChangeKind says how a schedule changed
+type ScheduleChangeKind string
+
+// The kinds of schedule changes
+const (
+	TrackSwapped       ScheduleChangeKind = "track_swapped"
+	SessionTimeChanged ScheduleChangeKind = "session_time_changed"
+	WeekAdded          ScheduleChangeKind = "week_added"
+	WeekRemoved        ScheduleChangeKind = "week_removed"
+	SeasonAdded        ScheduleChangeKind = "season_added"
+	SeasonRemoved      ScheduleChangeKind = "season_removed"
+)
+
+// ScheduleChange is a change of a season schedule.  Old and New are the
+// week before and after, nil when it was added or removed.  Season changes
+// have neither.
+type ScheduleChange struct {
+	Kind        ScheduleChangeKind
+	SeasonID    int64
+	SeriesID    int64
+	SeasonName  string
+	RaceWeekNum int
+	Old         *SeasonWeek
+	New         *SeasonWeek
+}
+
+func (c ScheduleChange) String() string {
+	switch c.Kind {
+	case TrackSwapped:
+		return fmt.Sprintf("%s week %d: %s swapped for %s", c.SeasonName, c.RaceWeekNum+1, trackLabel(c.Old.Track), trackLabel(c.New.Track))
+	case SeasonAdded, SeasonRemoved:
+		return fmt.Sprintf("%s: %s", c.SeasonName, c.Kind)
+	}
+
+	return fmt.Sprintf("%s week %d: %s", c.SeasonName, c.RaceWeekNum+1, c.Kind)
+}
+
+func trackLabel(track SearchTrack) string {
+	if track.ConfigName == "" {
+		return track.TrackName
+	}
+
+	return track.TrackName + " - " + track.ConfigName
+}
+
+// ScheduleDiff returns how the schedules of the seasons changed from old
+// to new, ordered by season and week.  Only the track, the start date and
+// the session times of a week are compared, anything else (e.g. asset urls)
+// may change freely.
+func ScheduleDiff(old []Season, new []Season) []ScheduleChange {
+	var changes []ScheduleChange
+
+	before := seasonsByID(old)
+	after := seasonsByID(new)
+
+	for id, o := range before {
+		n, ok := after[id]
+		if !ok {
+			changes = append(changes, seasonChange(SeasonRemoved, o))
+			continue
+		}
+
+		changes = append(changes, weekChanges(o, n)...)
+	}
+
+	for id, n := range after {
+		if _, ok := before[id]; !ok {
+			changes = append(changes, seasonChange(SeasonAdded, n))
+		}
+	}
+
+	sort.SliceStable(changes, func(a, b int) bool {
+		if changes[a].SeasonID != changes[b].SeasonID {
+			return changes[a].SeasonID < changes[b].SeasonID
+		}
+
+		if changes[a].RaceWeekNum != changes[b].RaceWeekNum {
+			return changes[a].RaceWeekNum < changes[b].RaceWeekNum
+		}
+
+		return changes[a].Kind < changes[b].Kind
+	})
+
+	return changes
+}
+
+func seasonsByID(seasons []Season) map[int64]Season {
+	byID := make(map[int64]Season)
+
+	for _, season := range seasons {
+		byID[season.SeasonID] = season
+	}
+
+	return byID
+}
+
+func seasonChange(kind ScheduleChangeKind, season Season) ScheduleChange {
+	return ScheduleChange{
+		Kind:       kind,
+		SeasonID:   season.SeasonID,
+		SeriesID:   season.SeriesID,
+		SeasonName: season.SeasonName,
+	}
+}
+
+// weekChanges compares the schedules of a season week by week
+func weekChanges(old Season, new Season) []ScheduleChange {
+	var changes []ScheduleChange
+
+	change := func(kind ScheduleChangeKind, week int, o *SeasonWeek, n *SeasonWeek) {
+		c := seasonChange(kind, new)
+		c.RaceWeekNum, c.Old, c.New = week, o, n
+
+		changes = append(changes, c)
+	}
+
+	before := weeksByNum(old.Schedules)
+	after := weeksByNum(new.Schedules)
+
+	for num, o := range before {
+		n, ok := after[num]
+		if !ok {
+			change(WeekRemoved, num, o, nil)
+			continue
+		}
+
+		if o.Track.TrackID != n.Track.TrackID || o.Track.ConfigName != n.Track.ConfigName {
+			change(TrackSwapped, num, o, n)
+		}
+
+		if o.StartDate != n.StartDate || !reflect.DeepEqual(o.RaceTimeDescriptors, n.RaceTimeDescriptors) {
+			change(SessionTimeChanged, num, o, n)
+		}
+	}
+
+	for num, n := range after {
+		if _, ok := before[num]; !ok {
+			change(WeekAdded, num, nil, n)
+		}
+	}
+
+	return changes
+}
+
+func weeksByNum(weeks []SeasonWeek) map[int]*SeasonWeek {
+	byNum := make(map[int]*SeasonWeek)
+
+	for n := range weeks {
+		byNum[weeks[n].RaceWeekNum] = &weeks[n]
+	}
+
+	return byNum
+}
+
+// SeasonsWatcher polls GetSeasons and delivers the schedule changes of the
+// followed series.
+//
+// When the cache is enabled the last schedules seen are kept in it so a
+// restarted watcher reports what changed while it was down.
+type SeasonsWatcher struct {
+	// MaxBackoff caps the delay between polls after consecutive errors
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every error encountered while polling
+	OnError func(error)
+
+	i         *Irdata
+	seriesIDs map[int64]bool
+	interval  time.Duration
+	stateKey  string
+	snapshot  []Season
+}
+
+// NewSeasonsWatcher returns a watcher for the schedules of seriesIDs (all
+// series if none) that polls every interval.  Schedules rarely change, an
+// interval of hours is plenty.  Call Watch to start it.
+func (i *Irdata) NewSeasonsWatcher(seriesIDs []int64, interval time.Duration) *SeasonsWatcher {
+	sorted := append([]int64{}, seriesIDs...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+	key, _ := json.Marshal(sorted)
+
+	followed := make(map[int64]bool)
+
+	for _, seriesID := range seriesIDs {
+		followed[seriesID] = true
+	}
+
+	return &SeasonsWatcher{
+		MaxBackoff: defaultWatchMaxBackoff,
+		i:          i,
+		seriesIDs:  followed,
+		interval:   interval,
+		stateKey:   fmt.Sprintf("irdata.watcher.seasons.%x", md5.Sum(key)),
+	}
+}
+
+// Watch starts polling and returns the channel the changes found by each
+// poll are delivered on.  The first poll only takes a snapshot unless one
+// was cached.  The channel is closed once ctx is done or the instance is
+// closed.
+func (w *SeasonsWatcher) Watch(ctx context.Context) <-chan []ScheduleChange {
+	out := make(chan []ScheduleChange)
+
+	ctx, stop, ok := w.i.beginWatch(ctx)
+	if !ok {
+		close(out)
+		return out
+	}
+
+	w.loadSnapshot()
+
+	go func() {
+		defer close(out)
+		defer stop()
+
+		failures := 0
+
+		for {
+			delay := w.interval
+
+			changes, err := w.poll(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				failures++
+				delay = watchBackoff(w.interval, w.MaxBackoff, failures)
+
+				w.i.logger.WithFields(log.Fields{
+					"err":      err,
+					"failures": failures,
+					"delay":    delay,
+				}).Info("Seasons watcher poll failed")
+
+				if w.OnError != nil {
+					w.OnError(err)
+				}
+			} else {
+				failures = 0
+			}
+
+			if len(changes) > 0 {
+				select {
+				case out <- changes:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			timer := w.i.clock.NewTimer(delay)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+
+	return out
+}
+
+// poll fetches the schedules and returns how they changed since the last
+// snapshot, which it replaces
+func (w *SeasonsWatcher) poll(ctx context.Context) ([]ScheduleChange, error) {
+	seasons, err := w.i.GetSeasons(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var followed []Season
+
+	for _, season := range seasons {
+		if len(w.seriesIDs) == 0 || w.seriesIDs[season.SeriesID] {
+			followed = append(followed, season)
+		}
+	}
+
+	var changes []ScheduleChange
+
+	if w.snapshot != nil {
+		changes = ScheduleDiff(w.snapshot, followed)
+	}
+
+	if followed == nil {
+		followed = []Season{}
+	}
+
+	w.snapshot = followed
+	w.saveSnapshot()
+
+	return changes, nil
+}
+
+func (w *SeasonsWatcher) loadSnapshot() {
+	if w.i.cache == nil {
+		return
+	}
+
+	if _, err := w.i.getCachedJSON(w.i.ctx, w.stateKey, &w.snapshot); err != nil {
+		w.i.logger.WithFields(log.Fields{"err": err}).Info("Unable to load seasons watcher state")
+	}
+}
+
+func (w *SeasonsWatcher) saveSnapshot() {
+	if w.i.cache == nil {
+		return
+	}
+
+	if err := w.i.setCachedJSON(w.i.ctx, w.stateKey, w.snapshot, seasonsSnapshotTTL); err != nil {
+		w.i.logger.WithFields(log.Fields{"err": err}).Info("Unable to save seasons watcher state")
+	}
+}
@@ -0,0 +1,138 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSeasons(t *testing.T, fn string) []Season {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seasons []Season
+
+	if err := json.Unmarshal(data, &seasons); err != nil {
+		t.Fatal(err)
+	}
+
+	return seasons
+}
+
+func TestScheduleDiffTrackSwap(t *testing.T) {
+	before := loadSeasons(t, "testdata/seasons_before_swap.json")
+	after := loadSeasons(t, "testdata/seasons_after_swap.json")
+
+	changes := ScheduleDiff(before, after)
+	assert.Len(t, changes, 2)
+
+	assert.Equal(t, SessionTimeChanged, changes[0].Kind)
+	assert.Equal(t, int64(4711), changes[0].SeasonID)
+	assert.Equal(t, 2, changes[0].RaceWeekNum)
+	assert.Equal(t, "00:30:00", changes[0].New.RaceTimeDescriptors[0].FirstSessionTime)
+
+	swap := changes[1]
+	assert.Equal(t, TrackSwapped, swap.Kind)
+	assert.Equal(t, int64(447), swap.SeriesID)
+	assert.Equal(t, 7, swap.RaceWeekNum)
+	assert.Equal(t, int64(341), swap.Old.Track.TrackID)
+	assert.Equal(t, int64(252), swap.New.Track.TrackID)
+	assert.Equal(t, "IMSA iRacing Series - 2024 Season 2 week 8: Lime Rock Park - Full Course swapped for Circuit of the Americas - Grand Prix", swap.String())
+
+	// the logos of every week changed too without adding noise, renaming a
+	// track doesn't add any either
+	renamed := loadSeasons(t, "testdata/seasons_after_swap.json")
+	renamed[0].Schedules[3].Track.TrackName = "Michelin Raceway Road Atlanta"
+
+	assert.Empty(t, ScheduleDiff(after, renamed))
+}
+
+func TestScheduleDiffWeeksAndSeasons(t *testing.T) {
+	old := []Season{
+		{SeasonID: 1, SeasonName: "A", Schedules: []SeasonWeek{{RaceWeekNum: 0}, {RaceWeekNum: 1}}},
+		{SeasonID: 2, SeasonName: "B"},
+	}
+	new := []Season{
+		{SeasonID: 1, SeasonName: "A", Schedules: []SeasonWeek{{RaceWeekNum: 0}, {RaceWeekNum: 2, StartDate: "2024-03-26"}}},
+		{SeasonID: 3, SeasonName: "C"},
+	}
+
+	var kinds []ScheduleChangeKind
+
+	for _, change := range ScheduleDiff(old, new) {
+		kinds = append(kinds, change.Kind)
+	}
+
+	assert.Equal(t, []ScheduleChangeKind{WeekRemoved, WeekAdded, SeasonRemoved, SeasonAdded}, kinds)
+	assert.Empty(t, ScheduleDiff(old, old))
+}
+
+// mockSeasons serves the seasons fixture it is currently set to
+type mockSeasons struct {
+	mu      sync.Mutex
+	fixture string
+}
+
+func (s *mockSeasons) set(fixture string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixture = fixture
+}
+
+func (s *mockSeasons) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	http.ServeFile(w, r, s.fixture)
+}
+
+func TestSeasonsWatcher(t *testing.T) {
+	m := newMockAPI(t)
+
+	seasons := &mockSeasons{fixture: "testdata/seasons_before_swap.json"}
+	m.handle("/data/series/seasons", seasons.serve)
+
+	clock := newFakeClock()
+	cache := NewMemoryCacheWithClock(clock)
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(cache)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	changes := api.NewSeasonsWatcher([]int64{447}, 6*time.Hour).Watch(ctx)
+
+	// the first poll only takes the snapshot
+	assert.Eventually(t, func() bool { return clock.waiting() == 1 }, time.Second, time.Millisecond)
+
+	seasons.set("testdata/seasons_after_swap.json")
+	clock.advance(6 * time.Hour)
+
+	got := <-changes
+	assert.Len(t, got, 1)
+	assert.Equal(t, TrackSwapped, got[0].Kind)
+
+	cancel()
+
+	for range changes {
+	}
+
+	// a restarted watcher compares with the cached snapshot
+	seasons.set("testdata/seasons_before_swap.json")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	got = <-api.NewSeasonsWatcher([]int64{447}, 6*time.Hour).Watch(ctx)
+	assert.Len(t, got, 1)
+	assert.Equal(t, int64(252), got[0].Old.Track.TrackID)
+}
@@ -0,0 +1,371 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// the simsession_number of the main event, earlier sessions are negative
+const mainEventSimsession = 0
+
+// Tiebreaker decides between drivers on equal points
+type Tiebreaker int
+
+const (
+	// TiebreakWins prefers the driver with more wins
+	TiebreakWins Tiebreaker = iota
+
+	// TiebreakCountback prefers the driver with more wins, then more
+	// seconds, then more thirds and so on
+	TiebreakCountback
+
+	// TiebreakAverageFinish prefers the driver with the better average finish
+	TiebreakAverageFinish
+
+	// TiebreakLatestResult prefers the driver who finished ahead in the
+	// most recent race both drivers entered
+	TiebreakLatestResult
+)
+
+// PointsSystem is a league's own way of scoring a season
+type PointsSystem struct {
+	// Points are the points for each finish position of the main event by
+	// class, Points[0] for the win.  Positions beyond score nothing.
+	Points []int
+
+	// PoleBonus is added for starting first in class
+	PoleBonus int
+
+	// FastestLapBonus is added for the fastest lap of the class
+	FastestLapBonus int
+
+	// DropWeeks is the number of worst results left out of the total,
+	// races not entered being the worst
+	DropWeeks int
+
+	// Adjustments are points added to (or, if negative, taken from) a
+	// driver's total by cust_id, e.g. penalties
+	Adjustments map[int64][]int
+
+	// Tiebreakers are applied in order to drivers on equal points.  Drivers
+	// still tied after all of them share the position.
+	Tiebreakers []Tiebreaker
+}
+
+// driverSeasonT is what a driver did over the season
+type driverSeasonT struct {
+	row LeagueStandingsRow
+
+	// points per race in season order, -1 if not entered
+	points []int
+
+	// finish position (0 based) per race in season order, -1 if not entered
+	finishes []int
+
+	starts []int
+}
+
+// RecalculateStandings scores the main events of the results under ps and
+// returns the standings in the shape of GetLeagueSeasonStandings.  Races
+// are taken in the order of sessions and results of subsessions not among
+// sessions are ignored.  Positions are by class when results have several.
+func RecalculateStandings(sessions []LeagueSeasonSession, results []SubsessionResult, ps PointsSystem) []LeagueStandingsRow {
+	bySubsession := make(map[int64]SubsessionResult)
+
+	for _, result := range results {
+		bySubsession[result.SubsessionID] = result
+	}
+
+	var races [][]SessionResultRow
+
+	for _, session := range sessions {
+		if result, ok := bySubsession[session.SubsessionID]; ok {
+			races = append(races, mainEvent(result))
+		}
+	}
+
+	drivers := make(map[int64]*driverSeasonT)
+
+	var order []int64
+
+	for n, rows := range races {
+		fastest := fastestLaps(rows)
+
+		for _, row := range rows {
+			d := drivers[row.CustID]
+			if d == nil {
+				d = &driverSeasonT{
+					row: LeagueStandingsRow{
+						Driver: LeagueStandingsDriver{CustID: row.CustID, DisplayName: row.DisplayName},
+					},
+					points:   filled(len(races), -1),
+					finishes: filled(len(races), -1),
+					starts:   filled(len(races), -1),
+				}
+
+				drivers[row.CustID] = d
+				order = append(order, row.CustID)
+			}
+
+			points := 0
+
+			if row.FinishPositionInClass < len(ps.Points) {
+				points = ps.Points[row.FinishPositionInClass]
+			}
+
+			if row.StartingPositionInClass == 0 {
+				points += ps.PoleBonus
+			}
+
+			if row.BestLapTime > 0 && row.BestLapTime == fastest[row.CarClassID] {
+				points += ps.FastestLapBonus
+			}
+
+			d.points[n] = points
+			d.finishes[n] = row.FinishPositionInClass
+			d.starts[n] = row.StartingPositionInClass
+
+			if row.FinishPositionInClass == 0 {
+				d.row.Wins++
+			}
+		}
+	}
+
+	var standings []*driverSeasonT
+
+	for _, custID := range order {
+		d := drivers[custID]
+
+		d.row.BasePoints = keptPoints(d.points, ps.DropWeeks)
+		d.row.AverageStart = averagePosition(d.starts)
+		d.row.AverageFinish = averagePosition(d.finishes)
+
+		for _, adjustment := range ps.Adjustments[custID] {
+			if adjustment < 0 {
+				d.row.NegativeAdjustments += adjustment
+			} else {
+				d.row.PositiveAdjustments += adjustment
+			}
+		}
+
+		d.row.TotalAdjustments = d.row.NegativeAdjustments + d.row.PositiveAdjustments
+		d.row.TotalPoints = d.row.BasePoints + d.row.TotalAdjustments
+
+		standings = append(standings, d)
+	}
+
+	sort.SliceStable(standings, func(a, b int) bool {
+		if c := compareDrivers(standings[a], standings[b], ps.Tiebreakers); c != 0 {
+			return c > 0
+		}
+
+		return standings[a].row.Driver.CustID < standings[b].row.Driver.CustID
+	})
+
+	rows := make([]LeagueStandingsRow, len(standings))
+
+	for n, d := range standings {
+		d.row.Rownum = n + 1
+		d.row.Position = n + 1
+
+		if n > 0 && compareDrivers(d, standings[n-1], ps.Tiebreakers) == 0 {
+			d.row.Position = rows[n-1].Position
+		}
+
+		rows[n] = d.row
+	}
+
+	return rows
+}
+
+// compareDrivers is positive if a is ahead of b, negative if behind and 0
+// if they are tied
+func compareDrivers(a *driverSeasonT, b *driverSeasonT, tiebreakers []Tiebreaker) int {
+	if a.row.TotalPoints != b.row.TotalPoints {
+		return a.row.TotalPoints - b.row.TotalPoints
+	}
+
+	for _, tiebreaker := range tiebreakers {
+		if c := tiebreak(a, b, tiebreaker); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func tiebreak(a *driverSeasonT, b *driverSeasonT, tiebreaker Tiebreaker) int {
+	switch tiebreaker {
+	case TiebreakWins:
+		return a.row.Wins - b.row.Wins
+	case TiebreakCountback:
+		for position := 0; ; position++ {
+			countA, countB := countFinishes(a.finishes, position), countFinishes(b.finishes, position)
+
+			if countA < 0 && countB < 0 {
+				return 0
+			}
+
+			if countA < 0 {
+				countA = 0
+			}
+
+			if countB < 0 {
+				countB = 0
+			}
+
+			if countA != countB {
+				return countA - countB
+			}
+		}
+	case TiebreakAverageFinish:
+		// a lower average is better, not having finished is worst
+		averageA, averageB := a.row.AverageFinish, b.row.AverageFinish
+
+		switch {
+		case averageA == averageB:
+			return 0
+		case averageA == 0:
+			return -1
+		case averageB == 0:
+			return 1
+		}
+
+		return averageB - averageA
+	case TiebreakLatestResult:
+		for n := len(a.finishes) - 1; n >= 0; n-- {
+			if a.finishes[n] >= 0 && b.finishes[n] >= 0 && a.finishes[n] != b.finishes[n] {
+				return b.finishes[n] - a.finishes[n]
+			}
+		}
+	}
+
+	return 0
+}
+
+// countFinishes counts the finishes in position, -1 once nobody could
+// have finished there
+func countFinishes(finishes []int, position int) int {
+	count, further := 0, false
+
+	for _, finish := range finishes {
+		if finish == position {
+			count++
+		}
+
+		if finish >= position {
+			further = true
+		}
+	}
+
+	if !further {
+		return -1
+	}
+
+	return count
+}
+
+// keptPoints totals the points left after dropping the drop worst races
+func keptPoints(points []int, drop int) int {
+	sorted := append([]int{}, points...)
+	sort.Ints(sorted)
+
+	total := 0
+
+	for n, p := range sorted {
+		if n >= drop && p > 0 {
+			total += p
+		}
+	}
+
+	return total
+}
+
+// averagePosition is the rounded average of the (0 based) positions
+// entered as a 1 based position, 0 if none were
+func averagePosition(positions []int) int {
+	sum, count := 0, 0
+
+	for _, position := range positions {
+		if position >= 0 {
+			sum += position + 1
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return (sum + count/2) / count
+}
+
+// mainEvent returns the result rows of the main event of result
+func mainEvent(result SubsessionResult) []SessionResultRow {
+	for _, session := range result.SessionResults {
+		if session.SimsessionNumber == mainEventSimsession {
+			return session.Results
+		}
+	}
+
+	return nil
+}
+
+// fastestLaps returns the fastest lap by class
+func fastestLaps(rows []SessionResultRow) map[int64]int {
+	fastest := make(map[int64]int)
+
+	for _, row := range rows {
+		if row.BestLapTime <= 0 {
+			continue
+		}
+
+		if best, ok := fastest[row.CarClassID]; !ok || row.BestLapTime < best {
+			fastest[row.CarClassID] = row.BestLapTime
+		}
+	}
+
+	return fastest
+}
+
+func filled(n int, value int) []int {
+	s := make([]int, n)
+
+	for k := range s {
+		s[k] = value
+	}
+
+	return s
+}
+
+// RecalculateLeagueStandings fetches the sessions of the league season
+// with results and their subsession results and scores them under ps, see
+// RecalculateStandings
+func (i *Irdata) RecalculateLeagueStandings(ctx context.Context, leagueID int64, seasonID int64, ps PointsSystem) ([]LeagueStandingsRow, error) {
+	sessions, err := i.GetLeagueSeasonSessions(ctx, leagueID, seasonID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SubsessionResult
+
+	for _, session := range sessions {
+		if session.SubsessionID == 0 {
+			continue
+		}
+
+		result, err := i.GetSubsessionResult(ctx, session.SubsessionID)
+		if errors.Is(err, ErrNotYetAvailable) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, *result)
+	}
+
+	return RecalculateStandings(sessions, results, ps), nil
+}
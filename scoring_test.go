@@ -0,0 +1,143 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRace(subsessionID int64, rows ...SessionResultRow) SubsessionResult {
+	return SubsessionResult{
+		SubsessionID: subsessionID,
+		SessionResults: []SessionResults{
+			{SimsessionNumber: -1, SimsessionTypeName: "Qualifying", Results: []SessionResultRow{{CustID: 99}}},
+			{SimsessionNumber: 0, SimsessionTypeName: "Race", Results: rows},
+		},
+	}
+}
+
+func testRow(custID int64, finish int, start int, bestLap int) SessionResultRow {
+	return SessionResultRow{
+		CustID:                  custID,
+		DisplayName:             fmt.Sprintf("driver %d", custID),
+		FinishPositionInClass:   finish,
+		StartingPositionInClass: start,
+		BestLapTime:             bestLap,
+	}
+}
+
+var testLeagueSessions = []LeagueSeasonSession{{SubsessionID: 1}, {SubsessionID: 2}, {SubsessionID: 3}}
+
+var testLeagueResults = []SubsessionResult{
+	// out of order on purpose, the sessions decide
+	testRace(3, testRow(3, 0, 0, 901), testRow(1, 1, 1, 900)),
+	testRace(1, testRow(1, 0, 0, 900), testRow(2, 1, 1, 905), testRow(3, 2, 2, 910)),
+	testRace(2, testRow(2, 0, 0, 900), testRow(1, 1, 1, 905), testRow(3, 2, 2, -1)),
+	// not part of the season
+	testRace(4, testRow(4, 0, 0, 800)),
+}
+
+var testPointsSystem = PointsSystem{
+	Points:          []int{25, 18, 15},
+	PoleBonus:       1,
+	FastestLapBonus: 1,
+	DropWeeks:       1,
+	Adjustments: map[int64][]int{
+		2: {1},
+		3: {-2, -3},
+	},
+}
+
+func TestRecalculateStandings(t *testing.T) {
+	ps := testPointsSystem
+	ps.Tiebreakers = []Tiebreaker{TiebreakWins, TiebreakCountback}
+
+	standings := RecalculateStandings(testLeagueSessions, testLeagueResults, ps)
+	assert.Len(t, standings, 3)
+
+	// 27 + 18 + 19 dropping the 18
+	assert.Equal(t, LeagueStandingsRow{
+		Rownum:        1,
+		Position:      1,
+		Driver:        LeagueStandingsDriver{CustID: 1, DisplayName: "driver 1"},
+		Wins:          1,
+		AverageStart:  2,
+		AverageFinish: 2,
+		BasePoints:    46,
+		TotalPoints:   46,
+	}, standings[0])
+
+	// 18 + 27 dropping the race not entered, tied on points and wins but
+	// with fewer seconds
+	second := standings[1]
+	assert.Equal(t, int64(2), second.Driver.CustID)
+	assert.Equal(t, 2, second.Position)
+	assert.Equal(t, 45, second.BasePoints)
+	assert.Equal(t, 1, second.PositiveAdjustments)
+	assert.Equal(t, 46, second.TotalPoints)
+
+	third := standings[2]
+	assert.Equal(t, int64(3), third.Driver.CustID)
+	assert.Equal(t, 41, third.BasePoints)
+	assert.Equal(t, -5, third.NegativeAdjustments)
+	assert.Equal(t, -5, third.TotalAdjustments)
+	assert.Equal(t, 36, third.TotalPoints)
+}
+
+func TestRecalculateStandingsTies(t *testing.T) {
+	// without tiebreakers the tie is shared
+	standings := RecalculateStandings(testLeagueSessions, testLeagueResults, testPointsSystem)
+	assert.Equal(t, []int{1, 1, 3}, []int{standings[0].Position, standings[1].Position, standings[2].Position})
+	assert.Equal(t, int64(1), standings[0].Driver.CustID)
+
+	// driver 2 beat 1 in the last race they both entered
+	ps := testPointsSystem
+	ps.Tiebreakers = []Tiebreaker{TiebreakWins, TiebreakLatestResult}
+
+	standings = RecalculateStandings(testLeagueSessions, testLeagueResults, ps)
+	assert.Equal(t, int64(2), standings[0].Driver.CustID)
+	assert.Equal(t, 2, standings[1].Position)
+}
+
+func TestTiebreakAverageFinish(t *testing.T) {
+	a := &driverSeasonT{row: LeagueStandingsRow{AverageFinish: 2}}
+	b := &driverSeasonT{row: LeagueStandingsRow{AverageFinish: 3}}
+	none := &driverSeasonT{}
+
+	assert.Positive(t, tiebreak(a, b, TiebreakAverageFinish))
+	assert.Negative(t, tiebreak(b, a, TiebreakAverageFinish))
+	assert.Negative(t, tiebreak(none, b, TiebreakAverageFinish))
+	assert.Zero(t, tiebreak(a, a, TiebreakAverageFinish))
+}
+
+func TestRecalculateLeagueStandings(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/league/season_sessions", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "4403", r.URL.Query().Get("league_id"))
+		assert.Equal(t, "77", r.URL.Query().Get("season_id"))
+		assert.Equal(t, "true", r.URL.Query().Get("results_only"))
+
+		fmt.Fprint(w, `{"sessions":[{"subsession_id":1},{"subsession_id":2}]}`)
+	})
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("subsession_id") {
+		case "1":
+			fmt.Fprint(w, `{"subsession_id":1,"session_results":[{"simsession_number":0,"results":[`+
+				`{"cust_id":1,"finish_position_in_class":0,"starting_position_in_class":1},`+
+				`{"cust_id":2,"finish_position_in_class":1,"starting_position_in_class":0}]}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	api := m.openAuthed(t)
+
+	standings, err := api.RecalculateLeagueStandings(context.Background(), 4403, 77, PointsSystem{Points: []int{10, 5}, PoleBonus: 2})
+	assert.NoError(t, err)
+	assert.Len(t, standings, 2)
+	assert.Equal(t, 10, standings[0].TotalPoints)
+	assert.Equal(t, 7, standings[1].TotalPoints)
+}
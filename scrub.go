@@ -0,0 +1,421 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The kinds of personal data of the `pii` struct tags of the bindings
+const (
+	piiCustID = "cust_id"
+	piiName   = "name"
+	piiEmail  = "email"
+	piiClub   = "club"
+)
+
+// scrubbedBindings are the bindings whose `pii` tags tell FixtureScrubber
+// which fields are personal, along with those having fields of the same
+// names that aren't, e.g. the names of cars.  The types they hold are
+// walked too.
+var scrubbedBindings = []interface{}{
+	SubsessionResult{},
+	Lap{},
+	EventLogEntry{},
+	SearchResult{},
+	HostedResult{},
+	HostedSession{},
+	CustLeagueSession{},
+	LeagueSeasonSession{},
+	LeagueStandingsRow{},
+	LeagueDirectoryEntry{},
+	QualifyResult{},
+	TimeTrialResult{},
+	TimeTrialStanding{},
+	DriverStanding{},
+	WorldRecord{},
+	Member{},
+	membersResponseT{},
+	MemberInfo{},
+	RecentRace{},
+	ChartData{},
+	ParticipationCredit{},
+	MemberAward{},
+	AwardInstance{},
+	MemberRecap{},
+	Club{},
+	Car{},
+	Track{},
+	Series{},
+	CarClass{},
+	PastSeries{},
+	PastSeason{},
+	Season{},
+}
+
+// untypedPII are the personal fields of responses without bindings, e.g.
+// the account's email
+var untypedPII = map[string]string{
+	"email":        piiEmail,
+	"host_cust_id": piiCustID,
+}
+
+// piiStructT are the json names of the fields of a binding and their kind
+// of personal data, "" for fields that aren't
+type piiStructT map[string]string
+
+// piiFieldsT is what the bindings say about the fields of responses
+type piiFieldsT struct {
+	// always are the fields personal in every binding that has them
+	always map[string]string
+
+	// ambiguous are the bindings having a field that's personal in some
+	// but not others, by the name of the field
+	ambiguous map[string][]piiStructT
+}
+
+var (
+	piiFieldsOnce sync.Once
+	piiFields     piiFieldsT
+)
+
+// loadPIIFields walks the bindings for their `pii` tags
+func loadPIIFields() piiFieldsT {
+	piiFieldsOnce.Do(func() {
+		var structs []piiStructT
+
+		seen := make(map[reflect.Type]bool)
+
+		for _, binding := range scrubbedBindings {
+			structs = collectPIIStructs(reflect.TypeOf(binding), seen, structs)
+		}
+
+		kinds := make(map[string]map[string]bool)
+
+		for _, fields := range structs {
+			for name, kind := range fields {
+				if kinds[name] == nil {
+					kinds[name] = make(map[string]bool)
+				}
+
+				kinds[name][kind] = true
+			}
+		}
+
+		piiFields = piiFieldsT{always: make(map[string]string), ambiguous: make(map[string][]piiStructT)}
+
+		for name, kind := range untypedPII {
+			piiFields.always[name] = kind
+		}
+
+		for name, found := range kinds {
+			if len(found) == 1 {
+				for kind := range found {
+					if kind != "" {
+						piiFields.always[name] = kind
+					}
+				}
+
+				continue
+			}
+
+			for _, fields := range structs {
+				if _, ok := fields[name]; ok {
+					piiFields.ambiguous[name] = append(piiFields.ambiguous[name], fields)
+				}
+			}
+		}
+	})
+
+	return piiFields
+}
+
+// collectPIIStructs appends the fields of t and of the structs it holds
+func collectPIIStructs(t reflect.Type, seen map[reflect.Type]bool, structs []piiStructT) []piiStructT {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return collectPIIStructs(t.Elem(), seen, structs)
+	case reflect.Struct:
+	default:
+		return structs
+	}
+
+	if seen[t] {
+		return structs
+	}
+
+	seen[t] = true
+
+	fields := make(piiStructT)
+
+	var walk func(t reflect.Type)
+
+	walk = func(t reflect.Type) {
+		for n := 0; n < t.NumField(); n++ {
+			field := t.Field(n)
+
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+
+			name, _, _ := strings.Cut(tag, ",")
+
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				walk(field.Type)
+				continue
+			}
+
+			if !field.IsExported() || name == "" {
+				continue
+			}
+
+			fields[name] = field.Tag.Get("pii")
+
+			structs = collectPIIStructs(field.Type, seen, structs)
+		}
+	}
+
+	walk(t)
+
+	return append(structs, fields)
+}
+
+// kind returns the kind of personal data of the field key of obj, "" if
+// it isn't.  Fields only personal in some bindings go by the binding obj
+// has the most fields of, personal on a tie.
+func (p piiFieldsT) kind(key string, obj map[string]interface{}) string {
+	if kind, ok := p.always[key]; ok {
+		return kind
+	}
+
+	kind, best := "", -1
+
+	for _, fields := range p.ambiguous[key] {
+		matched := 0
+		for name := range obj {
+			if _, ok := fields[name]; ok {
+				matched++
+			}
+		}
+
+		if matched > best || matched == best && fields[key] != "" {
+			kind, best = fields[key], matched
+		}
+	}
+
+	return kind
+}
+
+var custIDParam = regexp.MustCompile(`(?i)((?:host_)?cust_ids?=)(\d+)`)
+
+// FixtureScrubber pseudonymizes the personal data of recorded responses so
+// they can be shared, e.g. as fixtures for tests.  Customer ids, names and
+// clubs are replaced by stand-ins, the same value getting the same stand-in
+// in every response it scrubs so they still join up; the structure and
+// everything else, lap times and ratings included, are kept.  Link
+// signatures, auth tokens and emails in free text are removed.
+//
+// Which fields are personal is told by the bindings' `pii` struct tags.
+// Stand-ins are numbered in the order they're found, so scrubbing the same
+// responses in the same order gives the same output, and scrubbing that
+// output again changes nothing.
+type FixtureScrubber struct {
+	mu        sync.Mutex
+	custIDs   map[string]int64
+	names     map[string]string
+	clubIDs   map[string]int64
+	clubNames map[string]string
+}
+
+// NewFixtureScrubber returns a scrubber for a set of responses
+func NewFixtureScrubber() *FixtureScrubber {
+	return &FixtureScrubber{
+		custIDs:   make(map[string]int64),
+		names:     make(map[string]string),
+		clubIDs:   make(map[string]int64),
+		clubNames: make(map[string]string),
+	}
+}
+
+// Scrub returns the JSON data with its personal data pseudonymized,
+// indented for review
+func (s *FixtureScrubber) Scrub(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out, err := json.MarshalIndent(s.scrubValue(loadPIIFields(), "", v), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
+
+// ScrubFixtures scrubs the .json files under dir with one scrubber, in
+// lexical order, writing them to the same paths under out.  out may be dir
+// to scrub them in place.
+func ScrubFixtures(dir string, out string) error {
+	var paths []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".json") {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s := NewFixtureScrubber()
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		scrubbed, err := s.Scrub(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if err := s.writeFile(filepath.Join(out, rel), scrubbed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteFixture scrubs data and writes it to the file name in dir
+func (s *FixtureScrubber) WriteFixture(dir string, name string, data []byte) error {
+	scrubbed, err := s.Scrub(data)
+	if err != nil {
+		return err
+	}
+
+	return s.writeFile(filepath.Join(dir, name), scrubbed)
+}
+
+func (s *FixtureScrubber) writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func (s *FixtureScrubber) scrubValue(pii piiFieldsT, kind string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		// in order so the stand-ins are the same every run
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			v[key] = s.scrubValue(pii, pii.kind(key, v), v[key])
+		}
+
+		return v
+	case []interface{}:
+		for n, value := range v {
+			v[n] = s.scrubValue(pii, kind, value)
+		}
+
+		return v
+	case json.Number:
+		switch kind {
+		case piiCustID:
+			return json.Number(fmt.Sprint(s.standIn(s.custIDs, 1001, v.String())))
+		case piiClub:
+			return json.Number(fmt.Sprint(s.standIn(s.clubIDs, 1, v.String())))
+		}
+	case string:
+		switch kind {
+		case piiEmail:
+			return "driver@example.com"
+		case piiName:
+			return s.name(s.names, "Driver", v)
+		case piiClub:
+			return s.name(s.clubNames, "Club", v)
+		case piiCustID:
+			if _, err := json.Number(v).Int64(); err == nil {
+				return fmt.Sprint(s.standIn(s.custIDs, 1001, v))
+			}
+		}
+
+		// signatures, auth tokens, emails and cust_ids in free text
+		v = redactString(v, RedactionPolicy{MaskPII: true})
+
+		return custIDParam.ReplaceAllStringFunc(v, func(m string) string {
+			parts := custIDParam.FindStringSubmatch(m)
+
+			return parts[1] + fmt.Sprint(s.standIn(s.custIDs, 1001, parts[2]))
+		})
+	}
+
+	return v
+}
+
+// standIn returns the stand-in of the id, numbered from first in the order
+// they're found
+func (s *FixtureScrubber) standIn(ids map[string]int64, first int64, id string) int64 {
+	if n, ok := ids[id]; ok {
+		return n
+	}
+
+	n := first + int64(len(ids))
+	ids[id] = n
+
+	return n
+}
+
+// name returns the stand-in of the name, "Driver 1" and so on
+func (s *FixtureScrubber) name(names map[string]string, prefix string, name string) string {
+	if name == "" {
+		return name
+	}
+
+	if stand, ok := names[name]; ok {
+		return stand
+	}
+
+	stand := fmt.Sprintf("%s %d", prefix, len(names)+1)
+	names[name] = stand
+
+	return stand
+}
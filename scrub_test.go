@@ -0,0 +1,167 @@
+package irdata
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixtureScrubber(t *testing.T) {
+	s := NewFixtureScrubber()
+
+	data, err := s.Scrub([]byte(`{"cust_id":123456,"display_name":"Jane Racer","email":"jane@racer.example",` +
+		`"results":[{"cust_id":789,"display_name":"Joe"},{"cust_id":123456,"display_name":"Jane Racer"}],` +
+		`"host":{"host_cust_id":789},"cust_ids":[789,42],"link":"https://x.s3.amazonaws.com/a?X-Amz-Signature=abc&b=1",` +
+		`"subsession_id":70000001,"note":"mail jane@racer.example"}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var v struct {
+		CustID      int64  `json:"cust_id"`
+		DisplayName string `json:"display_name"`
+		Email       string `json:"email"`
+		Results     []struct {
+			CustID      int64  `json:"cust_id"`
+			DisplayName string `json:"display_name"`
+		} `json:"results"`
+		Host struct {
+			HostCustID int64 `json:"host_cust_id"`
+		} `json:"host"`
+		CustIDs      []int64 `json:"cust_ids"`
+		Link         string  `json:"link"`
+		SubsessionID int64   `json:"subsession_id"`
+		Note         string  `json:"note"`
+	}
+
+	if !assert.NoError(t, json.Unmarshal(data, &v)) {
+		return
+	}
+
+	assert.NotContains(t, string(data), "Jane")
+	assert.NotContains(t, string(data), "123456")
+	assert.NotContains(t, string(data), "abc")
+
+	// the same person gets the same stand-in everywhere
+	assert.Equal(t, v.CustID, v.Results[1].CustID)
+	assert.Equal(t, v.DisplayName, v.Results[1].DisplayName)
+	assert.Equal(t, v.Results[0].CustID, v.Host.HostCustID)
+	assert.Equal(t, []int64{v.Host.HostCustID, 1003}, v.CustIDs)
+	assert.Equal(t, "driver@example.com", v.Email)
+
+	// ids of anything but people are kept
+	assert.Equal(t, int64(70000001), v.SubsessionID)
+	assert.Contains(t, v.Link, "&b=1")
+}
+
+func TestFixtureScrubberBindings(t *testing.T) {
+	s := NewFixtureScrubber()
+
+	data, err := s.Scrub([]byte(`{
+		"laps": [{"group_id": 7, "name": "Jane Racer", "cust_id": 123456, "display_name": "Jane Racer", "lap_time": 912345}],
+		"classes": [{"car_class_id": 74, "name": "Mazda MX-5 Cup", "short_name": "MX-5 Cup"}],
+		"member": {"cust_id": 123456, "first_name": "Jane", "last_name": "Racer", "club_id": 7, "club_name": "Pacific"},
+		"league": {"league_id": 9, "owner_id": 789, "league_name": "Sunday Cup"}
+	}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var v struct {
+		Laps []struct {
+			Name        string `json:"name"`
+			CustID      int64  `json:"cust_id"`
+			DisplayName string `json:"display_name"`
+			LapTime     int    `json:"lap_time"`
+		} `json:"laps"`
+		Classes []struct {
+			Name string `json:"name"`
+		} `json:"classes"`
+		Member struct {
+			CustID   int64  `json:"cust_id"`
+			LastName string `json:"last_name"`
+			ClubID   int64  `json:"club_id"`
+			ClubName string `json:"club_name"`
+		} `json:"member"`
+		League struct {
+			OwnerID    int64  `json:"owner_id"`
+			LeagueName string `json:"league_name"`
+		} `json:"league"`
+	}
+
+	if !assert.NoError(t, json.Unmarshal(data, &v)) {
+		return
+	}
+
+	// name is a driver's in laps but a car's in car classes
+	assert.Equal(t, v.Laps[0].DisplayName, v.Laps[0].Name)
+	assert.Equal(t, "Mazda MX-5 Cup", v.Classes[0].Name)
+
+	assert.Equal(t, v.Laps[0].CustID, v.Member.CustID)
+	assert.NotEqual(t, "Racer", v.Member.LastName)
+	assert.Equal(t, int64(1), v.Member.ClubID)
+	assert.Equal(t, "Club 1", v.Member.ClubName)
+	assert.Equal(t, int64(1002), v.League.OwnerID)
+
+	assert.Equal(t, 912345, v.Laps[0].LapTime)
+	assert.Equal(t, "Sunday Cup", v.League.LeagueName)
+}
+
+func TestScrubFixtures(t *testing.T) {
+	src := t.TempDir()
+
+	fixtures := []string{
+		"event_log_race.json",
+		"owned_carclasses.json",
+		"owned_member_info.json",
+		"subsession_dns_withdrawn.json",
+		"subsession_fresh.json",
+		"subsession_team.json",
+	}
+
+	for _, name := range fixtures {
+		assert.NoError(t, os.WriteFile(filepath.Join(src, name), readFixture(t, name), 0644))
+	}
+
+	scrubbed := t.TempDir()
+	assert.NoError(t, ScrubFixtures(src, scrubbed))
+
+	again := t.TempDir()
+	assert.NoError(t, ScrubFixtures(scrubbed, again))
+
+	rerun := t.TempDir()
+	assert.NoError(t, ScrubFixtures(src, rerun))
+
+	read := func(dir string, name string) []byte {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+
+		return data
+	}
+
+	for _, name := range fixtures {
+		// scrubbing is deterministic and idempotent
+		assert.Equal(t, string(read(scrubbed, name)), string(read(again, name)), name)
+		assert.Equal(t, string(read(scrubbed, name)), string(read(rerun, name)), name)
+
+		assert.NotContains(t, string(read(scrubbed, name)), "Prost", name)
+	}
+
+	var fresh, withdrawn SubsessionResult
+
+	assert.NoError(t, json.Unmarshal(read(scrubbed, "subsession_fresh.json"), &fresh))
+	assert.NoError(t, json.Unmarshal(read(scrubbed, "subsession_dns_withdrawn.json"), &withdrawn))
+
+	// the same driver joins up across the files, performance data is kept
+	prost := mainEvent(fresh)[0]
+	assert.Equal(t, prost.CustID, mainEvent(withdrawn)[0].CustID)
+	assert.Equal(t, prost.DisplayName, mainEvent(withdrawn)[0].DisplayName)
+	assert.NotEqual(t, int64(101), prost.CustID)
+	assert.Equal(t, 1013422, prost.BestLapTime)
+	assert.Equal(t, 1910, prost.OldiRating)
+	assert.Equal(t, int64(68911202), fresh.SubsessionID)
+	assert.Equal(t, "Okayama International Circuit", fresh.Track.TrackName)
+}
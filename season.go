@@ -0,0 +1,93 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// seasonDateFormat is the format of the schedule start dates
+const seasonDateFormat = "2006-01-02"
+
+// RaceTimeDescriptor describes when the sessions of a race week start,
+// either every RepeatMinutes from FirstSessionTime on the DayOffset days or
+// at the SessionTimes
+type RaceTimeDescriptor struct {
+	Repeating        bool        `json:"repeating"`
+	SuperSession     bool        `json:"super_session"`
+	SessionMinutes   int         `json:"session_minutes"`
+	StartDate        string      `json:"start_date"`
+	DayOffset        []int       `json:"day_offset"`
+	FirstSessionTime string      `json:"first_session_time"`
+	RepeatMinutes    int         `json:"repeat_minutes"`
+	SessionTimes     []time.Time `json:"session_times"`
+}
+
+// SeasonWeek is a single race week of a season schedule
+type SeasonWeek struct {
+	SeasonID            int64                `json:"season_id"`
+	SeriesID            int64                `json:"series_id"`
+	RaceWeekNum         int                  `json:"race_week_num"`
+	StartDate           string               `json:"start_date"`
+	Track               SearchTrack          `json:"track"`
+	RaceTimeDescriptors []RaceTimeDescriptor `json:"race_time_descriptors"`
+
+	// CarRestrictions override those of the season for the week, nil if
+	// the payload has none
+	CarRestrictions []CarRestriction `json:"car_restrictions"`
+}
+
+// Start returns when the week begins or the zero time if StartDate doesn't
+// parse
+func (w SeasonWeek) Start() time.Time {
+	start, err := time.Parse(seasonDateFormat, w.StartDate)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return start
+}
+
+// Season is a season of a series as returned by /data/series/seasons
+type Season struct {
+	SeasonID            int64        `json:"season_id"`
+	SeriesID            int64        `json:"series_id"`
+	SeasonName          string       `json:"season_name"`
+	SeasonYear          int          `json:"season_year"`
+	SeasonQuarter       int          `json:"season_quarter"`
+	Active              bool         `json:"active"`
+	Official            bool         `json:"official"`
+	CarClassIDs         []int64      `json:"car_class_ids"`
+	Drops               int          `json:"drops"`
+	MaxWeeks            int          `json:"max_weeks"`
+	RaceWeek            int          `json:"race_week"`
+	HasSupersessions    bool         `json:"has_supersessions"`
+	LicenseGroup        int          `json:"license_group"`
+	ScheduleDescription string       `json:"schedule_description"`
+	Schedules           []SeasonWeek `json:"schedules"`
+
+	// AllowedLicenses, LicenseGroupTypes and the iRating bounds (0 when
+	// there are none) gate who may register, see CanRegister.
+	// AllowedSeasonMembers is left as sent, null unless the season is
+	// limited to invited members.
+	AllowedLicenses      AllowedLicenses    `json:"allowed_licenses"`
+	LicenseGroupTypes    []LicenseGroupType `json:"license_group_types"`
+	MinIRating           int                `json:"min_irating"`
+	MaxIRating           int                `json:"max_irating"`
+	AllowedSeasonMembers json.RawMessage    `json:"allowed_season_members"`
+
+	// CarRestrictions apply to every week unless the week overrides them,
+	// see RestrictionsFor
+	CarRestrictions []CarRestriction `json:"car_restrictions"`
+}
+
+// GetSeasons returns the current seasons and their schedules
+func (i *Irdata) GetSeasons(ctx context.Context) ([]Season, error) {
+	var seasons []Season
+
+	if err := i.GetJSON(ctx, "/data/series/seasons?include_series=true", &seasons); err != nil {
+		return nil, err
+	}
+
+	return seasons, nil
+}
@@ -0,0 +1,30 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSeasons = `[{"season_id":4711,"series_id":139,"season_name":"Advanced Mazda Cup","car_class_ids":[74],"schedules":[` +
+	`{"season_id":4711,"series_id":139,"race_week_num":0,"start_date":"2024-03-12","track":{"track_id":1,"track_name":"Lime Rock"}},` +
+	`{"season_id":4711,"series_id":139,"race_week_num":1,"start_date":"2024-03-19","track":{"track_id":2,"track_name":"Okayama"}},` +
+	`{"season_id":4711,"series_id":139,"race_week_num":2,"start_date":"2024-03-26","track":{"track_id":3,"track_name":"Spa"}},` +
+	`{"season_id":4711,"series_id":139,"race_week_num":3,"start_date":"2024-04-02","track":{"track_id":4,"track_name":"Suzuka"}}]}]`
+
+func TestGetSeasons(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/series/seasons", testSeasons)
+
+	api := m.openAuthed(t)
+
+	seasons, err := api.GetSeasons(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, seasons, 1)
+	assert.Equal(t, []int64{74}, seasons[0].CarClassIDs)
+	assert.Len(t, seasons[0].Schedules, 4)
+	assert.Equal(t, time.Date(2024, 3, 19, 0, 0, 0, 0, time.UTC), seasons[0].Schedules[1].Start())
+	assert.True(t, SeasonWeek{StartDate: "soon"}.Start().IsZero())
+}
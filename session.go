@@ -0,0 +1,188 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// CredentialRetention says what happens to the credentials once logged in
+type CredentialRetention int
+
+const (
+	// DiscardCredentials zeroes the encoded password as soon as the login
+	// succeeded.  When the session expires requests fail with
+	// ErrSessionExpired and an Auth method must be called again.
+	DiscardCredentials CredentialRetention = iota
+
+	// RetainCredentials keeps the encoded password in memory, and only
+	// there, to log in again when the session expires
+	RetainCredentials
+)
+
+// credentialsT is authDataT with the encoded password held as bytes so it
+// can be zeroed
+type credentialsT struct {
+	username        string
+	encodedPassword []byte
+}
+
+func newCredentials(authData authDataT) *credentialsT {
+	return &credentialsT{
+		username:        authData.Username,
+		encodedPassword: []byte(authData.EncodedPassword),
+	}
+}
+
+// loginBody returns the body posted to the login endpoint, the caller
+// should zero it when done
+func (c *credentialsT) loginBody() []byte {
+	email, _ := json.Marshal(c.username)
+
+	body := make([]byte, 0, len(email)+len(c.encodedPassword)+32)
+	body = append(body, `{"email": `...)
+	body = append(body, email...)
+	body = append(body, ` ,"password": "`...)
+	body = append(body, c.encodedPassword...)
+	body = append(body, `"}`...)
+
+	return body
+}
+
+func (c *credentialsT) zero() {
+	zero(c.encodedPassword)
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+type sessionT struct {
+	mu        sync.Mutex
+	retention CredentialRetention
+	creds     *credentialsT
+	expired   bool
+
+	// bearer is set while authenticated with AuthWithOAuth
+	bearer *bearerT
+}
+
+// SetCredentialRetention sets whether the credentials are kept to log in
+// again when the session expires, the default is DiscardCredentials.
+// Switching to DiscardCredentials zeroes credentials kept so far.
+func (i *Irdata) SetCredentialRetention(mode CredentialRetention) {
+	i.session.mu.Lock()
+	defer i.session.mu.Unlock()
+
+	i.session.retention = mode
+
+	if mode == DiscardCredentials {
+		i.session.forget()
+	}
+}
+
+// keepCredentials holds on to creds if they are to be retained and zeroes
+// them otherwise, called after logging in with them
+func (i *Irdata) keepCredentials(creds *credentialsT) {
+	i.session.mu.Lock()
+	defer i.session.mu.Unlock()
+
+	i.session.expired = false
+	i.session.bearer = nil
+
+	if i.session.creds != creds {
+		i.session.forget()
+	}
+
+	if i.session.retention == RetainCredentials {
+		i.session.creds = creds
+	} else {
+		creds.zero()
+	}
+}
+
+// forgetCredentials zeroes the retained credentials, if any, and forgets
+// the token source
+func (i *Irdata) forgetCredentials() {
+	i.session.mu.Lock()
+	defer i.session.mu.Unlock()
+
+	i.session.forget()
+	i.session.bearer = nil
+}
+
+func (s *sessionT) forget() {
+	if s.creds != nil {
+		s.creds.zero()
+		s.creds = nil
+	}
+}
+
+func (i *Irdata) sessionExpired() bool {
+	i.session.mu.Lock()
+	defer i.session.mu.Unlock()
+
+	return i.session.expired
+}
+
+// reauth logs in again with the retained credentials, or gets a new token
+// from the token source, after the session expired or returns
+// ErrSessionExpired when there are none
+func (i *Irdata) reauth(ctx context.Context) error {
+	i.session.mu.Lock()
+	defer i.session.mu.Unlock()
+
+	if i.session.bearer != nil {
+		i.logger.Info("Token rejected, asking for a new one")
+
+		if err := i.session.refreshBearer(ctx); err != nil {
+			i.session.expired = true
+
+			return err
+		}
+
+		return nil
+	}
+
+	if i.session.creds == nil {
+		i.session.expired = true
+
+		return ErrSessionExpired
+	}
+
+	i.logger.Info("Session expired, logging in again")
+
+	if err := i.login(ctx, i.session.creds); err != nil {
+		i.session.expired = true
+
+		return err
+	}
+
+	return nil
+}
+
+// authedDo is retryingDo adding the bearer token, if any, logging in
+// again and repeating the request once if the session expired
+func (i *Irdata) authedDo(ctx context.Context, method string, url string, body []byte, header http.Header, retry func(status int) bool) (*http.Response, error) {
+	release, err := i.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := i.retryingDo(ctx, method, url, body, i.bearerHeader(url, header), retry)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	resp.Body.Close()
+
+	if err := i.reauth(withSlotHeld(ctx)); err != nil {
+		return nil, err
+	}
+
+	return i.retryingDo(ctx, method, url, body, i.bearerHeader(url, header), retry)
+}
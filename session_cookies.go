@@ -0,0 +1,146 @@
+package irdata
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// authCookieNames are the cookies that carry the session, the others (e.g.
+// load balancer affinity) don't matter to it
+var authCookieNames = map[string]bool{
+	"authtoken_members": true,
+	"irsso_membersv2":   true,
+}
+
+// SessionInfo is the session as held in the cookie jar, for storing it
+// outside the instance, see SetSessionChangedFunc
+type SessionInfo struct {
+	// Cookies are the auth cookies, as the API host would be sent them
+	Cookies []*http.Cookie
+
+	// Expires is when the first of them expires, the zero time if they
+	// all last as long as the session
+	Expires time.Time
+}
+
+// SetSessionChangedFunc calls fn whenever a response changes the auth
+// cookies in the jar: when logging in and when iRacing rotates the token,
+// which the instance follows on its own but a copy of the token exported
+// earlier doesn't.  Other cookies don't call it.  fn is called like the
+// hooks of WithHookQueueSize, nil removes it.
+func (i *Irdata) SetSessionChangedFunc(fn func(SessionInfo)) {
+	i.sessionChanged.Store(&fn)
+}
+
+// sessionJar is a cookie jar noticing when the auth cookies change
+type sessionJar struct {
+	http.CookieJar
+
+	i *Irdata
+
+	mu   sync.Mutex
+	seen map[string]authCookieT
+}
+
+// authCookieT is what's compared of an auth cookie
+type authCookieT struct {
+	value   string
+	expires time.Time
+}
+
+// newCookieJar returns an empty jar for the client
+func (i *Irdata) newCookieJar() http.CookieJar {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		i.logger.Panic(err)
+	}
+
+	return &sessionJar{CookieJar: jar, i: i, seen: make(map[string]authCookieT)}
+}
+
+// SetCookies stores cookies and reports the session changed if any of the
+// auth cookies did
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+
+	now := j.i.clock.Now()
+	changed := false
+
+	j.mu.Lock()
+
+	for _, cookie := range cookies {
+		if !authCookieNames[cookie.Name] {
+			continue
+		}
+
+		c := authCookieT{value: cookie.Value, expires: cookieExpiry(cookie, now)}
+
+		if j.seen[cookie.Name] != c {
+			j.seen[cookie.Name] = c
+			changed = true
+		}
+	}
+
+	info := j.sessionInfo(u)
+
+	j.mu.Unlock()
+
+	if changed {
+		j.i.notifySessionChanged(info)
+	}
+}
+
+// sessionInfo describes the auth cookies held for u, the caller holds mu
+func (j *sessionJar) sessionInfo(u *url.URL) SessionInfo {
+	var info SessionInfo
+
+	for _, cookie := range j.CookieJar.Cookies(u) {
+		if !authCookieNames[cookie.Name] {
+			continue
+		}
+
+		// the jar only hands out names and values
+		if seen, ok := j.seen[cookie.Name]; ok && !seen.expires.IsZero() {
+			cookie.Expires = seen.expires
+
+			if info.Expires.IsZero() || seen.expires.Before(info.Expires) {
+				info.Expires = seen.expires
+			}
+		}
+
+		info.Cookies = append(info.Cookies, cookie)
+	}
+
+	sort.Slice(info.Cookies, func(a, b int) bool { return info.Cookies[a].Name < info.Cookies[b].Name })
+
+	return info
+}
+
+// cookieExpiry is when cookie, set at now, expires, the zero time for a
+// session cookie
+func cookieExpiry(cookie *http.Cookie, now time.Time) time.Time {
+	if cookie.MaxAge > 0 {
+		return now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+
+	return cookie.Expires
+}
+
+func (i *Irdata) notifySessionChanged(info SessionInfo) {
+	fn, _ := i.sessionChanged.Load().(*func(SessionInfo))
+	if fn == nil || *fn == nil {
+		return
+	}
+
+	i.logger.WithFields(log.Fields{"expires": info.Expires}).Debug("Session cookies changed")
+
+	hook := *fn
+
+	i.callHook(func() { hook(info) })
+}
@@ -0,0 +1,79 @@
+package irdata
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionChangedFunc(t *testing.T) {
+	// the jar drops cookies that expired by the wall clock
+	expires := time.Now().Add(7 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	m := newMockAPI(t)
+	m.handle("/data/affinity", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "AWSALB", Value: "node-2", Path: "/"})
+		w.Write([]byte(`{}`))
+	})
+	m.handle("/data/rotate", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: mockAuthCookie, Value: "rotated", Path: "/", Expires: expires})
+		w.Write([]byte(`{}`))
+	})
+	m.handle("/data/session", func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie(mockAuthCookie)
+		w.Write([]byte(`{"token":"` + cookie.Value + `"}`))
+	})
+
+	var mu sync.Mutex
+	var changes []SessionInfo
+
+	api := m.open(t, WithClock(newFakeClock()))
+	api.SetSessionChangedFunc(func(info SessionInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		changes = append(changes, info)
+	})
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	for _, uri := range []string{"/data/affinity", "/data/rotate", "/data/rotate", "/data/affinity"} {
+		_, err := api.Get(uri)
+		assert.NoError(t, err)
+	}
+
+	// the instance goes on with the rotated token
+	data, err := api.Get("/data/session")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"token":"rotated"}`, string(data))
+
+	api.flushHooks(false)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// logging in and the rotation, the other cookie and the same token
+	// again don't count
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, mockToken(1, string(testUsername)), changes[0].Cookies[0].Value)
+		assert.True(t, changes[0].Expires.IsZero())
+
+		assert.Len(t, changes[1].Cookies, 1)
+		assert.Equal(t, mockAuthCookie, changes[1].Cookies[0].Name)
+		assert.Equal(t, "rotated", changes[1].Cookies[0].Value)
+		assert.True(t, expires.Equal(changes[1].Expires))
+		assert.True(t, expires.Equal(changes[1].Cookies[0].Expires))
+	}
+}
+
+func TestCookieExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(48 * time.Hour)
+
+	assert.Equal(t, now.Add(time.Hour), cookieExpiry(&http.Cookie{MaxAge: 3600, Expires: expires}, now))
+	assert.Equal(t, expires, cookieExpiry(&http.Cookie{Expires: expires}, now))
+	assert.True(t, cookieExpiry(&http.Cookie{}, now).IsZero())
+}
@@ -0,0 +1,215 @@
+package irdata
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SessionOptions are the options a hosted or league session is run with,
+// as iRacing lists them along with the session
+type SessionOptions struct {
+	MaxDrivers         int
+	FullCourseCautions bool
+
+	FixedSetup FixedSetup
+	Weather    Weather
+
+	// TimeOfDay is iRacing's code for the time of day and SimulatedStart
+	// the date and time at the track, in its local time
+	TimeOfDay      int
+	SimulatedStart time.Time
+}
+
+// FixedSetup says whether the cars run a fixed setup, Name is that of the
+// setup if iRacing gave it
+type FixedSetup struct {
+	Fixed bool
+	Name  string
+}
+
+// Skies is the cloud cover
+type Skies int
+
+const (
+	SkiesClear Skies = iota
+	SkiesPartlyCloudy
+	SkiesMostlyCloudy
+	SkiesOvercast
+)
+
+func (s Skies) String() string {
+	switch s {
+	case SkiesClear:
+		return "clear"
+	case SkiesPartlyCloudy:
+		return "partly cloudy"
+	case SkiesMostlyCloudy:
+		return "mostly cloudy"
+	case SkiesOvercast:
+		return "overcast"
+	default:
+		return "unknown"
+	}
+}
+
+// Weather is the weather of a session, in metric units whatever units
+// the session was set up in.  Sessions set up before dynamic weather
+// shipped have no forecast: the high and low are the temperature and the
+// starting skies, there's no chance of rain.
+type Weather struct {
+	TempC     float64
+	TempHighC float64
+	TempLowC  float64
+
+	Skies    Skies
+	SkiesMax Skies
+
+	WindKph     float64
+	WindDir     int
+	RelHumidity int
+	FogPct      int
+
+	// Dynamic is set if the weather changes during the session and
+	// VariedStart if it's drawn at random when the session starts
+	Dynamic     bool
+	VariedStart bool
+
+	// RainAllowed, PrecipChance (in %) and ForecastURL are only known
+	// with dynamic weather
+	RainAllowed  bool
+	PrecipChance int
+	ForecastURL  string
+}
+
+// weatherT is the weather object of a session payload.  Dynamic weather
+// added the version, the summary of the forecast and the precipitation
+// options and replaced the weather_var flags by type.
+type weatherT struct {
+	Version int `json:"version"`
+	Type    int `json:"type"`
+
+	TempUnits   int     `json:"temp_units"`
+	TempValue   float64 `json:"temp_value"`
+	RelHumidity int     `json:"rel_humidity"`
+	Fog         int     `json:"fog"`
+	WindDir     int     `json:"wind_dir"`
+	WindUnits   int     `json:"wind_units"`
+	WindValue   float64 `json:"wind_value"`
+	Skies       Skies   `json:"skies"`
+
+	WeatherVarInitial int `json:"weather_var_initial"`
+	WeatherVarOngoing int `json:"weather_var_ongoing"`
+
+	TimeOfDay          int    `json:"time_of_day"`
+	SimulatedStartTime string `json:"simulated_start_time"`
+
+	PrecipOption   int    `json:"precip_option"`
+	WeatherURL     string `json:"weather_url"`
+	WeatherSummary *struct {
+		TempUnits    int     `json:"temp_units"`
+		TempHigh     float64 `json:"temp_high"`
+		TempLow      float64 `json:"temp_low"`
+		SkiesHigh    Skies   `json:"skies_high"`
+		SkiesLow     Skies   `json:"skies_low"`
+		PrecipChance int     `json:"precip_chance"`
+	} `json:"weather_summary"`
+}
+
+// weatherTypeForecast is the type of dynamic weather following a forecast
+const weatherTypeForecast = 3
+
+// imperial is the value of temp_units and wind_units for Fahrenheit and
+// mph, metric is 1
+const imperial = 0
+
+func celsius(value float64, units int) float64 {
+	if units == imperial {
+		return (value - 32) * 5 / 9
+	}
+
+	return value
+}
+
+func (w weatherT) weather() Weather {
+	weather := Weather{
+		TempC:       celsius(w.TempValue, w.TempUnits),
+		Skies:       w.Skies,
+		SkiesMax:    w.Skies,
+		WindKph:     w.WindValue,
+		WindDir:     w.WindDir,
+		RelHumidity: w.RelHumidity,
+		FogPct:      w.Fog,
+		ForecastURL: w.WeatherURL,
+	}
+
+	if w.WindUnits == imperial {
+		weather.WindKph = w.WindValue * 1.609344
+	}
+
+	weather.TempHighC, weather.TempLowC = weather.TempC, weather.TempC
+
+	if w.Version == 0 {
+		weather.Dynamic = w.WeatherVarOngoing != 0
+		weather.VariedStart = w.WeatherVarInitial != 0
+
+		return weather
+	}
+
+	weather.Dynamic = w.Type == weatherTypeForecast
+	weather.RainAllowed = w.PrecipOption != 0
+
+	if summary := w.WeatherSummary; summary != nil {
+		weather.TempHighC = celsius(summary.TempHigh, summary.TempUnits)
+		weather.TempLowC = celsius(summary.TempLow, summary.TempUnits)
+		weather.Skies = summary.SkiesLow
+		weather.SkiesMax = summary.SkiesHigh
+		weather.PrecipChance = summary.PrecipChance
+	}
+
+	return weather
+}
+
+// decodeSessionOptions decodes the options from a session payload, which
+// holds them among its other fields
+func decodeSessionOptions(data []byte, o *SessionOptions) error {
+	var session struct {
+		MaxDrivers         int      `json:"max_drivers"`
+		FullCourseCautions bool     `json:"full_course_cautions"`
+		FixedSetup         bool     `json:"fixed_setup"`
+		FixedSetupName     string   `json:"fixed_setup_name"`
+		Weather            weatherT `json:"weather"`
+	}
+
+	if err := json.Unmarshal(data, &session); err != nil {
+		return err
+	}
+
+	*o = SessionOptions{
+		MaxDrivers:         session.MaxDrivers,
+		FullCourseCautions: session.FullCourseCautions,
+		FixedSetup:         FixedSetup{Fixed: session.FixedSetup || session.FixedSetupName != "", Name: session.FixedSetupName},
+		Weather:            session.Weather.weather(),
+		TimeOfDay:          session.Weather.TimeOfDay,
+	}
+
+	if session.Weather.SimulatedStartTime != "" {
+		start, err := time.Parse("2006-01-02T15:04:05", session.Weather.SimulatedStartTime)
+		if err != nil {
+			return err
+		}
+
+		o.SimulatedStart = start
+	}
+
+	return nil
+}
+
+// decodeSession decodes a session payload into v, a pointer to an alias of
+// the session type so its UnmarshalJSON isn't called again, and options
+func decodeSession(data []byte, v interface{}, options *SessionOptions) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	return decodeSessionOptions(data, options)
+}
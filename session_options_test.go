@@ -0,0 +1,105 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHostedSessionsStaticWeather(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/hosted/combined_sessions", string(readFixture(t, "hosted_sessions_static_weather.json")))
+
+	api := m.openAuthed(t)
+
+	sessions, err := api.GetHostedSessions(context.Background(), 0)
+	if !assert.NoError(t, err) || !assert.Len(t, sessions, 1) {
+		return
+	}
+
+	assert.Equal(t, int64(211044560), sessions[0].SessionID)
+	assert.Equal(t, "Jane Doe", sessions[0].Host.DisplayName)
+
+	options := sessions[0].Options
+
+	assert.Equal(t, 24, options.MaxDrivers)
+	assert.True(t, options.FullCourseCautions)
+	assert.Equal(t, FixedSetup{Fixed: true}, options.FixedSetup)
+	assert.Equal(t, 2, options.TimeOfDay)
+	assert.Equal(t, time.Date(2023, 5, 18, 14, 0, 0, 0, time.UTC), options.SimulatedStart)
+
+	weather := options.Weather
+
+	assert.Equal(t, 25.0, weather.TempC)
+	assert.Equal(t, weather.TempC, weather.TempHighC)
+	assert.Equal(t, weather.TempC, weather.TempLowC)
+	assert.Equal(t, SkiesPartlyCloudy, weather.Skies)
+	assert.Equal(t, SkiesPartlyCloudy, weather.SkiesMax)
+	assert.InDelta(t, 8.05, weather.WindKph, 0.01)
+	assert.Equal(t, 55, weather.RelHumidity)
+	assert.True(t, weather.VariedStart)
+	assert.False(t, weather.Dynamic)
+	assert.False(t, weather.RainAllowed)
+	assert.Empty(t, weather.ForecastURL)
+}
+
+func TestGetHostedSessionsDynamicWeather(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/hosted/combined_sessions", string(readFixture(t, "hosted_sessions_dynamic_weather.json")))
+
+	api := m.openAuthed(t)
+
+	sessions, err := api.GetHostedSessions(context.Background(), 0)
+	if !assert.NoError(t, err) || !assert.Len(t, sessions, 1) {
+		return
+	}
+
+	options := sessions[0].Options
+
+	assert.Equal(t, 40, options.MaxDrivers)
+	assert.False(t, options.FullCourseCautions)
+	assert.Equal(t, FixedSetup{Fixed: true, Name: "iRacing Wet"}, options.FixedSetup)
+
+	assert.Equal(t, Weather{
+		TempC:        18,
+		TempHighC:    21.5,
+		TempLowC:     16,
+		Skies:        SkiesPartlyCloudy,
+		SkiesMax:     SkiesOvercast,
+		WindKph:      14,
+		WindDir:      6,
+		RelHumidity:  80,
+		FogPct:       5,
+		Dynamic:      true,
+		RainAllowed:  true,
+		PrecipChance: 40,
+		ForecastURL:  "https://scorpio-assets.s3.amazonaws.com/weather/253118217.json",
+	}, options.Weather)
+}
+
+func TestLeagueSessionOptions(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/league/season_sessions", `{"sessions":[{"league_id":4403,"session_id":1,"max_drivers":30,"fixed_setup_name":"baseline","weather":{"temp_units":1,"temp_value":20,"skies":3,"weather_var_ongoing":1}}]}`)
+
+	api := m.openAuthed(t)
+
+	sessions, err := api.GetLeagueSeasonSessions(context.Background(), 4403, 77, false)
+	if !assert.NoError(t, err) || !assert.Len(t, sessions, 1) {
+		return
+	}
+
+	assert.Equal(t, int64(4403), sessions[0].LeagueID)
+
+	options := sessions[0].Options
+
+	assert.Equal(t, 30, options.MaxDrivers)
+	assert.Equal(t, FixedSetup{Fixed: true, Name: "baseline"}, options.FixedSetup)
+	assert.Equal(t, 20.0, options.Weather.TempC)
+	assert.Equal(t, SkiesOvercast, options.Weather.Skies)
+	assert.True(t, options.Weather.Dynamic)
+	assert.True(t, options.SimulatedStart.IsZero())
+
+	assert.Equal(t, "mostly cloudy", SkiesMostlyCloudy.String())
+}
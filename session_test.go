@@ -0,0 +1,159 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// expireSession drops the session cookie as if iRacing expired it
+func expireSession(t *testing.T, api *Irdata) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api.httpClient.Jar = jar
+}
+
+func testCredentials() *credentialsT {
+	return &credentialsT{
+		username:        string(testUsername),
+		encodedPassword: encodedPassword(testUsername, testPassword),
+	}
+}
+
+func TestDiscardZeroesEncodedPassword(t *testing.T) {
+	m := newMockAPI(t)
+	api := m.open(t)
+
+	creds := testCredentials()
+	encoded := creds.encodedPassword
+
+	assert.NotEqual(t, make([]byte, len(encoded)), encoded)
+
+	assert.NoError(t, api.authenticate(creds))
+
+	assert.Equal(t, make([]byte, len(encoded)), encoded)
+	assert.Nil(t, api.session.creds)
+}
+
+func TestFailedLoginZeroesEncodedPassword(t *testing.T) {
+	m := newMockAPI(t)
+	api := m.open(t)
+
+	api.SetCredentialRetention(RetainCredentials)
+
+	creds := &credentialsT{username: "nobody@example.com", encodedPassword: []byte("wrong")}
+
+	assert.ErrorIs(t, api.authenticate(creds), ErrBadCredentials)
+	assert.Equal(t, make([]byte, 5), creds.encodedPassword)
+	assert.Nil(t, api.session.creds)
+}
+
+func TestDiscardSessionExpired(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/thing", `{"ok":true}`)
+
+	api := m.openAuthed(t)
+
+	expireSession(t, api)
+
+	var out map[string]bool
+
+	assert.ErrorIs(t, api.GetJSON(context.Background(), "/data/thing", &out), ErrSessionExpired)
+	assert.Equal(t, 1, m.loginCount())
+
+	// authenticating explicitly starts a new session
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	assert.True(t, out["ok"])
+	assert.Equal(t, 2, m.loginCount())
+}
+
+func TestRetainReauths(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/thing", `{"ok":true}`)
+
+	api := m.open(t)
+	api.SetCredentialRetention(RetainCredentials)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	expireSession(t, api)
+
+	var out map[string]bool
+
+	assert.NoError(t, api.GetJSON(context.Background(), "/data/thing", &out))
+	assert.True(t, out["ok"])
+	assert.Equal(t, 2, m.loginCount())
+
+	retained := api.session.creds.encodedPassword
+
+	api.Logout()
+
+	assert.Equal(t, make([]byte, len(retained)), retained)
+	assert.Nil(t, api.session.creds)
+}
+
+func TestReauthUnreachable(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleJSON("/data/thing", `{"ok":true}`)
+
+	errDown := errors.New("login is down")
+
+	var down int32
+
+	api := m.open(t, WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == loginURI && atomic.LoadInt32(&down) == 1 {
+				return nil, errDown
+			}
+
+			return next.RoundTrip(req)
+		})
+	}))
+	api.SetCredentialRetention(RetainCredentials)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	expireSession(t, api)
+	atomic.StoreInt32(&down, 1)
+
+	// without strict errors only the Auth methods panic
+	var out map[string]bool
+
+	assert.NotPanics(t, func() {
+		assert.ErrorIs(t, api.GetJSON(context.Background(), "/data/thing", &out), errDown)
+	})
+
+	assert.Panics(t, func() { api.AuthWithProvideCreds(testCreds{}) })
+}
+
+func TestSwitchingToDiscardZeroes(t *testing.T) {
+	m := newMockAPI(t)
+
+	api := m.open(t)
+	api.SetCredentialRetention(RetainCredentials)
+
+	assert.NoError(t, api.AuthWithProvideCreds(testCreds{}))
+
+	retained := api.session.creds.encodedPassword
+	assert.NotEqual(t, make([]byte, len(retained)), retained)
+
+	api.SetCredentialRetention(DiscardCredentials)
+
+	assert.Equal(t, make([]byte, len(retained)), retained)
+	assert.Nil(t, api.session.creds)
+}
+
+func TestLoginBody(t *testing.T) {
+	creds := &credentialsT{username: `a"b@example.com`, encodedPassword: []byte("c2VjcmV0")}
+
+	assert.JSONEq(t, `{"email":"a\"b@example.com","password":"c2VjcmV0"}`, string(creds.loginBody()))
+}
@@ -0,0 +1,178 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// SplitQuery identifies an official session, which iRacing splits into
+// subsessions of up to a grid each when it fills up
+type SplitQuery struct {
+	SeriesID      int64
+	SeasonYear    int
+	SeasonQuarter int
+	RaceWeekNum   int
+	StartTime     time.Time
+}
+
+// SessionSplit is a subsession of a split session
+type SessionSplit struct {
+	// Split is the (1 based) place of the split, the strongest is 1
+	Split int
+
+	SubsessionID    int64
+	StrengthOfField int
+	Result          *SubsessionResult
+}
+
+// SessionSplits are the splits of a session, strongest first
+type SessionSplits struct {
+	SessionID int64
+	Splits    []SessionSplit
+}
+
+// ForDriver returns the split custID raced in, false if they're in none
+func (s *SessionSplits) ForDriver(custID int64) (SessionSplit, bool) {
+	for _, split := range s.Splits {
+		for _, row := range mainEvent(*split.Result) {
+			if row.CustID == custID {
+				return split, true
+			}
+
+			for _, driver := range row.DriverResults {
+				if driver.CustID == custID {
+					return split, true
+				}
+			}
+		}
+	}
+
+	return SessionSplit{}, false
+}
+
+// GetSessionSplits finds the subsessions of the session q describes with
+// search_series and returns them ordered by the strength of field computed
+// from their results.  Every split's results are fetched, a session that
+// wasn't split has one.
+func (i *Irdata) GetSessionSplits(ctx context.Context, q SplitQuery) (*SessionSplits, error) {
+	if q.SeriesID == 0 || q.StartTime.IsZero() {
+		return nil, errors.New("must provide series id and start time")
+	}
+
+	raceWeek := q.RaceWeekNum
+
+	results, err := i.SearchSeriesResults(ctx, SearchSeriesParams{
+		SeasonYear:      q.SeasonYear,
+		SeasonQuarter:   q.SeasonQuarter,
+		StartRangeBegin: q.StartTime,
+		StartRangeEnd:   q.StartTime.Add(time.Minute),
+		SeriesID:        q.SeriesID,
+		RaceWeekNum:     &raceWeek,
+		EventTypes:      []int{raceEventType},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	splits := &SessionSplits{}
+
+	seen := make(map[int64]bool)
+
+	for _, row := range results.Rows {
+		if seen[row.SubsessionID] || row.SeriesID != q.SeriesID || !row.StartTime.Equal(q.StartTime) {
+			continue
+		}
+
+		seen[row.SubsessionID] = true
+
+		result, err := i.GetSubsessionResult(ctx, row.SubsessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		splits.SessionID = row.SessionID
+		splits.Splits = append(splits.Splits, SessionSplit{
+			SubsessionID:    row.SubsessionID,
+			StrengthOfField: ComputeSOF(mainEvent(*result)),
+			Result:          result,
+		})
+	}
+
+	if len(splits.Splits) == 0 {
+		return nil, errors.New("no subsessions found for session")
+	}
+
+	sort.SliceStable(splits.Splits, func(a, b int) bool {
+		if splits.Splits[a].StrengthOfField != splits.Splits[b].StrengthOfField {
+			return splits.Splits[a].StrengthOfField > splits.Splits[b].StrengthOfField
+		}
+
+		return splits.Splits[a].SubsessionID < splits.Splits[b].SubsessionID
+	})
+
+	for n := range splits.Splits {
+		splits.Splits[n].Split = n + 1
+	}
+
+	return splits, nil
+}
+
+// sofScale is the rating difference that halves the odds of winning in
+// iRacing's rating formula
+const sofScale = 1600 / math.Ln2
+
+// ComputeSOF returns the strength of field of rows like iRacing computes it,
+// from the iRating each entry had before the session.  Team rows count with
+// the average of their drivers' ratings.  Entries that didn't start or
+// withdrew, see ParticipationStatus, and those without a rating are left
+// out.  It returns 0 if no entry has a rating.
+func ComputeSOF(rows []SessionResultRow) int {
+	n := 0
+	sum := 0.0
+
+	for _, row := range rows {
+		if !row.started() {
+			continue
+		}
+
+		rating, ok := entryRating(row)
+		if !ok {
+			continue
+		}
+
+		n++
+		sum += math.Exp(-rating / sofScale)
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return int(math.Round(sofScale * math.Log(float64(n)/sum)))
+}
+
+// entryRating returns the rating counted for row, false if it has none
+func entryRating(row SessionResultRow) (float64, bool) {
+	if len(row.DriverResults) == 0 {
+		return float64(row.OldiRating), row.OldiRating > 0
+	}
+
+	n := 0
+	sum := 0
+
+	for _, driver := range row.DriverResults {
+		if driver.OldiRating > 0 {
+			n++
+			sum += driver.OldiRating
+		}
+	}
+
+	if n == 0 {
+		return 0, false
+	}
+
+	return float64(sum) / float64(n), true
+}
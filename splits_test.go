@@ -0,0 +1,117 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSplit(t *testing.T, subsessionID int64) SubsessionResult {
+	var result SubsessionResult
+
+	assert.NoError(t, json.Unmarshal(readFixture(t, fmt.Sprintf("split_%d.json", subsessionID)), &result))
+
+	return result
+}
+
+func TestComputeSOF(t *testing.T) {
+	// event_strength_of_field is what iRacing shows for each of them
+	for _, subsessionID := range []int64{68000001, 68000002, 68000010} {
+		result := loadSplit(t, subsessionID)
+
+		assert.Equal(t, result.EventStrengthOfField, ComputeSOF(mainEvent(result)), "subsession %d", subsessionID)
+	}
+
+	assert.Equal(t, 0, ComputeSOF(nil))
+	assert.Equal(t, 0, ComputeSOF([]SessionResultRow{{OldiRating: -1}}))
+	assert.Equal(t, 1350, ComputeSOF([]SessionResultRow{{OldiRating: 1350}}))
+
+	// a field of equal ratings is that rating
+	assert.Equal(t, 2000, ComputeSOF([]SessionResultRow{{OldiRating: 2000}, {OldiRating: 2000}, {OldiRating: 2000}}))
+}
+
+func newSplitsAPI(t *testing.T, search string) *Irdata {
+	m := newMockAPI(t)
+
+	m.handleChunked("/data/results/search_series", func(r *http.Request) []string {
+		q := r.URL.Query()
+
+		assert.Equal(t, "139", q.Get("series_id"))
+		assert.Equal(t, "5", q.Get("race_week_num"))
+		assert.Equal(t, "5", q.Get("event_types"))
+		assert.Equal(t, "2024-02-13T18:00Z", q.Get("start_range_begin"))
+
+		return []string{search}
+	})
+
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(readFixture(t, fmt.Sprintf("split_%s.json", r.URL.Query().Get("subsession_id"))))
+	})
+
+	return m.openAuthed(t)
+}
+
+var testSplitQuery = SplitQuery{
+	SeriesID:      139,
+	SeasonYear:    2024,
+	SeasonQuarter: 1,
+	RaceWeekNum:   5,
+	StartTime:     time.Date(2024, 2, 13, 18, 0, 0, 0, time.UTC),
+}
+
+func TestGetSessionSplits(t *testing.T) {
+	api := newSplitsAPI(t, string(readFixture(t, "split_search.json")))
+
+	splits, err := api.GetSessionSplits(context.Background(), testSplitQuery)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, int64(251000100), splits.SessionID)
+
+	if assert.Len(t, splits.Splits, 2) {
+		assert.Equal(t, 1, splits.Splits[0].Split)
+		assert.Equal(t, int64(68000001), splits.Splits[0].SubsessionID)
+		assert.Equal(t, 3123, splits.Splits[0].StrengthOfField)
+
+		assert.Equal(t, 2, splits.Splits[1].Split)
+		assert.Equal(t, int64(68000002), splits.Splits[1].SubsessionID)
+		assert.Equal(t, 1839, splits.Splits[1].StrengthOfField)
+		assert.Len(t, mainEvent(*splits.Splits[1].Result), 7, "the withdrawn entry is kept in the results")
+	}
+
+	split, ok := splits.ForDriver(2004)
+	assert.True(t, ok)
+	assert.Equal(t, 2, split.Split)
+
+	_, ok = splits.ForDriver(9999)
+	assert.False(t, ok)
+}
+
+func TestGetSessionSplitsSingle(t *testing.T) {
+	api := newSplitsAPI(t, `[{"session_id":251000100,"subsession_id":68000002,"start_time":"2024-02-13T18:00:00Z","series_id":139,"event_type":5}]`)
+
+	splits, err := api.GetSessionSplits(context.Background(), testSplitQuery)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, splits.Splits, 1)
+	assert.Equal(t, 1, splits.Splits[0].Split)
+	assert.Equal(t, 1839, splits.Splits[0].StrengthOfField)
+}
+
+func TestGetSessionSplitsNotFound(t *testing.T) {
+	api := newSplitsAPI(t, `[]`)
+
+	_, err := api.GetSessionSplits(context.Background(), testSplitQuery)
+	assert.Error(t, err)
+
+	_, err = api.GetSessionSplits(context.Background(), SplitQuery{SeriesID: 139})
+	assert.Error(t, err)
+}
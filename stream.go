@@ -0,0 +1,93 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrStopIteration can be returned by the callbacks of DecodeArrayStream and
+// GetElementsWithCache to stop early without an error
+var ErrStopIteration = errors.New("stop iteration")
+
+// DecodeArrayStream reads the JSON array in r one element at a time and
+// calls fn with each, so only one element is held in memory at once.  A
+// null array has no elements.
+//
+// Returning ErrStopIteration from fn stops reading and DecodeArrayStream
+// returns nil, any other error is returned as is.
+func DecodeArrayStream(r io.Reader, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok == nil {
+		return nil
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+
+	for dec.More() {
+		var element json.RawMessage
+
+		if err := dec.Decode(&element); err != nil {
+			return err
+		}
+
+		if err := fn(element); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	// the closing bracket
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetElementsWithCache is GetChunksWithCache calling fn with each element of
+// the result rather than each chunk.  At most one chunk and one element are
+// held in memory, whether the chunks are read from the cache or downloaded
+// (unless a cache filter or archiving needs the whole result).  A result
+// that isn't an array is an error.
+//
+// Returning ErrStopIteration from fn stops the iteration and
+// GetElementsWithCache returns nil.
+func (i *Irdata) GetElementsWithCache(uri string, ttl time.Duration, fn func(json.RawMessage) error) error {
+	errStopped := errors.New("stopped")
+
+	err := i.GetChunksWithCache(uri, ttl, func(chunk Chunk) error {
+		return DecodeArrayStream(bytes.NewReader(chunk.Data), func(element json.RawMessage) error {
+			if err := fn(element); err != nil {
+				if errors.Is(err, ErrStopIteration) {
+					// the remaining chunks must not be read either
+					return errStopped
+				}
+
+				return err
+			}
+
+			return nil
+		})
+	})
+
+	if errors.Is(err, errStopped) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,267 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeAll(t *testing.T, in string) ([]string, error) {
+	var elements []string
+
+	err := DecodeArrayStream(strings.NewReader(in), func(element json.RawMessage) error {
+		elements = append(elements, string(element))
+		return nil
+	})
+
+	return elements, err
+}
+
+func TestDecodeArrayStream(t *testing.T) {
+	elements, err := decodeAll(t, ` [{"id":1}, [2, 3] ,"four",null] `)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"id":1}`, `[2, 3]`, `"four"`, `null`}, elements)
+
+	elements, err = decodeAll(t, `[]`)
+	assert.NoError(t, err)
+	assert.Empty(t, elements)
+
+	elements, err = decodeAll(t, `null`)
+	assert.NoError(t, err)
+	assert.Empty(t, elements)
+
+	_, err = decodeAll(t, `{"id":1}`)
+	assert.Error(t, err)
+
+	_, err = decodeAll(t, `[{"id":1},`)
+	assert.Error(t, err)
+}
+
+func TestDecodeArrayStreamStops(t *testing.T) {
+	var seen int
+
+	assert.NoError(t, DecodeArrayStream(strings.NewReader(`[1,2,3,garbage`), func(json.RawMessage) error {
+		seen++
+
+		if seen == 2 {
+			return ErrStopIteration
+		}
+
+		return nil
+	}))
+	assert.Equal(t, 2, seen)
+
+	errBoom := errors.New("boom")
+
+	assert.ErrorIs(t, DecodeArrayStream(strings.NewReader(`[1,2]`), func(json.RawMessage) error { return errBoom }), errBoom)
+}
+
+func TestGetElementsWithCache(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1},{"id":2}]`, `[]`, `[{"id":3}]`))
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	for pass := 0; pass < 2; pass++ {
+		var ids []int
+
+		assert.NoError(t, api.GetElementsWithCache("/data/results/search_series", time.Hour, func(element json.RawMessage) error {
+			var row struct{ ID int }
+
+			assert.NoError(t, json.Unmarshal(element, &row))
+			ids = append(ids, row.ID)
+
+			return nil
+		}))
+
+		assert.Equal(t, []int{1, 2, 3}, ids)
+	}
+
+	// fetched once, read from the cache the second time
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+
+	var ids []string
+
+	// stopping in the first chunk skips the others
+	assert.NoError(t, api.GetElementsWithCache("/data/results/search_series", time.Hour, func(element json.RawMessage) error {
+		ids = append(ids, string(element))
+		return ErrStopIteration
+	}))
+	assert.Equal(t, []string{`{"id":1}`}, ids)
+}
+
+func TestGetElementsWithCacheStreamsMiss(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleChunked("/data/results/search_series", mockChunks(`[{"id":1}]`, `[{"id":2}]`, `[{"id":3}]`))
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	chunkPath := func(n int) string {
+		return fmt.Sprintf("/chunks/data/results/search_series/0/%d.json", n)
+	}
+
+	n := 0
+
+	assert.NoError(t, api.GetElementsWithCache("/data/results/search_series", time.Hour, func(element json.RawMessage) error {
+		// each element arrives before the next chunk is downloaded
+		assert.Equal(t, 1, m.hitCount(chunkPath(n)))
+		assert.Equal(t, 0, m.hitCount(chunkPath(n+1)))
+
+		n++
+
+		return nil
+	}))
+	assert.Equal(t, 3, n)
+
+	// and the chunks were cached along the way
+	data, err := api.GetWithCache("/data/results/search_series", time.Hour)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1},{"id":2},{"id":3}]`, string(data))
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+
+	// stopping early downloads nothing more and caches nothing
+	m.handleChunked("/data/results/search_hosted", mockChunks(`[{"id":1}]`, `[{"id":2}]`))
+
+	assert.NoError(t, api.GetElementsWithCache("/data/results/search_hosted", time.Hour, func(json.RawMessage) error {
+		return ErrStopIteration
+	}))
+	assert.Equal(t, 0, m.hitCount("/chunks/data/results/search_hosted/0/1.json"))
+
+	_, err = api.GetWithCache("/data/results/search_hosted", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, m.hitCount("/data/results/search_hosted"))
+}
+
+func TestGetElementsWithCacheNotArray(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/member/info", testMemberInfo)
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	assert.Error(t, api.GetElementsWithCache("/data/member/info", time.Hour, func(json.RawMessage) error { return nil }))
+}
+
+const benchmarkRows = 200000
+
+// lapRowsReader generates a JSON array of n lap rows as it is read, so the
+// input itself never sits in memory
+type lapRowsReader struct {
+	n   int
+	row int
+	buf bytes.Buffer
+}
+
+func (r *lapRowsReader) Read(p []byte) (int, error) {
+	for r.buf.Len() < len(p) && r.row <= r.n {
+		switch {
+		case r.row == 0:
+			r.buf.WriteByte('[')
+		case r.row == r.n:
+			fmt.Fprintf(&r.buf, `{"lap_number":%d,"lap_time":912345,"cust_id":4242,"flags":0,"lap_events":[]}]`, r.row)
+		default:
+			fmt.Fprintf(&r.buf, `{"lap_number":%d,"lap_time":912345,"cust_id":4242,"flags":0,"lap_events":[]},`, r.row)
+		}
+
+		r.row++
+	}
+
+	if r.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return r.buf.Read(p)
+}
+
+// heapPeak tracks the most heap in use above where it started
+type heapPeak struct {
+	base uint64
+	peak uint64
+}
+
+func newHeapPeak() *heapPeak {
+	runtime.GC()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return &heapPeak{base: stats.HeapAlloc}
+}
+
+func (h *heapPeak) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	if stats.HeapAlloc > h.base && stats.HeapAlloc-h.base > h.peak {
+		h.peak = stats.HeapAlloc - h.base
+	}
+}
+
+// compare with BenchmarkDecodeArraySlice, -benchmem and the peak-heap-B
+// metric show the difference
+func BenchmarkDecodeArrayStream(b *testing.B) {
+	var peak uint64
+
+	for n := 0; n < b.N; n++ {
+		h := newHeapPeak()
+		rows := 0
+
+		err := DecodeArrayStream(&lapRowsReader{n: benchmarkRows}, func(element json.RawMessage) error {
+			if rows++; rows%10000 == 0 {
+				h.sample()
+			}
+
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if h.peak > peak {
+			peak = h.peak
+		}
+	}
+
+	b.ReportAllocs()
+	b.ReportMetric(float64(peak), "peak-heap-B")
+}
+
+func BenchmarkDecodeArraySlice(b *testing.B) {
+	var peak uint64
+
+	for n := 0; n < b.N; n++ {
+		h := newHeapPeak()
+
+		data, err := io.ReadAll(&lapRowsReader{n: benchmarkRows})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		var elements []json.RawMessage
+
+		if err := json.Unmarshal(data, &elements); err != nil {
+			b.Fatal(err)
+		}
+
+		h.sample()
+
+		if h.peak > peak {
+			peak = h.peak
+		}
+
+		runtime.KeepAlive(elements)
+	}
+
+	b.ReportAllocs()
+	b.ReportMetric(float64(peak), "peak-heap-B")
+}
@@ -0,0 +1,55 @@
+package irdata
+
+import "errors"
+
+// WithStrictErrors makes the instance return errors where it would
+// otherwise panic.  Affected are AuthWithCredsFromFile, which panics on an
+// unreadable key or creds file, and the Auth methods, which panic when
+// iRacing can't be reached.
+//
+// The package level SaveProvidedCredsToFile can't return an error and still
+// panics, use SaveProvidedCredsToProfile instead.
+//
+// Strict errors will become the default in a future release.
+func WithStrictErrors() Option {
+	return func(i *Irdata) {
+		i.strictErrors = true
+	}
+}
+
+// fail panics with err as the legacy code paths did unless strict errors
+// are enabled, returning err then
+func (i *Irdata) fail(err error) error {
+	if !i.strictErrors {
+		i.logger.Panic(err)
+	}
+
+	return err
+}
+
+// unreachableError is a failure to reach iRacing while logging in, which
+// the Auth methods hand to fail.  Logging in again during a request just
+// returns it.
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unreachableError) Unwrap() error {
+	return e.err
+}
+
+// failLogin is fail for the login errors that are unreachableErrors, other
+// than ErrOffline which isn't a failure to reach iRacing
+func (i *Irdata) failLogin(err error) error {
+	var unreachable *unreachableError
+
+	if errors.As(err, &unreachable) && !errors.Is(err, ErrOffline) {
+		return i.fail(err)
+	}
+
+	return err
+}
@@ -0,0 +1,107 @@
+package irdata
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the functions allowed to call a logger's Panic and why that's fine in strict mode
+var allowedPanics = map[string]string{
+	"fail":                       "only panics when strict errors are off",
+	"writeCreds":                 "legacy wrapper of writeCredsFile for SaveProvidedCredsToFile",
+	"readCreds":                  "legacy wrapper of readCredsFile, unused by exported functions",
+	"getKey":                     "legacy wrapper of getKeyFile, unused by exported functions",
+	"encodedPassword":            "hashing never fails",
+	"init":                       "parses a constant",
+	"newCookieJar":               "cookiejar.New never fails",
+	"CredsFromTerminal.GetCreds": "CredsProvider can't return errors",
+}
+
+func TestNoPanicsInStrictMode(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	assert.NoError(t, err)
+
+	fset := token.NewFileSet()
+
+	for _, fn := range files {
+		if strings.HasSuffix(fn, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, fn, nil, 0)
+		assert.NoError(t, err)
+
+		for _, decl := range f.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+
+			name := funcDecl.Name.Name
+
+			if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+				if star, ok := funcDecl.Recv.List[0].Type.(*ast.StarExpr); ok {
+					if ident, ok := star.X.(*ast.Ident); ok && ident.Name != "Irdata" {
+						name = ident.Name + "." + name
+					}
+				} else if ident, ok := funcDecl.Recv.List[0].Type.(*ast.Ident); ok {
+					name = ident.Name + "." + name
+				}
+			}
+
+			ast.Inspect(funcDecl, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+
+				// log, packageLogger or an instance's logger
+				if strings.HasPrefix(sel.Sel.Name, "Panic") {
+					_, allowed := allowedPanics[name]
+					assert.True(t, allowed, "%s panics in %s, use fail", fset.Position(call.Pos()), name)
+				}
+
+				return true
+			})
+		}
+	}
+}
+
+func TestStrictAuthWithCredsFromFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.creds")
+
+	api := Open(context.Background(), WithStrictErrors())
+	assert.Error(t, api.AuthWithCredsFromFile(testKeyFilename, missing))
+
+	assert.Panics(t, func() {
+		Open(context.Background()).AuthWithCredsFromFile(testKeyFilename, missing)
+	})
+}
+
+func TestStrictAuthUnreachable(t *testing.T) {
+	m := newMockAPI(t)
+	m.Close()
+
+	unreachable, err := url.Parse(m.URL)
+	assert.NoError(t, err)
+
+	api := Open(context.Background(), WithBaseURL(unreachable), WithStrictErrors())
+	assert.Error(t, api.AuthWithProvideCreds(testCreds{}))
+
+	assert.Panics(t, func() {
+		Open(context.Background(), WithBaseURL(unreachable)).AuthWithProvideCreds(testCreds{})
+	})
+}
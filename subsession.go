@@ -0,0 +1,174 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the first and the longest delay between polls of WaitForSubsessionResult
+const waitResultFirstDelay = 15 * time.Second
+const waitResultMaxDelay = 2 * time.Minute
+
+// SubsessionResult is the response of /data/results/get
+type SubsessionResult struct {
+	SubsessionID         int64            `json:"subsession_id"`
+	SessionID            int64            `json:"session_id"`
+	SeasonID             int64            `json:"season_id"`
+	SeasonName           string           `json:"season_name"`
+	SeriesID             int64            `json:"series_id"`
+	SeriesName           string           `json:"series_name"`
+	StartTime            time.Time        `json:"start_time"`
+	EndTime              time.Time        `json:"end_time"`
+	LicenseCategoryID    int64            `json:"license_category_id"`
+	EventType            int              `json:"event_type"`
+	EventTypeName        string           `json:"event_type_name"`
+	OfficialSession      bool             `json:"official_session"`
+	EventStrengthOfField int              `json:"event_strength_of_field"`
+	NumDrivers           int              `json:"num_drivers"`
+	Track                SearchTrack      `json:"track"`
+	SessionResults       []SessionResults `json:"session_results"`
+
+	// HeatInfo is the format of heat racing events, whose heats,
+	// consolations and feature may be split over the subsessions listed
+	// by AssociatedSubsessionIDs, see GetHeatProgression
+	HeatInfoID              int64     `json:"heat_info_id"`
+	HeatInfo                *HeatInfo `json:"heat_info"`
+	AssociatedSubsessionIDs []int64   `json:"associated_subsession_ids"`
+}
+
+// SessionResults are the results of one simsession (practice, qualifying,
+// race) of a subsession
+type SessionResults struct {
+	SimsessionNumber   int                `json:"simsession_number"`
+	SimsessionType     int                `json:"simsession_type"`
+	SimsessionTypeName string             `json:"simsession_type_name"`
+	SimsessionName     string             `json:"simsession_name"`
+	Results            []SessionResultRow `json:"results"`
+}
+
+// SessionResultRow is a single driver's (or team's) result in a simsession
+type SessionResultRow struct {
+	CustID                  int64  `json:"cust_id" pii:"cust_id"`
+	TeamID                  int64  `json:"team_id"`
+	DisplayName             string `json:"display_name" pii:"name"`
+	FinishPosition          int    `json:"finish_position"`
+	FinishPositionInClass   int    `json:"finish_position_in_class"`
+	StartingPosition        int    `json:"starting_position"`
+	StartingPositionInClass int    `json:"starting_position_in_class"`
+	LapsComplete            int    `json:"laps_complete"`
+	LapsLead                int    `json:"laps_lead"`
+	Incidents               int    `json:"incidents"`
+	BestLapTime             int    `json:"best_lap_time"`
+	AverageLap              int    `json:"average_lap"`
+	CarID                   int64  `json:"car_id"`
+	CarClassID              int64  `json:"car_class_id"`
+	OldiRating              int    `json:"oldi_rating"`
+	NewiRating              int    `json:"newi_rating"`
+	ChampPoints             int    `json:"champ_points"`
+	ReasonOut               string `json:"reason_out"`
+	ReasonOutID             int    `json:"reason_out_id"`
+
+	// Status is how the entry took part, set by GetSubsessionResult, see
+	// ClassifyEntries
+	Status ParticipationStatus `json:"-"`
+
+	// DriverResults are the results of the drivers of a team in team
+	// events, the row itself is the team's
+	DriverResults []SessionResultRow `json:"driver_results"`
+}
+
+// GetSubsessionResult returns the results of the subsession or
+// ErrNotYetAvailable if iRacing hasn't scored it yet
+func (i *Irdata) GetSubsessionResult(ctx context.Context, subsessionID int64) (*SubsessionResult, error) {
+	data, err := i.getSubsessionResultData(ctx, subsessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SubsessionResult
+
+	if err := i.decodeJSON(subsessionResultURI(subsessionID), data, &result); err != nil {
+		return nil, err
+	}
+
+	result.classifyEntries(i.reasonOutByID)
+
+	return &result, nil
+}
+
+func subsessionResultURI(subsessionID int64) string {
+	return fmt.Sprintf("/data/results/get?subsession_id=%d", subsessionID)
+}
+
+// getSubsessionResultData is GetSubsessionResult returning the raw result
+func (i *Irdata) getSubsessionResultData(ctx context.Context, subsessionID int64) ([]byte, error) {
+	resp, err := i.Do(ctx, http.MethodGet, subsessionResultURI(subsessionID), nil, WithFollowLink())
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := readResponse(subsessionResultURI(subsessionID), resp, i.responseLimit(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// iRacing answers 404 until the subsession is scored
+		return nil, ErrNotYetAvailable
+	default:
+		return nil, fmt.Errorf("getting subsession %d failed: %s", subsessionID, resp.Status)
+	}
+
+	i.archivePayload(subsessionResultURI(subsessionID), &payload{data: data})
+
+	return data, nil
+}
+
+// WaitForSubsessionResult polls for the results of the subsession until
+// they are available, backing off exponentially between polls.  If they
+// aren't available within maxWait a *WaitTimeoutError is returned.
+func (i *Irdata) WaitForSubsessionResult(ctx context.Context, subsessionID int64, maxWait time.Duration) (*SubsessionResult, error) {
+	deadline := i.clock.Now().Add(maxWait)
+	delay := waitResultFirstDelay
+
+	for attempt := 1; ; attempt++ {
+		result, err := i.GetSubsessionResult(ctx, subsessionID)
+		if !errors.Is(err, ErrNotYetAvailable) {
+			return result, err
+		}
+
+		remaining := deadline.Sub(i.clock.Now())
+		if remaining <= 0 {
+			return nil, &WaitTimeoutError{Attempts: attempt}
+		}
+
+		// one last poll right at the deadline
+		if delay > remaining {
+			delay = remaining
+		}
+
+		i.logger.WithFields(log.Fields{
+			"subsessionID": subsessionID,
+			"attempt":      attempt,
+			"delay":        delay,
+		}).Info("Subsession result not yet available")
+
+		if err := i.clock.Sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		delay *= 2
+		if delay > waitResultMaxDelay {
+			delay = waitResultMaxDelay
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSubsessionResult = `{
+	"subsession_id": 12345,
+	"series_name": "Formula Vee",
+	"event_type": 5,
+	"session_results": [{
+		"simsession_number": 0,
+		"simsession_type_name": "Race",
+		"results": [{"cust_id": 1, "display_name": "Jane Doe", "finish_position": 0}]
+	}]
+}`
+
+// handleSubsession serves the result after answering 404 pending times
+func (m *mockAPI) handleSubsession(pending int32) {
+	var calls int32
+
+	m.handleLinked("/data/results/scored", testSubsessionResult)
+
+	m.handle("/data/results/get", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= pending {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprintf(w, `{"link":"%s/s3/data/results/scored"}`, m.URL)
+	})
+}
+
+func TestGetSubsessionResult(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleSubsession(1)
+
+	api := m.openAuthed(t)
+
+	_, err := api.GetSubsessionResult(context.Background(), 12345)
+	assert.ErrorIs(t, err, ErrNotYetAvailable)
+
+	result, err := api.GetSubsessionResult(context.Background(), 12345)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), result.SubsessionID)
+	assert.Equal(t, "Jane Doe", result.SessionResults[0].Results[0].DisplayName)
+}
+
+func TestWaitForSubsessionResult(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleSubsession(3)
+
+	api := m.openAuthed(t)
+
+	clock := newFakeClock()
+	api.clock = clock
+
+	result, err := api.WaitForSubsessionResult(context.Background(), 12345, 10*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), result.SubsessionID)
+
+	assert.Equal(t, []time.Duration{15 * time.Second, 30 * time.Second, time.Minute}, clock.slept())
+	assert.Equal(t, 4, m.hitCount("/data/results/get"))
+}
+
+func TestWaitForSubsessionResultTimesOut(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleSubsession(1000)
+
+	api := m.openAuthed(t)
+
+	clock := newFakeClock()
+	api.clock = clock
+
+	_, err := api.WaitForSubsessionResult(context.Background(), 12345, 5*time.Minute)
+	assert.ErrorIs(t, err, ErrTimedOutWaiting)
+
+	var timeout *WaitTimeoutError
+
+	assert.True(t, errors.As(err, &timeout))
+	assert.Equal(t, 6, timeout.Attempts)
+	assert.Equal(t, fmt.Sprintf("timed out waiting after %d attempts", 6), err.Error())
+
+	// capped at two minutes and never sleeping past the deadline
+	assert.Equal(t, []time.Duration{15 * time.Second, 30 * time.Second, time.Minute, 2 * time.Minute, 75 * time.Second}, clock.slept())
+}
+
+func TestWaitForSubsessionResultCancelled(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleSubsession(1000)
+
+	api := m.openAuthed(t)
+	api.clock = newFakeClock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := api.WaitForSubsessionResult(ctx, 12345, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+}
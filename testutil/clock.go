@@ -0,0 +1,115 @@
+// Package testutil has helpers for testing code that uses irdata
+package testutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/popmonkey/irdata"
+)
+
+// SimpleFakeClock is an irdata.Clock that only moves when it is slept on or
+// advanced, pass it to irdata.WithClock or irdata.NewMemoryCacheWithClock.
+// Sleep returns at once, moving the clock forward by the duration.
+type SimpleFakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	slept  []time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *SimpleFakeClock
+	c       chan time.Time
+	at      time.Time
+	stopped bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	stopped := t.stopped
+	t.stopped = true
+
+	return !stopped
+}
+
+// NewSimpleFakeClock returns a clock set to start
+func NewSimpleFakeClock(start time.Time) *SimpleFakeClock {
+	return &SimpleFakeClock{now: start}
+}
+
+func (c *SimpleFakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *SimpleFakeClock) NewTimer(d time.Duration) irdata.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d)}
+	c.timers = append(c.timers, t)
+
+	c.fire()
+
+	return t
+}
+
+func (c *SimpleFakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.slept = append(c.slept, d)
+	c.mu.Unlock()
+
+	c.Advance(d)
+
+	return nil
+}
+
+// Advance moves the clock forward by d, firing the timers due by then
+func (c *SimpleFakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	c.fire()
+}
+
+// Slept returns the durations Sleep was called with so far
+func (c *SimpleFakeClock) Slept() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]time.Duration{}, c.slept...)
+}
+
+// fire sends on and forgets the timers that are due, c.mu must be held
+func (c *SimpleFakeClock) fire() {
+	pending := c.timers[:0]
+
+	for _, t := range c.timers {
+		switch {
+		case t.stopped:
+		case !t.at.After(c.now):
+			t.stopped = true
+			t.c <- c.now
+		default:
+			pending = append(pending, t)
+		}
+	}
+
+	c.timers = pending
+}
@@ -0,0 +1,72 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/popmonkey/irdata"
+	"github.com/stretchr/testify/assert"
+)
+
+var testStart = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSimpleFakeClockSleep(t *testing.T) {
+	clock := NewSimpleFakeClock(testStart)
+
+	assert.NoError(t, clock.Sleep(context.Background(), time.Hour))
+	assert.Equal(t, testStart.Add(time.Hour), clock.Now())
+	assert.Equal(t, []time.Duration{time.Hour}, clock.Slept())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, clock.Sleep(ctx, time.Hour), context.Canceled)
+	assert.Equal(t, testStart.Add(time.Hour), clock.Now())
+}
+
+func TestSimpleFakeClockTimers(t *testing.T) {
+	clock := NewSimpleFakeClock(testStart)
+
+	due := clock.NewTimer(time.Minute)
+	stopped := clock.NewTimer(time.Minute)
+
+	assert.True(t, stopped.Stop())
+
+	clock.Advance(59 * time.Second)
+
+	select {
+	case <-due.C():
+		t.Fatal("fired early")
+	default:
+	}
+
+	clock.Advance(time.Second)
+
+	assert.Equal(t, testStart.Add(time.Minute), <-due.C())
+	assert.False(t, due.Stop())
+
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+}
+
+func TestSimpleFakeClockMemoryCache(t *testing.T) {
+	clock := NewSimpleFakeClock(testStart)
+
+	var _ irdata.Clock = clock
+
+	cache := irdata.NewMemoryCacheWithClock(clock)
+
+	key := []byte("key")
+
+	assert.NoError(t, cache.PutWithTTL(context.Background(), key, []byte("value"), time.Hour))
+
+	clock.Advance(59 * time.Minute)
+	assert.True(t, cache.Has(context.Background(), key))
+
+	clock.Advance(2 * time.Minute)
+	assert.False(t, cache.Has(context.Background(), key))
+}
@@ -0,0 +1,74 @@
+package irdata
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WithRootCAs verifies the certificates of the API, s3 and asset hosts
+// against pool instead of the system's, e.g. to trust the CA of a proxy
+// intercepting TLS.  Add the system pool's certificates to pool to trust
+// both.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(i *Irdata) {
+		i.rootCAs = pool
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the connections to the API,
+// s3 and asset hosts, WithRootCAs replaces its RootCAs if also given.  Both
+// apply to the transport Open builds whatever the order of the options, and
+// are no-ops for transports that aren't an *http.Transport.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(i *Irdata) {
+		i.tlsConfig = config
+	}
+}
+
+// applyTLS sets up the TLS configuration of the transports Open built
+func (i *Irdata) applyTLS() {
+	if i.tlsConfig == nil && i.rootCAs == nil {
+		return
+	}
+
+	var config *tls.Config
+
+	if i.tlsConfig != nil {
+		config = i.tlsConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+
+	if i.rootCAs != nil {
+		config.RootCAs = i.rootCAs
+	}
+
+	for _, client := range []*http.Client{&i.httpClient, &i.assetClient} {
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			i.logger.WithFields(log.Fields{"transport": client.Transport}).Warn("TLS options don't apply to the transport, it isn't an *http.Transport")
+			continue
+		}
+
+		transport.TLSClientConfig = config
+	}
+}
+
+// tlsVerificationError is err as a *TLSVerificationError when it's a
+// certificate verification failure, err otherwise
+func tlsVerificationError(host string, err error) error {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var systemRoots x509.SystemRootsError
+
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalid) || errors.As(err, &hostname) || errors.As(err, &systemRoots) {
+		return &TLSVerificationError{Host: host, Err: err}
+	}
+
+	return err
+}
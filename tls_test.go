@@ -0,0 +1,76 @@
+package irdata
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTLSHosts starts an API and an s3 host with certificates of a CA the
+// system doesn't trust, and returns the pool trusting it
+func newTLSHosts(t *testing.T) (*httptest.Server, *httptest.Server, *x509.CertPool) {
+	s3 := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"linked":true}`)
+	}))
+	t.Cleanup(s3.Close)
+
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/linked"}`, s3.URL)
+	}))
+	t.Cleanup(api.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(api.Certificate())
+	pool.AddCert(s3.Certificate())
+
+	return api, s3, pool
+}
+
+func TestTLSOptions(t *testing.T) {
+	apiHost, s3Host, pool := newTLSHosts(t)
+
+	baseURL, _ := url.Parse(apiHost.URL)
+
+	// after WithTransportOptions, which replaces the transport
+	for _, opt := range []Option{
+		WithRootCAs(pool),
+		WithTLSConfig(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}),
+	} {
+		api := Open(context.Background(), WithBaseURL(baseURL), opt, WithTransportOptions(TransportOptions{}))
+
+		data, err := api.Get("/data/track/get", AllowAnonymous())
+		assert.NoError(t, err)
+		assert.Equal(t, `{"linked":true}`, string(data))
+
+		asset, err := api.getAsset(context.Background(), s3Host.URL+"/map.svg")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"linked":true}`, string(asset))
+	}
+}
+
+func TestTLSVerificationError(t *testing.T) {
+	apiHost, _, _ := newTLSHosts(t)
+
+	baseURL, _ := url.Parse(apiHost.URL)
+
+	api := Open(context.Background(), WithBaseURL(baseURL))
+
+	_, err := api.Get("/data/track/get", AllowAnonymous())
+	assert.ErrorIs(t, err, ErrTLSVerification)
+	assert.Contains(t, err.Error(), "WithRootCAs")
+
+	var verr *TLSVerificationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, baseURL.Host, verr.Host)
+	}
+
+	var unknown x509.UnknownAuthorityError
+	assert.ErrorAs(t, err, &unknown)
+}
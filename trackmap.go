@@ -0,0 +1,224 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// The layers of a track map, listed bottom to top
+const (
+	LayerBackground  = "background"
+	LayerInactive    = "inactive"
+	LayerActive      = "active"
+	LayerPitRoad     = "pitroad"
+	LayerStartFinish = "start-finish"
+	LayerTurns       = "turns"
+)
+
+var trackMapLayerOrder = []string{
+	LayerBackground,
+	LayerInactive,
+	LayerActive,
+	LayerPitRoad,
+	LayerStartFinish,
+	LayerTurns,
+}
+
+const svgNamespace = "http://www.w3.org/2000/svg"
+
+// Layer is one SVG layer of a track map
+type Layer struct {
+	Name string
+	SVG  []byte
+}
+
+type trackAssetsT struct {
+	TrackID        int64             `json:"track_id"`
+	TrackMap       string            `json:"track_map"`
+	TrackMapLayers map[string]string `json:"track_map_layers"`
+}
+
+// GetTrackMapLayers fetches the map layers of the track, bottom layer first.
+// The track assets are cached for a long time when the cache is enabled.
+func (i *Irdata) GetTrackMapLayers(ctx context.Context, trackID int64) ([]Layer, error) {
+	var assets map[string]trackAssetsT
+
+	if err := i.getLookup(ctx, "/data/track/assets", &assets); err != nil {
+		return nil, err
+	}
+
+	track, ok := assets[strconv.FormatInt(trackID, 10)]
+	if !ok || track.TrackMap == "" {
+		return nil, fmt.Errorf("no track map for track %d", trackID)
+	}
+
+	var layers []Layer
+
+	for name, fileName := range track.TrackMapLayers {
+		assetURL, err := i.assetURL(track.TrackMap + fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		svg, err := i.getAsset(ctx, assetURL)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, Layer{Name: name, SVG: svg})
+	}
+
+	sortLayers(layers)
+
+	return layers, nil
+}
+
+// sortLayers puts layers in drawing order, unknown layers on top
+func sortLayers(layers []Layer) {
+	rank := func(name string) int {
+		for n, known := range trackMapLayerOrder {
+			if name == known {
+				return n
+			}
+		}
+
+		return len(trackMapLayerOrder)
+	}
+
+	sort.SliceStable(layers, func(a, b int) bool {
+		ra, rb := rank(layers[a].Name), rank(layers[b].Name)
+		if ra != rb {
+			return ra < rb
+		}
+
+		return layers[a].Name < layers[b].Name
+	})
+}
+
+// svgRoot is the root element of an SVG document split into its attributes
+// and the markup within
+type svgRoot struct {
+	attrs []xml.Attr
+	inner []byte
+}
+
+func parseSVGRoot(svg []byte) (*svgRoot, error) {
+	dec := xml.NewDecoder(bytes.NewReader(svg))
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, errors.New("no svg element found")
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if start.Name.Local != "svg" {
+			return nil, fmt.Errorf("root element is %s, not svg", start.Name.Local)
+		}
+
+		// content is copied as is rather than re-encoded which would mangle
+		// the namespaces
+		begin := int(dec.InputOffset())
+
+		end := bytes.LastIndex(svg, []byte("</svg>"))
+		if end < begin {
+			// <svg/>
+			end = begin
+		}
+
+		return &svgRoot{attrs: start.Attr, inner: svg[begin:end]}, nil
+	}
+}
+
+// Composite merges layers into a single SVG document written to w.  The
+// layers are stacked in drawing order, each in a group with the layer name
+// as id, and the viewBox of the bottom layer is kept.
+//
+// Style sheets embedded in the layers apply to the whole document once
+// merged, so layers reusing a class name for different styles clash.
+func Composite(w io.Writer, layers ...Layer) error {
+	if len(layers) == 0 {
+		return errors.New("no layers to composite")
+	}
+
+	sorted := append([]Layer{}, layers...)
+	sortLayers(sorted)
+
+	var roots []*svgRoot
+
+	for _, layer := range sorted {
+		root, err := parseSVGRoot(layer.SVG)
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.Name, err)
+		}
+
+		roots = append(roots, root)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(`<svg xmlns="` + svgNamespace + `"`)
+
+	// sizing comes from the bottom layer, namespaces from every layer
+	seen := map[string]bool{}
+
+	for n, root := range roots {
+		for _, attr := range root.attrs {
+			var name string
+
+			switch {
+			case attr.Name.Space == "xmlns":
+				name = "xmlns:" + attr.Name.Local
+			case n == 0 && attr.Name.Space == "" && isSVGSizing(attr.Name.Local):
+				name = attr.Name.Local
+			default:
+				continue
+			}
+
+			if seen[name] {
+				continue
+			}
+
+			seen[name] = true
+
+			buf.WriteString(" " + name + `="`)
+			xml.EscapeText(&buf, []byte(attr.Value))
+			buf.WriteString(`"`)
+		}
+	}
+
+	buf.WriteString(">\n")
+
+	for n, root := range roots {
+		buf.WriteString(`<g id="`)
+		xml.EscapeText(&buf, []byte(sorted[n].Name))
+		buf.WriteString(`">`)
+		buf.Write(root.inner)
+		buf.WriteString("</g>\n")
+	}
+
+	buf.WriteString("</svg>\n")
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+func isSVGSizing(attr string) bool {
+	switch attr {
+	case "viewBox", "width", "height", "x", "y", "preserveAspectRatio":
+		return true
+	}
+
+	return false
+}
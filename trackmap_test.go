@@ -0,0 +1,95 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func trackMapFixture(t *testing.T) []Layer {
+	var layers []Layer
+
+	for _, name := range trackMapLayerOrder {
+		layers = append(layers, Layer{Name: name, SVG: readFixture(t, filepath.Join("trackmap", name+".svg"))})
+	}
+
+	return layers
+}
+
+func TestComposite(t *testing.T) {
+	layers := trackMapFixture(t)
+
+	// the order passed in doesn't matter
+	layers[0], layers[5] = layers[5], layers[0]
+
+	var buf bytes.Buffer
+
+	assert.NoError(t, Composite(&buf, layers...))
+
+	golden := filepath.Join("testdata", "trackmap", "composite.golden.svg")
+
+	if *update {
+		assert.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+	}
+
+	assert.Equal(t, string(readFixture(t, filepath.Join("trackmap", "composite.golden.svg"))), buf.String())
+}
+
+func TestCompositeSelectedLayers(t *testing.T) {
+	layers := trackMapFixture(t)
+
+	var buf bytes.Buffer
+
+	assert.NoError(t, Composite(&buf, layers[2], layers[5]))
+	assert.Contains(t, buf.String(), `viewBox="0 0 1500 1000"`)
+	assert.Contains(t, buf.String(), `<g id="active">`)
+	assert.NotContains(t, buf.String(), `<g id="background">`)
+}
+
+func TestCompositeErrors(t *testing.T) {
+	assert.Error(t, Composite(&bytes.Buffer{}))
+	assert.Error(t, Composite(&bytes.Buffer{}, Layer{Name: "bad", SVG: []byte("<html></html>")}))
+	assert.Error(t, Composite(&bytes.Buffer{}, Layer{Name: "empty"}))
+}
+
+func TestGetTrackMapLayers(t *testing.T) {
+	m := newMockAPI(t)
+
+	m.mux.HandleFunc("/assets/limerock/", func(w http.ResponseWriter, r *http.Request) {
+		// the static host doesn't get the session
+		_, err := r.Cookie(mockAuthCookie)
+		assert.ErrorIs(t, err, http.ErrNoCookie)
+
+		http.ServeFile(w, r, filepath.Join("testdata", "trackmap", filepath.Base(r.URL.Path)))
+	})
+
+	m.handleLinked("/data/track/assets", fmt.Sprintf(`{"1":{"track_id":1,"track_map":"%s/assets/limerock/","track_map_layers":{
+		"background":"background.svg","inactive":"inactive.svg","active":"active.svg",
+		"pitroad":"pitroad.svg","start-finish":"start-finish.svg","turns":"turns.svg"}}}`, m.URL))
+
+	api := m.openAuthed(t)
+	api.EnableCacheBackend(NewMemoryCache())
+
+	layers, err := api.GetTrackMapLayers(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, trackMapFixture(t), layers)
+
+	// layers come from the cache the second time
+	_, err = api.GetTrackMapLayers(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, m.hitCount("/assets/limerock/active.svg"))
+	assert.Equal(t, 1, m.hitCount("/data/track/assets"))
+	assert.Equal(t, lookupTTL, cachedTTL(t, api, "/data/track/assets"))
+
+	_, err = api.GetTrackMapLayers(context.Background(), 2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// isTransientTransportError reports whether err is the API edge dropping
+// the connection (an HTTP/2 GOAWAY, a reset or the connection closing
+// mid-response) rather than something retrying won't fix
+func isTransientTransportError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	// the http2 GOAWAY error net/http bundles isn't exported
+	return strings.Contains(err.Error(), "GOAWAY")
+}
+
+// isIdempotent reports whether a request with method may be sent again
+// after the connection dropped without knowing if it got through
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
+
+// TransportRetryCount returns how many requests were retried because the
+// connection was dropped, as opposed to an error status being answered
+func (i *Irdata) TransportRetryCount() int64 {
+	return atomic.LoadInt64(&i.transportRetries)
+}
+
+// transportRetry logs and counts a retry after err and waits before it
+func (i *Irdata) transportRetry(ctx context.Context, url string, attempt int, err error) error {
+	atomic.AddInt64(&i.transportRetries, 1)
+
+	delay := time.Duration(attempt+1) * retryBackoff
+
+	retryTranscriptFrom(ctx).backoff(delay)
+
+	i.logger.WithFields(log.Fields{
+		"url":   url,
+		"err":   err,
+		"delay": delay,
+	}).Info("*** Retrying dropped connection")
+
+	return i.clock.Sleep(ctx, delay)
+}
+
+// withRetryBudget returns ctx with a retry transcript if it has none yet,
+// so the retry loops nested under it share one attempt budget
+func (i *Irdata) withRetryBudget(ctx context.Context) context.Context {
+	if retryTranscriptFrom(ctx) == nil {
+		ctx, _ = i.withRetryTranscript(ctx)
+	}
+
+	return ctx
+}
+
+// getBody GETs url and reads the whole body, retrying when the connection
+// drops while reading.  Nothing has been handed to the caller yet at that
+// point so starting over is safe.  Web pages are returned as a
+// *NotJSONError.  The response header comes along for its dates.
+func (i *Irdata) getBody(ctx context.Context, url string) ([]byte, http.Header, error) {
+	return i.getBodyWith(ctx, url, nil)
+}
+
+// getBodyWith is getBody sending header along
+func (i *Irdata) getBodyWith(ctx context.Context, url string, header http.Header) ([]byte, http.Header, error) {
+	ctx = i.withRetryBudget(ctx)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := i.authedDo(ctx, http.MethodGet, url, nil, header, retryServerErrors)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data, err := readResponse(url, resp, i.responseLimit(ctx))
+		resp.Body.Close()
+
+		if errors.Is(err, ErrResponseTooLarge) {
+			return nil, nil, err
+		}
+
+		if err == nil {
+			if err := i.checkLinkResponse(url, resp, data); err != nil {
+				return nil, nil, err
+			}
+
+			if err := checkJSONBody(url, resp.Header.Get("Content-Type"), data); err != nil {
+				return nil, nil, err
+			}
+
+			return data, resp.Header, nil
+		}
+
+		retryTranscriptFrom(ctx).failed(err)
+
+		if !isTransientTransportError(err) || !retryTranscriptFrom(ctx).canRetry(url, attempt) {
+			return nil, nil, err
+		}
+
+		if err := i.transportRetry(ctx, url, attempt, err); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// getLinkedBody is getBody for s3 links and chunks, which every so often
+// answer with an empty body while iRacing regenerates them.  Those are
+// retried and if they persist an *EmptyPayloadError is returned.
+func (i *Irdata) getLinkedBody(ctx context.Context, url string) ([]byte, http.Header, error) {
+	if err := i.checkLinkHost(url); err != nil {
+		return nil, nil, err
+	}
+
+	ctx = i.withRetryBudget(ctx)
+
+	for attempt := 1; ; attempt++ {
+		data, header, err := i.getBody(ctx, url)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(bytes.TrimSpace(data)) > 0 {
+			return data, header, nil
+		}
+
+		retryTranscriptFrom(ctx).failed(ErrEmptyPayload)
+
+		if !retryTranscriptFrom(ctx).canRetry(url, attempt) {
+			return nil, nil, &EmptyPayloadError{URL: url, Attempts: attempt}
+		}
+
+		delay := time.Duration(attempt+1) * retryBackoff
+
+		retryTranscriptFrom(ctx).backoff(delay)
+
+		i.logger.WithFields(log.Fields{
+			"url":   url,
+			"delay": delay,
+		}).Info("*** Retrying empty payload")
+
+		if err := i.clock.Sleep(ctx, delay); err != nil {
+			return nil, nil, err
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dropConnection closes the connection halfway through the response
+// headers.  Closing it without a word would have net/http retry on its own.
+func dropConnection(t *testing.T, w http.ResponseWriter) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Fprint(buf, "HTTP/1.1 200 OK\r\n")
+	buf.Flush()
+	conn.Close()
+}
+
+// truncateResponse promises a body and closes the connection halfway through
+func truncateResponse(t *testing.T, w http.ResponseWriter, body string) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(body)*2, body)
+	buf.Flush()
+	conn.Close()
+}
+
+func TestIsTransientTransportError(t *testing.T) {
+	assert.True(t, isTransientTransportError(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	assert.True(t, isTransientTransportError(io.ErrUnexpectedEOF))
+	assert.True(t, isTransientTransportError(errors.New("http2: server sent GOAWAY and closed the connection; LastStreamID=1")))
+	assert.False(t, isTransientTransportError(context.Canceled))
+	assert.False(t, isTransientTransportError(errors.New("x509: certificate signed by unknown authority")))
+}
+
+func TestTransportErrorsRetried(t *testing.T) {
+	m := newMockAPI(t)
+
+	var calls int32
+
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			dropConnection(t, w)
+			return
+		}
+
+		fmt.Fprint(w, testMemberInfo)
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	data, err := api.Get("/data/member/info")
+	assert.NoError(t, err)
+	assert.Equal(t, testMemberInfo, string(data))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, int64(2), api.TransportRetryCount())
+}
+
+func TestTruncatedBodyRetried(t *testing.T) {
+	m := newMockAPI(t)
+
+	var calls int32
+
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			truncateResponse(t, w, testMemberInfo[:10])
+			return
+		}
+
+		fmt.Fprint(w, testMemberInfo)
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	data, err := api.Get("/data/member/info")
+	assert.NoError(t, err)
+	assert.Equal(t, testMemberInfo, string(data))
+	assert.Equal(t, int64(1), api.TransportRetryCount())
+}
+
+func TestTransportErrorsGiveUp(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		dropConnection(t, w)
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	err := api.GetJSON(context.Background(), "/data/member/info", &struct{}{})
+	assert.Error(t, err)
+	assert.Equal(t, maxAttempts, m.hitCount("/data/member/info"))
+}
+
+func TestTransportErrorsNotRetried(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/league/apply", func(w http.ResponseWriter, r *http.Request) {
+		dropConnection(t, w)
+	})
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		truncateResponse(t, w, testMemberInfo[:10])
+	})
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	// a POST may have been acted on
+	assert.Error(t, api.PostJSON(context.Background(), "/data/league/apply", struct{}{}, nil))
+	assert.Equal(t, 1, m.hitCount("/data/league/apply"))
+
+	// and the body Do returned was already being consumed
+	resp, err := api.Do(context.Background(), http.MethodGet, "/data/member/info", nil)
+	assert.NoError(t, err)
+
+	_, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, 1, m.hitCount("/data/member/info"))
+	assert.Equal(t, int64(0), api.TransportRetryCount())
+}
+
+func TestTransportRetryWaitsForContext(t *testing.T) {
+	m := newMockAPI(t)
+	m.handle("/data/member/info", func(w http.ResponseWriter, r *http.Request) {
+		dropConnection(t, w)
+	})
+
+	api := m.openAuthed(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := api.Do(ctx, http.MethodGet, "/data/member/info", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// emptyAtFirst answers with an empty body the first empties times
+func emptyAtFirst(empties int32, body string) http.HandlerFunc {
+	var calls int32
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= empties {
+			return
+		}
+
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestEmptyLinkRetried(t *testing.T) {
+	m := newMockAPI(t)
+	m.mux.HandleFunc("/s3/regenerating", emptyAtFirst(1, `{"ok":true}`))
+	m.handleJSON("/data/regenerating", `{"link":"`+m.URL+`/s3/regenerating?signature=abc"}`)
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	data, err := api.Get("/data/regenerating")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, 2, m.hitCount("/s3/regenerating"))
+}
+
+func TestEmptyChunkRetried(t *testing.T) {
+	m := newMockAPI(t)
+	m.mux.HandleFunc("/s3/chunks/0.json", emptyAtFirst(1, `[{"id":1}]`))
+	m.handleJSON("/data/chunked", `{"type":"chunked","data":{"success":true,"chunk_info":{"num_chunks":1,"base_download_url":"`+m.URL+`/s3/chunks/","chunk_file_names":["0.json"]}}}`)
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	data, err := api.Get("/data/chunked")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1}]`, string(data))
+	assert.Equal(t, 2, m.hitCount("/s3/chunks/0.json"))
+}
+
+func TestEmptyPayloadGivesUp(t *testing.T) {
+	m := newMockAPI(t)
+	m.mux.HandleFunc("/s3/empty", emptyAtFirst(maxAttempts, `{"ok":true}`))
+	m.handleJSON("/data/empty", `{"link":"`+m.URL+`/s3/empty?signature=abc"}`)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	_, err := api.GetWithCache("/data/empty", time.Hour)
+	assert.ErrorIs(t, err, ErrEmptyPayload)
+
+	var emptyErr *EmptyPayloadError
+
+	assert.True(t, errors.As(err, &emptyErr))
+	assert.Equal(t, maxAttempts, emptyErr.Attempts)
+	assert.Contains(t, emptyErr.URL, "/s3/empty")
+	assert.NotContains(t, err.Error(), "signature=abc")
+	assert.Len(t, clock.slept(), maxAttempts-1)
+
+	// nothing empty was cached, the next call gets the regenerated data
+	data, err := api.GetWithCache("/data/empty", time.Hour)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, 2, m.hitCount("/data/empty"))
+}
+
+func TestNestedRetriesShareAttempts(t *testing.T) {
+	m := newMockAPI(t)
+
+	var calls int32
+
+	// every other answer is retried by a different retry loop
+	m.mux.HandleFunc("/s3/flapping", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	m.handleJSON("/data/flapping", `{"link":"`+m.URL+`/s3/flapping?signature=abc"}`)
+
+	api := m.openAuthed(t, WithClock(newFakeClock()))
+
+	_, err := api.Get("/data/flapping")
+	assert.Error(t, err)
+	assert.Equal(t, maxAttempts, m.hitCount("/s3/flapping"))
+}
@@ -0,0 +1,305 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UnknownFieldsPolicy decides what the typed getters do with fields of a
+// response their types don't have, e.g. ones iRacing just added
+type UnknownFieldsPolicy int
+
+const (
+	// UnknownFieldsIgnore drops them silently.  The default.
+	UnknownFieldsIgnore UnknownFieldsPolicy = iota
+
+	// UnknownFieldsWarn decodes as usual but logs the paths of the unknown
+	// fields, each once per endpoint, and passes them to the hook set with
+	// WithUnknownFieldsHook
+	UnknownFieldsWarn
+
+	// UnknownFieldsReject fails the decoding with an *UnknownFieldsError,
+	// as json.Decoder.DisallowUnknownFields would but listing every path
+	UnknownFieldsReject
+)
+
+// ErrUnknownFields is returned, as an *UnknownFieldsError, by the typed
+// getters of an instance with UnknownFieldsReject when a response has
+// fields their types don't
+var ErrUnknownFields = errors.New("response has unknown fields")
+
+// UnknownFieldsError lists the paths of the unknown fields of the response
+// to URI, e.g. "session_results[].results[].new_field".  It matches
+// ErrUnknownFields.
+type UnknownFieldsError struct {
+	URI   string
+	Paths []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("%v from %s: %s", ErrUnknownFields, e.URI, strings.Join(e.Paths, ", "))
+}
+
+func (e *UnknownFieldsError) Is(target error) bool {
+	return target == ErrUnknownFields
+}
+
+type unknownFieldsT struct {
+	policy UnknownFieldsPolicy
+	hook   func(uri string, paths []string)
+
+	mu     sync.Mutex
+	warned map[string]bool
+}
+
+// WithUnknownFields sets what the typed getters (GetJSON, GetSeasons,
+// GetSubsessionResult and the like) do with fields their types don't have
+func WithUnknownFields(policy UnknownFieldsPolicy) Option {
+	return func(i *Irdata) {
+		i.unknownFields.policy = policy
+	}
+}
+
+// WithUnknownFieldsHook calls hook with the uri and unknown field paths of
+// every response decoded with unknown fields under UnknownFieldsWarn, e.g.
+// to count them in a metrics system
+func WithUnknownFieldsHook(hook func(uri string, paths []string)) Option {
+	return func(i *Irdata) {
+		i.unknownFields.hook = hook
+	}
+}
+
+// decodeJSON unmarshals the response to uri into v applying the unknown
+// fields policy
+func (i *Irdata) decodeJSON(uri string, data []byte, v interface{}, opts ...JSONOption) error {
+	policy := i.unknownFields.policy
+
+	if policy != UnknownFieldsIgnore {
+		if paths := unknownFieldPaths(data, reflect.TypeOf(v)); len(paths) > 0 {
+			if policy == UnknownFieldsReject {
+				return &UnknownFieldsError{URI: uri, Paths: paths}
+			}
+
+			i.warnUnknownFields(uri, paths)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	for _, opt := range opts {
+		opt(dec)
+	}
+
+	return dec.Decode(v)
+}
+
+// warnUnknownFields logs the paths not logged before for the endpoint of uri
+// and calls the hook with all of them
+func (i *Irdata) warnUnknownFields(uri string, paths []string) {
+	endpoint := uri
+	if u, err := url.Parse(uri); err == nil {
+		endpoint = u.Path
+	}
+
+	u := &i.unknownFields
+
+	u.mu.Lock()
+
+	if u.warned == nil {
+		u.warned = make(map[string]bool)
+	}
+
+	var fresh []string
+
+	for _, p := range paths {
+		if key := endpoint + "\x00" + p; !u.warned[key] {
+			u.warned[key] = true
+			fresh = append(fresh, p)
+		}
+	}
+
+	hook := u.hook
+
+	u.mu.Unlock()
+
+	if len(fresh) > 0 {
+		i.logger.WithFields(log.Fields{
+			"endpoint": endpoint,
+			"fields":   strings.Join(fresh, ","),
+		}).Warn("Response has fields the types don't")
+	}
+
+	if hook != nil {
+		i.callHook(func() { hook(uri, paths) })
+	}
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFieldPaths returns the sorted paths of the fields in the JSON data
+// decoding into t would drop.  Maps, interfaces and types unmarshalling
+// themselves take whatever they're given.
+func unknownFieldPaths(data []byte, t reflect.Type) []string {
+	var value interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if dec.Decode(&value) != nil {
+		// left for the real decoding to report
+		return nil
+	}
+
+	seen := make(map[string]bool)
+
+	collectUnknownFields(value, t, "", seen)
+
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+func collectUnknownFields(value interface{}, t reflect.Type, path string, seen map[string]bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		fields := structFields(t)
+
+		for key, v := range object {
+			field, ok := fields.lookup(key)
+			if !ok {
+				seen[joinPath(path, key)] = true
+				continue
+			}
+
+			collectUnknownFields(v, field, joinPath(path, key), seen)
+		}
+
+	case reflect.Slice, reflect.Array:
+		elements, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+
+		for _, v := range elements {
+			collectUnknownFields(v, t.Elem(), path+"[]", seen)
+		}
+
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		for _, v := range object {
+			collectUnknownFields(v, t.Elem(), joinPath(path, "*"), seen)
+		}
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// fieldsT are the JSON names of the fields of a struct and their types
+type fieldsT map[string]reflect.Type
+
+// lookup finds the field key decodes into, matching the names exactly or
+// else ignoring case as encoding/json does
+func (f fieldsT) lookup(key string) (reflect.Type, bool) {
+	if t, ok := f[key]; ok {
+		return t, true
+	}
+
+	for name, t := range f {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+var structFieldsCache sync.Map
+
+// structFields returns the fields of t by JSON name, with the fields of
+// embedded structs promoted
+func structFields(t reflect.Type) fieldsT {
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.(fieldsT)
+	}
+
+	fields := make(fieldsT)
+
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range structFields(embedded) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = field.Type
+	}
+
+	structFieldsCache.Store(t, fields)
+
+	return fields
+}
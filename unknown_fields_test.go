@@ -0,0 +1,127 @@
+package irdata
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type unknownBase struct {
+	ID int64 `json:"id"`
+}
+
+type unknownRow struct {
+	Name  string `json:"name"`
+	Extra interface{}
+}
+
+type unknownDoc struct {
+	unknownBase
+
+	Rows    []unknownRow          `json:"rows"`
+	ByClass map[string]unknownRow `json:"by_class"`
+	Raw     json.RawMessage       `json:"raw"`
+	When    time.Time             `json:"when"`
+	Skipped string                `json:"-"`
+	Nested  *struct {
+		Depth int `json:"depth"`
+	} `json:"nested"`
+}
+
+func TestUnknownFieldPaths(t *testing.T) {
+	data := []byte(`{
+		"id": 1,
+		"ROWS": [{"name": "a", "extra": {"anything": true}}, {"name": "b", "new_row_field": 2}],
+		"by_class": {"gtp": {"name": "c", "new_class_field": 3}},
+		"raw": {"whatever": 1},
+		"when": "2024-03-01T12:00:00Z",
+		"Skipped": "x",
+		"nested": {"depth": 1, "new_nested_field": 4},
+		"new_top_field": 5
+	}`)
+
+	// paths are as the response spells them
+	assert.Equal(t, []string{
+		"ROWS[].new_row_field",
+		"Skipped",
+		"by_class.*.new_class_field",
+		"nested.new_nested_field",
+		"new_top_field",
+	}, unknownFieldPaths(data, reflect.TypeOf(&unknownDoc{})))
+
+	// arrays at the top and anything that takes any field
+	assert.Equal(t, []string{"[].new_row_field"}, unknownFieldPaths([]byte(`[{"name":"a","new_row_field":1}]`), reflect.TypeOf(&[]unknownRow{})))
+	assert.Empty(t, unknownFieldPaths(data, reflect.TypeOf(&map[string]interface{}{})))
+	assert.Empty(t, unknownFieldPaths([]byte(`not json`), reflect.TypeOf(&unknownDoc{})))
+}
+
+const subsessionWithNewFields = `{
+	"subsession_id": 68911202,
+	"new_top_field": true,
+	"track": {"track_id": 1, "track_name": "Lime Rock Park", "new_track_field": 1},
+	"session_results": [{
+		"simsession_number": 0,
+		"results": [{"cust_id": 101, "new_row_field": 1}]
+	}]
+}`
+
+func TestUnknownFieldsWarn(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", subsessionWithNewFields)
+
+	var reported [][]string
+
+	out, logTo := captureLog(t, log.WarnLevel)
+
+	api := m.openAuthed(t, logTo, WithUnknownFields(UnknownFieldsWarn), WithUnknownFieldsHook(func(uri string, paths []string) {
+		assert.Equal(t, "/data/results/get?subsession_id=68911202", uri)
+		reported = append(reported, paths)
+	}))
+
+	for n := 0; n < 2; n++ {
+		result, err := api.GetSubsessionResult(api.ctx, 68911202)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(101), result.SessionResults[0].Results[0].CustID)
+	}
+
+	paths := []string{"new_top_field", "session_results[].results[].new_row_field", "track.new_track_field"}
+
+	api.flushHooks(false)
+	assert.Equal(t, [][]string{paths, paths}, reported)
+
+	// logged once per endpoint
+	assert.Equal(t, 1, strings.Count(out.String(), "Response has fields the types don't"))
+	assert.Contains(t, out.String(), "session_results[].results[].new_row_field")
+}
+
+func TestUnknownFieldsReject(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", subsessionWithNewFields)
+
+	api := m.openAuthed(t, WithUnknownFields(UnknownFieldsReject))
+
+	_, err := api.GetSubsessionResult(api.ctx, 68911202)
+	assert.ErrorIs(t, err, ErrUnknownFields)
+	assert.Contains(t, err.Error(), "track.new_track_field")
+
+	// untyped decoding takes everything
+	var v map[string]interface{}
+
+	assert.NoError(t, api.GetJSON(api.ctx, "/data/results/get?subsession_id=68911202", &v))
+}
+
+func TestUnknownFieldsIgnore(t *testing.T) {
+	m := newMockAPI(t)
+	m.handleLinked("/data/results/get", subsessionWithNewFields)
+
+	api := m.openAuthed(t)
+
+	result, err := api.GetSubsessionResult(api.ctx, 68911202)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(68911202), result.SubsessionID)
+}
@@ -0,0 +1,305 @@
+package irdata
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultWatchLookback = 6 * time.Hour
+const defaultWatchMaxBackoff = 30 * time.Minute
+
+// ResultsFilter selects the results announced by a ResultsWatcher.
+//
+// Each entry of CustIDs is searched with /data/results/search_series while
+// LeagueID is searched with /data/results/search_hosted.  That endpoint
+// doesn't search by league alone so LeagueID needs HostCustID, the member
+// hosting the league's sessions, and the sessions they host are filtered by
+// league.  EventTypes and OfficialOnly only apply to the series searches.
+type ResultsFilter struct {
+	CustIDs      []int64
+	LeagueID     int64
+	HostCustID   int64
+	EventTypes   []int
+	OfficialOnly bool
+}
+
+func (f ResultsFilter) validate() error {
+	if f.LeagueID != 0 && f.HostCustID == 0 {
+		return errors.New("must provide host cust id with league id")
+	}
+
+	return nil
+}
+
+// watchSearchTTL is how long the results searches of the watchers are
+// cached.  Their range begins on the minute so a search stays the same for
+// a minute, watchers sharing the cache share it then, but a poll is never
+// answered with results older than its interval.
+func watchSearchTTL(interval time.Duration) time.Duration {
+	if interval < time.Minute {
+		return interval
+	}
+
+	return time.Minute
+}
+
+// watchLeagueResults searches the results of the sessions of leagueID
+// hosted by hostCustID that finished since begin
+func (i *Irdata) watchLeagueResults(ctx context.Context, hostCustID int64, leagueID int64, begin time.Time, ttl time.Duration) ([]SearchResult, error) {
+	params := SearchHostedParams{FinishRangeBegin: begin, HostCustID: hostCustID, LeagueID: leagueID}
+	uri := "/data/results/search_hosted?" + params.values().Encode()
+
+	var rows []struct {
+		SearchResult
+		LeagueID int64 `json:"league_id"`
+	}
+
+	if err := i.getJSONWithTTL(ctx, uri, ttl, &rows); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+
+	// the server doesn't reliably honor league_id, see SearchHostedParams
+	for _, row := range rows {
+		if row.LeagueID == leagueID {
+			results = append(results, row.SearchResult)
+		}
+	}
+
+	return results, nil
+}
+
+// ResultsWatcher polls the results search endpoints and delivers every
+// newly finished subsession once.
+//
+// When the cache is enabled the subsessions already delivered are kept in
+// it so a restarted watcher with the same filter picks up where it left off.
+type ResultsWatcher struct {
+	// Lookback is how far back each poll searches for finished sessions.
+	// It should comfortably exceed the time iRacing takes to post results.
+	Lookback time.Duration
+
+	// MaxBackoff caps the delay between polls after consecutive errors
+	MaxBackoff time.Duration
+
+	// OnTick, if set, is called at the start of every poll
+	OnTick func(time.Time)
+
+	// OnError, if set, is called with every error encountered while polling
+	OnError func(error)
+
+	i        *Irdata
+	filter   ResultsFilter
+	interval time.Duration
+	stateKey string
+	seen     map[int64]time.Time
+}
+
+// NewResultsWatcher returns a watcher for filter that polls every interval.
+// Call Watch to start it.
+func (i *Irdata) NewResultsWatcher(filter ResultsFilter, interval time.Duration) *ResultsWatcher {
+	key, _ := json.Marshal(filter)
+
+	return &ResultsWatcher{
+		Lookback:   defaultWatchLookback,
+		MaxBackoff: defaultWatchMaxBackoff,
+		i:          i,
+		filter:     filter,
+		interval:   interval,
+		stateKey:   fmt.Sprintf("irdata.watcher.results.%x", md5.Sum(key)),
+		seen:       make(map[int64]time.Time),
+	}
+}
+
+// Watch starts polling and returns the channel newly observed results are
+// delivered on, oldest first.  The channel is closed once ctx is done or
+// the instance is closed.
+func (w *ResultsWatcher) Watch(ctx context.Context) <-chan SearchResult {
+	out := make(chan SearchResult)
+
+	ctx, stop, ok := w.i.beginWatch(ctx)
+	if !ok {
+		close(out)
+		return out
+	}
+
+	w.loadSeen()
+
+	go func() {
+		defer close(out)
+		defer stop()
+
+		failures := 0
+
+		for {
+			delay := w.interval
+
+			results, err := w.poll(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				failures++
+				delay = w.backoff(failures)
+
+				w.i.logger.WithFields(log.Fields{
+					"err":      err,
+					"failures": failures,
+					"delay":    delay,
+				}).Info("Results watcher poll failed")
+
+				if w.OnError != nil {
+					w.OnError(err)
+				}
+			} else {
+				failures = 0
+			}
+
+			for _, result := range results {
+				select {
+				case out <- result:
+					w.seen[result.SubsessionID] = result.EndTime
+				case <-ctx.Done():
+					w.saveSeen()
+					return
+				}
+			}
+
+			if len(results) > 0 {
+				w.saveSeen()
+			}
+
+			timer := w.i.clock.NewTimer(delay)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+
+	return out
+}
+
+// poll returns the results not seen before
+func (w *ResultsWatcher) poll(ctx context.Context) ([]SearchResult, error) {
+	now := w.i.clock.Now()
+
+	if w.OnTick != nil {
+		w.OnTick(now)
+	}
+
+	// truncating keeps the query identical between polls within an interval
+	begin := now.Add(-w.Lookback).Truncate(time.Minute)
+
+	if err := w.filter.validate(); err != nil {
+		return nil, err
+	}
+
+	ttl := watchSearchTTL(w.interval)
+
+	var rows []SearchResult
+
+	for _, custID := range w.filter.CustIDs {
+		r, err := w.i.searchSeriesResults(ctx, SearchSeriesParams{
+			FinishRangeBegin: begin,
+			CustID:           custID,
+			EventTypes:       w.filter.EventTypes,
+			OfficialOnly:     w.filter.OfficialOnly,
+		}, ttl)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, r.Rows...)
+	}
+
+	if w.filter.LeagueID != 0 {
+		r, err := w.i.watchLeagueResults(ctx, w.filter.HostCustID, w.filter.LeagueID, begin, ttl)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, r...)
+	}
+
+	var results []SearchResult
+
+	fresh := make(map[int64]bool)
+
+	for _, row := range rows {
+		if _, ok := w.seen[row.SubsessionID]; ok || fresh[row.SubsessionID] {
+			continue
+		}
+
+		fresh[row.SubsessionID] = true
+
+		results = append(results, row)
+	}
+
+	sort.SliceStable(results, func(a, b int) bool { return results[a].EndTime.Before(results[b].EndTime) })
+
+	// anything older than the lookback can't show up again
+	for subsessionID, endTime := range w.seen {
+		if endTime.Before(begin.Add(-w.Lookback)) {
+			delete(w.seen, subsessionID)
+		}
+	}
+
+	return results, nil
+}
+
+func (w *ResultsWatcher) backoff(failures int) time.Duration {
+	return watchBackoff(w.interval, w.MaxBackoff, failures)
+}
+
+// watchBackoff doubles interval for every consecutive failure up to
+// maxBackoff
+func watchBackoff(interval time.Duration, maxBackoff time.Duration, failures int) time.Duration {
+	delay := interval
+
+	for n := 0; n < failures && delay < maxBackoff; n++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	return delay
+}
+
+func (w *ResultsWatcher) loadSeen() {
+	if w.i.cache == nil {
+		return
+	}
+
+	if _, err := w.i.getCachedJSON(w.i.ctx, w.stateKey, &w.seen); err != nil {
+		w.i.logger.WithFields(log.Fields{"err": err}).Info("Unable to load results watcher state")
+	}
+
+	if w.seen == nil {
+		w.seen = make(map[int64]time.Time)
+	}
+}
+
+func (w *ResultsWatcher) saveSeen() {
+	if w.i.cache == nil {
+		return
+	}
+
+	if err := w.i.setCachedJSON(w.i.ctx, w.stateKey, w.seen, 2*w.Lookback); err != nil {
+		w.i.logger.WithFields(log.Fields{"err": err}).Info("Unable to save results watcher state")
+	}
+}
@@ -0,0 +1,498 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultGroupBuffer = 16
+const defaultGroupSpacing = 2 * time.Second
+
+// groupMinHeadroom is the share of the rate limit below which the group
+// spreads its polls over what's left until the reset
+const groupMinHeadroom = 0.2
+
+// WatcherGroup runs the polls of many watch subscriptions in a single loop.
+// Subscriptions needing the same query share its fetch: two subscriptions
+// following a league search it once per poll.  Fetches are made one at a
+// time, Spacing apart, at PriorityLow, and spread out further once less than
+// a fifth of the rate limit is left.
+//
+// Every subscription gets its own buffered channel.  Events for a
+// subscription whose buffer is full are dropped and counted rather than
+// holding up the others, see Dropped.
+type WatcherGroup struct {
+	// Lookback is how far back the results searches look, see
+	// ResultsWatcher
+	Lookback time.Duration
+
+	// MaxBackoff caps the delay between the polls of a query after
+	// consecutive errors
+	MaxBackoff time.Duration
+
+	// Spacing is the least time between two fetches
+	Spacing time.Duration
+
+	// Buffer is the size of the channel of each subscription
+	Buffer int
+
+	// OnError, if set, is called with every error encountered while polling
+	OnError func(error)
+
+	i *Irdata
+
+	mu      sync.Mutex
+	queries map[string]*groupQuery
+	subs    []groupSubscriber
+	started bool
+	closed  bool
+	wake    chan struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// groupQuery is a fetch shared by the subscriptions needing it
+type groupQuery struct {
+	key      string
+	fetch    func(ctx context.Context, g *WatcherGroup, ttl time.Duration) (interface{}, error)
+	interval time.Duration
+	next     time.Time
+	failures int
+	subs     []groupSubscriber
+}
+
+// groupSubscriber is a subscription of a WatcherGroup
+type groupSubscriber interface {
+	// deliver hands the subscription what query fetched, under the lock
+	// of the group
+	deliver(query string, fetched interface{})
+
+	close()
+}
+
+// NewWatcherGroup returns an empty group.  Subscribe with WatchResults and
+// WatchSeasons, then call Start.
+func (i *Irdata) NewWatcherGroup() *WatcherGroup {
+	return &WatcherGroup{
+		Lookback:   defaultWatchLookback,
+		MaxBackoff: defaultWatchMaxBackoff,
+		Spacing:    defaultGroupSpacing,
+		Buffer:     defaultGroupBuffer,
+		i:          i,
+		queries:    make(map[string]*groupQuery),
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+}
+
+// ResultsSubscription delivers the newly finished subsessions matching its
+// filter on C, each once
+type ResultsSubscription struct {
+	C <-chan SearchResult
+
+	group   *WatcherGroup
+	out     chan SearchResult
+	dropped int64
+	seen    map[int64]time.Time
+}
+
+// Dropped counts the results not delivered because C was full
+func (s *ResultsSubscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// SeasonsSubscription delivers the schedule changes of its series on C.
+// The first poll only takes a snapshot.
+type SeasonsSubscription struct {
+	C <-chan []ScheduleChange
+
+	out       chan []ScheduleChange
+	dropped   int64
+	seriesIDs map[int64]bool
+	snapshot  []Season
+}
+
+// Dropped counts the batches of changes not delivered because C was full
+func (s *SeasonsSubscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// WatchResults subscribes to the results matching filter, polled at least
+// every interval.  Each cust id and the league are a query of their own,
+// shared with the other subscriptions of the group.
+func (g *WatcherGroup) WatchResults(filter ResultsFilter, interval time.Duration) *ResultsSubscription {
+	out := make(chan SearchResult, g.buffer())
+
+	s := &ResultsSubscription{C: out, group: g, out: out, seen: make(map[int64]time.Time)}
+
+	var keys []string
+	var fetches []func(context.Context, *WatcherGroup, time.Duration) (interface{}, error)
+
+	for _, custID := range uniqueIDs(filter.CustIDs) {
+		params := SearchSeriesParams{CustID: custID, EventTypes: filter.EventTypes, OfficialOnly: filter.OfficialOnly}
+
+		keys = append(keys, fmt.Sprintf("series:%d:%v:%v", custID, filter.EventTypes, filter.OfficialOnly))
+		fetches = append(fetches, func(ctx context.Context, g *WatcherGroup, ttl time.Duration) (interface{}, error) {
+			params := params
+			params.FinishRangeBegin = g.lookbackBegin()
+
+			r, err := g.i.searchSeriesResults(ctx, params, ttl)
+			if err != nil {
+				return nil, err
+			}
+
+			return r.Rows, nil
+		})
+	}
+
+	if filter.LeagueID != 0 {
+		leagueID, hostCustID := filter.LeagueID, filter.HostCustID
+
+		keys = append(keys, fmt.Sprintf("hosted:%d:%d", hostCustID, leagueID))
+		fetches = append(fetches, func(ctx context.Context, g *WatcherGroup, ttl time.Duration) (interface{}, error) {
+			if err := filter.validate(); err != nil {
+				return nil, err
+			}
+
+			return g.i.watchLeagueResults(ctx, hostCustID, leagueID, g.lookbackBegin(), ttl)
+		})
+	}
+
+	g.subscribe(s, interval, keys, fetches)
+
+	return s
+}
+
+// WatchSeasons subscribes to the schedule changes of seriesIDs (all series
+// if none), polled at least every interval.  Every seasons subscription of
+// the group shares one GetSeasons.
+func (g *WatcherGroup) WatchSeasons(seriesIDs []int64, interval time.Duration) *SeasonsSubscription {
+	out := make(chan []ScheduleChange, g.buffer())
+
+	s := &SeasonsSubscription{C: out, out: out, seriesIDs: make(map[int64]bool)}
+
+	for _, seriesID := range seriesIDs {
+		s.seriesIDs[seriesID] = true
+	}
+
+	g.subscribe(s, interval, []string{"seasons"}, []func(context.Context, *WatcherGroup, time.Duration) (interface{}, error){
+		func(ctx context.Context, g *WatcherGroup, _ time.Duration) (interface{}, error) {
+			return g.i.GetSeasons(ctx)
+		},
+	})
+
+	return s
+}
+
+// Start starts polling.  The subscriptions are closed once ctx is done, the
+// group or the instance is closed.
+func (g *WatcherGroup) Start(ctx context.Context) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.started || g.closed {
+		return
+	}
+
+	g.started = true
+
+	ctx, stop, ok := g.i.beginWatch(ctx)
+	if !ok {
+		g.shutdown()
+		close(g.done)
+
+		return
+	}
+
+	ctx, g.cancel = context.WithCancel(ctx)
+
+	go func() {
+		defer close(g.done)
+		defer stop()
+
+		g.loop(ctx)
+
+		g.mu.Lock()
+		g.shutdown()
+		g.mu.Unlock()
+	}()
+}
+
+// Close stops polling and closes every subscription, waiting for a poll in
+// progress to end
+func (g *WatcherGroup) Close() {
+	g.mu.Lock()
+
+	if !g.started {
+		g.started = true
+		g.shutdown()
+		g.mu.Unlock()
+		close(g.done)
+
+		return
+	}
+
+	if g.cancel != nil {
+		g.cancel()
+	}
+
+	g.mu.Unlock()
+
+	<-g.done
+}
+
+// shutdown closes the subscriptions, under the lock
+func (g *WatcherGroup) shutdown() {
+	if g.closed {
+		return
+	}
+
+	g.closed = true
+
+	for _, s := range g.subs {
+		s.close()
+	}
+}
+
+func (g *WatcherGroup) buffer() int {
+	if g.Buffer <= 0 {
+		return defaultGroupBuffer
+	}
+
+	return g.Buffer
+}
+
+// lookbackBegin is where the results searches begin, truncated so the
+// query stays the same within a minute
+func (g *WatcherGroup) lookbackBegin() time.Time {
+	return g.i.clock.Now().Add(-g.Lookback).Truncate(time.Minute)
+}
+
+func (g *WatcherGroup) subscribe(s groupSubscriber, interval time.Duration, keys []string, fetches []func(context.Context, *WatcherGroup, time.Duration) (interface{}, error)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		s.close()
+		return
+	}
+
+	g.subs = append(g.subs, s)
+
+	for n, key := range keys {
+		q, ok := g.queries[key]
+		if !ok {
+			q = &groupQuery{key: key, fetch: fetches[n], interval: interval}
+			g.queries[key] = q
+		}
+
+		// the query is polled as often as its most demanding subscription
+		// wants, and straight away so the new one gets its results
+		if interval < q.interval {
+			q.interval = interval
+		}
+
+		q.next = time.Time{}
+		q.subs = append(q.subs, s)
+	}
+
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop polls the query due first, one at a time, until ctx is done
+func (g *WatcherGroup) loop(ctx context.Context) {
+	var notBefore time.Time
+
+	for {
+		g.mu.Lock()
+
+		var due *groupQuery
+
+		for _, q := range g.queries {
+			if due == nil || q.next.Before(due.next) || (q.next.Equal(due.next) && q.key < due.key) {
+				due = q
+			}
+		}
+
+		var at time.Time
+		var ttl time.Duration
+
+		if due != nil {
+			at = due.next
+			ttl = watchSearchTTL(due.interval)
+		}
+
+		g.mu.Unlock()
+
+		if due == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-g.wake:
+				continue
+			}
+		}
+
+		if at.Before(notBefore) {
+			at = notBefore
+		}
+
+		if wait := at.Sub(g.i.clock.Now()); wait > 0 {
+			timer := g.i.clock.NewTimer(wait)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-g.wake:
+				// a new query may be due sooner
+				timer.Stop()
+				continue
+			case <-timer.C():
+			}
+		}
+
+		fetched, err := due.fetch(WithPriority(ctx, PriorityLow), g, ttl)
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := g.i.clock.Now()
+
+		g.mu.Lock()
+
+		delay := due.interval
+
+		if err != nil {
+			due.failures++
+			delay = watchBackoff(due.interval, g.MaxBackoff, due.failures)
+		} else {
+			due.failures = 0
+
+			for _, s := range due.subs {
+				s.deliver(due.key, fetched)
+			}
+		}
+
+		due.next = now.Add(delay)
+
+		g.mu.Unlock()
+
+		if err != nil {
+			g.i.logger.WithFields(log.Fields{
+				"err":      err,
+				"query":    due.key,
+				"failures": due.failures,
+				"delay":    delay,
+			}).Info("Watcher group poll failed")
+
+			if g.OnError != nil {
+				g.OnError(err)
+			}
+		}
+
+		notBefore = now.Add(g.spacing(now))
+	}
+}
+
+// spacing is how long to wait before the next fetch, Spacing unless the
+// rate limit is running low
+func (g *WatcherGroup) spacing(now time.Time) time.Duration {
+	spacing := g.Spacing
+
+	rl := g.i.RateLimit()
+
+	if rl.Limit <= 0 || float64(rl.Remaining) >= float64(rl.Limit)*groupMinHeadroom || !rl.Reset.After(now) {
+		return spacing
+	}
+
+	spread := rl.Reset.Sub(now) / time.Duration(rl.Remaining+1)
+	if spread > spacing {
+		spacing = spread
+	}
+
+	return spacing
+}
+
+func (s *ResultsSubscription) deliver(query string, fetched interface{}) {
+	rows, _ := fetched.([]SearchResult)
+
+	var fresh []SearchResult
+
+	for _, row := range rows {
+		if _, ok := s.seen[row.SubsessionID]; ok {
+			continue
+		}
+
+		// dropped results count as seen too, they'd be dropped again
+		s.seen[row.SubsessionID] = row.EndTime
+
+		fresh = append(fresh, row)
+	}
+
+	sort.SliceStable(fresh, func(a, b int) bool { return fresh[a].EndTime.Before(fresh[b].EndTime) })
+
+	for _, row := range fresh {
+		select {
+		case s.out <- row:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	}
+
+	// anything older than the lookback can't show up again
+	horizon := s.group.lookbackBegin().Add(-s.group.Lookback)
+
+	for subsessionID, endTime := range s.seen {
+		if endTime.Before(horizon) {
+			delete(s.seen, subsessionID)
+		}
+	}
+}
+
+func (s *ResultsSubscription) close() {
+	close(s.out)
+}
+
+func (s *SeasonsSubscription) deliver(query string, fetched interface{}) {
+	seasons, _ := fetched.([]Season)
+
+	followed := []Season{}
+
+	for _, season := range seasons {
+		if len(s.seriesIDs) == 0 || s.seriesIDs[season.SeriesID] {
+			followed = append(followed, season)
+		}
+	}
+
+	var changes []ScheduleChange
+
+	if s.snapshot != nil {
+		changes = ScheduleDiff(s.snapshot, followed)
+	}
+
+	s.snapshot = followed
+
+	if len(changes) == 0 {
+		return
+	}
+
+	select {
+	case s.out <- changes:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+func (s *SeasonsSubscription) close() {
+	close(s.out)
+}
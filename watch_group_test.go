@@ -0,0 +1,219 @@
+package irdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcherGroupSharesQueries(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{}
+	rows.add(1)
+	rows.add(2)
+
+	m.handleChunked("/data/results/search_hosted", rows.chunks)
+
+	api := m.openAuthed(t)
+
+	g := api.NewWatcherGroup()
+	g.Spacing = time.Millisecond
+
+	first := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, time.Hour)
+	second := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, time.Hour)
+
+	g.Start(context.Background())
+
+	assert.Equal(t, []int64{1, 2}, receive(t, first.C, 2))
+	assert.Equal(t, []int64{1, 2}, receive(t, second.C, 2))
+	assertNothingMore(t, first.C)
+
+	assert.Equal(t, 1, m.hitCount("/data/results/search_hosted"))
+
+	g.Close()
+
+	_, ok := <-first.C
+	assert.False(t, ok)
+
+	_, ok = <-second.C
+	assert.False(t, ok)
+
+	late := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, time.Hour)
+
+	_, ok = <-late.C
+	assert.False(t, ok, "subscribing to a closed group")
+}
+
+func TestWatcherGroupPollsAtShortestInterval(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{}
+	rows.add(1)
+
+	m.handleChunked("/data/results/search_hosted", rows.chunks)
+
+	api := m.openAuthed(t)
+
+	g := api.NewWatcherGroup()
+	g.Spacing = time.Millisecond
+
+	slow := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, time.Hour)
+
+	g.Start(context.Background())
+	defer g.Close()
+
+	assert.Equal(t, []int64{1}, receive(t, slow.C, 1))
+
+	fast := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, 5*time.Millisecond)
+
+	assert.Equal(t, []int64{1}, receive(t, fast.C, 1))
+
+	rows.add(2)
+
+	assert.Equal(t, []int64{2}, receive(t, slow.C, 1))
+	assert.Equal(t, []int64{2}, receive(t, fast.C, 1))
+}
+
+func TestWatcherGroupDropsForSlowConsumers(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{}
+	for n := int64(1); n <= 5; n++ {
+		rows.add(n)
+	}
+
+	m.handleChunked("/data/results/search_hosted", rows.chunks)
+
+	api := m.openAuthed(t)
+
+	g := api.NewWatcherGroup()
+	g.Spacing = time.Millisecond
+	g.Buffer = 2
+
+	slow := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, 5*time.Millisecond)
+
+	g.Buffer = 10
+
+	fast := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, 5*time.Millisecond)
+
+	g.Start(context.Background())
+	defer g.Close()
+
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, receive(t, fast.C, 5))
+	assert.Equal(t, []int64{1, 2}, receive(t, slow.C, 2))
+
+	// dropped results aren't delivered by later polls either
+	rows.add(6)
+
+	assert.Equal(t, []int64{6}, receive(t, fast.C, 1))
+	assert.Equal(t, []int64{6}, receive(t, slow.C, 1))
+
+	assert.Equal(t, int64(3), slow.Dropped())
+	assert.Zero(t, fast.Dropped())
+}
+
+func TestWatcherGroupSurvivesErrors(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{fail: true}
+	rows.add(1)
+
+	m.handleChunked("/data/results/search_hosted", rows.chunks)
+
+	api := m.openAuthed(t)
+
+	errs := make(chan error, 10)
+
+	g := api.NewWatcherGroup()
+	g.Spacing = time.Millisecond
+	g.MaxBackoff = 5 * time.Millisecond
+	g.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+
+	s := g.WatchResults(ResultsFilter{LeagueID: 42, HostCustID: 7}, time.Millisecond)
+
+	g.Start(context.Background())
+	defer g.Close()
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no error reported")
+	}
+
+	rows.mu.Lock()
+	rows.fail = false
+	rows.mu.Unlock()
+
+	assert.Equal(t, []int64{1}, receive(t, s.C, 1))
+}
+
+func TestWatcherGroupSpacing(t *testing.T) {
+	clock := newFakeClock()
+
+	api := &Irdata{clock: clock}
+
+	g := api.NewWatcherGroup()
+	g.Spacing = time.Second
+
+	now := clock.Now()
+
+	assert.Equal(t, time.Second, g.spacing(now), "no rate limit known")
+
+	api.rateLimit = RateLimit{Limit: 240, Remaining: 200, Reset: now.Add(time.Minute)}
+	assert.Equal(t, time.Second, g.spacing(now), "plenty left")
+
+	api.rateLimit = RateLimit{Limit: 240, Remaining: 11, Reset: now.Add(time.Minute)}
+	assert.Equal(t, 5*time.Second, g.spacing(now), "spread over what's left")
+
+	api.rateLimit = RateLimit{Limit: 240, Remaining: 0, Reset: now.Add(time.Minute)}
+	assert.Equal(t, time.Minute, g.spacing(now), "wait for the reset")
+
+	api.rateLimit = RateLimit{Limit: 240, Remaining: 0, Reset: now.Add(-time.Second)}
+	assert.Equal(t, time.Second, g.spacing(now), "reset passed")
+}
+
+func TestWatcherGroupSeasons(t *testing.T) {
+	m := newMockAPI(t)
+
+	seasons := &mockSeasons{fixture: "testdata/seasons_before_swap.json"}
+	m.handle("/data/series/seasons", seasons.serve)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+
+	g := api.NewWatcherGroup()
+
+	followed := g.WatchSeasons([]int64{447}, 6*time.Hour)
+	other := g.WatchSeasons([]int64{1}, 12*time.Hour)
+
+	g.Start(context.Background())
+	defer g.Close()
+
+	// the first poll only takes the snapshots
+	assert.Eventually(t, func() bool { return clock.waiting() == 1 }, time.Second, time.Millisecond)
+
+	seasons.set("testdata/seasons_after_swap.json")
+	clock.advance(6 * time.Hour)
+
+	got := <-followed.C
+	assert.Len(t, got, 1)
+	assert.Equal(t, TrackSwapped, got[0].Kind)
+
+	select {
+	case changes := <-other.C:
+		t.Fatalf("unexpected changes %v", changes)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal(t, 2, m.hitCount("/data/series/seasons"))
+}
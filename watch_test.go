@@ -0,0 +1,231 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockResults struct {
+	mu   sync.Mutex
+	rows []string
+	fail bool
+}
+
+func (r *mockResults) add(subsessionID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rows = append(r.rows, fmt.Sprintf(`{"subsession_id":%d,"event_type":5,"official_session":true,"league_id":42,"end_time":"%s"}`, subsessionID, time.Now().UTC().Format(time.RFC3339)))
+}
+
+func (r *mockResults) chunks(*http.Request) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fail {
+		return []string{`{"error":"oops"}`}
+	}
+
+	chunk := "["
+	for n, row := range r.rows {
+		if n > 0 {
+			chunk += ","
+		}
+		chunk += row
+	}
+
+	return []string{chunk + "]"}
+}
+
+func receive(t *testing.T, results <-chan SearchResult, n int) []int64 {
+	var ids []int64
+
+	for len(ids) < n {
+		select {
+		case result := <-results:
+			ids = append(ids, result.SubsessionID)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only received %v", ids)
+		}
+	}
+
+	return ids
+}
+
+func assertNothingMore(t *testing.T, results <-chan SearchResult) {
+	select {
+	case result := <-results:
+		t.Fatalf("unexpected result %d", result.SubsessionID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestResultsWatcher(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{}
+	rows.add(1)
+	rows.add(2)
+
+	m.handleChunked("/data/results/search_series", rows.chunks)
+
+	api := m.openAuthed(t)
+
+	var ticks int
+
+	w := api.NewResultsWatcher(ResultsFilter{CustIDs: []int64{4242}}, 5*time.Millisecond)
+	w.OnTick = func(time.Time) { ticks++ }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := w.Watch(ctx)
+
+	assert.Equal(t, []int64{1, 2}, receive(t, results, 2))
+
+	rows.add(3)
+
+	assert.Equal(t, []int64{3}, receive(t, results, 1))
+	assertNothingMore(t, results)
+
+	cancel()
+
+	for range results {
+	}
+
+	assert.Greater(t, ticks, 2)
+}
+
+func TestResultsWatcherSurvivesErrors(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{fail: true}
+	rows.add(1)
+
+	m.handleChunked("/data/results/search_series", rows.chunks)
+
+	api := m.openAuthed(t)
+
+	errs := make(chan error, 10)
+
+	w := api.NewResultsWatcher(ResultsFilter{CustIDs: []int64{4242}}, time.Millisecond)
+	w.MaxBackoff = 5 * time.Millisecond
+	w.OnError = func(err error) { errs <- err }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := w.Watch(ctx)
+
+	assert.Error(t, <-errs)
+
+	rows.mu.Lock()
+	rows.fail = false
+	rows.mu.Unlock()
+
+	assert.Equal(t, []int64{1}, receive(t, results, 1))
+}
+
+func TestResultsWatcherResumes(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{}
+	rows.add(1)
+
+	m.handleChunked("/data/results/search_series", rows.chunks)
+
+	cacheDir := t.TempDir()
+	filter := ResultsFilter{CustIDs: []int64{4242}, OfficialOnly: true}
+
+	watchOnce := func(expected int) []int64 {
+		api := m.openAuthed(t)
+		defer api.Close()
+
+		assert.NoError(t, api.EnableCache(cacheDir))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		results := api.NewResultsWatcher(filter, 5*time.Millisecond).Watch(ctx)
+
+		ids := receive(t, results, expected)
+		assertNothingMore(t, results)
+
+		cancel()
+
+		for range results {
+		}
+
+		return ids
+	}
+
+	assert.Equal(t, []int64{1}, watchOnce(1))
+
+	rows.add(2)
+
+	// the restarted watcher only delivers what it hasn't seen
+	assert.Equal(t, []int64{2}, watchOnce(1))
+}
+
+func TestResultsWatcherLeague(t *testing.T) {
+	m := newMockAPI(t)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	m.handleChunked("/data/results/search_hosted", func(r *http.Request) []string {
+		// search_hosted doesn't search by league alone
+		assert.Equal(t, "7", r.URL.Query().Get("host_cust_id"))
+		assert.Equal(t, "42", r.URL.Query().Get("league_id"))
+
+		return []string{fmt.Sprintf(`[{"subsession_id":1,"league_id":42,"end_time":"%[1]s"},{"subsession_id":2,"league_id":43,"end_time":"%[1]s"},{"subsession_id":3,"league_id":42,"end_time":"%[1]s"}]`, now)}
+	})
+
+	api := m.openAuthed(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := api.NewResultsWatcher(ResultsFilter{LeagueID: 42, HostCustID: 7}, 5*time.Millisecond).Watch(ctx)
+
+	assert.Equal(t, []int64{1, 3}, receive(t, results, 2))
+	assertNothingMore(t, results)
+
+	errs := make(chan error, 10)
+
+	w := api.NewResultsWatcher(ResultsFilter{LeagueID: 42}, 5*time.Millisecond)
+	w.OnError = func(err error) { errs <- err }
+	w.Watch(ctx)
+
+	assert.EqualError(t, <-errs, "must provide host cust id with league id")
+}
+
+func TestResultsWatcherPollsThroughCache(t *testing.T) {
+	m := newMockAPI(t)
+
+	rows := &mockResults{}
+	rows.add(1)
+
+	m.handleChunked("/data/results/search_series", rows.chunks)
+
+	clock := newFakeClock()
+
+	api := m.openAuthed(t, WithClock(clock))
+	api.EnableCacheBackend(NewMemoryCache())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.Equal(t, []int64{1}, receive(t, api.NewResultsWatcher(ResultsFilter{CustIDs: []int64{4242}}, time.Hour).Watch(ctx), 1))
+
+	// anyone making the same search within the minute gets the cached one
+	params := SearchSeriesParams{CustID: 4242, FinishRangeBegin: clock.Now().Add(-defaultWatchLookback).Truncate(time.Minute)}
+
+	_, err := api.GetWithCache("/data/results/search_series?"+params.values().Encode(), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, m.hitCount("/data/results/search_series"))
+}